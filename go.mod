@@ -1,5 +1,8 @@
 module github.com/winkstreaming/wink-rtsp-bench
 
-go 1.19
+go 1.21
 
-require golang.org/x/time v0.5.0
+require (
+	golang.org/x/sys v0.20.0
+	golang.org/x/time v0.5.0
+)
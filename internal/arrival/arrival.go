@@ -0,0 +1,349 @@
+// Created by WINK Streaming (https://www.wink.co)
+package arrival
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Magic starts every arrival file
+const Magic = "WRTA"
+
+// Version is the file format version written
+const Version = 1
+
+// Record kinds
+const (
+	KindTrack  = 'T' // A track started playing
+	KindPacket = 'P' // One RTP packet
+	KindBucket = 'B' // A run of RTP packets of one track
+)
+
+// Record sizes, kind byte included, for the fixed-size kinds
+const (
+	packetRecordSize = 23
+	bucketRecordSize = 26
+)
+
+// headerSize is the size of the file header: magic, version, bucket size
+// and start time
+const headerSize = 16
+
+// Packet is an RTP packet as it arrived
+type Packet struct {
+	Track     int
+	Time      time.Time
+	Seq       uint16
+	Timestamp uint32
+	SSRC      uint32
+	Size      int
+	Marker    bool
+	Lost      uint64 // Packets the sequence tracker found missing before it
+}
+
+// bucket accumulates the packets of a track until the bucket is full
+type bucket struct {
+	first    time.Time
+	last     time.Time
+	count    int
+	firstSeq uint16
+	lastSeq  uint16
+	lost     uint64
+	bytes    uint64
+}
+
+// Writer writes the packet arrival timeline of a reader: every packet, or
+// one record per Bucket packets of a track. It is safe for concurrent
+// use; after the first write error it writes nothing.
+type Writer struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	closer  io.Closer
+	start   time.Time
+	size    int // Packets per record (1 = every packet)
+	pending map[int]*bucket
+	err     error
+}
+
+// Create creates an arrival file at path, summarizing bucketSize packets
+// per record (0 or 1 = a record per packet)
+func Create(path string, bucketSize int) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(f, bucketSize)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// NewWriter writes the file header to w and returns a Writer for records
+func NewWriter(w io.Writer, bucketSize int) (*Writer, error) {
+	if bucketSize < 1 {
+		bucketSize = 1
+	}
+	if bucketSize > 0xffff {
+		return nil, fmt.Errorf("bucket size %d too large", bucketSize)
+	}
+	aw := &Writer{
+		w:       bufio.NewWriterSize(w, 64*1024),
+		start:   time.Now(),
+		size:    bucketSize,
+		pending: make(map[int]*bucket),
+	}
+	var header [headerSize]byte
+	copy(header[0:], Magic)
+	binary.LittleEndian.PutUint16(header[4:], Version)
+	binary.LittleEndian.PutUint16(header[6:], uint16(bucketSize))
+	binary.LittleEndian.PutUint64(header[8:], uint64(aw.start.UnixNano()))
+	if _, err := aw.w.Write(header[:]); err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+// Track records that track index started playing, with its RTP clock
+// rate and a name such as "video/H264". Packet times before the next
+// track record of the same index belong to this session.
+func (w *Writer) Track(index, clockRate int, name string, at time.Time) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushBucket(index)
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	record := make([]byte, 15, 15+len(name))
+	record[0] = KindTrack
+	binary.LittleEndian.PutUint64(record[1:], w.offset(at))
+	record[9] = byte(index)
+	binary.LittleEndian.PutUint32(record[10:], uint32(clockRate))
+	record[14] = byte(len(name))
+	w.write(append(record, name...))
+}
+
+// Packet records an arrived packet
+func (w *Writer) Packet(p Packet) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size == 1 {
+		var record [packetRecordSize]byte
+		record[0] = KindPacket
+		binary.LittleEndian.PutUint64(record[1:], w.offset(p.Time))
+		record[9] = byte(p.Track)
+		if p.Marker {
+			record[10] = 1
+		}
+		binary.LittleEndian.PutUint16(record[11:], p.Seq)
+		binary.LittleEndian.PutUint32(record[13:], p.Timestamp)
+		binary.LittleEndian.PutUint32(record[17:], p.SSRC)
+		binary.LittleEndian.PutUint16(record[21:], uint16(min(p.Size, 0xffff)))
+		w.write(record[:])
+		return
+	}
+
+	b := w.pending[p.Track]
+	if b == nil {
+		b = &bucket{}
+		w.pending[p.Track] = b
+	}
+	if b.count == 0 {
+		b.first, b.firstSeq = p.Time, p.Seq
+	}
+	b.last, b.lastSeq = p.Time, p.Seq
+	b.count++
+	b.lost += p.Lost
+	b.bytes += uint64(p.Size)
+	if b.count >= w.size {
+		w.flushBucket(p.Track)
+	}
+}
+
+// flushBucket writes the pending bucket of a track, if any; the caller
+// must hold w.mu
+func (w *Writer) flushBucket(track int) {
+	b := w.pending[track]
+	if b == nil || b.count == 0 {
+		return
+	}
+	var record [bucketRecordSize]byte
+	record[0] = KindBucket
+	binary.LittleEndian.PutUint64(record[1:], w.offset(b.first))
+	binary.LittleEndian.PutUint32(record[9:], uint32(b.last.Sub(b.first).Microseconds()))
+	record[13] = byte(track)
+	binary.LittleEndian.PutUint16(record[14:], uint16(b.count))
+	binary.LittleEndian.PutUint16(record[16:], b.firstSeq)
+	binary.LittleEndian.PutUint16(record[18:], b.lastSeq)
+	binary.LittleEndian.PutUint16(record[20:], uint16(min(b.lost, 0xffff)))
+	binary.LittleEndian.PutUint32(record[22:], uint32(min(b.bytes, 0xffffffff)))
+	w.write(record[:])
+	*b = bucket{}
+}
+
+// offset returns t as nanoseconds since the file started
+func (w *Writer) offset(t time.Time) uint64 {
+	if t.Before(w.start) {
+		return 0
+	}
+	return uint64(t.Sub(w.start))
+}
+
+// write writes a record; the caller must hold w.mu
+func (w *Writer) write(record []byte) {
+	if w.err != nil {
+		return
+	}
+	if _, err := w.w.Write(record); err != nil {
+		w.err = err
+	}
+}
+
+// Close writes the partly filled buckets, flushes the file and closes it
+// if the Writer created it
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for track := range w.pending {
+		w.flushBucket(track)
+	}
+	err := w.w.Flush()
+	if w.err == nil {
+		w.err = err
+	}
+	if w.closer != nil {
+		if cerr := w.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	w.err = os.ErrClosed
+	return err
+}
+
+// Record is a record read back from an arrival file. Fields not carried
+// by its kind are zero.
+type Record struct {
+	Kind  byte
+	Time  time.Duration // Since the file started; the first packet of a bucket
+	Track int
+
+	// KindTrack
+	ClockRate int
+	Name      string
+
+	// KindPacket
+	Seq       uint16
+	Timestamp uint32
+	SSRC      uint32
+	Marker    bool
+	Size      int
+
+	// KindBucket
+	Span     time.Duration // From the first packet to the last
+	Count    int
+	FirstSeq uint16
+	LastSeq  uint16
+	Lost     int
+	Bytes    int64
+}
+
+// Reader reads an arrival file
+type Reader struct {
+	r      *bufio.Reader
+	Start  time.Time // When the file was started
+	Bucket int       // Packets per record (1 = every packet)
+}
+
+// NewReader reads the file header from r
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	var header [headerSize]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return nil, err
+	}
+	if string(header[:4]) != Magic {
+		return nil, errors.New("not an arrival file")
+	}
+	if v := binary.LittleEndian.Uint16(header[4:]); v != Version {
+		return nil, fmt.Errorf("unsupported arrival file version %d", v)
+	}
+	return &Reader{
+		r:      br,
+		Bucket: int(binary.LittleEndian.Uint16(header[6:])),
+		Start:  time.Unix(0, int64(binary.LittleEndian.Uint64(header[8:]))),
+	}, nil
+}
+
+// Next returns the next record, or io.EOF at the end of the file
+func (r *Reader) Next() (Record, error) {
+	kind, err := r.r.ReadByte()
+	if err != nil {
+		return Record{}, err
+	}
+	var size int
+	switch kind {
+	case KindTrack:
+		size = 14
+	case KindPacket:
+		size = packetRecordSize - 1
+	case KindBucket:
+		size = bucketRecordSize - 1
+	default:
+		return Record{}, fmt.Errorf("unknown record kind %q", kind)
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return Record{}, unexpected(err)
+	}
+
+	rec := Record{Kind: kind, Time: time.Duration(binary.LittleEndian.Uint64(buf[0:]))}
+	switch kind {
+	case KindTrack:
+		rec.Track = int(buf[8])
+		rec.ClockRate = int(binary.LittleEndian.Uint32(buf[9:]))
+		name := make([]byte, buf[13])
+		if _, err := io.ReadFull(r.r, name); err != nil {
+			return Record{}, unexpected(err)
+		}
+		rec.Name = string(name)
+	case KindPacket:
+		rec.Track = int(buf[8])
+		rec.Marker = buf[9]&1 != 0
+		rec.Seq = binary.LittleEndian.Uint16(buf[10:])
+		rec.Timestamp = binary.LittleEndian.Uint32(buf[12:])
+		rec.SSRC = binary.LittleEndian.Uint32(buf[16:])
+		rec.Size = int(binary.LittleEndian.Uint16(buf[20:]))
+	case KindBucket:
+		rec.Span = time.Duration(binary.LittleEndian.Uint32(buf[8:])) * time.Microsecond
+		rec.Track = int(buf[12])
+		rec.Count = int(binary.LittleEndian.Uint16(buf[13:]))
+		rec.FirstSeq = binary.LittleEndian.Uint16(buf[15:])
+		rec.LastSeq = binary.LittleEndian.Uint16(buf[17:])
+		rec.Lost = int(binary.LittleEndian.Uint16(buf[19:]))
+		rec.Bytes = int64(binary.LittleEndian.Uint32(buf[21:]))
+	}
+	return rec, nil
+}
+
+// unexpected turns the end of the file inside a record into
+// io.ErrUnexpectedEOF
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
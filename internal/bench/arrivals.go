@@ -0,0 +1,76 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/arrival"
+)
+
+// DefaultArrivalDir is where packet arrival timelines are written
+const DefaultArrivalDir = "arrivals"
+
+// arrivalSampler picks the good readers whose packet arrivals are written
+// to files, for offline jitter, burst loss and gap analysis
+type arrivalSampler struct {
+	dir     string
+	ratio   float64
+	limit   int64 // Most timelines per run (0 = no limit)
+	bucket  int   // Packets per record
+	started atomic.Int64
+	random  *lockedRand
+}
+
+// newArrivalSampler creates the sampler of Config.ArrivalRatio and its
+// directory
+func newArrivalSampler(config Config, random *lockedRand) (*arrivalSampler, error) {
+	if config.ArrivalRatio < 0 || config.ArrivalRatio > 1 {
+		return nil, fmt.Errorf("arrival ratio must be between 0 and 1")
+	}
+	if config.ArrivalLimit < 0 {
+		return nil, fmt.Errorf("arrival limit must not be negative")
+	}
+	if config.ArrivalBucket < 0 || config.ArrivalBucket > 0xffff {
+		return nil, fmt.Errorf("arrival bucket must be between 0 and 65535 packets")
+	}
+	s := &arrivalSampler{
+		dir:    config.ArrivalDir,
+		ratio:  config.ArrivalRatio,
+		limit:  int64(config.ArrivalLimit),
+		bucket: config.ArrivalBucket,
+		random: random,
+	}
+	if s.ratio == 0 {
+		return s, nil
+	}
+	if s.dir == "" {
+		s.dir = DefaultArrivalDir
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create arrival directory: %w", err)
+	}
+	return s, nil
+}
+
+// open starts the arrival file of a reader if it is sampled, or returns
+// nil. The caller closes it when the reader is done.
+func (s *arrivalSampler) open(connID string, log *slog.Logger) *arrival.Writer {
+	if s.ratio == 0 || s.random.Float64() >= s.ratio {
+		return nil
+	}
+	if n := s.started.Add(1); s.limit > 0 && n > s.limit {
+		return nil
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("session-%s.arrivals", connID))
+	w, err := arrival.Create(path, s.bucket)
+	if err != nil {
+		log.Warn("failed to start arrival log", "error", err)
+		return nil
+	}
+	log.Debug("logging packet arrivals", "path", path)
+	return w
+}
@@ -0,0 +1,82 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// badClientPicker chooses bad client types by weight; nil picks uniformly
+type badClientPicker struct {
+	types      []rtsp.BadClientType
+	cumulative []float64
+}
+
+// newBadClientPicker builds the picker from Config.BadClientType and
+// Config.BadClientWeights, or returns nil if neither is set
+func newBadClientPicker(config Config) (*badClientPicker, error) {
+	if config.BadClientType != "" {
+		t, err := rtsp.ParseBadClientType(config.BadClientType)
+		if err != nil {
+			return nil, err
+		}
+		return &badClientPicker{types: []rtsp.BadClientType{t}, cumulative: []float64{1}}, nil
+	}
+	if len(config.BadClientWeights) == 0 {
+		return nil, nil
+	}
+	
+	// Sorted, so the same random sequence picks the same types
+	names := make([]string, 0, len(config.BadClientWeights))
+	for name := range config.BadClientWeights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	p := &badClientPicker{}
+	total := 0.0
+	for _, name := range names {
+		weight := config.BadClientWeights[name]
+		t, err := rtsp.ParseBadClientType(name)
+		if err != nil {
+			return nil, err
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("negative weight for bad client type %s", name)
+		}
+		if weight == 0 {
+			continue
+		}
+		total += weight
+		p.types = append(p.types, t)
+		p.cumulative = append(p.cumulative, total)
+	}
+	if total == 0 {
+		return nil, errors.New("all bad client weights are zero")
+	}
+	return p, nil
+}
+
+// newClient creates a bad client for url with a picked type, seeded from rng
+func (p *badClientPicker) newClient(url string, rng *lockedRand) *rtsp.BadClient {
+	bc := rtsp.NewBadClientOfType(url, p.pick(rng))
+	bc.Seed(rng.Int63())
+	return bc
+}
+
+// pick draws a type from rng
+func (p *badClientPicker) pick(rng *lockedRand) rtsp.BadClientType {
+	if p == nil {
+		return rtsp.BadClientType(rng.Intn(int(rtsp.NumBadClientTypes)))
+	}
+	n := rng.Float64() * p.cumulative[len(p.cumulative)-1]
+	for i, c := range p.cumulative {
+		if n < c {
+			return p.types[i]
+		}
+	}
+	return p.types[len(p.types)-1]
+}
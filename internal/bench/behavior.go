@@ -0,0 +1,119 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/impair"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// Viewer behavior defaults
+const (
+	DefaultPauseInterval = 30 * time.Second
+	DefaultPauseDuration = 5 * time.Second
+)
+
+// viewer is the behavior picked for one good reader; the zero value just
+// plays
+type viewer struct {
+	pauseEvery time.Duration
+	pauseHold  time.Duration
+	seekEvery  time.Duration
+	impairment *impair.Model // nil = clean network
+	readKbps   int           // Slow consumer read cap (0 = read at full speed)
+	readBuffer int
+	feedback   rtsp.Feedback
+	player     *rtsp.PlayerProfile // Player mimicked, nil = the bench's own behavior
+	tracks     string              // rtsp.TracksAll, TracksVideo or TracksAudio
+	backchannel bool
+}
+
+// newViewer picks a behavior for a good reader: Config.PauseRatio of
+// readers get a PAUSE/PLAY cycle, and all seek if Config.SeekInterval is set
+func newViewer(config Config, rng *lockedRand) viewer {
+	v := viewer{seekEvery: config.SeekInterval, feedback: config.RTCPFeedback, backchannel: config.Backchannel}
+	if config.SlowReaderRatio > 0 && rng.Float64() < config.SlowReaderRatio {
+		v.readKbps = config.SlowReaderKbps
+		if v.readKbps <= 0 {
+			v.readKbps = DefaultSlowReaderKbps
+		}
+		v.readBuffer = config.SlowReaderBuffer
+	}
+	if config.Impairment != nil && (config.ImpairRatio <= 0 || rng.Float64() < config.ImpairRatio) {
+		v.impairment = config.Impairment
+	}
+	if config.PauseRatio > 0 && rng.Float64() < config.PauseRatio {
+		v.pauseEvery = config.PauseInterval
+		if v.pauseEvery <= 0 {
+			v.pauseEvery = DefaultPauseInterval
+		}
+		v.pauseHold = config.PauseDuration
+		if v.pauseHold <= 0 {
+			v.pauseHold = DefaultPauseDuration
+		}
+	}
+	return v
+}
+
+// apply configures client for the behavior
+func (v viewer) apply(client *rtsp.Client) {
+	if v.pauseEvery > 0 {
+		client.SetPauseCycle(v.pauseEvery, v.pauseHold)
+	}
+	if v.seekEvery > 0 {
+		client.SetSeek(v.seekEvery)
+	}
+	if v.impairment != nil {
+		client.SetImpairment(*v.impairment)
+	}
+	if v.readKbps > 0 {
+		client.SetReadRate(v.readKbps, v.readBuffer)
+	}
+	if v.feedback.Enabled() {
+		client.SetFeedback(v.feedback)
+	}
+	if v.player != nil {
+		client.SetPlayerProfile(*v.player)
+	}
+	if v.tracks != "" {
+		client.SetTrackSelection(v.tracks)
+	}
+	if v.backchannel {
+		client.SetBackchannel(true)
+	}
+}
+
+// fillViewerStats copies the pause and seek statistics into stats
+func fillViewerStats(stats *Stats, snapshot rtp.Snapshot) {
+	stats.Pauses = snapshot.Pauses
+	stats.PauseFailures = snapshot.PauseFailures
+	stats.PauseAvg = snapshot.PauseAvg
+	stats.PauseP95 = snapshot.PauseP95
+	stats.ResumeAvg = snapshot.ResumeAvg
+	stats.ResumeP95 = snapshot.ResumeP95
+	stats.Seeks = snapshot.Seeks
+	stats.SeekFailures = snapshot.SeekFailures
+	stats.SeekAvg = snapshot.SeekAvg
+	stats.SeekP95 = snapshot.SeekP95
+	stats.SeekMediaAvg = snapshot.SeekMediaAvg
+	stats.SeeksChecked = snapshot.SeeksChecked
+	stats.SeekMismatches = snapshot.SeekMismatches
+	stats.ImpairedSessions = snapshot.ImpairedSessions
+	stats.ImpairDropped = snapshot.ImpairDropped
+	stats.ImpairDuplicated = snapshot.ImpairDuplicated
+	stats.ImpairReordered = snapshot.ImpairReordered
+	stats.NACKsSent = snapshot.NACKsSent
+	stats.NACKRequested = snapshot.NACKRequested
+	stats.NACKRepaired = snapshot.NACKRepaired
+	stats.NACKRepairAvg = snapshot.NACKRepairAvg
+	stats.NACKRepairP95 = snapshot.NACKRepairP95
+	stats.PLIsSent = snapshot.PLIsSent
+	stats.PLIAnswered = snapshot.PLIAnswered
+	stats.PLIKeyframeAvg = snapshot.PLIKeyframeAvg
+	stats.PLIKeyframeP95 = snapshot.PLIKeyframeP95
+	stats.ByesSent = snapshot.ByesSent
+	stats.RTXPackets = snapshot.RTXPackets
+	stats.RTXRecovered = snapshot.RTXRecovered
+}
@@ -0,0 +1,39 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+)
+
+// fillLossBursts copies the loss burst distribution into stats, keyed by
+// length class
+func fillLossBursts(stats *Stats, snapshot rtp.Snapshot) {
+	for i, n := range snapshot.LossBursts {
+		if n > 0 {
+			if stats.LossBursts == nil {
+				stats.LossBursts = make(map[string]int64, rtp.BurstClasses)
+			}
+			stats.LossBursts[rtp.BurstClassNames[i]] = int64(n)
+		}
+	}
+	stats.LossBurstMax = float64(snapshot.LongestBurst)
+	stats.SessionLossBurstP50 = snapshot.SessionBurstP50
+	stats.SessionLossBurstP95 = snapshot.SessionBurstP95
+}
+
+// writeLossBursts prints the loss burst line of the text summary
+func writeLossBursts(w io.Writer, stats Stats) error {
+	var classes []string
+	for _, name := range rtp.BurstClassNames {
+		if n := stats.LossBursts[name]; n > 0 {
+			classes = append(classes, fmt.Sprintf("%s: %d", name, n))
+		}
+	}
+	_, err := fmt.Fprintf(w, "  Loss bursts: %s | Longest: %.0f | Per-session longest: p50 %.0f, p95 %.0f\n",
+		strings.Join(classes, ", "), stats.LossBurstMax, stats.SessionLossBurstP50, stats.SessionLossBurstP95)
+	return err
+}
@@ -0,0 +1,71 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/pcap"
+)
+
+// DefaultCaptureDir is where session captures are written
+const DefaultCaptureDir = "captures"
+
+// captureSampler picks the good readers whose sessions are written to pcap
+// files, for post-mortem analysis of loss and corruption
+type captureSampler struct {
+	dir     string
+	ratio   float64
+	limit   int64 // Most captures per run (0 = no limit)
+	started atomic.Int64
+	random  *lockedRand
+}
+
+// newCaptureSampler creates the sampler of Config.CaptureRatio and its
+// directory
+func newCaptureSampler(config Config, random *lockedRand) (*captureSampler, error) {
+	if config.CaptureRatio < 0 || config.CaptureRatio > 1 {
+		return nil, fmt.Errorf("capture ratio must be between 0 and 1")
+	}
+	if config.CaptureLimit < 0 {
+		return nil, fmt.Errorf("capture limit must not be negative")
+	}
+	s := &captureSampler{
+		dir:    config.CaptureDir,
+		ratio:  config.CaptureRatio,
+		limit:  int64(config.CaptureLimit),
+		random: random,
+	}
+	if s.ratio == 0 {
+		return s, nil
+	}
+	if s.dir == "" {
+		s.dir = DefaultCaptureDir
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	return s, nil
+}
+
+// open starts the capture file of a reader if it is sampled, or returns
+// nil. The caller closes it when the reader is done.
+func (s *captureSampler) open(connID string, log *slog.Logger) *pcap.Writer {
+	if s.ratio == 0 || s.random.Float64() >= s.ratio {
+		return nil
+	}
+	if n := s.started.Add(1); s.limit > 0 && n > s.limit {
+		return nil
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("session-%s.pcap", connID))
+	w, err := pcap.Create(path)
+	if err != nil {
+		log.Warn("failed to start capture", "error", err)
+		return nil
+	}
+	log.Debug("capturing session", "path", path)
+	return w
+}
@@ -0,0 +1,211 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrRegression is returned by Run when a metric got worse than the
+// baseline by more than its tolerance, so the caller can exit nonzero
+var ErrRegression = errors.New("regression against baseline")
+
+// DefaultTolerances are the comparisons made when Config.Tolerances is
+// empty: connect p95 up to 10% slower, loss up to 0.1 points higher, and
+// peak and find-max connections up to 5% lower
+const DefaultTolerances = "p95_connect_ms=10%,loss_pct=0.1,peak_active=5%,capacity=5%"
+
+// Tolerance is how much worse than the baseline a metric may get, e.g.
+// p95_connect_ms=10% or loss_pct=0.1
+type Tolerance struct {
+	Metric   string
+	Value    float64
+	Relative bool // Value is a percentage of the baseline
+}
+
+// ParseTolerances parses a comma-separated list of tolerances. Metric names
+// are those of assertions (see MetricNames).
+func ParseTolerances(s string) ([]Tolerance, error) {
+	known := statsMetrics(Stats{})
+
+	var tolerances []Tolerance
+	for _, expr := range strings.Split(s, ",") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		metric, value, ok := strings.Cut(expr, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid tolerance %q: want metric=value or metric=value%%", expr)
+		}
+		t := Tolerance{Metric: strings.TrimSpace(metric)}
+		value = strings.TrimSpace(value)
+		if strings.HasSuffix(value, "%") {
+			t.Relative = true
+			value = strings.TrimSuffix(value, "%")
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid tolerance %q: bad value", expr)
+		}
+		t.Value = v
+		if _, ok := known[t.Metric]; !ok {
+			return nil, fmt.Errorf("invalid tolerance %q: unknown metric %s", expr, t.Metric)
+		}
+		tolerances = append(tolerances, t)
+	}
+	return tolerances, nil
+}
+
+// String formats the tolerance as it was written
+func (t Tolerance) String() string {
+	s := t.Metric + "=" + strconv.FormatFloat(t.Value, 'f', -1, 64)
+	if t.Relative {
+		s += "%"
+	}
+	return s
+}
+
+// higherIsBetter lists the compared metrics for which a drop is the
+// regression; for all others a rise is
+var higherIsBetter = map[string]bool{
+	"peak_active": true, "capacity": true, "connects": true, "packets": true, "bytes": true,
+	"keyframes": true, "frames_verified": true, "reconnects_recovered": true, "nack_repaired": true,
+	"pli_answered": true, "rtx_recovered": true, "rate_connections": true,
+	"conn_bitrate_min_kbps": true, "conn_bitrate_p5_kbps": true, "conn_bitrate_p50_kbps": true,
+	"conn_bitrate_p95_kbps": true, "conn_pps_min": true, "conn_pps_p5": true, "conn_pps_p50": true,
+	"conn_pps_p95": true,
+}
+
+// ComparisonResult is one metric against the baseline
+type ComparisonResult struct {
+	Metric    string  `json:"metric"`
+	Baseline  float64 `json:"baseline"`
+	Actual    float64 `json:"actual"`
+	Delta     float64 `json:"delta"`
+	DeltaPct  float64 `json:"delta_pct"` // Delta as a percentage of the baseline (0 when the baseline is 0)
+	Tolerance string  `json:"tolerance"`
+	Pass      bool    `json:"pass"`
+}
+
+// Compare checks the final statistics against a baseline run. Metrics that
+// are zero in both runs are left out.
+func Compare(baseline, stats Stats, tolerances []Tolerance) []ComparisonResult {
+	before, after := statsMetrics(baseline), statsMetrics(stats)
+	var results []ComparisonResult
+	for _, t := range tolerances {
+		b, a := before[t.Metric], after[t.Metric]
+		if b == 0 && a == 0 {
+			continue
+		}
+		r := ComparisonResult{
+			Metric:    t.Metric,
+			Baseline:  b,
+			Actual:    a,
+			Delta:     a - b,
+			Tolerance: t.String(),
+		}
+		if b != 0 {
+			r.DeltaPct = (a - b) * 100 / math.Abs(b)
+		}
+		worse := a - b
+		if higherIsBetter[t.Metric] {
+			worse = b - a
+		}
+		allowed := t.Value
+		if t.Relative {
+			allowed = math.Abs(b) * t.Value / 100
+		}
+		r.Pass = worse <= allowed
+		results = append(results, r)
+	}
+	return results
+}
+
+// LoadBaseline reads the final statistics of an earlier run from its JSON
+// output (Config.LogFormat json): the summary record, or the last record
+// if there is none. A plain Stats object works too. peak_active is taken
+// from the samples for runs that predate it.
+func LoadBaseline(path string) (Stats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer f.Close()
+
+	var final *jsonRecord
+	var peak int64
+	dec := json.NewDecoder(f)
+	for {
+		var rec jsonRecord
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Stats{}, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+		}
+		if rec.ActiveConnects > peak {
+			peak = rec.ActiveConnects
+		}
+		if final == nil || final.Type != "summary" {
+			final = &rec
+		}
+	}
+	if final == nil {
+		return Stats{}, fmt.Errorf("%s: no statistics found", path)
+	}
+	stats := final.Stats
+	if peak > stats.PeakActive {
+		stats.PeakActive = peak
+	}
+	return stats, nil
+}
+
+// comparison is the baseline and tolerances of Config.Baseline
+type comparison struct {
+	path       string
+	baseline   Stats
+	tolerances []Tolerance
+}
+
+// newComparison loads Config.Baseline and parses Config.Tolerances; nil if
+// no baseline is set
+func newComparison(config Config) (*comparison, error) {
+	if config.Baseline == "" {
+		if config.Tolerances != "" {
+			return nil, fmt.Errorf("tolerances need a baseline")
+		}
+		return nil, nil
+	}
+	spec := config.Tolerances
+	if spec == "" {
+		spec = DefaultTolerances
+	}
+	tolerances, err := ParseTolerances(spec)
+	if err != nil {
+		return nil, err
+	}
+	baseline, err := LoadBaseline(config.Baseline)
+	if err != nil {
+		return nil, err
+	}
+	return &comparison{path: config.Baseline, baseline: baseline, tolerances: tolerances}, nil
+}
+
+// trackPeak raises peak to n if n is higher
+func trackPeak(peak *atomic.Int64, n int64) {
+	for {
+		p := peak.Load()
+		if n <= p || peak.CompareAndSwap(p, n) {
+			return
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// Per-connection rate tracking
+const (
+	RateWindow         = 5 * time.Second // Time constant of the rate averages
+	rateSampleInterval = time.Second
+	starvedFraction    = 0.5 // Below this share of the median bitrate a connection is starved
+)
+
+// ConnectionStats describes one live reader connection
+type ConnectionStats struct {
+	ID          string  `json:"id"`
+	Target      string  `json:"target"`
+	LocalAddr   string  `json:"local_addr,omitempty"` // Of the control connection
+	Transport   string  `json:"transport"`
+	Tracks      string  `json:"tracks"` // Track selection: all, video or audio
+	AgeSeconds  float64 `json:"age_s"`
+	Packets     uint64  `json:"packets"`
+	Bytes       uint64  `json:"bytes"`
+	BitrateKbps float64 `json:"bitrate_kbps"` // RateWindow moving average
+	PPS         float64 `json:"pps"`          // RateWindow moving average
+	Sampled     bool    `json:"sampled"`      // False until the first sample interval has passed
+}
+
+// connRate is the rate state of one connection
+type connRate struct {
+	stats  ConnectionStats
+	client *rtsp.Client
+	start  time.Time
+	last   time.Time // Last sample
+}
+
+// connRates samples the received counters of live connections and keeps
+// an exponentially weighted bitrate and packet rate for each
+type connRates struct {
+	mu      sync.Mutex
+	conns   map[string]*connRate
+	ips     map[string]bool    // Local IPs of every connection added
+	medians map[string]float64 // Median kbps by track selection, as of the last sample
+}
+
+// newConnRates creates an empty connection rate tracker
+func newConnRates() *connRates {
+	return &connRates{conns: make(map[string]*connRate), ips: make(map[string]bool)}
+}
+
+// add starts tracking a connected client
+func (c *connRates) add(id, target, transport, tracks string, client *rtsp.Client) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conns[id] = &connRate{
+		stats:  ConnectionStats{ID: id, Target: target, Transport: transport, Tracks: tracks},
+		client: client,
+		start:  now,
+		last:   now,
+	}
+	if addr := client.LocalAddr(); addr != "" {
+		c.ips[hostOf(addr)] = true
+	}
+}
+
+// remove stops tracking a connection
+func (c *connRates) remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.conns, id)
+}
+
+// run samples every connection each rateSampleInterval until ctx is done
+func (c *connRates) run(ctx context.Context) {
+	ticker := time.NewTicker(rateSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.sample(now)
+		}
+	}
+}
+
+// sample updates the moving averages from the counters' growth since the
+// previous sample. The first sample seeds the averages directly.
+func (c *connRates) sample(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, conn := range c.conns {
+		dt := now.Sub(conn.last).Seconds()
+		if dt <= 0 {
+			continue
+		}
+		packets, bytes := conn.client.Received()
+		pps := float64(packets-conn.stats.Packets) / dt
+		kbps := float64(bytes-conn.stats.Bytes) * 8 / 1000 / dt
+
+		if conn.stats.Sampled {
+			alpha := 1 - math.Exp(-dt/RateWindow.Seconds())
+			conn.stats.PPS += alpha * (pps - conn.stats.PPS)
+			conn.stats.BitrateKbps += alpha * (kbps - conn.stats.BitrateKbps)
+		} else {
+			conn.stats.PPS = pps
+			conn.stats.BitrateKbps = kbps
+			conn.stats.Sampled = true
+		}
+		conn.stats.Packets = packets
+		conn.stats.Bytes = bytes
+		conn.last = now
+	}
+	
+	cohorts := make(map[string][]float64)
+	for _, conn := range c.conns {
+		if conn.stats.Sampled {
+			cohorts[conn.stats.Tracks] = append(cohorts[conn.stats.Tracks], conn.stats.BitrateKbps)
+		}
+	}
+	c.medians = make(map[string]float64, len(cohorts))
+	for tracks, cohort := range cohorts {
+		sort.Float64s(cohort)
+		c.medians[tracks] = percentile(cohort, 50)
+	}
+}
+
+// median returns the median bitrate of the connections with the track
+// selection tracks as of the last sample, or 0 if none was sampled
+func (c *connRates) median(tracks string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.medians[tracks]
+}
+
+// list returns every live connection, oldest first
+func (c *connRates) list() []ConnectionStats {
+	now := time.Now()
+	c.mu.Lock()
+	conns := make([]*connRate, 0, len(c.conns))
+	for _, conn := range c.conns {
+		conns = append(conns, conn)
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].start.Before(conns[j].start) })
+
+	list := make([]ConnectionStats, len(conns))
+	for i, conn := range conns {
+		list[i] = conn.stats
+		list[i].AgeSeconds = now.Sub(conn.start).Seconds()
+		list[i].LocalAddr = conn.client.LocalAddr()
+	}
+	c.mu.Unlock()
+	return list
+}
+
+// liveReader is a connection as server-side session checks see it
+type liveReader struct {
+	addr  string // Local address of the control connection
+	bytes uint64 // Received so far, read now rather than at the last sample
+	age   time.Duration
+}
+
+// sourceIP reports whether any connection used ip as its local address
+func (c *connRates) sourceIP(ip string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ips[ip]
+}
+
+// readers returns every live connection with its current byte count
+func (c *connRates) readers() []liveReader {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	readers := make([]liveReader, 0, len(c.conns))
+	for _, conn := range c.conns {
+		_, bytes := conn.client.Received()
+		readers = append(readers, liveReader{addr: conn.client.LocalAddr(), bytes: bytes, age: now.Sub(conn.start)})
+	}
+	return readers
+}
+
+// fill summarizes the sampled connection rates into stats as percentiles
+// across connections, and counts connections starved relative to the
+// median of those with the same track selection
+func (c *connRates) fill(stats *Stats) {
+	var kbps, pps []float64
+	cohorts := make(map[string][]float64) // kbps by track selection
+	for _, conn := range c.list() {
+		if conn.Sampled {
+			kbps = append(kbps, conn.BitrateKbps)
+			pps = append(pps, conn.PPS)
+			cohorts[conn.Tracks] = append(cohorts[conn.Tracks], conn.BitrateKbps)
+		}
+	}
+	if len(kbps) == 0 {
+		return
+	}
+	sort.Float64s(kbps)
+	sort.Float64s(pps)
+
+	stats.RateConnections = len(kbps)
+	stats.ConnBitrateMin = kbps[0]
+	stats.ConnBitrateP5 = percentile(kbps, 5)
+	stats.ConnBitrateP50 = percentile(kbps, 50)
+	stats.ConnBitrateP95 = percentile(kbps, 95)
+	stats.ConnPPSMin = pps[0]
+	stats.ConnPPSP5 = percentile(pps, 5)
+	stats.ConnPPSP50 = percentile(pps, 50)
+	stats.ConnPPSP95 = percentile(pps, 95)
+
+	for _, cohort := range cohorts {
+		sort.Float64s(cohort)
+		threshold := percentile(cohort, 50) * starvedFraction
+		for _, v := range cohort {
+			if v >= threshold {
+				break
+			}
+			stats.StarvedConnections++
+		}
+	}
+}
+
+// percentile returns the p-th percentile of sorted values (nearest rank)
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
@@ -0,0 +1,350 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Control API paths
+const (
+	ControlPathStats       = "/stats"
+	ControlPathStatus      = "/status"
+	ControlPathTarget      = "/target"
+	ControlPathTargets     = "/targets"
+	ControlPathBadClients  = "/bad-client-ratio"
+	ControlPathPhaseNext   = "/phase/next"
+	ControlPathPhasePause  = "/phase/pause"
+	ControlPathPhaseResume = "/phase/resume"
+	ControlPathStop        = "/stop"
+	ControlPathConnections = "/connections"
+)
+
+// ControlStatus is returned by the status endpoint
+type ControlStatus struct {
+	Mode           string   `json:"mode"`
+	Phase          int      `json:"phase,omitempty"` // 1-based, scenario mode only
+	PhaseName      string   `json:"phase_name,omitempty"`
+	Paused         bool     `json:"paused"`
+	Target         int64    `json:"target"`
+	TargetOverride *int     `json:"target_override,omitempty"`
+	BadClientRatio *float64 `json:"bad_client_ratio,omitempty"` // Override, if set
+}
+
+// controlState holds runtime overrides set through the control API. The
+// pool-driven run loops consult it every tick.
+type controlState struct {
+	mu        sync.Mutex
+	target    *int
+	badRatio  *float64
+	paused    bool
+	skip      bool
+	stop      func()
+	phase     int
+	phaseName string
+}
+
+// readers returns the target override, or def if none is set
+func (c *controlState) readers(def int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.target != nil {
+		return *c.target
+	}
+	return def
+}
+
+// badClientRatio returns the bad-client ratio override, or def if none is set
+func (c *controlState) badClientRatio(def float64) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.badRatio != nil {
+		return *c.badRatio
+	}
+	return def
+}
+
+// isPaused reports whether scenario phase time is frozen
+func (c *controlState) isPaused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// takeSkip reports and clears a pending request to end the current phase
+func (c *controlState) takeSkip() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	skip := c.skip
+	c.skip = false
+	return skip
+}
+
+// setPhase records the running scenario phase for the status endpoint
+func (c *controlState) setPhase(i int, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.phase = i
+	c.phaseName = name
+	c.skip = false
+}
+
+// startControl serves the control API on Config.ControlAddr until the
+// returned stop function is called. stopRun ends the benchmark early. It
+// is a no-op when no address is configured.
+func (r *Runner) startControl(stopRun func()) (func(), error) {
+	if r.config.ControlAddr == "" {
+		return func() {}, nil
+	}
+
+	ln, err := net.Listen("tcp", r.config.ControlAddr)
+	if err != nil {
+		return nil, err
+	}
+	r.control.mu.Lock()
+	r.control.stop = stopRun
+	r.control.mu.Unlock()
+
+	srv := &http.Server{Handler: r.controlHandler()}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			r.log.Error("control API failed", "error", err)
+		}
+	}()
+	r.log.Info("control API listening", "addr", ln.Addr().String())
+	stopLive := make(chan struct{})
+	go r.live.run(r.GetStats, stopLive)
+
+	return func() {
+		close(stopLive)
+		srv.Close()
+	}, nil
+}
+
+// controlHandler returns the control API
+func (r *Runner) controlHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ControlPathStats, func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, r.GetStats())
+	})
+	mux.HandleFunc(ControlPathStatus, func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, r.controlStatus())
+	})
+	mux.HandleFunc(ControlPathConnections, func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, r.Connections())
+	})
+	mux.HandleFunc(ControlPathTarget, r.handleTarget)
+	mux.HandleFunc(ControlPathTargets, r.handleTargets)
+	mux.HandleFunc(ControlPathBadClients, r.handleBadClientRatio)
+	mux.HandleFunc(ControlPathPhaseNext, r.controlAction("phase skipped", func(c *controlState) { c.skip = true }))
+	mux.HandleFunc(ControlPathPhasePause, r.controlAction("phase paused", func(c *controlState) { c.paused = true }))
+	mux.HandleFunc(ControlPathPhaseResume, r.controlAction("phase resumed", func(c *controlState) { c.paused = false }))
+	mux.HandleFunc(ControlPathStop, r.controlAction("stop requested", func(c *controlState) {
+		if c.stop != nil {
+			c.stop()
+		}
+	}))
+	mux.HandleFunc(ControlPathUI, r.handleUI)
+	mux.HandleFunc(ControlPathLive, r.handleLive)
+	return mux
+}
+
+// controlMode names the run mode for the status endpoint
+func (r *Runner) controlMode() string {
+	switch {
+	case r.config.Scenario != nil:
+		return "scenario"
+	case r.config.FindMax != nil:
+		return "find-max"
+	case r.config.Profile != nil:
+		return "profile"
+	default:
+		return "fixed"
+	}
+}
+
+// controlStatus returns the current overrides and phase
+func (r *Runner) controlStatus() ControlStatus {
+	c := r.control
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ControlStatus{
+		Mode:           r.controlMode(),
+		Phase:          c.phase,
+		PhaseName:      c.phaseName,
+		Paused:         c.paused,
+		Target:         r.targetReaders.Load(),
+		TargetOverride: c.target,
+		BadClientRatio: c.badRatio,
+	}
+}
+
+// handleTarget sets the reader count override. {"readers": null} clears it.
+func (r *Runner) handleTarget(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.config.FindMax != nil {
+		http.Error(w, "target cannot be changed during a find-max search", http.StatusConflict)
+		return
+	}
+
+	var body struct {
+		Readers *int `json:"readers"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Readers != nil && *body.Readers < 0 {
+		http.Error(w, "readers must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	r.control.mu.Lock()
+	r.control.target = body.Readers
+	r.control.mu.Unlock()
+	r.log.Info("target changed", "readers", logValue(body.Readers))
+	writeJSON(w, r.controlStatus())
+}
+
+// ControlTargets is returned by the targets endpoint
+type ControlTargets struct {
+	URLs    []string `json:"urls"`              // Where new readers go
+	Retired []string `json:"retired,omitempty"` // Taken out by a swap
+}
+
+// handleTargets returns the target list, or swaps it on POST with
+// {"urls": [...], "weights": [...]}
+func (r *Runner) handleTargets(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var body struct {
+			URLs    []string `json:"urls"`
+			Weights []int    `json:"weights"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := r.SwapTargets(body.URLs, body.Weights); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	targets := ControlTargets{URLs: r.targets.urls()}
+	for _, t := range r.targets.all() {
+		if t.retired.Load() {
+			targets.Retired = append(targets.Retired, t.url)
+		}
+	}
+	writeJSON(w, targets)
+}
+
+// handleBadClientRatio sets the bad-client ratio override. {"ratio": null}
+// clears it.
+func (r *Runner) handleBadClientRatio(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Ratio *float64 `json:"ratio"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Ratio != nil && (*body.Ratio < 0 || *body.Ratio > 1) {
+		http.Error(w, "ratio must be between 0 and 1", http.StatusBadRequest)
+		return
+	}
+
+	r.control.mu.Lock()
+	r.control.badRatio = body.Ratio
+	r.control.mu.Unlock()
+	r.log.Info("bad client ratio changed", "ratio", logValue(body.Ratio))
+	writeJSON(w, r.controlStatus())
+}
+
+// controlAction returns a POST handler that applies fn to the control state
+func (r *Runner) controlAction(msg string, fn func(c *controlState)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		r.control.mu.Lock()
+		fn(r.control)
+		r.control.mu.Unlock()
+		r.log.Info(msg)
+		writeJSON(w, r.controlStatus())
+	}
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// logValue renders an optional override for logging
+func logValue[T any](v *T) slog.Value {
+	if v == nil {
+		return slog.StringValue("cleared")
+	}
+	return slog.AnyValue(*v)
+}
+
+// runControlled runs a fixed-count benchmark whose reader count and
+// bad-client ratio can be changed through the control API. Readers are
+// started at up to Config.Rate and run until the benchmark ends.
+func (r *Runner) runControlled(ctx context.Context) error {
+	r.log.Info("starting controlled benchmark", "readers", r.config.Readers, "rate", r.config.Rate,
+		"transport", r.config.Transport, "targets", len(r.targets.all()))
+
+	pool := newReaderPool(ctx, r)
+	defer func() {
+		pool.closeAll()
+		r.log.Info("waiting for connections to close", "active", r.activeConnects.Load())
+		r.wg.Wait()
+	}()
+
+	s := r.poolSession()
+	badRatio := 0.0
+	if r.config.IncludeBadClients {
+		badRatio = r.config.BadClientRatio
+	}
+	maxSpawn := 0
+	if r.config.Rate > 0 {
+		maxSpawn = int(r.config.Rate*scenarioTick.Seconds() + 0.5)
+		if maxSpawn < 1 {
+			maxSpawn = 1
+		}
+	}
+
+	ticker := time.NewTicker(scenarioTick)
+	defer ticker.Stop()
+	for {
+		pool.resize(r.control.readers(r.config.Readers), s, r.control.badClientRatio(badRatio), maxSpawn)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
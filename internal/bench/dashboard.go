@@ -0,0 +1,220 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dashboard layout
+const (
+	DashboardRefresh = time.Second
+	dashboardEvents  = 500 // Event log lines kept in memory
+	dashboardHeader  = 10  // Lines used above the event log
+)
+
+// ANSI escape sequences
+const (
+	ansiAltScreen  = "\x1b[?1049h\x1b[?25l" // Alternate screen, hide cursor
+	ansiMainScreen = "\x1b[?25h\x1b[?1049l" // Restore screen and cursor
+	ansiHome       = "\x1b[H\x1b[2J"
+	ansiBold       = "\x1b[1m"
+	ansiReset      = "\x1b[0m"
+)
+
+// dashboard renders a live full-screen view of the run. Everything the
+// benchmark prints to stdout is captured into a scrolling event log.
+type dashboard struct {
+	out      *os.File // The real stdout
+	target   int64    // Readers (fixed mode) when Stats has no target
+	getStats func() Stats
+
+	mu     sync.Mutex
+	events []string
+
+	start     time.Time
+	lastBytes uint64
+	lastTime  time.Time
+	bitrate   float64 // Mbps over the last refresh
+
+	readerDone chan struct{}
+}
+
+// startDashboard takes over the terminal until the returned function is
+// called, which restores it and prints the tail of the event log. It keeps
+// refreshing through shutdown (drain, connection close) until then.
+func startDashboard(config Config, getStats func() Stats) (func(), error) {
+	if !config.Dashboard {
+		return func() {}, nil
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture output: %w", err)
+	}
+
+	d := &dashboard{
+		out:        os.Stdout,
+		target:     int64(config.Readers),
+		getStats:   getStats,
+		start:      time.Now(),
+		lastTime:   time.Now(),
+		readerDone: make(chan struct{}),
+	}
+	os.Stdout = w
+	go d.capture(r)
+
+	fmt.Fprint(d.out, ansiAltScreen)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(DashboardRefresh)
+		defer ticker.Stop()
+		d.render()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				d.render()
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+
+		os.Stdout = d.out
+		w.Close()
+		<-d.readerDone
+
+		fmt.Fprint(d.out, ansiMainScreen)
+		d.mu.Lock()
+		for _, line := range tail(d.events, d.height()-1) {
+			fmt.Fprintln(d.out, line)
+		}
+		d.mu.Unlock()
+	}, nil
+}
+
+// capture reads lines printed to stdout into the event log
+func (d *dashboard) capture(r io.ReadCloser) {
+	defer close(d.readerDone)
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		d.mu.Lock()
+		d.events = append(d.events, scanner.Text())
+		if len(d.events) > dashboardEvents {
+			d.events = d.events[len(d.events)-dashboardEvents:]
+		}
+		d.mu.Unlock()
+	}
+}
+
+// render redraws the whole screen
+func (d *dashboard) render() {
+	stats := d.getStats()
+	now := time.Now()
+
+	if secs := now.Sub(d.lastTime).Seconds(); secs > 0 && stats.RTPBytes >= d.lastBytes {
+		d.bitrate = float64(stats.RTPBytes-d.lastBytes) * 8 / secs / 1_000_000
+	}
+	d.lastBytes = stats.RTPBytes
+	d.lastTime = now
+
+	target := stats.TargetConnects
+	if target == 0 {
+		target = d.target
+	}
+
+	width := d.width()
+	var b strings.Builder
+	b.WriteString(ansiHome)
+	fmt.Fprintf(&b, "%sWINK RTSP Bench%s  elapsed %s\n", ansiBold, ansiReset,
+		now.Sub(d.start).Round(time.Second))
+	fmt.Fprintf(&b, "Connections  active %d / target %d | total %d | failed %d | session timeouts %d\n",
+		stats.ActiveConnects, target, stats.TotalConnects, stats.TotalFailures, stats.SessionTimeouts)
+	fmt.Fprintf(&b, "Connect      p50 %.1fms | p90 %.1fms | p95 %.1fms | p99 %.1fms | max %.1fms\n",
+		stats.P50ConnectTime, stats.P90ConnectTime, stats.P95ConnectTime, stats.P99ConnectTime, stats.MaxConnectTime)
+	fmt.Fprintf(&b, "Response     p50 %.1fms | p90 %.1fms | p95 %.1fms | p99 %.1fms | max %.1fms\n",
+		stats.ResponseConnectP50, stats.ResponseConnectP90, stats.ResponseConnectP95, stats.ResponseConnectP99, stats.ResponseConnectMax)
+	fmt.Fprintf(&b, "Media        %.2f Mbps | packets %d | loss %.2f%% | jitter avg %.2fms\n",
+		d.bitrate, stats.RTPPackets, lossPercent(stats), stats.JitterAvg)
+	fmt.Fprintf(&b, "Teardown     sent %d | failed %d | avg %.1fms | p95 %.1fms\n",
+		stats.Teardowns, stats.TeardownFailures, stats.TeardownAvg, stats.TeardownP95)
+	fmt.Fprintf(&b, "Bad clients  %d %s\n", stats.BadClients, formatCounts(stats.BadClientTypes))
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "%sEvents%s\n", ansiBold, ansiReset)
+
+	d.mu.Lock()
+	for _, line := range tail(d.events, d.height()-dashboardHeader) {
+		if len(line) > width {
+			line = line[:width]
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	d.mu.Unlock()
+
+	fmt.Fprint(d.out, b.String())
+}
+
+// height returns the terminal height from $LINES, defaulting to 24
+func (d *dashboard) height() int {
+	return envInt("LINES", 24)
+}
+
+// width returns the terminal width from $COLUMNS, defaulting to 80
+func (d *dashboard) width() int {
+	return envInt("COLUMNS", 80)
+}
+
+// envInt parses a positive integer environment variable
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// tail returns up to the last n lines
+func tail(lines []string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// formatCounts renders a count map as "(a:1, b:2)" in key order
+func formatCounts(counts map[string]int64) string {
+	if len(counts) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s:%d", k, counts[k])
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
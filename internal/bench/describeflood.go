@@ -0,0 +1,70 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// A flood counts as degraded when its last DESCRIBE responses are both
+// DescribeDegradeFactor times and DescribeDegradeMin slower than its first
+const (
+	DescribeDegradeFactor = 2
+	DescribeDegradeMin    = time.Millisecond // Ignore drift in sub-millisecond responses
+)
+
+// describeFloodStats tracks the DescribeFlood bad clients
+type describeFloodStats struct {
+	floods      atomic.Int64
+	requests    atomic.Int64
+	errors      atomic.Int64
+	regenerated atomic.Int64 // Floods that saw the SDP change between responses
+	degraded    atomic.Int64 // Floods whose latency grew past the degrade thresholds
+	baseline    *histogram.Histogram
+	final       *histogram.Histogram
+	max         *histogram.Histogram
+}
+
+// newDescribeFloodStats creates empty DESCRIBE flood statistics
+func newDescribeFloodStats() *describeFloodStats {
+	return &describeFloodStats{baseline: histogram.New(), final: histogram.New(), max: histogram.New()}
+}
+
+// record adds a finished flood; floods that got no response are ignored
+func (s *describeFloodStats) record(res rtsp.DescribeFloodResult, log *slog.Logger) {
+	if res.Requests == 0 {
+		return
+	}
+	s.floods.Add(1)
+	s.requests.Add(int64(res.Requests))
+	s.errors.Add(int64(res.Errors))
+	if res.SDPChanges > 0 {
+		s.regenerated.Add(1)
+	}
+	degraded := res.Final > DescribeDegradeFactor*res.Baseline && res.Final-res.Baseline > DescribeDegradeMin
+	if degraded {
+		s.degraded.Add(1)
+	}
+	s.baseline.Record(res.Baseline)
+	s.final.Record(res.Final)
+	s.max.Record(res.Max)
+	log.Debug("describe flood finished", "requests", res.Requests, "errors", res.Errors,
+		"baseline_ms", res.Baseline.Milliseconds(), "final_ms", res.Final.Milliseconds(),
+		"sdp_changes", res.SDPChanges, "sdp_variants", res.SDPVariants, "degraded", degraded)
+}
+
+// fill copies the DESCRIBE flood statistics into stats
+func (s *describeFloodStats) fill(stats *Stats) {
+	stats.DescribeFloods = s.floods.Load()
+	stats.DescribeFloodRequests = s.requests.Load()
+	stats.DescribeFloodErrors = s.errors.Load()
+	stats.DescribeFloodRegenerated = s.regenerated.Load()
+	stats.DescribeFloodDegraded = s.degraded.Load()
+	stats.DescribeBaselineAvg = s.baseline.Summary().Mean
+	stats.DescribeFinalAvg = s.final.Summary().Mean
+	stats.DescribeMax = s.max.Summary().Max
+}
@@ -0,0 +1,77 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// dialer holds how connections reach the server: local source addresses,
+// address family selection, name resolution and socket tuning
+type dialer struct {
+	sources  *sourcePool // nil for the kernel default
+	opts     rtsp.DialOptions
+	sockOpts rtsp.SocketOptions
+	resolver *resolver
+}
+
+// newDialer builds the dialer from Config.SourceIPs, Config.BindInterface,
+// Config.IPFamily, Config.HappyEyeballsDelay, the DNS settings and the
+// socket options
+func newDialer(config Config) (*dialer, error) {
+	switch config.IPFamily {
+	case rtsp.FamilyAny, rtsp.FamilyIPv4, rtsp.FamilyIPv6:
+	default:
+		return nil, fmt.Errorf("unknown IP family %q (want ipv4 or ipv6)", config.IPFamily)
+	}
+	sources, err := newSourcePool(config)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := newResolver(config)
+	if err != nil {
+		return nil, err
+	}
+	sockOpts := rtsp.SocketOptions{
+		Nagle:             config.TCPNagle,
+		RecvBuffer:        config.SocketRecvBuffer,
+		SendBuffer:        config.SocketSendBuffer,
+		KeepAliveIdle:     config.TCPKeepAlive,
+		KeepAliveInterval: config.TCPKeepAliveInterval,
+		KeepAliveCount:    config.TCPKeepAliveCount,
+		DSCP:              config.DSCP,
+	}
+	if err := sockOpts.Validate(); err != nil {
+		return nil, fmt.Errorf("socket options: %w", err)
+	}
+	return &dialer{
+		sources:  sources,
+		opts:     rtsp.DialOptions{Family: config.IPFamily, HappyEyeballs: config.HappyEyeballsDelay},
+		sockOpts: sockOpts,
+		resolver: resolver,
+	}, nil
+}
+
+// apply configures a reader client for its next connection
+func (d *dialer) apply(client *rtsp.Client) {
+	client.SetSource(d.sources.pick())
+	client.SetDialOptions(d.opts)
+	client.SetSocketOptions(d.sockOpts)
+	client.SetResolver(d.resolver.resolve)
+}
+
+// applyPublisher configures a publisher for its next connection
+func (d *dialer) applyPublisher(pub *rtsp.Publisher) {
+	pub.SetSource(d.sources.pick())
+	pub.SetDialOptions(d.opts)
+	pub.SetSocketOptions(d.sockOpts)
+	pub.SetResolver(d.resolver.resolve)
+}
+
+// fill copies the DNS statistics into stats; nothing if the settings were invalid
+func (d *dialer) fill(stats *Stats) {
+	if d != nil {
+		d.resolver.fill(stats)
+	}
+}
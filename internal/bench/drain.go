@@ -0,0 +1,122 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+	"golang.org/x/time/rate"
+)
+
+// drainer closes sessions at a fixed rate at the end of a run instead of
+// letting them all stop at once, and measures TEARDOWN latency meanwhile
+type drainer struct {
+	rate float64 // sessions closed per second
+	log  *slog.Logger
+
+	// Sessions run under ctx, which is independent of the run context so
+	// that they survive until the drain closes them
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	sessions map[uint64]context.CancelFunc
+	nextID   uint64
+	draining atomic.Bool
+
+	teardowns *teardownStats // Sessions closed by the drain only
+}
+
+// newDrainer creates a drainer, or returns nil if drainRate is not positive
+func newDrainer(drainRate float64, log *slog.Logger) *drainer {
+	if drainRate <= 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &drainer{
+		rate:            drainRate,
+		log:             log,
+		ctx:             ctx,
+		cancel:          cancel,
+		sessions:        make(map[uint64]context.CancelFunc),
+		teardowns:       newTeardownStats(),
+	}
+}
+
+// session returns a context for a new session and a release function that
+// must be called when the session ends
+func (d *drainer) session(timeout time.Duration) (context.Context, func()) {
+	ctx, cancel := context.WithTimeout(d.ctx, timeout)
+
+	d.mu.Lock()
+	id := d.nextID
+	d.nextID++
+	d.sessions[id] = cancel
+	d.mu.Unlock()
+
+	return ctx, func() {
+		d.mu.Lock()
+		delete(d.sessions, id)
+		d.mu.Unlock()
+		cancel()
+	}
+}
+
+// recordTeardown records a session's TEARDOWN outcome if it was closed by the drain
+func (d *drainer) recordTeardown(result rtsp.TeardownResult) {
+	if d.draining.Load() {
+		d.teardowns.record(result)
+	}
+}
+
+// run closes registered sessions at the configured rate until none remain
+func (d *drainer) run() {
+	d.draining.Store(true)
+	defer d.cancel() // Backstop for sessions registered after the drain
+
+	d.mu.Lock()
+	total := len(d.sessions)
+	d.mu.Unlock()
+
+	d.log.Info("draining connections", "count", total, "rate", d.rate)
+
+	burst := int(d.rate)
+	if burst < 1 {
+		burst = 1
+	}
+	limiter := rate.NewLimiter(rate.Limit(d.rate), burst)
+	start := time.Now()
+	closed := 0
+
+	for {
+		d.mu.Lock()
+		var cancel context.CancelFunc
+		for id, c := range d.sessions {
+			cancel = c
+			delete(d.sessions, id)
+			break
+		}
+		d.mu.Unlock()
+
+		if cancel == nil {
+			break
+		}
+
+		_ = limiter.Wait(context.Background())
+		cancel()
+		closed++
+
+		if closed%1000 == 0 {
+			d.log.Info("drained connections", "closed", closed, "total", total)
+		}
+	}
+
+	summary := d.teardowns.latency.Summary()
+	d.log.Info("drain complete", "closed", closed, "elapsed", time.Since(start).Round(time.Millisecond),
+		"teardown_avg_ms", summary.Mean, "teardown_p95_ms", summary.P95, "teardown_max_ms", summary.Max,
+		"teardown_failures", d.teardowns.failures.Load())
+}
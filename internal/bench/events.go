@@ -0,0 +1,195 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+)
+
+// TrafficEvent is a sudden crowd of extra viewers on top of the real-world
+// load, like a breaking-news alert or a kickoff. Viewers who start watching
+// during the ramp and hold are counted as the event's.
+type TrafficEvent struct {
+	Name    string
+	At      time.Duration // Start, since the beginning of the run
+	Viewers int           // Extra viewers at the peak
+	Ramp    time.Duration // Time to reach the peak (0 = at once)
+	Hold    time.Duration // Time at the peak
+	Decay   time.Duration // Time constant of the exponential decay after Hold (0 = all leave at once)
+}
+
+// extra returns the event's extra viewers elapsed into the run
+func (e *TrafficEvent) extra(elapsed time.Duration) float64 {
+	t := elapsed - e.At
+	switch {
+	case t < 0:
+		return 0
+	case t < e.Ramp:
+		return float64(e.Viewers) * float64(t) / float64(e.Ramp)
+	case t < e.Ramp+e.Hold:
+		return float64(e.Viewers)
+	case e.Decay <= 0:
+		return 0
+	}
+	v := float64(e.Viewers) * math.Exp(-float64(t-e.Ramp-e.Hold)/float64(e.Decay))
+	if v < 0.5 {
+		return 0 // The tail never reaches zero otherwise
+	}
+	return v
+}
+
+// end returns when viewers stop being counted as the event's
+func (e *TrafficEvent) end() time.Duration {
+	return e.At + e.Ramp + e.Hold
+}
+
+// EventStats holds how the viewers of one traffic event fared: whether
+// they got to play and how long connecting took
+type EventStats struct {
+	Viewers  int64   `json:"viewers"`  // Extra viewers at the peak
+	Attempts int64   `json:"attempts"` // Viewers started during the ramp and hold
+	Connects int64   `json:"connects"`
+	Failures int64   `json:"failures"` // Connect failures and sessions that failed before playing
+	Avg      float64 `json:"avg_connect_ms"`
+	P50      float64 `json:"p50_connect_ms"`
+	P95      float64 `json:"p95_connect_ms"`
+	P99      float64 `json:"p99_connect_ms"`
+	Max      float64 `json:"max_connect_ms"`
+}
+
+// eventCounters is one event's viewer outcomes
+type eventCounters struct {
+	attempts atomic.Int64
+	connects atomic.Int64
+	failures atomic.Int64
+	latency  *histogram.Histogram
+	reported atomic.Bool // Summary logged once the ramp and hold are over
+}
+
+// eventSet schedules the traffic events of a run and counts their viewers
+type eventSet struct {
+	events   []TrafficEvent
+	counters []*eventCounters
+}
+
+// newEventSet validates events and names the unnamed ones
+func newEventSet(events []TrafficEvent) (*eventSet, error) {
+	set := &eventSet{}
+	names := make(map[string]bool)
+	for i, e := range events {
+		if e.Name == "" {
+			e.Name = fmt.Sprintf("event-%d", i+1)
+		}
+		if names[e.Name] {
+			return nil, fmt.Errorf("duplicate traffic event name: %s", e.Name)
+		}
+		names[e.Name] = true
+		if e.At < 0 || e.Viewers <= 0 || e.Ramp < 0 || e.Hold < 0 || e.Decay < 0 {
+			return nil, fmt.Errorf("traffic event %s: needs positive viewers and no negative times", e.Name)
+		}
+		if e.Ramp+e.Hold == 0 {
+			return nil, fmt.Errorf("traffic event %s: needs a ramp or hold", e.Name)
+		}
+		set.events = append(set.events, e)
+		set.counters = append(set.counters, &eventCounters{latency: histogram.New()})
+	}
+	return set, nil
+}
+
+// extra returns the extra viewers of all events elapsed into the run
+func (s *eventSet) extra(elapsed time.Duration) int64 {
+	var sum float64
+	for i := range s.events {
+		sum += s.events[i].extra(elapsed)
+	}
+	return int64(sum + 0.5)
+}
+
+// current returns the counters of the event a viewer starting now belongs
+// to, the latest one in its ramp or hold, or nil
+func (s *eventSet) current(elapsed time.Duration) *eventCounters {
+	var latest *eventCounters
+	var latestAt time.Duration = -1
+	for i, e := range s.events {
+		if elapsed >= e.At && elapsed < e.end() && e.At > latestAt {
+			latest, latestAt = s.counters[i], e.At
+		}
+	}
+	return latest
+}
+
+// report logs the summary of every event whose ramp and hold are over
+func (s *eventSet) report(elapsed time.Duration, log *slog.Logger) {
+	for i, e := range s.events {
+		c := s.counters[i]
+		if elapsed < e.end() || c.reported.Swap(true) {
+			continue
+		}
+		es := c.stats(e)
+		log.Info("traffic event peak over", "event", e.Name, "attempts", es.Attempts,
+			"connects", es.Connects, "failures", es.Failures, "p95_connect_ms", es.P95)
+	}
+}
+
+// stats returns the event's statistics so far
+func (c *eventCounters) stats(e TrafficEvent) EventStats {
+	latency := c.latency.Summary()
+	return EventStats{
+		Viewers:  int64(e.Viewers),
+		Attempts: c.attempts.Load(),
+		Connects: c.connects.Load(),
+		Failures: c.failures.Load(),
+		Avg:      latency.Mean,
+		P50:      latency.P50,
+		P95:      latency.P95,
+		P99:      latency.P99,
+		Max:      latency.Max,
+	}
+}
+
+// fill copies the statistics of the events that started into stats
+func (s *eventSet) fill(stats *Stats, elapsed time.Duration) {
+	for i, e := range s.events {
+		if elapsed < e.At {
+			continue
+		}
+		if stats.Events == nil {
+			stats.Events = make(map[string]EventStats)
+		}
+		stats.Events[e.Name] = s.counters[i].stats(e)
+	}
+}
+
+// writeEvents prints one line per traffic event, in name order
+func writeEvents(w io.Writer, events map[string]EventStats) error {
+	names := make([]string, 0, len(events))
+	for name := range events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e := events[name]
+		success := 0.0
+		if e.Attempts > 0 {
+			success = float64(e.Attempts-e.Failures) / float64(e.Attempts) * 100
+		}
+		parts := []string{
+			fmt.Sprintf("%d viewers", e.Viewers),
+			fmt.Sprintf("%d attempts", e.Attempts),
+			fmt.Sprintf("%.1f%% success", success),
+			fmt.Sprintf("connect p50 %.1fms p95 %.1fms p99 %.1fms", e.P50, e.P95, e.P99),
+		}
+		if _, err := fmt.Fprintf(w, "  Event %s: %s\n", name, strings.Join(parts, " | ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Push exporter settings
+const (
+	exportTimeout     = 5 * time.Second       // Per push; a slow backend delays the next sample
+	influxMeasurement = "rtsp_bench"          // Measurement of the stats fields
+	influxFailures    = "rtsp_bench_failures" // Measurement of failure_categories, tagged by category
+	exportService     = "wink-rtsp-bench"     // OTLP service.name and scope
+	otlpPrefix        = "rtsp_bench."         // Prefix of the OTLP metric names
+)
+
+// ExportTags identify the samples of one run in an observability backend
+type ExportTags struct {
+	RunID    string
+	Scenario string // Scenario name, or the run mode without one
+	Target   string // Target hosts, comma-separated
+}
+
+// NewExportTags returns the tags of config's run, generating a run ID
+// unless Config.RunID is set
+func NewExportTags(config Config) ExportTags {
+	tags := ExportTags{RunID: config.RunID, Scenario: runMode(config)}
+	if tags.RunID == "" {
+		tags.RunID = NewRunID()
+	}
+	if config.Scenario != nil && config.Scenario.Name != "" {
+		tags.Scenario = config.Scenario.Name
+	}
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, target := range reportTargets(config) {
+		u, err := url.Parse(target)
+		if err != nil || u.Host == "" || seen[u.Host] {
+			continue
+		}
+		seen[u.Host] = true
+		hosts = append(hosts, u.Host)
+	}
+	tags.Target = strings.Join(hosts, ",")
+	return tags
+}
+
+// attrs lists the tags as key/value pairs, skipping empty values
+func (t ExportTags) attrs() [][2]string {
+	var attrs [][2]string
+	for _, kv := range [][2]string{{"run_id", t.RunID}, {"scenario", t.Scenario}, {"target_host", t.Target}} {
+		if kv[1] != "" {
+			attrs = append(attrs, kv)
+		}
+	}
+	return attrs
+}
+
+// NewExporters returns a Formatter for each push exporter configured in
+// config (Config.InfluxURL, Config.OTLPURL). Pushes that fail are logged
+// and skipped, so an unreachable backend never stops a run.
+func NewExporters(config Config, tags ExportTags, log *slog.Logger) []Formatter {
+	var exporters []Formatter
+	if config.InfluxURL != "" {
+		p := newPusher("influx", config.InfluxURL, log)
+		if config.InfluxToken != "" {
+			p.header.Set("Authorization", "Token "+config.InfluxToken)
+		}
+		exporters = append(exporters, &influxExporter{pusher: p, tags: tags})
+	}
+	if config.OTLPURL != "" {
+		exporters = append(exporters, &otlpExporter{pusher: newPusher("otlp", config.OTLPURL, log), tags: tags})
+	}
+	return exporters
+}
+
+// pusher posts records to an HTTP endpoint, logging when pushes start
+// failing and when they recover
+type pusher struct {
+	name    string
+	url     string
+	header  http.Header
+	client  *http.Client
+	log     *slog.Logger
+	failing bool
+}
+
+func newPusher(name, endpoint string, log *slog.Logger) *pusher {
+	return &pusher{
+		name:   name,
+		url:    endpoint,
+		header: make(http.Header),
+		client: &http.Client{Timeout: exportTimeout},
+		log:    log,
+	}
+}
+
+// push posts body and logs a change between success and failure
+func (p *pusher) push(contentType string, body []byte) {
+	err := p.post(contentType, body)
+	switch {
+	case err != nil && !p.failing:
+		p.log.Warn("stats export failed", "exporter", p.name, "url", redactURL(p.url), "error", err)
+	case err == nil && p.failing:
+		p.log.Info("stats export recovered", "exporter", p.name)
+	}
+	p.failing = err != nil
+}
+
+func (p *pusher) post(contentType string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range p.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// sortedMetrics returns the numeric stats in name order, with the elapsed
+// time as elapsed_sec
+func sortedMetrics(elapsed time.Duration, stats Stats) ([]string, map[string]float64) {
+	metrics := statsMetrics(stats)
+	metrics["elapsed_sec"] = elapsed.Seconds()
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, metrics
+}
+
+// influxExporter pushes records in InfluxDB line protocol, which the v1
+// /write and v2 /api/v2/write endpoints both accept
+type influxExporter struct {
+	*pusher
+	tags ExportTags
+}
+
+func (e *influxExporter) WriteSample(elapsed time.Duration, stats Stats) error {
+	e.push("text/plain; charset=utf-8", e.lines(time.Now(), elapsed, stats))
+	return nil
+}
+
+func (e *influxExporter) WriteSummary(elapsed time.Duration, stats Stats) error {
+	return e.WriteSample(elapsed, stats)
+}
+
+// lines formats one record: the stats fields, and a line per failure
+// category
+func (e *influxExporter) lines(now time.Time, elapsed time.Duration, stats Stats) []byte {
+	var b bytes.Buffer
+	tags := ""
+	for _, kv := range e.tags.attrs() {
+		tags += "," + influxEscape(kv[0]) + "=" + influxEscape(kv[1])
+	}
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+
+	names, metrics := sortedMetrics(elapsed, stats)
+	b.WriteString(influxMeasurement + tags + " ")
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(influxEscape(name) + "=" + strconv.FormatFloat(metrics[name], 'f', -1, 64))
+	}
+	b.WriteString(" " + ts + "\n")
+
+	categories := make([]string, 0, len(stats.FailureCategories))
+	for category := range stats.FailureCategories {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		fmt.Fprintf(&b, "%s%s,category=%s count=%di %s\n", influxFailures, tags,
+			influxEscape(category), stats.FailureCategories[category], ts)
+	}
+	return b.Bytes()
+}
+
+// influxEscape escapes a tag key, tag value or field key
+func influxEscape(s string) string {
+	return strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `).Replace(s)
+}
+
+// otlpExporter pushes records as OTLP/HTTP JSON metrics, one gauge per
+// stats field, with the tags as resource attributes
+type otlpExporter struct {
+	*pusher
+	tags ExportTags
+}
+
+func (e *otlpExporter) WriteSample(elapsed time.Duration, stats Stats) error {
+	body, err := json.Marshal(e.request(time.Now(), elapsed, stats))
+	if err != nil {
+		return err
+	}
+	e.push("application/json", body)
+	return nil
+}
+
+func (e *otlpExporter) WriteSummary(elapsed time.Duration, stats Stats) error {
+	return e.WriteSample(elapsed, stats)
+}
+
+// OTLP JSON encoding of ExportMetricsServiceRequest, limited to gauges
+type (
+	otlpRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpMetric struct {
+		Name  string    `json:"name"`
+		Unit  string    `json:"unit,omitempty"`
+		Gauge otlpGauge `json:"gauge"`
+	}
+	otlpGauge struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	}
+	otlpDataPoint struct {
+		Attributes   []otlpAttribute `json:"attributes,omitempty"`
+		TimeUnixNano string          `json:"timeUnixNano"`
+		AsDouble     float64         `json:"asDouble"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// request builds one record: a gauge per stats field, and a failures gauge
+// with a point per category
+func (e *otlpExporter) request(now time.Time, elapsed time.Duration, stats Stats) otlpRequest {
+	resource := []otlpAttribute{{Key: "service.name", Value: otlpValue{exportService}}}
+	for _, kv := range e.tags.attrs() {
+		resource = append(resource, otlpAttribute{Key: kv[0], Value: otlpValue{kv[1]}})
+	}
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+
+	names, values := sortedMetrics(elapsed, stats)
+	metrics := make([]otlpMetric, 0, len(names)+1)
+	for _, name := range names {
+		metrics = append(metrics, otlpMetric{
+			Name:  otlpPrefix + name,
+			Unit:  otlpUnit(name),
+			Gauge: otlpGauge{DataPoints: []otlpDataPoint{{TimeUnixNano: ts, AsDouble: values[name]}}},
+		})
+	}
+	if len(stats.FailureCategories) > 0 {
+		failures := otlpMetric{Name: otlpPrefix + "failures_by_category"}
+		for category, n := range stats.FailureCategories {
+			failures.Gauge.DataPoints = append(failures.Gauge.DataPoints, otlpDataPoint{
+				Attributes:   []otlpAttribute{{Key: "category", Value: otlpValue{category}}},
+				TimeUnixNano: ts,
+				AsDouble:     float64(n),
+			})
+		}
+		sort.Slice(failures.Gauge.DataPoints, func(i, j int) bool {
+			return failures.Gauge.DataPoints[i].Attributes[0].Value.StringValue <
+				failures.Gauge.DataPoints[j].Attributes[0].Value.StringValue
+		})
+		metrics = append(metrics, failures)
+	}
+
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource:     otlpResource{Attributes: resource},
+		ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: exportService}, Metrics: metrics}},
+	}}}
+}
+
+// otlpUnit derives the UCUM unit from a metric name's suffix
+func otlpUnit(name string) string {
+	switch {
+	case strings.HasSuffix(name, "_ms"):
+		return "ms"
+	case strings.HasSuffix(name, "_sec"):
+		return "s"
+	case strings.HasSuffix(name, "_pct"):
+		return "%"
+	case strings.HasSuffix(name, "_kbps"):
+		return "kbit/s"
+	case strings.HasSuffix(name, "_mbps"):
+		return "Mbit/s"
+	}
+	return ""
+}
@@ -0,0 +1,242 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+	"golang.org/x/time/rate"
+)
+
+// migrator moves readers off the targets a target swap retired, at a
+// fixed rate, like viewers leaving a node that is being drained, and
+// measures how long each went without playback
+type migrator struct {
+	rate float64 // Readers moved per second (0 = readers are not moved)
+	log  *slog.Logger
+
+	mu      sync.Mutex
+	readers map[string]*migrant // By connection ID
+	wake    chan struct{}       // A swap retired targets
+
+	swaps     atomic.Int64
+	moved     atomic.Int64 // Readers that left a retired target
+	failed    atomic.Int64 // Of those, readers that did not play again
+	gap       *histogram.Histogram // From leaving the retired target to PLAY on the new one
+}
+
+// migrant is a reader's session that may be told to move
+type migrant struct {
+	target *target
+	move   context.CancelFunc
+	moving bool
+}
+
+// newMigrator creates a migrator moving migrationRate readers per second
+func newMigrator(migrationRate float64, log *slog.Logger) *migrator {
+	return &migrator{
+		rate:    migrationRate,
+		log:     log,
+		readers: make(map[string]*migrant),
+		wake:    make(chan struct{}, 1),
+		gap:     histogram.New(),
+	}
+}
+
+// watch registers a reader's session on t. The session runs under the
+// returned context, which the migrator cancels to move the reader;
+// release unregisters it and reports whether that happened.
+func (m *migrator) watch(ctx context.Context, connID string, t *target) (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	mg := &migrant{target: t, move: cancel}
+	m.mu.Lock()
+	m.readers[connID] = mg
+	m.mu.Unlock()
+	if t.retired.Load() {
+		m.signal() // Picked before the swap
+	}
+
+	return ctx, func() bool {
+		m.mu.Lock()
+		delete(m.readers, connID)
+		moving := mg.moving
+		m.mu.Unlock()
+		cancel()
+		return moving
+	}
+}
+
+// signal wakes the migrator
+func (m *migrator) signal() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run moves readers off retired targets, one at a time at the configured
+// rate, until ctx ends
+func (m *migrator) run(ctx context.Context) {
+	if m.rate <= 0 {
+		return
+	}
+	burst := int(m.rate)
+	if burst < 1 {
+		burst = 1
+	}
+	limiter := rate.NewLimiter(rate.Limit(m.rate), burst)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.wake:
+		}
+		for {
+			if limiter.Wait(ctx) != nil {
+				return
+			}
+			mg := m.nextRetired()
+			if mg == nil {
+				break
+			}
+			mg.move()
+		}
+	}
+}
+
+// nextRetired marks a reader on a retired target as moving and returns it,
+// or nil if none is left
+func (m *migrator) nextRetired() *migrant {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, mg := range m.readers {
+		if !mg.moving && mg.target.retired.Load() {
+			mg.moving = true
+			return mg
+		}
+	}
+	return nil
+}
+
+// landed records the session of a reader that moved at left: the gap
+// until it played on its new target, or a failed move
+func (m *migrator) landed(client *rtsp.Client, left time.Time) {
+	if !client.Playing() {
+		m.failed.Add(1)
+		return
+	}
+	m.gap.Record(client.PlayTime().Sub(left))
+}
+
+// fill copies the swap and migration statistics into stats, with the
+// readers still on retired targets
+func (m *migrator) fill(stats *Stats, targets *targetSet) {
+	stats.TargetSwaps = m.swaps.Load()
+	stats.Migrations = m.moved.Load()
+	stats.MigrationsFailed = m.failed.Load()
+	if gap := m.gap.Summary(); gap.Count > 0 {
+		stats.MigrationGapAvg = gap.Mean
+		stats.MigrationGapP95 = gap.P95
+		stats.MigrationGapMax = gap.Max
+	}
+	for _, t := range targets.all() {
+		if t.retired.Load() {
+			stats.RetiredActive += t.active.Load()
+		}
+	}
+}
+
+// writeMigrations prints the target swap line of the text summary
+func writeMigrations(w io.Writer, stats Stats) error {
+	_, err := fmt.Fprintf(w, "  Target swaps: %d | Migrated: %d (failed: %d), gap avg %.0fms, p95 %.0fms, max %.0fms | Still on retired targets: %d\n",
+		stats.TargetSwaps, stats.Migrations, stats.MigrationsFailed, stats.MigrationGapAvg, stats.MigrationGapP95, stats.MigrationGapMax, stats.RetiredActive)
+	return err
+}
+
+// SwapTargets replaces the target list during the run: new readers, and
+// readers moved off targets left out, go to urls, with optional weights
+// as in Config.TargetWeights. Targets that stay keep their readers.
+func (r *Runner) SwapTargets(urls []string, weights []int) error {
+	if len(urls) == 0 {
+		return fmt.Errorf("target list is empty")
+	}
+	for _, u := range urls {
+		if u == "" {
+			return fmt.Errorf("empty target URL")
+		}
+	}
+
+	retired := r.targets.swap(urls, weights)
+	r.migrations.swaps.Add(1)
+	r.log.Info("targets swapped", "targets", len(r.targets.urls()), "retired", len(retired), "migration_rate", r.migrations.rate)
+	if len(retired) > 0 {
+		r.migrations.signal()
+	}
+	return nil
+}
+
+// watchTargetsFile swaps the target list for the contents of
+// Config.TargetsFile on every SIGHUP until ctx ends
+func (r *Runner) watchTargetsFile(ctx context.Context) {
+	if r.config.TargetsFile == "" {
+		return
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+			}
+			urls, weights, err := LoadURLFile(r.config.TargetsFile)
+			if err == nil {
+				err = r.SwapTargets(urls, weights)
+			}
+			if err != nil {
+				r.log.Error("target reload failed", "file", r.config.TargetsFile, "error", err)
+			}
+		}
+	}()
+}
+
+// connectMoved connects a new client for a reader that moved to t.
+// Returns nil if it failed, which is counted like a failed reconnect.
+func (r *Runner) connectMoved(t *target, newClient func() (*rtsp.Client, error), log *slog.Logger) *rtsp.Client {
+	client, err := newClient()
+	if err != nil {
+		r.totalFailures.Add(1)
+		r.failures.record(rtsp.FailureOther)
+		t.failures.Add(1)
+		return nil
+	}
+	start := time.Now()
+	err = client.Connect()
+	r.families.record(client.DialAttempts())
+	if err != nil {
+		category := rtsp.ClassifyFailure(err, false)
+		log.Warn("connect after target swap failed", "error", err, "category", category)
+		r.totalFailures.Add(1)
+		r.failures.record(category)
+		t.failures.Add(1)
+		r.transcripts.dump(client, category, err, t.url, log)
+		return nil
+	}
+	t.connectLatency.Record(time.Since(start))
+	r.totalConnects.Add(1)
+	t.connects.Add(1)
+	return client
+}
@@ -0,0 +1,50 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// failureStats counts reader failures by rtsp.FailureCategories
+type failureStats struct {
+	counts [len(rtsp.FailureCategories)]atomic.Int64
+}
+
+// record counts a failure of the given category
+func (s *failureStats) record(category string) {
+	for i, c := range rtsp.FailureCategories {
+		if c == category {
+			s.counts[i].Add(1)
+			return
+		}
+	}
+}
+
+// fill copies the non-zero categories into stats
+func (s *failureStats) fill(stats *Stats) {
+	for i, c := range rtsp.FailureCategories {
+		if n := s.counts[i].Load(); n > 0 {
+			if stats.FailureCategories == nil {
+				stats.FailureCategories = make(map[string]int64)
+			}
+			stats.FailureCategories[c] = n
+		}
+	}
+}
+
+// writeFailures prints the non-zero categories on one line, in report order
+func writeFailures(w io.Writer, counts map[string]int64) error {
+	var parts []string
+	for _, c := range rtsp.FailureCategories {
+		if n := counts[c]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", c, n))
+		}
+	}
+	_, err := fmt.Fprintf(w, "  Failures by cause: %s\n", strings.Join(parts, " | "))
+	return err
+}
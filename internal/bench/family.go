@@ -0,0 +1,44 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"sync/atomic"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// familyStats counts connection attempts by address family
+type familyStats struct {
+	ipv4Connects atomic.Int64
+	ipv4Failures atomic.Int64
+	ipv6Connects atomic.Int64
+	ipv6Failures atomic.Int64
+	canceled     atomic.Int64 // Lost a Happy Eyeballs race
+}
+
+// record adds the attempts of one Connect
+func (s *familyStats) record(attempts []rtsp.DialAttempt) {
+	for _, a := range attempts {
+		switch {
+		case a.Canceled:
+			s.canceled.Add(1)
+		case a.IPv6 && a.Err == nil:
+			s.ipv6Connects.Add(1)
+		case a.IPv6:
+			s.ipv6Failures.Add(1)
+		case a.Err == nil:
+			s.ipv4Connects.Add(1)
+		default:
+			s.ipv4Failures.Add(1)
+		}
+	}
+}
+
+// fill copies the per-family counts into stats
+func (s *familyStats) fill(stats *Stats) {
+	stats.IPv4Connects = s.ipv4Connects.Load()
+	stats.IPv4Failures = s.ipv4Failures.Load()
+	stats.IPv6Connects = s.ipv6Connects.Load()
+	stats.IPv6Failures = s.ipv6Failures.Load()
+	stats.DialCanceled = s.canceled.Load()
+}
@@ -0,0 +1,185 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+)
+
+// FindMax configures the capacity search: readers are increased in steps
+// until a level violates the SLO, then the boundary is bisected between the
+// last good and first bad level
+type FindMax struct {
+	Start         int           // First level to test
+	Step          int           // Increment while searching upwards (doubles after each good level)
+	Max           int           // Stop searching upwards here (0 = no limit)
+	Settle        time.Duration // How long each level is measured after reaching it
+	Resolution    int           // Stop bisecting when the bounds are this close
+	MaxFailurePct float64       // SLO: failed attempts during a level, percent
+	MaxP95Connect float64       // SLO: connect p95 during a level, milliseconds (0 = not checked)
+}
+
+// probeResult is the outcome of testing one reader level
+type probeResult struct {
+	readers    int
+	failurePct float64
+	p95Connect float64
+	ok         bool
+}
+
+// validate fills defaults and checks the search parameters
+func (f *FindMax) validate() error {
+	if f.Start <= 0 {
+		f.Start = 100
+	}
+	if f.Step <= 0 {
+		f.Step = f.Start
+	}
+	if f.Settle <= 0 {
+		f.Settle = 30 * time.Second
+	}
+	if f.Resolution <= 0 {
+		f.Resolution = 1
+	}
+	if f.MaxFailurePct <= 0 && f.MaxP95Connect <= 0 {
+		return fmt.Errorf("find-max needs a failure rate or connect latency SLO")
+	}
+	return nil
+}
+
+// runFindMax searches for the largest reader count that meets the SLO
+func (r *Runner) runFindMax(ctx context.Context) error {
+	fm := *r.config.FindMax
+	if err := fm.validate(); err != nil {
+		return err
+	}
+	r.log.Info("starting capacity search", "start", fm.Start, "step", fm.Step, "max", fm.Max,
+		"settle", fm.Settle, "max_failure_pct", fm.MaxFailurePct, "max_p95_connect_ms", fm.MaxP95Connect)
+
+	pool := newReaderPool(ctx, r)
+	defer func() {
+		pool.closeAll()
+		r.log.Info("waiting for connections to close", "active", r.activeConnects.Load())
+		r.wg.Wait()
+	}()
+
+	good, bad := 0, 0 // Highest passing and lowest failing level (0 = none yet)
+	n, step := fm.Start, fm.Step
+	for ctx.Err() == nil {
+		// Retest lower levels from the last good one so that connect
+		// latency is measured on fresh connections
+		if pool.size() > n {
+			pool.resize(good, r.poolSession(), 0, 0)
+		}
+
+		result := r.probe(ctx, pool, n, fm)
+		if ctx.Err() != nil {
+			break
+		}
+		r.log.Info("capacity probe", "readers", n, "ok", result.ok,
+			"failure_pct", result.failurePct, "connect_p95_ms", result.p95Connect)
+
+		if result.ok {
+			good = n
+		} else {
+			bad = n
+		}
+
+		// Search upwards until the first failure, then bisect
+		if bad == 0 {
+			if fm.Max > 0 && n >= fm.Max {
+				break
+			}
+			n += step
+			step *= 2
+			if fm.Max > 0 && n > fm.Max {
+				n = fm.Max
+			}
+			continue
+		}
+		if bad-good <= fm.Resolution {
+			break
+		}
+		n = good + (bad-good)/2
+	}
+
+	r.capacity.Store(int64(good))
+	if bad == 0 {
+		r.log.Info("capacity search complete: no SLO violation found", "capacity", good)
+	} else {
+		r.log.Info("capacity search complete", "capacity", good, "first_failing", bad)
+	}
+	return nil
+}
+
+// poolSession returns the session settings for pool-managed readers
+func (r *Runner) poolSession() session {
+	return session{transport: r.config.Transport, managed: true}
+}
+
+// probe grows the pool to n readers, holds it for fm.Settle and checks the
+// failure rate and connect latency seen meanwhile against the SLO
+func (r *Runner) probe(ctx context.Context, pool *readerPool, n int, fm FindMax) probeResult {
+	latency := histogram.New()
+	r.levelLatency.Store(latency)
+	defer r.levelLatency.Store(nil)
+
+	connects := r.totalConnects.Load()
+	failures := r.totalFailures.Load()
+	attempts := func() int64 {
+		return r.totalConnects.Load() - connects + r.totalFailures.Load() - failures
+	}
+
+	// Every reader added for this level must finish connecting (or fail)
+	// before the level is judged, unless that takes far longer than Settle
+	added := int64(n - pool.size())
+
+	maxSpawn := 0
+	if r.config.Rate > 0 {
+		maxSpawn = int(r.config.Rate*scenarioTick.Seconds() + 0.5)
+		if maxSpawn < 1 {
+			maxSpawn = 1
+		}
+	}
+
+	ticker := time.NewTicker(scenarioTick)
+	defer ticker.Stop()
+
+	// Ramp to n, then measure for the settle window
+	var settled time.Time
+	for {
+		pool.resize(n, r.poolSession(), 0, maxSpawn)
+		if settled.IsZero() && pool.size() >= n {
+			settled = time.Now()
+		}
+		if !settled.IsZero() {
+			waited := time.Since(settled)
+			if waited >= fm.Settle && (attempts() >= added || waited >= 4*fm.Settle) {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return probeResult{readers: n}
+		case <-ticker.C:
+		}
+	}
+
+	result := probeResult{readers: n, ok: true}
+	failed := r.totalFailures.Load() - failures
+	if total := attempts(); total > 0 {
+		result.failurePct = float64(failed) * 100 / float64(total)
+	}
+	result.p95Connect = latency.Summary().P95
+
+	if fm.MaxFailurePct > 0 && result.failurePct > fm.MaxFailurePct {
+		result.ok = false
+	}
+	if fm.MaxP95Connect > 0 && result.p95Connect > fm.MaxP95Connect {
+		result.ok = false
+	}
+	return result
+}
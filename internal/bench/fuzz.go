@@ -0,0 +1,45 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"bytes"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// fuzzStats totals the MalformedRequests bad clients
+type fuzzStats struct {
+	requests     atomic.Int64
+	rejected     atomic.Int64
+	serverErrors atomic.Int64
+	dropped      atomic.Int64
+	timeouts     atomic.Int64
+}
+
+// record adds a finished fuzzer and logs its findings
+func (s *fuzzStats) record(res rtsp.FuzzResult, log *slog.Logger) {
+	s.requests.Add(int64(res.Requests))
+	s.rejected.Add(int64(res.Rejected))
+	s.serverErrors.Add(int64(res.ServerErrors))
+	s.dropped.Add(int64(res.Dropped))
+	s.timeouts.Add(int64(res.Timeouts))
+	for _, f := range res.Findings {
+		line, _, _ := bytes.Cut(f.Request, []byte("\r\n"))
+		if len(line) > 200 {
+			line = line[:200]
+		}
+		log.Warn("fuzz finding", "reason", f.Reason, "status", f.Status, "request_line", string(line),
+			"size", len(f.Request), "file", f.File)
+	}
+}
+
+// fill copies the fuzzing statistics into stats
+func (s *fuzzStats) fill(stats *Stats) {
+	stats.FuzzRequests = s.requests.Load()
+	stats.FuzzRejected = s.rejected.Load()
+	stats.FuzzServerErrors = s.serverErrors.Load()
+	stats.FuzzDropped = s.dropped.Load()
+	stats.FuzzTimeouts = s.timeouts.Load()
+}
@@ -0,0 +1,252 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// Headers naming the node that answered a response
+const (
+	HopHeaderServer = "server" // The Server header: the origin or the proxy that rewrote it
+	HopHeaderVia    = "via"    // The whole Via chain: every proxy the response passed
+)
+
+// NoNode is the node key of responses without the HopHeader
+const NoNode = "none"
+
+// NodeStats holds the responses readers got from one node
+type NodeStats struct {
+	Responses  int64   `json:"responses"`
+	Sessions   int64   `json:"sessions"`   // Sessions whose first response came from the node
+	Unexpected int64   `json:"unexpected"` // Responses failing ExpectVia or ExpectServer
+	Avg        float64 `json:"avg_ms"`
+	P50        float64 `json:"p50_ms"`
+	P95        float64 `json:"p95_ms"`
+	P99        float64 `json:"p99_ms"`
+	Max        float64 `json:"max_ms"`
+}
+
+// nodeCounters is one node's response state
+type nodeCounters struct {
+	responses  int64
+	sessions   int64
+	unexpected int64
+	latency    *histogram.Histogram
+}
+
+// hopStats checks which nodes of a proxy chain or behind a load balancer
+// answer the readers, from the Via and Server headers of their responses
+type hopStats struct {
+	header       string
+	expectVia    *regexp.Regexp // nil = any
+	expectServer *regexp.Regexp // nil = any
+	sticky       bool
+
+	unexpected atomic.Int64
+	violations atomic.Int64 // Responses from another node than the session's first
+	unsticky   atomic.Int64 // Sessions answered by more than one node
+
+	mu    sync.Mutex
+	nodes map[string]*nodeCounters
+}
+
+// newHopStats returns the node checks of config, nil if none are set
+func newHopStats(config Config) (*hopStats, error) {
+	if config.HopHeader == "" && config.ExpectVia == "" && config.ExpectServer == "" && !config.CheckSticky {
+		return nil, nil
+	}
+	h := &hopStats{header: config.HopHeader, sticky: config.CheckSticky, nodes: make(map[string]*nodeCounters)}
+	switch h.header {
+	case "":
+		h.header = HopHeaderServer
+	case HopHeaderServer, HopHeaderVia:
+	default:
+		return nil, fmt.Errorf("unknown hop header %q (want %s or %s)", config.HopHeader, HopHeaderServer, HopHeaderVia)
+	}
+	var err error
+	if config.ExpectVia != "" {
+		if h.expectVia, err = regexp.Compile(config.ExpectVia); err != nil {
+			return nil, fmt.Errorf("expected Via: %w", err)
+		}
+	}
+	if config.ExpectServer != "" {
+		if h.expectServer, err = regexp.Compile(config.ExpectServer); err != nil {
+			return nil, fmt.Errorf("expected Server: %w", err)
+		}
+	}
+	return h, nil
+}
+
+// apply reports the responses of a reader's client, logging its node
+// problems to log
+func (h *hopStats) apply(client *rtsp.Client, log *slog.Logger) {
+	if h != nil {
+		client.SetHopRecorder(&hopSession{stats: h, log: log})
+	}
+}
+
+// node returns the node a response came from
+func (h *hopStats) node(hop rtsp.Hop) string {
+	name := hop.Server
+	if h.header == HopHeaderVia {
+		name = strings.Join(hop.Via, ", ")
+	}
+	if name == "" {
+		return NoNode
+	}
+	return name
+}
+
+// expected reports whether a response passed the nodes it should have
+func (h *hopStats) expected(hop rtsp.Hop) bool {
+	if h.expectVia != nil && !h.expectVia.MatchString(strings.Join(hop.Via, ", ")) {
+		return false
+	}
+	return h.expectServer == nil || h.expectServer.MatchString(hop.Server)
+}
+
+// record counts a response from node; first marks a session's first
+func (h *hopStats) record(node string, hop rtsp.Hop, expected, first bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n, ok := h.nodes[node]
+	if !ok {
+		n = &nodeCounters{latency: histogram.New()}
+		h.nodes[node] = n
+	}
+	n.responses++
+	n.latency.Record(hop.Latency)
+	if first {
+		n.sessions++
+	}
+	if !expected {
+		n.unexpected++
+	}
+}
+
+// fill copies the node statistics into stats
+func (h *hopStats) fill(stats *Stats) {
+	if h == nil {
+		return
+	}
+	stats.UnexpectedNodeResponses = h.unexpected.Load()
+	stats.StickyViolations = h.violations.Load()
+	stats.UnstickySessions = h.unsticky.Load()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.nodes) == 0 {
+		return
+	}
+	stats.Nodes = make(map[string]NodeStats, len(h.nodes))
+	for node, n := range h.nodes {
+		latency := n.latency.Summary()
+		stats.Nodes[node] = NodeStats{
+			Responses:  n.responses,
+			Sessions:   n.sessions,
+			Unexpected: n.unexpected,
+			Avg:        latency.Mean,
+			P50:        latency.P50,
+			P95:        latency.P95,
+			P99:        latency.P99,
+			Max:        latency.Max,
+		}
+	}
+}
+
+// hopSession follows the nodes answering one reader; it implements
+// rtsp.HopRecorder
+type hopSession struct {
+	stats *hopStats
+	log   *slog.Logger
+
+	mu        sync.Mutex
+	first     string // Node of the first response since the last redirect
+	redirects int
+	warned    bool // Unexpected node logged
+	unsticky  bool
+}
+
+// RecordHop counts a response and checks it came from the nodes expected
+func (s *hopSession) RecordHop(hop rtsp.Hop) {
+	h := s.stats
+	node := h.node(hop)
+	expected := h.expected(hop)
+
+	s.mu.Lock()
+	if hop.Redirects != s.redirects {
+		s.first, s.redirects = "", hop.Redirects // Redirected to another node on purpose
+	}
+	first := s.first == ""
+	if first {
+		s.first = node
+	}
+	moved := h.sticky && node != s.first
+	logMoved := moved && !s.unsticky
+	if moved {
+		s.unsticky = true
+	}
+	logUnexpected := !expected && !s.warned
+	if !expected {
+		s.warned = true
+	}
+	from := s.first
+	s.mu.Unlock()
+
+	h.record(node, hop, expected, first)
+	if !expected {
+		h.unexpected.Add(1)
+	}
+	if moved {
+		h.violations.Add(1)
+	}
+	if logMoved {
+		h.unsticky.Add(1)
+		s.log.Warn("session answered by another node", "method", hop.Method, "first_node", from, "node", node)
+	}
+	if logUnexpected {
+		s.log.Warn("response from unexpected node", "method", hop.Method, "via", strings.Join(hop.Via, ", "), "server", hop.Server)
+	}
+}
+
+// writeNodes prints the response count, latency and problems of every
+// node, busiest first
+func writeNodes(w io.Writer, stats Stats) error {
+	nodes := make([]string, 0, len(stats.Nodes))
+	for node := range stats.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		ri, rj := stats.Nodes[nodes[i]].Responses, stats.Nodes[nodes[j]].Responses
+		if ri != rj {
+			return ri > rj
+		}
+		return nodes[i] < nodes[j]
+	})
+	for _, node := range nodes {
+		n := stats.Nodes[node]
+		line := fmt.Sprintf("  Node %s: %d responses, %d sessions | avg %.1fms | p95 %.1fms | max %.1fms",
+			node, n.Responses, n.Sessions, n.Avg, n.P95, n.Max)
+		if n.Unexpected > 0 {
+			line += fmt.Sprintf(" | %d unexpected", n.Unexpected)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	if stats.UnexpectedNodeResponses == 0 && stats.StickyViolations == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "  Unexpected node responses: %d | Sticky violations: %d responses in %d sessions\n",
+		stats.UnexpectedNodeResponses, stats.StickyViolations, stats.UnstickySessions)
+	return err
+}
@@ -0,0 +1,46 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// DefaultUserAgent is the readers' User-Agent when Config.UserAgent is
+// empty; {run} and {conn} are replaced as in Config.UserAgent
+const DefaultUserAgent = rtsp.DefaultUserAgent + " (run {run})"
+
+// BenchHeader carries "<run ID>/<connection ID>" when Config.BenchHeader
+// is set
+const BenchHeader = "X-Bench-Run"
+
+// NewRunID generates a run ID from the current time, e.g.
+// 20250314-100000-4ec55d
+func NewRunID() string {
+	var b [3]byte
+	rand.Read(b[:])
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b[:])
+}
+
+// applyIdentity sets a reader's User-Agent and, if enabled, BenchHeader,
+// so server logs can be tied to the run and the connection. A reader
+// mimicking player keeps the player's User-Agent unless Config.UserAgent
+// is set.
+func applyIdentity(client *rtsp.Client, config Config, connID string, player *rtsp.PlayerProfile) {
+	ua := config.UserAgent
+	if ua == "" {
+		if player != nil {
+			ua = player.UserAgent
+		} else {
+			ua = DefaultUserAgent
+		}
+	}
+	client.SetUserAgent(strings.NewReplacer("{run}", config.RunID, "{conn}", connID).Replace(ua))
+	if config.BenchHeader {
+		client.SetHeader(BenchHeader, config.RunID+"/"+connID)
+	}
+}
@@ -0,0 +1,141 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Viewer lifetime distributions
+const (
+	LifetimeUniform     = "uniform"     // Evenly between Min and Max
+	LifetimeFixed       = "fixed"       // Always Mean
+	LifetimeExponential = "exponential" // Memoryless: viewers leave at a constant rate
+	LifetimeLogNormal   = "lognormal"   // Most viewers near the median, some much longer
+	LifetimePareto      = "pareto"      // Long tail: many short visits, a few viewers who stay for hours
+)
+
+// Default distribution shapes
+const (
+	DefaultLifetimeSigma = 1.0
+	DefaultLifetimeAlpha = 1.5
+)
+
+// Lifetime is how long viewers stay in real-world mode. Every type but
+// uniform is set by its Mean; Min and Max then bound the samples.
+type Lifetime struct {
+	Type  string
+	Mean  time.Duration // fixed, exponential, lognormal, pareto: mean session length
+	Min   time.Duration // uniform: shortest session; others: floor (0 = none)
+	Max   time.Duration // uniform: longest session; others: cap (0 = none)
+	Sigma float64       // lognormal: standard deviation of the log duration (default 1)
+	Alpha float64       // pareto: tail index, above 1; lower means a longer tail (default 1.5)
+}
+
+// validate checks the distribution parameters and fills in default shapes
+func (l *Lifetime) validate() error {
+	if l.Sigma == 0 {
+		l.Sigma = DefaultLifetimeSigma
+	}
+	if l.Alpha == 0 {
+		l.Alpha = DefaultLifetimeAlpha
+	}
+	if l.Min < 0 || (l.Max > 0 && l.Max < l.Min) {
+		return fmt.Errorf("lifetime min must be between 0 and max")
+	}
+	switch l.Type {
+	case LifetimeUniform:
+		if l.Max <= l.Min {
+			return fmt.Errorf("uniform lifetime needs max above min")
+		}
+	case LifetimeFixed, LifetimeExponential, LifetimeLogNormal, LifetimePareto:
+		if l.Mean <= 0 {
+			return fmt.Errorf("%s lifetime needs a positive mean", l.Type)
+		}
+		if l.Type == LifetimeLogNormal && l.Sigma < 0 {
+			return fmt.Errorf("lognormal lifetime needs a positive sigma")
+		}
+		if l.Type == LifetimePareto && l.Alpha <= 1 {
+			return fmt.Errorf("pareto lifetime needs alpha above 1 for a finite mean")
+		}
+	default:
+		return fmt.Errorf("unknown lifetime distribution: %s", l.Type)
+	}
+	return nil
+}
+
+// mean returns the mean session length, ignoring Min and Max for all but
+// uniform
+func (l *Lifetime) mean() time.Duration {
+	if l.Type == LifetimeUniform {
+		return (l.Min + l.Max) / 2
+	}
+	return l.Mean
+}
+
+// scaled returns the distribution stretched to the given mean, keeping its
+// shape
+func (l Lifetime) scaled(mean time.Duration) *Lifetime {
+	k := float64(mean) / float64(l.mean())
+	l.Mean = time.Duration(float64(l.Mean) * k)
+	l.Min = time.Duration(float64(l.Min) * k)
+	l.Max = time.Duration(float64(l.Max) * k)
+	return &l
+}
+
+// sample draws a session length
+func (l *Lifetime) sample(rng *lockedRand) time.Duration {
+	mean := float64(l.Mean)
+	var d float64
+	switch l.Type {
+	case LifetimeUniform:
+		return l.Min + time.Duration(rng.Int63n(int64(l.Max-l.Min)))
+	case LifetimeFixed:
+		d = mean
+	case LifetimeExponential:
+		d = mean * rng.ExpFloat64()
+	case LifetimeLogNormal:
+		mu := math.Log(mean) - l.Sigma*l.Sigma/2
+		d = math.Exp(mu + l.Sigma*rng.NormFloat64())
+	case LifetimePareto:
+		scale := mean * (l.Alpha - 1) / l.Alpha
+		d = scale / math.Pow(1-rng.Float64(), 1/l.Alpha)
+	}
+
+	if l.Max > 0 && d > float64(l.Max) {
+		return l.Max
+	}
+	if d < float64(l.Min) {
+		return l.Min
+	}
+	return time.Duration(d)
+}
+
+// newLifetime returns the viewer lifetime model of config: Config.Lifetime,
+// or uniform between 30s and Config.Duration (5m if shorter), with its
+// mean set by Config.ChurnRate when that is given
+func newLifetime(config Config) (*Lifetime, error) {
+	var l *Lifetime
+	if config.Lifetime != nil {
+		copied := *config.Lifetime
+		l = &copied
+	} else {
+		l = &Lifetime{Type: LifetimeUniform, Min: 30 * time.Second, Max: config.Duration}
+		if l.Max <= l.Min {
+			l.Max = 5 * time.Minute
+		}
+	}
+	if err := l.validate(); err != nil {
+		return nil, err
+	}
+	if config.ChurnRate < 0 {
+		return nil, fmt.Errorf("churn rate must not be negative")
+	}
+	if config.ChurnRate > 0 {
+		// Viewers leave at the churn rate when they stay 1/rate minutes on
+		// average, as the simulator replaces each one that leaves
+		l = l.scaled(time.Duration(float64(time.Minute) / config.ChurnRate))
+	}
+	return l, nil
+}
@@ -0,0 +1,138 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadAdjustInterval is how often real-world mode changes its target load
+// at normal speed
+const loadAdjustInterval = 10 * time.Second
+
+// builtinDailyCurve is the real-world load factor for each hour of the day
+var builtinDailyCurve = []float64{
+	0.6, 0.6, 0.6, 0.6, 0.6, 0.6, // Night low
+	0.8, 0.8, 0.8,
+	1.2, 1.2, 1.2, // Morning peak
+	0.9, 0.9, // Lunch dip
+	1.1, 1.1, 1.1, 1.1, // Afternoon steady
+	1.3, 1.3, 1.3, 1.3, 1.3, // Evening peak
+	0.6,
+}
+
+// simClock is the simulated time of real-world mode: it starts at the wall
+// clock and runs Config.TimeCompression times faster
+type simClock struct {
+	start       time.Time
+	compression float64
+}
+
+// newSimClock starts a simulated clock now
+func newSimClock(compression float64) simClock {
+	if compression <= 0 {
+		compression = 1
+	}
+	return simClock{start: time.Now(), compression: compression}
+}
+
+// elapsed returns the simulated time since the start
+func (c simClock) elapsed() time.Duration {
+	return time.Duration(float64(time.Since(c.start)) * c.compression)
+}
+
+// now returns the simulated time of day
+func (c simClock) now() time.Time {
+	return c.start.Add(c.elapsed())
+}
+
+// tick returns how often to adjust the load, so the simulated time between
+// adjustments stays the same, but at most once a second
+func (c simClock) tick() time.Duration {
+	d := time.Duration(float64(loadAdjustInterval) / c.compression)
+	if d < time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+// curveFactor returns the load factor of curve at time of day t. The
+// points are spread evenly from midnight, each holding for its share of
+// the day.
+func curveFactor(curve []float64, t time.Time) float64 {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	day := float64(t.Sub(midnight)) / float64(24*time.Hour)
+	i := int(day * float64(len(curve)))
+	if i >= len(curve) {
+		i = len(curve) - 1
+	}
+	return curve[i]
+}
+
+// validateLoadPattern checks the real-world load pattern settings
+func validateLoadPattern(config Config) error {
+	if config.TimeCompression < 0 {
+		return fmt.Errorf("time compression must not be negative")
+	}
+	for _, f := range config.DailyCurve {
+		if f < 0 {
+			return fmt.Errorf("daily curve factors must not be negative")
+		}
+	}
+	for _, n := range config.TargetCurve {
+		if n < 0 {
+			return fmt.Errorf("target curve connections must not be negative")
+		}
+	}
+	return nil
+}
+
+// LoadTargetCSV reads a real-world target curve: target connections per
+// minute, one row each, in the last column ("minute,connections" or just
+// "connections"). A header row and lines starting with # are ignored.
+func LoadTargetCSV(path string) ([]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var targets []int
+	for first := true; ; first = false {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := strings.TrimSpace(record[len(record)-1])
+		line, _ := r.FieldPos(len(record) - 1)
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			if first {
+				continue // Header
+			}
+			return nil, fmt.Errorf("%s:%d: invalid connection count %q", path, line, field)
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("%s:%d: negative connection count %d", path, line, n)
+		}
+		targets = append(targets, n)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%s: no targets found", path)
+	}
+	return targets, nil
+}
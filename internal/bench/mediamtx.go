@@ -0,0 +1,329 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MediaMTX cross-check settings
+const (
+	mtxSettle    = 5 * time.Second        // Readers younger than this may not have a server session yet
+	mtxGhostWait = 3 * time.Second        // Time the server gets to close sessions after the run
+	mtxPageSize  = 1000                   // Items per API page
+	mtxTimeout   = 10 * time.Second       // Per poll, all pages
+	mtxPollDelay = 500 * time.Millisecond // Between ghost checks
+)
+
+// MediaMTX control API list responses
+type (
+	mtxList[T any] struct {
+		PageCount int `json:"pageCount"`
+		Items     []T `json:"items"`
+	}
+	mtxSession struct {
+		ID         string `json:"id"`
+		RemoteAddr string `json:"remoteAddr"`
+		State      string `json:"state"` // idle, read or publish
+		Path       string `json:"path"`
+		BytesSent  uint64 `json:"bytesSent"`
+	}
+	mtxPath struct {
+		Name    string            `json:"name"`
+		Readers []json.RawMessage `json:"readers"`
+	}
+)
+
+// mtxStats is what the checker reports
+type mtxStats struct {
+	sessions    int64
+	unmatched   int64
+	missing     int64
+	bytesGapPct float64
+	pathReaders int64
+	ghosts      int64
+	polls       int64
+	errors      int64
+}
+
+// mtxChecker polls the MediaMTX control API during the run and compares
+// the sessions the server reports with the readers' own view: sessions no
+// reader owns, readers the server has no session for, bytes the server
+// sent against bytes received, and sessions left after the run
+type mtxChecker struct {
+	base      string
+	endpoints []string // Session list paths
+	interval  time.Duration
+	client    *http.Client
+	log       *slog.Logger
+	rates     *connRates // The live readers; set by Run
+
+	mu       sync.Mutex
+	stats    mtxStats
+	failing  bool
+	reported [2]int64 // Unmatched and missing last logged
+}
+
+// newMTXChecker returns the checker of Config.MediaMTXAPI, or nil if unset
+func newMTXChecker(config Config, log *slog.Logger) (*mtxChecker, error) {
+	if config.MediaMTXAPI == "" {
+		return nil, nil
+	}
+	base, err := url.Parse(strings.TrimRight(config.MediaMTXAPI, "/"))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("invalid MediaMTX API URL %q", config.MediaMTXAPI)
+	}
+	endpoints := []string{"/v3/rtspsessions/list"}
+	for _, target := range reportTargets(config) {
+		if strings.HasPrefix(target, "rtsps://") {
+			endpoints = append(endpoints, "/v3/rtspssessions/list")
+			break
+		}
+	}
+	interval := config.StatsInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &mtxChecker{
+		base:      base.String(),
+		endpoints: endpoints,
+		interval:  interval,
+		client:    &http.Client{Timeout: mtxTimeout},
+		log:       log,
+	}, nil
+}
+
+// run polls every StatsInterval until ctx is done. A nil checker returns
+// at once.
+func (m *mtxChecker) run(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.log.Info("cross-checking MediaMTX sessions", "api", redactURL(m.base))
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.poll(ctx)
+		}
+	}
+}
+
+// poll compares the server's sessions with the live readers once
+func (m *mtxChecker) poll(parent context.Context) {
+	ctx, cancel := context.WithTimeout(parent, mtxTimeout)
+	defer cancel()
+	sessions, err := m.sessions(ctx)
+	var paths []mtxPath
+	if err == nil {
+		paths, err = mtxListAll[mtxPath](ctx, m, "/v3/paths/list")
+	}
+	if parent.Err() != nil {
+		return
+	}
+	readers := m.rates.readers()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.polls++
+	if !m.pollOK(err) {
+		return
+	}
+	byAddr := make(map[string]liveReader, len(readers))
+	for _, r := range readers {
+		if r.addr == "" {
+			continue
+		}
+		byAddr[r.addr] = r
+	}
+
+	var ours, unmatched, missing int64
+	var sent, received float64
+	matched := make(map[string]bool)
+	for _, s := range m.ours(sessions) {
+		ours++
+		r, ok := byAddr[s.RemoteAddr]
+		if !ok {
+			unmatched++
+			continue
+		}
+		matched[s.RemoteAddr] = true
+		sent += float64(s.BytesSent)
+		received += float64(r.bytes)
+	}
+	for addr, r := range byAddr {
+		if r.age >= mtxSettle && !matched[addr] {
+			missing++
+		}
+	}
+	var pathReaders int64
+	for _, p := range paths {
+		pathReaders += int64(len(p.Readers))
+	}
+
+	m.stats.sessions, m.stats.unmatched, m.stats.missing = ours, unmatched, missing
+	m.stats.pathReaders = pathReaders
+	m.stats.bytesGapPct = 0
+	if sent > 0 {
+		m.stats.bytesGapPct = (sent - received) * 100 / sent
+	}
+	if (unmatched > 0 || missing > 0) && [2]int64{unmatched, missing} != m.reported {
+		m.log.Warn("server sessions disagree with readers", "server_sessions", ours, "readers", len(byAddr),
+			"unmatched", unmatched, "missing", missing)
+	}
+	m.reported = [2]int64{unmatched, missing}
+}
+
+// pollOK counts and logs a poll error and reports whether there was
+// none; the caller must hold m.mu
+func (m *mtxChecker) pollOK(err error) bool {
+	switch {
+	case err != nil && !m.failing:
+		m.log.Warn("MediaMTX API poll failed", "error", err)
+	case err == nil && m.failing:
+		m.log.Info("MediaMTX API poll recovered")
+	}
+	m.failing = err != nil
+	if err != nil {
+		m.stats.errors++
+	}
+	return err == nil
+}
+
+// finish looks for sessions from the readers' addresses that the server
+// still holds after every reader closed, giving it mtxGhostWait to clean
+// up. A nil checker does nothing.
+func (m *mtxChecker) finish() {
+	if m == nil {
+		return
+	}
+	deadline := time.Now().Add(mtxGhostWait)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), mtxTimeout)
+		sessions, err := m.sessions(ctx)
+		cancel()
+
+		m.mu.Lock()
+		if !m.pollOK(err) {
+			m.mu.Unlock()
+			return
+		}
+		ghosts := m.ours(sessions)
+		if len(ghosts) == 0 || time.Now().After(deadline) {
+			m.stats.ghosts = int64(len(ghosts))
+			m.mu.Unlock()
+			if len(ghosts) > 0 {
+				m.log.Warn("ghost sessions left on server", "count", len(ghosts), "sessions", describeSessions(ghosts, 5))
+			}
+			return
+		}
+		m.mu.Unlock()
+		time.Sleep(mtxPollDelay)
+	}
+}
+
+// fill copies the cross-check results into stats. A nil checker leaves
+// them zero.
+func (m *mtxChecker) fill(stats *Stats) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats.ServerSessions = m.stats.sessions
+	stats.ServerUnmatched = m.stats.unmatched
+	stats.ServerMissing = m.stats.missing
+	stats.ServerBytesGapPct = m.stats.bytesGapPct
+	stats.ServerPathReaders = m.stats.pathReaders
+	stats.GhostSessions = m.stats.ghosts
+	stats.ServerPolls = m.stats.polls
+	stats.ServerPollErrors = m.stats.errors
+}
+
+// ours returns the sessions from addresses readers connected from, except
+// publishers
+func (m *mtxChecker) ours(sessions []mtxSession) []mtxSession {
+	var list []mtxSession
+	for _, s := range sessions {
+		if s.State != "publish" && m.rates.sourceIP(hostOf(s.RemoteAddr)) {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// sessions lists the RTSP (and RTSPS) sessions
+func (m *mtxChecker) sessions(ctx context.Context) ([]mtxSession, error) {
+	var all []mtxSession
+	for _, endpoint := range m.endpoints {
+		list, err := mtxListAll[mtxSession](ctx, m, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, list...)
+	}
+	return all, nil
+}
+
+// mtxListAll fetches every page of a list endpoint
+func mtxListAll[T any](ctx context.Context, m *mtxChecker, endpoint string) ([]T, error) {
+	var all []T
+	for page := 0; ; page++ {
+		u := fmt.Sprintf("%s%s?itemsPerPage=%d&page=%d", m.base, endpoint, mtxPageSize, page)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := m.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var list mtxList[T]
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s: %s", endpoint, resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", endpoint, err)
+		}
+		all = append(all, list.Items...)
+		if page+1 >= list.PageCount {
+			return all, nil
+		}
+	}
+}
+
+// hostOf returns the IP of a host:port address
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// describeSessions lists up to n sessions as "addr state path"
+func describeSessions(sessions []mtxSession, n int) string {
+	var parts []string
+	for i, s := range sessions {
+		if i == n {
+			parts = append(parts, fmt.Sprintf("and %d more", len(sessions)-n))
+			break
+		}
+		parts = append(parts, s.RemoteAddr+" "+s.State+" "+s.Path)
+	}
+	return strings.Join(parts, ", ")
+}
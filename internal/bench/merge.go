@@ -0,0 +1,198 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"reflect"
+	"strings"
+)
+
+// MergeStats combines statistics from several independent runners (e.g.
+// distributed agents) into one report. Counters, rates and totals are
+// summed, minimums, maximums and per-runner peaks are taken across inputs,
+// and averages and percentiles are averaged weighted by connects - an
+// approximation, since the underlying histograms are not merged.
+func MergeStats(all []Stats) Stats {
+	var merged Stats
+	mergeInto(reflect.ValueOf(&merged).Elem(), valuesOf(all))
+	return merged
+}
+
+// valuesOf converts a slice of Stats to reflect values
+func valuesOf(all []Stats) []reflect.Value {
+	values := make([]reflect.Value, len(all))
+	for i := range all {
+		values[i] = reflect.ValueOf(all[i])
+	}
+	return values
+}
+
+// mergeInto merges the struct values in inputs field by field into dst
+func mergeInto(dst reflect.Value, inputs []reflect.Value) {
+	t := dst.Type()
+
+	// Weight for averages: the struct's "connects" field
+	weights := make([]float64, len(inputs))
+	var totalWeight float64
+	for i, in := range inputs {
+		if idx := fieldByJSONName(t, "connects"); idx >= 0 {
+			weights[i] = float64(in.Field(idx).Int())
+		}
+		totalWeight += weights[i]
+	}
+
+	for f := 0; f < t.NumField(); f++ {
+		name := strings.Split(t.Field(f).Tag.Get("json"), ",")[0]
+		out := dst.Field(f)
+
+		switch out.Kind() {
+		case reflect.Int, reflect.Int64:
+			var sum, peak int64
+			for _, in := range inputs {
+				v := in.Field(f).Int()
+				sum += v
+				peak = max(peak, v)
+			}
+			if isPeak(name) {
+				out.SetInt(peak)
+			} else {
+				out.SetInt(sum)
+			}
+
+		case reflect.Uint64:
+			var sum, peak uint64
+			for _, in := range inputs {
+				v := in.Field(f).Uint()
+				sum += v
+				peak = max(peak, v)
+			}
+			if isPeak(name) {
+				out.SetUint(peak)
+			} else {
+				out.SetUint(sum)
+			}
+
+		case reflect.Float64:
+			out.SetFloat(mergeFloat(name, f, inputs, weights, totalWeight))
+
+		case reflect.Map:
+			mergeMap(out, f, inputs)
+		}
+	}
+}
+
+// isPeak reports whether an integer field is a per-runner maximum, such
+// as shared_udp_fanout_max or self_goroutines_peak, taken across inputs
+// rather than summed. peak_active is summed: the agents' readers all load
+// the same servers.
+func isPeak(name string) bool {
+	if name == "peak_active" {
+		return false
+	}
+	for _, word := range strings.Split(name, "_") {
+		if word == "max" || word == "peak" {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeFloat combines one float field according to its name
+func mergeFloat(name string, f int, inputs []reflect.Value, weights []float64, totalWeight float64) float64 {
+	switch {
+	case strings.HasSuffix(name, "_time_sec"):
+		// Totals, such as stall_time_sec
+		var sum float64
+		for _, in := range inputs {
+			sum += in.Field(f).Float()
+		}
+		return sum
+	case strings.Contains(name, "min"):
+		// Smallest non-zero value (zero means no samples)
+		var min float64
+		for _, in := range inputs {
+			if v := in.Field(f).Float(); v > 0 && (min == 0 || v < min) {
+				min = v
+			}
+		}
+		return min
+	case strings.Contains(name, "max"):
+		var max float64
+		for _, in := range inputs {
+			if v := in.Field(f).Float(); v > max {
+				max = v
+			}
+		}
+		return max
+	case !strings.HasPrefix(name, "conn_") && (strings.Contains(name, "bitrate") || strings.Contains(name, "mbps")):
+		// Aggregate throughput; per-connection percentiles are averaged
+		var sum float64
+		for _, in := range inputs {
+			sum += in.Field(f).Float()
+		}
+		return sum
+	default:
+		// Averages and percentiles
+		if totalWeight == 0 {
+			var sum float64
+			for _, in := range inputs {
+				sum += in.Field(f).Float()
+			}
+			return sum / float64(len(inputs))
+		}
+		var sum float64
+		for i, in := range inputs {
+			sum += in.Field(f).Float() * weights[i]
+		}
+		return sum / totalWeight
+	}
+}
+
+// mergeMap merges map fields: numeric values are summed, struct values are
+// merged recursively per key
+func mergeMap(out reflect.Value, f int, inputs []reflect.Value) {
+	mapType := out.Type()
+	keys := make(map[interface{}]reflect.Value)
+	for _, in := range inputs {
+		iter := in.Field(f).MapRange()
+		for iter.Next() {
+			keys[iter.Key().Interface()] = iter.Key()
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	merged := reflect.MakeMapWithSize(mapType, len(keys))
+	for _, key := range keys {
+		var values []reflect.Value
+		for _, in := range inputs {
+			if v := in.Field(f).MapIndex(key); v.IsValid() {
+				values = append(values, v)
+			}
+		}
+
+		elem := reflect.New(mapType.Elem()).Elem()
+		switch elem.Kind() {
+		case reflect.Int, reflect.Int64:
+			var sum int64
+			for _, v := range values {
+				sum += v.Int()
+			}
+			elem.SetInt(sum)
+		case reflect.Struct:
+			mergeInto(elem, values)
+		}
+		merged.SetMapIndex(key, elem)
+	}
+	out.Set(merged)
+}
+
+// fieldByJSONName returns the index of the field with the given json name
+func fieldByJSONName(t reflect.Type, name string) int {
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("json"), ",")[0] == name {
+			return i
+		}
+	}
+	return -1
+}
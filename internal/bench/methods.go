@@ -0,0 +1,204 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// NoResponse is the status key of requests that got no response
+const NoResponse = "none"
+
+// methodOrder is the order methods are reported in; others follow sorted
+var methodOrder = []string{"OPTIONS", "DESCRIBE", "SETUP", "PLAY", rtsp.MethodKeepAlive, "PAUSE", "TEARDOWN"}
+
+// MethodStats holds the responses to one request method: status code
+// histogram and round-trip latency of the answered requests
+type MethodStats struct {
+	Requests int64            `json:"requests"`
+	Codes    map[string]int64 `json:"codes"` // Responses by status code; NoResponse for unanswered requests
+	Avg      float64          `json:"avg_ms"`
+	P50      float64          `json:"p50_ms"`
+	P95      float64          `json:"p95_ms"`
+	P99      float64          `json:"p99_ms"`
+	Max      float64          `json:"max_ms"`
+}
+
+// methodCounters is one method's response state
+type methodCounters struct {
+	codes   map[int]int64
+	latency *histogram.Histogram
+}
+
+// methodStats collects MethodStats across all readers; it implements
+// rtsp.ResponseRecorder
+type methodStats struct {
+	mu      sync.Mutex
+	methods map[string]*methodCounters
+}
+
+// RecordResponse counts one request of method answered with code (0 = no
+// response) after latency
+func (s *methodStats) RecordResponse(method string, code int, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.methods == nil {
+		s.methods = make(map[string]*methodCounters)
+	}
+	m, ok := s.methods[method]
+	if !ok {
+		m = &methodCounters{codes: make(map[int]int64), latency: histogram.New()}
+		s.methods[method] = m
+	}
+	m.codes[code]++
+	if code > 0 {
+		m.latency.Record(latency)
+	}
+}
+
+// fill copies the per-method statistics into stats
+func (s *methodStats) fill(stats *Stats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.methods) == 0 {
+		return
+	}
+	stats.Methods = make(map[string]MethodStats, len(s.methods))
+	for method, m := range s.methods {
+		latency := m.latency.Summary()
+		ms := MethodStats{
+			Codes: make(map[string]int64, len(m.codes)),
+			Avg:   latency.Mean,
+			P50:   latency.P50,
+			P95:   latency.P95,
+			P99:   latency.P99,
+			Max:   latency.Max,
+		}
+		for code, n := range m.codes {
+			ms.Requests += n
+			ms.Codes[statusKey(code)] = n
+		}
+		stats.Methods[method] = ms
+	}
+}
+
+// statusKey returns the histogram key of a status code
+func statusKey(code int) string {
+	if code == 0 {
+		return NoResponse
+	}
+	return strconv.Itoa(code)
+}
+
+// unexpectedStatus reports whether a histogram key is a redirect, an error
+// or a missing response
+func unexpectedStatus(key string) bool {
+	code, err := strconv.Atoi(key)
+	return err != nil || code >= 300
+}
+
+// methodRank returns the position of method in report order
+func methodRank(method string) int {
+	for i, m := range methodOrder {
+		if m == method {
+			return i
+		}
+	}
+	return len(methodOrder)
+}
+
+// sortedMethods returns the methods of a histogram in report order
+func sortedMethods(methods map[string]MethodStats) []string {
+	names := make([]string, 0, len(methods))
+	for m := range methods {
+		names = append(names, m)
+	}
+	sortByMethod(names, func(name string) string { return name })
+	return names
+}
+
+// sortByMethod sorts keys in report order of the method method(key) returns,
+// then by key
+func sortByMethod(keys []string, method func(string) string) {
+	sort.Slice(keys, func(i, j int) bool {
+		ri, rj := methodRank(method(keys[i])), methodRank(method(keys[j]))
+		if ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+// statusTrend follows the histograms from sample to sample
+type statusTrend struct {
+	prev map[string]MethodStats
+}
+
+// next returns the unexpected responses received since the previous
+// sample, keyed "METHOD code", or nil if there were none
+func (t *statusTrend) next(current map[string]MethodStats) map[string]int64 {
+	var delta map[string]int64
+	for method, m := range current {
+		for code, n := range m.Codes {
+			if !unexpectedStatus(code) {
+				continue
+			}
+			if d := n - t.prev[method].Codes[code]; d > 0 {
+				if delta == nil {
+					delta = make(map[string]int64)
+				}
+				delta[method+" "+code] = d
+			}
+		}
+	}
+	t.prev = current
+	return delta
+}
+
+// formatTrend renders next's result as "DESCRIBE 503 +12, keepalive none +1"
+// in report order
+func formatTrend(delta map[string]int64) string {
+	keys := make([]string, 0, len(delta))
+	for key := range delta {
+		keys = append(keys, key)
+	}
+	sortByMethod(keys, func(key string) string { return strings.SplitN(key, " ", 2)[0] })
+	
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s +%d", key, delta[key])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeMethods prints the histogram of every method on one line, then a
+// latency line per answered method
+func writeMethods(w io.Writer, methods map[string]MethodStats) error {
+	parts := make([]string, 0, len(methods))
+	for _, method := range sortedMethods(methods) {
+		parts = append(parts, method+" "+strings.Trim(formatCounts(methods[method].Codes), "()"))
+	}
+	if _, err := fmt.Fprintf(w, "  Statuses: %s\n", strings.Join(parts, " | ")); err != nil {
+		return err
+	}
+	
+	for _, method := range sortedMethods(methods) {
+		m := methods[method]
+		if m.Requests == m.Codes[NoResponse] {
+			continue // Never answered
+		}
+		if _, err := fmt.Fprintf(w, "  %-12s avg %.1fms | p50 %.1fms | p95 %.1fms | p99 %.1fms | max %.1fms\n",
+			method, m.Avg, m.P50, m.P95, m.P99, m.Max); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,411 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MonitorProcCommand prints the /proc files a Config.MonitorCommand must
+// return; prefix it with ssh to read them from the target host
+const MonitorProcCommand = "cat /proc/stat /proc/meminfo /proc/net/sockstat /proc/net/dev"
+
+// monitorTimeout bounds a single scrape
+const monitorTimeout = 5 * time.Second
+
+// hostSample is one scrape of the target host. CPU and network counters
+// are cumulative; the rates come from two samples.
+type hostSample struct {
+	at       time.Time
+	cpuBusy  float64 // Seconds or jiffies, all cores
+	cpuTotal float64
+	memTotal float64 // Bytes
+	memAvail float64
+	sockets  float64 // TCP sockets in use, or the server's RTSP connections
+	rxBytes  float64 // All interfaces but loopback
+	txBytes  float64
+
+	hasCPU, hasMem, hasSockets, hasNet bool
+}
+
+// merge fills the groups s lacks from o, so a node_exporter and a MediaMTX
+// source can complement each other
+func (s *hostSample) merge(o hostSample) {
+	if !s.hasCPU && o.hasCPU {
+		s.cpuBusy, s.cpuTotal, s.hasCPU = o.cpuBusy, o.cpuTotal, true
+	}
+	if !s.hasMem && o.hasMem {
+		s.memTotal, s.memAvail, s.hasMem = o.memTotal, o.memAvail, true
+	}
+	if !s.hasSockets && o.hasSockets {
+		s.sockets, s.hasSockets = o.sockets, true
+	}
+	if !s.hasNet && o.hasNet {
+		s.rxBytes, s.txBytes, s.hasNet = o.rxBytes, o.txBytes, true
+	}
+}
+
+// hostStats is what the monitor reports, derived from the last two samples
+type hostStats struct {
+	cpuPct    float64
+	memPct    float64
+	memUsedMB float64
+	sockets   int64
+	rxMbps    float64
+	txMbps    float64
+	scrapes   int64
+	errors    int64
+}
+
+// HostMonitor scrapes the target host's CPU, memory, sockets and network
+// throughput every StatsInterval, from node_exporter or MediaMTX metrics
+// (Config.MonitorURLs) or the output of Config.MonitorCommand
+type HostMonitor struct {
+	urls     []string
+	command  string
+	interval time.Duration
+	client   *http.Client
+	log      *slog.Logger
+
+	mu      sync.Mutex
+	prev    hostSample
+	stats   hostStats
+	failing bool
+}
+
+// NewHostMonitor returns the monitor of config, or nil if no source is set
+func NewHostMonitor(config Config, log *slog.Logger) *HostMonitor {
+	if len(config.MonitorURLs) == 0 && config.MonitorCommand == "" {
+		return nil
+	}
+	interval := config.StatsInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &HostMonitor{
+		urls:     config.MonitorURLs,
+		command:  config.MonitorCommand,
+		interval: interval,
+		client:   &http.Client{Timeout: monitorTimeout},
+		log:      log,
+	}
+}
+
+// Run scrapes until ctx is done. A nil monitor returns at once.
+func (m *HostMonitor) Run(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.log.Info("monitoring target host", "urls", len(m.urls), "command", m.command != "")
+	m.scrape(ctx)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.scrape(ctx)
+		}
+	}
+}
+
+// scrape reads every source once and updates the stats
+func (m *HostMonitor) scrape(parent context.Context) {
+	ctx, cancel := context.WithTimeout(parent, monitorTimeout)
+	defer cancel()
+
+	sample := hostSample{at: time.Now()}
+	var errs []error
+	for _, u := range m.urls {
+		s, err := m.scrapeURL(ctx, u)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", redactURL(u), err))
+			continue
+		}
+		sample.merge(s)
+	}
+	if m.command != "" {
+		s, err := m.scrapeCommand(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("command: %w", err))
+		} else {
+			sample.merge(s)
+		}
+	}
+	if parent.Err() != nil {
+		// Stopping, not a scrape failure
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.scrapes++
+	switch {
+	case len(errs) > 0 && !m.failing:
+		m.log.Warn("target host scrape failed", "error", errs[0])
+	case len(errs) == 0 && m.failing:
+		m.log.Info("target host scrape recovered")
+	}
+	m.failing = len(errs) > 0
+	if m.failing {
+		m.stats.errors++
+	}
+	m.update(sample)
+}
+
+// update derives the stats from sample and the previous one; the caller
+// must hold m.mu
+func (m *HostMonitor) update(sample hostSample) {
+	prev := m.prev
+	if sample.hasCPU && prev.hasCPU {
+		if total := sample.cpuTotal - prev.cpuTotal; total > 0 {
+			m.stats.cpuPct = math.Max(sample.cpuBusy-prev.cpuBusy, 0) * 100 / total
+		}
+	}
+	if sample.hasMem && sample.memTotal > 0 {
+		used := sample.memTotal - sample.memAvail
+		m.stats.memPct = used * 100 / sample.memTotal
+		m.stats.memUsedMB = used / (1 << 20)
+	}
+	if sample.hasSockets {
+		m.stats.sockets = int64(sample.sockets)
+	}
+	if sample.hasNet && prev.hasNet {
+		if secs := sample.at.Sub(prev.at).Seconds(); secs > 0 {
+			// Counters go backwards when interfaces or connections go away
+			m.stats.rxMbps = math.Max(sample.rxBytes-prev.rxBytes, 0) * 8 / secs / 1e6
+			m.stats.txMbps = math.Max(sample.txBytes-prev.txBytes, 0) * 8 / secs / 1e6
+		}
+	}
+	m.prev = sample
+}
+
+// Fill copies the latest host figures into stats. A nil monitor leaves
+// them zero.
+func (m *HostMonitor) Fill(stats *Stats) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats.HostCPUPct = m.stats.cpuPct
+	stats.HostMemPct = m.stats.memPct
+	stats.HostMemUsedMB = m.stats.memUsedMB
+	stats.HostSockets = m.stats.sockets
+	stats.HostRxMbps = m.stats.rxMbps
+	stats.HostTxMbps = m.stats.txMbps
+	stats.HostScrapes = m.stats.scrapes
+	stats.HostScrapeErrors = m.stats.errors
+}
+
+// scrapeURL reads a Prometheus text exposition
+func (m *HostMonitor) scrapeURL(ctx context.Context, u string) (hostSample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return hostSample{}, err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return hostSample{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return hostSample{}, fmt.Errorf("%s", resp.Status)
+	}
+	return parsePrometheus(resp.Body)
+}
+
+// scrapeCommand runs Config.MonitorCommand and reads /proc files from its
+// output
+func (m *HostMonitor) scrapeCommand(ctx context.Context) (hostSample, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", m.command)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return hostSample{}, fmt.Errorf("%w: %s", err, msg)
+		}
+		return hostSample{}, err
+	}
+	return parseProc(bytes.NewReader(out))
+}
+
+// parsePrometheus picks the host figures from node_exporter metrics, or
+// the connection and path counters of MediaMTX
+func parsePrometheus(r io.Reader) (hostSample, error) {
+	var s hostSample
+	var mtxRx, mtxTx, mtxConns float64
+	var hasMtxNet, hasMtxConns bool
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		name, labels, value, ok := parsePromLine(line)
+		if !ok {
+			continue
+		}
+		switch name {
+		case "node_cpu_seconds_total":
+			s.cpuTotal += value
+			if mode := promLabel(labels, "mode"); mode != "idle" && mode != "iowait" {
+				s.cpuBusy += value
+			}
+			s.hasCPU = true
+		case "node_memory_MemTotal_bytes":
+			s.memTotal, s.hasMem = value, true
+		case "node_memory_MemAvailable_bytes":
+			s.memAvail = value
+		case "node_sockstat_TCP_inuse":
+			s.sockets, s.hasSockets = value, true
+		case "node_network_receive_bytes_total", "node_network_transmit_bytes_total":
+			if promLabel(labels, "device") == "lo" {
+				continue
+			}
+			if name == "node_network_receive_bytes_total" {
+				s.rxBytes += value
+			} else {
+				s.txBytes += value
+			}
+			s.hasNet = true
+		case "rtsp_conns", "rtsps_conns":
+			mtxConns += value
+			hasMtxConns = true
+		case "paths_bytes_received":
+			mtxRx += value
+			hasMtxNet = true
+		case "paths_bytes_sent":
+			mtxTx += value
+			hasMtxNet = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return hostSample{}, err
+	}
+	if !s.hasSockets && hasMtxConns {
+		s.sockets, s.hasSockets = mtxConns, true
+	}
+	if !s.hasNet && hasMtxNet {
+		s.rxBytes, s.txBytes, s.hasNet = mtxRx, mtxTx, true
+	}
+	if !s.hasCPU && !s.hasMem && !s.hasSockets && !s.hasNet {
+		return hostSample{}, fmt.Errorf("no node_exporter or MediaMTX metrics found")
+	}
+	return s, nil
+}
+
+// parsePromLine splits `name{labels} value [timestamp]`
+func parsePromLine(line string) (name, labels string, value float64, ok bool) {
+	rest := line
+	if i := strings.IndexByte(line, '{'); i >= 0 {
+		j := strings.LastIndexByte(line, '}')
+		if j < i {
+			return "", "", 0, false
+		}
+		name, labels, rest = line[:i], line[i+1:j], line[j+1:]
+	} else {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return "", "", 0, false
+		}
+		name, rest = line[:i], line[i:]
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", "", 0, false
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", "", 0, false
+	}
+	return name, labels, v, true
+}
+
+// promLabel returns the value of key in a label list
+func promLabel(labels, key string) string {
+	for _, pair := range strings.Split(labels, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && k == key {
+			return strings.Trim(v, `"`)
+		}
+	}
+	return ""
+}
+
+// parseProc reads the concatenated output of MonitorProcCommand
+func parseProc(r io.Reader) (hostSample, error) {
+	var s hostSample
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case fields[0] == "cpu" && len(fields) >= 5:
+			// user nice system idle iowait irq softirq steal; guest time is
+			// already in user
+			for i := 1; i < len(fields) && i <= 8; i++ {
+				v, _ := strconv.ParseFloat(fields[i], 64)
+				s.cpuTotal += v
+				if i != 4 && i != 5 {
+					s.cpuBusy += v
+				}
+			}
+			s.hasCPU = true
+		case fields[0] == "MemTotal:" && len(fields) >= 2:
+			v, _ := strconv.ParseFloat(fields[1], 64)
+			s.memTotal, s.hasMem = v*1024, true
+		case fields[0] == "MemAvailable:" && len(fields) >= 2:
+			v, _ := strconv.ParseFloat(fields[1], 64)
+			s.memAvail = v * 1024
+		case fields[0] == "TCP:" && len(fields) >= 3 && fields[1] == "inuse":
+			v, _ := strconv.ParseFloat(fields[2], 64)
+			s.sockets, s.hasSockets = v, true
+		default:
+			// /proc/net/dev: "iface: rx_bytes 7 more rx fields tx_bytes ..."
+			iface, counters, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			c := strings.Fields(counters)
+			if len(c) != 16 {
+				continue
+			}
+			if strings.TrimSpace(iface) == "lo" {
+				continue
+			}
+			rx, err1 := strconv.ParseFloat(c[0], 64)
+			tx, err2 := strconv.ParseFloat(c[8], 64)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			s.rxBytes += rx
+			s.txBytes += tx
+			s.hasNet = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return hostSample{}, err
+	}
+	if !s.hasCPU && !s.hasMem && !s.hasSockets && !s.hasNet {
+		return hostSample{}, fmt.Errorf("no /proc data in command output")
+	}
+	return s, nil
+}
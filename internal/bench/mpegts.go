@@ -0,0 +1,29 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+)
+
+// fillMPEGTSStats copies the MPEG-TS continuity and PCR statistics into
+// stats
+func fillMPEGTSStats(stats *Stats, snapshot rtp.Snapshot) {
+	stats.MPEGTSPackets = snapshot.MPEGTSPackets
+	stats.MPEGTSSyncErrors = snapshot.MPEGTSSyncErrors
+	stats.MPEGTSCCErrors = snapshot.MPEGTSCCErrors
+	stats.MPEGTSErrorSessions = snapshot.MPEGTSErrorSessions
+	stats.PCRJitterAvg = snapshot.PCRJitterAvg
+	stats.PCRJitterMax = snapshot.PCRJitterMax
+}
+
+// writeMPEGTS prints the transport stream line of the text summary, below
+// the RTP loss it complements
+func writeMPEGTS(w io.Writer, stats Stats) error {
+	_, err := fmt.Fprintf(w, "  MPEG-TS: %d packets | CC errors: %d in %d sessions | Sync errors: %d | PCR jitter: avg %.2fms, max %.2fms\n",
+		stats.MPEGTSPackets, stats.MPEGTSCCErrors, stats.MPEGTSErrorSessions, stats.MPEGTSSyncErrors,
+		stats.PCRJitterAvg, stats.PCRJitterMax)
+	return err
+}
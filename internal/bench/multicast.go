@@ -0,0 +1,87 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+)
+
+// GroupStats holds per-group statistics of a multicast run
+type GroupStats struct {
+	Members     int64   `json:"members"` // Tracks currently joined
+	Joins       int64   `json:"joins"`   // Tracks that joined during the run
+	RTPPackets  uint64  `json:"packets"` // Summed over members: each receives its own copy
+	RTPLoss     uint64  `json:"loss"`
+	RTPBytes    uint64  `json:"bytes"`
+	BitrateMbps float64 `json:"bitrate_mbps"`
+}
+
+// multicastGroup is one group's reception state
+type multicastGroup struct {
+	aggregator *rtp.Aggregator // Separate from the run-wide aggregator
+	members    atomic.Int64
+	joins      atomic.Int64
+}
+
+// groupSet tracks the multicast groups readers join; it implements
+// rtsp.GroupTracker
+type groupSet struct {
+	mu     sync.Mutex
+	groups map[string]*multicastGroup
+}
+
+// newGroupSet creates an empty group set
+func newGroupSet() *groupSet {
+	return &groupSet{groups: make(map[string]*multicastGroup)}
+}
+
+// Join counts a member joining group and returns the group's aggregator
+func (gs *groupSet) Join(group string) *rtp.Aggregator {
+	gs.mu.Lock()
+	g, ok := gs.groups[group]
+	if !ok {
+		g = &multicastGroup{aggregator: rtp.NewAggregator()}
+		gs.groups[group] = g
+	}
+	gs.mu.Unlock()
+
+	g.members.Add(1)
+	g.joins.Add(1)
+	return g.aggregator
+}
+
+// Leave counts a member leaving group
+func (gs *groupSet) Leave(group string) {
+	gs.mu.Lock()
+	g := gs.groups[group]
+	gs.mu.Unlock()
+	if g != nil {
+		g.members.Add(-1)
+	}
+}
+
+// stats returns per-group statistics, or nil if no group was joined
+func (gs *groupSet) stats(elapsed time.Duration) map[string]GroupStats {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if len(gs.groups) == 0 {
+		return nil
+	}
+
+	result := make(map[string]GroupStats, len(gs.groups))
+	for name, g := range gs.groups {
+		snapshot := g.aggregator.Snapshot()
+		result[name] = GroupStats{
+			Members:     g.members.Load(),
+			Joins:       g.joins.Load(),
+			RTPPackets:  snapshot.Packets,
+			RTPLoss:     snapshot.Lost,
+			RTPBytes:    snapshot.Bytes,
+			BitrateMbps: snapshot.Bitrate(elapsed.Seconds()),
+		}
+	}
+	return result
+}
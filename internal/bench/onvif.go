@@ -0,0 +1,13 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import "github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+
+// fillONVIFStats copies the ONVIF metadata and backchannel counts into
+// stats
+func fillONVIFStats(stats *Stats, snapshot rtp.Snapshot) {
+	stats.MetadataPackets = snapshot.MetadataPackets
+	stats.MetadataBytes = snapshot.MetadataBytes
+	stats.BackchannelPackets = snapshot.BackchannelPackets
+	stats.BackchannelBytes = snapshot.BackchannelBytes
+}
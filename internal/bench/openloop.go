@@ -0,0 +1,102 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Load models
+const (
+	LoadModelClosed = "closed" // Attempts wait for the rate limiter and a free slot, and slow down when they fail
+	LoadModelOpen   = "open"   // Attempts start on schedule, however many are still outstanding
+)
+
+// openLoop tracks the attempts of an open-loop run: launched on a fixed
+// schedule, each leaves the queue once it connects or fails
+type openLoop struct {
+	scheduled atomic.Int64
+	pending   atomic.Int64 // Queue depth: launched, not yet connected or failed
+	peak      atomic.Int64
+	lagMax    atomic.Int64 // Nanoseconds an attempt started after its scheduled time
+}
+
+// newOpenLoop returns the open-loop tracker of config, nil for a closed
+// loop
+func newOpenLoop(config Config) (*openLoop, error) {
+	switch config.LoadModel {
+	case "", LoadModelClosed:
+		return nil, nil
+	case LoadModelOpen:
+	default:
+		return nil, fmt.Errorf("unknown load model %q (want %s or %s)", config.LoadModel, LoadModelClosed, LoadModelOpen)
+	}
+	if config.RealWorld || config.Scenario != nil || config.Replay != nil || config.FindMax != nil ||
+		config.Profile != nil || config.ControlAddr != "" {
+		return nil, fmt.Errorf("load model %s only applies to fixed reader count runs", LoadModelOpen)
+	}
+	return &openLoop{}, nil
+}
+
+// launched records an attempt started lag after its scheduled time and
+// returns the function that takes it off the queue
+func (o *openLoop) launched(lag time.Duration) func() {
+	o.scheduled.Add(1)
+	trackPeak(&o.peak, o.pending.Add(1))
+	trackPeak(&o.lagMax, int64(lag))
+	return func() { o.pending.Add(-1) }
+}
+
+// fill copies the open-loop figures into stats
+func (o *openLoop) fill(stats *Stats) {
+	if o == nil {
+		return
+	}
+	stats.OpenLoopScheduled = o.scheduled.Load()
+	stats.OpenLoopPending = o.pending.Load()
+	stats.OpenLoopPendingPeak = o.peak.Load()
+	stats.OpenLoopLagMax = float64(o.lagMax.Load()) / float64(time.Millisecond)
+}
+
+// spawnOpenLoop starts Readers attempts on the arrival process's
+// schedule. Unlike spawnConnections it never waits for earlier attempts,
+// backs off on failures or retries: a slow server grows the queue instead
+// of slowing the arrivals.
+func (r *Runner) spawnOpenLoop(ctx context.Context) {
+	defer r.wg.Done()
+
+	start := time.Now()
+	for i := 0; i < r.config.Readers; i++ {
+		scheduled := start.Add(r.schedule.nextArrival())
+		if !waitUntil(ctx, scheduled) {
+			return
+		}
+
+		r.semaphore <- struct{}{} // Sized for every reader in an open loop: never blocks
+		r.wg.Add(1)
+		s := r.defaultSession()
+		s.intended = scheduled
+		if r.config.IncludeBadClients && r.random.Float64() < r.config.BadClientRatio {
+			go r.runBadClient(ctx, s)
+		} else {
+			s.connected = r.openLoop.launched(time.Since(scheduled))
+			go r.runConnection(ctx, s)
+		}
+
+		if n := i + 1; (n <= 1000 && n%100 == 0) || n%1000 == 0 {
+			r.log.Info("spawned connections", "count", n, "pending", r.openLoop.pending.Load())
+		}
+	}
+
+	r.log.Info("finished spawning connections", "count", r.config.Readers, "pending", r.openLoop.pending.Load())
+}
+
+// writeOpenLoop prints the open-loop line of the text summary
+func writeOpenLoop(w io.Writer, stats Stats) error {
+	_, err := fmt.Fprintf(w, "  Open loop: %d attempts scheduled | Queue depth: peak %d, at end %d | Start lag max: %.1fms\n",
+		stats.OpenLoopScheduled, stats.OpenLoopPendingPeak, stats.OpenLoopPending, stats.OpenLoopLagMax)
+	return err
+}
@@ -0,0 +1,441 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Formatter writes periodic stats samples and a final summary
+type Formatter interface {
+	WriteSample(elapsed time.Duration, stats Stats) error
+	WriteSummary(elapsed time.Duration, stats Stats) error
+}
+
+// NewFormatter creates a formatter for the given format (text, json or csv)
+func NewFormatter(format string, w io.Writer) (Formatter, error) {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return &textFormatter{w: w}, nil
+	case "json":
+		return &jsonFormatter{enc: json.NewEncoder(w)}, nil
+	case "csv":
+		return &csvFormatter{w: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// multiFormatter writes every record to each of its formatters
+type multiFormatter []Formatter
+
+// MultiFormatter returns a Formatter writing to all of formatters, stopping
+// at the first error
+func MultiFormatter(formatters ...Formatter) Formatter {
+	return multiFormatter(formatters)
+}
+
+func (m multiFormatter) WriteSample(elapsed time.Duration, stats Stats) error {
+	for _, f := range m {
+		if err := f.WriteSample(elapsed, stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiFormatter) WriteSummary(elapsed time.Duration, stats Stats) error {
+	for _, f := range m {
+		if err := f.WriteSummary(elapsed, stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TimelineFormat returns the format of a Config.TimelineFile: csv for a
+// .csv file, json otherwise
+func TimelineFormat(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return "csv"
+	}
+	return "json"
+}
+
+// OpenOutput opens the stats output destination; an empty path means stdout
+func OpenOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// textFormatter writes the human-readable single line format
+type textFormatter struct {
+	w     io.Writer
+	trend statusTrend
+}
+
+func (f *textFormatter) WriteSample(elapsed time.Duration, stats Stats) error {
+	_, err := fmt.Fprintf(f.w, "[%s] Active: %d | Total: %d | Failed: %d | Avg Connect: %.1fms | Packets: %d | Loss: %.2f%%\n",
+		time.Now().Format("15:04:05"),
+		stats.ActiveConnects,
+		stats.TotalConnects,
+		stats.TotalFailures,
+		stats.AvgConnectTime,
+		stats.RTPPackets,
+		lossPercent(stats),
+	)
+	if err != nil {
+		return err
+	}
+	// Redirects, errors and unanswered requests since the last sample
+	if delta := f.trend.next(stats.Methods); delta != nil {
+		_, err = fmt.Fprintf(f.w, "  Unexpected: %s\n", formatTrend(delta))
+	}
+	return err
+}
+
+func (f *textFormatter) WriteSummary(elapsed time.Duration, stats Stats) error {
+	_, err := fmt.Fprintf(f.w, "Duration: %s | Total: %d | Failed: %d | Avg Connect: %.1fms | P95 Connect: %.1fms | Packets: %d | Loss: %.2f%%\n",
+		elapsed.Round(time.Millisecond),
+		stats.TotalConnects,
+		stats.TotalFailures,
+		stats.AvgConnectTime,
+		stats.P95ConnectTime,
+		stats.RTPPackets,
+		lossPercent(stats),
+	)
+	if err != nil {
+		return err
+	}
+	if stats.ResponseConnectMax > 0 {
+		if _, err := fmt.Fprintf(f.w, "  Response from intended start: Avg %.1fms | P95 %.1fms | P99 %.1fms | Max %.1fms (service P99 %.1fms)\n",
+			stats.ResponseConnectAvg, stats.ResponseConnectP95, stats.ResponseConnectP99, stats.ResponseConnectMax, stats.P99ConnectTime); err != nil {
+			return err
+		}
+	}
+	if stats.OpenLoopScheduled > 0 {
+		if err := writeOpenLoop(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if stats.SelfOverloaded > 0 {
+		if err := writeSelfOverload(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if stats.MPEGTSPackets > 0 {
+		if err := writeMPEGTS(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if stats.Stalls > 0 {
+		if err := writeStalls(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if len(stats.LossBursts) > 0 {
+		if err := writeLossBursts(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if stats.Duplicates > 0 || stats.Reordered > 0 {
+		if err := writeReordering(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if stats.StreamResets > 0 {
+		if err := writeStreamResets(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if stats.QoESessions > 0 {
+		if err := writeQoE(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if stats.SessionLifetimes > 0 {
+		if err := writeSessionLifetimes(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if stats.KeepAliveRetries > 0 {
+		if _, err := fmt.Fprintf(f.w, "  Keepalive retries: %d | Recovered: %d\n", stats.KeepAliveRetries, stats.KeepAlivesRecovered); err != nil {
+			return err
+		}
+	}
+	if stats.PopularityShifts > 0 {
+		if err := writePopularity(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if stats.TargetSwaps > 0 {
+		if err := writeMigrations(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if stats.SessionResumes > 0 || stats.SessionResumeFailures > 0 {
+		if _, err := fmt.Fprintf(f.w, "  Sessions resumed: %d (failed: %d) | Interruption: avg %.0fms, p95 %.0fms, max %.0fms\n",
+			stats.SessionResumes, stats.SessionResumeFailures, stats.InterruptionAvg, stats.InterruptionP95, stats.InterruptionMax); err != nil {
+			return err
+		}
+	}
+	if len(stats.Methods) > 0 {
+		if err := writeMethods(f.w, stats.Methods); err != nil {
+			return err
+		}
+	}
+	if stats.SharedUDPPackets > 0 || stats.SharedUDPUnrouted > 0 {
+		if err := writeSharedUDP(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if len(stats.Nodes) > 0 || stats.UnexpectedNodeResponses > 0 || stats.StickyViolations > 0 {
+		if err := writeNodes(f.w, stats); err != nil {
+			return err
+		}
+	}
+	if len(stats.FailureCategories) > 0 {
+		if err := writeFailures(f.w, stats.FailureCategories); err != nil {
+			return err
+		}
+	}
+	if len(stats.Events) > 0 {
+		if err := writeEvents(f.w, stats.Events); err != nil {
+			return err
+		}
+	}
+	if len(stats.BadClientReactions) == 0 {
+		return nil
+	}
+	return writeReactions(f.w, stats.BadClientReactions)
+}
+
+// jsonFormatter writes one JSON object per line
+type jsonFormatter struct {
+	enc   *json.Encoder
+	trend statusTrend
+}
+
+// jsonRecord wraps Stats with record metadata
+type jsonRecord struct {
+	Type       string           `json:"type"`
+	Time       string           `json:"time"`
+	Elapsed    float64          `json:"elapsed_sec"`
+	Unexpected map[string]int64 `json:"unexpected_statuses,omitempty"` // Samples: "METHOD code" counts since the last sample
+	Stats
+}
+
+func (f *jsonFormatter) WriteSample(elapsed time.Duration, stats Stats) error {
+	return f.write("sample", elapsed, stats, f.trend.next(stats.Methods))
+}
+
+func (f *jsonFormatter) WriteSummary(elapsed time.Duration, stats Stats) error {
+	return f.write("summary", elapsed, stats, nil)
+}
+
+func (f *jsonFormatter) write(kind string, elapsed time.Duration, stats Stats, unexpected map[string]int64) error {
+	return f.enc.Encode(jsonRecord{
+		Type:       kind,
+		Time:       time.Now().Format(time.RFC3339),
+		Elapsed:    elapsed.Seconds(),
+		Unexpected: unexpected,
+		Stats:      stats,
+	})
+}
+
+// csvFormatter writes a header row followed by one row per record
+type csvFormatter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (f *csvFormatter) WriteSample(elapsed time.Duration, stats Stats) error {
+	return f.write("sample", elapsed, stats)
+}
+
+func (f *csvFormatter) WriteSummary(elapsed time.Duration, stats Stats) error {
+	return f.write("summary", elapsed, stats)
+}
+
+func (f *csvFormatter) write(kind string, elapsed time.Duration, stats Stats) error {
+	names, values := statsColumns(stats)
+
+	if !f.wroteHeader {
+		header := append([]string{"type", "time", "elapsed_sec"}, names...)
+		if err := f.w.Write(header); err != nil {
+			return err
+		}
+		f.wroteHeader = true
+	}
+
+	row := append([]string{
+		kind,
+		time.Now().Format(time.RFC3339),
+		strconv.FormatFloat(elapsed.Seconds(), 'f', 3, 64),
+	}, values...)
+	if err := f.w.Write(row); err != nil {
+		return err
+	}
+
+	f.w.Flush()
+	return f.w.Error()
+}
+
+// statsColumns flattens Stats into column names (from json tags) and values
+func statsColumns(stats Stats) ([]string, []string) {
+	v := reflect.ValueOf(stats)
+	t := v.Type()
+
+	names := make([]string, 0, t.NumField())
+	values := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			name = t.Field(i).Name
+		}
+		names = append(names, name)
+		values = append(values, formatValue(v.Field(i)))
+	}
+	return names, values
+}
+
+// formatValue renders a single Stats field for CSV output. Maps become
+// "key=value;key=value" with sorted keys.
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', 3, 64)
+	case reflect.Map:
+		keys := v.MapKeys()
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			parts = append(parts, fmt.Sprintf("%v=%v", k.Interface(), v.MapIndex(k).Interface()))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ";")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// lossPercent returns the RTP loss rate as a percentage
+func lossPercent(stats Stats) float64 {
+	if stats.RTPPackets == 0 {
+		return 0
+	}
+	return float64(stats.RTPLoss) * 100.0 / float64(stats.RTPPackets+stats.RTPLoss)
+}
+
+// startReporter writes a sample to Config.OutputFile every StatsInterval
+// using Config.LogFormat and to Config.TimelineFile, pushes it to the
+// configured exporters, and collects them for the Config.ReportFile HTML
+// report. The returned finish
+// function stops sampling, writes the final summary and report and closes
+// the files. It is a no-op when nothing is configured.
+func startReporter(ctx context.Context, config Config, log *slog.Logger, getStats func() Stats) (func(), error) {
+	if config.OutputFile == "" && config.TimelineFile == "" && config.ReportFile == "" &&
+		config.InfluxURL == "" && config.OTLPURL == "" {
+		return func() {}, nil
+	}
+
+	var formatters []Formatter
+	var files []io.Closer
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	if config.OutputFile != "" {
+		out, err := OpenOutput(config.OutputFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open output: %w", err)
+		}
+		files = append(files, out)
+		formatter, err := NewFormatter(config.LogFormat, out)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		formatters = append(formatters, formatter)
+	}
+	if config.TimelineFile != "" {
+		timeline, err := os.Create(config.TimelineFile)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to create timeline: %w", err)
+		}
+		files = append(files, timeline)
+		formatter, _ := NewFormatter(TimelineFormat(config.TimelineFile), timeline)
+		formatters = append(formatters, formatter)
+	}
+	if config.ReportFile != "" {
+		report, err := os.Create(config.ReportFile)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to create report: %w", err)
+		}
+		files = append(files, report)
+		formatters = append(formatters, NewHTMLReport(report, config))
+	}
+	if config.InfluxURL != "" || config.OTLPURL != "" {
+		tags := NewExportTags(config)
+		log.Info("exporting stats", "run_id", tags.RunID, "scenario", tags.Scenario, "target_host", tags.Target)
+		formatters = append(formatters, NewExporters(config, tags, log)...)
+	}
+	formatter := MultiFormatter(formatters...)
+
+	interval := config.StatsInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	start := time.Now()
+	var mu sync.Mutex
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				mu.Lock()
+				_ = formatter.WriteSample(time.Since(start), getStats())
+				mu.Unlock()
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+		mu.Lock()
+		_ = formatter.WriteSummary(time.Since(start), getStats())
+		mu.Unlock()
+		closeAll()
+	}, nil
+}
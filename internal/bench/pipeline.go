@@ -0,0 +1,44 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// parserProbeStats totals the MalformedPipeline bad clients
+type parserProbeStats struct {
+	probes     atomic.Int64
+	requests   atomic.Int64
+	answered   atomic.Int64
+	disordered atomic.Int64
+	smuggled   atomic.Int64
+	closed     atomic.Int64
+}
+
+// record adds a finished MalformedPipeline client
+func (s *parserProbeStats) record(res rtsp.ParserProbeResult, log *slog.Logger) {
+	if res.Probes == 0 {
+		return
+	}
+	s.probes.Add(int64(res.Probes))
+	s.requests.Add(int64(res.Requests))
+	s.answered.Add(int64(res.Answered))
+	s.disordered.Add(int64(res.Disordered))
+	s.smuggled.Add(int64(res.Smuggled))
+	s.closed.Add(int64(res.Closed))
+	log.Debug("parser probes finished", "probes", res.Probes, "requests", res.Requests, "answered", res.Answered,
+		"disordered", res.Disordered, "smuggled", res.Smuggled, "closed", res.Closed)
+}
+
+// fill copies the parser probe statistics into stats
+func (s *parserProbeStats) fill(stats *Stats) {
+	stats.ParserProbes = s.probes.Load()
+	stats.ParserRequests = s.requests.Load()
+	stats.ParserAnswered = s.answered.Load()
+	stats.ParserDisordered = s.disordered.Load()
+	stats.ParserSmuggled = s.smuggled.Load()
+	stats.ParserClosed = s.closed.Load()
+}
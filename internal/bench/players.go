@@ -0,0 +1,79 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"strings"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// BenchPlayer is the Config.PlayerWeights name of readers that keep the
+// bench's own behavior
+const BenchPlayer = "bench"
+
+// playerPicker chooses the player profile each reader mimics, by
+// Config.PlayerProfile or Config.PlayerWeights; nil keeps every reader's
+// own behavior
+type playerPicker struct {
+	*weighted
+	profiles []*rtsp.PlayerProfile // By name index, nil for BenchPlayer
+}
+
+// newPlayerPicker builds the picker from the config, or returns nil if
+// neither option is set
+func newPlayerPicker(config Config) (*playerPicker, error) {
+	weights := config.PlayerWeights
+	if config.PlayerProfile != "" {
+		weights = map[string]float64{config.PlayerProfile: 1}
+	}
+	if len(weights) == 0 {
+		return nil, nil
+	}
+	w, err := newWeighted(weights, "player profile", func(name string) error {
+		if strings.EqualFold(name, BenchPlayer) {
+			return nil
+		}
+		_, err := rtsp.ParsePlayerProfile(name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	p := &playerPicker{weighted: w}
+	for _, name := range w.names {
+		var profile *rtsp.PlayerProfile
+		if name != BenchPlayer {
+			pp, _ := rtsp.ParsePlayerProfile(name)
+			profile = &pp
+		}
+		p.profiles = append(p.profiles, profile)
+	}
+	return p, nil
+}
+
+// pick draws a profile from rng and counts it; nil means the bench's own
+// behavior. A nil picker returns nil without drawing.
+func (p *playerPicker) pick(rng *lockedRand) *rtsp.PlayerProfile {
+	if p == nil {
+		return nil
+	}
+	return p.profiles[p.weighted.pick(rng)]
+}
+
+// readerTransport returns the transport of a reader mimicking player:
+// the configured one, or else the player's preference
+func readerTransport(configured string, player *rtsp.PlayerProfile) string {
+	if configured == "" && player != nil {
+		return player.Transport
+	}
+	return configured
+}
+
+// fill copies the readers per profile into stats. A nil picker leaves
+// them empty.
+func (p *playerPicker) fill(stats *Stats) {
+	if p == nil {
+		return
+	}
+	stats.PlayerProfiles = p.drawn()
+}
@@ -0,0 +1,115 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// readerPool keeps a target number of readers running, replacing readers
+// that end early. Used by scenario phases, load profiles and replays.
+type readerPool struct {
+	r   *Runner
+	ctx context.Context
+
+	mu       sync.Mutex
+	sessions map[uint64]context.CancelFunc
+	nextID   uint64
+}
+
+// newReaderPool creates an empty pool whose readers run under ctx
+func newReaderPool(ctx context.Context, r *Runner) *readerPool {
+	return &readerPool{
+		r:        r,
+		ctx:      ctx,
+		sessions: make(map[uint64]context.CancelFunc),
+	}
+}
+
+// size returns the number of readers running or connecting
+func (p *readerPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.sessions)
+}
+
+// resize starts or stops readers to reach target. At most maxSpawn readers
+// are started per call (0 = unlimited); stops are immediate.
+func (p *readerPool) resize(target int, s session, badRatio float64, maxSpawn int) {
+	p.r.targetReaders.Store(int64(target))
+
+	diff := target - p.size()
+	if diff < 0 {
+		p.shrink(-diff)
+		return
+	}
+	if maxSpawn > 0 && diff > maxSpawn {
+		diff = maxSpawn
+	}
+	for i := 0; i < diff; i++ {
+		if _, ok := p.spawn(s, badRatio); !ok {
+			return
+		}
+	}
+}
+
+// spawn starts one reader, or a bad client with probability badRatio, and
+// returns its ID. Returns false if the pool context is done.
+func (p *readerPool) spawn(s session, badRatio float64) (uint64, bool) {
+	s.intended = time.Now()
+	select {
+	case p.r.semaphore <- struct{}{}:
+	case <-p.ctx.Done():
+		return 0, false
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	p.mu.Lock()
+	id := p.nextID
+	p.nextID++
+	p.sessions[id] = cancel
+	p.mu.Unlock()
+
+	p.r.wg.Add(1)
+	go func() {
+		defer p.remove(id)
+		if badRatio > 0 && p.r.random.Float64() < badRatio {
+			p.r.runBadClient(ctx, s)
+		} else {
+			p.r.runConnection(ctx, s)
+		}
+	}()
+	return id, true
+}
+
+// remove stops a reader, if still running, and drops it from the pool
+func (p *readerPool) remove(id uint64) {
+	p.mu.Lock()
+	cancel, ok := p.sessions[id]
+	delete(p.sessions, id)
+	p.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// shrink stops n readers
+func (p *readerPool) shrink(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, cancel := range p.sessions {
+		if n <= 0 {
+			break
+		}
+		cancel()
+		delete(p.sessions, id)
+		n--
+	}
+}
+
+// closeAll stops every reader in the pool
+func (p *readerPool) closeAll() {
+	p.shrink(p.size())
+	p.r.targetReaders.Store(0)
+}
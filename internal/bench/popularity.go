@@ -0,0 +1,154 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTrendShare is the share of targets rising to the top ranks at
+// each popularity shift
+const DefaultTrendShare = 0.05
+
+// minTrendTick bounds how often a compressed clock shifts the ranking
+const minTrendTick = 100 * time.Millisecond
+
+// popularityChurn shifts the popularity ranking of the targets during a
+// real-world run: trending targets rise to the top ranks and push the
+// others down, so viewers keep moving to streams nobody watched before
+type popularityChurn struct {
+	interval time.Duration // Simulated time between shifts, 0 = fixed ranking
+	share    float64
+
+	shifts  atomic.Int64
+	started atomic.Int64 // Targets gaining their first viewer
+	stopped atomic.Int64 // Targets losing their last viewer
+}
+
+// newPopularityChurn returns the popularity churn of config. The ranking
+// only matters to the zipf and weighted distributions.
+func newPopularityChurn(config Config) (*popularityChurn, error) {
+	p := &popularityChurn{interval: config.TrendInterval, share: config.TrendShare}
+	if p.share <= 0 {
+		p.share = DefaultTrendShare
+	}
+	if p.share >= 1 {
+		return nil, fmt.Errorf("trend share %.2f is not below 1", p.share)
+	}
+	if p.interval > 0 && config.Distribution != DistributionZipf && config.Distribution != DistributionWeighted {
+		return nil, fmt.Errorf("popularity churn needs the %s or %s distribution", DistributionZipf, DistributionWeighted)
+	}
+	return p, nil
+}
+
+// run shifts the ranking of targets every interval of clock's simulated
+// time until ctx is done
+func (p *popularityChurn) run(ctx context.Context, targets *targetSet, clock simClock, random *lockedRand, log *slog.Logger) {
+	if p.interval <= 0 {
+		return
+	}
+	tick := max(time.Duration(float64(p.interval)/clock.compression), minTrendTick)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rising := targets.rerank(p.share, random)
+			if len(rising) == 0 {
+				continue
+			}
+			p.shifts.Add(1)
+			log.Info("popularity shifted", "rising", len(rising), "top", rising[0], "live_targets", targets.live())
+		}
+	}
+}
+
+// joined counts a viewer connecting to t
+func (p *popularityChurn) joined(t *target) {
+	if t.active.Add(1) == 1 {
+		p.started.Add(1)
+	}
+}
+
+// left counts a viewer of t going away
+func (p *popularityChurn) left(t *target) {
+	if t.active.Add(-1) == 0 {
+		p.stopped.Add(1)
+	}
+}
+
+// fill copies the popularity figures into stats
+func (p *popularityChurn) fill(stats *Stats, targets *targetSet) {
+	if p == nil {
+		return
+	}
+	stats.PopularityShifts = p.shifts.Load()
+	stats.TargetStarts = p.started.Load()
+	stats.TargetStops = p.stopped.Load()
+	stats.LiveTargets = targets.live()
+}
+
+// rerank moves a random share of the targets below the top ranks to the
+// top, in random order, and slides the others down. Weights stay with
+// the ranks. Returns the URLs of the targets that rose, most popular
+// first.
+func (ts *targetSet) rerank(share float64, random *lockedRand) []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	list := ts.list.Load()
+	n := len(list.targets)
+	if n < 2 {
+		return nil
+	}
+	k := min(max(int(math.Round(share*float64(n))), 1), n-1)
+
+	// Partial Fisher-Yates over the ranks below the top k
+	order := append([]*target(nil), list.targets...)
+	m := min(k, n-k)
+	for i := 0; i < m; i++ {
+		j := k + i + random.Intn(n-k-i)
+		order[k+i], order[j] = order[j], order[k+i]
+	}
+	rising := order[k : k+m]
+
+	next := &targetList{weights: list.weights, totalWeight: list.totalWeight}
+	next.targets = append(next.targets, rising...)
+	for _, t := range list.targets {
+		if !slices.Contains(rising, t) {
+			next.targets = append(next.targets, t)
+		}
+	}
+	ts.list.Store(next)
+
+	urls := make([]string, len(rising))
+	for i, t := range rising {
+		urls[i] = t.url
+	}
+	return urls
+}
+
+// live returns how many targets have viewers now
+func (ts *targetSet) live() int64 {
+	var n int64
+	for _, t := range ts.all() {
+		if t.active.Load() > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// writePopularity prints the popularity churn line of the text summary
+func writePopularity(w io.Writer, stats Stats) error {
+	_, err := fmt.Fprintf(w, "  Popularity shifts: %d | Targets gaining a first viewer: %d, losing the last: %d | Live now: %d\n",
+		stats.PopularityShifts, stats.TargetStarts, stats.TargetStops, stats.LiveTargets)
+	return err
+}
@@ -0,0 +1,165 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+)
+
+// Load profile types
+const (
+	ProfileStep     = "step"     // Add Step readers every Interval up to Peak
+	ProfileSpike    = "spike"    // Base readers, burst to Peak for Hold, then recover to Base
+	ProfileSawtooth = "sawtooth" // Like step, but drop back to Base after reaching Peak
+)
+
+// LoadProfile drives the reader count over time, independently of how the
+// server responds, to locate the count where connect latency degrades.
+// The profile runs for Config.Duration.
+type LoadProfile struct {
+	Type     string
+	Base     int           // Starting (and spike recovery) reader count
+	Step     int           // step/sawtooth: readers added per Interval
+	Interval time.Duration // step/sawtooth: time per level; spike: time at Base before the burst
+	Peak     int           // step: cap (0 = Config.Readers); spike: burst count; sawtooth: reset point
+	Hold     time.Duration // spike: how long the burst lasts
+}
+
+// validate checks the profile parameters
+func (p *LoadProfile) validate() error {
+	switch p.Type {
+	case ProfileStep, ProfileSawtooth:
+		if p.Step <= 0 || p.Interval <= 0 {
+			return fmt.Errorf("%s profile needs a positive step and interval", p.Type)
+		}
+		if p.Type == ProfileSawtooth && p.Peak <= p.Base {
+			return fmt.Errorf("sawtooth profile needs peak above base")
+		}
+	case ProfileSpike:
+		if p.Peak <= 0 || p.Hold <= 0 {
+			return fmt.Errorf("spike profile needs a peak and hold")
+		}
+	default:
+		return fmt.Errorf("unknown load profile: %s", p.Type)
+	}
+	return nil
+}
+
+// readers returns the target reader count elapsed into the run
+func (p *LoadProfile) readers(elapsed time.Duration, maxReaders int) int {
+	switch p.Type {
+	case ProfileSpike:
+		if elapsed >= p.Interval && elapsed < p.Interval+p.Hold {
+			return p.Peak
+		}
+		return p.Base
+	case ProfileSawtooth:
+		levels := (p.Peak-p.Base)/p.Step + 1
+		level := int(elapsed/p.Interval) % levels
+		return p.Base + level*p.Step
+	default:
+		peak := p.Peak
+		if peak <= 0 {
+			peak = maxReaders
+		}
+		n := p.Base + int(elapsed/p.Interval)*p.Step
+		if peak > 0 && n > peak {
+			n = peak
+		}
+		return n
+	}
+}
+
+// levelStats collects connect latency while the reader count is at one level
+type levelStats struct {
+	readers  int
+	start    time.Time
+	latency  *histogram.Histogram
+	failures int64 // Runner failure count when the level started
+}
+
+// runProfile steers the reader pool along Config.Profile for Config.Duration,
+// logging connect latency per level so the knee is easy to spot
+func (r *Runner) runProfile(ctx context.Context) error {
+	profile := r.config.Profile
+	if err := profile.validate(); err != nil {
+		return err
+	}
+	r.log.Info("starting load profile", "profile", profile.Type, "base", profile.Base,
+		"step", profile.Step, "interval", profile.Interval, "peak", profile.Peak,
+		"duration", r.config.Duration, "transport", r.config.Transport)
+
+	pool := newReaderPool(ctx, r)
+	defer func() {
+		pool.closeAll()
+		r.log.Info("waiting for connections to close", "active", r.activeConnects.Load())
+		r.wg.Wait()
+	}()
+
+	s := r.poolSession()
+	badRatio := 0.0
+	if r.config.IncludeBadClients {
+		badRatio = r.config.BadClientRatio
+	}
+
+	// Open loop: readers are started at the profile's pace whatever the
+	// outcome, bounded only by Config.Rate
+	maxSpawn := 0
+	if r.config.Rate > 0 {
+		maxSpawn = int(r.config.Rate*scenarioTick.Seconds() + 0.5)
+		if maxSpawn < 1 {
+			maxSpawn = 1
+		}
+	}
+
+	ticker := time.NewTicker(scenarioTick)
+	defer ticker.Stop()
+
+	var level *levelStats
+	start := time.Now()
+	for {
+		elapsed := time.Since(start)
+		if elapsed >= r.config.Duration {
+			break
+		}
+
+		target := r.control.readers(profile.readers(elapsed, r.config.Readers))
+		if level == nil || target != level.readers {
+			r.endLevel(level)
+			level = &levelStats{
+				readers:  target,
+				start:    time.Now(),
+				latency:  histogram.New(),
+				failures: r.totalFailures.Load(),
+			}
+			r.levelLatency.Store(level.latency)
+		}
+		pool.resize(target, s, r.control.badClientRatio(badRatio), maxSpawn)
+
+		select {
+		case <-ctx.Done():
+			r.endLevel(level)
+			return nil
+		case <-ticker.C:
+		}
+	}
+
+	r.endLevel(level)
+	r.log.Info("load profile complete", "profile", profile.Type)
+	return nil
+}
+
+// endLevel logs the connect latency seen while at a reader level
+func (r *Runner) endLevel(level *levelStats) {
+	if level == nil {
+		return
+	}
+	summary := level.latency.Summary()
+	r.log.Info("profile level", "readers", level.readers, "active", r.activeConnects.Load(),
+		"elapsed", time.Since(level.start).Round(time.Millisecond), "connects", summary.Count,
+		"connect_p50_ms", summary.P50, "connect_p95_ms", summary.P95, "connect_p99_ms", summary.P99,
+		"failures", r.totalFailures.Load()-level.failures)
+}
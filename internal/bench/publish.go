@@ -0,0 +1,159 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/synth"
+)
+
+// publishReadyTimeout bounds how long readers wait for publishers to start
+// recording
+const publishReadyTimeout = 5 * time.Second
+
+// publisherSet runs Config.Publishers synthetic sources for the length of
+// the benchmark, restarting any the server drops
+type publisherSet struct {
+	config Config
+	r      *Runner
+
+	live     sync.Map // *rtsp.Publisher -> struct{}, currently running
+	failures atomic.Int64
+	packets  atomic.Uint64 // Sent by publishers that have finished
+	bytes    atomic.Uint64
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// startPublishers starts the configured publishers and waits until they
+// are recording or publishReadyTimeout has passed. Publishers outlive ctx
+// so readers never see their source vanish first; call stop after the
+// readers are done. Returns nil when no publishers are configured.
+func (r *Runner) startPublishers(ctx context.Context) *publisherSet {
+	if r.config.Publishers <= 0 {
+		return nil
+	}
+
+	urls := r.config.PublishURLs
+	if len(urls) == 0 {
+		for _, t := range r.targets.all() {
+			urls = append(urls, t.url)
+		}
+	}
+
+	pubCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	ps := &publisherSet{config: r.config, r: r, cancel: cancel}
+	r.log.Info("starting publishers", "publishers", r.config.Publishers, "urls", len(urls),
+		"bitrate_kbps", r.config.PublishBitrateKbps, "transport", r.config.Transport)
+	for i := 0; i < r.config.Publishers; i++ {
+		ps.wg.Add(1)
+		go ps.run(pubCtx, urls[i%len(urls)])
+	}
+
+	deadline := time.Now().Add(publishReadyTimeout)
+	for ps.recording() < int64(r.config.Publishers) && time.Now().Before(deadline) && ctx.Err() == nil {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if active := ps.recording(); active < int64(r.config.Publishers) {
+		r.log.Warn("not all publishers are recording", "active", active, "publishers", r.config.Publishers)
+	}
+	return ps
+}
+
+// run keeps one publisher recording to url until ctx is done
+func (ps *publisherSet) run(ctx context.Context, url string) {
+	defer ps.wg.Done()
+	log := ps.r.log.With("publisher", url)
+
+	for ctx.Err() == nil {
+		pub, err := rtsp.NewPublisher(url, ps.config.Transport, synth.Options{
+			BitrateKbps: ps.config.PublishBitrateKbps,
+			PacketSize:  ps.config.PublishPacketSize,
+			PayloadType: ps.config.PublishPayloadType,
+			Timestamps:  true,
+		})
+		if err != nil {
+			log.Error("publisher creation failed", "error", err)
+			ps.failures.Add(1)
+			return
+		}
+		ps.r.dialer.applyPublisher(pub)
+		if ps.config.Username != "" {
+			pub.SetCredentials(ps.config.Username, ps.config.Password)
+		}
+
+		ps.live.Store(pub, struct{}{})
+		err = pub.Run(ctx)
+		ps.live.Delete(pub)
+		ps.packets.Add(pub.PacketsSent())
+		ps.bytes.Add(pub.BytesSent())
+		if ctx.Err() != nil {
+			return
+		}
+
+		ps.failures.Add(1)
+		log.Warn("publisher failed, restarting", "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// recording returns the number of publishers currently recording
+func (ps *publisherSet) recording() int64 {
+	var n int64
+	ps.live.Range(func(key, _ any) bool {
+		if key.(*rtsp.Publisher).Recording() {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// stop ends all publishers and waits for their TEARDOWNs
+func (ps *publisherSet) stop() {
+	if ps == nil {
+		return
+	}
+	ps.cancel()
+	ps.wg.Wait()
+}
+
+// fill copies the publisher statistics into stats
+func (ps *publisherSet) fill(stats *Stats) {
+	if ps == nil {
+		return
+	}
+	stats.PublishFailures = ps.failures.Load()
+	stats.PublishPackets = ps.packets.Load()
+	stats.PublishBytes = ps.bytes.Load()
+	ps.live.Range(func(key, _ any) bool {
+		pub := key.(*rtsp.Publisher)
+		if pub.Recording() {
+			stats.Publishers++
+		}
+		stats.PublishPackets += pub.PacketsSent()
+		stats.PublishBytes += pub.BytesSent()
+		return true
+	})
+}
+
+// fillE2EStats copies the publish-to-receive latency into stats. Readers
+// only see samples when the stream comes from a timestamping publisher.
+func fillE2EStats(stats *Stats, snapshot rtp.Snapshot) {
+	stats.E2ESamples = snapshot.E2ESamples
+	stats.E2EAvg = snapshot.E2EAvg
+	stats.E2EP50 = snapshot.E2EP50
+	stats.E2EP95 = snapshot.E2EP95
+	stats.E2EP99 = snapshot.E2EP99
+	stats.E2EMax = snapshot.E2EMax
+}
@@ -0,0 +1,209 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// QoE score range, and the resolution of the score distribution
+const (
+	QoEMax        = 5.0
+	QoEMin        = 1.0
+	qoeResolution = 10 // Buckets per score point
+)
+
+// DefaultQoEModel is the scoring used when Config.QoE is nil
+var DefaultQoEModel = QoEModel{
+	LossWeight:      0.4,
+	JitterWeight:    0.1,
+	StallWeight:     0.3,
+	StallTimeWeight: 0.1,
+	BitrateWeight:   0.2,
+	Thresholds:      []float64{3, 4},
+}
+
+// QoEModel turns a session's loss, jitter, stalls and bitrate into a
+// MOS-like score: QoEMax less a penalty per factor, floored at QoEMin. A
+// zero weight leaves its factor out.
+type QoEModel struct {
+	LossWeight      float64   // Per percent of packets lost
+	JitterWeight    float64   // Per 10 ms of interarrival jitter (worst track)
+	StallWeight     float64   // Per stall
+	StallTimeWeight float64   // Per percent of playing time spent stalled
+	BitrateWeight   float64   // Per 10% of bitrate below the expected bitrate
+	ExpectedKbps    float64   // Expected session bitrate (0 = the median of readers with the same track selection)
+	Thresholds      []float64 // Scores whose sessions at or above are counted (empty = 3 and 4)
+}
+
+// validate checks the model and fills in defaults
+func (m *QoEModel) validate() error {
+	for _, w := range []float64{m.LossWeight, m.JitterWeight, m.StallWeight, m.StallTimeWeight, m.BitrateWeight, m.ExpectedKbps} {
+		if w < 0 {
+			return fmt.Errorf("QoE weights and expected bitrate must not be negative")
+		}
+	}
+	if len(m.Thresholds) == 0 {
+		m.Thresholds = DefaultQoEModel.Thresholds
+	}
+	for _, t := range m.Thresholds {
+		if t < QoEMin || t > QoEMax {
+			return fmt.Errorf("QoE threshold %g is outside %g-%g", t, QoEMin, QoEMax)
+		}
+	}
+	return nil
+}
+
+// qoeInput is what a session is scored on
+type qoeInput struct {
+	lossPct   float64
+	jitterMs  float64
+	stalls    int
+	stallPct  float64
+	shortfall float64 // Share of the expected bitrate missing (0-1), 0 if unknown
+}
+
+// score applies the model to one session
+func (m *QoEModel) score(in qoeInput) float64 {
+	penalty := m.LossWeight*in.lossPct +
+		m.JitterWeight*in.jitterMs/10 +
+		m.StallWeight*float64(in.stalls) +
+		m.StallTimeWeight*in.stallPct +
+		m.BitrateWeight*in.shortfall*10
+	return math.Max(QoEMin, QoEMax-penalty)
+}
+
+// qoeScorer scores every session that received media and keeps the score
+// distribution
+type qoeScorer struct {
+	model   QoEModel
+	rates   *connRates // Cohort medians when the model has no expected bitrate
+	buckets [int(QoEMax*qoeResolution) + 1]atomic.Int64
+	sum     atomic.Int64 // Scores times qoeResolution
+}
+
+// newQoEScorer builds the scorer of Config.QoE, or of DefaultQoEModel
+func newQoEScorer(config Config, rates *connRates) (*qoeScorer, error) {
+	model := DefaultQoEModel
+	if config.QoE != nil {
+		model = *config.QoE
+	}
+	if err := model.validate(); err != nil {
+		return nil, err
+	}
+	return &qoeScorer{model: model, rates: rates}, nil
+}
+
+// record scores a finished session of client, whose reader had the given
+// track selection. Sessions that never received media are failures, not
+// scored. A nil scorer does nothing.
+func (q *qoeScorer) record(client *rtsp.Client, tracks string) {
+	if q == nil {
+		return
+	}
+	_, bytes := client.Received()
+	playing := time.Since(client.PlayTime())
+	if bytes == 0 || client.PlayTime().IsZero() || playing <= 0 {
+		return
+	}
+
+	var in qoeInput
+	var packets, lost uint64
+	for _, t := range client.TrackStats() {
+		packets += t.Packets
+		lost += t.Lost
+		in.jitterMs = math.Max(in.jitterMs, t.JitterMs)
+	}
+	if packets+lost > 0 {
+		in.lossPct = float64(lost) * 100 / float64(packets+lost)
+	}
+	var stallTime time.Duration
+	in.stalls, stallTime = client.Stalls()
+	in.stallPct = math.Min(100, stallTime.Seconds()*100/playing.Seconds())
+
+	// Bitrate is only meaningful once the rate average has settled
+	expected := q.model.ExpectedKbps
+	if expected == 0 && q.rates != nil {
+		expected = q.rates.median(tracks)
+	}
+	if expected > 0 && playing >= RateWindow {
+		kbps := float64(bytes) * 8 / 1000 / playing.Seconds()
+		in.shortfall = math.Max(0, 1-kbps/expected)
+	}
+
+	score := q.model.score(in)
+	q.buckets[int(math.Round(score*qoeResolution))].Add(1)
+	q.sum.Add(int64(math.Round(score * qoeResolution)))
+}
+
+// fill copies the score distribution into stats. A nil scorer leaves it
+// empty.
+func (q *qoeScorer) fill(stats *Stats) {
+	if q == nil {
+		return
+	}
+	var counts [len(q.buckets)]int64
+	var total int64
+	for i := range q.buckets {
+		counts[i] = q.buckets[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return
+	}
+	score := func(p float64) float64 {
+		rank := int64(math.Ceil(p / 100 * float64(total)))
+		if rank < 1 {
+			rank = 1
+		}
+		var seen int64
+		for i, n := range counts {
+			if seen += n; seen >= rank {
+				return float64(i) / qoeResolution
+			}
+		}
+		return QoEMax
+	}
+	stats.QoESessions = total
+	stats.QoEAvg = float64(q.sum.Load()) / qoeResolution / float64(total)
+	stats.QoEMin = score(0)
+	stats.QoEP5 = score(5)
+	stats.QoEP50 = score(50)
+	stats.QoEAtOrAbove = make(map[string]int64, len(q.model.Thresholds))
+	for _, t := range q.model.Thresholds {
+		var n int64
+		for i := int(math.Ceil(t * qoeResolution)); i < len(counts); i++ {
+			n += counts[i]
+		}
+		stats.QoEAtOrAbove[qoeLabel(t)] = n
+	}
+}
+
+// qoeLabel formats a threshold as a QoEAtOrAbove key, e.g. "4.0"
+func qoeLabel(t float64) string {
+	return strconv.FormatFloat(t, 'f', 1, 64)
+}
+
+// writeQoE prints the QoE line of the text summary: the average and the
+// share of sessions at or above each threshold
+func writeQoE(w io.Writer, stats Stats) error {
+	labels := make([]string, 0, len(stats.QoEAtOrAbove))
+	for label := range stats.QoEAtOrAbove {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	parts := []string{fmt.Sprintf("avg %.2f", stats.QoEAvg), fmt.Sprintf("p5 %.1f", stats.QoEP5)}
+	for _, label := range labels {
+		parts = append(parts, fmt.Sprintf("%.1f%% >= %s", float64(stats.QoEAtOrAbove[label])*100/float64(stats.QoESessions), label))
+	}
+	_, err := fmt.Fprintf(w, "  QoE (%d sessions): %s\n", stats.QoESessions, strings.Join(parts, " | "))
+	return err
+}
@@ -0,0 +1,96 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// BadClientReaction is how the server treated the bad clients of one type
+type BadClientReaction struct {
+	Clients         int64            `json:"clients"`          // Finished bad clients of the type
+	Statuses        map[string]int64 `json:"statuses"`         // Responses by status code
+	Closed          int64            `json:"closed"`           // Connections the server closed
+	Resets          int64            `json:"resets"`           // Connections the server reset
+	Timeouts        int64            `json:"timeouts"`         // Requests not answered in time
+	Refused         int64            `json:"refused"`          // Connection attempts refused
+	ConnectTimeouts int64            `json:"connect_timeouts"` // Connection attempts that timed out (SYNs dropped)
+}
+
+// reactionStats collects BadClientReaction per bad client type
+type reactionStats struct {
+	mu     sync.Mutex
+	byType map[string]*BadClientReaction
+}
+
+// record adds a finished bad client
+func (s *reactionStats) record(typeName string, r rtsp.Reactions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byType == nil {
+		s.byType = make(map[string]*BadClientReaction)
+	}
+	agg, ok := s.byType[typeName]
+	if !ok {
+		agg = &BadClientReaction{Statuses: make(map[string]int64)}
+		s.byType[typeName] = agg
+	}
+	agg.Clients++
+	for code, n := range r.Statuses {
+		agg.Statuses[strconv.Itoa(code)] += int64(n)
+	}
+	agg.Closed += int64(r.Closed)
+	agg.Resets += int64(r.Resets)
+	agg.Timeouts += int64(r.Timeouts)
+	agg.Refused += int64(r.Refused)
+	agg.ConnectTimeouts += int64(r.ConnectTimeouts)
+}
+
+// stats returns a copy of the per-type reactions, or nil if no bad client
+// has finished
+func (s *reactionStats) stats() map[string]BadClientReaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.byType) == 0 {
+		return nil
+	}
+	result := make(map[string]BadClientReaction, len(s.byType))
+	for name, r := range s.byType {
+		c := *r
+		c.Statuses = make(map[string]int64, len(r.Statuses))
+		for code, n := range r.Statuses {
+			c.Statuses[code] = n
+		}
+		result[name] = c
+	}
+	return result
+}
+
+// writeReactions prints one line per bad client type, sorted by name
+func writeReactions(w io.Writer, reactions map[string]BadClientReaction) error {
+	names := make([]string, 0, len(reactions))
+	for name := range reactions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	for _, name := range names {
+		r := reactions[name]
+		statuses := formatCounts(r.Statuses)
+		if statuses == "" {
+			statuses = "none"
+		}
+		statuses = strings.Trim(statuses, "()")
+		if _, err := fmt.Fprintf(w, "  %-19s clients %d | statuses %s | closed %d | resets %d | timeouts %d | refused %d | connect timeouts %d\n",
+			name, r.Clients, statuses, r.Closed, r.Resets, r.Timeouts, r.Refused, r.ConnectTimeouts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+)
+
+// Reconnect policy defaults
+const (
+	DefaultReconnectBackoff    = time.Second
+	DefaultReconnectMaxBackoff = 30 * time.Second
+)
+
+// ReconnectPolicy is how good readers recover when a session fails after
+// PLAY, like a player retrying a dropped stream. Each reconnect waits
+// Backoff, doubling per attempt up to MaxBackoff, then starts a new
+// session; recorded media resumes where it stopped.
+type ReconnectPolicy struct {
+	MaxAttempts int           // Reconnects in a row before giving up (0 = none)
+	Backoff     time.Duration // Wait before the first reconnect (default 1s)
+	MaxBackoff  time.Duration // Longest wait (default 30s)
+	Jitter      float64       // Random share of each wait added or taken off (0.0-1.0)
+}
+
+// validate checks the policy and fills in defaults
+func (p *ReconnectPolicy) validate() error {
+	if p.MaxAttempts < 0 || p.Backoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("reconnect attempts and backoff must not be negative")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("reconnect jitter must be between 0 and 1")
+	}
+	if p.Backoff == 0 {
+		p.Backoff = DefaultReconnectBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = DefaultReconnectMaxBackoff
+	}
+	return nil
+}
+
+// delay returns the wait before reconnect attempt n (from 1)
+func (p *ReconnectPolicy) delay(n int, rng *lockedRand) time.Duration {
+	d := p.Backoff
+	for i := 1; i < n && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + p.Jitter*(2*rng.Float64()-1)))
+	}
+	return d
+}
+
+// reconnectStats counts reconnects and how the server copes with them
+type reconnectStats struct {
+	attempts  atomic.Int64
+	recovered atomic.Int64 // Drops after which playback resumed
+	exhausted atomic.Int64 // Readers that gave up after MaxAttempts
+	connect   *histogram.Histogram // Connect time of reconnects
+	recovery  *histogram.Histogram // From the drop to playing again
+
+	// Reconnect attempts per second: the current second and the peak
+	mu     sync.Mutex
+	second int64
+	count  int64
+	peak   int64
+}
+
+// newReconnectStats creates empty reconnect statistics
+func newReconnectStats() *reconnectStats {
+	return &reconnectStats{connect: histogram.New(), recovery: histogram.New()}
+}
+
+// attempt counts a reconnect attempt
+func (s *reconnectStats) attempt() {
+	s.attempts.Add(1)
+	now := time.Now().Unix()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now != s.second {
+		s.second, s.count = now, 0
+	}
+	s.count++
+	if s.count > s.peak {
+		s.peak = s.count
+	}
+}
+
+// fill copies the reconnect statistics into stats
+func (s *reconnectStats) fill(stats *Stats) {
+	stats.Reconnects = s.attempts.Load()
+	stats.ReconnectsRecovered = s.recovered.Load()
+	stats.ReconnectsExhausted = s.exhausted.Load()
+	s.mu.Lock()
+	stats.ReconnectPeakRate = s.peak
+	s.mu.Unlock()
+	if connect := s.connect.Summary(); connect.Count > 0 {
+		stats.ReconnectConnectAvg = connect.Mean
+		stats.ReconnectConnectP95 = connect.P95
+	}
+	if recovery := s.recovery.Summary(); recovery.Count > 0 {
+		stats.RecoveryAvg = recovery.Mean
+		stats.RecoveryP95 = recovery.P95
+	}
+}
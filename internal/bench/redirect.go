@@ -0,0 +1,34 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// redirectStats counts the redirects readers follow
+type redirectStats struct {
+	followed   atomic.Int64
+	redirected atomic.Int64 // Sessions redirected at least once
+	exceeded   atomic.Int64 // Sessions that gave up after rtsp.MaxRedirects
+}
+
+// record adds a finished session that followed redirects and ended with err
+func (s *redirectStats) record(redirects int, err error) {
+	if redirects > 0 {
+		s.followed.Add(int64(redirects))
+		s.redirected.Add(1)
+	}
+	if errors.Is(err, rtsp.ErrTooManyRedirects) {
+		s.exceeded.Add(1)
+	}
+}
+
+// fill copies the redirect counts into stats
+func (s *redirectStats) fill(stats *Stats) {
+	stats.Redirects = s.followed.Load()
+	stats.RedirectedSessions = s.redirected.Load()
+	stats.RedirectsExceeded = s.exceeded.Load()
+}
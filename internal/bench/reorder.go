@@ -0,0 +1,34 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+)
+
+// fillReordering copies the duplicate, reorder and stream reset counts
+// into stats
+func fillReordering(stats *Stats, snapshot rtp.Snapshot) {
+	stats.Duplicates = snapshot.Duplicates
+	stats.Reordered = snapshot.Reordered
+	stats.ReorderDistanceMax = float64(snapshot.ReorderMax)
+	stats.DuplicateSessions = snapshot.DuplicateSessions
+	stats.ReorderedSessions = snapshot.ReorderedSessions
+	stats.StreamResets = snapshot.StreamResets
+	stats.StreamResetSessions = snapshot.ResetSessions
+}
+
+// writeReordering prints the duplicate and reorder line of the text summary
+func writeReordering(w io.Writer, stats Stats) error {
+	_, err := fmt.Fprintf(w, "  Duplicates: %d (%d sessions) | Reordered: %d (%d sessions), max distance %.0f\n",
+		stats.Duplicates, stats.DuplicateSessions, stats.Reordered, stats.ReorderedSessions, stats.ReorderDistanceMax)
+	return err
+}
+
+// writeStreamResets prints the stream reset line of the text summary
+func writeStreamResets(w io.Writer, stats Stats) error {
+	_, err := fmt.Fprintf(w, "  Stream resets: %d (%d sessions)\n", stats.StreamResets, stats.StreamResetSessions)
+	return err
+}
@@ -0,0 +1,265 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Replay event types
+const (
+	ReplayConnect    = "connect"
+	ReplayDisconnect = "disconnect"
+)
+
+// ReplayEvent is a viewer arriving or leaving in a recorded log
+type ReplayEvent struct {
+	At     time.Duration // Since the first event
+	Viewer string
+	Type   string
+}
+
+// Replay is a recorded sequence of viewer arrivals and departures,
+// reproduced against the targets. The run ends after the last event.
+type Replay struct {
+	Events []ReplayEvent // In order of At
+	Scale  float64       // Readers per recorded viewer, e.g. 0.1 or 3 (0 = 1)
+	Speed  float64       // Replay speed, e.g. 10 plays an hour in 6m (0 = 1)
+}
+
+// Duration returns the replay's real running time
+func (rp *Replay) Duration() time.Duration {
+	if len(rp.Events) == 0 {
+		return 0
+	}
+	return rp.realTime(rp.Events[len(rp.Events)-1].At)
+}
+
+// realTime returns when an event at recorded offset at happens in the run
+func (rp *Replay) realTime(at time.Duration) time.Duration {
+	if rp.Speed <= 0 {
+		return at
+	}
+	return time.Duration(float64(at) / rp.Speed)
+}
+
+// validate checks the replay parameters
+func (rp *Replay) validate() error {
+	if rp.Scale < 0 || rp.Speed < 0 {
+		return fmt.Errorf("replay scale and speed must not be negative")
+	}
+	for i, ev := range rp.Events {
+		if ev.Type != ReplayConnect && ev.Type != ReplayDisconnect {
+			return fmt.Errorf("event %d: unknown type %q", i+1, ev.Type)
+		}
+		if i > 0 && ev.At < rp.Events[i-1].At {
+			return fmt.Errorf("event %d: out of order", i+1)
+		}
+	}
+	return nil
+}
+
+// replayRecord is a log line before its timestamp is made relative
+type replayRecord struct {
+	time   time.Time
+	viewer string
+	event  string
+}
+
+// LoadReplay reads a viewer event log: CSV rows of time,viewer,event, or
+// JSON objects with "time", "viewer" and "event" keys, either as an array
+// (.json) or one per line (.jsonl, .ndjson). Times are RFC 3339 or Unix
+// seconds; events are connect or disconnect. Events are sorted by time.
+func LoadReplay(path string) (*Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []replayRecord
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".jsonl", ".ndjson":
+		records, err = parseReplayJSON(data)
+	default:
+		records, err = parseReplayCSV(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse replay %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: no events found", path)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].time.Before(records[j].time) })
+	rp := &Replay{Events: make([]ReplayEvent, len(records))}
+	for i, rec := range records {
+		rp.Events[i] = ReplayEvent{At: rec.time.Sub(records[0].time), Viewer: rec.viewer, Type: rec.event}
+	}
+	if err := rp.validate(); err != nil {
+		return nil, fmt.Errorf("invalid replay %s: %w", path, err)
+	}
+	return rp, nil
+}
+
+// parseReplayCSV parses time,viewer,event rows, skipping a header row and
+// lines starting with #
+func parseReplayCSV(data []byte) ([]replayRecord, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var records []replayRecord
+	for first := true; ; first = false {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		if len(row) < 3 {
+			return nil, fmt.Errorf("line %d: want time,viewer,event", line)
+		}
+		t, err := parseReplayTime(row[0])
+		if err != nil {
+			if first {
+				continue // Header
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, replayRecord{time: t, viewer: row[1], event: strings.ToLower(row[2])})
+	}
+}
+
+// replayJSON is an event in a JSON log; Time is a string or a number
+type replayJSON struct {
+	Time   json.RawMessage `json:"time"`
+	Viewer json.RawMessage `json:"viewer"`
+	Event  string          `json:"event"`
+}
+
+// parseReplayJSON parses an array of event objects or one object per line
+func parseReplayJSON(data []byte) ([]replayRecord, error) {
+	var events []replayJSON
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &events); err != nil {
+			return nil, err
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for line := 1; scanner.Scan(); line++ {
+			text := bytes.TrimSpace(scanner.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+			var ev replayJSON
+			if err := json.Unmarshal(text, &ev); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			events = append(events, ev)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	records := make([]replayRecord, len(events))
+	for i, ev := range events {
+		t, err := parseReplayTime(strings.Trim(string(ev.Time), `"`))
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %w", i+1, err)
+		}
+		records[i] = replayRecord{time: t, viewer: strings.Trim(string(ev.Viewer), `"`), event: strings.ToLower(ev.Event)}
+	}
+	return records, nil
+}
+
+// parseReplayTime parses an RFC 3339 time or Unix seconds
+func parseReplayTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		whole, frac := math.Modf(secs)
+		return time.Unix(int64(whole), int64(frac*1e9)), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	return t, nil
+}
+
+// runReplay starts and stops readers as the recorded viewers arrived and
+// left. Each viewer becomes Scale readers; readers that fail are not
+// replaced.
+func (r *Runner) runReplay(ctx context.Context) error {
+	rp := r.config.Replay
+	if err := rp.validate(); err != nil {
+		return err
+	}
+	scale := rp.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	r.log.Info("starting replay", "events", len(rp.Events), "scale", scale,
+		"duration", rp.Duration(), "targets", len(r.targets.all()))
+
+	pool := newReaderPool(ctx, r)
+	defer func() {
+		pool.closeAll()
+		r.log.Info("waiting for connections to close", "active", r.activeConnects.Load())
+		r.wg.Wait()
+	}()
+
+	s := r.poolSession()
+	viewers := make(map[string][]uint64) // Readers started for each viewer
+	var started float64                   // Readers owed so far at the scale
+	start := time.Now()
+
+	for _, ev := range rp.Events {
+		if wait := time.Until(start.Add(rp.realTime(ev.At))); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(wait):
+			}
+		}
+
+		switch ev.Type {
+		case ReplayConnect:
+			// Fractional scales start a reader for every 1/scale viewers
+			n := int(started+scale) - int(started)
+			started += scale
+			for i := 0; i < n; i++ {
+				id, ok := pool.spawn(s, 0)
+				if !ok {
+					return nil
+				}
+				viewers[ev.Viewer] = append(viewers[ev.Viewer], id)
+			}
+		case ReplayDisconnect:
+			for _, id := range viewers[ev.Viewer] {
+				pool.remove(id)
+			}
+			delete(viewers, ev.Viewer)
+		}
+		r.targetReaders.Store(int64(pool.size()))
+	}
+
+	r.log.Info("replay complete", "viewers_left", len(viewers))
+	return nil
+}
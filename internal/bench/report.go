@@ -0,0 +1,533 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// reportSample is one point of the report's timelines
+type reportSample struct {
+	elapsed  time.Duration
+	active   int64
+	target   int64
+	p50      float64
+	p95      float64
+	p99      float64
+	lossPct  float64 // Over the interval since the previous sample
+	mbps     float64 // Over the interval since the previous sample
+	failures int64   // In the interval since the previous sample
+	host     bool    // The target host figures below were scraped
+	hostCPU  float64
+	hostMem  float64
+	sockets  int64
+	hostRx   float64
+	hostTx   float64
+}
+
+// htmlReport collects samples during the run and writes a self-contained
+// HTML report with the summary
+type htmlReport struct {
+	w       io.Writer
+	config  Config
+	samples []reportSample
+	last    Stats
+	lastAt  time.Duration
+	peak    int64
+}
+
+// NewHTMLReport returns a Formatter that writes nothing until the summary,
+// then a single HTML page with timeline charts, failure categories and the
+// run configuration. It needs no network access to view.
+func NewHTMLReport(w io.Writer, config Config) Formatter {
+	return &htmlReport{w: w, config: config}
+}
+
+func (f *htmlReport) WriteSample(elapsed time.Duration, stats Stats) error {
+	f.add(elapsed, stats)
+	return nil
+}
+
+func (f *htmlReport) WriteSummary(elapsed time.Duration, stats Stats) error {
+	f.add(elapsed, stats)
+	return reportTemplate.Execute(f.w, f.page(elapsed, stats))
+}
+
+// add appends a sample, with rates over the time since the previous one
+func (f *htmlReport) add(elapsed time.Duration, stats Stats) {
+	s := reportSample{
+		elapsed:  elapsed,
+		active:   stats.ActiveConnects,
+		target:   stats.TargetConnects,
+		p50:      stats.P50ConnectTime,
+		p95:      stats.P95ConnectTime,
+		p99:      stats.P99ConnectTime,
+		failures: stats.TotalFailures - f.last.TotalFailures,
+		host:     stats.HostScrapes > 0,
+		hostCPU:  stats.HostCPUPct,
+		hostMem:  stats.HostMemPct,
+		sockets:  stats.HostSockets,
+		hostRx:   stats.HostRxMbps,
+		hostTx:   stats.HostTxMbps,
+	}
+	packets := float64(stats.RTPPackets) - float64(f.last.RTPPackets)
+	loss := float64(stats.RTPLoss) - float64(f.last.RTPLoss)
+	if packets+loss > 0 {
+		s.lossPct = math.Max(loss, 0) * 100 / (packets + loss)
+	}
+	if secs := (elapsed - f.lastAt).Seconds(); secs > 0 {
+		s.mbps = (float64(stats.RTPBytes) - float64(f.last.RTPBytes)) * 8 / secs / 1e6
+	}
+	if stats.ActiveConnects > f.peak {
+		f.peak = stats.ActiveConnects
+	}
+	f.samples = append(f.samples, s)
+	f.last, f.lastAt = stats, elapsed
+}
+
+// reportPage is what the template renders
+type reportPage struct {
+	Generated string
+	Duration  string
+	Mode      string
+	Targets   []string
+	Cards     []reportCard
+	Charts    []template.HTML
+	Lifetimes template.HTML // Completion histogram, empty if no session was measured
+	Early     []reportRow   // Early terminations by cause
+	Failures  []reportRow
+	Config    []reportRow
+}
+
+type reportCard struct {
+	Label string
+	Value string
+}
+
+type reportRow struct {
+	Name  string
+	Value string
+}
+
+// page builds the report contents from the samples and final stats
+func (f *htmlReport) page(elapsed time.Duration, stats Stats) reportPage {
+	success := 100.0
+	if attempts := stats.TotalConnects + stats.TotalFailures; attempts > 0 {
+		success = float64(stats.TotalConnects) * 100 / float64(attempts)
+	}
+	avgMbps := 0.0
+	if elapsed > 0 {
+		avgMbps = float64(stats.RTPBytes) * 8 / elapsed.Seconds() / 1e6
+	}
+	p := reportPage{
+		Generated: time.Now().Format(time.RFC1123),
+		Duration:  elapsed.Round(time.Second).String(),
+		Mode:      runMode(f.config),
+		Cards: []reportCard{
+			{"Peak active", fmt.Sprint(f.peak)},
+			{"Connects", fmt.Sprint(stats.TotalConnects)},
+			{"Failures", fmt.Sprint(stats.TotalFailures)},
+			{"Success", fmt.Sprintf("%.2f%%", success)},
+			{"Connect p50", fmt.Sprintf("%.1f ms", stats.P50ConnectTime)},
+			{"Connect p95", fmt.Sprintf("%.1f ms", stats.P95ConnectTime)},
+			{"Connect p99", fmt.Sprintf("%.1f ms", stats.P99ConnectTime)},
+			{"RTP loss", fmt.Sprintf("%.3f%%", lossPercent(stats))},
+			{"Avg bitrate", fmt.Sprintf("%.1f Mbps", avgMbps)},
+		},
+	}
+	for _, u := range reportTargets(f.config) {
+		p.Targets = append(p.Targets, redactURL(u))
+	}
+
+	n := len(f.samples)
+	x := make([]float64, n)
+	active, target := make([]float64, n), make([]float64, n)
+	p50, p95, p99 := make([]float64, n), make([]float64, n), make([]float64, n)
+	loss, mbps, failures := make([]float64, n), make([]float64, n), make([]float64, n)
+	hasTarget := false
+	for i, s := range f.samples {
+		x[i] = s.elapsed.Seconds()
+		active[i], target[i] = float64(s.active), float64(s.target)
+		p50[i], p95[i], p99[i] = s.p50, s.p95, s.p99
+		loss[i], mbps[i], failures[i] = s.lossPct, s.mbps, float64(s.failures)
+		hasTarget = hasTarget || s.target > 0
+	}
+	connections := []chartSeries{{"active", "#2563eb", active}}
+	if hasTarget {
+		connections = append(connections, chartSeries{"target", "#9ca3af", target})
+	}
+	p.Charts = []template.HTML{
+		lineChart("Active connections", "", x, connections),
+		lineChart("Connect latency (cumulative)", "ms", x, []chartSeries{
+			{"p50", "#16a34a", p50}, {"p95", "#ea580c", p95}, {"p99", "#dc2626", p99},
+		}),
+		lineChart("RTP loss rate", "%", x, []chartSeries{{"loss", "#dc2626", loss}}),
+		lineChart("Received bitrate", "Mbps", x, []chartSeries{{"bitrate", "#7c3aed", mbps}}),
+		lineChart("Failures per interval", "", x, []chartSeries{{"failures", "#b91c1c", failures}}),
+	}
+	if stats.HostScrapes > 0 {
+		p.Charts = append(p.Charts, f.hostCharts(x, active)...)
+		p.Cards = append(p.Cards, reportCard{"Peak host CPU", fmt.Sprintf("%.1f%%", f.peakHostCPU())})
+	}
+
+	if stats.SessionLifetimes > 0 {
+		p.Cards = append(p.Cards, reportCard{"Early terminations", fmt.Sprintf("%d (%.1f%%)",
+			stats.EarlyTerminations, float64(stats.EarlyTerminations)*100/float64(stats.SessionLifetimes))})
+		p.Lifetimes = template.HTML("<p>No session had an intended duration.</p>")
+		if len(stats.SessionCompletion) > 0 {
+			p.Lifetimes = completionChart(stats)
+		}
+		for _, c := range rtsp.TerminationCauses {
+			if n := stats.EarlyTerminationCauses[c]; n > 0 {
+				p.Early = append(p.Early, reportRow{c, fmt.Sprint(n)})
+			}
+		}
+	}
+
+	for _, c := range rtsp.FailureCategories {
+		if n := stats.FailureCategories[c]; n > 0 {
+			p.Failures = append(p.Failures, reportRow{c, fmt.Sprint(n)})
+		}
+	}
+	p.Config = configRows(f.config)
+	return p
+}
+
+// hostCharts draws the target host's resources against the run: the
+// timelines, and CPU against active connections
+func (f *htmlReport) hostCharts(x, active []float64) []template.HTML {
+	n := len(f.samples)
+	cpu, mem, sockets := make([]float64, n), make([]float64, n), make([]float64, n)
+	rx, tx := make([]float64, n), make([]float64, n)
+	var scatterX, scatterY []float64
+	for i, s := range f.samples {
+		cpu[i], mem[i], sockets[i] = s.hostCPU, s.hostMem, float64(s.sockets)
+		rx[i], tx[i] = s.hostRx, s.hostTx
+		if s.host {
+			scatterX = append(scatterX, active[i])
+			scatterY = append(scatterY, s.hostCPU)
+		}
+	}
+	return []template.HTML{
+		scatterChart("Target host CPU vs active connections", "%", scatterX, scatterY),
+		lineChart("Target host CPU and memory", "%", x, []chartSeries{
+			{"cpu", "#ea580c", cpu}, {"memory", "#0891b2", mem},
+		}),
+		lineChart("Target host sockets and active connections", "", x, []chartSeries{
+			{"sockets", "#0891b2", sockets}, {"active", "#2563eb", active},
+		}),
+		lineChart("Target host network", "Mbps", x, []chartSeries{
+			{"rx", "#16a34a", rx}, {"tx", "#7c3aed", tx},
+		}),
+	}
+}
+
+// peakHostCPU returns the highest host CPU sample
+func (f *htmlReport) peakHostCPU() float64 {
+	peak := 0.0
+	for _, s := range f.samples {
+		peak = math.Max(peak, s.hostCPU)
+	}
+	return peak
+}
+
+// runMode names the mode config runs in, as Runner.Run picks it
+func runMode(config Config) string {
+	switch {
+	case config.RealWorld:
+		return "real-world"
+	case config.Scenario != nil:
+		return "scenario"
+	case config.Replay != nil:
+		return "replay"
+	case config.FindMax != nil:
+		return "find-max"
+	case config.Profile != nil:
+		return "profile"
+	case config.ControlAddr != "":
+		return "controlled"
+	}
+	return "fixed"
+}
+
+// reportTargets returns the reader URLs of config
+func reportTargets(config Config) []string {
+	if len(config.URLs) > 0 {
+		return config.URLs
+	}
+	return []string{config.URL}
+}
+
+// redactURL hides the password of a URL
+func redactURL(s string) string {
+	if u, err := url.Parse(s); err == nil {
+		return u.Redacted()
+	}
+	return s
+}
+
+// configRows lists the fields of config that are set, with credentials
+// hidden and long values cut short
+func configRows(config Config) []reportRow {
+	v := reflect.ValueOf(config)
+	t := v.Type()
+	var rows []reportRow
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.IsZero() {
+			continue
+		}
+		name := t.Field(i).Name
+		var value string
+		switch name {
+		case "Password":
+			value = "(hidden)"
+		case "URL":
+			value = redactURL(config.URL)
+		case "URLs":
+			urls := make([]string, len(config.URLs))
+			for i, u := range config.URLs {
+				urls[i] = redactURL(u)
+			}
+			value = strings.Join(urls, " ")
+		default:
+			if field.Kind() == reflect.Pointer {
+				field = field.Elem()
+			}
+			value = fmt.Sprintf("%+v", field.Interface())
+		}
+		if len(value) > 300 {
+			value = value[:300] + "…"
+		}
+		rows = append(rows, reportRow{name, value})
+	}
+	return rows
+}
+
+// chartSeries is a line of a chart
+type chartSeries struct {
+	name   string
+	color  string
+	values []float64
+}
+
+// Chart geometry, in SVG units
+const (
+	chartWidth  = 760
+	chartHeight = 240
+	chartLeft   = 56
+	chartRight  = 16
+	chartTop    = 32
+	chartBottom = 36
+)
+
+// lineChart draws series against x (seconds) as an inline SVG
+func lineChart(title, unit string, x []float64, series []chartSeries) template.HTML {
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	maxX, maxY := 1.0, 0.0
+	for _, v := range x {
+		maxX = math.Max(maxX, v)
+	}
+	for _, s := range series {
+		for _, v := range s.values {
+			maxY = math.Max(maxY, v)
+		}
+	}
+	maxY = niceCeil(maxY)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" class="chart" role="img">`, chartWidth, chartHeight)
+	label := title
+	if unit != "" {
+		label += " (" + unit + ")"
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="18" class="title">%s</text>`, chartLeft, template.HTMLEscapeString(label))
+
+	// Grid and axis labels
+	for i := 0; i <= 4; i++ {
+		y := float64(chartTop) + plotH*float64(4-i)/4
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" class="grid"/>`, chartLeft, y, chartWidth-chartRight, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" class="axis" text-anchor="end">%s</text>`, chartLeft-6, y+4, formatTick(maxY*float64(i)/4))
+	}
+	for i := 0; i <= 5; i++ {
+		px := float64(chartLeft) + plotW*float64(i)/5
+		secs := maxX * float64(i) / 5
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="axis" text-anchor="middle">%s</text>`, px, chartHeight-14,
+			(time.Duration(secs) * time.Second).String())
+	}
+
+	// Lines, and a legend along the top right
+	for i, s := range series {
+		var points []string
+		for j, v := range s.values {
+			px := float64(chartLeft) + plotW*x[j]/maxX
+			py := float64(chartTop) + plotH*(1-v/maxY)
+			points = append(points, fmt.Sprintf("%.1f,%.1f", px, py))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.8" points="%s"/>`, s.color, strings.Join(points, " "))
+		lx := chartWidth - chartRight - 90*(len(series)-i)
+		fmt.Fprintf(&b, `<rect x="%d" y="10" width="10" height="10" fill="%s"/><text x="%d" y="19" class="axis">%s</text>`,
+			lx, s.color, lx+14, template.HTMLEscapeString(s.name))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// scatterChart draws one dot per sample of y against x (connections) as an
+// inline SVG
+func scatterChart(title, unit string, x, y []float64) template.HTML {
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	maxX, maxY := 0.0, 0.0
+	for i := range x {
+		maxX, maxY = math.Max(maxX, x[i]), math.Max(maxY, y[i])
+	}
+	maxX, maxY = niceCeil(maxX), niceCeil(maxY)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" class="chart" role="img">`, chartWidth, chartHeight)
+	label := title
+	if unit != "" {
+		label += " (" + unit + ")"
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="18" class="title">%s</text>`, chartLeft, template.HTMLEscapeString(label))
+	for i := 0; i <= 4; i++ {
+		y := float64(chartTop) + plotH*float64(4-i)/4
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" class="grid"/>`, chartLeft, y, chartWidth-chartRight, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" class="axis" text-anchor="end">%s</text>`, chartLeft-6, y+4, formatTick(maxY*float64(i)/4))
+	}
+	for i := 0; i <= 5; i++ {
+		px := float64(chartLeft) + plotW*float64(i)/5
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="axis" text-anchor="middle">%s</text>`, px, chartHeight-14,
+			formatTick(maxX*float64(i)/5))
+	}
+	for i := range x {
+		px := float64(chartLeft) + plotW*x[i]/maxX
+		py := float64(chartTop) + plotH*(1-y[i]/maxY)
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="#ea580c" fill-opacity="0.6"/>`, px, py)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// completionChart draws the sessions by share of their intended duration
+// reached as an inline SVG bar chart
+func completionChart(stats Stats) template.HTML {
+	labels := make([]string, completionBuckets+1)
+	values := make([]float64, completionBuckets+1)
+	for i := range labels {
+		labels[i] = completionLabel(i) + "%"
+		values[i] = float64(stats.SessionCompletion[completionLabel(i)])
+	}
+	labels[completionBuckets] = "full"
+	return barChart("Sessions by share of intended duration", labels, values)
+}
+
+// barChart draws one bar per label as an inline SVG
+func barChart(title string, labels []string, values []float64) template.HTML {
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	maxY := 0.0
+	for _, v := range values {
+		maxY = math.Max(maxY, v)
+	}
+	maxY = niceCeil(maxY)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" class="chart" role="img">`, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="18" class="title">%s</text>`, chartLeft, template.HTMLEscapeString(title))
+	for i := 0; i <= 4; i++ {
+		y := float64(chartTop) + plotH*float64(4-i)/4
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" class="grid"/>`, chartLeft, y, chartWidth-chartRight, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" class="axis" text-anchor="end">%s</text>`, chartLeft-6, y+4, formatTick(maxY*float64(i)/4))
+	}
+	slot := plotW / float64(len(values))
+	for i, v := range values {
+		x := float64(chartLeft) + slot*float64(i)
+		h := plotH * v / maxY
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="#2563eb"/>`,
+			x+slot*0.15, float64(chartTop)+plotH-h, slot*0.7, h)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="axis" text-anchor="middle">%s</text>`, x+slot/2, chartHeight-14,
+			template.HTMLEscapeString(labels[i]))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// niceCeil rounds a chart maximum up to 1, 2 or 5 times a power of ten
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	mag := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*mag {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
+
+// formatTick prints an axis value without needless decimals
+func formatTick(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.3f", v), "0"), ".")
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>RTSP benchmark report</title>
+<style>
+body { font: 14px/1.4 system-ui, sans-serif; color: #111827; margin: 24px auto; max-width: 800px; padding: 0 16px; }
+h1 { font-size: 22px; margin-bottom: 4px; }
+h2 { font-size: 17px; margin-top: 28px; border-bottom: 1px solid #e5e7eb; padding-bottom: 4px; }
+.meta { color: #6b7280; }
+.cards { display: grid; grid-template-columns: repeat(3, 1fr); gap: 8px; }
+.card { border: 1px solid #e5e7eb; border-radius: 6px; padding: 8px 12px; }
+.card b { display: block; font-size: 18px; }
+.card span { color: #6b7280; font-size: 12px; }
+.chart { width: 100%; height: auto; margin: 8px 0; }
+.chart .title { font-size: 13px; font-weight: 600; fill: #111827; }
+.chart .axis { font-size: 11px; fill: #6b7280; }
+.chart .grid { stroke: #e5e7eb; }
+table { border-collapse: collapse; width: 100%; }
+td { border-bottom: 1px solid #f3f4f6; padding: 4px 8px; vertical-align: top; }
+td:first-child { white-space: nowrap; color: #374151; width: 1%; }
+td.value { font-family: ui-monospace, monospace; font-size: 12px; word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>RTSP benchmark report</h1>
+<div class="meta">{{.Generated}} &middot; {{.Duration}} &middot; {{.Mode}} mode</div>
+<div class="meta">{{range $i, $t := .Targets}}{{if $i}}, {{end}}{{$t}}{{end}}</div>
+
+<h2>Summary</h2>
+<div class="cards">{{range .Cards}}<div class="card"><span>{{.Label}}</span><b>{{.Value}}</b></div>{{end}}</div>
+
+<h2>Timeline</h2>
+{{range .Charts}}{{.}}
+{{end}}
+{{if .Lifetimes}}<h2>Session lifetime</h2>
+{{.Lifetimes}}
+{{if .Early}}<table>{{range .Early}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>{{end}}</table>{{else}}<p>No session was ended early by the server.</p>{{end}}
+{{end}}<h2>Failures by cause</h2>
+{{if .Failures}}<table>{{range .Failures}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>{{end}}</table>{{else}}<p>No failures.</p>{{end}}
+
+<h2>Configuration</h2>
+<table>{{range .Config}}<tr><td>{{.Name}}</td><td class="value">{{.Value}}</td></tr>{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,151 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+)
+
+// DNS resolution modes
+const (
+	DNSPerConnection = "per-connection" // Resolve for every connection (default)
+	DNSOnce          = "once"           // Resolve each host once and reuse all its records
+	DNSRoundRobin    = "round-robin"    // Resolve once and give each connection the next record
+)
+
+// resolver resolves target hostnames according to Config.DNSMode and
+// Config.DNSPin, timing every lookup it makes
+type resolver struct {
+	mode     string
+	pin      net.IP
+	latency  *histogram.Histogram
+	lookups  atomic.Int64
+	failures atomic.Int64
+
+	mu    sync.Mutex
+	hosts map[string]*resolvedHost
+}
+
+// resolvedHost is the cached answer for one hostname
+type resolvedHost struct {
+	mu    sync.Mutex // Held during the lookup so concurrent connections wait for it
+	addrs []net.IPAddr
+	next  atomic.Uint64
+}
+
+// newResolver creates the resolver for config
+func newResolver(config Config) (*resolver, error) {
+	r := &resolver{
+		mode:    config.DNSMode,
+		latency: histogram.New(),
+		hosts:   make(map[string]*resolvedHost),
+	}
+	switch r.mode {
+	case "":
+		r.mode = DNSPerConnection
+	case DNSPerConnection, DNSOnce, DNSRoundRobin:
+	default:
+		return nil, fmt.Errorf("unknown DNS mode %q (want %s, %s or %s)", config.DNSMode, DNSPerConnection, DNSOnce, DNSRoundRobin)
+	}
+	if config.DNSPin != "" {
+		if r.pin = net.ParseIP(config.DNSPin); r.pin == nil {
+			return nil, fmt.Errorf("invalid DNS pin address %q", config.DNSPin)
+		}
+	}
+	return r, nil
+}
+
+// resolve returns the addresses a connection to host should dial. It is an
+// rtsp.Resolver.
+func (r *resolver) resolve(ctx context.Context, host string) ([]net.IPAddr, error) {
+	// Literals need no lookup
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+
+	var addrs []net.IPAddr
+	var err error
+	if r.mode == DNSPerConnection {
+		addrs, err = r.lookup(ctx, host)
+	} else {
+		addrs, err = r.cached(ctx, host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if r.pin != nil {
+		for _, addr := range addrs {
+			if addr.IP.Equal(r.pin) {
+				return []net.IPAddr{addr}, nil
+			}
+		}
+		return nil, fmt.Errorf("pinned address %s is not among the addresses of %s", r.pin, host)
+	}
+	if r.mode == DNSRoundRobin {
+		h := r.host(host)
+		return []net.IPAddr{addrs[(h.next.Add(1)-1)%uint64(len(addrs))]}, nil
+	}
+	return addrs, nil
+}
+
+// cached returns the cached records of host, looking them up on first use.
+// Failed lookups are not cached, so the next connection retries.
+func (r *resolver) cached(ctx context.Context, host string) ([]net.IPAddr, error) {
+	h := r.host(host)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.addrs != nil {
+		return h.addrs, nil
+	}
+	addrs, err := r.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	h.addrs = addrs
+	return addrs, nil
+}
+
+// host returns the cache entry for host
+func (r *resolver) host(host string) *resolvedHost {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.hosts[host]
+	if !ok {
+		h = &resolvedHost{}
+		r.hosts[host] = h
+	}
+	return h
+}
+
+// lookup queries the system resolver and records its latency
+func (r *resolver) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	r.lookups.Add(1)
+	if err == nil && len(addrs) == 0 {
+		err = fmt.Errorf("no addresses for %s", host)
+	}
+	if err != nil {
+		r.failures.Add(1)
+		return nil, err
+	}
+	r.latency.Record(time.Since(start))
+	return addrs, nil
+}
+
+// fill copies the DNS statistics into stats
+func (r *resolver) fill(stats *Stats) {
+	summary := r.latency.Summary()
+	stats.DNSLookups = r.lookups.Load()
+	stats.DNSFailures = r.failures.Load()
+	stats.DNSAvg = summary.Mean
+	stats.DNSP95 = summary.P95
+	stats.DNSMax = summary.Max
+}
@@ -3,13 +3,17 @@ package bench
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math/rand"
-	"sort"
+	"log/slog"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/impair"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/logging"
 	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
 	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
 	"golang.org/x/time/rate"
@@ -17,42 +21,208 @@ import (
 
 // Config holds benchmark configuration
 type Config struct {
-	URL           string
+	URL           string   // May be a template, e.g. "rtsp://media1/cam-{1..500}" for 500 targets
+	URLs          []string // Multiple targets or templates (overrides URL when set)
+	TargetWeights []int    // Per-URL weights, parallel to URLs
+	Distribution  string   // round-robin (default), weighted or zipf
+	ZipfExponent  float64  // Zipf distribution: the target ranked k gets 1/k^s of the first's readers, in URL order (0 = DefaultZipfExponent)
 	Readers       int
 	Duration      time.Duration
 	Rate          float64 // connections per second
-	Transport     string
+	ConnectArrivals string // Arrival process of fixed runs: constant (default), poisson or bursty
+	ConnectBatch  int     // Bursty arrivals: connections arriving at once
+	ConnectBatchGap time.Duration // Bursty arrivals: time between batches (0 = ConnectBatch/Rate, Rate on average)
+	LoadModel     string  // closed (default): attempts wait for earlier ones and slow down on failures; open: attempts start on ConnectArrivals' schedule regardless (fixed runs only)
+	Transport     string  // tcp (default), udp, multicast, or auto: udp, retried over tcp when no RTP arrives within FallbackTimeout
+	FallbackTimeout time.Duration // Transport auto: time UDP may go without RTP after PLAY (default 5s)
+	StallThreshold time.Duration // Gap without RTP on a playing session that counts as a stall (0 = rtsp.DefaultStallThreshold)
+	KeepAliveAttempts int           // Tries of a keepalive that times out or gets a 5xx before the session fails (0 = rtsp.DefaultKeepAliveAttempts, 1 = no retry)
+	KeepAliveBackoff  time.Duration // Wait before the first keepalive retry, doubled per retry (0 = rtsp.DefaultKeepAliveBackoff)
+	RequestTimeout    time.Duration // Time an RTSP request may wait for its response, or to be sent (0 = rtsp.ReadTimeout)
+	MessageTimeout    time.Duration // Time the rest of an RTSP message may take once it started arriving during playback (0 = rtsp.DefaultMessageTimeout)
+	ResumeAttempts    int           // Tries to resume a session over a new connection when its own is lost during playback (0 = off)
+	ResumeBackoff     time.Duration // Wait before the second resume attempt, doubled per retry (0 = rtsp.DefaultResumeBackoff)
+	QoE           *QoEModel // Per-session QoE score weights (nil = DefaultQoEModel)
 	StatsInterval time.Duration
-	LogFormat     string
+	LogFormat     string  // text, json or csv
+	OutputFile    string  // Write stats samples and summary here (empty = disabled)
+	TimelineFile  string  // Also write every stats sample here, as CSV if the name ends in .csv and JSON lines otherwise (empty = disabled)
+	ReportFile    string  // Write a self-contained HTML report with timeline charts here at the end of the run (empty = disabled)
+	InfluxURL     string  // Push every stats sample in InfluxDB line protocol to this write URL, e.g. "http://influx:8086/api/v2/write?org=ops&bucket=bench" (empty = disabled)
+	InfluxToken   string  // API token for InfluxURL
+	OTLPURL       string  // Push every stats sample as OTLP/HTTP JSON metrics to this URL, e.g. "http://collector:4318/v1/metrics" (empty = disabled)
+	RunID         string  // Tags exported samples and readers' requests (empty = generated from the start time)
+	UserAgent     string  // Readers' User-Agent, e.g. a real player's; {run} and {conn} become the run ID and connection ID (empty = DefaultUserAgent)
+	BenchHeader   bool    // Send "X-Bench-Run: <run ID>/<connection ID>" with every reader request
+	PlayerWeights map[string]float64 // Relative weight per player profile readers mimic, e.g. {"ffmpeg": 50, "vlc": 30, "bench": 20} (empty = all behave as the bench)
+	PlayerProfile string  // Make every reader mimic this player: ffmpeg, vlc, gstreamer or kinesis (overrides PlayerWeights)
+	TrackWeights  map[string]float64 // Relative weight per track selection across readers, e.g. {"all": 70, "audio": 30} (empty = all tracks)
+	Tracks        string  // Tracks every reader sets up from the SDP: all (default), video or audio (overrides TrackWeights)
+	Backchannel   bool    // Ask for the ONVIF audio backchannel (Require: www.onvif.org/ver20/backchannel), set it up and send silence on it
+	MonitorURLs   []string // node_exporter or MediaMTX metrics URLs of the target host, scraped every StatsInterval
+	MonitorCommand string  // Command printing the target host's /proc files, e.g. "ssh media1 " + MonitorProcCommand (empty = disabled)
+	MediaMTXAPI   string  // MediaMTX control API, e.g. "http://media1:9997", polled to cross-check sessions with the readers (empty = disabled)
+	SelfCPUWarnPct float64     // Warn that the bench is the bottleneck when its CPU use exceeds this share of GOMAXPROCS cores (0 = DefaultSelfCPUWarnPct)
+	SelfSchedWarn  time.Duration // Warn that the bench is the bottleneck when runnable goroutines wait longer than this to run, p99 (0 = DefaultSelfSchedWarn)
+	ProfileDir     string      // Write the bench's own CPU, heap and goroutine pprof profiles here every ProfileInterval (empty = disabled)
+	ProfileInterval time.Duration // Time each set of profiles covers (0 = DefaultProfileInterval)
 	RealWorld     bool    // Enable real-world simulation
 	AvgConnections int    // Average connections for real-world mode
 	Variance      float64 // Load variance (0.0-1.0)
+	Lifetime      *Lifetime // Real-world viewer session length distribution (nil = uniform between 30s and Duration)
+	ChurnRate     float64   // Share of real-world viewers leaving per minute; sets the mean of Lifetime (0 = Lifetime's own)
+	TimeCompression float64 // Real-world simulated time per real time, e.g. 48 plays a day in 30m (0 = 1, wall clock)
+	DailyCurve    []float64 // Real-world load factor through the simulated day, spread evenly from midnight (nil = built-in peaks and valleys)
+	TargetCurve   []int     // Real-world target connections per simulated minute, looped (overrides AvgConnections and DailyCurve; see LoadTargetCSV)
+	Events        []TrafficEvent // Real-world bursts of extra viewers, e.g. a kickoff at T+10m
+	TrendInterval time.Duration  // Real-world: simulated time between shifts of the targets' popularity ranking (0 = fixed ranking; needs the zipf or weighted distribution)
+	TrendShare    float64        // Real-world: share of the targets rising to the top ranks at each shift (0 = DefaultTrendShare)
 	IncludeBadClients bool    // Include misbehaving clients
 	BadClientRatio    float64 // Ratio of bad clients (0.0-1.0)
+	BadClientWeights  map[string]float64 // Relative weight per bad client type name, e.g. {"SlowSender": 50, "GarbageSender": 10} (empty = uniform, unlisted types are never picked)
+	BadClientType     string  // Make every bad client this type (overrides BadClientWeights)
+	FuzzCorpusDir     string  // Save fuzzed requests the server dropped the connection on or answered 5xx to here (empty = log only)
+	Seed              int64   // Random seed for bad clients, reader behaviors, seek positions, impairment, target picks and real-world load and durations (0 = from the clock, logged at startup)
+	MaxBandwidthMbps  float64 // Aggregate receive cap across all connections (0 = unlimited)
+	DrainRate         float64 // Connections closed per second at the end of the run (0 = all at once)
+	MigrationRate     float64 // Readers per second moved off targets a target swap retired (0 = they stay until their session ends)
+	TargetsFile       string  // URL file, as read by LoadURLFile, re-read on SIGHUP to swap the target list mid-run (empty = disabled, not used in real-world mode)
+	Username          string  // RTSP credentials (override any in the URL)
+	Password          string
+	Assertions        string  // Pass/fail thresholds, e.g. "p95_connect_ms<500,loss_pct<0.1"
+	VerdictFile       string  // Write the JSON verdict here instead of stdout
+	Baseline          string  // Earlier run's JSON output to compare the final stats against (empty = no comparison)
+	Tolerances        string  // Allowed regressions against Baseline, e.g. "p95_connect_ms=10%,loss_pct=0.1" (empty = DefaultTolerances)
+	Dashboard         bool    // Full-screen live view instead of scrolling log output
+	Scenario          *Scenario    // Multi-phase plan (replaces Readers/Duration/Rate ramp when set)
+	ScenarioFile      string       // File Scenario was loaded from, re-read on SIGHUP to apply new phase reader counts and bad client ratios (empty = no reload)
+	Profile           *LoadProfile // Step/spike/sawtooth reader count over Duration (nil = constant)
+	FindMax           *FindMax     // Search for the maximum reader count meeting an SLO
+	Replay            *Replay      // Recorded viewer arrivals and departures to reproduce (see LoadReplay)
+	LogLevel          string  // debug, info (default), warn or error
+	JSONLogs          bool    // Structured JSON log lines instead of text
+	VerifyReaders     int     // Readers per URL that verify frames against a reference reader (0 = disabled, not used in real-world mode)
+	ControlAddr       string  // Serve the runtime control API here, e.g. ":8090" (empty = disabled, not used in real-world mode)
+	PauseRatio        float64       // Share of good readers that periodically PAUSE and resume (0.0-1.0)
+	PauseInterval     time.Duration // Playback time between pauses (default 30s)
+	PauseDuration     time.Duration // Time spent paused (default 5s)
+	SeekInterval      time.Duration // VOD: start good readers at a random position and re-seek this often (0 = disabled)
+	Publishers        int      // Synthetic H.264 sources pushed with ANNOUNCE/RECORD before readers start (0 = none, not used in real-world mode)
+	PublishURLs       []string // Where publishers record to (default: the reader URLs, round-robin)
+	PublishBitrateKbps int     // Publisher video bitrate (default 2000)
+	PublishPacketSize  int     // Publisher maximum RTP payload in bytes (default 1200)
+	PublishPayloadType int     // Publisher RTP payload type (default 96)
+	SourceIPs          []string // Local addresses or CIDR ranges connections rotate through (empty = kernel default)
+	BindInterface      string   // Bind every socket to this network interface (Linux only, empty = none)
+	IPFamily           string        // Dial only ipv4 or ipv6 server addresses (empty = either)
+	HappyEyeballsDelay time.Duration // Race IPv6 and IPv4 addresses, starting one every delay (0 = try in resolver order)
+	DNSMode            string        // per-connection (default), once or round-robin
+	DNSPin             string        // Only connect to this address among the hostname's records (empty = any)
+	TCPNagle           bool          // Clear TCP_NODELAY on control connections so small writes are coalesced (default: set, as Go does)
+	SocketRecvBuffer   int           // SO_RCVBUF of control connections and UDP media sockets in bytes (0 = kernel default, 2MB for UDP RTP)
+	SocketSendBuffer   int           // SO_SNDBUF of control connections and UDP media sockets in bytes (0 = kernel default)
+	TCPKeepAlive       time.Duration // Idle time before TCP keepalive probes on control connections (0 = Go's 15s, negative = off)
+	TCPKeepAliveInterval time.Duration // Between TCP keepalive probes (0 = TCPKeepAlive; Linux only)
+	TCPKeepAliveCount  int           // Unanswered TCP keepalive probes before a connection drops (0 = kernel default; Linux only)
+	UDPShards          int           // Receive every UDP reader's media on this many SO_REUSEPORT sockets sharing one port (0 = a socket pair per track; Linux only)
+	UDPShardWorkers    int           // Goroutines processing the shared sockets' packets (0 = GOMAXPROCS)
+	UDPShardPort       int           // Even RTP port of the shared sockets, RTCP on the next (0 = any free pair)
+	DSCP               int           // DSCP marking of packets readers send, 0-63, e.g. 34 (AF41) as video players use (0 = unmarked; Linux only)
+	Impairment         *impair.Model // Emulated bad network on good readers' received RTP and requests (nil = none)
+	ImpairRatio        float64       // Share of good readers that get Impairment (0 = all)
+	SlowReaderRatio    float64       // Share of good TCP readers that read at a capped rate (0.0-1.0)
+	SlowReaderKbps     int           // Slow reader read rate (default 500)
+	SlowReaderBuffer   int           // Slow reader socket receive buffer in bytes (0 = kernel default)
+	RTCPFeedback       rtsp.Feedback // NACK, PLI and BYE sent by good readers (zero = receiver reports only)
+	Reconnect          *ReconnectPolicy // Good readers reconnect after a session drops mid-stream (nil = never)
+	FollowServerRedirects bool       // Reconnect when the server sends REDIRECT during playback (default: acknowledge and ignore)
+	HopHeader          string // Header naming the node that answered, for per-node stats: server or via (empty = off, server if a node check is set)
+	ExpectVia          string // Regexp every response's Via chain must match, e.g. the proxies of a chain (empty = any)
+	ExpectServer       string // Regexp every response's Server header must match (empty = any)
+	CheckSticky        bool   // Flag sessions answered by another node than their first response came from
+	Transcripts        int    // Failures of each category whose RTSP exchange is saved to TranscriptDir (0 = disabled)
+	TranscriptDir      string // Where transcripts are written (default "transcripts")
+	TranscriptSize     int    // Control messages kept per connection for transcripts (default 50)
+	CaptureRatio       float64 // Share of good readers whose sessions are written to CaptureDir as pcap files (0 = none)
+	CaptureDir         string  // Where captures are written (default "captures")
+	CaptureLimit       int     // Most sessions captured per run (0 = no limit)
+	ArrivalRatio       float64 // Share of good readers whose packet arrivals are written to ArrivalDir (0 = none)
+	ArrivalDir         string  // Where arrival timelines are written (default "arrivals")
+	ArrivalLimit       int     // Most sessions logged per run (0 = no limit)
+	ArrivalBucket      int     // Packets of a track summarized per record (0 or 1 = a record per packet)
 }
 
 // Runner orchestrates the benchmark
 type Runner struct {
 	config     Config
 	aggregator *rtp.Aggregator
+	targets    *targetSet
+	startTime  time.Time
+	log        *slog.Logger
+	nextConnID atomic.Uint64
+	
+	// Reader count the pool is steering towards (scenario/profile modes)
+	targetReaders atomic.Int64
+	levelLatency  atomic.Pointer[histogram.Histogram] // Connect times at the current profile level
+	capacity      atomic.Int64                        // Result of the find-max search
+	control       *controlState                       // Runtime overrides from the control API
+	live          *liveFeed                           // Samples for the web UI on the control listener
+	scenario      atomic.Pointer[Scenario]            // Config.Scenario, as last reloaded
+	publishers    atomic.Pointer[publisherSet]        // Synthetic sources, nil if none
+	simulator     *RealWorldSimulator                 // Real-world mode's simulator, whose stats GetStats returns; set by Run
+	started       atomic.Bool                         // Run has finished setting up; GetStats reads nothing before
 	
 	// Statistics
 	activeConnects  atomic.Int64
+	peakActive      atomic.Int64
 	totalConnects   atomic.Int64
 	totalFailures   atomic.Int64
-	connectLatency  atomic.Int64 // cumulative milliseconds
-	connectCount    atomic.Int64
+	failures        failureStats // totalFailures by category
+	methods         methodStats  // Response codes and latency by request method
+	redirects       redirectStats
+	serverRequests  serverRequestStats // Requests servers sent during playback
+	reconnects      *reconnectStats
+	sessionTimeouts atomic.Int64 // Sessions dropped by the server for timing out
 	badClients      atomic.Int64 // Number of bad clients spawned
 	badClientTypes  sync.Map     // Track types of bad clients
 	
 	// Latency tracking
-	latencies      []float64
-	latenciesMu    sync.Mutex
-	minLatency     atomic.Int64
-	maxLatency     atomic.Int64
+	connectLatency *histogram.Histogram
+	responseLatency *histogram.Histogram // Connect times from the intended start, scheduling delay and retries included
+	teardowns      *teardownStats
+	lifetimes      *sessionLifetimes
+	families       *familyStats
+	slowReaders    *slowReaderStats
+	describes      *describeFloodStats
+	silent         *silentStats
+	parserProbes   parserProbeStats
+	fuzz           fuzzStats
+	reactions      reactionStats
+	groups         *groupSet
+	rates          *connRates
 	
 	// Control
 	limiter    *rate.Limiter
+	bwLimiter  *rate.Limiter // Shared bandwidth cap, nil if unlimited
+	drain      *drainer      // Ramp-down controller, nil if disabled
+	openLoop   *openLoop     // Open-loop attempt queue, nil for a closed loop; set by Run
+	schedule   *connectSchedule // Arrival times of fixed runs; set by Run
+	migrations *migrator     // Moves readers off targets retired by a swap
+	dialer     *dialer       // Source addresses, address families and DNS, nil if dialErr is set
+	dialErr    error         // Invalid dialing settings, reported by Run
+	transcripts *transcriptDumper // Saves failed exchanges; set by Run
+	captures   *captureSampler   // Sessions written to pcap files; set by Run
+	arrivals   *arrivalSampler   // Sessions whose packet arrivals are logged; set by Run
+	monitor    *HostMonitor      // Target host resources, nil if not monitored; set by Run
+	self       *SelfMonitor      // The bench process's own resources; set by Run
+	mediamtx   *mtxChecker       // Server-side session cross-check, nil if disabled; set by Run
+	hops       *hopStats         // Nodes answering readers, nil if not checked; set by Run
+	sharedUDP  *rtsp.SharedUDP   // UDP receive sockets shared by all readers, nil if none; set by Run
+	badPicker  *badClientPicker // Bad client type weights, nil = uniform; set by Run
+	players    *playerPicker    // Player profiles readers mimic, nil = none; set by Run
+	tracks     *trackPicker     // Track selections of readers, nil = all tracks; set by Run
+	qoe        *qoeScorer       // Session QoE scores; set by Run
+	random     *lockedRand      // Seeded from Config.Seed
 	semaphore  chan struct{}
 	wg         sync.WaitGroup
 }
@@ -68,6 +238,21 @@ func NewRunner(config Config, agg *rtp.Aggregator) *Runner {
 		burst = 100
 	}
 	
+	// Poisson and bursty arrivals pace themselves: the limiter only has to
+	// let their clusters and batches through, and slow them down on
+	// failures. A second of Poisson arrivals rarely exceeds twice the rate.
+	limit := config.Rate
+	switch config.ConnectArrivals {
+	case ArrivalsPoisson:
+		burst = max(burst, int(config.Rate))
+		limit = 2 * config.Rate
+	case ArrivalsBursty:
+		burst = max(burst, config.ConnectBatch)
+		if config.ConnectBatchGap > 0 {
+			limit = max(limit, float64(config.ConnectBatch)/config.ConnectBatchGap.Seconds())
+		}
+	}
+	
 	// Semaphore to limit concurrent connection attempts
 	// This prevents overwhelming the system during ramp-up
 	maxConcurrent := 10000
@@ -77,29 +262,265 @@ func NewRunner(config Config, agg *rtp.Aggregator) *Runner {
 			maxConcurrent = 50000
 		}
 	}
+	if config.LoadModel == LoadModelOpen && config.Readers > maxConcurrent {
+		maxConcurrent = config.Readers // An open loop never waits for a slot
+	}
 	
+	log := newLogger(config)
+	dialer, dialErr := newDialer(config)
 	r := &Runner{
-		config:     config,
-		aggregator: agg,
-		limiter:    rate.NewLimiter(rate.Limit(config.Rate), burst),
-		semaphore:  make(chan struct{}, maxConcurrent),
-		latencies:  make([]float64, 0, 1000),
-	}
-	r.minLatency.Store(99999999)
-	r.maxLatency.Store(0)
+		config:         config,
+		aggregator:     agg,
+		targets:        newTargetSet(config, agg),
+		limiter:        rate.NewLimiter(rate.Limit(limit), burst),
+		bwLimiter:      newBandwidthLimiter(config.MaxBandwidthMbps),
+		drain:          newDrainer(config.DrainRate, log),
+		migrations:     newMigrator(config.MigrationRate, log),
+		dialer:         dialer,
+		dialErr:        dialErr,
+		semaphore:      make(chan struct{}, maxConcurrent),
+		connectLatency: histogram.New(),
+		responseLatency: histogram.New(),
+		teardowns:      newTeardownStats(),
+		lifetimes:      newSessionLifetimes(),
+		families:       &familyStats{},
+		slowReaders:    newSlowReaderStats(),
+		reconnects:     newReconnectStats(),
+		describes:      newDescribeFloodStats(),
+		silent:         newSilentStats(),
+		groups:         newGroupSet(),
+		rates:          newConnRates(),
+		control:        &controlState{},
+		live:           newLiveFeed(),
+		random:         newLockedRand(config.Seed),
+		log:            log,
+	}
+	r.targets.random = r.random
+	r.scenario.Store(config.Scenario)
 	return r
 }
 
-// Run executes the benchmark
+// newLogger creates the run logger from Config.LogLevel and Config.JSONLogs.
+// An invalid level falls back to info; Run reports it as an error.
+func newLogger(config Config) *slog.Logger {
+	log, err := logging.New(logging.Options{Level: config.LogLevel, JSON: config.JSONLogs})
+	if err != nil {
+		log, _ = logging.New(logging.Options{JSON: config.JSONLogs})
+	}
+	return log
+}
+
+// newBandwidthLimiter creates a byte-rate token bucket for the given cap.
+// Returns nil when mbps is not positive (unlimited).
+func newBandwidthLimiter(mbps float64) *rate.Limiter {
+	if mbps <= 0 {
+		return nil
+	}
+	
+	bytesPerSec := mbps * 1_000_000 / 8
+	
+	// Burst must cover the largest single read (64KB UDP / interleaved frame)
+	burst := int(bytesPerSec / 10)
+	if burst < 128*1024 {
+		burst = 128 * 1024
+	}
+	
+	return rate.NewLimiter(rate.Limit(bytesPerSec), burst)
+}
+
+// Run executes the benchmark. If Config.Assertions is set, the final
+// statistics are checked afterwards and ErrThresholdsFailed is returned
+// when any assertion fails.
 func (r *Runner) Run(ctx context.Context) error {
+	if _, err := logging.ParseLevel(r.config.LogLevel); err != nil {
+		return err
+	}
+	assertions, err := ParseAssertions(r.config.Assertions)
+	if err != nil {
+		return err
+	}
+	comparison, err := newComparison(r.config)
+	if err != nil {
+		return err
+	}
+	if r.dialErr != nil {
+		return r.dialErr
+	}
+	if r.badPicker, err = newBadClientPicker(r.config); err != nil {
+		return err
+	}
+	if r.openLoop, err = newOpenLoop(r.config); err != nil {
+		return err
+	}
+	if r.schedule, err = newConnectSchedule(r.config, r.random); err != nil {
+		return err
+	}
+	if r.players, err = newPlayerPicker(r.config); err != nil {
+		return err
+	}
+	if r.tracks, err = newTrackPicker(r.config); err != nil {
+		return err
+	}
+	if r.qoe, err = newQoEScorer(r.config, r.rates); err != nil {
+		return err
+	}
+	if r.transcripts, err = newTranscriptDumper(r.config); err != nil {
+		return err
+	}
+	if r.captures, err = newCaptureSampler(r.config, r.random); err != nil {
+		return err
+	}
+	if r.arrivals, err = newArrivalSampler(r.config, r.random); err != nil {
+		return err
+	}
+	if r.mediamtx, err = newMTXChecker(r.config, r.log); err != nil {
+		return err
+	}
+	if r.hops, err = newHopStats(r.config); err != nil {
+		return err
+	}
+	if r.sharedUDP, err = newSharedUDP(r.config, r.dialer); err != nil {
+		return err
+	}
+	if r.sharedUDP != nil {
+		defer r.sharedUDP.Close()
+		r.log.Info("shared UDP sockets", "port", r.sharedUDP.Port(), "sockets", r.config.UDPShards)
+	}
+	if r.config.Reconnect != nil {
+		if err := r.config.Reconnect.validate(); err != nil {
+			return err
+		}
+	}
+	if r.config.Seed == 0 {
+		r.log.Info("random seed", "seed", r.random.seed)
+	}
+	if r.config.RunID == "" {
+		r.config.RunID = NewRunID()
+		r.log.Info("run ID", "run_id", r.config.RunID)
+	}
+	r.monitor = NewHostMonitor(r.config, r.log)
+	monitorCtx, stopMonitor := context.WithCancel(ctx)
+	defer stopMonitor()
+	go r.monitor.Run(monitorCtx)
+	r.self = NewSelfMonitor(r.config, r.log)
+	selfDone := make(chan struct{})
+	go func() {
+		defer close(selfDone)
+		r.self.Run(monitorCtx)
+	}()
+	defer func() {
+		stopMonitor()
+		<-selfDone // Let the last profiles be written
+	}()
+	if r.mediamtx != nil {
+		r.mediamtx.rates = r.rates
+	}
+	if r.dialer.sources != nil {
+		r.log.Info("binding connections", "source_ips", r.dialer.sources.size(), "interface", r.config.BindInterface)
+	}
+	if o := r.dialer.sockOpts; o != (rtsp.SocketOptions{}) {
+		r.log.Info("socket options", "nagle", o.Nagle, "rcvbuf", o.RecvBuffer, "sndbuf", o.SendBuffer,
+			"keepalive", o.KeepAliveIdle, "keepalive_interval", o.KeepAliveInterval, "keepalive_count", o.KeepAliveCount, "dscp", o.DSCP)
+	}
+	
 	// Check if real-world mode is enabled
+	getStats := r.GetStats
 	if r.config.RealWorld {
 		simulator := NewRealWorldSimulator(r.config, r.aggregator)
-		return simulator.Run(ctx)
+		simulator.log = r.log
+		simulator.dialer = r.dialer
+		simulator.transcripts = r.transcripts
+		simulator.captures = r.captures
+		simulator.arrivals = r.arrivals
+		simulator.monitor = r.monitor
+		simulator.self = r.self
+		simulator.mediamtx = r.mediamtx
+		simulator.hops = r.hops
+		simulator.sharedUDP = r.sharedUDP
+		simulator.players = r.players
+		simulator.tracks = r.tracks
+		simulator.qoe = r.qoe
+		simulator.qoe.rates = simulator.rates
+		if r.mediamtx != nil {
+			r.mediamtx.rates = simulator.rates
+		}
+		simulator.random = r.random
+		simulator.targets.random = r.random
+		r.simulator = simulator
+		r.started.Store(true)
+		r.watchDumpSignal(ctx, getStats, simulator.rates)
+		err = r.runWithOutput(ctx, getStats, simulator.Run)
+	} else {
+		runCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		stopControl, err := r.startControl(stop)
+		if err != nil {
+			return err
+		}
+		defer stopControl()
+		r.watchTargetsFile(runCtx)
+		r.watchScenarioFile(runCtx)
+		r.watchDumpSignal(runCtx, getStats, r.rates)
+		go r.migrations.run(runCtx)
+		
+		// Sources first, so readers find the streams; stopped after them
+		publishers := r.startPublishers(runCtx)
+		r.publishers.Store(publishers)
+		defer publishers.stop()
+		
+		r.startTime = time.Now()
+		r.started.Store(true)
+		go r.rates.run(runCtx)
+		if r.config.Scenario != nil {
+			err = r.runWithOutput(runCtx, getStats, r.runScenario)
+		} else if r.config.Replay != nil {
+			err = r.runWithOutput(runCtx, getStats, r.runReplay)
+		} else if r.config.FindMax != nil {
+			err = r.runWithOutput(runCtx, getStats, r.runFindMax)
+		} else if r.config.Profile != nil {
+			err = r.runWithOutput(runCtx, getStats, r.runProfile)
+		} else if r.config.ControlAddr != "" {
+			err = r.runWithOutput(runCtx, getStats, r.runControlled)
+		} else {
+			err = r.runWithOutput(runCtx, getStats, r.runFixed)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
 	}
 	
-	fmt.Printf("[%s] Starting benchmark: %d readers at %.1f/sec\n",
-		time.Now().Format("15:04:05"), r.config.Readers, r.config.Rate)
+	return checkThresholds(r.config, r.log, assertions, comparison, getStats())
+}
+
+// runWithOutput runs the benchmark with the stats reporter and dashboard
+// active, writing the summary when it finishes
+func (r *Runner) runWithOutput(ctx context.Context, getStats func() Stats, run func(context.Context) error) error {
+	finish, err := startReporter(ctx, r.config, r.log, getStats)
+	if err != nil {
+		return err
+	}
+	defer finish()
+	stopDashboard, err := startDashboard(r.config, getStats)
+	if err != nil {
+		return err
+	}
+	defer stopDashboard()
+	checkCtx, stopCheck := context.WithCancel(ctx)
+	go r.mediamtx.run(checkCtx)
+	
+	err = run(ctx)
+	stopCheck()
+	r.mediamtx.finish()
+	return err
+}
+
+// runFixed runs the fixed reader count benchmark
+func (r *Runner) runFixed(ctx context.Context) error {
+	r.log.Info("starting benchmark", "readers", r.config.Readers, "rate", r.config.Rate,
+		"transport", r.config.Transport, "targets", len(r.targets.all()))
 	
 	// Create a context that we can cancel
 	runCtx, cancel := context.WithCancel(ctx)
@@ -107,13 +528,22 @@ func (r *Runner) Run(ctx context.Context) error {
 	
 	// Start connection spawner
 	r.wg.Add(1)
-	go r.spawnConnections(runCtx)
+	if r.openLoop != nil {
+		go r.spawnOpenLoop(runCtx)
+	} else {
+		go r.spawnConnections(runCtx)
+	}
 	
 	// Wait for completion or cancellation
 	<-runCtx.Done()
 	
+	// Ramp down gradually instead of a TEARDOWN storm
+	if r.drain != nil {
+		r.drain.run()
+	}
+	
 	// Wait for all connections to finish
-	fmt.Printf("[%s] Waiting for connections to close...\n", time.Now().Format("15:04:05"))
+	r.log.Info("waiting for connections to close", "active", r.activeConnects.Load())
 	r.wg.Wait()
 	
 	return nil
@@ -126,6 +556,8 @@ func (r *Runner) spawnConnections(ctx context.Context) {
 	connectionsCreated := 0
 	lastCheck := time.Now()
 	lastFailures := int64(0)
+	fullRate := r.limiter.Limit()
+	start := time.Now()
 	
 	for connectionsCreated < r.config.Readers {
 		// Check for cancellation
@@ -133,6 +565,13 @@ func (r *Runner) spawnConnections(ctx context.Context) {
 			return
 		}
 		
+		// The attempt is due on the arrival process's schedule, however
+		// long the limiter and semaphore hold it back
+		intended := start.Add(r.schedule.nextArrival())
+		if r.schedule.paced() && !waitUntil(ctx, intended) {
+			return
+		}
+		
 		// Adaptive rate limiting - check every 10 connections
 		if connectionsCreated > 0 && connectionsCreated%10 == 0 {
 			now := time.Now()
@@ -149,17 +588,16 @@ func (r *Runner) spawnConnections(ctx context.Context) {
 						newRate = 1
 					}
 					r.limiter.SetLimit(newRate)
-					fmt.Printf("[%s] High failure rate detected (%d/%d), reducing rate to %.1f/s\n",
-						time.Now().Format("15:04:05"), failureDelta, totalDelta, float64(newRate))
-				} else if failureDelta == 0 && r.limiter.Limit() < rate.Limit(r.config.Rate) {
+					r.log.Warn("high failure rate detected, reducing rate",
+						"failures", failureDelta, "window", totalDelta, "rate", float64(newRate))
+				} else if failureDelta == 0 && r.limiter.Limit() < fullRate {
 					// If no failures and we're below target rate, increase by 20%
 					newRate := r.limiter.Limit() * 1.2
-					if newRate > rate.Limit(r.config.Rate) {
-						newRate = rate.Limit(r.config.Rate)
+					if newRate > fullRate {
+						newRate = fullRate
 					}
 					r.limiter.SetLimit(newRate)
-					fmt.Printf("[%s] Success rate good, increasing rate to %.1f/s\n",
-						time.Now().Format("15:04:05"), float64(newRate))
+					r.log.Info("success rate good, increasing rate", "rate", float64(newRate))
 				}
 				
 				lastCheck = now
@@ -181,39 +619,91 @@ func (r *Runner) spawnConnections(ctx context.Context) {
 		
 		// Spawn connection - decide if it should be a bad client
 		r.wg.Add(1)
-		if r.config.IncludeBadClients && rand.Float64() < r.config.BadClientRatio {
-			go r.runBadClient(ctx)
+		s := r.defaultSession()
+		s.intended = intended
+		if r.config.IncludeBadClients && r.random.Float64() < r.config.BadClientRatio {
+			go r.runBadClient(ctx, s)
 		} else {
-			go r.runConnection(ctx)
+			go r.runConnection(ctx, s)
 		}
 		
 		connectionsCreated++
 		
 		// Log progress every 100 connections initially, then every 1000
-		if connectionsCreated <= 1000 && connectionsCreated%100 == 0 {
-			fmt.Printf("[%s] Spawned %d connections\n", 
-				time.Now().Format("15:04:05"), connectionsCreated)
-		} else if connectionsCreated%1000 == 0 {
-			fmt.Printf("[%s] Spawned %d connections\n",
-				time.Now().Format("15:04:05"), connectionsCreated)
+		if (connectionsCreated <= 1000 && connectionsCreated%100 == 0) || connectionsCreated%1000 == 0 {
+			r.log.Info("spawned connections", "count", connectionsCreated)
 		}
 	}
 	
-	fmt.Printf("[%s] Finished spawning %d connections\n",
-		time.Now().Format("15:04:05"), connectionsCreated)
+	r.log.Info("finished spawning connections", "count", connectionsCreated)
+}
+
+// session holds per-connection settings that may differ from Config
+type session struct {
+	transport string
+	managed   bool // Runs until ctx is cancelled (reader pool) instead of for Config.Duration
+	connected func() // Called once the connect attempt succeeds or fails (nil = none)
+	intended  time.Time // When the attempt should have started, had nothing held it back (zero = when it did)
+}
+
+// defaultSession returns the session settings for fixed-count runs
+func (r *Runner) defaultSession() session {
+	return session{transport: r.config.Transport}
 }
 
 // runConnection manages a single RTSP connection
-func (r *Runner) runConnection(ctx context.Context) {
+func (r *Runner) runConnection(ctx context.Context, s session) {
 	defer r.wg.Done()
 	defer func() { <-r.semaphore }() // Release semaphore slot
 	
-	// Retry logic for connection establishment
-	const maxRetries = 3
+	// Retry logic for connection establishment; in an open loop each
+	// arrival is a single attempt
+	maxRetries := 3
+	if r.openLoop != nil {
+		maxRetries = 1
+	}
+	attemptDone := func() {
+		if s.connected != nil {
+			s.connected()
+			s.connected = nil
+		}
+	}
+	defer attemptDone()
+	if began := time.Now(); s.intended.IsZero() || s.intended.After(began) {
+		s.intended = began // Started early (limiter burst) or unscheduled
+	}
 	var client *rtsp.Client
 	var err error
 	var connectDuration time.Duration
 	
+	// Distribute readers across target URLs
+	t := r.targets.pick()
+	reference, verify := t.verificationRole(r.config.VerifyReaders)
+	behavior := newViewer(r.config, r.random)
+	behavior.player = r.players.pick(r.random)
+	behavior.tracks = r.tracks.pick(r.random)
+	connID := strconv.FormatUint(r.nextConnID.Add(1), 10)
+	log := r.log.With("conn", connID, "target", t.url)
+	
+	// Every client of the reader, reconnects included, goes into its capture
+	// and arrival log
+	capture := r.captures.open(connID, log)
+	if capture != nil {
+		defer capture.Close()
+	}
+	arrivals := r.arrivals.open(connID, log)
+	if arrivals != nil {
+		defer arrivals.Close()
+	}
+	newClient := func() (*rtsp.Client, error) {
+		client, err := r.newReader(connID, t, s, reference, verify, behavior)
+		if err == nil {
+			client.SetCapture(capture)
+			client.SetArrivalLog(arrivals)
+		}
+		return client, err
+	}
+	
 	for retry := 0; retry < maxRetries; retry++ {
 		// Check if context is cancelled
 		if ctx.Err() != nil {
@@ -222,10 +712,13 @@ func (r *Runner) runConnection(ctx context.Context) {
 		
 		// Create client
 		startTime := time.Now()
-		client, err = rtsp.NewClient(r.config.URL, r.config.Transport, r.aggregator)
+		client, err = newClient()
 		if err != nil {
 			if retry == maxRetries-1 {
+				log.Warn("client creation failed", "error", err)
 				r.totalFailures.Add(1)
+				r.failures.record(rtsp.FailureOther)
+				t.failures.Add(1)
 				return
 			}
 			// Exponential backoff: 100ms, 200ms, 400ms
@@ -234,11 +727,19 @@ func (r *Runner) runConnection(ctx context.Context) {
 		}
 		
 		// Connect
-		if err = client.Connect(); err != nil {
+		err = client.Connect()
+		r.families.record(client.DialAttempts())
+		if err != nil {
 			if retry == maxRetries-1 {
+				category := rtsp.ClassifyFailure(err, false)
+				log.Warn("connect failed", "error", err, "category", category, "attempts", maxRetries)
 				r.totalFailures.Add(1)
+				r.failures.record(category)
+				t.failures.Add(1)
+				r.transcripts.dump(client, category, err, t.url, log)
 				return
 			}
+			log.Debug("connect failed, retrying", "error", err, "attempt", retry+1)
 			// Exponential backoff
 			time.Sleep(time.Duration(100*(1<<retry)) * time.Millisecond)
 			continue
@@ -248,60 +749,239 @@ func (r *Runner) runConnection(ctx context.Context) {
 		connectDuration = time.Since(startTime)
 		break
 	}
+	attemptDone()
 	
-	// Track connection time
-	latencyMs := connectDuration.Milliseconds()
-	r.connectLatency.Add(int64(latencyMs))
-	r.connectCount.Add(1)
-	
-	// Update min/max
-	for {
-		oldMin := r.minLatency.Load()
-		if int64(latencyMs) >= oldMin || r.minLatency.CompareAndSwap(oldMin, int64(latencyMs)) {
-			break
-		}
-	}
-	for {
-		oldMax := r.maxLatency.Load()
-		if int64(latencyMs) <= oldMax || r.maxLatency.CompareAndSwap(oldMax, int64(latencyMs)) {
-			break
-		}
-	}
-	
-	// Store for percentile calculation
-	r.latenciesMu.Lock()
-	if len(r.latencies) < 10000 { // Limit memory usage
-		r.latencies = append(r.latencies, float64(latencyMs))
+	// Track connection time: the server's service time, and the response
+	// time a viewer arriving on schedule would have seen
+	r.connectLatency.Record(connectDuration)
+	r.responseLatency.Record(time.Since(s.intended))
+	t.connectLatency.Record(connectDuration)
+	if level := r.levelLatency.Load(); level != nil {
+		level.Record(connectDuration)
 	}
-	r.latenciesMu.Unlock()
 	
 	// Update counters
 	r.totalConnects.Add(1)
-	r.activeConnects.Add(1)
+	t.connects.Add(1)
+	trackPeak(&r.peakActive, r.activeConnects.Add(1))
 	defer r.activeConnects.Add(-1)
+	t.active.Add(1)
+	defer func() { t.active.Add(-1) }()
+	r.rates.add(connID, t.url, s.transport, behavior.tracks, client)
+	defer func() { r.rates.remove(connID) }()
 	
-	// Create context with duration timeout
-	runCtx, cancel := context.WithTimeout(ctx, r.config.Duration)
+	// moveTo makes nt the reader's target, for the clients it creates next
+	moveTo := func(nt *target) {
+		t.active.Add(-1)
+		t = nt
+		t.active.Add(1)
+		reference, verify = t.verificationRole(r.config.VerifyReaders)
+		log = r.log.With("conn", connID, "target", t.url)
+	}
+	
+	// Create context with duration timeout; when draining, sessions
+	// outlive the run context until the drain closes them
+	var runCtx context.Context
+	var cancel func()
+	if s.managed {
+		runCtx, cancel = context.WithCancel(ctx)
+	} else if r.drain != nil {
+		runCtx, cancel = r.drain.session(r.config.Duration)
+	} else {
+		runCtx, cancel = context.WithTimeout(ctx, r.config.Duration)
+	}
 	defer cancel()
 	
-	// Run the session
-	if err := client.Run(runCtx); err != nil && err != context.DeadlineExceeded && err != context.Canceled {
+	// Run the session; after a drop mid-stream, reconnect as players do
+	policy := r.config.Reconnect
+	var lost time.Time          // When the session dropped, while reconnecting
+	var position time.Duration // Where recorded media resumes
+	var left time.Time         // When the reader left a retired target
+	attempt := 0
+	for {
+		start := time.Now()
+		sessionCtx, release := r.migrations.watch(runCtx, connID, t)
+		err = client.Run(sessionCtx)
+		moving := release() && runCtx.Err() == nil
+		if !lost.IsZero() && client.Playing() {
+			r.reconnects.recovered.Add(1)
+			r.reconnects.recovery.Record(client.PlayTime().Sub(lost))
+			lost, attempt = time.Time{}, 0
+		}
+		if !left.IsZero() {
+			r.migrations.landed(client, left)
+			left = time.Time{}
+		}
+		r.sessionEnded(client, err, t, s, log)
+		r.qoe.record(client, behavior.tracks)
+		r.lifetimes.record(runCtx, client, err, start)
+		if client.Playing() {
+			position = client.Position()
+		}
+		
+		if moving {
+			// The target was retired by a swap: carry on at a current one
+			left = time.Now()
+			r.migrations.moved.Add(1)
+			moveTo(r.targets.pick())
+			if client = r.connectMoved(t, newClient, log); client == nil {
+				r.migrations.failed.Add(1)
+				return
+			}
+			client.SetStartPosition(position)
+			r.rates.remove(connID)
+			r.rates.add(connID, t.url, s.transport, behavior.tracks, client)
+			continue
+		}
+		
+		failed := err != nil && err != context.DeadlineExceeded && err != context.Canceled
+		if !failed || policy == nil || policy.MaxAttempts == 0 || runCtx.Err() != nil {
+			return
+		}
+		if lost.IsZero() {
+			if !client.Playing() {
+				return // Never played: a failed connect, not a drop
+			}
+			lost = time.Now()
+		}
+		if t.retired.Load() {
+			moveTo(r.targets.pick()) // Reconnect where the list now points
+		}
+		
+		if client = r.reconnect(runCtx, t, policy, &attempt, newClient, log); client == nil {
+			return
+		}
+		client.SetStartPosition(position)
+		r.rates.remove(connID)
+		r.rates.add(connID, t.url, s.transport, behavior.tracks, client)
+	}
+}
+
+// newReader creates a good reader's client for t with the run's settings
+func (r *Runner) newReader(connID string, t *target, s session, reference, verify bool, behavior viewer) (*rtsp.Client, error) {
+	client, err := rtsp.NewClient(t.url, readerTransport(s.transport, behavior.player), t.aggregator)
+	if err != nil {
+		return nil, err
+	}
+	client.Seed(r.random.Int63())
+	client.SetBandwidthLimiter(r.bwLimiter)
+	client.SetGroupTracker(r.groups)
+	client.SetResponseRecorder(&r.methods)
+	client.SetFollowServerRedirects(r.config.FollowServerRedirects)
+	r.hops.apply(client, r.log.With("conn", connID, "target", t.url))
+	client.SetSharedUDP(r.sharedUDP)
+	r.dialer.apply(client)
+	r.transcripts.apply(client)
+	applyIdentity(client, r.config, connID, behavior.player)
+	if r.config.FallbackTimeout > 0 {
+		client.SetFallbackTimeout(r.config.FallbackTimeout)
+	}
+	if r.config.StallThreshold > 0 {
+		client.SetStallThreshold(r.config.StallThreshold)
+	}
+	client.SetKeepAliveRetry(r.config.KeepAliveAttempts, r.config.KeepAliveBackoff)
+	client.SetResume(r.config.ResumeAttempts, r.config.ResumeBackoff)
+	client.SetTimeouts(r.config.RequestTimeout, r.config.MessageTimeout)
+	if r.config.Username != "" {
+		client.SetCredentials(r.config.Username, r.config.Password)
+	}
+	if reference {
+		client.SetReference(t.reference)
+	} else if verify {
+		client.SetVerifier(t.reference)
+	}
+	behavior.apply(client)
+	return client, nil
+}
+
+// reconnect waits out the policy's backoff and connects a new client,
+// until one connects or attempt passes MaxAttempts. Returns nil if the
+// reader gives up or ctx ends.
+func (r *Runner) reconnect(ctx context.Context, t *target, policy *ReconnectPolicy, attempt *int,
+	newClient func() (*rtsp.Client, error), log *slog.Logger) *rtsp.Client {
+	for {
+		*attempt++
+		if *attempt > policy.MaxAttempts {
+			r.reconnects.exhausted.Add(1)
+			log.Warn("reader gave up reconnecting", "attempts", policy.MaxAttempts)
+			return nil
+		}
+		wait := time.NewTimer(policy.delay(*attempt, r.random))
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return nil
+		case <-wait.C:
+		}
+		
+		r.reconnects.attempt()
+		log.Debug("reconnecting", "attempt", *attempt)
+		client, err := newClient()
+		if err != nil {
+			r.totalFailures.Add(1)
+			r.failures.record(rtsp.FailureOther)
+			t.failures.Add(1)
+			continue
+		}
+		start := time.Now()
+		err = client.Connect()
+		r.families.record(client.DialAttempts())
+		if err != nil {
+			category := rtsp.ClassifyFailure(err, false)
+			log.Warn("reconnect failed", "error", err, "category", category, "attempt", *attempt)
+			r.totalFailures.Add(1)
+			r.failures.record(category)
+			t.failures.Add(1)
+			r.transcripts.dump(client, category, err, t.url, log)
+			continue
+		}
+		r.reconnects.connect.Record(time.Since(start))
+		r.totalConnects.Add(1)
+		t.connects.Add(1)
+		return client
+	}
+}
+
+// sessionEnded records the outcome of a good reader's session
+func (r *Runner) sessionEnded(client *rtsp.Client, err error, t *target, s session, log *slog.Logger) {
+	if err != nil && err != context.DeadlineExceeded && err != context.Canceled {
 		// Only count as failure if it's not a normal timeout/cancel
+		category := rtsp.ClassifyFailure(err, client.Playing())
 		r.totalFailures.Add(1)
+		r.failures.record(category)
+		t.failures.Add(1)
+		if errors.Is(err, rtsp.ErrSessionExpired) {
+			r.sessionTimeouts.Add(1)
+		}
+		log.Warn("session failed", "error", err, "category", category)
+		r.transcripts.dump(client, category, err, t.url, log)
+	}
+	r.slowReaders.record(client.SlowReadResult(), err, log)
+	r.redirects.record(client.Redirects(), err)
+	r.serverRequests.record(client.ServerRequests(), client.ServerRedirects())
+	
+	teardown := client.LastTeardown()
+	log.Debug("session closed", "teardown_status", teardown.Status, "teardown_ms",
+		float64(teardown.Latency.Microseconds())/1000)
+	r.teardowns.record(teardown)
+	if r.drain != nil && !s.managed {
+		r.drain.recordTeardown(teardown)
 	}
 }
 
 // runBadClient manages a single misbehaving RTSP client
-func (r *Runner) runBadClient(ctx context.Context) {
+func (r *Runner) runBadClient(ctx context.Context, s session) {
 	defer r.wg.Done()
 	defer func() { <-r.semaphore }() // Release semaphore slot
 	
 	// Create bad client
-	badClient := rtsp.NewBadClient(r.config.URL)
+	t := r.targets.pick()
+	badClient := r.badPicker.newClient(t.url, r.random)
+	badClient.SetFuzzCorpus(r.config.FuzzCorpusDir)
 	
 	// Track bad client statistics
 	r.badClients.Add(1)
-	r.activeConnects.Add(1)
+	trackPeak(&r.peakActive, r.activeConnects.Add(1))
 	defer r.activeConnects.Add(-1)
 	
 	// Track bad client type
@@ -313,53 +993,327 @@ func (r *Runner) runBadClient(ctx context.Context) {
 	}
 	
 	// Create context with duration timeout
-	runCtx, cancel := context.WithTimeout(ctx, r.config.Duration)
+	var runCtx context.Context
+	var cancel func()
+	if s.managed {
+		runCtx, cancel = context.WithCancel(ctx)
+	} else {
+		runCtx, cancel = context.WithTimeout(ctx, r.config.Duration)
+	}
 	defer cancel()
 	
 	// Run the bad client (errors are expected and ignored)
-	_ = badClient.Run(runCtx)
+	err := badClient.Run(runCtx)
+	log := r.log.With("conn", r.nextConnID.Add(1), "target", t.url, "bad_client_type", typeName)
+	log.Debug("bad client finished", "error", err)
+	r.reactions.record(typeName, badClient.Reactions())
+	if res, ok := badClient.DescribeFlood(); ok {
+		r.describes.record(res, log)
+	}
+	if res, ok := badClient.Idle(); ok {
+		r.silent.record(res, log)
+	}
+	if res, ok := badClient.ParserProbes(); ok {
+		r.parserProbes.record(res, log)
+	}
+	if res, ok := badClient.Fuzz(); ok {
+		r.fuzz.record(res, log)
+	}
 }
 
 // Stats represents current benchmark statistics
 type Stats struct {
-	ActiveConnects  int64
-	TotalConnects   int64
-	TotalFailures   int64
-	TargetConnects  int64   // For real-world mode
-	AvgConnectTime  float64 // milliseconds
-	MinConnectTime  float64 // milliseconds
-	MaxConnectTime  float64 // milliseconds
-	P95ConnectTime  float64 // milliseconds
-	RTPPackets      uint64
-	RTPLoss         uint64
-	RTPBytes        uint64
-	BadClients      int64   // Number of bad clients
-	BadClientTypes  map[string]int64 // Count by type
+	ActiveConnects  int64            `json:"active"`
+	PeakActive      int64            `json:"peak_active"`       // Most connections active at once (summed across agents)
+	TotalConnects   int64            `json:"connects"`
+	TotalFailures   int64            `json:"failures"`
+	FailureCategories map[string]int64 `json:"failure_categories,omitempty"` // Failures by cause: dns, connect_timeout, refused, tls, rtsp_4xx, rtsp_5xx, response_timeout, stream, other
+	Methods         map[string]MethodStats `json:"methods,omitempty"` // Reader responses by method (keepalive separate): status codes and latency
+	SessionTimeouts int64            `json:"session_timeouts"`  // Sessions dropped by the server for timing out
+	Redirects          int64         `json:"redirects"`               // 3xx redirects followed during handshakes
+	RedirectedSessions int64         `json:"redirected_sessions"`     // Sessions redirected at least once
+	RedirectsExceeded  int64         `json:"redirects_exceeded"`      // Sessions that gave up after rtsp.MaxRedirects
+	ServerRequests     map[string]int64 `json:"server_requests,omitempty"` // Requests servers sent readers during playback, by method
+	ServerRedirects    int64         `json:"server_redirects"`        // Server REDIRECT requests readers followed
+	Nodes              map[string]NodeStats `json:"nodes,omitempty"`  // Reader responses by the node that answered (HopHeader): counts and latency
+	UnexpectedNodeResponses int64    `json:"unexpected_node_responses"` // Responses failing ExpectVia or ExpectServer
+	StickyViolations   int64         `json:"sticky_violations"`       // Responses from another node than their session's first
+	UnstickySessions   int64         `json:"unsticky_sessions"`       // Sessions answered by more than one node
+	SharedUDPPackets   uint64        `json:"shared_udp_packets"`      // RTP and RTCP packets received on the shared UDP sockets
+	SharedUDPUnrouted  uint64        `json:"shared_udp_unrouted"`     // Shared UDP packets no reader was expecting
+	SharedUDPBusiestPct float64      `json:"shared_udp_busiest_pct"`  // Busiest shared RTP socket's share of the RTP packets
+	SharedUDPFanoutMax int64         `json:"shared_udp_fanout_max"`   // Most readers sharing one route: the same stream from the same server port
+	Events             map[string]EventStats `json:"events,omitempty"` // Real-world traffic events by name: viewer outcomes and connect latency
+	Reconnects          int64   `json:"reconnects"`                // Reconnect attempts after sessions dropped mid-stream
+	ReconnectsRecovered int64   `json:"reconnects_recovered"`      // Drops after which a reconnect played again
+	ReconnectsExhausted int64   `json:"reconnects_exhausted"`      // Readers that gave up after the policy's attempts
+	ReconnectPeakRate   int64   `json:"reconnect_peak_per_s"`      // Most reconnect attempts in one second
+	ReconnectConnectAvg float64 `json:"avg_reconnect_connect_ms"`  // Connect time of reconnects
+	ReconnectConnectP95 float64 `json:"p95_reconnect_connect_ms"`
+	RecoveryAvg         float64 `json:"avg_recovery_ms"`           // From a drop to playing again
+	RecoveryP95         float64 `json:"p95_recovery_ms"`
+	TargetSwaps         int64   `json:"target_swaps"`              // Target list swaps through the control API or SIGHUP
+	Migrations          int64   `json:"migrations"`                // Readers moved off retired targets
+	MigrationsFailed    int64   `json:"migrations_failed"`         // Moved readers that did not play again
+	MigrationGapAvg     float64 `json:"migration_gap_avg_ms"`      // From leaving a retired target to PLAY on the new one
+	MigrationGapP95     float64 `json:"migration_gap_p95_ms"`
+	MigrationGapMax     float64 `json:"migration_gap_max_ms"`
+	RetiredActive       int64   `json:"retired_active"`            // Readers still on retired targets
+	PopularityShifts    int64   `json:"popularity_shifts"`         // Real-world: shifts of the targets' popularity ranking
+	TargetStarts        int64   `json:"target_starts"`             // Real-world: targets gaining their first viewer (an origin pull on a lazy server)
+	TargetStops         int64   `json:"target_stops"`              // Real-world: targets losing their last viewer
+	LiveTargets         int64   `json:"live_targets"`              // Real-world: targets with viewers now
+	TargetConnects  int64            `json:"target"`            // Real-world, scenario and profile modes
+	AvgConnectTime  float64          `json:"avg_connect_ms"`    // milliseconds
+	MinConnectTime  float64          `json:"min_connect_ms"`    // milliseconds
+	MaxConnectTime  float64          `json:"max_connect_ms"`    // milliseconds
+	P50ConnectTime  float64          `json:"p50_connect_ms"`    // milliseconds
+	P90ConnectTime  float64          `json:"p90_connect_ms"`    // milliseconds
+	P95ConnectTime  float64          `json:"p95_connect_ms"`    // milliseconds
+	P99ConnectTime  float64          `json:"p99_connect_ms"`    // milliseconds
+	P999ConnectTime float64          `json:"p999_connect_ms"`   // milliseconds
+	ResponseConnectAvg  float64      `json:"avg_response_connect_ms"` // Connect time from the intended start: scheduling delay and retries included (coordinated omission corrected)
+	ResponseConnectP50  float64      `json:"p50_response_connect_ms"`
+	ResponseConnectP90  float64      `json:"p90_response_connect_ms"`
+	ResponseConnectP95  float64      `json:"p95_response_connect_ms"`
+	ResponseConnectP99  float64      `json:"p99_response_connect_ms"`
+	ResponseConnectP999 float64      `json:"p999_response_connect_ms"`
+	ResponseConnectMax  float64      `json:"max_response_connect_ms"`
+	OpenLoopScheduled int64          `json:"open_loop_scheduled"` // Open loop: readers started on schedule
+	OpenLoopPending int64            `json:"open_loop_pending"` // Open loop: of those, still connecting (queue depth)
+	OpenLoopPendingPeak int64        `json:"open_loop_pending_peak"`
+	OpenLoopLagMax  float64          `json:"open_loop_lag_max_ms"` // Open loop: latest a reader started after its scheduled time (the bench falling behind)
+	RTPPackets      uint64           `json:"packets"`
+	RTPLoss         uint64           `json:"loss"`
+	RTPBytes        uint64           `json:"bytes"`
+	RTPRejected     uint64           `json:"rejected"`          // UDP packets from unexpected sources
+	TransportFallbacks uint64        `json:"transport_fallbacks"` // Transport auto: sessions that got no RTP over UDP and retried over TCP
+	KeepAliveRetries uint64          `json:"keepalive_retries"` // Keepalives sent again after timing out or a 5xx
+	KeepAlivesRecovered uint64       `json:"keepalives_recovered"` // Keepalives that succeeded on a retry: sessions kept that a single failure would have ended
+	SessionResumes  uint64           `json:"session_resumes"`   // Sessions resumed over a new connection after losing theirs, as the same viewer
+	SessionResumeFailures uint64     `json:"session_resume_failures"` // Lost connections whose session could not be resumed
+	InterruptionAvg float64          `json:"interruption_avg_ms"` // Time from losing the connection to the resumed PLAY
+	InterruptionP95 float64          `json:"interruption_p95_ms"`
+	InterruptionMax float64          `json:"interruption_max_ms"`
+	LossBursts      map[string]int64 `json:"loss_bursts,omitempty"` // Runs of consecutive lost packets by length: "1", "2-5", "6-20", ">20"
+	LossBurstMax    float64          `json:"loss_burst_max"`    // Longest run in any session, packets
+	SessionLossBurstP50 float64      `json:"session_loss_burst_p50"` // Median of each session's longest run, packets
+	SessionLossBurstP95 float64      `json:"session_loss_burst_p95"`
+	Duplicates      uint64           `json:"duplicates"`        // RTP packets received more than once
+	Reordered       uint64           `json:"reordered"`         // RTP packets that arrived after a later one
+	ReorderDistanceMax float64       `json:"reorder_distance_max"` // Largest distance a packet arrived behind the highest, in sequence numbers
+	DuplicateSessions uint64         `json:"duplicate_sessions"` // Sessions with at least one duplicate
+	ReorderedSessions uint64         `json:"reordered_sessions"` // Sessions with at least one reordered packet
+	StreamResets    uint64           `json:"stream_resets"`     // Servers restarting a stream with a new sequence base mid-session (not counted as loss)
+	StreamResetSessions uint64       `json:"stream_reset_sessions"` // Sessions with at least one stream reset
+	MetadataPackets uint64           `json:"metadata_packets"`  // ONVIF metadata (vnd.onvif.metadata) packets received, also counted in packets
+	MetadataBytes   uint64           `json:"metadata_bytes"`
+	BackchannelPackets uint64        `json:"backchannel_packets"` // ONVIF backchannel audio packets sent
+	BackchannelBytes uint64          `json:"backchannel_bytes"`
+	MPEGTSPackets   uint64           `json:"mpegts_packets"`    // MPEG-TS packets in MP2T (payload type 33) RTP payloads
+	MPEGTSSyncErrors uint64          `json:"mpegts_sync_errors"` // 188-byte units without the 0x47 sync byte
+	MPEGTSCCErrors  uint64           `json:"mpegts_cc_errors"`  // Continuity-counter gaps, per PID
+	MPEGTSErrorSessions uint64       `json:"mpegts_cc_error_sessions"` // Sessions with a continuity-counter gap
+	PCRJitterAvg    float64          `json:"pcr_jitter_avg_ms"` // Smoothed PCR jitter against arrival time, averaged over sessions
+	PCRJitterMax    float64          `json:"pcr_jitter_max_ms"` // Largest PCR deviation in any session, milliseconds
+	JitterMin       float64          `json:"jitter_min_ms"`     // milliseconds
+	JitterAvg       float64          `json:"jitter_avg_ms"`     // milliseconds
+	JitterMax       float64          `json:"jitter_max_ms"`     // milliseconds
+	TTFPMin         float64          `json:"ttfp_min_ms"`       // Time to first packet, milliseconds
+	TTFPAvg         float64          `json:"ttfp_avg_ms"`       // milliseconds
+	TTFPP95         float64          `json:"ttfp_p95_ms"`       // milliseconds
+	TTFPMax         float64          `json:"ttfp_max_ms"`       // milliseconds
+	Stalls          uint64           `json:"stalls"`            // Gaps without RTP longer than StallThreshold on playing sessions
+	StallTime       float64          `json:"stall_time_sec"`    // Total stall time, seconds
+	StallAvg        float64          `json:"stall_avg_ms"`      // milliseconds
+	StallMax        float64          `json:"stall_max_ms"`      // milliseconds
+	StalledSessions uint64           `json:"stalled_sessions"`  // Sessions with at least one stall
+	StalledPct      float64          `json:"stalled_sessions_pct"` // Share of sessions that received media and stalled (rebuffering ratio)
+	QoESessions     int64            `json:"qoe_sessions"`      // Sessions scored: those that received media
+	QoEAvg          float64          `json:"qoe_avg"`           // MOS-like score, 1-5
+	QoEMin          float64          `json:"qoe_min"`
+	QoEP5           float64          `json:"qoe_p5"`            // 5% of sessions scored at or below this
+	QoEP50          float64          `json:"qoe_p50"`
+	QoEAtOrAbove    map[string]int64 `json:"qoe_at_or_above,omitempty"` // Sessions scoring at least each QoEModel threshold, e.g. "4.0"
+	SessionLifetimes int64           `json:"session_lifetimes"` // Playing sessions that ran to their intended end or were ended by the server
+	SessionsCompleted int64          `json:"sessions_completed"` // Of those, sessions that lasted their intended duration
+	SessionLifetimeAvg float64       `json:"session_lifetime_avg_sec"` // From the start of the handshake, seconds
+	SessionLifetimeP50 float64       `json:"session_lifetime_p50_sec"` // seconds
+	SessionLifetimeP95 float64       `json:"session_lifetime_p95_sec"` // seconds
+	SessionCompletionAvg float64     `json:"session_completion_avg_pct"` // Lifetime as a share of the intended duration, averaged over sessions that had one
+	SessionCompletion map[string]int64 `json:"session_completion,omitempty"` // Sessions by 10% step of the intended duration reached, e.g. "40" = 40-50%, "100" = all of it
+	EarlyTerminations int64          `json:"early_terminations"` // Sessions the server ended before their intended duration
+	EarlyTerminationCauses map[string]int64 `json:"early_termination_causes,omitempty"` // By cause: reset, closed, rtsp_error, expired, timeout, other
+	Teardowns       int64            `json:"teardowns"`         // TEARDOWN requests sent
+	TeardownFailures int64           `json:"teardown_failures"` // Timeouts, I/O errors and error statuses
+	TeardownAvg     float64          `json:"teardown_avg_ms"`   // Successful TEARDOWN round trip, milliseconds
+	TeardownP95     float64          `json:"teardown_p95_ms"`   // milliseconds
+	TeardownMax     float64          `json:"teardown_max_ms"`   // milliseconds
+	TeardownCodes   map[string]int64 `json:"teardown_codes,omitempty"` // Count by response status
+	Keyframes       uint64           `json:"keyframes"`         // H.264/H.265 IDR/IRAP frames
+	FragmentErrors  uint64           `json:"fragment_errors"`   // Broken FU-A/FU reassemblies
+	AudioFrames     uint64           `json:"audio_frames"`      // AAC access units and Opus frames in valid payloads
+	AudioMalformed  uint64           `json:"audio_malformed"`   // AAC (RFC 3640/6416) or Opus (RFC 7587) payloads that failed validation
+	AudioMalformedSessions uint64    `json:"audio_malformed_sessions"` // Sessions with a malformed audio payload
+	GOPAvg          float64          `json:"gop_avg_ms"`        // Average keyframe interval, milliseconds
+	FramesVerified  uint64           `json:"frames_verified"`   // Verify mode: frames compared to the reference
+	FramesCorrupt   uint64           `json:"frames_corrupt"`    // Verify mode: frames that did not match
+	TSDiscontinuities uint64         `json:"ts_discontinuities"` // RTP timestamp jumps inconsistent with arrival time
+	TSStalls        uint64           `json:"ts_stalls"`         // Periods where the RTP timestamp stopped advancing
+	TSDiscontinuousSessions uint64   `json:"ts_discontinuous_sessions"` // Sessions with a timestamp discontinuity
+	TSStalledSessions uint64         `json:"ts_stalled_sessions"` // Sessions with a stalled timestamp
+	ClockDriftSessions uint64        `json:"clock_drift_sessions"` // Sessions whose RTP clock drifted from the SDP clock rate
+	ClockIssueSessions uint64        `json:"clock_issue_sessions"` // Sessions with any timestamp issue above
+	ClockDriftMax   float64          `json:"clock_drift_max_pct"` // Largest drift in any session, percent
+	Pauses          uint64           `json:"pauses"`            // Viewer behavior: mid-session PAUSE requests answered
+	PauseFailures   uint64           `json:"pause_failures"`    // PAUSE or resuming PLAY with an error status
+	PauseAvg        float64          `json:"pause_avg_ms"`      // PAUSE round trip, milliseconds
+	PauseP95        float64          `json:"pause_p95_ms"`      // milliseconds
+	ResumeAvg       float64          `json:"resume_avg_ms"`     // Resuming PLAY round trip, milliseconds
+	ResumeP95       float64          `json:"resume_p95_ms"`     // milliseconds
+	Seeks           uint64           `json:"seeks"`             // VOD: seeking PLAY requests answered
+	SeekFailures    uint64           `json:"seek_failures"`     // Seeking PLAY with an error status
+	SeekAvg         float64          `json:"seek_avg_ms"`       // Seeking PLAY round trip, milliseconds
+	SeekP95         float64          `json:"seek_p95_ms"`       // milliseconds
+	SeekMediaAvg    float64          `json:"seek_media_avg_ms"` // Seek to first packet from the new position, milliseconds
+	SeeksChecked    uint64           `json:"seeks_checked"`     // Seeks compared against RTP-Info
+	SeekMismatches  uint64           `json:"seek_mismatches"`   // RTP sequence/timestamp did not restart as announced
+	ImpairedSessions uint64          `json:"impaired_sessions"` // Sessions behind the emulated impaired network
+	ImpairDropped   uint64           `json:"impair_dropped"`    // Packets dropped by the emulation (included in loss)
+	ImpairDuplicated uint64          `json:"impair_duplicated"` // Packets delivered twice by the emulation
+	ImpairReordered uint64           `json:"impair_reordered"`  // Packets held back so later ones overtook them
+	NACKsSent       uint64           `json:"nacks_sent"`        // Generic NACK packets sent
+	NACKRequested   uint64           `json:"nack_requested"`    // Packets asked for by NACKs
+	NACKRepaired    uint64           `json:"nack_repaired"`     // NACKed packets retransmitted within 1s
+	NACKRepairAvg   float64          `json:"nack_repair_avg_ms"` // NACK to retransmission, milliseconds
+	NACKRepairP95   float64          `json:"nack_repair_p95_ms"` // milliseconds
+	PLIsSent        uint64           `json:"plis_sent"`         // Picture Loss Indications sent
+	PLIAnswered     uint64           `json:"pli_answered"`      // PLIs followed by a keyframe
+	PLIKeyframeAvg  float64          `json:"pli_keyframe_avg_ms"` // PLI to keyframe, milliseconds
+	PLIKeyframeP95  float64          `json:"pli_keyframe_p95_ms"` // milliseconds
+	ByesSent        uint64           `json:"byes_sent"`         // Sessions that sent RTCP BYE before TEARDOWN
+	RTXPackets      uint64           `json:"rtx_packets"`       // RTX (RFC 4588) retransmissions received
+	RTXRecovered    uint64           `json:"rtx_recovered"`     // Retransmissions that filled a gap; not counted in loss
+	SlowReaders     int64            `json:"slow_readers"`      // Finished sessions of rate-capped readers
+	SlowDisconnected int64           `json:"slow_disconnected"` // Slow readers the server disconnected
+	SlowDropping    int64            `json:"slow_dropping"`     // Slow readers that saw the server skip packets
+	SlowBuffering   int64            `json:"slow_buffering"`    // Slow readers whose media fell over 1s behind real time
+	SlowStalled     int64            `json:"slow_stalled"`      // Slow readers with a media gap over 1s
+	SlowLagAvg      float64          `json:"slow_lag_avg_ms"`   // Final delivery lag behind real time, milliseconds
+	SlowLagMax      float64          `json:"slow_lag_max_ms"`   // milliseconds
+	Publishers      int64            `json:"publishers"`        // Publishers currently recording
+	PublishFailures int64            `json:"publish_failures"`  // Publisher sessions that failed or were dropped
+	PublishPackets  uint64           `json:"publish_packets"`   // RTP packets sent by publishers
+	PublishBytes    uint64           `json:"publish_bytes"`     // RTP bytes sent by publishers
+	E2ESamples      uint64           `json:"e2e_samples"`       // Frames received with a publisher timestamp
+	E2EAvg          float64          `json:"e2e_avg_ms"`        // Publish to receive latency, milliseconds
+	E2EP50          float64          `json:"e2e_p50_ms"`        // milliseconds
+	E2EP95          float64          `json:"e2e_p95_ms"`        // milliseconds
+	E2EP99          float64          `json:"e2e_p99_ms"`        // milliseconds
+	E2EMax          float64          `json:"e2e_max_ms"`        // milliseconds
+	RateConnections int              `json:"rate_connections"`  // Connections with a per-connection rate sample
+	ConnBitrateMin  float64          `json:"conn_bitrate_min_kbps"` // Per-connection 5s average bitrate across connections
+	ConnBitrateP5   float64          `json:"conn_bitrate_p5_kbps"`
+	ConnBitrateP50  float64          `json:"conn_bitrate_p50_kbps"`
+	ConnBitrateP95  float64          `json:"conn_bitrate_p95_kbps"`
+	ConnPPSMin      float64          `json:"conn_pps_min"`      // Per-connection 5s average packets/s across connections
+	ConnPPSP5       float64          `json:"conn_pps_p5"`
+	ConnPPSP50      float64          `json:"conn_pps_p50"`
+	ConnPPSP95      float64          `json:"conn_pps_p95"`
+	StarvedConnections int           `json:"starved_connections"` // Below half the median connection bitrate
+	IPv4Connects    int64            `json:"ipv4_connects"`     // Connection attempts to IPv4 server addresses that succeeded
+	IPv4Failures    int64            `json:"ipv4_failures"`     // Connection attempts to IPv4 server addresses that failed
+	IPv6Connects    int64            `json:"ipv6_connects"`
+	IPv6Failures    int64            `json:"ipv6_failures"`
+	DialCanceled    int64            `json:"dial_canceled"`     // Happy Eyeballs attempts abandoned after another address connected
+	DNSLookups      int64            `json:"dns_lookups"`       // Hostname resolutions made
+	DNSFailures     int64            `json:"dns_failures"`      // Resolutions that failed or returned no address
+	DNSAvg          float64          `json:"dns_avg_ms"`        // Successful resolution time, milliseconds
+	DNSP95          float64          `json:"dns_p95_ms"`        // milliseconds
+	DNSMax          float64          `json:"dns_max_ms"`        // milliseconds
+	Capacity        int64            `json:"capacity"`          // Find-max: highest reader count meeting the SLO
+	BadClients      int64            `json:"bad_clients"`       // Number of bad clients
+	BadClientTypes  map[string]int64 `json:"bad_client_types"`  // Count by type
+	BadClientReactions map[string]BadClientReaction `json:"bad_client_reactions,omitempty"` // How the server treated each bad client type
+	DescribeFloods  int64            `json:"describe_floods"`   // DescribeFlood bad clients that got a response
+	DescribeFloodRequests int64      `json:"describe_flood_requests"` // DESCRIBEs answered during floods
+	DescribeFloodErrors int64        `json:"describe_flood_errors"` // Answered with a 4xx/5xx status
+	DescribeFloodRegenerated int64   `json:"describe_flood_regenerated"` // Floods that saw the SDP change (not cached)
+	DescribeFloodDegraded int64      `json:"describe_flood_degraded"` // Floods whose last responses were over 2x and 1ms slower than the first
+	DescribeBaselineAvg float64      `json:"describe_baseline_avg_ms"` // First 20 responses of a flood, milliseconds
+	DescribeFinalAvg float64         `json:"describe_final_avg_ms"` // Last 20 responses of a flood, milliseconds
+	DescribeMax     float64          `json:"describe_max_ms"`   // milliseconds
+	SilentHolders   int64            `json:"silent_holders"`    // SilentHolder and HalfOpen bad clients that connected
+	SilentReaped    int64            `json:"silent_reaped"`     // Silent connections the server closed
+	SilentReapAvg   float64          `json:"silent_reap_avg_ms"` // Connect to server close, milliseconds
+	SilentReapMax   float64          `json:"silent_reap_max_ms"` // milliseconds
+	HalfOpenVanished int64           `json:"half_open_vanished"` // HalfOpen sockets dropped without FIN, left half-open on the server
+	ParserProbes    int64            `json:"parser_probes"`     // MalformedPipeline probes sent
+	ParserRequests  int64            `json:"parser_requests"`   // Requests in them the server should answer
+	ParserAnswered  int64            `json:"parser_answered"`
+	ParserDisordered int64           `json:"parser_disordered"` // Pipelined responses out of CSeq order
+	ParserSmuggled  int64            `json:"parser_smuggled"`   // Requests hidden in a body that the server answered
+	ParserClosed    int64            `json:"parser_closed"`     // Probes the server closed the connection on
+	FuzzRequests    int64            `json:"fuzz_requests"`     // Mutated requests sent by MalformedRequests clients
+	FuzzRejected    int64            `json:"fuzz_rejected"`     // Answered with 4xx, 501, 505 or 551
+	FuzzServerErrors int64           `json:"fuzz_server_errors"` // Answered with another 5xx (findings)
+	FuzzDropped     int64            `json:"fuzz_dropped"`      // Connection closed without an answer (findings)
+	FuzzTimeouts    int64            `json:"fuzz_timeouts"`     // Not answered within 2s
+	HostCPUPct      float64          `json:"host_cpu_pct"`      // Target host: CPU busy across all cores since the previous scrape
+	HostMemPct      float64          `json:"host_mem_pct"`      // Target host: memory in use (total minus available)
+	HostMemUsedMB   float64          `json:"host_mem_used_mb"`
+	HostSockets     int64            `json:"host_sockets"`      // Target host: TCP sockets in use, or the server's RTSP connections
+	HostRxMbps      float64          `json:"host_rx_mbps"`      // Target host: received over all interfaces but loopback since the previous scrape
+	HostTxMbps      float64          `json:"host_tx_mbps"`
+	HostScrapes     int64            `json:"host_scrapes"`
+	HostScrapeErrors int64           `json:"host_scrape_errors"` // Scrapes where a source failed
+	SelfCPUPct      float64          `json:"self_cpu_pct"`      // Bench process: average CPU use, as a share of GOMAXPROCS cores
+	SelfCPUMaxPct   float64          `json:"self_cpu_max_pct"`  // Bench process: busiest StatsInterval
+	SelfHeapMaxMB   float64          `json:"self_heap_max_mb"`
+	SelfGoroutinesPeak int64         `json:"self_goroutines_peak"`
+	SelfGCPauseMax  float64          `json:"self_gc_pause_max_ms"`
+	SelfSchedP99Max float64          `json:"self_sched_latency_p99_max_ms"` // Bench process: worst p99 wait of runnable goroutines over a StatsInterval
+	SelfOverloaded  int64            `json:"self_overloaded_intervals"` // StatsIntervals the bench itself was the likely bottleneck
+	SelfIntervals   int64            `json:"self_intervals"`
+	ServerSessions  int64            `json:"server_sessions"`   // MediaMTX: RTSP sessions from the readers' addresses, publishers excepted
+	ServerUnmatched int64            `json:"server_unmatched"`  // MediaMTX: of those, sessions no live reader owns (bad clients, ghosts)
+	ServerMissing   int64            `json:"server_missing"`    // MediaMTX: readers connected for 5s the server has no session for
+	ServerBytesGapPct float64        `json:"server_bytes_gap_pct"` // MediaMTX: share of the bytes sent to matched readers that they did not receive
+	ServerPathReaders int64          `json:"server_path_readers"` // MediaMTX: readers of any protocol on all paths
+	GhostSessions   int64            `json:"ghost_sessions"`    // MediaMTX: sessions from the readers' addresses left after the run
+	ServerPolls     int64            `json:"server_polls"`
+	ServerPollErrors int64           `json:"server_poll_errors"`
+	PlayerProfiles  map[string]int64 `json:"player_profiles,omitempty"` // Readers started per mimicked player profile (bench = own behavior)
+	TrackSelections map[string]int64 `json:"track_selections,omitempty"` // Readers started per track selection: all, video or audio
+	Targets         map[string]TargetStats `json:"targets,omitempty"` // Per-URL stats (multi-URL runs)
+	MulticastGroups map[string]GroupStats  `json:"multicast_groups,omitempty"` // Per-group stats (multicast transport)
 }
 
-// GetStats returns current statistics
+// fillClockStats copies the RTP timestamp continuity statistics into stats
+func fillClockStats(stats *Stats, snapshot rtp.Snapshot) {
+	stats.TSDiscontinuities = snapshot.TimestampDiscontinuities
+	stats.TSStalls = snapshot.TimestampStalls
+	stats.TSDiscontinuousSessions = snapshot.DiscontinuousSessions
+	stats.TSStalledSessions = snapshot.StalledSessions
+	stats.ClockDriftSessions = snapshot.DriftingSessions
+	stats.ClockIssueSessions = snapshot.ClockIssueSessions
+	stats.ClockDriftMax = snapshot.DriftMaxPct
+}
+
+// GetStats returns current statistics, zero until Run has set up. In
+// real-world mode they are the simulator's.
 func (r *Runner) GetStats() Stats {
-	snapshot := r.aggregator.Snapshot()
-	
-	// Calculate average connection time
-	var avgConnect float64
-	count := r.connectCount.Load()
-	if count > 0 {
-		avgConnect = float64(r.connectLatency.Load()) / float64(count)
+	if !r.started.Load() {
+		return Stats{}
 	}
-	
-	// Calculate percentiles
-	var p95 float64
-	r.latenciesMu.Lock()
-	if len(r.latencies) > 0 {
-		p95 = calculatePercentile(r.latencies, 95)
+	if r.simulator != nil {
+		return r.simulator.GetStats()
 	}
-	r.latenciesMu.Unlock()
+	snapshot := r.aggregator.Snapshot()
 	
-	minLat := float64(r.minLatency.Load())
-	if minLat == 99999999 {
-		minLat = 0
-	}
+	// Connection time distribution
+	connect := r.connectLatency.Summary()
+	response := r.responseLatency.Summary()
 	
 	// Collect bad client types
 	badClientTypes := make(map[string]int64)
@@ -368,29 +1322,108 @@ func (r *Runner) GetStats() Stats {
 		return true
 	})
 	
-	return Stats{
+	stats := Stats{
 		ActiveConnects:  r.activeConnects.Load(),
+		PeakActive:      r.peakActive.Load(),
 		TotalConnects:   r.totalConnects.Load(),
 		TotalFailures:   r.totalFailures.Load(),
-		AvgConnectTime:  avgConnect,
-		MinConnectTime:  minLat,
-		MaxConnectTime:  float64(r.maxLatency.Load()),
-		P95ConnectTime:  p95,
+		TargetConnects:  r.targetReaders.Load(),
+		Capacity:        r.capacity.Load(),
+		SessionTimeouts: r.sessionTimeouts.Load(),
+		AvgConnectTime:  connect.Mean,
+		MinConnectTime:  connect.Min,
+		MaxConnectTime:  connect.Max,
+		P50ConnectTime:  connect.P50,
+		P90ConnectTime:  connect.P90,
+		P95ConnectTime:  connect.P95,
+		P99ConnectTime:  connect.P99,
+		P999ConnectTime: connect.P999,
+		ResponseConnectAvg: response.Mean,
+		ResponseConnectP50: response.P50,
+		ResponseConnectP90: response.P90,
+		ResponseConnectP95: response.P95,
+		ResponseConnectP99: response.P99,
+		ResponseConnectP999: response.P999,
+		ResponseConnectMax: response.Max,
 		RTPPackets:      snapshot.Packets,
 		RTPLoss:         snapshot.Lost,
 		RTPBytes:        snapshot.Bytes,
+		RTPRejected:     snapshot.Rejected,
+		TransportFallbacks: snapshot.Fallbacks,
+		KeepAliveRetries: snapshot.KeepAliveRetries,
+		KeepAlivesRecovered: snapshot.KeepAliveRecovered,
+		SessionResumes:  snapshot.SessionResumes,
+		SessionResumeFailures: snapshot.SessionResumeFailures,
+		InterruptionAvg: snapshot.InterruptionAvg,
+		InterruptionP95: snapshot.InterruptionP95,
+		InterruptionMax: snapshot.InterruptionMax,
+		JitterMin:       snapshot.JitterMin,
+		JitterAvg:       snapshot.JitterAvg,
+		JitterMax:       snapshot.JitterMax,
+		TTFPMin:         snapshot.TTFPMin,
+		TTFPAvg:         snapshot.TTFPAvg,
+		TTFPP95:         snapshot.TTFPP95,
+		TTFPMax:         snapshot.TTFPMax,
+		Keyframes:       snapshot.Keyframes,
+		FragmentErrors:  snapshot.FragmentErrors,
+		AudioFrames:     snapshot.AudioFrames,
+		AudioMalformed:  snapshot.AudioMalformed,
+		AudioMalformedSessions: snapshot.AudioMalformedSessions,
+		GOPAvg:          snapshot.GOPAvg,
+		FramesVerified:  snapshot.FramesVerified,
+		FramesCorrupt:   snapshot.FramesCorrupt,
 		BadClients:      r.badClients.Load(),
 		BadClientTypes:  badClientTypes,
+		BadClientReactions: r.reactions.stats(),
+		Targets:         r.targets.stats(time.Since(r.startTime)),
+		MulticastGroups: r.groups.stats(time.Since(r.startTime)),
 	}
+	r.failures.fill(&stats)
+	r.methods.fill(&stats)
+	r.redirects.fill(&stats)
+	r.serverRequests.fill(&stats)
+	r.hops.fill(&stats)
+	fillSharedUDP(&stats, r.sharedUDP)
+	r.reconnects.fill(&stats)
+	r.migrations.fill(&stats, r.targets)
+	r.teardowns.fill(&stats)
+	r.lifetimes.fill(&stats)
+	r.families.fill(&stats)
+	r.slowReaders.fill(&stats)
+	r.describes.fill(&stats)
+	r.silent.fill(&stats)
+	r.parserProbes.fill(&stats)
+	r.fuzz.fill(&stats)
+	r.dialer.fill(&stats)
+	fillClockStats(&stats, snapshot)
+	fillViewerStats(&stats, snapshot)
+	fillE2EStats(&stats, snapshot)
+	fillONVIFStats(&stats, snapshot)
+	fillMPEGTSStats(&stats, snapshot)
+	fillStallStats(&stats, snapshot)
+	fillLossBursts(&stats, snapshot)
+	fillReordering(&stats, snapshot)
+	r.rates.fill(&stats)
+	r.publishers.Load().fill(&stats)
+	r.monitor.Fill(&stats)
+	r.self.Fill(&stats)
+	r.openLoop.fill(&stats)
+	r.mediamtx.fill(&stats)
+	r.players.fill(&stats)
+	r.tracks.fill(&stats)
+	r.qoe.fill(&stats)
+	return stats
+}
+
+// Connections returns the live reader connections with their current rates
+func (r *Runner) Connections() []ConnectionStats {
+	return r.rates.list()
 }
 
 // PrintStats prints formatted statistics
 func (r *Runner) PrintStats() {
 	stats := r.GetStats()
-	lossRate := float64(0)
-	if stats.RTPPackets > 0 {
-		lossRate = float64(stats.RTPLoss) * 100.0 / float64(stats.RTPPackets+stats.RTPLoss)
-	}
+	lossRate := lossPercent(stats)
 	
 	fmt.Printf("Active: %d | Total: %d | Failed: %d | Avg Connect: %.1fms | Packets: %d | Loss: %.2f%%\n",
 		stats.ActiveConnects,
@@ -400,28 +1433,4 @@ func (r *Runner) PrintStats() {
 		stats.RTPPackets,
 		lossRate,
 	)
-}
-
-// calculatePercentile calculates the nth percentile of a slice of values
-func calculatePercentile(values []float64, percentile float64) float64 {
-	if len(values) == 0 {
-		return 0
-	}
-	
-	// Create a copy to avoid modifying original
-	sorted := make([]float64, len(values))
-	copy(sorted, values)
-	sort.Float64s(sorted)
-	
-	index := (percentile / 100) * float64(len(sorted)-1)
-	lower := int(index)
-	upper := lower + 1
-	
-	if upper >= len(sorted) {
-		return sorted[lower]
-	}
-	
-	// Linear interpolation
-	weight := index - float64(lower)
-	return sorted[lower]*(1-weight) + sorted[upper]*weight
 }
\ No newline at end of file
@@ -0,0 +1,267 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/yaml"
+)
+
+// Scenario phase types
+const (
+	PhaseRamp  = "ramp"  // Linear change to Readers over Duration
+	PhaseHold  = "hold"  // Keep the reader count (or jump to Readers if set) for Duration
+	PhaseSpike = "spike" // Jump to Readers as fast as possible, then hold for Duration
+	PhaseDrain = "drain" // Linear decrease to Readers (default 0) over Duration
+)
+
+// scenarioTick is how often the reader count is adjusted during a phase
+const scenarioTick = 100 * time.Millisecond
+
+// Scenario is a multi-phase test plan loaded from a YAML or JSON file
+type Scenario struct {
+	Name           string   `json:"name"`
+	URL            string   `json:"url"`
+	URLs           []string `json:"urls"`
+	Transport      string   `json:"transport"`
+	Rate           float64  `json:"rate"`             // Max connects per second (0 = unlimited)
+	BadClientRatio float64  `json:"bad_client_ratio"` // Default for all phases
+	Username       string   `json:"username"`
+	Password       string   `json:"password"`
+	Phases         []Phase  `json:"phases"`
+}
+
+// Phase is one step of a scenario. Transport and BadClientRatio apply to
+// readers started during the phase.
+type Phase struct {
+	Name           string   `json:"name"`
+	Type           string   `json:"type"`
+	Readers        int      `json:"readers"`
+	Duration       Duration `json:"duration"`
+	Transport      string   `json:"transport"`
+	BadClientRatio *float64 `json:"bad_client_ratio"`
+}
+
+// Duration is a time.Duration that unmarshals from "2m" style strings or
+// a number of seconds
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string or seconds
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+	var secs float64
+	if err := json.Unmarshal(b, &secs); err != nil {
+		return fmt.Errorf("invalid duration: %s", b)
+	}
+	*d = Duration(secs * float64(time.Second))
+	return nil
+}
+
+// MarshalJSON formats the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadScenario reads a scenario from a .yaml/.yml or .json file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sc Scenario
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &sc)
+	default:
+		err = json.Unmarshal(data, &sc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+
+	if err := sc.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return &sc, nil
+}
+
+// validate checks the phase list
+func (sc *Scenario) validate() error {
+	if len(sc.Phases) == 0 {
+		return fmt.Errorf("no phases")
+	}
+	for i, ph := range sc.Phases {
+		switch ph.Type {
+		case PhaseRamp, PhaseHold, PhaseSpike, PhaseDrain:
+		default:
+			return fmt.Errorf("phase %d: unknown type %q", i+1, ph.Type)
+		}
+		if ph.Readers < 0 || ph.Duration < 0 {
+			return fmt.Errorf("phase %d: readers and duration must not be negative", i+1)
+		}
+		if ph.Type == PhaseRamp && ph.Duration == 0 {
+			return fmt.Errorf("phase %d: ramp needs a duration", i+1)
+		}
+		if ph.BadClientRatio != nil && (*ph.BadClientRatio < 0 || *ph.BadClientRatio > 1) {
+			return fmt.Errorf("phase %d: bad_client_ratio must be between 0 and 1", i+1)
+		}
+	}
+	return nil
+}
+
+// Apply returns config with the scenario's settings filled in. Fields the
+// scenario leaves empty keep their config values.
+func (sc *Scenario) Apply(config Config) Config {
+	if sc.URL != "" {
+		config.URL = sc.URL
+	}
+	if len(sc.URLs) > 0 {
+		config.URLs = sc.URLs
+	}
+	if sc.Transport != "" {
+		config.Transport = sc.Transport
+	}
+	if sc.Rate > 0 {
+		config.Rate = sc.Rate
+	}
+	if sc.BadClientRatio > 0 {
+		config.IncludeBadClients = true
+		config.BadClientRatio = sc.BadClientRatio
+	}
+	if sc.Username != "" {
+		config.Username = sc.Username
+		config.Password = sc.Password
+	}
+
+	// Size the connection semaphore for the largest phase
+	for _, ph := range sc.Phases {
+		if ph.Readers > config.Readers {
+			config.Readers = ph.Readers
+		}
+	}
+	config.Scenario = sc
+	return config
+}
+
+// Total returns the combined duration of all phases
+func (sc *Scenario) Total() time.Duration {
+	var total time.Duration
+	for _, ph := range sc.Phases {
+		total += time.Duration(ph.Duration)
+	}
+	return total
+}
+
+// desired returns the reader count elapsed into the phase, starting from
+// start readers
+func (ph Phase) desired(start int, elapsed time.Duration) int {
+	switch ph.Type {
+	case PhaseRamp, PhaseDrain:
+		d := time.Duration(ph.Duration)
+		if d <= 0 || elapsed >= d {
+			return ph.Readers
+		}
+		return start + int(float64(ph.Readers-start)*elapsed.Seconds()/d.Seconds())
+	case PhaseSpike:
+		return ph.Readers
+	default:
+		if ph.Readers > 0 {
+			return ph.Readers
+		}
+		return start
+	}
+}
+
+// runScenario runs Config.Scenario's phases in order, then closes all readers
+func (r *Runner) runScenario(ctx context.Context) error {
+	sc := r.config.Scenario
+	r.log.Info("starting scenario", "name", sc.Name, "phases", len(sc.Phases),
+		"duration", sc.Total(), "targets", len(r.targets.all()))
+
+	pool := newReaderPool(ctx, r)
+	defer func() {
+		pool.closeAll()
+		r.log.Info("waiting for connections to close", "active", r.activeConnects.Load())
+		r.wg.Wait()
+	}()
+
+	ticker := time.NewTicker(scenarioTick)
+	defer ticker.Stop()
+
+	for i := range sc.Phases {
+		ph, badRatio := r.phase(i)
+		s := session{transport: r.config.Transport, managed: true}
+		if ph.Transport != "" {
+			s.transport = ph.Transport
+		}
+
+		// Spikes start readers as fast as possible; otherwise respect Rate
+		maxSpawn := 0
+		if ph.Type != PhaseSpike && r.config.Rate > 0 {
+			maxSpawn = int(r.config.Rate*scenarioTick.Seconds() + 0.5)
+			if maxSpawn < 1 {
+				maxSpawn = 1
+			}
+		}
+
+		start := pool.size()
+		r.log.Info("scenario phase", "phase", i+1, "name", ph.Name, "type", ph.Type,
+			"from", start, "readers", ph.desired(start, time.Duration(ph.Duration)),
+			"duration", time.Duration(ph.Duration), "transport", s.transport, "bad_client_ratio", badRatio)
+
+		// Phase time stands still while paused through the control API
+		r.control.setPhase(i+1, ph.Name)
+		var elapsed time.Duration
+		last := time.Now()
+		for {
+			now := time.Now()
+			if !r.control.isPaused() {
+				elapsed += now.Sub(last)
+			}
+			last = now
+
+			// Reader counts and bad client ratios change on SIGHUP
+			ph, badRatio = r.phase(i)
+			target := r.control.readers(ph.desired(start, elapsed))
+			pool.resize(target, s, r.control.badClientRatio(badRatio), maxSpawn)
+			if elapsed >= time.Duration(ph.Duration) || r.control.takeSkip() {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+
+	r.log.Info("scenario complete", "name", sc.Name)
+	return nil
+}
+
+// phase returns phase i of the running scenario, as last reloaded, with
+// its bad client ratio
+func (r *Runner) phase(i int) (Phase, float64) {
+	sc := r.scenario.Load()
+	ph := sc.Phases[i]
+	if ph.BadClientRatio != nil {
+		return ph, *ph.BadClientRatio
+	}
+	return ph, sc.BadClientRatio
+}
@@ -0,0 +1,97 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Connection arrival processes
+const (
+	ArrivalsConstant = "constant" // One connection every 1/Rate
+	ArrivalsPoisson  = "poisson"  // Exponential gaps averaging 1/Rate
+	ArrivalsBursty   = "bursty"   // ConnectBatch connections at once, every ConnectBatchGap
+)
+
+// connectSchedule yields when each connection of a fixed reader count run
+// arrives, as an offset from the start
+type connectSchedule struct {
+	kind     string
+	interval time.Duration // constant and poisson: mean gap
+	batch    int           // bursty
+	gap      time.Duration // bursty
+	random   *lockedRand
+
+	n    int
+	next time.Duration
+}
+
+// newConnectSchedule returns the arrival process of config
+func newConnectSchedule(config Config, random *lockedRand) (*connectSchedule, error) {
+	s := &connectSchedule{kind: config.ConnectArrivals, batch: config.ConnectBatch, gap: config.ConnectBatchGap, random: random}
+	if config.Rate > 0 {
+		s.interval = time.Duration(float64(time.Second) / config.Rate)
+	}
+	switch s.kind {
+	case "":
+		s.kind = ArrivalsConstant
+	case ArrivalsConstant:
+	case ArrivalsPoisson:
+		if config.Rate <= 0 {
+			return nil, fmt.Errorf("poisson arrivals need a rate")
+		}
+	case ArrivalsBursty:
+		if s.batch < 1 {
+			return nil, fmt.Errorf("bursty arrivals need a batch size")
+		}
+		if s.gap <= 0 {
+			if config.Rate <= 0 {
+				return nil, fmt.Errorf("bursty arrivals need a batch gap or a rate")
+			}
+			s.gap = time.Duration(s.batch) * s.interval // Rate on average
+		}
+	default:
+		return nil, fmt.Errorf("unknown connection arrivals %q (want %s, %s or %s)", s.kind, ArrivalsConstant, ArrivalsPoisson, ArrivalsBursty)
+	}
+	return s, nil
+}
+
+// paced reports whether the spawner must wait for each arrival itself;
+// constant arrivals are paced by the rate limiter
+func (s *connectSchedule) paced() bool {
+	return s.kind != ArrivalsConstant
+}
+
+// nextArrival returns the offset of the next connection from the start
+func (s *connectSchedule) nextArrival() time.Duration {
+	at := s.next
+	s.n++
+	switch s.kind {
+	case ArrivalsPoisson:
+		s.next += time.Duration(s.random.ExpFloat64() * float64(s.interval))
+	case ArrivalsBursty:
+		if s.n%s.batch == 0 {
+			s.next += s.gap
+		}
+	default:
+		s.next = time.Duration(s.n) * s.interval
+	}
+	return at
+}
+
+// waitUntil sleeps until t, returning false if ctx ends first
+func waitUntil(ctx context.Context, t time.Time) bool {
+	wait := time.Until(t)
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,66 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedRand is a seeded random source safe for concurrent use. All random
+// choices of a run draw from one, so Config.Seed reproduces them.
+type lockedRand struct {
+	mu   sync.Mutex
+	rng  *rand.Rand
+	seed int64
+}
+
+// newLockedRand creates a source from seed, or from the clock if seed is 0
+func newLockedRand(seed int64) *lockedRand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &lockedRand{rng: rand.New(rand.NewSource(seed)), seed: seed}
+}
+
+// Float64 returns a number in [0.0, 1.0)
+func (l *lockedRand) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rng.Float64()
+}
+
+// Intn returns a number in [0, n)
+func (l *lockedRand) Intn(n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rng.Intn(n)
+}
+
+// Int63n returns a number in [0, n)
+func (l *lockedRand) Int63n(n int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rng.Int63n(n)
+}
+
+// Int63 returns a non-negative number, used to seed per-client sources
+func (l *lockedRand) Int63() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rng.Int63()
+}
+
+// NormFloat64 returns a standard normally distributed number
+func (l *lockedRand) NormFloat64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rng.NormFloat64()
+}
+
+// ExpFloat64 returns an exponentially distributed number with mean 1
+func (l *lockedRand) ExpFloat64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rng.ExpFloat64()
+}
@@ -0,0 +1,13 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build windows
+
+package bench
+
+import "time"
+
+// processCPU is not available on Windows; the self monitor then judges
+// overload by scheduling latency alone
+func processCPU() (time.Duration, bool) {
+	return 0, false
+}
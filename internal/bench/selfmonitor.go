@@ -0,0 +1,347 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Self-monitoring defaults
+const (
+	DefaultSelfCPUWarnPct  = 85.0
+	DefaultSelfSchedWarn   = 10 * time.Millisecond
+	DefaultProfileInterval = time.Minute
+)
+
+// Runtime metrics read by the self monitor
+const (
+	metricGoroutines = "/sched/goroutines:goroutines"
+	metricHeap       = "/memory/classes/heap/objects:bytes"
+	metricGCPauses   = "/gc/pauses:seconds"
+	metricSchedWait  = "/sched/latencies:seconds"
+)
+
+// selfStats is what the self monitor reports over the run
+type selfStats struct {
+	intervals      int64
+	overloaded     int64
+	cpuSum         float64 // Percent, summed over intervals
+	cpuMax         float64
+	heapMaxMB      float64
+	goroutinesPeak int64
+	gcPauseMax     float64 // milliseconds
+	schedP99Max    float64 // milliseconds
+}
+
+// SelfMonitor watches the bench process itself every StatsInterval: CPU,
+// heap, goroutines, GC pauses and how long runnable goroutines wait to
+// run. It warns when the load generator, not the server, is likely the
+// bottleneck, and can write pprof profiles at intervals.
+type SelfMonitor struct {
+	interval   time.Duration
+	cpuWarn    float64
+	schedWarn  time.Duration
+	profileDir string
+	profileInt time.Duration
+	log        *slog.Logger
+
+	samples []metrics.Sample
+	prevCPU time.Duration
+	prevAt  time.Time
+	prevGC  *metrics.Float64Histogram
+	prevRun *metrics.Float64Histogram
+
+	mu         sync.Mutex
+	stats      selfStats
+	overloaded bool
+}
+
+// NewSelfMonitor returns the self monitor of config
+func NewSelfMonitor(config Config, log *slog.Logger) *SelfMonitor {
+	m := &SelfMonitor{
+		interval:   config.StatsInterval,
+		cpuWarn:    config.SelfCPUWarnPct,
+		schedWarn:  config.SelfSchedWarn,
+		profileDir: config.ProfileDir,
+		profileInt: config.ProfileInterval,
+		log:        log,
+		samples: []metrics.Sample{
+			{Name: metricGoroutines}, {Name: metricHeap}, {Name: metricGCPauses}, {Name: metricSchedWait},
+		},
+	}
+	if m.interval <= 0 {
+		m.interval = 5 * time.Second
+	}
+	if m.cpuWarn <= 0 {
+		m.cpuWarn = DefaultSelfCPUWarnPct
+	}
+	if m.schedWarn <= 0 {
+		m.schedWarn = DefaultSelfSchedWarn
+	}
+	if m.profileInt <= 0 {
+		m.profileInt = DefaultProfileInterval
+	}
+	return m
+}
+
+// Run samples until ctx is done, writing profiles if ProfileDir is set.
+// It returns once the last profiles are written.
+func (m *SelfMonitor) Run(ctx context.Context) {
+	if m.profileDir != "" {
+		profiled := make(chan struct{})
+		go func() {
+			defer close(profiled)
+			m.profile(ctx)
+		}()
+		defer func() { <-profiled }()
+	}
+	m.sample()
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sample()
+		}
+	}
+}
+
+// sample reads the runtime once and updates the stats with the interval
+// since the previous sample
+func (m *SelfMonitor) sample() {
+	now := time.Now()
+	cpu, hasCPU := processCPU()
+	metrics.Read(m.samples)
+	goroutines := metricValue(m.samples[0])
+	heapMB := metricValue(m.samples[1]) / (1 << 20)
+	gc := metricHistogram(m.samples[2])
+	run := metricHistogram(m.samples[3])
+
+	first := m.prevAt.IsZero()
+	var cpuPct, gcPauseMax, schedP99 float64
+	if !first {
+		if secs := now.Sub(m.prevAt).Seconds(); hasCPU && secs > 0 {
+			cpuPct = (cpu - m.prevCPU).Seconds() * 100 / (secs * float64(runtime.GOMAXPROCS(0)))
+		}
+		gcPauseMax = histogramMax(gc, m.prevGC) * 1000
+		schedP99 = histogramQuantile(run, m.prevRun, 0.99) * 1000
+	}
+	m.prevCPU, m.prevAt, m.prevGC, m.prevRun = cpu, now, gc, run
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := &m.stats
+	s.heapMaxMB = math.Max(s.heapMaxMB, heapMB)
+	s.goroutinesPeak = max(s.goroutinesPeak, int64(goroutines))
+	if first {
+		return
+	}
+	s.intervals++
+	s.cpuSum += cpuPct
+	s.cpuMax = math.Max(s.cpuMax, cpuPct)
+	s.gcPauseMax = math.Max(s.gcPauseMax, gcPauseMax)
+	s.schedP99Max = math.Max(s.schedP99Max, schedP99)
+
+	var reasons []string
+	if cpuPct > m.cpuWarn {
+		reasons = append(reasons, fmt.Sprintf("CPU %.0f%% of %d cores", cpuPct, runtime.GOMAXPROCS(0)))
+	}
+	if schedP99 > float64(m.schedWarn)/float64(time.Millisecond) {
+		reasons = append(reasons, fmt.Sprintf("goroutines wait %.1fms to run (p99)", schedP99))
+	}
+	if len(reasons) > 0 {
+		s.overloaded++
+	}
+	switch {
+	case len(reasons) > 0 && !m.overloaded:
+		m.log.Warn("load generator is the bottleneck, results may understate the server",
+			"reason", strings.Join(reasons, ", "), "goroutines", int64(goroutines), "heap_mb", math.Round(heapMB), "gc_pause_max_ms", math.Round(gcPauseMax*100)/100)
+	case len(reasons) == 0 && m.overloaded:
+		m.log.Info("load generator no longer overloaded", "cpu_pct", math.Round(cpuPct), "sched_p99_ms", math.Round(schedP99*100)/100)
+	}
+	m.overloaded = len(reasons) > 0
+}
+
+// Fill copies the self-monitoring figures into stats. A nil monitor
+// leaves them zero.
+func (m *SelfMonitor) Fill(stats *Stats) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stats
+	stats.SelfIntervals = s.intervals
+	stats.SelfOverloaded = s.overloaded
+	if s.intervals > 0 {
+		stats.SelfCPUPct = s.cpuSum / float64(s.intervals)
+	}
+	stats.SelfCPUMaxPct = s.cpuMax
+	stats.SelfHeapMaxMB = s.heapMaxMB
+	stats.SelfGoroutinesPeak = s.goroutinesPeak
+	stats.SelfGCPauseMax = s.gcPauseMax
+	stats.SelfSchedP99Max = s.schedP99Max
+}
+
+// writeSelfOverload prints the overload warning of the text summary
+func writeSelfOverload(w io.Writer, stats Stats) error {
+	_, err := fmt.Fprintf(w, "  Bench overloaded in %d of %d intervals (CPU max %.0f%%, scheduling p99 max %.1fms): results may understate the server\n",
+		stats.SelfOverloaded, stats.SelfIntervals, stats.SelfCPUMaxPct, stats.SelfSchedP99Max)
+	return err
+}
+
+// profile writes a CPU profile of every ProfileInterval, and heap and
+// goroutine profiles at its end, until ctx is done
+func (m *SelfMonitor) profile(ctx context.Context) {
+	if err := os.MkdirAll(m.profileDir, 0o755); err != nil {
+		m.log.Error("profiles disabled", "dir", m.profileDir, "error", err)
+		return
+	}
+	m.log.Info("writing profiles", "dir", m.profileDir, "interval", m.profileInt)
+	ticker := time.NewTicker(m.profileInt)
+	defer ticker.Stop()
+	for n := 1; ; n++ {
+		stopCPU := m.startCPUProfile(n)
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+		stopCPU()
+		m.writeProfile("heap", n)
+		m.writeProfile("goroutine", n)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// startCPUProfile starts CPU profiling into profile n and returns the
+// function that stops it
+func (m *SelfMonitor) startCPUProfile(n int) func() {
+	f, err := os.Create(m.profilePath("cpu", n))
+	if err != nil {
+		m.log.Error("cpu profile failed", "error", err)
+		return func() {}
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		m.log.Error("cpu profile failed", "error", err)
+		f.Close()
+		os.Remove(f.Name())
+		return func() {}
+	}
+	return func() {
+		pprof.StopCPUProfile()
+		f.Close()
+	}
+}
+
+// writeProfile writes the named runtime profile as profile n
+func (m *SelfMonitor) writeProfile(name string, n int) {
+	f, err := os.Create(m.profilePath(name, n))
+	if err != nil {
+		m.log.Error(name+" profile failed", "error", err)
+		return
+	}
+	defer f.Close()
+	if err := pprof.Lookup(name).WriteTo(f, 0); err != nil {
+		m.log.Error(name+" profile failed", "error", err)
+	}
+}
+
+// profilePath names profile n of a kind, e.g. cpu-0003.pprof
+func (m *SelfMonitor) profilePath(kind string, n int) string {
+	return filepath.Join(m.profileDir, fmt.Sprintf("%s-%04d.pprof", kind, n))
+}
+
+// metricValue returns a gauge sample as a float, 0 if unsupported
+func metricValue(s metrics.Sample) float64 {
+	switch s.Value.Kind() {
+	case metrics.KindUint64:
+		return float64(s.Value.Uint64())
+	case metrics.KindFloat64:
+		return s.Value.Float64()
+	}
+	return 0
+}
+
+// metricHistogram returns a copy of a histogram sample, nil if unsupported.
+// metrics.Read reuses the histogram's memory on the next call.
+func metricHistogram(s metrics.Sample) *metrics.Float64Histogram {
+	if s.Value.Kind() != metrics.KindFloat64Histogram {
+		return nil
+	}
+	h := s.Value.Float64Histogram()
+	return &metrics.Float64Histogram{
+		Counts:  append([]uint64(nil), h.Counts...),
+		Buckets: h.Buckets,
+	}
+}
+
+// histogramDelta returns the counts h gained since prev
+func histogramDelta(h, prev *metrics.Float64Histogram) []uint64 {
+	if h == nil {
+		return nil
+	}
+	counts := append([]uint64(nil), h.Counts...)
+	if prev != nil && len(prev.Counts) == len(counts) {
+		for i := range counts {
+			counts[i] -= prev.Counts[i]
+		}
+	}
+	return counts
+}
+
+// bucketValue is a representative value of bucket i: its upper bound, or
+// its lower bound for the last, open-ended bucket
+func bucketValue(buckets []float64, i int) float64 {
+	if upper := buckets[i+1]; !math.IsInf(upper, 1) {
+		return upper
+	}
+	return math.Max(buckets[i], 0)
+}
+
+// histogramQuantile returns quantile q of the samples h gained since prev
+func histogramQuantile(h, prev *metrics.Float64Histogram, q float64) float64 {
+	counts := histogramDelta(h, prev)
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen >= target {
+			return bucketValue(h.Buckets, i)
+		}
+	}
+	return 0
+}
+
+// histogramMax returns the largest sample h gained since prev, to bucket
+// resolution
+func histogramMax(h, prev *metrics.Float64Histogram) float64 {
+	counts := histogramDelta(h, prev)
+	for i := len(counts) - 1; i >= 0; i-- {
+		if counts[i] > 0 {
+			return bucketValue(h.Buckets, i)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// serverRequestStats counts the requests servers send readers during
+// playback
+type serverRequestStats struct {
+	mu        sync.Mutex
+	requests  map[string]int64 // By method
+	redirects atomic.Int64     // REDIRECT requests followed
+}
+
+// record adds the server requests of a finished session
+func (s *serverRequestStats) record(requests map[string]int, redirects int) {
+	s.redirects.Add(int64(redirects))
+	if len(requests) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.requests == nil {
+		s.requests = make(map[string]int64)
+	}
+	for method, n := range requests {
+		s.requests[method] += int64(n)
+	}
+}
+
+// fill copies the server request counts into stats
+func (s *serverRequestStats) fill(stats *Stats) {
+	stats.ServerRedirects = s.redirects.Load()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) > 0 {
+		stats.ServerRequests = make(map[string]int64, len(s.requests))
+		for method, n := range s.requests {
+			stats.ServerRequests[method] = n
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// completionBuckets is the number of 10% steps of the completion histogram;
+// sessions that ran their full intended duration get a bucket of their own
+const completionBuckets = 10
+
+// sessionLifetimes tracks how long good sessions lasted against how long
+// they were meant to, and why the server ended the ones it cut short
+type sessionLifetimes struct {
+	lifetime   *histogram.Histogram
+	intended   atomic.Int64 // Sessions with an intended duration
+	completion atomic.Int64 // Their sum of completion ratios, per mille
+	completed  atomic.Int64 // Sessions that reached their intended duration
+	early      [len(rtsp.TerminationCauses)]atomic.Int64
+	buckets    [completionBuckets + 1]atomic.Int64
+}
+
+// newSessionLifetimes creates empty lifetime statistics
+func newSessionLifetimes() *sessionLifetimes {
+	return &sessionLifetimes{lifetime: histogram.New()}
+}
+
+// record adds a session that ran from start until Run returned err, with
+// ctx the session's context, whose deadline is the intended end. Sessions
+// that never played are failed connects, and sessions the bench stopped
+// itself (end of run, drain, reader pools) were not cut short by the
+// server; neither is recorded. Pool sessions the server ended are, but
+// without an intended duration they have no completion.
+func (l *sessionLifetimes) record(ctx context.Context, client *rtsp.Client, err error, start time.Time) {
+	if !client.Playing() {
+		return
+	}
+	ended := err != nil && err != context.DeadlineExceeded && err != context.Canceled
+	if !ended && ctx.Err() != context.DeadlineExceeded {
+		return
+	}
+
+	lifetime := time.Since(start)
+	l.lifetime.Record(lifetime)
+	if ended {
+		cause := rtsp.ClassifyTermination(err)
+		for i, c := range rtsp.TerminationCauses {
+			if c == cause {
+				l.early[i].Add(1)
+			}
+		}
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+
+	l.intended.Add(1)
+	if !ended {
+		l.completed.Add(1)
+		l.completion.Add(1000)
+		l.buckets[completionBuckets].Add(1)
+		return
+	}
+	ratio := 1.0
+	if intended := deadline.Sub(start); intended > 0 && lifetime < intended {
+		ratio = lifetime.Seconds() / intended.Seconds()
+	}
+	l.completion.Add(int64(ratio * 1000))
+	bucket := int(ratio * completionBuckets)
+	if bucket >= completionBuckets {
+		bucket = completionBuckets - 1 // Cut short, if only just
+	}
+	l.buckets[bucket].Add(1)
+}
+
+// fill copies the lifetime statistics into stats
+func (l *sessionLifetimes) fill(stats *Stats) {
+	summary := l.lifetime.Summary()
+	if summary.Count == 0 {
+		return
+	}
+	stats.SessionLifetimes = int64(summary.Count)
+	stats.SessionsCompleted = l.completed.Load()
+	stats.SessionLifetimeAvg = summary.Mean / 1000
+	stats.SessionLifetimeP50 = summary.P50 / 1000
+	stats.SessionLifetimeP95 = summary.P95 / 1000
+	if intended := l.intended.Load(); intended > 0 {
+		stats.SessionCompletionAvg = float64(l.completion.Load()) / 10 / float64(intended)
+	}
+
+	stats.SessionCompletion = make(map[string]int64)
+	for i := range l.buckets {
+		if n := l.buckets[i].Load(); n > 0 {
+			stats.SessionCompletion[completionLabel(i)] = n
+		}
+	}
+	for i, c := range rtsp.TerminationCauses {
+		if n := l.early[i].Load(); n > 0 {
+			if stats.EarlyTerminationCauses == nil {
+				stats.EarlyTerminationCauses = make(map[string]int64)
+			}
+			stats.EarlyTerminationCauses[c] = n
+			stats.EarlyTerminations += n
+		}
+	}
+}
+
+// completionLabel names a completion bucket by its lower bound, e.g. "40"
+// for 40-50% of the intended duration and "100" for the full duration
+func completionLabel(i int) string {
+	return strconv.Itoa(i * 100 / completionBuckets)
+}
+
+// writeSessionLifetimes prints the lifetime line of the text summary, with
+// the early terminations by cause
+func writeSessionLifetimes(w io.Writer, stats Stats) error {
+	line := fmt.Sprintf("  Session lifetime: avg %.1fs, p50 %.1fs, p95 %.1fs | %.1f%% of intended on average | Early terminations: %d (%.1f%%)",
+		stats.SessionLifetimeAvg, stats.SessionLifetimeP50, stats.SessionLifetimeP95, stats.SessionCompletionAvg,
+		stats.EarlyTerminations, float64(stats.EarlyTerminations)*100/float64(stats.SessionLifetimes))
+	var causes []string
+	for _, c := range rtsp.TerminationCauses {
+		if n := stats.EarlyTerminationCauses[c]; n > 0 {
+			causes = append(causes, fmt.Sprintf("%s %d", c, n))
+		}
+	}
+	if len(causes) > 0 {
+		line += " (" + strings.Join(causes, ", ") + ")"
+	}
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
@@ -0,0 +1,49 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// newSharedUDP opens the shared UDP receive sockets of config, nil if
+// every reader track gets its own socket pair
+func newSharedUDP(config Config, d *dialer) (*rtsp.SharedUDP, error) {
+	if config.UDPShards <= 0 {
+		return nil, nil
+	}
+	src := rtsp.Source{Device: config.BindInterface}
+	shared, err := rtsp.NewSharedUDP(src, config.UDPShards, config.UDPShardWorkers, config.UDPShardPort, d.sockOpts)
+	if err != nil {
+		return nil, fmt.Errorf("shared UDP: %w", err)
+	}
+	return shared, nil
+}
+
+// fillSharedUDP copies the shared socket counts into stats
+func fillSharedUDP(stats *Stats, shared *rtsp.SharedUDP) {
+	if shared == nil {
+		return
+	}
+	s := shared.Stats()
+	stats.SharedUDPPackets = s.Packets
+	stats.SharedUDPUnrouted = s.Unrouted
+	stats.SharedUDPFanoutMax = int64(s.FanoutMax)
+	var total, busiest uint64
+	for _, n := range s.SocketPackets {
+		total += n
+		busiest = max(busiest, n)
+	}
+	if total > 0 {
+		stats.SharedUDPBusiestPct = float64(busiest) * 100 / float64(total)
+	}
+}
+
+// writeSharedUDP prints the shared socket line of the text summary
+func writeSharedUDP(w io.Writer, stats Stats) error {
+	_, err := fmt.Fprintf(w, "  Shared UDP: %d packets | Unrouted: %d | Busiest socket: %.1f%% of RTP | Readers per route: max %d\n",
+		stats.SharedUDPPackets, stats.SharedUDPUnrouted, stats.SharedUDPBusiestPct, stats.SharedUDPFanoutMax)
+	return err
+}
@@ -0,0 +1,13 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build !windows
+
+package bench
+
+import (
+	"os"
+	"syscall"
+)
+
+// dumpSignal asks for a state dump in the log
+var dumpSignal os.Signal = syscall.SIGUSR1
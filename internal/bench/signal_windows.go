@@ -0,0 +1,10 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build windows
+
+package bench
+
+import "os"
+
+// dumpSignal is not available on Windows, which has no SIGUSR1
+var dumpSignal os.Signal
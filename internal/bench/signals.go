@@ -0,0 +1,190 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// goroutineTop is how many goroutine entry functions a state dump lists
+const goroutineTop = 10
+
+// watchDumpSignal logs a state dump on every dumpSignal (SIGUSR1) until
+// ctx ends: the full stats, goroutines by entry function and the live
+// connections
+func (r *Runner) watchDumpSignal(ctx context.Context, getStats func() Stats, rates *connRates) {
+	if dumpSignal == nil {
+		return
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, dumpSignal)
+	go func() {
+		defer signal.Stop(sig)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sig:
+			}
+			r.dumpState(getStats, rates)
+		}
+	}()
+}
+
+// dumpState logs the current stats, goroutines and connections
+func (r *Runner) dumpState(getStats func() Stats, rates *connRates) {
+	r.log.Info("state dump: stats", "stats", getStats())
+	r.log.Info("state dump: goroutines", "count", runtime.NumGoroutine(), "top", goroutineSummary(goroutineTop))
+
+	conns := rates.list()
+	byTarget := make(map[string]int)
+	byTransport := make(map[string]int)
+	var oldest float64
+	kbps := make([]float64, 0, len(conns))
+	for _, c := range conns {
+		byTarget[c.Target]++
+		transport := c.Transport
+		if transport == "" {
+			transport = "default"
+		}
+		byTransport[transport]++
+		oldest = max(oldest, c.AgeSeconds)
+		if c.Sampled {
+			kbps = append(kbps, c.BitrateKbps)
+		}
+	}
+	var median float64
+	if len(kbps) > 0 {
+		sort.Float64s(kbps)
+		median = kbps[len(kbps)/2]
+	}
+	r.log.Info("state dump: connections", "count", len(conns), "by_target", byTarget, "by_transport", byTransport,
+		"oldest", time.Duration(oldest*float64(time.Second)).Round(time.Second), "median_kbps", math.Round(median))
+}
+
+// goroutineSummary counts goroutines by the function they were started
+// with, largest groups first, as "function=count"
+func goroutineSummary(top int) []string {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil
+	}
+
+	// Blocks of identical stacks: "N @ addresses", then one "#" line per
+	// frame, innermost first
+	counts := make(map[string]int)
+	var n int
+	var entry string
+	flush := func() {
+		if n > 0 && entry != "" {
+			counts[entry] += n
+		}
+		n, entry = 0, ""
+	}
+	sc := bufio.NewScanner(&buf)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "#"):
+			if fields := strings.Fields(line); len(fields) >= 3 {
+				entry = fields[2]
+				if i := strings.LastIndex(entry, "+0x"); i > 0 {
+					entry = entry[:i]
+				}
+			}
+		case strings.Contains(line, " @ "):
+			flush()
+			fmt.Sscanf(line, "%d @", &n)
+		}
+	}
+	flush()
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+	if len(names) > top {
+		names = names[:top]
+	}
+	summary := make([]string, len(names))
+	for i, name := range names {
+		summary[i] = fmt.Sprintf("%s=%d", strings.TrimPrefix(name, modulePath+"/internal/"), counts[name])
+	}
+	return summary
+}
+
+// modulePath is trimmed from function names in goroutine summaries
+const modulePath = "github.com/winkstreaming/wink-rtsp-bench"
+
+// watchScenarioFile re-reads Config.ScenarioFile on every SIGHUP until ctx
+// ends, applying its phase reader counts and bad client ratios
+func (r *Runner) watchScenarioFile(ctx context.Context) {
+	if r.config.ScenarioFile == "" || r.config.Scenario == nil {
+		return
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+			}
+			if err := r.reloadScenario(); err != nil {
+				r.log.Error("scenario reload failed", "file", r.config.ScenarioFile, "error", err)
+			}
+		}
+	}()
+}
+
+// reloadScenario applies the reader counts and bad client ratios of
+// Config.ScenarioFile to the running scenario. The phases must keep their
+// number and types; other changes are ignored, since the run has already
+// been set up for them.
+func (r *Runner) reloadScenario() error {
+	sc, err := LoadScenario(r.config.ScenarioFile)
+	if err != nil {
+		return err
+	}
+	current := r.scenario.Load()
+	if len(sc.Phases) != len(current.Phases) {
+		return fmt.Errorf("phase count changed from %d to %d", len(current.Phases), len(sc.Phases))
+	}
+	for i, ph := range sc.Phases {
+		if ph.Type != current.Phases[i].Type {
+			return fmt.Errorf("phase %d: type changed from %s to %s", i+1, current.Phases[i].Type, ph.Type)
+		}
+	}
+
+	next := *current
+	next.BadClientRatio = sc.BadClientRatio
+	next.Phases = slices.Clone(current.Phases)
+	for i, ph := range sc.Phases {
+		next.Phases[i].Readers = ph.Readers
+		next.Phases[i].BadClientRatio = ph.BadClientRatio
+	}
+	r.scenario.Store(&next)
+	r.log.Info("scenario reloaded", "file", r.config.ScenarioFile, "phase", r.controlStatus().Phase)
+	return nil
+}
@@ -0,0 +1,51 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// silentStats tracks how the server reaped the SilentHolder and HalfOpen
+// bad clients
+type silentStats struct {
+	holders  atomic.Int64
+	reaped   atomic.Int64
+	vanished atomic.Int64
+	reapTime *histogram.Histogram
+}
+
+// newSilentStats creates empty silent connection statistics
+func newSilentStats() *silentStats {
+	return &silentStats{reapTime: histogram.New()}
+}
+
+// record adds a finished silent connection; failed connects are ignored
+func (s *silentStats) record(res rtsp.IdleResult, log *slog.Logger) {
+	if !res.Connected {
+		return
+	}
+	s.holders.Add(1)
+	if res.Reaped {
+		s.reaped.Add(1)
+		s.reapTime.Record(res.After)
+	}
+	if res.Vanished {
+		s.vanished.Add(1)
+	}
+	log.Debug("silent connection finished", "reaped", res.Reaped, "after_ms", res.After.Milliseconds(),
+		"vanished", res.Vanished)
+}
+
+// fill copies the silent connection statistics into stats
+func (s *silentStats) fill(stats *Stats) {
+	reap := s.reapTime.Summary()
+	stats.SilentHolders = s.holders.Load()
+	stats.SilentReaped = s.reaped.Load()
+	stats.SilentReapAvg = reap.Mean
+	stats.SilentReapMax = reap.Max
+	stats.HalfOpenVanished = s.vanished.Load()
+}
@@ -3,27 +3,67 @@ package bench
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"log/slog"
 	"math"
 	"math/rand"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
 	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+	"golang.org/x/time/rate"
 )
 
 // RealWorldSimulator simulates realistic traffic patterns
 type RealWorldSimulator struct {
 	config      Config
 	aggregator  *rtp.Aggregator
+	targets     *targetSet
+	bwLimiter   *rate.Limiter
+	startTime   time.Time
+	teardowns   *teardownStats
+	lifetimes   *sessionLifetimes
+	dialer      *dialer // Source addresses, address families and DNS; set by Run
+	transcripts *transcriptDumper // Saves failed exchanges; set by Run
+	captures    *captureSampler   // Sessions written to pcap files; set by Run
+	arrivals    *arrivalSampler   // Sessions whose packet arrivals are logged; set by Run
+	monitor     *HostMonitor      // Target host resources; set by Run
+	self        *SelfMonitor      // The bench process's own resources; set by Run
+	mediamtx    *mtxChecker       // Server-side session cross-check; set by Run
+	hops        *hopStats         // Nodes answering readers, nil if not checked; set by Run
+	sharedUDP   *rtsp.SharedUDP   // UDP receive sockets shared by all readers, nil if none; set by Run
+	players     *playerPicker     // Player profiles readers mimic, nil = none; set by Run
+	tracks      *trackPicker      // Track selections of readers, nil = all tracks; set by Run
+	qoe         *qoeScorer        // Session QoE scores; set by Run
+	families    *familyStats
+	slowReaders *slowReaderStats
+	groups      *groupSet
+	rates       *connRates
+	random      *lockedRand
+	lifetime    *Lifetime // Session lengths; set by Run
+	clock       simClock  // Simulated time of day; set by Run
+	events      *eventSet // Traffic events; set by Run
+	popularity  *popularityChurn // Ranking shifts of the targets; set by Run
+	started     atomic.Bool      // Run has finished setting up; GetStats reads nothing before
+	log         *slog.Logger
 	
 	// Statistics
 	activeConnects  atomic.Int64
+	peakActive      atomic.Int64
+	nextConnID      atomic.Uint64
 	totalConnects   atomic.Int64
 	totalFailures   atomic.Int64
+	failures        failureStats
+	methods         methodStats
+	redirects       redirectStats
+	serverRequests  serverRequestStats
 	targetConnects  atomic.Int64
+	baseTarget      atomic.Int64 // Target of the load pattern, before traffic events
+	eventExtra      int64        // Traffic event viewers at the last adjustment
+	sessionTimeouts atomic.Int64
 	
 	// Control
 	connections map[string]*Connection
@@ -44,15 +84,98 @@ func NewRealWorldSimulator(config Config, agg *rtp.Aggregator) *RealWorldSimulat
 	return &RealWorldSimulator{
 		config:      config,
 		aggregator:  agg,
+		targets:     newTargetSet(config, agg),
+		bwLimiter:   newBandwidthLimiter(config.MaxBandwidthMbps),
+		teardowns:   newTeardownStats(),
+		lifetimes:   newSessionLifetimes(),
+		families:    &familyStats{},
+		slowReaders: newSlowReaderStats(),
+		groups:      newGroupSet(),
+		rates:       newConnRates(),
+		random:      newLockedRand(config.Seed),
+		log:         newLogger(config),
 		connections: make(map[string]*Connection),
 	}
 }
 
 // Run executes the real-world simulation
 func (s *RealWorldSimulator) Run(ctx context.Context) error {
-	fmt.Printf("[%s] Starting real-world simulation\n", time.Now().Format("15:04:05"))
-	fmt.Printf("[%s] Target: %d avg connections (±%.0f%% variance)\n", 
-		time.Now().Format("15:04:05"), s.config.AvgConnections, s.config.Variance*100)
+	if s.dialer == nil {
+		dialer, err := newDialer(s.config)
+		if err != nil {
+			return err
+		}
+		s.dialer = dialer
+	}
+	if s.transcripts == nil {
+		transcripts, err := newTranscriptDumper(s.config)
+		if err != nil {
+			return err
+		}
+		s.transcripts = transcripts
+	}
+	if s.captures == nil {
+		captures, err := newCaptureSampler(s.config, s.random)
+		if err != nil {
+			return err
+		}
+		s.captures = captures
+	}
+	if s.arrivals == nil {
+		arrivals, err := newArrivalSampler(s.config, s.random)
+		if err != nil {
+			return err
+		}
+		s.arrivals = arrivals
+	}
+	if s.players == nil {
+		players, err := newPlayerPicker(s.config)
+		if err != nil {
+			return err
+		}
+		s.players = players
+	}
+	if s.tracks == nil {
+		tracks, err := newTrackPicker(s.config)
+		if err != nil {
+			return err
+		}
+		s.tracks = tracks
+	}
+	if s.hops == nil {
+		hops, err := newHopStats(s.config)
+		if err != nil {
+			return err
+		}
+		s.hops = hops
+	}
+	if s.qoe == nil {
+		qoe, err := newQoEScorer(s.config, s.rates)
+		if err != nil {
+			return err
+		}
+		s.qoe = qoe
+	}
+	lifetime, err := newLifetime(s.config)
+	if err != nil {
+		return err
+	}
+	s.lifetime = lifetime
+	if err := validateLoadPattern(s.config); err != nil {
+		return err
+	}
+	s.clock = newSimClock(s.config.TimeCompression)
+	if s.events, err = newEventSet(s.config.Events); err != nil {
+		return err
+	}
+	if s.popularity, err = newPopularityChurn(s.config); err != nil {
+		return err
+	}
+	s.startTime = time.Now()
+	s.started.Store(true)
+	s.log.Info("starting real-world simulation", "avg_connections", s.config.AvgConnections,
+		"variance_pct", s.config.Variance*100, "lifetime", lifetime.Type, "mean_lifetime", lifetime.mean(),
+		"time_compression", s.clock.compression, "target_curve_minutes", len(s.config.TargetCurve))
 	
 	// Start load pattern generator
 	s.wg.Add(1)
@@ -61,11 +184,13 @@ func (s *RealWorldSimulator) Run(ctx context.Context) error {
 	// Start connection manager
 	s.wg.Add(1)
 	go s.manageConnections(ctx)
+	go s.rates.run(ctx)
+	go s.popularity.run(ctx, s.targets, s.clock, s.random, s.log)
 	
 	// Wait for completion
 	<-ctx.Done()
 	
-	fmt.Printf("[%s] Shutting down simulation...\n", time.Now().Format("15:04:05"))
+	s.log.Info("shutting down simulation", "active", s.activeConnects.Load())
 	s.wg.Wait()
 	
 	return nil
@@ -75,11 +200,11 @@ func (s *RealWorldSimulator) Run(ctx context.Context) error {
 func (s *RealWorldSimulator) generateLoadPattern(ctx context.Context) {
 	defer s.wg.Done()
 	
-	ticker := time.NewTicker(10 * time.Second) // Adjust load every 10 seconds
+	ticker := time.NewTicker(s.clock.tick())
 	defer ticker.Stop()
 	
 	// Initial target
-	s.targetConnects.Store(int64(s.config.AvgConnections))
+	s.adjustTargetLoad()
 	
 	for {
 		select {
@@ -93,31 +218,29 @@ func (s *RealWorldSimulator) generateLoadPattern(ctx context.Context) {
 
 // adjustTargetLoad simulates realistic load variations
 func (s *RealWorldSimulator) adjustTargetLoad() {
+	now := s.clock.now()
+	
+	// A recorded curve is replayed as is
+	if curve := s.config.TargetCurve; len(curve) > 0 {
+		target := curve[int(s.clock.elapsed()/time.Minute)%len(curve)]
+		s.baseTarget.Store(int64(target))
+		s.log.Info("load adjustment", "target", target, "active", s.activeConnects.Load(),
+			"sim_time", now.Format("15:04"))
+		return
+	}
+	
 	avg := float64(s.config.AvgConnections)
 	variance := s.config.Variance
 	
-	// Generate patterns: peak hours, off-hours, gradual changes
-	hour := time.Now().Hour()
-	dayFactor := 1.0
-	
-	// Simulate daily patterns
-	switch {
-	case hour >= 9 && hour <= 11:   // Morning peak
-		dayFactor = 1.2
-	case hour >= 12 && hour <= 13:  // Lunch dip
-		dayFactor = 0.9
-	case hour >= 14 && hour <= 17:  // Afternoon steady
-		dayFactor = 1.1
-	case hour >= 18 && hour <= 22:  // Evening peak
-		dayFactor = 1.3
-	case hour >= 23 || hour <= 5:   // Night low
-		dayFactor = 0.6
-	default:
-		dayFactor = 0.8
+	// Simulate daily patterns: peak hours, off-hours, gradual changes
+	curve := s.config.DailyCurve
+	if len(curve) == 0 {
+		curve = builtinDailyCurve
 	}
+	dayFactor := curveFactor(curve, now)
 	
 	// Add random variation
-	randomFactor := 1.0 + (rand.Float64()-0.5)*variance
+	randomFactor := 1.0 + (s.random.Float64()-0.5)*variance
 	
 	// Calculate new target
 	newTarget := int64(avg * dayFactor * randomFactor)
@@ -133,10 +256,10 @@ func (s *RealWorldSimulator) adjustTargetLoad() {
 		newTarget = maxTarget
 	}
 	
-	s.targetConnects.Store(newTarget)
+	s.baseTarget.Store(newTarget)
 	
-	fmt.Printf("[%s] Load adjustment: target=%d active=%d\n",
-		time.Now().Format("15:04:05"), newTarget, s.activeConnects.Load())
+	s.log.Info("load adjustment", "target", newTarget, "active", s.activeConnects.Load(),
+		"sim_time", now.Format("15:04"))
 }
 
 // manageConnections handles connection lifecycle
@@ -159,16 +282,30 @@ func (s *RealWorldSimulator) manageConnections(ctx context.Context) {
 
 // adjustConnections adds or removes connections to meet target
 func (s *RealWorldSimulator) adjustConnections(ctx context.Context) {
+	// Traffic events come on top of the load pattern, and may add or
+	// remove viewers faster than the burst limits
+	elapsed := time.Since(s.startTime)
+	extra := s.events.extra(elapsed)
+	addLimit, removeLimit := int64(50), int64(20)
+	if extra > s.eventExtra {
+		addLimit += extra - s.eventExtra
+	} else {
+		removeLimit += s.eventExtra - extra
+	}
+	s.eventExtra = extra
+	s.events.report(elapsed, s.log)
+	
 	current := s.activeConnects.Load()
-	target := s.targetConnects.Load()
+	target := s.baseTarget.Load() + extra
+	s.targetConnects.Store(target)
 	
 	diff := target - current
 	
 	if diff > 0 {
 		// Add connections
 		toAdd := diff
-		if toAdd > 50 { // Limit burst additions
-			toAdd = 50
+		if toAdd > addLimit { // Limit burst additions
+			toAdd = addLimit
 		}
 		
 		for i := int64(0); i < toAdd; i++ {
@@ -178,8 +315,8 @@ func (s *RealWorldSimulator) adjustConnections(ctx context.Context) {
 	} else if diff < 0 {
 		// Remove connections
 		toRemove := -diff
-		if toRemove > 20 { // Limit burst removals
-			toRemove = 20
+		if toRemove > removeLimit { // Limit burst removals
+			toRemove = removeLimit
 		}
 		
 		s.removeConnections(toRemove)
@@ -191,38 +328,91 @@ func (s *RealWorldSimulator) addConnection(ctx context.Context) {
 	defer s.wg.Done()
 	
 	// Create unique ID
-	connID := fmt.Sprintf("conn-%d-%d", time.Now().UnixNano(), rand.Int())
+	connID := strconv.FormatUint(s.nextConnID.Add(1), 10)
+	event := s.events.current(time.Since(s.startTime))
+	if event != nil {
+		event.attempts.Add(1)
+	}
 	
 	// Create client
-	client, err := rtsp.NewClient(s.config.URL, s.config.Transport, s.aggregator)
+	t := s.targets.pick()
+	log := s.log.With("conn", connID, "target", t.url)
+	behavior := newViewer(s.config, s.random)
+	behavior.player = s.players.pick(s.random)
+	behavior.tracks = s.tracks.pick(s.random)
+	client, err := rtsp.NewClient(t.url, readerTransport(s.config.Transport, behavior.player), t.aggregator)
 	if err != nil {
+		log.Warn("client creation failed", "error", err)
 		s.totalFailures.Add(1)
+		s.failures.record(rtsp.FailureOther)
+		t.failures.Add(1)
+		if event != nil {
+			event.failures.Add(1)
+		}
 		return
 	}
+	client.Seed(s.random.Int63())
+	client.SetBandwidthLimiter(s.bwLimiter)
+	client.SetGroupTracker(s.groups)
+	client.SetResponseRecorder(&s.methods)
+	client.SetFollowServerRedirects(s.config.FollowServerRedirects)
+	s.hops.apply(client, log)
+	client.SetSharedUDP(s.sharedUDP)
+	s.dialer.apply(client)
+	s.transcripts.apply(client)
+	applyIdentity(client, s.config, connID, behavior.player)
+	if s.config.FallbackTimeout > 0 {
+		client.SetFallbackTimeout(s.config.FallbackTimeout)
+	}
+	if s.config.StallThreshold > 0 {
+		client.SetStallThreshold(s.config.StallThreshold)
+	}
+	client.SetKeepAliveRetry(s.config.KeepAliveAttempts, s.config.KeepAliveBackoff)
+	client.SetResume(s.config.ResumeAttempts, s.config.ResumeBackoff)
+	client.SetTimeouts(s.config.RequestTimeout, s.config.MessageTimeout)
+	if capture := s.captures.open(connID, log); capture != nil {
+		defer capture.Close()
+		client.SetCapture(capture)
+	}
+	if arrivals := s.arrivals.open(connID, log); arrivals != nil {
+		defer arrivals.Close()
+		client.SetArrivalLog(arrivals)
+	}
+	if s.config.Username != "" {
+		client.SetCredentials(s.config.Username, s.config.Password)
+	}
+	behavior.apply(client)
 	
 	// Connect
-	if err := client.Connect(); err != nil {
+	connectStart := time.Now()
+	err = client.Connect()
+	s.families.record(client.DialAttempts())
+	if err != nil {
+		category := rtsp.ClassifyFailure(err, false)
+		log.Warn("connect failed", "error", err, "category", category)
 		s.totalFailures.Add(1)
+		s.failures.record(category)
+		t.failures.Add(1)
+		s.transcripts.dump(client, category, err, t.url, log)
+		if event != nil {
+			event.failures.Add(1)
+		}
 		return
 	}
+	t.connectLatency.Record(time.Since(connectStart))
+	if event != nil {
+		event.connects.Add(1)
+		event.latency.Record(time.Since(connectStart))
+	}
 	
 	// Update stats
 	s.totalConnects.Add(1)
-	s.activeConnects.Add(1)
-	
-	// Random session duration (realistic variance)
-	minDuration := 30 * time.Second
-	maxDuration := s.config.Duration
-	if maxDuration <= minDuration {
-		maxDuration = 5 * time.Minute
-	}
+	t.connects.Add(1)
+	trackPeak(&s.peakActive, s.activeConnects.Add(1))
+	s.popularity.joined(t)
 	
-	durationRange := maxDuration - minDuration
-	if durationRange <= 0 {
-		durationRange = 4*time.Minute + 30*time.Second
-	}
-	
-	duration := minDuration + time.Duration(rand.Int63n(int64(durationRange)))
+	// Random session duration from the viewer lifetime model
+	duration := s.lifetime.sample(s.random)
 	
 	// Create context with timeout
 	connCtx, cancel := context.WithTimeout(ctx, duration)
@@ -238,13 +428,35 @@ func (s *RealWorldSimulator) addConnection(ctx context.Context) {
 	s.connMu.Lock()
 	s.connections[connID] = conn
 	s.connMu.Unlock()
+	s.rates.add(connID, t.url, s.config.Transport, behavior.tracks, client)
 	
 	// Run session
-	if err := client.Run(connCtx); err != nil && err != context.DeadlineExceeded && err != context.Canceled {
+	start := time.Now()
+	err = client.Run(connCtx)
+	if err != nil && err != context.DeadlineExceeded && err != context.Canceled {
+		category := rtsp.ClassifyFailure(err, client.Playing())
 		s.totalFailures.Add(1)
+		s.failures.record(category)
+		t.failures.Add(1)
+		if errors.Is(err, rtsp.ErrSessionExpired) {
+			s.sessionTimeouts.Add(1)
+		}
+		if event != nil && !client.Playing() {
+			event.failures.Add(1)
+		}
+		log.Warn("session failed", "error", err, "category", category)
+		s.transcripts.dump(client, category, err, t.url, log)
 	}
+	s.slowReaders.record(client.SlowReadResult(), err, log)
+	s.qoe.record(client, behavior.tracks)
+	s.lifetimes.record(connCtx, client, err, start)
+	s.redirects.record(client.Redirects(), err)
+	s.serverRequests.record(client.ServerRequests(), client.ServerRedirects())
+	s.teardowns.record(client.LastTeardown())
 	
 	// Cleanup
+	s.popularity.left(t)
+	s.rates.remove(connID)
 	s.connMu.Lock()
 	delete(s.connections, connID)
 	s.connMu.Unlock()
@@ -283,19 +495,86 @@ func (s *RealWorldSimulator) closeAllConnections() {
 	s.connections = make(map[string]*Connection)
 }
 
-// GetStats returns current statistics
+// GetStats returns current statistics, zero until Run has set up
 func (s *RealWorldSimulator) GetStats() Stats {
+	if !s.started.Load() {
+		return Stats{}
+	}
 	snapshot := s.aggregator.Snapshot()
 	
-	return Stats{
+	stats := Stats{
 		ActiveConnects:  s.activeConnects.Load(),
+		PeakActive:      s.peakActive.Load(),
 		TotalConnects:   s.totalConnects.Load(),
 		TotalFailures:   s.totalFailures.Load(),
+		SessionTimeouts: s.sessionTimeouts.Load(),
 		TargetConnects:  s.targetConnects.Load(),
 		RTPPackets:      snapshot.Packets,
 		RTPLoss:         snapshot.Lost,
 		RTPBytes:        snapshot.Bytes,
+		RTPRejected:     snapshot.Rejected,
+		TransportFallbacks: snapshot.Fallbacks,
+		KeepAliveRetries: snapshot.KeepAliveRetries,
+		KeepAlivesRecovered: snapshot.KeepAliveRecovered,
+		SessionResumes:  snapshot.SessionResumes,
+		SessionResumeFailures: snapshot.SessionResumeFailures,
+		InterruptionAvg: snapshot.InterruptionAvg,
+		InterruptionP95: snapshot.InterruptionP95,
+		InterruptionMax: snapshot.InterruptionMax,
+		JitterMin:       snapshot.JitterMin,
+		JitterAvg:       snapshot.JitterAvg,
+		JitterMax:       snapshot.JitterMax,
+		TTFPMin:         snapshot.TTFPMin,
+		TTFPAvg:         snapshot.TTFPAvg,
+		TTFPP95:         snapshot.TTFPP95,
+		TTFPMax:         snapshot.TTFPMax,
+		Keyframes:       snapshot.Keyframes,
+		FragmentErrors:  snapshot.FragmentErrors,
+		AudioFrames:     snapshot.AudioFrames,
+		AudioMalformed:  snapshot.AudioMalformed,
+		AudioMalformedSessions: snapshot.AudioMalformedSessions,
+		GOPAvg:          snapshot.GOPAvg,
+		FramesVerified:  snapshot.FramesVerified,
+		FramesCorrupt:   snapshot.FramesCorrupt,
+		Targets:         s.targets.stats(time.Since(s.startTime)),
+		MulticastGroups: s.groups.stats(time.Since(s.startTime)),
+	}
+	s.teardowns.fill(&stats)
+	s.lifetimes.fill(&stats)
+	s.families.fill(&stats)
+	s.failures.fill(&stats)
+	s.methods.fill(&stats)
+	s.redirects.fill(&stats)
+	s.serverRequests.fill(&stats)
+	s.hops.fill(&stats)
+	fillSharedUDP(&stats, s.sharedUDP)
+	if s.events != nil {
+		s.events.fill(&stats, time.Since(s.startTime))
 	}
+	s.slowReaders.fill(&stats)
+	s.dialer.fill(&stats)
+	fillClockStats(&stats, snapshot)
+	fillViewerStats(&stats, snapshot)
+	fillE2EStats(&stats, snapshot)
+	fillONVIFStats(&stats, snapshot)
+	fillMPEGTSStats(&stats, snapshot)
+	fillStallStats(&stats, snapshot)
+	fillLossBursts(&stats, snapshot)
+	fillReordering(&stats, snapshot)
+	s.rates.fill(&stats)
+	s.monitor.Fill(&stats)
+	s.self.Fill(&stats)
+	s.popularity.fill(&stats, s.targets)
+	s.mediamtx.fill(&stats)
+	s.players.fill(&stats)
+	s.tracks.fill(&stats)
+	s.qoe.fill(&stats)
+	return stats
+}
+
+// Connections returns the live connections with their current rates
+func (s *RealWorldSimulator) Connections() []ConnectionStats {
+	return s.rates.list()
 }
 
 // LoadPattern represents different load patterns
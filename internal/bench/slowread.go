@@ -0,0 +1,84 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// Slow consumer defaults
+const (
+	DefaultSlowReaderKbps = 500
+	SlowLagThreshold      = time.Second // Lag above this means the server queued media for the reader
+)
+
+// Slow reader outcomes, from the most to the least drastic server reaction
+const (
+	SlowOutcomeDisconnected = "disconnected" // The server closed or broke the session
+	SlowOutcomeDropping     = "dropping"     // The server skipped packets to keep up
+	SlowOutcomeBuffering    = "buffering"    // The server queued media; delivery fell behind real time
+	SlowOutcomeKeptUp       = "kept-up"      // The stream fit within the read rate
+)
+
+// slowReaderStats tracks how the server treated rate-capped readers
+type slowReaderStats struct {
+	sessions     atomic.Int64
+	disconnected atomic.Int64
+	dropping     atomic.Int64
+	buffering    atomic.Int64
+	stalled      atomic.Int64 // Sessions with at least one media gap
+	lag          *histogram.Histogram
+}
+
+// newSlowReaderStats creates empty slow reader statistics
+func newSlowReaderStats() *slowReaderStats {
+	return &slowReaderStats{lag: histogram.New()}
+}
+
+// record classifies a finished session of a slow reader; err is what its
+// Run returned. Other readers are ignored.
+func (s *slowReaderStats) record(res rtsp.SlowReadResult, err error, log *slog.Logger) {
+	if !res.Enabled {
+		return
+	}
+	s.sessions.Add(1)
+	if res.Stalls > 0 {
+		s.stalled.Add(1)
+	}
+	if res.Lag > 0 {
+		s.lag.Record(res.Lag)
+	}
+
+	outcome := SlowOutcomeKeptUp
+	switch {
+	case err != nil && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled):
+		outcome = SlowOutcomeDisconnected
+		s.disconnected.Add(1)
+	case res.Lost > 0:
+		outcome = SlowOutcomeDropping
+		s.dropping.Add(1)
+	case res.Lag > SlowLagThreshold:
+		outcome = SlowOutcomeBuffering
+		s.buffering.Add(1)
+	}
+	log.Info("slow reader finished", "outcome", outcome, "packets", res.Packets, "lost", res.Lost,
+		"lag_ms", res.Lag.Milliseconds(), "stalls", res.Stalls)
+}
+
+// fill copies the slow reader statistics into stats
+func (s *slowReaderStats) fill(stats *Stats) {
+	lag := s.lag.Summary()
+	stats.SlowReaders = s.sessions.Load()
+	stats.SlowDisconnected = s.disconnected.Load()
+	stats.SlowDropping = s.dropping.Load()
+	stats.SlowBuffering = s.buffering.Load()
+	stats.SlowStalled = s.stalled.Load()
+	stats.SlowLagAvg = lag.Mean
+	stats.SlowLagMax = lag.Max
+}
@@ -0,0 +1,113 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// maxSourceIPs bounds how many addresses a CIDR in Config.SourceIPs may
+// expand to
+const maxSourceIPs = 65536
+
+// sourcePool rotates connections through local source addresses so one
+// bench host is not limited to the ephemeral ports of a single IP
+type sourcePool struct {
+	ips    []net.IP
+	device string
+	next   atomic.Uint64
+}
+
+// newSourcePool builds the pool from Config.SourceIPs and
+// Config.BindInterface. Entries are addresses or CIDR ranges; IPv4 ranges
+// skip their network and broadcast addresses. Returns nil if neither is set.
+func newSourcePool(config Config) (*sourcePool, error) {
+	if len(config.SourceIPs) == 0 && config.BindInterface == "" {
+		return nil, nil
+	}
+
+	p := &sourcePool{device: config.BindInterface}
+	for _, entry := range config.SourceIPs {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid source IP %q", entry)
+			}
+			p.ips = append(p.ips, ip)
+			continue
+		}
+		ips, err := expandCIDR(entry, maxSourceIPs-len(p.ips))
+		if err != nil {
+			return nil, err
+		}
+		p.ips = append(p.ips, ips...)
+	}
+	return p, nil
+}
+
+// expandCIDR lists the usable host addresses of a CIDR range, failing if
+// there are more than limit
+func expandCIDR(cidr string, limit int) ([]net.IP, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source range %q: %w", cidr, err)
+	}
+	ones, bits := network.Mask.Size()
+	if bits-ones > 16 || 1<<(bits-ones) > limit+2 {
+		return nil, fmt.Errorf("source range %q has more than %d addresses", cidr, maxSourceIPs)
+	}
+
+	var ips []net.IP
+	for ip := network.IP.Mask(network.Mask); network.Contains(ip); ip = nextIP(ip) {
+		ips = append(ips, ip)
+	}
+	// Network and broadcast addresses are not usable as IPv4 sources
+	if network.IP.To4() != nil && bits-ones >= 2 {
+		ips = ips[1 : len(ips)-1]
+	}
+	if len(ips) > limit {
+		return nil, fmt.Errorf("source range %q has more than %d addresses", cidr, maxSourceIPs)
+	}
+	return ips, nil
+}
+
+// nextIP returns the address after ip
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// pick returns the source for the next connection
+func (p *sourcePool) pick() rtsp.Source {
+	if p == nil {
+		return rtsp.Source{}
+	}
+	src := rtsp.Source{Device: p.device}
+	if len(p.ips) > 0 {
+		src.IP = p.ips[(p.next.Add(1)-1)%uint64(len(p.ips))]
+	}
+	return src
+}
+
+// size returns the number of source addresses, 0 for the kernel default
+func (p *sourcePool) size() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.ips)
+}
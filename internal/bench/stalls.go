@@ -0,0 +1,29 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+)
+
+// fillStallStats copies the media stall statistics into stats, with the
+// share of sessions that stalled
+func fillStallStats(stats *Stats, snapshot rtp.Snapshot) {
+	stats.Stalls = snapshot.Stalls
+	stats.StallTime = snapshot.StallTime
+	stats.StallAvg = snapshot.StallAvg
+	stats.StallMax = snapshot.StallMax
+	stats.StalledSessions = snapshot.MediaStalledSessions
+	if snapshot.MediaStallSessions > 0 {
+		stats.StalledPct = float64(snapshot.MediaStalledSessions) * 100 / float64(snapshot.MediaStallSessions)
+	}
+}
+
+// writeStalls prints the stall line of the text summary
+func writeStalls(w io.Writer, stats Stats) error {
+	_, err := fmt.Fprintf(w, "  Stalls: %d (%.1fs total, avg %.0fms, max %.0fms) | Stalled sessions: %d (%.1f%%)\n",
+		stats.Stalls, stats.StallTime, stats.StallAvg, stats.StallMax, stats.StalledSessions, stats.StalledPct)
+	return err
+}
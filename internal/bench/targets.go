@@ -0,0 +1,276 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/codec"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+)
+
+// Target distribution modes
+const (
+	DistributionRoundRobin = "round-robin"
+	DistributionWeighted   = "weighted"
+	DistributionZipf       = "zipf" // Weighted by popularity rank, in list order
+)
+
+// target is a single benchmark URL with its own statistics
+type target struct {
+	url    string
+
+	aggregator     *rtp.Aggregator // Child of the run-wide aggregator
+	connectLatency *histogram.Histogram
+	connects       atomic.Int64
+	failures       atomic.Int64
+	active         atomic.Int64 // Readers connected to it now
+	retired        atomic.Bool  // Taken out of the list by a swap
+
+	// Frame verification: the first reader is the reference, the next
+	// Config.VerifyReaders readers verify against it
+	reference *codec.Reference
+	verifiers atomic.Int64
+	hasRef    atomic.Bool
+}
+
+// targetSet distributes connections across benchmark URLs. The list can
+// be swapped during the run; targets taken out keep their statistics.
+type targetSet struct {
+	targets     []*target // Every target of the run, retired ones included
+	list        atomic.Pointer[targetList] // Targets new connections go to
+	weighted    bool
+	zipf        float64 // Zipf exponent, 0 unless the zipf distribution
+	next        atomic.Uint64
+	random      *lockedRand
+	aggregator  *rtp.Aggregator // Parent of the targets' aggregators
+	mu          sync.Mutex      // Guards targets once the run started
+}
+
+// targetList is the current target list with its weights
+type targetList struct {
+	targets     []*target
+	weights     []int
+	totalWeight int
+}
+
+// TargetStats holds per-URL statistics
+type TargetStats struct {
+	Connects       int64   `json:"connects"`
+	Failures       int64   `json:"failures"`
+	Active         int64   `json:"active"`            // Readers connected to it at the time
+	Retired        bool    `json:"retired,omitempty"` // Taken out of the list by a target swap
+	AvgConnectTime float64 `json:"avg_connect_ms"` // milliseconds
+	P95ConnectTime float64 `json:"p95_connect_ms"` // milliseconds
+	RTPPackets     uint64  `json:"packets"`
+	RTPLoss        uint64  `json:"loss"`
+	RTPBytes       uint64  `json:"bytes"`
+	BitrateMbps    float64 `json:"bitrate_mbps"`
+}
+
+// newTargetSet builds the target list from Config.URLs (falling back to
+// Config.URL) and Config.TargetWeights
+func newTargetSet(config Config, agg *rtp.Aggregator) *targetSet {
+	urls := config.URLs
+	if len(urls) == 0 {
+		urls = []string{config.URL}
+	}
+
+	ts := &targetSet{
+		weighted:   config.Distribution == DistributionWeighted || config.Distribution == DistributionZipf,
+		random:     newLockedRand(config.Seed),
+		aggregator: agg,
+	}
+	if config.Distribution == DistributionZipf {
+		ts.zipf = config.ZipfExponent
+		if ts.zipf <= 0 {
+			ts.zipf = DefaultZipfExponent
+		}
+	}
+	ts.swap(urls, config.TargetWeights)
+	return ts
+}
+
+// swap makes urls, with their optional weights, the targets new
+// connections go to. URL templates are expanded, each URL getting the
+// template's weight. Targets already known are reused, so their
+// statistics carry on; the ones left out are retired and returned.
+func (ts *targetSet) swap(urls []string, weights []int) (retired []*target) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	var expanded []string
+	var expandedWeights []int
+	for i, u := range urls {
+		weight := 1
+		if i < len(weights) && weights[i] > 0 {
+			weight = weights[i]
+		}
+		for _, e := range expandURLTemplate(u) {
+			expanded = append(expanded, e)
+			expandedWeights = append(expandedWeights, weight)
+		}
+	}
+	if ts.zipf > 0 {
+		expandedWeights = zipfWeights(len(expanded), ts.zipf)
+	}
+
+	list := &targetList{}
+	for i, u := range expanded {
+		weight := expandedWeights[i]
+		var t *target
+		for _, known := range ts.targets {
+			if known.url == u {
+				t = known
+				break
+			}
+		}
+		if t == nil {
+			t = &target{
+				url:            u,
+				aggregator:     ts.aggregator.Child(),
+				connectLatency: histogram.New(),
+				reference:      codec.NewReference(),
+			}
+			ts.targets = append(ts.targets, t)
+		}
+		t.retired.Store(false)
+		list.targets = append(list.targets, t)
+		list.weights = append(list.weights, weight)
+		list.totalWeight += weight
+	}
+
+	for _, t := range ts.targets {
+		if !slices.Contains(list.targets, t) && !t.retired.Swap(true) {
+			retired = append(retired, t)
+		}
+	}
+	ts.list.Store(list)
+	return retired
+}
+
+// all returns every target of the run, retired ones included
+func (ts *targetSet) all() []*target {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return slices.Clone(ts.targets)
+}
+
+// urls returns the URLs new connections currently go to
+func (ts *targetSet) urls() []string {
+	var urls []string
+	for _, t := range ts.list.Load().targets {
+		urls = append(urls, t.url)
+	}
+	return urls
+}
+
+// verificationRole assigns a new reader of t as the frame verification
+// reference, as one of up to limit verifiers, or neither
+func (t *target) verificationRole(limit int) (reference, verify bool) {
+	if limit <= 0 {
+		return false, false
+	}
+	if t.hasRef.CompareAndSwap(false, true) {
+		return true, false
+	}
+	if t.verifiers.Add(1) <= int64(limit) {
+		return false, true
+	}
+	return false, false
+}
+
+// pick selects the target for the next connection
+func (ts *targetSet) pick() *target {
+	list := ts.list.Load()
+	if len(list.targets) == 1 {
+		return list.targets[0]
+	}
+
+	if ts.weighted {
+		n := ts.random.Intn(list.totalWeight)
+		for i, t := range list.targets {
+			if n < list.weights[i] {
+				return t
+			}
+			n -= list.weights[i]
+		}
+	}
+
+	idx := ts.next.Add(1) - 1
+	return list.targets[idx%uint64(len(list.targets))]
+}
+
+// stats returns per-URL statistics, or nil for a single-target run
+func (ts *targetSet) stats(elapsed time.Duration) map[string]TargetStats {
+	targets := ts.all()
+	if len(targets) < 2 {
+		return nil
+	}
+
+	result := make(map[string]TargetStats, len(targets))
+	for _, t := range targets {
+		snapshot := t.aggregator.Snapshot()
+		connect := t.connectLatency.Summary()
+		result[t.url] = TargetStats{
+			Connects:       t.connects.Load(),
+			Failures:       t.failures.Load(),
+			Active:         t.active.Load(),
+			Retired:        t.retired.Load(),
+			AvgConnectTime: connect.Mean,
+			P95ConnectTime: connect.P95,
+			RTPPackets:     snapshot.Packets,
+			RTPLoss:        snapshot.Lost,
+			RTPBytes:       snapshot.Bytes,
+			BitrateMbps:    snapshot.Bitrate(elapsed.Seconds()),
+		}
+	}
+	return result
+}
+
+// LoadURLFile reads benchmark URLs from a file, one per line with an
+// optional integer weight ("rtsp://host/path 3"). Blank lines and lines
+// starting with # are ignored.
+func LoadURLFile(path string) ([]string, []int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var urls []string
+	var weights []int
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		weight := 1
+		if len(fields) > 1 {
+			weight, err = strconv.Atoi(fields[1])
+			if err != nil || weight <= 0 {
+				return nil, nil, fmt.Errorf("%s:%d: invalid weight %q", path, lineNo, fields[1])
+			}
+		}
+		urls = append(urls, fields[0])
+		weights = append(weights, weight)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(urls) == 0 {
+		return nil, nil, fmt.Errorf("%s: no URLs found", path)
+	}
+	return urls, weights, nil
+}
@@ -0,0 +1,60 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// teardownStats tracks TEARDOWN outcomes across sessions
+type teardownStats struct {
+	latency  *histogram.Histogram // Successful TEARDOWNs only
+	sent     atomic.Int64
+	failures atomic.Int64 // Timeouts, I/O errors and error statuses
+	codes    sync.Map     // Status code -> *atomic.Int64
+}
+
+// newTeardownStats creates empty TEARDOWN statistics
+func newTeardownStats() *teardownStats {
+	return &teardownStats{latency: histogram.New()}
+}
+
+// record adds a session's TEARDOWN outcome
+func (s *teardownStats) record(result rtsp.TeardownResult) {
+	if !result.Sent {
+		return
+	}
+	s.sent.Add(1)
+
+	if result.Status > 0 {
+		v, _ := s.codes.LoadOrStore(result.Status, new(atomic.Int64))
+		v.(*atomic.Int64).Add(1)
+	}
+	if result.Err != nil {
+		s.failures.Add(1)
+		return
+	}
+	s.latency.Record(result.Latency)
+}
+
+// fill copies the TEARDOWN statistics into stats
+func (s *teardownStats) fill(stats *Stats) {
+	summary := s.latency.Summary()
+	stats.Teardowns = s.sent.Load()
+	stats.TeardownFailures = s.failures.Load()
+	stats.TeardownAvg = summary.Mean
+	stats.TeardownP95 = summary.P95
+	stats.TeardownMax = summary.Max
+
+	s.codes.Range(func(key, value interface{}) bool {
+		if stats.TeardownCodes == nil {
+			stats.TeardownCodes = make(map[string]int64)
+		}
+		stats.TeardownCodes[strconv.Itoa(key.(int))] = value.(*atomic.Int64).Load()
+		return true
+	})
+}
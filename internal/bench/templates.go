@@ -0,0 +1,70 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// DefaultZipfExponent is the popularity skew of the zipf distribution
+const DefaultZipfExponent = 1.0
+
+// zipfScale is the weight of the most popular target; integer weights
+// stay precise to a part in a million
+const zipfScale = 1_000_000
+
+// templateRange matches a numeric range of a URL template, e.g. {1..500}
+var templateRange = regexp.MustCompile(`\{(\d+)\.\.(\d+)\}`)
+
+// expandURLTemplate returns the URLs of a template, in order: every
+// numeric range, e.g. rtsp://host/cam-{1..500}, is replaced by each of its
+// numbers. A leading zero pads them to the width of the bounds
+// ({001..500}), a descending range counts down and several ranges
+// multiply. A URL without ranges is returned as it is.
+func expandURLTemplate(u string) []string {
+	loc := templateRange.FindStringSubmatchIndex(u)
+	if loc == nil {
+		return []string{u}
+	}
+	lo, hi := u[loc[2]:loc[3]], u[loc[4]:loc[5]]
+	from, err1 := strconv.Atoi(lo)
+	to, err2 := strconv.Atoi(hi)
+	if err1 != nil || err2 != nil {
+		return []string{u} // Out of range: leave it literal
+	}
+	width := 0
+	if (len(lo) > 1 && lo[0] == '0') || (len(hi) > 1 && hi[0] == '0') {
+		width = max(len(lo), len(hi))
+	}
+	step := 1
+	if to < from {
+		step = -1
+	}
+
+	prefix, rests := u[:loc[0]], expandURLTemplate(u[loc[1]:])
+	var urls []string
+	for n := from; ; n += step {
+		num := strconv.Itoa(n)
+		for len(num) < width {
+			num = "0" + num
+		}
+		for _, rest := range rests {
+			urls = append(urls, prefix+num+rest)
+		}
+		if n == to {
+			break
+		}
+	}
+	return urls
+}
+
+// zipfWeights returns the weights of n targets ranked by popularity:
+// rank k gets 1/k^s of the first's, at least 1
+func zipfWeights(n int, s float64) []int {
+	weights := make([]int, n)
+	for k := range weights {
+		weights[k] = max(1, int(math.Round(zipfScale/math.Pow(float64(k+1), s))))
+	}
+	return weights
+}
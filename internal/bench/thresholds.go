@@ -0,0 +1,231 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrThresholdsFailed is returned by Run when any assertion fails, so the
+// caller can exit nonzero
+var ErrThresholdsFailed = errors.New("thresholds failed")
+
+// Assertion is a single threshold on a final Stats metric, e.g.
+// p95_connect_ms<500
+type Assertion struct {
+	Metric string
+	Op     string
+	Value  float64
+}
+
+// assertionOps in match order (two-character operators first)
+var assertionOps = []string{"<=", ">=", "==", "!=", "<", ">", "="}
+
+// ParseAssertions parses a comma-separated list of assertions such as
+// "p95_connect_ms<500,loss_pct<0.1,failures=0". Metric names are the Stats
+// json names plus loss_pct and failure_pct.
+func ParseAssertions(s string) ([]Assertion, error) {
+	known := statsMetrics(Stats{})
+
+	var assertions []Assertion
+	for _, expr := range strings.Split(s, ",") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+
+		var a Assertion
+		for _, op := range assertionOps {
+			if i := strings.Index(expr, op); i > 0 {
+				a.Metric = strings.TrimSpace(expr[:i])
+				a.Op = op
+				value, err := strconv.ParseFloat(strings.TrimSpace(expr[i+len(op):]), 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid assertion %q: bad value", expr)
+				}
+				a.Value = value
+				break
+			}
+		}
+		if a.Op == "" {
+			return nil, fmt.Errorf("invalid assertion %q: missing operator", expr)
+		}
+		if _, ok := known[a.Metric]; !ok {
+			return nil, fmt.Errorf("invalid assertion %q: unknown metric %s", expr, a.Metric)
+		}
+		assertions = append(assertions, a)
+	}
+	return assertions, nil
+}
+
+// String formats the assertion as it was written
+func (a Assertion) String() string {
+	return a.Metric + a.Op + strconv.FormatFloat(a.Value, 'f', -1, 64)
+}
+
+// check reports whether actual satisfies the assertion
+func (a Assertion) check(actual float64) bool {
+	switch a.Op {
+	case "<":
+		return actual < a.Value
+	case "<=":
+		return actual <= a.Value
+	case ">":
+		return actual > a.Value
+	case ">=":
+		return actual >= a.Value
+	case "!=":
+		return actual != a.Value
+	default: // "=", "=="
+		return actual == a.Value
+	}
+}
+
+// AssertionResult is the outcome of one assertion
+type AssertionResult struct {
+	Assertion string  `json:"assertion"`
+	Actual    float64 `json:"actual"`
+	Pass      bool    `json:"pass"`
+}
+
+// Verdict is the machine-readable outcome of all assertions and the
+// baseline comparison
+type Verdict struct {
+	Pass       bool               `json:"pass"`
+	Time       string             `json:"time"`
+	Results    []AssertionResult  `json:"results"`
+	Baseline   string             `json:"baseline,omitempty"`   // Baseline file compared against
+	Comparison []ComparisonResult `json:"comparison,omitempty"` // Metrics against the baseline
+}
+
+// Evaluate checks the final statistics against the assertions
+func Evaluate(stats Stats, assertions []Assertion) Verdict {
+	metrics := statsMetrics(stats)
+	verdict := Verdict{
+		Pass: true,
+		Time: time.Now().Format(time.RFC3339),
+	}
+	for _, a := range assertions {
+		actual := metrics[a.Metric]
+		pass := a.check(actual)
+		verdict.Results = append(verdict.Results, AssertionResult{
+			Assertion: a.String(),
+			Actual:    actual,
+			Pass:      pass,
+		})
+		if !pass {
+			verdict.Pass = false
+		}
+	}
+	return verdict
+}
+
+// statsMetrics returns every numeric Stats field by json name, plus the
+// derived loss_pct and failure_pct
+func statsMetrics(stats Stats) map[string]float64 {
+	metrics := make(map[string]float64)
+
+	v := reflect.ValueOf(stats)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		switch f := v.Field(i); f.Kind() {
+		case reflect.Int, reflect.Int64:
+			metrics[name] = float64(f.Int())
+		case reflect.Uint64:
+			metrics[name] = float64(f.Uint())
+		case reflect.Float64:
+			metrics[name] = f.Float()
+		}
+	}
+
+	metrics["loss_pct"] = lossPercent(stats)
+	metrics["failure_pct"] = 0
+	if attempts := stats.TotalConnects + stats.TotalFailures; attempts > 0 {
+		metrics["failure_pct"] = float64(stats.TotalFailures) * 100 / float64(attempts)
+	}
+	return metrics
+}
+
+// checkThresholds evaluates the assertions and compares against the
+// baseline, logs each result and writes the verdict to Config.VerdictFile
+// (or stdout). Returns ErrThresholdsFailed and ErrRegression on failure.
+func checkThresholds(config Config, log *slog.Logger, assertions []Assertion, cmp *comparison, stats Stats) error {
+	if len(assertions) == 0 && cmp == nil {
+		return nil
+	}
+
+	verdict := Evaluate(stats, assertions)
+	assertionsPass := verdict.Pass
+	for _, r := range verdict.Results {
+		if r.Pass {
+			log.Info("assertion passed", "assertion", r.Assertion, "actual", r.Actual)
+		} else {
+			log.Error("assertion failed", "assertion", r.Assertion, "actual", r.Actual)
+		}
+	}
+	regressed := false
+	if cmp != nil {
+		verdict.Baseline = cmp.path
+		verdict.Comparison = Compare(cmp.baseline, stats, cmp.tolerances)
+		for _, r := range verdict.Comparison {
+			args := []any{"metric", r.Metric, "baseline", r.Baseline, "actual", r.Actual,
+				"delta_pct", math.Round(r.DeltaPct*10) / 10, "tolerance", r.Tolerance}
+			if r.Pass {
+				log.Info("within baseline", args...)
+			} else {
+				log.Error("regression against baseline", args...)
+				regressed = true
+			}
+		}
+		verdict.Pass = verdict.Pass && !regressed
+	}
+
+	// Keep "<" and ">" readable in the assertion strings
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(verdict); err != nil {
+		return err
+	}
+	if config.VerdictFile != "" {
+		if err := os.WriteFile(config.VerdictFile, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write verdict: %w", err)
+		}
+	} else {
+		fmt.Print(buf.String())
+	}
+
+	var errs []error
+	if !assertionsPass {
+		errs = append(errs, ErrThresholdsFailed)
+	}
+	if regressed {
+		errs = append(errs, ErrRegression)
+	}
+	return errors.Join(errs...)
+}
+
+// MetricNames lists the metrics usable in assertions
+func MetricNames() []string {
+	metrics := statsMetrics(Stats{})
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
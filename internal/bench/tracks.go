@@ -0,0 +1,50 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// trackPicker chooses the tracks each reader sets up, by Config.Tracks or
+// Config.TrackWeights; nil sets up all of them
+type trackPicker struct {
+	*weighted
+}
+
+// newTrackPicker builds the picker from the config, or returns nil if
+// neither option is set
+func newTrackPicker(config Config) (*trackPicker, error) {
+	weights := config.TrackWeights
+	if config.Tracks != "" {
+		weights = map[string]float64{config.Tracks: 1}
+	}
+	if len(weights) == 0 {
+		return nil, nil
+	}
+	w, err := newWeighted(weights, "track selection", func(name string) error {
+		_, err := rtsp.ParseTrackSelection(name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &trackPicker{weighted: w}, nil
+}
+
+// pick draws a track selection from rng and counts it. A nil picker
+// returns rtsp.TracksAll without drawing.
+func (p *trackPicker) pick(rng *lockedRand) string {
+	if p == nil {
+		return rtsp.TracksAll
+	}
+	return p.names[p.weighted.pick(rng)]
+}
+
+// fill copies the readers per track selection into stats. A nil picker
+// leaves them empty.
+func (p *trackPicker) fill(stats *Stats) {
+	if p == nil {
+		return
+	}
+	stats.TrackSelections = p.drawn()
+}
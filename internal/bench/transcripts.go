@@ -0,0 +1,96 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtsp"
+)
+
+// Transcript defaults
+const (
+	DefaultTranscriptDir  = "transcripts"
+	DefaultTranscriptSize = 50
+)
+
+// transcriptDumper saves the control messages of the first failures of each
+// category, to attach real wire exchanges to bug reports
+type transcriptDumper struct {
+	dir   string
+	limit int64 // Transcripts per category (0 = disabled)
+	size  int   // Messages kept per connection
+	taken [len(rtsp.FailureCategories)]atomic.Int64
+}
+
+// newTranscriptDumper creates the dumper of Config.Transcripts and its
+// directory
+func newTranscriptDumper(config Config) (*transcriptDumper, error) {
+	if config.Transcripts < 0 || config.TranscriptSize < 0 {
+		return nil, fmt.Errorf("transcript count and size must not be negative")
+	}
+	d := &transcriptDumper{
+		dir:   config.TranscriptDir,
+		limit: int64(config.Transcripts),
+		size:  config.TranscriptSize,
+	}
+	if d.limit == 0 {
+		return d, nil
+	}
+	if d.dir == "" {
+		d.dir = DefaultTranscriptDir
+	}
+	if d.size == 0 {
+		d.size = DefaultTranscriptSize
+	}
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create transcript directory: %w", err)
+	}
+	return d, nil
+}
+
+// apply makes a reader client keep its transcript
+func (d *transcriptDumper) apply(client *rtsp.Client) {
+	if d.limit > 0 {
+		client.SetTranscript(d.size)
+	}
+}
+
+// dump writes the client's transcript if fewer than the limit of the
+// failure's category were written so far
+func (d *transcriptDumper) dump(client *rtsp.Client, category string, err error, target string, log *slog.Logger) {
+	if d.limit == 0 {
+		return
+	}
+	n := int64(0)
+	for i, c := range rtsp.FailureCategories {
+		if c == category {
+			n = d.taken[i].Add(1)
+		}
+	}
+	if n == 0 || n > d.limit {
+		return
+	}
+
+	if u, err := url.Parse(target); err == nil {
+		target = u.Redacted() // Credentials stay out of bug reports
+	}
+	entries, dropped := client.Transcript()
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Target:   %s\nCategory: %s\nError:    %v\nTime:     %s\n\n",
+		target, category, err, time.Now().Format(time.RFC3339Nano))
+	rtsp.WriteTranscript(&b, entries, dropped)
+
+	path := filepath.Join(d.dir, fmt.Sprintf("%s-%d.txt", category, n))
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		log.Warn("failed to save transcript", "error", err)
+		return
+	}
+	log.Info("transcript saved", "path", path, "category", category)
+}
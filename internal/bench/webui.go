@@ -0,0 +1,194 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	_ "embed"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/websocket"
+)
+
+// Web UI paths on the control listener
+const (
+	ControlPathUI   = "/"
+	ControlPathLive = "/live"
+)
+
+// Live feed sampling
+const (
+	LiveInterval = time.Second
+	liveHistory  = 900 // Samples kept for browsers that connect late (15 minutes)
+)
+
+//go:embed webui.html
+var webUIPage []byte
+
+// liveSample is one point of the web UI's charts
+type liveSample struct {
+	Elapsed    float64 `json:"t"` // Seconds since the control API started
+	Active     int64   `json:"active"`
+	Target     int64   `json:"target"`
+	Connects   int64   `json:"connects"`
+	Failures   int64   `json:"failures"`  // In the interval since the previous sample
+	LossPct    float64 `json:"loss_pct"`  // Over the interval since the previous sample
+	Mbps       float64 `json:"mbps"`      // Over the interval since the previous sample
+	ConnectP50 float64 `json:"connect_p50_ms"`
+	ConnectP95 float64 `json:"connect_p95_ms"`
+	TTFPP95    float64 `json:"ttfp_p95_ms"`
+	JitterAvg  float64 `json:"jitter_avg_ms"`
+	Stalls     uint64  `json:"stalls"`
+}
+
+// liveMessage is what the web UI receives: the samples so far on
+// connecting, then each new one
+type liveMessage struct {
+	Status  ControlStatus `json:"status"`
+	Samples []liveSample  `json:"samples"`
+}
+
+// liveFeed samples the run once per LiveInterval and pushes the samples
+// to the browsers watching the web UI
+type liveFeed struct {
+	mu          sync.Mutex
+	history     []liveSample
+	subscribers map[chan liveSample]struct{}
+}
+
+func newLiveFeed() *liveFeed {
+	return &liveFeed{subscribers: make(map[chan liveSample]struct{})}
+}
+
+// run samples getStats until stop is closed, then disconnects every
+// subscriber
+func (f *liveFeed) run(getStats func() Stats, stop <-chan struct{}) {
+	start := time.Now()
+	ticker := time.NewTicker(LiveInterval)
+	defer ticker.Stop()
+	var last Stats
+	lastAt := start
+	for {
+		select {
+		case <-stop:
+			f.mu.Lock()
+			for ch := range f.subscribers {
+				close(ch)
+				delete(f.subscribers, ch)
+			}
+			f.mu.Unlock()
+			return
+		case now := <-ticker.C:
+			stats := getStats()
+			f.publish(newLiveSample(now.Sub(start), now.Sub(lastAt), stats, last))
+			last, lastAt = stats, now
+		}
+	}
+}
+
+// newLiveSample builds a sample from stats, with rates over interval since
+// the previous stats
+func newLiveSample(elapsed, interval time.Duration, stats, last Stats) liveSample {
+	s := liveSample{
+		Elapsed:    math.Round(elapsed.Seconds()*10) / 10,
+		Active:     stats.ActiveConnects,
+		Target:     stats.TargetConnects,
+		Connects:   stats.TotalConnects,
+		Failures:   stats.TotalFailures - last.TotalFailures,
+		ConnectP50: stats.P50ConnectTime,
+		ConnectP95: stats.P95ConnectTime,
+		TTFPP95:    stats.TTFPP95,
+		JitterAvg:  stats.JitterAvg,
+		Stalls:     stats.Stalls,
+	}
+	packets := float64(stats.RTPPackets) - float64(last.RTPPackets)
+	loss := float64(stats.RTPLoss) - float64(last.RTPLoss)
+	if packets+loss > 0 {
+		s.LossPct = math.Max(loss, 0) * 100 / (packets + loss)
+	}
+	if secs := interval.Seconds(); secs > 0 {
+		s.Mbps = (float64(stats.RTPBytes) - float64(last.RTPBytes)) * 8 / secs / 1e6
+	}
+	return s
+}
+
+// publish keeps a sample and sends it to the subscribers. A browser too
+// slow to take it misses the sample.
+func (f *liveFeed) publish(s liveSample) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.history) == liveHistory {
+		copy(f.history, f.history[1:])
+		f.history = f.history[:liveHistory-1]
+	}
+	f.history = append(f.history, s)
+	for ch := range f.subscribers {
+		select {
+		case ch <- s:
+		default:
+		}
+	}
+}
+
+// subscribe returns the samples so far and a channel for the next ones,
+// closed when the feed stops
+func (f *liveFeed) subscribe() ([]liveSample, chan liveSample) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan liveSample, 8)
+	f.subscribers[ch] = struct{}{}
+	return append([]liveSample(nil), f.history...), ch
+}
+
+// unsubscribe stops sending to ch
+func (f *liveFeed) unsubscribe(ch chan liveSample) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.subscribers, ch)
+}
+
+// handleUI serves the web UI page
+func (r *Runner) handleUI(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != ControlPathUI {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(webUIPage)
+}
+
+// handleLive streams the live feed to a web UI over a websocket
+func (r *Runner) handleLive(w http.ResponseWriter, req *http.Request) {
+	conn, err := websocket.Upgrade(w, req)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	history, samples := r.live.subscribe()
+	defer r.live.unsubscribe(samples)
+	if r.sendLive(conn, history) != nil {
+		return
+	}
+	for {
+		select {
+		case <-conn.Done():
+			return
+		case s, ok := <-samples:
+			if !ok || r.sendLive(conn, []liveSample{s}) != nil {
+				return
+			}
+		}
+	}
+}
+
+// sendLive writes samples to a web UI with the current control status
+func (r *Runner) sendLive(conn *websocket.Conn, samples []liveSample) error {
+	data, err := json.Marshal(liveMessage{Status: r.controlStatus(), Samples: samples})
+	if err != nil {
+		return err
+	}
+	return conn.WriteText(data)
+}
@@ -0,0 +1,79 @@
+// Created by WINK Streaming (https://www.wink.co)
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// weighted draws names by relative weight and counts the draws
+type weighted struct {
+	names      []string
+	cumulative []float64
+	counts     []atomic.Int64
+}
+
+// newWeighted builds a draw over weights, whose names valid checks; what
+// names the choice in errors. Names are lowercased and zero weights left
+// out.
+func newWeighted(weights map[string]float64, what string, valid func(name string) error) (*weighted, error) {
+	// Sorted, so the same random sequence picks the same names
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := &weighted{}
+	total := 0.0
+	for _, name := range names {
+		weight := weights[name]
+		if err := valid(name); err != nil {
+			return nil, err
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("negative weight for %s %s", what, name)
+		}
+		if weight == 0 {
+			continue
+		}
+		total += weight
+		w.names = append(w.names, strings.ToLower(name))
+		w.cumulative = append(w.cumulative, total)
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("all %s weights are zero", what)
+	}
+	w.counts = make([]atomic.Int64, len(w.names))
+	return w, nil
+}
+
+// pick draws the index of a name from rng and counts it. A single name is
+// picked without drawing.
+func (w *weighted) pick(rng *lockedRand) int {
+	i := len(w.cumulative) - 1
+	if i > 0 {
+		n := rng.Float64() * w.cumulative[i]
+		for j, c := range w.cumulative {
+			if n < c {
+				i = j
+				break
+			}
+		}
+	}
+	w.counts[i].Add(1)
+	return i
+}
+
+// drawn returns the count of every name drawn at least once
+func (w *weighted) drawn() map[string]int64 {
+	counts := make(map[string]int64, len(w.names))
+	for i, name := range w.names {
+		if n := w.counts[i].Load(); n > 0 {
+			counts[name] = n
+		}
+	}
+	return counts
+}
@@ -0,0 +1,352 @@
+// Created by WINK Streaming (https://www.wink.co)
+package codec
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// AudioFormat identifies a validated audio payload format
+type AudioFormat int
+
+const (
+	AACGeneric AudioFormat = iota // RFC 3640 mpeg4-generic
+	AACLATM                       // RFC 6416 MP4A-LATM, out-of-band config
+	Opus                          // RFC 7587
+)
+
+// Opus packet limits (RFC 6716 section 3)
+const (
+	opusMaxFrame    = 1275
+	opusMaxDuration = 1200 // Tenths of a millisecond per packet (120 ms)
+)
+
+// AudioStats holds per-stream audio payload statistics
+type AudioStats struct {
+	Frames    uint64 // AAC access units or Opus frames in well-formed payloads
+	Malformed uint64 // Payloads that failed validation
+}
+
+// AudioValidator checks that AAC and Opus RTP payloads are well formed:
+// AU headers that add up to the payload, LATM lengths that fit, Opus TOC
+// and frame lengths as RFC 6716 allows. It does not decode audio.
+type AudioValidator struct {
+	mu     sync.Mutex
+	format AudioFormat
+	stats  AudioStats
+
+	// RFC 3640 AU header layout from the fmtp, in bits
+	sizeLength       int
+	indexLength      int
+	indexDeltaLength int
+
+	// Fragmented AU (RFC 3640 section 3.2.3): size announced and bytes so far
+	inFragment   bool
+	fragmentSize int
+	fragmentGot  int
+
+	haveSeq bool
+	lastSeq uint16
+}
+
+// NewAudioValidator creates a validator for the SDP encoding name and
+// fmtp, or returns nil if the payload format is not supported. mpeg4-generic
+// needs sizelength, and MP4A-LATM cpresent=0.
+func NewAudioValidator(encoding, fmtp string) *AudioValidator {
+	params := fmtpParams(fmtp)
+	switch strings.ToUpper(encoding) {
+	case "MPEG4-GENERIC":
+		v := &AudioValidator{format: AACGeneric}
+		v.sizeLength, _ = strconv.Atoi(params["sizelength"])
+		v.indexLength, _ = strconv.Atoi(params["indexlength"])
+		v.indexDeltaLength, _ = strconv.Atoi(params["indexdeltalength"])
+		if v.sizeLength <= 0 || v.sizeLength > 16 {
+			return nil
+		}
+		return v
+	case "MP4A-LATM":
+		if params["cpresent"] != "0" {
+			return nil
+		}
+		return &AudioValidator{format: AACLATM}
+	case "OPUS":
+		return &AudioValidator{format: Opus}
+	}
+	return nil
+}
+
+// fmtpParams parses "key=value; key=value" with lowercased keys
+func fmtpParams(fmtp string) map[string]string {
+	params := make(map[string]string)
+	for _, p := range strings.Split(fmtp, ";") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		params[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+	}
+	return params
+}
+
+// Push validates one RTP payload (without the RTP header)
+func (v *AudioValidator) Push(payload []byte, seq uint16, marker bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// A lost packet may have carried part of a fragmented AU
+	if v.haveSeq && seq != v.lastSeq+1 {
+		v.inFragment = false
+	}
+	v.haveSeq = true
+	v.lastSeq = seq
+
+	var frames int
+	var ok bool
+	switch v.format {
+	case AACGeneric:
+		frames, ok = v.pushGeneric(payload, marker)
+	case AACLATM:
+		frames, ok = latmFrames(payload)
+	case Opus:
+		frames, ok = opusFrames(payload)
+	}
+	if !ok {
+		v.stats.Malformed++
+		return
+	}
+	v.stats.Frames += uint64(frames)
+}
+
+// pushGeneric checks an RFC 3640 payload: the AU-headers-length, then one
+// AU-size per header, which must add up to the data that follows unless a
+// single AU is fragmented across packets
+func (v *AudioValidator) pushGeneric(payload []byte, marker bool) (int, bool) {
+	if len(payload) < 2 {
+		return 0, false
+	}
+	headerBits := int(payload[0])<<8 | int(payload[1])
+	headerBytes := (headerBits + 7) / 8
+	if headerBits == 0 || 2+headerBytes > len(payload) {
+		return 0, false
+	}
+	r := bitReader{data: payload[2 : 2+headerBytes]}
+	data := len(payload) - 2 - headerBytes
+
+	var sizes []int
+	for bits := 0; bits < headerBits; {
+		indexBits := v.indexDeltaLength
+		if len(sizes) == 0 {
+			indexBits = v.indexLength
+		}
+		if bits+v.sizeLength+indexBits > headerBits {
+			return 0, false
+		}
+		sizes = append(sizes, r.read(v.sizeLength))
+		r.read(indexBits)
+		bits += v.sizeLength + indexBits
+	}
+
+	// Continuation or last piece of a fragmented AU
+	if len(sizes) == 1 && (v.inFragment || sizes[0] > data) {
+		if v.inFragment && sizes[0] != v.fragmentSize {
+			v.inFragment = false
+			return 0, false
+		}
+		if !v.inFragment {
+			v.inFragment, v.fragmentSize, v.fragmentGot = true, sizes[0], 0
+		}
+		v.fragmentGot += data
+		if v.fragmentGot > v.fragmentSize || (marker && v.fragmentGot != v.fragmentSize) {
+			v.inFragment = false
+			return 0, false
+		}
+		if marker {
+			v.inFragment = false
+			return 1, true
+		}
+		return 0, true
+	}
+	v.inFragment = false
+
+	total := 0
+	offset := 2 + headerBytes
+	for _, size := range sizes {
+		total += size
+		if size == 0 || total > data || !adtsConsistent(payload[offset:offset+size], size) {
+			return 0, false
+		}
+		offset += size
+	}
+	if total != data {
+		return 0, false
+	}
+	return len(sizes), true
+}
+
+// adtsConsistent reports whether an AU that starts with an ADTS header, as
+// some packetizers wrongly leave in, announces its own size. Raw AUs pass.
+func adtsConsistent(au []byte, size int) bool {
+	if len(au) < 7 || au[0] != 0xFF || au[1]&0xF6 != 0xF0 {
+		return true
+	}
+	frameLength := int(au[3]&0x03)<<11 | int(au[4])<<3 | int(au[5])>>5
+	return frameLength == size
+}
+
+// latmFrames checks an RFC 6416 payload without in-band config: one or
+// more PayloadLengthInfo (0xFF runs) and PayloadMux pairs that exactly
+// fill the payload
+func latmFrames(payload []byte) (int, bool) {
+	frames := 0
+	for len(payload) > 0 {
+		length := 0
+		for {
+			if len(payload) == 0 {
+				return 0, false
+			}
+			b := payload[0]
+			payload = payload[1:]
+			length += int(b)
+			if b != 0xFF {
+				break
+			}
+		}
+		if length == 0 || length > len(payload) {
+			return 0, false
+		}
+		payload = payload[length:]
+		frames++
+	}
+	return frames, frames > 0
+}
+
+// opusFrames checks an Opus packet against RFC 6716 section 3 (R1-R7) and
+// returns its frame count
+func opusFrames(p []byte) (int, bool) {
+	if len(p) < 1 {
+		return 0, false
+	}
+	toc := p[0]
+	duration := opusFrameDuration(toc >> 3)
+	p = p[1:]
+
+	switch toc & 0x03 {
+	case 0:
+		return 1, len(p) <= opusMaxFrame
+	case 1:
+		return 2, len(p)%2 == 0 && len(p)/2 <= opusMaxFrame
+	case 2:
+		first, n, ok := opusLength(p)
+		if !ok || first > opusMaxFrame || first > len(p)-n {
+			return 0, false
+		}
+		return 2, len(p)-n-first <= opusMaxFrame
+	}
+
+	// Code 3: frame count byte, optional padding, CBR or VBR frames
+	if len(p) < 1 {
+		return 0, false
+	}
+	vbr, padded, count := p[0]&0x80 != 0, p[0]&0x40 != 0, int(p[0]&0x3F)
+	p = p[1:]
+	if count == 0 || count*duration > opusMaxDuration {
+		return 0, false
+	}
+	if padded {
+		padding := 0
+		for {
+			if len(p) == 0 {
+				return 0, false
+			}
+			b := p[0]
+			p = p[1:]
+			if b == 255 {
+				padding += 254
+				continue
+			}
+			padding += int(b)
+			break
+		}
+		if padding > len(p) {
+			return 0, false
+		}
+		p = p[:len(p)-padding]
+	}
+	if !vbr {
+		return count, len(p)%count == 0 && len(p)/count <= opusMaxFrame
+	}
+	// VBR: count-1 lengths, then the frames; the last one takes the rest
+	sum := 0
+	for i := 0; i < count-1; i++ {
+		size, n, ok := opusLength(p)
+		if !ok || size > opusMaxFrame {
+			return 0, false
+		}
+		p = p[n:]
+		sum += size
+	}
+	if sum > len(p) {
+		return 0, false
+	}
+	return count, len(p)-sum <= opusMaxFrame
+}
+
+// opusLength decodes a 1 or 2 byte frame length, returning it and the
+// bytes it took
+func opusLength(p []byte) (int, int, bool) {
+	if len(p) < 1 {
+		return 0, 0, false
+	}
+	if p[0] < 252 {
+		return int(p[0]), 1, true
+	}
+	if len(p) < 2 {
+		return 0, 0, false
+	}
+	return int(p[1])*4 + int(p[0]), 2, true
+}
+
+// opusFrameDuration returns the frame duration of a TOC config, in tenths
+// of a millisecond
+func opusFrameDuration(config byte) int {
+	switch {
+	case config < 12: // SILK
+		return [4]int{100, 200, 400, 600}[config%4]
+	case config < 16: // Hybrid
+		return [2]int{100, 200}[config%2]
+	default: // CELT
+		return [4]int{25, 50, 100, 200}[config%4]
+	}
+}
+
+// Restart forgets a fragmented AU in progress, after a seek
+func (v *AudioValidator) Restart() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.inFragment = false
+	v.haveSeq = false
+}
+
+// Stats returns a snapshot of the statistics
+func (v *AudioValidator) Stats() AudioStats {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.stats
+}
+
+// bitReader reads big-endian bit fields
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+// read returns the next n bits (n <= 32); bits past the end read as zero
+func (r *bitReader) read(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		v <<= 1
+		if byteIdx := r.pos / 8; byteIdx < len(r.data) {
+			v |= int(r.data[byteIdx]>>(7-r.pos%8)) & 1
+		}
+		r.pos++
+	}
+	return v
+}
@@ -0,0 +1,149 @@
+// Created by WINK Streaming (https://www.wink.co)
+package codec
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MPEG-TS (ISO/IEC 13818-1) packet layout
+const (
+	tsPacketSize = 188
+	tsSyncByte   = 0x47
+	tsNullPID    = 0x1FFF
+	pcrClock     = 27000000 // PCR ticks per second
+	pcrWrap      = (1 << 33) * 300
+	pcrMaxGap    = time.Second // Larger PCR or arrival steps are discontinuities, not jitter
+)
+
+// TSStats holds per-stream transport stream statistics
+type TSStats struct {
+	Packets          uint64  // TS packets parsed
+	SyncErrors       uint64  // 188-byte units without the sync byte
+	ContinuityErrors uint64  // continuity_counter gaps, per PID
+	PCRs             uint64  // PCR samples
+	PCRJitterMs      float64 // Smoothed jitter of PCR against arrival time (RFC 3550 style)
+	PCRJitterMaxMs   float64 // Largest single PCR/arrival deviation
+}
+
+// IsMPEGTS reports whether an RTP encoding name (or static payload type 33)
+// carries an MPEG transport stream (RFC 2250)
+func IsMPEGTS(encoding string) bool {
+	return strings.EqualFold(encoding, "MP2T")
+}
+
+// TSAnalyzer parses the MPEG-TS packets of RTP payloads to count
+// continuity-counter errors and measure PCR jitter. It reads only packet
+// headers and adaptation fields, not PES or PSI.
+type TSAnalyzer struct {
+	mu    sync.Mutex
+	stats TSStats
+
+	cc map[uint16]uint8 // Last continuity_counter per PID
+
+	// PCR tracking, on the first PID seen carrying one
+	pcrPID     uint16
+	havePCR    bool
+	lastPCR    uint64
+	lastPCRAt  time.Time
+	jitterSecs float64
+}
+
+// NewTSAnalyzer returns an analyzer for an MP2T track, or nil for other
+// encodings
+func NewTSAnalyzer(encoding string) *TSAnalyzer {
+	if !IsMPEGTS(encoding) {
+		return nil
+	}
+	return &TSAnalyzer{cc: make(map[uint16]uint8)}
+}
+
+// Push parses one RTP payload received at arrival
+func (a *TSAnalyzer) Push(payload []byte, arrival time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for ; len(payload) >= tsPacketSize; payload = payload[tsPacketSize:] {
+		if payload[0] != tsSyncByte {
+			a.stats.SyncErrors++
+			continue
+		}
+		a.stats.Packets++
+		a.packet(payload[:tsPacketSize], arrival)
+	}
+}
+
+// packet checks the continuity counter and reads the PCR of one TS packet
+func (a *TSAnalyzer) packet(p []byte, arrival time.Time) {
+	pid := uint16(p[1]&0x1F)<<8 | uint16(p[2])
+	if pid == tsNullPID {
+		return
+	}
+	control := p[3] >> 4 & 0x3
+	cc := p[3] & 0x0F
+
+	// Adaptation field: discontinuity indicator and PCR
+	discontinuity := false
+	if control&0x2 != 0 && p[4] > 0 {
+		flags := p[5]
+		discontinuity = flags&0x80 != 0
+		if flags&0x10 != 0 && p[4] >= 7 {
+			a.pcr(pid, p[6:12], discontinuity, arrival)
+		}
+	}
+
+	// The counter only advances on packets with payload, and a repeated
+	// counter is a permitted duplicate
+	if control&0x1 == 0 {
+		return
+	}
+	if last, seen := a.cc[pid]; seen && !discontinuity && cc != (last+1)&0x0F && cc != last {
+		a.stats.ContinuityErrors++
+	}
+	a.cc[pid] = cc
+}
+
+// pcr compares the PCR's progress with the arrival time's
+func (a *TSAnalyzer) pcr(pid uint16, field []byte, discontinuity bool, arrival time.Time) {
+	if a.havePCR && pid != a.pcrPID {
+		return
+	}
+	base := uint64(field[0])<<25 | uint64(field[1])<<17 | uint64(field[2])<<9 | uint64(field[3])<<1 | uint64(field[4]>>7)
+	ext := uint64(field[4]&0x01)<<8 | uint64(field[5])
+	value := base*300 + ext
+	a.stats.PCRs++
+
+	if a.havePCR && !discontinuity {
+		elapsed := arrival.Sub(a.lastPCRAt).Seconds()
+		advanced := float64((value+pcrWrap-a.lastPCR)%pcrWrap) / pcrClock
+		d := math.Abs(elapsed - advanced)
+		if d < pcrMaxGap.Seconds() && advanced < pcrMaxGap.Seconds() {
+			a.jitterSecs += (d - a.jitterSecs) / 16
+			if ms := d * 1000; ms > a.stats.PCRJitterMaxMs {
+				a.stats.PCRJitterMaxMs = ms
+			}
+		}
+	}
+	a.pcrPID, a.havePCR = pid, true
+	a.lastPCR, a.lastPCRAt = value, arrival
+}
+
+// Restart forgets the continuity counters and PCR, so the first packets
+// after a seek are not errors
+func (a *TSAnalyzer) Restart() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cc = make(map[uint16]uint8)
+	a.havePCR = false
+}
+
+// Stats returns a snapshot of the statistics
+func (a *TSAnalyzer) Stats() TSStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := a.stats
+	s.PCRJitterMs = a.jitterSecs * 1000
+	return s
+}
@@ -0,0 +1,358 @@
+// Created by WINK Streaming (https://www.wink.co)
+package codec
+
+import (
+	"hash"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Codec identifies a supported video payload format
+type Codec int
+
+const (
+	H264 Codec = iota // RFC 6184
+	H265              // RFC 7798
+)
+
+// H.264 NAL unit types
+const (
+	h264IDR   = 5
+	h264STAPA = 24
+	h264FUA   = 28
+)
+
+// H.265 NAL unit types
+const (
+	h265IRAPFirst = 16 // BLA_W_LP
+	h265IRAPLast  = 21 // CRA_NUT
+	h265AP        = 48
+	h265FU        = 49
+)
+
+// Stats holds per-stream NAL and keyframe statistics
+type Stats struct {
+	NALUnits       uint64  // Complete NAL units (after reassembly)
+	Frames         uint64  // Access units (distinct RTP timestamps)
+	Keyframes      uint64  // IDR / IRAP access units
+	FragmentErrors uint64  // Broken FU reassemblies (missing start/end, gaps)
+	AvgGOPFrames   float64 // Average frames between keyframes
+	AvgGOPMs       float64 // Average time between keyframes
+	LastGOPMs      float64
+}
+
+// Analyzer depacketizes H.264/H.265 RTP payloads enough to identify NAL
+// types, count keyframes and verify fragmentation unit reassembly
+type Analyzer struct {
+	mu        sync.Mutex
+	codec     Codec
+	clockRate float64
+
+	stats Stats
+
+	// Access unit tracking
+	haveFrame      bool
+	lastTS         uint32
+	frameIsKey     bool
+	framesSinceKey uint64
+
+	// Keyframe interval tracking
+	haveKey   bool
+	lastKeyTS uint32
+	gopCount  uint64
+	gopFrames uint64
+	gopTicks  uint64
+
+	// Fragmentation unit reassembly
+	inFragment bool
+	fragSeq    uint16
+	fragType   int
+
+	// Frame hashing (verify mode). A frame is only hashed if every one of
+	// its packets arrived in sequence.
+	onFrame    func(hash uint64)
+	hasher     hash.Hash64
+	frameClean bool
+	frameBytes int
+	haveSeq    bool
+	lastSeq    uint16
+
+	// Publisher wall-clock SEI messages (end-to-end latency)
+	onTimestamp func(sent time.Time)
+}
+
+// annexBStartCode separates NAL units in the hashed frame data
+var annexBStartCode = []byte{0, 0, 0, 1}
+
+// NewAnalyzer creates an analyzer for the SDP encoding name, or returns nil
+// if the encoding is not H.264/H.265
+func NewAnalyzer(encoding string, clockRate int) *Analyzer {
+	var c Codec
+	switch strings.ToUpper(encoding) {
+	case "H264":
+		c = H264
+	case "H265", "HEVC":
+		c = H265
+	default:
+		return nil
+	}
+	if clockRate <= 0 {
+		clockRate = 90000
+	}
+	return &Analyzer{codec: c, clockRate: float64(clockRate)}
+}
+
+// OnFrame enables frame hashing: fn is called with a hash of each fully
+// received access unit. Must be called before the first Push.
+func (a *Analyzer) OnFrame(fn func(hash uint64)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onFrame = fn
+	a.hasher = fnv.New64a()
+}
+
+// OnTimestamp calls fn with the send time of each publisher timestamp SEI
+// message (see TimestampSEI). Must be called before the first Push.
+func (a *Analyzer) OnTimestamp(fn func(sent time.Time)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onTimestamp = fn
+}
+
+// Push processes one RTP payload (without the RTP header)
+func (a *Analyzer) Push(payload []byte, seq uint16, timestamp uint32) {
+	if len(payload) == 0 {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// A sequence gap may have taken packets from the current frame or the
+	// start of the next one
+	gap := a.haveSeq && seq != a.lastSeq+1
+	a.haveSeq = true
+	a.lastSeq = seq
+
+	// A new timestamp starts a new access unit. The first frame is never
+	// clean since we may have joined it part way through.
+	if !a.haveFrame || timestamp != a.lastTS {
+		if gap {
+			a.frameClean = false
+		}
+		a.finishFrame()
+		a.frameClean = a.haveFrame && !gap
+		a.haveFrame = true
+		a.lastTS = timestamp
+		a.frameIsKey = false
+	} else if gap {
+		a.frameClean = false
+	}
+
+	if a.codec == H264 {
+		a.pushH264(payload, seq)
+	} else {
+		a.pushH265(payload, seq)
+	}
+}
+
+// pushH264 handles single NAL, STAP-A and FU-A packets
+func (a *Analyzer) pushH264(payload []byte, seq uint16) {
+	nalType := int(payload[0] & 0x1F)
+
+	switch nalType {
+	case h264STAPA:
+		// STAP-A: [hdr] { [size16] [nal] }*
+		for b := payload[1:]; len(b) > 2; {
+			size := int(b[0])<<8 | int(b[1])
+			if size == 0 || size > len(b)-2 {
+				a.stats.FragmentErrors++
+				return
+			}
+			a.nalUnit(int(b[2]&0x1F), b[2:2+size])
+			b = b[2+size:]
+		}
+	case h264FUA:
+		if len(payload) < 2 {
+			a.stats.FragmentErrors++
+			return
+		}
+		fu := payload[1]
+		a.fragment(fu&0x80 != 0, fu&0x40 != 0, int(fu&0x1F), seq,
+			[]byte{payload[0]&0xE0 | fu&0x1F}, payload[2:])
+	default:
+		a.nalUnit(nalType, payload)
+	}
+}
+
+// pushH265 handles single NAL, AP and FU packets
+func (a *Analyzer) pushH265(payload []byte, seq uint16) {
+	if len(payload) < 2 {
+		a.stats.FragmentErrors++
+		return
+	}
+	nalType := int(payload[0]>>1) & 0x3F
+
+	switch nalType {
+	case h265AP:
+		// AP: [hdr16] { [size16] [nal] }*
+		for b := payload[2:]; len(b) > 3; {
+			size := int(b[0])<<8 | int(b[1])
+			if size < 2 || size > len(b)-2 {
+				a.stats.FragmentErrors++
+				return
+			}
+			a.nalUnit(int(b[2]>>1)&0x3F, b[2:2+size])
+			b = b[2+size:]
+		}
+	case h265FU:
+		if len(payload) < 3 {
+			a.stats.FragmentErrors++
+			return
+		}
+		fu := payload[2]
+		a.fragment(fu&0x80 != 0, fu&0x40 != 0, int(fu&0x3F), seq,
+			[]byte{payload[0]&0x81 | (fu&0x3F)<<1, payload[1]}, payload[3:])
+	default:
+		a.nalUnit(nalType, payload)
+	}
+}
+
+// fragment tracks FU start/middle/end packets and flags broken reassembly.
+// header is the reconstructed NAL header, data the fragment payload.
+func (a *Analyzer) fragment(start, end bool, nalType int, seq uint16, header, data []byte) {
+	switch {
+	case start:
+		if a.inFragment {
+			// Previous fragmented NAL never received its end
+			a.stats.FragmentErrors++
+			a.frameClean = false
+		}
+		a.inFragment = true
+		a.fragType = nalType
+		a.write(annexBStartCode)
+		a.write(header)
+	case !a.inFragment:
+		// Continuation without a start - start was lost or we joined mid-NAL
+		a.stats.FragmentErrors++
+		a.frameClean = false
+		return
+	case seq != a.fragSeq+1 || nalType != a.fragType:
+		// Gap or type change inside the fragmented NAL
+		a.stats.FragmentErrors++
+		a.frameClean = false
+		a.inFragment = false
+		return
+	}
+
+	a.fragSeq = seq
+	a.write(data)
+	if end {
+		a.inFragment = false
+		a.countNAL(nalType)
+	}
+}
+
+// nalUnit records a complete, unfragmented NAL unit
+func (a *Analyzer) nalUnit(nalType int, nal []byte) {
+	a.write(annexBStartCode)
+	a.write(nal)
+	a.countNAL(nalType)
+
+	if a.onTimestamp != nil {
+		var body []byte
+		switch {
+		case a.codec == H264 && nalType == h264SEI:
+			body = nal[1:]
+		case a.codec == H265 && nalType == h265PrefixSEI && len(nal) > 2:
+			body = nal[2:]
+		}
+		if sent, ok := parseTimestampSEI(body); ok {
+			a.onTimestamp(sent)
+		}
+	}
+}
+
+// countNAL updates counters for a complete NAL unit
+func (a *Analyzer) countNAL(nalType int) {
+	a.stats.NALUnits++
+	if a.isKeyframe(nalType) {
+		a.frameIsKey = true
+	}
+}
+
+// write adds NAL data to the current frame hash
+func (a *Analyzer) write(b []byte) {
+	if a.hasher != nil {
+		a.hasher.Write(b)
+		a.frameBytes += len(b)
+	}
+}
+
+// isKeyframe reports whether a NAL type starts a random access point
+func (a *Analyzer) isKeyframe(nalType int) bool {
+	if a.codec == H264 {
+		return nalType == h264IDR
+	}
+	return nalType >= h265IRAPFirst && nalType <= h265IRAPLast
+}
+
+// Restart marks an intended discontinuity such as a pause or seek. The
+// frame in progress is closed without hashing and the jump is not counted
+// as a broken fragment or a GOP interval.
+func (a *Analyzer) Restart() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.frameClean = false
+	a.finishFrame()
+	a.haveFrame = false
+	a.haveSeq = false
+	a.inFragment = false
+	a.haveKey = false
+}
+
+// finishFrame closes the current access unit and updates GOP statistics
+func (a *Analyzer) finishFrame() {
+	if !a.haveFrame {
+		return
+	}
+	a.stats.Frames++
+	a.framesSinceKey++
+
+	if a.hasher != nil {
+		if a.frameClean && !a.inFragment && a.frameBytes > 0 {
+			a.onFrame(a.hasher.Sum64())
+		}
+		a.hasher.Reset()
+		a.frameBytes = 0
+	}
+
+	if !a.frameIsKey {
+		return
+	}
+	a.stats.Keyframes++
+
+	if a.haveKey {
+		ticks := uint64(a.lastTS - a.lastKeyTS)
+		a.gopCount++
+		a.gopFrames += a.framesSinceKey
+		a.gopTicks += ticks
+		a.stats.LastGOPMs = float64(ticks) * 1000 / a.clockRate
+		a.stats.AvgGOPFrames = float64(a.gopFrames) / float64(a.gopCount)
+		a.stats.AvgGOPMs = float64(a.gopTicks) * 1000 / a.clockRate / float64(a.gopCount)
+	}
+	a.haveKey = true
+	a.lastKeyTS = a.lastTS
+	a.framesSinceKey = 0
+}
+
+// Stats returns the statistics so far. The access unit in progress is not
+// included until the next timestamp arrives.
+func (a *Analyzer) Stats() Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stats
+}
@@ -0,0 +1,107 @@
+// Created by WINK Streaming (https://www.wink.co)
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+)
+
+// SEI NAL unit types and the user_data_unregistered payload type
+const (
+	h264SEI                 = 6
+	h265PrefixSEI           = 39
+	seiUserDataUnregistered = 5
+)
+
+// TimestampUUID identifies the wall-clock SEI message sent by publishers
+var TimestampUUID = [16]byte{
+	0x57, 0x49, 0x4e, 0x4b, 0x2d, 0x52, 0x54, 0x53,
+	0x50, 0x2d, 0x42, 0x45, 0x4e, 0x43, 0x48, 0x01,
+}
+
+// TimestampSEI returns an H.264 SEI NAL unit carrying t as a
+// user_data_unregistered message, for end-to-end latency measurement.
+// Servers forward SEI like any other NAL unit.
+func TimestampSEI(t time.Time) []byte {
+	rbsp := make([]byte, 0, 2+16+8+1)
+	rbsp = append(rbsp, seiUserDataUnregistered, 16+8)
+	rbsp = append(rbsp, TimestampUUID[:]...)
+	rbsp = binary.BigEndian.AppendUint64(rbsp, uint64(t.UnixNano()))
+	rbsp = append(rbsp, 0x80) // rbsp_trailing_bits
+
+	return append([]byte{h264SEI}, escapeRBSP(rbsp)...)
+}
+
+// parseTimestampSEI looks for the timestamp message in an SEI NAL unit
+// body (after the NAL header)
+func parseTimestampSEI(body []byte) (time.Time, bool) {
+	b := unescapeRBSP(body)
+	for len(b) > 1 && b[0] != 0x80 {
+		payloadType, n := seiValue(b)
+		b = b[n:]
+		size, n := seiValue(b)
+		b = b[n:]
+		if n == 0 || size > len(b) {
+			return time.Time{}, false
+		}
+
+		if payloadType == seiUserDataUnregistered && size >= 24 && bytes.Equal(b[:16], TimestampUUID[:]) {
+			return time.Unix(0, int64(binary.BigEndian.Uint64(b[16:24]))), true
+		}
+		b = b[size:]
+	}
+	return time.Time{}, false
+}
+
+// seiValue decodes an SEI payload type or size (0xFF-extended byte run),
+// returning the value and bytes consumed (0 if truncated)
+func seiValue(b []byte) (int, int) {
+	v := 0
+	for i, c := range b {
+		v += int(c)
+		if c != 0xFF {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// escapeRBSP inserts emulation prevention bytes so the data cannot
+// contain a start code
+func escapeRBSP(rbsp []byte) []byte {
+	out := make([]byte, 0, len(rbsp)+4)
+	zeros := 0
+	for _, c := range rbsp {
+		if zeros >= 2 && c <= 3 {
+			out = append(out, 3)
+			zeros = 0
+		}
+		out = append(out, c)
+		if c == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+// unescapeRBSP removes emulation prevention bytes
+func unescapeRBSP(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	zeros := 0
+	for _, c := range b {
+		if zeros >= 2 && c == 3 {
+			zeros = 0
+			continue
+		}
+		out = append(out, c)
+		if c == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
@@ -0,0 +1,145 @@
+// Created by WINK Streaming (https://www.wink.co)
+package codec
+
+import (
+	"sync"
+	"time"
+)
+
+// Verification defaults
+const (
+	ReferenceWindow = 30 * time.Second // How long reference hashes are kept
+	VerifyGrace     = 5 * time.Second  // How far the reference may lag a verifier
+)
+
+// Reference holds the frame hashes seen by a single reference reader, which
+// other readers of the same stream compare their frames against
+type Reference struct {
+	mu      sync.Mutex
+	hashes  map[uint64]int // hash -> occurrences in the window
+	order   []refFrame     // Insertion order, for expiry
+	lastAdd time.Time
+}
+
+type refFrame struct {
+	hash uint64
+	at   time.Time
+}
+
+// NewReference creates an empty reference
+func NewReference() *Reference {
+	return &Reference{hashes: make(map[uint64]int)}
+}
+
+// Add records a frame hash from the reference reader
+func (r *Reference) Add(hash uint64) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hashes[hash]++
+	r.order = append(r.order, refFrame{hash, now})
+	r.lastAdd = now
+
+	// Expire hashes that have left the window
+	n := 0
+	for n < len(r.order) && now.Sub(r.order[n].at) > ReferenceWindow {
+		h := r.order[n].hash
+		if r.hashes[h]--; r.hashes[h] <= 0 {
+			delete(r.hashes, h)
+		}
+		n++
+	}
+	if n > 0 {
+		r.order = append(r.order[:0], r.order[n:]...)
+	}
+}
+
+// lookup reports whether hash was seen, and whether the reference has
+// received frames since t (i.e. a miss is meaningful)
+func (r *Reference) lookup(hash uint64, t time.Time) (found, current bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.hashes[hash] > 0, r.lastAdd.After(t)
+}
+
+// Verifier checks a reader's frame hashes against a Reference. Frames are
+// checked after VerifyGrace so the reference has time to receive them.
+type Verifier struct {
+	mu      sync.Mutex
+	ref     *Reference
+	pending []refFrame
+	result  VerifyResult
+}
+
+// VerifyResult counts verified frames
+type VerifyResult struct {
+	Matched    uint64 // Frames identical to the reference
+	Mismatched uint64 // Frames the reference never saw (corrupted)
+	Skipped    uint64 // Frames that could not be checked
+}
+
+// NewVerifier creates a verifier against ref
+func NewVerifier(ref *Reference) *Verifier {
+	return &Verifier{ref: ref}
+}
+
+// Check queues a frame hash and resolves frames older than VerifyGrace
+func (v *Verifier) Check(hash uint64) {
+	now := time.Now()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.pending = append(v.pending, refFrame{hash, now})
+	v.resolve(now.Add(-VerifyGrace))
+}
+
+// Flush resolves queued frames at the end of a session. Frames that are
+// still within the grace period are resolved only if the reference
+// already has them.
+func (v *Verifier) Flush() VerifyResult {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.resolve(time.Now().Add(-VerifyGrace))
+	for _, f := range v.pending {
+		if found, _ := v.ref.lookup(f.hash, f.at); found {
+			v.result.Matched++
+		} else {
+			v.result.Skipped++
+		}
+	}
+	v.pending = nil
+	return v.result
+}
+
+// Result returns the counts so far
+func (v *Verifier) Result() VerifyResult {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.result
+}
+
+// resolve checks all pending frames received before cutoff. The caller
+// holds v.mu.
+func (v *Verifier) resolve(cutoff time.Time) {
+	n := 0
+	for n < len(v.pending) && v.pending[n].at.Before(cutoff) {
+		f := v.pending[n]
+		found, current := v.ref.lookup(f.hash, f.at.Add(VerifyGrace/2))
+		switch {
+		case found:
+			v.result.Matched++
+		case current:
+			v.result.Mismatched++
+		default:
+			// Reference stopped receiving - nothing to compare against
+			v.result.Skipped++
+		}
+		n++
+	}
+	if n > 0 {
+		v.pending = append(v.pending[:0], v.pending[n:]...)
+	}
+}
@@ -0,0 +1,174 @@
+// Created by WINK Streaming (https://www.wink.co)
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/bench"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+)
+
+// Agent API paths
+const (
+	PathRun   = "/run"
+	PathStats = "/stats"
+	PathStop  = "/stop"
+)
+
+// Job is the share of a benchmark assigned to one agent
+type Job struct {
+	Config bench.Config `json:"config"`
+}
+
+// AgentStatus is returned by the agent's stats endpoint
+type AgentStatus struct {
+	Running bool        `json:"running"`
+	Stats   bench.Stats `json:"stats"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// Agent runs benchmark jobs on behalf of a coordinator
+type Agent struct {
+	log *slog.Logger
+
+	mu     sync.Mutex
+	runner *bench.Runner
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// NewAgent creates an idle agent
+func NewAgent(log *slog.Logger) *Agent {
+	return &Agent{log: log}
+}
+
+// Handler returns the agent's HTTP API
+func (a *Agent) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathRun, a.handleRun)
+	mux.HandleFunc(PathStats, a.handleStats)
+	mux.HandleFunc(PathStop, a.handleStop)
+	return mux
+}
+
+// ListenAndServe serves the agent API on addr until ctx is done
+func (a *Agent) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: a.Handler()}
+	go func() {
+		<-ctx.Done()
+		a.stop()
+		srv.Close()
+	}()
+
+	a.log.Info("agent listening", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// handleRun starts a job if none is running
+func (a *Agent) handleRun(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var job Job
+	if err := json.NewDecoder(req.Body).Decode(&job); err != nil {
+		http.Error(w, "invalid job: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.done != nil {
+		select {
+		case <-a.done:
+		default:
+			http.Error(w, "job already running", http.StatusConflict)
+			return
+		}
+	}
+
+	// The job runs until it finishes or is stopped, not for the request.
+	// Fixed-count and real-world runs last until their context ends.
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if selfTimed(job.Config) || job.Config.Duration <= 0 {
+		ctx, cancel = context.WithCancel(context.Background())
+	} else {
+		ctx, cancel = context.WithTimeout(context.Background(), job.Config.Duration)
+	}
+	runner := bench.NewRunner(job.Config, rtp.NewAggregator())
+	done := make(chan struct{})
+
+	a.runner = runner
+	a.cancel = cancel
+	a.done = done
+	a.err = nil
+
+	a.log.Info("job started", "readers", job.Config.Readers, "rate", job.Config.Rate)
+	go func() {
+		defer close(done)
+		defer cancel()
+		err := runner.Run(ctx)
+		a.mu.Lock()
+		a.err = err
+		a.mu.Unlock()
+		a.log.Info("job finished", "error", err)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// selfTimed reports whether the run mode ends on its own
+func selfTimed(config bench.Config) bool {
+	return !config.RealWorld && (config.Scenario != nil || config.Replay != nil || config.Profile != nil || config.FindMax != nil)
+}
+
+// handleStats reports the current job's statistics
+func (a *Agent) handleStats(w http.ResponseWriter, req *http.Request) {
+	a.mu.Lock()
+	status := AgentStatus{}
+	if a.runner != nil {
+		status.Stats = a.runner.GetStats()
+		select {
+		case <-a.done:
+		default:
+			status.Running = true
+		}
+		if a.err != nil {
+			status.Error = a.err.Error()
+		}
+	}
+	a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleStop cancels the running job
+func (a *Agent) handleStop(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	a.stop()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// stop cancels the running job, if any
+func (a *Agent) stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cancel != nil {
+		a.cancel()
+	}
+}
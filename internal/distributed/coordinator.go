@@ -0,0 +1,322 @@
+// Created by WINK Streaming (https://www.wink.co)
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/bench"
+)
+
+// Coordinator splits a benchmark across agents and aggregates their stats
+type Coordinator struct {
+	agents []string // Agent base URLs, e.g. http://10.0.0.2:7070
+	client *http.Client
+	log    *slog.Logger
+}
+
+// NewCoordinator creates a coordinator for the given agent addresses.
+// Addresses without a scheme are assumed to be http.
+func NewCoordinator(agents []string, log *slog.Logger) *Coordinator {
+	urls := make([]string, len(agents))
+	for i, a := range agents {
+		if !strings.Contains(a, "://") {
+			a = "http://" + a
+		}
+		urls[i] = strings.TrimRight(a, "/")
+	}
+	return &Coordinator{
+		agents: urls,
+		client: &http.Client{Timeout: 10 * time.Second},
+		log:    log,
+	}
+}
+
+// Run starts each agent's share of config, reports merged stats every
+// StatsInterval to Config.OutputFile (stdout by default) in Config.LogFormat,
+// and returns the final merged stats once all agents have finished
+func (c *Coordinator) Run(ctx context.Context, config bench.Config) (bench.Stats, error) {
+	if len(c.agents) == 0 {
+		return bench.Stats{}, fmt.Errorf("no agents")
+	}
+	if config.FindMax != nil {
+		return bench.Stats{}, fmt.Errorf("find-max is not supported in distributed mode")
+	}
+
+	// One run ID for all agents, so server logs show a single run
+	if config.RunID == "" {
+		config.RunID = bench.NewRunID()
+		c.log.Info("run ID", "run_id", config.RunID)
+	}
+
+	out, err := bench.OpenOutput(config.OutputFile)
+	if err != nil {
+		return bench.Stats{}, fmt.Errorf("failed to open output: %w", err)
+	}
+	defer out.Close()
+	formatter, err := bench.NewFormatter(config.LogFormat, out)
+	if err != nil {
+		return bench.Stats{}, err
+	}
+	if config.TimelineFile != "" {
+		timeline, err := os.Create(config.TimelineFile)
+		if err != nil {
+			return bench.Stats{}, fmt.Errorf("failed to create timeline: %w", err)
+		}
+		defer timeline.Close()
+		tf, _ := bench.NewFormatter(bench.TimelineFormat(config.TimelineFile), timeline)
+		formatter = bench.MultiFormatter(formatter, tf)
+	}
+	if config.ReportFile != "" {
+		report, err := os.Create(config.ReportFile)
+		if err != nil {
+			return bench.Stats{}, fmt.Errorf("failed to create report: %w", err)
+		}
+		defer report.Close()
+		formatter = bench.MultiFormatter(formatter, bench.NewHTMLReport(report, config))
+	}
+	if config.InfluxURL != "" || config.OTLPURL != "" {
+		tags := bench.NewExportTags(config)
+		c.log.Info("exporting stats", "run_id", tags.RunID, "scenario", tags.Scenario, "target_host", tags.Target)
+		formatter = bench.MultiFormatter(append([]bench.Formatter{formatter}, bench.NewExporters(config, tags, c.log)...)...)
+	}
+
+	// The coordinator watches the target host once, for all agents
+	monitor := bench.NewHostMonitor(config, c.log)
+	monitorCtx, stopMonitor := context.WithCancel(ctx)
+	defer stopMonitor()
+	go monitor.Run(monitorCtx)
+
+	// Start every agent; abort all if any refuses
+	for i, agent := range c.agents {
+		job := Job{Config: SplitConfig(config, i, len(c.agents))}
+		if err := c.post(agent+PathRun, job); err != nil {
+			c.stopAll()
+			return bench.Stats{}, fmt.Errorf("agent %s: %w", agent, err)
+		}
+		c.log.Info("agent started", "agent", agent, "readers", job.Config.Readers, "rate", job.Config.Rate, "seed", job.Config.Seed)
+	}
+
+	interval := config.StatsInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	last := make([]bench.Stats, len(c.agents))
+	for {
+		select {
+		case <-ctx.Done():
+			c.log.Info("stopping agents")
+			c.stopAll()
+			c.waitAll(last)
+			merged := bench.MergeStats(last)
+			monitor.Fill(&merged)
+			return merged, formatter.WriteSummary(time.Since(start), merged)
+		case <-ticker.C:
+		}
+
+		running := c.poll(last)
+		merged := bench.MergeStats(last)
+		monitor.Fill(&merged)
+		if running == 0 {
+			return merged, formatter.WriteSummary(time.Since(start), merged)
+		}
+		if err := formatter.WriteSample(time.Since(start), merged); err != nil {
+			return merged, err
+		}
+	}
+}
+
+// poll refreshes last with each agent's stats and returns how many agents
+// are still running. Unreachable agents keep their previous stats and
+// count as running.
+func (c *Coordinator) poll(last []bench.Stats) int {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	running := 0
+
+	for i, agent := range c.agents {
+		wg.Add(1)
+		go func(i int, agent string) {
+			defer wg.Done()
+			status, err := c.status(agent)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				c.log.Warn("agent unreachable", "agent", agent, "error", err)
+				running++
+				return
+			}
+			if status.Error != "" {
+				c.log.Warn("agent job failed", "agent", agent, "error", status.Error)
+			}
+			last[i] = status.Stats
+			if status.Running {
+				running++
+			}
+		}(i, agent)
+	}
+	wg.Wait()
+	return running
+}
+
+// waitAll polls until every agent has stopped or stops responding
+func (c *Coordinator) waitAll(last []bench.Stats) {
+	deadline := time.Now().Add(time.Minute)
+	for time.Now().Before(deadline) {
+		if c.poll(last) == 0 {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// status fetches one agent's status
+func (c *Coordinator) status(agent string) (AgentStatus, error) {
+	var status AgentStatus
+	resp, err := c.client.Get(agent + PathStats)
+	if err != nil {
+		return status, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return status, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	return status, err
+}
+
+// stopAll asks every agent to stop its job
+func (c *Coordinator) stopAll() {
+	for _, agent := range c.agents {
+		if err := c.post(agent+PathStop, nil); err != nil {
+			c.log.Warn("failed to stop agent", "agent", agent, "error", err)
+		}
+	}
+}
+
+// post sends a JSON body and expects 202 Accepted
+func (c *Coordinator) post(url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		var msg bytes.Buffer
+		msg.ReadFrom(resp.Body)
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(msg.String()))
+	}
+	return nil
+}
+
+// SplitConfig returns agent i's share (of n) of config: reader counts,
+// connection rates and bandwidth are divided, each agent gets its own seed,
+// publishers run on the first agent, and output is left to the coordinator
+func SplitConfig(config bench.Config, i, n int) bench.Config {
+	share := func(total int) int {
+		s := total / n
+		if i < total%n {
+			s++
+		}
+		return s
+	}
+	atLeastOne := func(total int) int {
+		if s := share(total); s > 0 || total == 0 {
+			return s
+		}
+		return 1
+	}
+
+	c := config
+	c.Readers = share(config.Readers)
+	c.AvgConnections = share(config.AvgConnections)
+	if config.TargetCurve != nil {
+		c.TargetCurve = make([]int, len(config.TargetCurve))
+		for m, n := range config.TargetCurve {
+			c.TargetCurve[m] = share(n)
+		}
+	}
+	c.Rate = config.Rate / float64(n)
+	c.DrainRate = config.DrainRate / float64(n)
+	c.MaxBandwidthMbps = config.MaxBandwidthMbps / float64(n)
+	if config.Seed != 0 {
+		// Reproducible, but the agents' random picks must not move in step
+		c.Seed = config.Seed + int64(i)
+	}
+
+	if config.Scenario != nil {
+		sc := *config.Scenario
+		sc.Rate = sc.Rate / float64(n)
+		sc.Phases = make([]bench.Phase, len(config.Scenario.Phases))
+		for p, ph := range config.Scenario.Phases {
+			ph.Readers = share(ph.Readers)
+			sc.Phases[p] = ph
+		}
+		c.Scenario = &sc
+	}
+	if config.Profile != nil {
+		p := *config.Profile
+		p.Base = share(p.Base)
+		p.Step = atLeastOne(p.Step)
+		p.Peak = share(p.Peak)
+		c.Profile = &p
+	}
+	if config.Events != nil {
+		c.Events = make([]bench.TrafficEvent, len(config.Events))
+		for e, ev := range config.Events {
+			ev.Viewers = atLeastOne(ev.Viewers)
+			c.Events[e] = ev
+		}
+	}
+	if i > 0 {
+		// Each publisher records to its own path, which two agents would
+		// fight over: the first agent runs them all
+		c.Publishers = 0
+		c.PublishURLs = nil
+	}
+	if config.Replay != nil {
+		// Each viewer's events go to one agent, so it also ends the session
+		rp := *config.Replay
+		rp.Events = nil
+		for _, ev := range config.Replay.Events {
+			h := fnv.New32a()
+			h.Write([]byte(ev.Viewer))
+			if int(h.Sum32()%uint32(n)) == i {
+				rp.Events = append(rp.Events, ev)
+			}
+		}
+		c.Replay = &rp
+	}
+
+	// The coordinator owns reporting
+	c.OutputFile = ""
+	c.TimelineFile = ""
+	c.MonitorURLs = nil
+	c.MonitorCommand = ""
+	c.ReportFile = ""
+	c.InfluxURL = ""
+	c.OTLPURL = ""
+	c.Dashboard = false
+	c.Assertions = ""
+	c.Baseline = ""
+	c.Tolerances = ""
+	c.VerdictFile = ""
+	return c
+}
@@ -0,0 +1,172 @@
+// Created by WINK Streaming (https://www.wink.co)
+package histogram
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Log-linear bucket layout (HDR-style): values below 2*subCount microseconds
+// get exact buckets, above that each power of two is split into subCount
+// linear sub-buckets, bounding the relative error to 1/subCount (~1.6%).
+const (
+	subBits   = 6
+	subCount  = 1 << subBits
+	maxShift  = 34 // Covers up to ~2^41 microseconds (~25 days)
+	numBucket = 2*subCount + maxShift*subCount
+)
+
+// Histogram records durations with bounded memory and lock-free updates.
+// It is safe for concurrent use by any number of goroutines.
+type Histogram struct {
+	counts [numBucket]atomic.Uint64
+	count  atomic.Uint64
+	sum    atomic.Uint64 // microseconds
+	min    atomic.Uint64 // microseconds
+	max    atomic.Uint64 // microseconds
+}
+
+// New creates an empty histogram
+func New() *Histogram {
+	h := &Histogram{}
+	h.min.Store(math.MaxUint64)
+	return h
+}
+
+// Record adds a duration sample
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	us := uint64(d / time.Microsecond)
+
+	h.counts[bucketIndex(us)].Add(1)
+	h.count.Add(1)
+	h.sum.Add(us)
+
+	for {
+		old := h.min.Load()
+		if us >= old || h.min.CompareAndSwap(old, us) {
+			break
+		}
+	}
+	for {
+		old := h.max.Load()
+		if us <= old || h.max.CompareAndSwap(old, us) {
+			break
+		}
+	}
+}
+
+// Count returns the number of recorded samples
+func (h *Histogram) Count() uint64 {
+	return h.count.Load()
+}
+
+// Min returns the smallest recorded sample
+func (h *Histogram) Min() time.Duration {
+	if h.count.Load() == 0 {
+		return 0
+	}
+	return time.Duration(h.min.Load()) * time.Microsecond
+}
+
+// Max returns the largest recorded sample
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max.Load()) * time.Microsecond
+}
+
+// Mean returns the average of all samples
+func (h *Histogram) Mean() time.Duration {
+	count := h.count.Load()
+	if count == 0 {
+		return 0
+	}
+	return time.Duration(h.sum.Load()/count) * time.Microsecond
+}
+
+// Percentile returns the value at the given percentile (0-100)
+func (h *Histogram) Percentile(p float64) time.Duration {
+	total := h.count.Load()
+	if total == 0 {
+		return 0
+	}
+
+	target := uint64(math.Ceil(p / 100 * float64(total)))
+	if target == 0 {
+		target = 1
+	}
+
+	var seen uint64
+	for i := range h.counts {
+		seen += h.counts[i].Load()
+		if seen >= target {
+			// Clamp the bucket midpoint to the exact observed range
+			us := bucketMidpoint(i)
+			if us < h.min.Load() {
+				us = h.min.Load()
+			}
+			if us > h.max.Load() {
+				us = h.max.Load()
+			}
+			return time.Duration(us) * time.Microsecond
+		}
+	}
+	return h.Max()
+}
+
+// Summary returns the common percentiles in milliseconds
+func (h *Histogram) Summary() Summary {
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+	return Summary{
+		Count: h.Count(),
+		Min:   ms(h.Min()),
+		Mean:  ms(h.Mean()),
+		Max:   ms(h.Max()),
+		P50:   ms(h.Percentile(50)),
+		P90:   ms(h.Percentile(90)),
+		P95:   ms(h.Percentile(95)),
+		P99:   ms(h.Percentile(99)),
+		P999:  ms(h.Percentile(99.9)),
+	}
+}
+
+// Summary holds histogram statistics in milliseconds
+type Summary struct {
+	Count uint64
+	Min   float64
+	Mean  float64
+	Max   float64
+	P50   float64
+	P90   float64
+	P95   float64
+	P99   float64
+	P999  float64
+}
+
+// bucketIndex maps a value in microseconds to its bucket
+func bucketIndex(us uint64) int {
+	if us < 2*subCount {
+		return int(us)
+	}
+	shift := bits.Len64(us) - (subBits + 1)
+	if shift > maxShift {
+		return numBucket - 1
+	}
+	return 2*subCount + (shift-1)*subCount + int(us>>uint(shift)) - subCount
+}
+
+// bucketMidpoint returns the midpoint value of a bucket in microseconds
+func bucketMidpoint(idx int) uint64 {
+	if idx < 2*subCount {
+		return uint64(idx)
+	}
+	idx -= 2 * subCount
+	shift := idx/subCount + 1
+	lower := uint64(idx%subCount+subCount) << uint(shift)
+	return lower + (uint64(1)<<uint(shift))/2
+}
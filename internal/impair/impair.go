@@ -0,0 +1,227 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+// Package impair emulates a bad last-mile network on received packets:
+// loss, delay, jitter, duplication and reordering, in the spirit of netem
+// but per connection and on the client side
+package impair
+
+import (
+	"container/heap"
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultReorderDelay is how long a reordered packet is held back when
+// Model.ReorderDelay is not set
+const DefaultReorderDelay = 20 * time.Millisecond
+
+// queueSize bounds the packets waiting to be scheduled; Push blocks when
+// the delivery loop falls this far behind
+const queueSize = 1024
+
+// Model describes the impairment applied to one connection. The zero value
+// changes nothing.
+type Model struct {
+	Loss         float64       // Probability a packet is dropped (0.0-1.0)
+	BurstLength  float64       // Average loss burst in packets; above 1, losses follow a Gilbert-Elliott model with the same average Loss
+	Delay        time.Duration // Added to every packet
+	Jitter       time.Duration // Random extra delay, uniform in [0, Jitter]; large values reorder packets
+	Duplicate    float64       // Probability a packet is delivered twice (0.0-1.0)
+	Reorder      float64       // Probability a packet is held back so later packets overtake it (0.0-1.0)
+	ReorderDelay time.Duration // How long reordered packets are held (default DefaultReorderDelay)
+	RequestDelay time.Duration // Added before every RTSP request the client sends
+}
+
+// Enabled reports whether the model changes received packets
+func (m Model) Enabled() bool {
+	return m.Loss > 0 || m.Delay > 0 || m.Jitter > 0 || m.Duplicate > 0 || m.Reorder > 0
+}
+
+// Counts summarizes what an Impairer did to its packets
+type Counts struct {
+	Dropped    uint64
+	Duplicated uint64
+	Reordered  uint64
+}
+
+// Impairer applies a Model to a packet stream. Packets go in with Push
+// from the reading goroutine and come out, in their impaired order, through
+// the deliver callback on the goroutine Start runs.
+type Impairer struct {
+	model   Model
+	rng     *rand.Rand
+	deliver func([]byte)
+	in      chan pending
+	done    chan struct{} // Closed when the current run stops
+
+	// Gilbert-Elliott state: in the bad state every packet is lost
+	bad       bool
+	goodToBad float64
+	badToGood float64
+
+	dropped    atomic.Uint64
+	duplicated atomic.Uint64
+	reordered  atomic.Uint64
+}
+
+// pending is a packet waiting for its delivery time
+type pending struct {
+	data []byte
+	due  time.Time
+	seq  uint64 // Tie-break, keeps equal due times in arrival order
+}
+
+// New creates an impairer for model that hands surviving packets to
+// deliver, drawing random numbers from rng
+func New(model Model, rng *rand.Rand, deliver func([]byte)) *Impairer {
+	if model.ReorderDelay <= 0 {
+		model.ReorderDelay = DefaultReorderDelay
+	}
+	i := &Impairer{
+		model:   model,
+		rng:     rng,
+		deliver: deliver,
+		in:      make(chan pending, queueSize),
+	}
+	if model.BurstLength > 1 && model.Loss > 0 && model.Loss < 1 {
+		i.badToGood = 1 / model.BurstLength
+		i.goodToBad = model.Loss * i.badToGood / (1 - model.Loss)
+	}
+	return i
+}
+
+// Push submits a received packet. data is copied, so the caller may reuse
+// its buffer. Push must be called from a single goroutine.
+func (i *Impairer) Push(data []byte) {
+	if i.lose() {
+		i.dropped.Add(1)
+		return
+	}
+
+	copies := 1
+	if i.model.Duplicate > 0 && i.rng.Float64() < i.model.Duplicate {
+		copies = 2
+		i.duplicated.Add(1)
+	}
+	for n := 0; n < copies; n++ {
+		delay := i.model.Delay
+		if i.model.Jitter > 0 {
+			delay += time.Duration(i.rng.Int63n(int64(i.model.Jitter) + 1))
+		}
+		if i.model.Reorder > 0 && i.rng.Float64() < i.model.Reorder {
+			delay += i.model.ReorderDelay
+			i.reordered.Add(1)
+		}
+		select {
+		case i.in <- pending{data: append([]byte(nil), data...), due: time.Now().Add(delay)}:
+		case <-i.done:
+			return
+		}
+	}
+}
+
+// lose decides whether the next packet is dropped
+func (i *Impairer) lose() bool {
+	if i.badToGood == 0 {
+		return i.model.Loss > 0 && i.rng.Float64() < i.model.Loss
+	}
+	if i.bad {
+		i.bad = i.rng.Float64() >= i.badToGood
+	} else {
+		i.bad = i.rng.Float64() < i.goodToBad
+	}
+	return i.bad
+}
+
+// Start delivers packets as they come due, on a new goroutine, until ctx
+// is done, and returns a function waiting for that goroutine to stop.
+// Packets still held back at that point are discarded. Once stopped, the
+// impairer may be started again; its counts and random state carry on.
+// Push must only be called between Start and the end of its run.
+func (i *Impairer) Start(ctx context.Context) (wait func()) {
+	done := make(chan struct{})
+	i.done = done
+	go i.run(ctx, done)
+	return func() { <-done }
+}
+
+// run is the delivery loop of Start
+func (i *Impairer) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	defer i.discard()
+	var queue pendingQueue
+	var next uint64
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		// Deliver everything that is due, then sleep until the next one
+		now := time.Now()
+		for len(queue) > 0 && !queue[0].due.After(now) {
+			i.deliver(heap.Pop(&queue).(pending).data)
+		}
+		wait := time.Hour
+		if len(queue) > 0 {
+			wait = queue[0].due.Sub(now)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-ctx.Done():
+			return
+		case p := <-i.in:
+			p.seq = next
+			next++
+			heap.Push(&queue, p)
+		case <-timer.C:
+		}
+	}
+}
+
+// discard drops the packets pushed but not yet taken by the run, so a
+// later run does not deliver them
+func (i *Impairer) discard() {
+	for {
+		select {
+		case <-i.in:
+		default:
+			return
+		}
+	}
+}
+
+// Counts returns what has been done to the packets so far
+func (i *Impairer) Counts() Counts {
+	return Counts{
+		Dropped:    i.dropped.Load(),
+		Duplicated: i.duplicated.Load(),
+		Reordered:  i.reordered.Load(),
+	}
+}
+
+// pendingQueue is a min-heap of packets by delivery time
+type pendingQueue []pending
+
+func (q pendingQueue) Len() int { return len(q) }
+func (q pendingQueue) Less(a, b int) bool {
+	if q[a].due.Equal(q[b].due) {
+		return q[a].seq < q[b].seq
+	}
+	return q[a].due.Before(q[b].due)
+}
+func (q pendingQueue) Swap(a, b int)       { q[a], q[b] = q[b], q[a] }
+func (q *pendingQueue) Push(x interface{}) { *q = append(*q, x.(pending)) }
+func (q *pendingQueue) Pop() interface{} {
+	old := *q
+	p := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return p
+}
@@ -0,0 +1,73 @@
+// Created by WINK Streaming (https://www.wink.co)
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Options configures the logger
+type Options struct {
+	Level string    // debug, info, warn or error (default info)
+	JSON  bool      // JSON lines instead of key=value text
+	Out   io.Writer // Defaults to the current os.Stdout
+}
+
+// New creates a leveled structured logger
+func New(opts Options) (*slog.Logger, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	out := opts.Out
+	if out == nil {
+		out = stdout{}
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	if opts.JSON {
+		return slog.New(slog.NewJSONHandler(out, handlerOpts)), nil
+	}
+
+	// Text logs keep the short [15:04:05] style timestamps
+	handlerOpts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.String(slog.TimeKey, a.Value.Time().Format("15:04:05"))
+		}
+		return a
+	}
+	return slog.New(slog.NewTextHandler(out, handlerOpts)), nil
+}
+
+// ParseLevel converts a level name to a slog.Level
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level: %s", name)
+	}
+}
+
+// Discard returns a logger that drops everything
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+// stdout writes to whatever os.Stdout is at the time of the write, so that
+// output redirection (e.g. the dashboard's event log) is picked up
+type stdout struct{}
+
+func (stdout) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
@@ -0,0 +1,272 @@
+// Created by WINK Streaming (https://www.wink.co)
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+)
+
+// LinkTypeRaw is the link type of the files: every packet starts with an
+// IPv4 or IPv6 header
+const LinkTypeRaw = 101
+
+// SnapLen is the largest packet the files declare
+const SnapLen = 262144
+
+// maxSegment is the largest TCP payload per synthesized packet, so the IP
+// length fields never overflow
+const maxSegment = 65000
+
+// TCP flags
+const (
+	flagFIN = 0x01
+	flagSYN = 0x02
+	flagPSH = 0x08
+	flagACK = 0x10
+)
+
+// Writer writes a libpcap capture file of synthesized IP packets. It is
+// safe for concurrent use; after the first write error it writes nothing.
+type Writer struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer
+	ipID   uint16
+	err    error
+}
+
+// Create creates a capture file at path
+func Create(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// NewWriter writes the file header to w and returns a Writer for packets
+func NewWriter(w io.Writer) (*Writer, error) {
+	bw := bufio.NewWriterSize(w, 64*1024)
+	var header [24]byte
+	binary.LittleEndian.PutUint32(header[0:], 0xa1b2c3d4) // Microsecond timestamps
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], SnapLen)
+	binary.LittleEndian.PutUint32(header[20:], LinkTypeRaw)
+	if _, err := bw.Write(header[:]); err != nil {
+		return nil, err
+	}
+	return &Writer{w: bw}, nil
+}
+
+// WritePacket writes a packet captured at ts
+func (w *Writer) WritePacket(ts time.Time, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writePacket(ts, data)
+}
+
+// writePacket writes a packet record; the caller must hold w.mu
+func (w *Writer) writePacket(ts time.Time, data []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	var record [16]byte
+	binary.LittleEndian.PutUint32(record[0:], uint32(ts.Unix()))
+	binary.LittleEndian.PutUint32(record[4:], uint32(ts.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(data)))
+	if _, err := w.w.Write(record[:]); err != nil {
+		w.err = err
+		return err
+	}
+	if _, err := w.w.Write(data); err != nil {
+		w.err = err
+	}
+	return w.err
+}
+
+// Close flushes the file and closes it if the Writer created it
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.w.Flush()
+	if w.err == nil {
+		w.err = err
+	}
+	if w.closer != nil {
+		if cerr := w.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	w.err = os.ErrClosed
+	return err
+}
+
+// UDP writes a datagram from src to dst
+func (w *Writer) UDP(src, dst netip.AddrPort, payload []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	udp := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint16(udp[0:], src.Port())
+	binary.BigEndian.PutUint16(udp[2:], dst.Port())
+	binary.BigEndian.PutUint16(udp[4:], uint16(len(udp)))
+	copy(udp[8:], payload)
+	w.writeIP(src.Addr(), dst.Addr(), 17, udp, 6)
+}
+
+// writeIP wraps a transport segment in an IP header, fills in the
+// segment's checksum at offset sum and writes the packet; the caller must
+// hold w.mu
+func (w *Writer) writeIP(src, dst netip.Addr, proto byte, segment []byte, sum int) {
+	src, dst = src.Unmap(), dst.Unmap()
+	var packet []byte
+	if src.Is4() && dst.Is4() {
+		w.ipID++
+		packet = make([]byte, 20, 20+len(segment))
+		packet[0] = 0x45
+		binary.BigEndian.PutUint16(packet[2:], uint16(20+len(segment)))
+		binary.BigEndian.PutUint16(packet[4:], w.ipID)
+		binary.BigEndian.PutUint16(packet[6:], 0x4000) // Don't fragment
+		packet[8] = 64
+		packet[9] = proto
+		s, d := src.As4(), dst.As4()
+		copy(packet[12:], s[:])
+		copy(packet[16:], d[:])
+		binary.BigEndian.PutUint16(packet[10:], checksum(packet[:20]))
+		pseudo := append(append(s[:], d[:]...), 0, proto, byte(len(segment)>>8), byte(len(segment)))
+		binary.BigEndian.PutUint16(segment[sum:], checksum(pseudo, segment))
+	} else {
+		packet = make([]byte, 40, 40+len(segment))
+		packet[0] = 0x60
+		binary.BigEndian.PutUint16(packet[4:], uint16(len(segment)))
+		packet[6] = proto
+		packet[7] = 64
+		s, d := src.As16(), dst.As16()
+		copy(packet[8:], s[:])
+		copy(packet[24:], d[:])
+		pseudo := append(append(s[:], d[:]...), 0, 0, byte(len(segment)>>8), byte(len(segment)), 0, 0, 0, proto)
+		binary.BigEndian.PutUint16(segment[sum:], checksum(pseudo, segment))
+	}
+	w.writePacket(time.Now(), append(packet, segment...))
+}
+
+// checksum returns the Internet checksum of the parts; all but the last
+// must have an even length
+func checksum(parts ...[]byte) uint16 {
+	var sum uint32
+	for _, data := range parts {
+		for i := 0; i+1 < len(data); i += 2 {
+			sum += uint32(data[i])<<8 | uint32(data[i+1])
+		}
+		if len(data)%2 == 1 {
+			sum += uint32(data[len(data)-1]) << 8
+		}
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	if sum == 0xffff {
+		return 0xffff // Zero means no checksum in UDP
+	}
+	return ^uint16(sum)
+}
+
+// TCPStream writes the two directions of a TCP connection as segments
+// with consistent sequence numbers, so analyzers reassemble the stream
+type TCPStream struct {
+	w      *Writer
+	client netip.AddrPort
+	server netip.AddrPort
+	seq    [2]uint32 // Next sequence number of the client and the server
+	closed bool
+}
+
+// TCP starts a connection from client to server with a three-way
+// handshake
+func (w *Writer) TCP(client, server netip.AddrPort) *TCPStream {
+	s := &TCPStream{w: w, client: client, server: server, seq: [2]uint32{1000, 5000}}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s.segment(0, flagSYN, nil)
+	s.segment(1, flagSYN|flagACK, nil)
+	s.segment(0, flagACK, nil)
+	return s
+}
+
+// Send writes data from the client to the server
+func (s *TCPStream) Send(data []byte) {
+	s.data(0, data)
+}
+
+// Receive writes data from the server to the client
+func (s *TCPStream) Receive(data []byte) {
+	s.data(1, data)
+}
+
+// data writes data in one direction, in segments of at most maxSegment
+func (s *TCPStream) data(dir int, data []byte) {
+	s.w.mu.Lock()
+	defer s.w.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxSegment {
+			n = maxSegment
+		}
+		s.segment(dir, flagPSH|flagACK, data[:n])
+		data = data[n:]
+	}
+}
+
+// Close writes the client closing the connection and the server's answer
+func (s *TCPStream) Close() {
+	s.w.mu.Lock()
+	defer s.w.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.segment(0, flagFIN|flagACK, nil)
+	s.segment(1, flagFIN|flagACK, nil)
+	s.segment(0, flagACK, nil)
+}
+
+// segment writes one segment in direction dir (0 = from the client) and
+// advances its sequence number; the caller must hold w.mu
+func (s *TCPStream) segment(dir int, flags byte, payload []byte) {
+	src, dst := s.client, s.server
+	if dir == 1 {
+		src, dst = dst, src
+	}
+	tcp := make([]byte, 20+len(payload))
+	binary.BigEndian.PutUint16(tcp[0:], src.Port())
+	binary.BigEndian.PutUint16(tcp[2:], dst.Port())
+	binary.BigEndian.PutUint32(tcp[4:], s.seq[dir])
+	if flags&flagACK != 0 {
+		binary.BigEndian.PutUint32(tcp[8:], s.seq[1-dir])
+	}
+	tcp[12] = 5 << 4
+	tcp[13] = flags
+	binary.BigEndian.PutUint16(tcp[14:], 65535)
+	copy(tcp[20:], payload)
+	s.w.writeIP(src.Addr(), dst.Addr(), 6, tcp, 16)
+
+	s.seq[dir] += uint32(len(payload))
+	if flags&(flagSYN|flagFIN) != 0 {
+		s.seq[dir]++
+	}
+}
@@ -0,0 +1,172 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtcp
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// RTCP packet types (RFC 3550)
+const (
+	TypeSenderReport   = 200
+	TypeReceiverReport = 201
+	TypeSDES           = 202
+	TypeBye            = 203
+	TypeRTPFB          = 205 // Transport layer feedback (RFC 4585)
+	TypePSFB           = 206 // Payload-specific feedback (RFC 4585)
+)
+
+// Feedback message types (FMT) used by the client
+const (
+	FormatNACK = 1 // Generic NACK, in an RTPFB packet
+	FormatPLI  = 1 // Picture Loss Indication, in a PSFB packet
+)
+
+// MaxNACKEntries caps the PID/BLP pairs in one NACK packet, 17 sequence
+// numbers each
+const MaxNACKEntries = 64
+
+// ReceptionReport is a single report block of an SR or RR
+type ReceptionReport struct {
+	SSRC           uint32 // Source being reported on
+	FractionLost   uint8  // Fraction lost since the previous report (x/256)
+	CumulativeLost int32  // Total lost, clamped to 24 bits signed
+	HighestSeq     uint32 // Extended highest sequence number received
+	Jitter         uint32 // Interarrival jitter in timestamp units
+	LSR            uint32 // Middle 32 bits of the last SR NTP timestamp
+	DLSR           uint32 // Delay since last SR in 1/65536 seconds
+}
+
+// SenderReport holds the sender info of a received SR
+type SenderReport struct {
+	SSRC        uint32
+	NTPTime     uint64
+	RTPTime     uint32
+	PacketCount uint32
+	OctetCount  uint32
+}
+
+// MiddleNTP returns the middle 32 bits of the NTP timestamp, as echoed in LSR
+func (sr SenderReport) MiddleNTP() uint32 {
+	return uint32(sr.NTPTime >> 16)
+}
+
+// BuildReceiverReport encodes an RR packet from the given report blocks.
+// At most 31 blocks fit in a single packet; extra blocks are dropped.
+func BuildReceiverReport(senderSSRC uint32, reports []ReceptionReport) []byte {
+	if len(reports) > 31 {
+		reports = reports[:31]
+	}
+
+	pkt := make([]byte, 8+24*len(reports))
+	pkt[0] = 0x80 | byte(len(reports)) // V=2, P=0, RC
+	pkt[1] = TypeReceiverReport
+	binary.BigEndian.PutUint16(pkt[2:4], uint16(len(pkt)/4-1))
+	binary.BigEndian.PutUint32(pkt[4:8], senderSSRC)
+
+	for i, r := range reports {
+		b := pkt[8+24*i:]
+		binary.BigEndian.PutUint32(b[0:4], r.SSRC)
+		binary.BigEndian.PutUint32(b[4:8], uint32(r.FractionLost)<<24|uint32(clampLost(r.CumulativeLost))&0xFFFFFF)
+		binary.BigEndian.PutUint32(b[8:12], r.HighestSeq)
+		binary.BigEndian.PutUint32(b[12:16], r.Jitter)
+		binary.BigEndian.PutUint32(b[16:20], r.LSR)
+		binary.BigEndian.PutUint32(b[20:24], r.DLSR)
+	}
+	return pkt
+}
+
+// ParseSenderReports extracts the sender info of every SR in a compound packet
+func ParseSenderReports(data []byte) []SenderReport {
+	var reports []SenderReport
+	for len(data) >= 4 {
+		length := (int(binary.BigEndian.Uint16(data[2:4])) + 1) * 4
+		if data[0]>>6 != 2 || length > len(data) {
+			break
+		}
+		if data[1] == TypeSenderReport && length >= 28 {
+			reports = append(reports, SenderReport{
+				SSRC:        binary.BigEndian.Uint32(data[4:8]),
+				NTPTime:     binary.BigEndian.Uint64(data[8:16]),
+				RTPTime:     binary.BigEndian.Uint32(data[16:20]),
+				PacketCount: binary.BigEndian.Uint32(data[20:24]),
+				OctetCount:  binary.BigEndian.Uint32(data[24:28]),
+			})
+		}
+		data = data[length:]
+	}
+	return reports
+}
+
+// BuildNACK encodes a generic NACK asking mediaSSRC to retransmit the given
+// sequence numbers. Consecutive numbers within 17 of each other share one
+// PID/BLP entry; numbers past MaxNACKEntries entries are dropped.
+func BuildNACK(senderSSRC, mediaSSRC uint32, seqs []uint16) []byte {
+	var fci []uint32
+	for i := 0; i < len(seqs) && len(fci) < MaxNACKEntries; {
+		pid := seqs[i]
+		var blp uint16
+		i++
+		for i < len(seqs) {
+			d := seqs[i] - pid
+			if d == 0 || d > 16 {
+				break
+			}
+			blp |= 1 << (d - 1)
+			i++
+		}
+		fci = append(fci, uint32(pid)<<16|uint32(blp))
+	}
+
+	pkt := feedbackHeader(TypeRTPFB, FormatNACK, senderSSRC, mediaSSRC, 4*len(fci))
+	for i, entry := range fci {
+		binary.BigEndian.PutUint32(pkt[12+4*i:], entry)
+	}
+	return pkt
+}
+
+// BuildPLI encodes a Picture Loss Indication asking mediaSSRC for a keyframe
+func BuildPLI(senderSSRC, mediaSSRC uint32) []byte {
+	return feedbackHeader(TypePSFB, FormatPLI, senderSSRC, mediaSSRC, 0)
+}
+
+// BuildBye encodes a BYE for the given source
+func BuildBye(ssrc uint32) []byte {
+	pkt := make([]byte, 8)
+	pkt[0] = 0x81 // V=2, P=0, SC=1
+	pkt[1] = TypeBye
+	binary.BigEndian.PutUint16(pkt[2:4], 1)
+	binary.BigEndian.PutUint32(pkt[4:8], ssrc)
+	return pkt
+}
+
+// feedbackHeader allocates an RFC 4585 feedback packet with room for
+// fciLen bytes of feedback control information after the common header
+func feedbackHeader(packetType, format byte, senderSSRC, mediaSSRC uint32, fciLen int) []byte {
+	pkt := make([]byte, 12+fciLen)
+	pkt[0] = 0x80 | format
+	pkt[1] = packetType
+	binary.BigEndian.PutUint16(pkt[2:4], uint16(len(pkt)/4-1))
+	binary.BigEndian.PutUint32(pkt[4:8], senderSSRC)
+	binary.BigEndian.PutUint32(pkt[8:12], mediaSSRC)
+	return pkt
+}
+
+// DelaySince converts the time elapsed since an SR arrived to DLSR units
+func DelaySince(received time.Time) uint32 {
+	if received.IsZero() {
+		return 0
+	}
+	return uint32(time.Since(received).Seconds() * 65536)
+}
+
+// clampLost clamps cumulative loss to the 24-bit signed field
+func clampLost(n int32) int32 {
+	if n > 0x7FFFFF {
+		return 0x7FFFFF
+	}
+	if n < -0x800000 {
+		return -0x800000
+	}
+	return n
+}
@@ -0,0 +1,81 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtp
+
+import "time"
+
+// Batch flushing thresholds: a batch is added to the aggregator after this
+// many packets or this long since the previous flush, whichever comes first
+const (
+	BatchPackets  = 64
+	BatchInterval = 100 * time.Millisecond
+)
+
+// Batch accumulates packet, byte and loss counts for one reader and adds
+// them to an Aggregator in bulk. With tens of thousands of readers, a
+// shared atomic per packet (and one more per parent aggregator) is a
+// measurable source of cross-core contention; batching touches them once
+// per flush instead.
+//
+// A Batch is owned by the goroutine reading the stream and is not safe for
+// concurrent use.
+type Batch struct {
+	agg       *Aggregator
+	tee       *Aggregator // Also receives the counts, nil if none
+	packets   uint64
+	bytes     uint64
+	lost      uint64
+	late      uint64 // Packets taken off lost again, see SeqTracker.Push
+	lastFlush time.Time
+}
+
+// NewBatch creates a batch that flushes into agg
+func NewBatch(agg *Aggregator) *Batch {
+	return &Batch{agg: agg, lastFlush: time.Now()}
+}
+
+// Tee makes the batch also flush into agg, which is kept separate from
+// the main aggregator and its parents (e.g. per multicast group)
+func (b *Batch) Tee(agg *Aggregator) {
+	b.tee = agg
+}
+
+// Add counts one packet of n bytes that revealed lost missing packets,
+// flushing if the batch is full or BatchInterval has passed
+func (b *Batch) Add(n int, lost uint64, now time.Time) {
+	b.packets++
+	b.bytes += uint64(n)
+	b.lost += lost
+	if b.packets >= BatchPackets || now.Sub(b.lastFlush) >= BatchInterval {
+		b.flush(now)
+	}
+}
+
+// Late counts a reordered packet that arrived into a gap already counted
+// as lost. Add counts the packet itself.
+func (b *Batch) Late() {
+	b.late++
+}
+
+// Flush adds any pending counts to the aggregator
+func (b *Batch) Flush() {
+	b.flush(time.Now())
+}
+
+// flush adds the pending counts and starts a new batch at now
+func (b *Batch) flush(now time.Time) {
+	if b.packets > 0 || b.lost > 0 {
+		b.agg.AddCounts(b.packets, b.bytes, b.lost)
+		if b.tee != nil {
+			b.tee.AddCounts(b.packets, b.bytes, b.lost)
+		}
+		b.packets, b.bytes, b.lost = 0, 0, 0
+	}
+	if b.late > 0 {
+		b.agg.AddLate(b.late)
+		if b.tee != nil {
+			b.tee.AddLate(b.late)
+		}
+		b.late = 0
+	}
+	b.lastFlush = now
+}
@@ -0,0 +1,183 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtp
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RTP timestamp analysis thresholds
+const (
+	// DiscontinuityThreshold is how far the timestamp may jump ahead of the
+	// arrival time, or back, before it counts as a discontinuity. Timestamps
+	// falling behind arrival time are network delay, not a discontinuity.
+	DiscontinuityThreshold = time.Second
+
+	// StallThreshold is how long packets may keep arriving with the same
+	// timestamp before the clock counts as stalled
+	StallThreshold = time.Second
+
+	// DriftWindow is the minimum span of arrivals needed to estimate drift.
+	// The minimum transit time over its first half is compared with that of
+	// each later half-window, so delayed packets do not look like drift.
+	DriftWindow = 10 * time.Second
+
+	// DriftThreshold is the relative rate difference between the timestamp
+	// clock and arrival time that marks a session as drifting
+	DriftThreshold = 0.02
+)
+
+// ClockStats summarizes timestamp continuity for one source or session
+type ClockStats struct {
+	Discontinuities uint64  // Jumps inconsistent with arrival time
+	Stalls          uint64  // Periods where the timestamp stopped advancing
+	Drift           float64 // Timestamp rate relative to arrival rate, minus 1
+	DriftValid      bool    // At least DriftWindow of arrivals observed
+}
+
+// Drifting reports whether the drift estimate exceeds DriftThreshold
+func (s ClockStats) Drifting() bool {
+	return s.DriftValid && math.Abs(s.Drift) > DriftThreshold
+}
+
+// Merge combines the stats of another source, keeping the larger drift
+func (s *ClockStats) Merge(o ClockStats) {
+	s.Discontinuities += o.Discontinuities
+	s.Stalls += o.Stalls
+	if o.DriftValid && (!s.DriftValid || math.Abs(o.Drift) > math.Abs(s.Drift)) {
+		s.Drift = o.Drift
+		s.DriftValid = true
+	}
+}
+
+// ClockTracker checks RTP timestamp increments against the declared clock
+// rate and packet arrival times
+type ClockTracker struct {
+	mu          sync.Mutex
+	clockRate   float64
+	initialized bool
+
+	// Drift baseline, rebased after every discontinuity. Transit is the
+	// arrival time minus the media time since the baseline, in seconds.
+	baseArrival time.Time
+	elapsed     int64   // Timestamp units since the baseline
+	baseMin     float64 // Minimum transit over the first half-window
+	bucketStart time.Time
+	bucketMin   float64 // Minimum transit in the current half-window
+
+	lastTS      uint32
+	lastArrival time.Time
+	lastChange  time.Time // Arrival of the last timestamp change
+	stalled     bool      // Current stall already counted
+
+	stats ClockStats
+}
+
+// NewClockTracker creates a clock tracker for the given RTP clock rate
+func NewClockTracker(clockRate int) *ClockTracker {
+	if clockRate <= 0 {
+		clockRate = DefaultClockRate
+	}
+	return &ClockTracker{clockRate: float64(clockRate)}
+}
+
+// Push checks a packet's RTP timestamp against its arrival time
+func (c *ClockTracker) Push(timestamp uint32, arrival time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.initialized {
+		c.initialized = true
+		c.rebase(timestamp, arrival)
+		return
+	}
+
+	delta := int32(timestamp - c.lastTS)
+	if delta == 0 {
+		// Packets of one frame share a timestamp; only a long run is a stall
+		if !c.stalled && arrival.Sub(c.lastChange) > StallThreshold {
+			c.stats.Stalls++
+			c.stalled = true
+		}
+		c.lastArrival = arrival
+		return
+	}
+	c.stalled = false
+
+	media := time.Duration(float64(delta) / c.clockRate * float64(time.Second))
+	if media > arrival.Sub(c.lastArrival)+DiscontinuityThreshold || media < -DiscontinuityThreshold {
+		c.stats.Discontinuities++
+		c.rebase(timestamp, arrival)
+		return
+	}
+
+	// Packets resuming after an arrival gap may start a catch-up burst, so
+	// the drift baseline starts over rather than comparing across the gap
+	if arrival.Sub(c.lastArrival) > DiscontinuityThreshold {
+		c.rebase(timestamp, arrival)
+		return
+	}
+
+	c.elapsed += int64(delta)
+	c.lastTS = timestamp
+	c.lastArrival = arrival
+	c.lastChange = arrival
+	c.trackDrift(arrival)
+}
+
+// trackDrift updates the minimum transit windows and, once a later
+// half-window completes, the drift estimate
+func (c *ClockTracker) trackDrift(arrival time.Time) {
+	half := DriftWindow / 2
+	since := arrival.Sub(c.baseArrival)
+	transit := since.Seconds() - float64(c.elapsed)/c.clockRate
+
+	if since < half {
+		c.baseMin = math.Min(c.baseMin, transit)
+		return
+	}
+	if c.bucketStart.IsZero() {
+		c.bucketStart = arrival
+		c.bucketMin = transit
+		return
+	}
+	if arrival.Sub(c.bucketStart) < half {
+		c.bucketMin = math.Min(c.bucketMin, transit)
+		return
+	}
+
+	// A fast timestamp clock makes transit shrink over time
+	span := c.bucketStart.Sub(c.baseArrival).Seconds()
+	c.stats.Drift = (c.baseMin - c.bucketMin) / span
+	c.stats.DriftValid = true
+	c.bucketStart = arrival
+	c.bucketMin = transit
+}
+
+// rebase starts a new drift baseline at the given packet
+func (c *ClockTracker) rebase(timestamp uint32, arrival time.Time) {
+	c.baseArrival = arrival
+	c.elapsed = 0
+	c.baseMin = 0
+	c.bucketStart = time.Time{}
+	c.lastTS = timestamp
+	c.lastArrival = arrival
+	c.lastChange = arrival
+	c.stalled = false
+}
+
+// Restart drops the baseline after an intended discontinuity such as a
+// pause or seek, keeping the counts and drift estimate
+func (c *ClockTracker) Restart() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.initialized = false
+}
+
+// GetStats returns the continuity statistics so far
+func (c *ClockTracker) GetStats() ClockStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
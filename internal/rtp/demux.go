@@ -0,0 +1,96 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtp
+
+import (
+	"sync"
+)
+
+// MaxSources bounds the number of SSRCs tracked per stream so that garbage
+// or spoofed packets cannot grow state without limit
+const MaxSources = 16
+
+// Source holds reception state for a single synchronization source
+type Source struct {
+	SSRC   uint32
+	Seq    *SeqTracker
+	Jitter *JitterTracker
+	Clock  *ClockTracker
+}
+
+// Restart marks a discontinuity in the source's sequence and timestamps
+func (s *Source) Restart() {
+	s.Seq.Restart()
+	s.Jitter.Restart()
+	s.Clock.Restart()
+}
+
+// SourceDemux routes packets to per-SSRC sequence and jitter trackers so
+// that interleaved sequence spaces don't show up as phantom loss
+type SourceDemux struct {
+	mu        sync.Mutex
+	clockRate int
+	sources   map[uint32]*Source
+	order     []*Source // In order of first appearance
+}
+
+// NewSourceDemux creates a demultiplexer for a stream with the given clock rate
+func NewSourceDemux(clockRate int) *SourceDemux {
+	return &SourceDemux{
+		clockRate: clockRate,
+		sources:   make(map[uint32]*Source),
+	}
+}
+
+// Source returns the state for ssrc, creating it on first use. Returns nil
+// once MaxSources distinct SSRCs have been seen.
+func (d *SourceDemux) Source(ssrc uint32) *Source {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if src, ok := d.sources[ssrc]; ok {
+		return src
+	}
+	if len(d.order) >= MaxSources {
+		return nil
+	}
+
+	src := &Source{
+		SSRC:   ssrc,
+		Seq:    NewSeqTracker(),
+		Jitter: NewJitterTracker(d.clockRate),
+		Clock:  NewClockTracker(d.clockRate),
+	}
+	d.sources[ssrc] = src
+	d.order = append(d.order, src)
+	return src
+}
+
+// Lookup returns the state for ssrc without creating it
+func (d *SourceDemux) Lookup(ssrc uint32) *Source {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.sources[ssrc]
+}
+
+// Sources returns all known sources in order of first appearance
+func (d *SourceDemux) Sources() []*Source {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sources := make([]*Source, len(d.order))
+	copy(sources, d.order)
+	return sources
+}
+
+// GetStats returns statistics summed across all sources
+func (d *SourceDemux) GetStats() Stats {
+	var total Stats
+	for _, src := range d.Sources() {
+		stats := src.Seq.GetStats()
+		total.Packets += stats.Packets
+		total.Lost += stats.Lost
+		total.LastSeq = stats.LastSeq
+		total.Cycles += stats.Cycles
+	}
+	return total
+}
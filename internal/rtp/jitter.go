@@ -0,0 +1,78 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtp
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultClockRate is used when the SDP does not specify one (video)
+const DefaultClockRate = 90000
+
+// JitterTracker computes RFC 3550 interarrival jitter from RTP timestamps
+type JitterTracker struct {
+	mu          sync.Mutex
+	clockRate   float64
+	start       time.Time
+	initialized bool
+	lastTransit uint32
+	jitter      float64 // In timestamp units
+}
+
+// NewJitterTracker creates a jitter tracker for the given RTP clock rate
+func NewJitterTracker(clockRate int) *JitterTracker {
+	if clockRate <= 0 {
+		clockRate = DefaultClockRate
+	}
+	return &JitterTracker{
+		clockRate: float64(clockRate),
+		start:     time.Now(),
+	}
+}
+
+// Push updates the jitter estimate with a packet's RTP timestamp and arrival time
+func (j *JitterTracker) Push(timestamp uint32, arrival time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// Arrival time in RTP timestamp units; wraps the same way as timestamps
+	arrivalTS := uint32(int64(arrival.Sub(j.start).Seconds() * j.clockRate))
+	transit := arrivalTS - timestamp
+
+	if !j.initialized {
+		j.lastTransit = transit
+		j.initialized = true
+		return
+	}
+
+	d := int32(transit - j.lastTransit)
+	if d < 0 {
+		d = -d
+	}
+	j.lastTransit = transit
+
+	// J(i) = J(i-1) + (|D(i-1,i)| - J(i-1))/16
+	j.jitter += (float64(d) - j.jitter) / 16
+}
+
+// Restart drops the transit baseline after a discontinuity such as a seek,
+// keeping the current estimate
+func (j *JitterTracker) Restart() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.initialized = false
+}
+
+// Jitter returns the current estimate in timestamp units, as used in RTCP RRs
+func (j *JitterTracker) Jitter() uint32 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return uint32(j.jitter)
+}
+
+// JitterMs returns the current estimate in milliseconds
+func (j *JitterTracker) JitterMs() float64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jitter * 1000 / j.clockRate
+}
@@ -0,0 +1,30 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtp
+
+import "encoding/binary"
+
+// Payload returns the payload of an RTP packet, skipping CSRCs, the header
+// extension and padding. It returns nil if the packet is malformed.
+func Payload(data []byte) []byte {
+	if len(data) < 12 || data[0]>>6 != 2 {
+		return nil
+	}
+
+	offset := 12 + 4*int(data[0]&0x0F)
+	if data[0]&0x10 != 0 {
+		// Header extension: 16-bit profile, 16-bit length in words
+		if len(data) < offset+4 {
+			return nil
+		}
+		offset += 4 + 4*int(binary.BigEndian.Uint16(data[offset+2:offset+4]))
+	}
+
+	end := len(data)
+	if data[0]&0x20 != 0 && end > offset {
+		end -= int(data[end-1])
+	}
+	if offset > end {
+		return nil
+	}
+	return data[offset:end]
+}
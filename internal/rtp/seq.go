@@ -2,8 +2,12 @@
 package rtp
 
 import (
+	"math"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/histogram"
 )
 
 // SeqTracker tracks RTP sequence numbers and detects packet loss
@@ -20,85 +24,233 @@ type SeqTracker struct {
 	baseSeq     uint32  // First sequence number
 	badSeq      uint32  // Last 'bad' sequence number + 1
 	probation   int     // Packets left in probation
+	resets      uint64  // Stream restarts with a new sequence base
+	
+	// RTCP reception report interval state
+	expectedPrior uint64
+	receivedPrior uint64
+	
+	// Set by Restart: the next packet continues the count without a gap
+	restart bool
+	
+	// Runs of consecutive lost packets
+	bursts LossBursts
+	
+	// Packets received again or late, told apart by the sequence numbers
+	// seen within reorderWindow of the highest
+	seen       [reorderWindow / 64]uint64
+	duplicates uint64
+	reordered  uint64
+	maxReorder uint64 // Largest distance behind the highest sequence number
 }
 
+// Sequence validation limits (RFC 3550 A.1)
+const (
+	minSequential = 2       // Packets in sequence before a new source is counted
+	maxDropout    = 3000    // Largest forward jump taken as loss
+	maxMisorder   = 100     // Largest backward jump taken as reordering
+	seqMod        = 1 << 16
+	noBadSeq      = seqMod + 1 // badSeq when no restart is pending; matches no sequence number
+)
+
+// reorderWindow is how many sequence numbers behind the highest are
+// remembered to tell duplicates from reordered packets. It covers
+// maxMisorder; packets further behind are not taken as late.
+const reorderWindow = 128
+
 // NewSeqTracker creates a new sequence tracker
 func NewSeqTracker() *SeqTracker {
-	return &SeqTracker{
-		probation: 0, // Start with no probation
-	}
+	return &SeqTracker{}
 }
 
-// Push processes a new RTP sequence number and returns packets lost
-func (s *SeqTracker) Push(seq uint16) uint64 {
+// Push processes a new RTP sequence number and returns packets lost, and
+// whether seq arrived late into a gap already counted as lost. Such a
+// packet is taken off the loss count again.
+func (s *SeqTracker) Push(seq uint16) (lost uint64, late bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if !s.initialized {
-		s.initSequence(seq)
-		return 0
+		s.initialized = true
+		s.probation = minSequential - 1
+		s.lastSeq = seq
+		return 0, false
+	}
+	if s.probation > 0 {
+		s.probe(seq)
+		return 0, false
+	}
+	if s.restart {
+		s.restartSequence(seq)
+		return 0, false
 	}
 
 	return s.updateSequence(seq)
 }
 
+// Recover counts a missing packet that arrived as a retransmission, such
+// as RTX, as received. It is neither reordered nor a sequence jump.
+func (s *SeqTracker) Recover(seq uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.initialized || s.probation > 0 {
+		return
+	}
+	s.totalPkts++
+	if uint16(s.lastSeq-seq) < reorderWindow {
+		s.mark(seq)
+	}
+}
+
+// Restart marks a discontinuity such as a seek: the next packet is taken
+// as following the last one, whatever its sequence number
+func (s *SeqTracker) Restart() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.restart = s.initialized
+}
+
+// restartSequence continues tracking from seq, moving the base so that the
+// expected count grows by exactly one
+func (s *SeqTracker) restartSequence(seq uint16) {
+	expected := s.maxSeq - s.baseSeq + 1
+	s.maxSeq = s.cycles<<16 | uint32(seq)
+	s.baseSeq = s.maxSeq - expected
+	s.lastSeq = seq
+	s.totalPkts++
+	s.restart = false
+	s.badSeq = noBadSeq
+	s.seen = [reorderWindow / 64]uint64{}
+	s.mark(seq)
+}
+
+// probe advances the probation of a new source: packets are counted from
+// the one completing minSequential in sequence, so a few stray packets do
+// not start tracking from a bogus base
+func (s *SeqTracker) probe(seq uint16) {
+	if seq != s.lastSeq+1 {
+		s.probation = minSequential - 1
+		s.lastSeq = seq
+		return
+	}
+	s.probation--
+	if s.probation > 0 {
+		s.lastSeq = seq
+		return
+	}
+	s.initSequence(seq)
+}
+
 // initSequence initializes tracking with the first sequence number
 func (s *SeqTracker) initSequence(seq uint16) {
 	s.baseSeq = uint32(seq)
 	s.maxSeq = uint32(seq)
 	s.lastSeq = seq
-	s.badSeq = uint32(seq) + 1
+	s.badSeq = noBadSeq
 	s.cycles = 0
-	s.initialized = true
 	s.totalPkts = 1
+	s.restart = false
+	s.mark(seq)
+}
+
+// mark records seq as received
+func (s *SeqTracker) mark(seq uint16) {
+	i := seq % reorderWindow
+	s.seen[i/64] |= 1 << (i % 64)
+}
+
+// marked reports whether seq was received, for seq within reorderWindow
+// of the highest
+func (s *SeqTracker) marked(seq uint16) bool {
+	i := seq % reorderWindow
+	return s.seen[i/64]&(1<<(i%64)) != 0
+}
+
+// advance clears the window slots the sequence numbers after lastSeq up to
+// seq take over
+func (s *SeqTracker) advance(seq uint16) {
+	n := uint16(seq - s.lastSeq)
+	if n >= reorderWindow {
+		s.seen = [reorderWindow / 64]uint64{}
+		return
+	}
+	for i := uint16(1); i <= n; i++ {
+		j := (s.lastSeq + i) % reorderWindow
+		s.seen[j/64] &^= 1 << (j % 64)
+	}
+}
+
+// late records a packet arriving dist behind the highest sequence number,
+// a duplicate if it was already received. A reordered packet was counted
+// as lost when the packets after it arrived; it is taken off totalLost and
+// true is returned. Its loss burst stays recorded.
+func (s *SeqTracker) late(seq uint16, dist uint64) bool {
+	if s.marked(seq) {
+		s.duplicates++
+		return false
+	}
+	s.reordered++
+	s.maxReorder = max(s.maxReorder, dist)
+	s.mark(seq)
+	if s.totalLost == 0 {
+		return false // From before the first packet counted
+	}
+	s.totalLost--
+	return true
 }
 
 // updateSequence updates tracking with a new sequence number
-func (s *SeqTracker) updateSequence(seq uint16) uint64 {
+func (s *SeqTracker) updateSequence(seq uint16) (uint64, bool) {
 	udelta := uint16(seq - s.lastSeq)
 	var lost uint64
 
-	// Handle sequence number wraparound
-	if udelta < 0x8000 {
-		// Forward jump
-		if udelta > 0 {
-			// We may have lost packets
-			if udelta > 1 {
-				lost = uint64(udelta - 1)
-				s.totalLost += lost
-			}
-			
-			// Update max sequence with cycle tracking
-			if seq < s.lastSeq {
-				// Wrapped around
-				s.cycles++
-			}
-			s.maxSeq = s.cycles<<16 | uint32(seq)
+	switch {
+	case udelta == 0:
+		// Duplicate of the last packet
+		s.duplicates++
+		s.totalPkts++
+		return 0, false
+		
+	case udelta < maxDropout:
+		// In order, with a permissible gap
+		s.advance(seq)
+		if udelta > 1 {
+			lost = uint64(udelta - 1)
+			s.totalLost += lost
+			s.bursts.Add(lost)
 		}
-		// else: duplicate packet (udelta == 0), ignore
-	} else {
-		// Large jump backwards or forwards
-		if uint16(s.lastSeq-seq) < 0x8000 {
-			// Actually a jump backwards - could be reordering
-			// For now, treat as out of order and don't count as loss
-		} else {
-			// Very large forward jump (wrapped around)
+		if seq < s.lastSeq {
+			// Wrapped around
 			s.cycles++
-			s.maxSeq = s.cycles<<16 | uint32(seq)
-			
-			// Calculate actual distance including wrap
-			actualDelta := (0x10000 - uint32(s.lastSeq)) + uint32(seq)
-			if actualDelta > 1 {
-				lost = uint64(actualDelta - 1)
-				s.totalLost += lost
-			}
 		}
+		s.maxSeq = s.cycles<<16 | uint32(seq)
+		
+	case int(udelta) <= seqMod-maxMisorder:
+		// A jump too large for loss or reordering. If the next packet
+		// follows this one, the server restarted the stream with a new
+		// sequence base; otherwise the packet is a stray and is dropped.
+		if uint32(seq) == s.badSeq {
+			s.restartSequence(seq)
+			s.resets++
+			return 0, false
+		}
+		s.badSeq = uint32(seq + 1)
+		return 0, false
+		
+	default:
+		// Behind the highest - could be reordering or a retransmission.
+		// Don't count as loss, and keep lastSeq so the next in-order
+		// packet is not seen as a second gap.
+		late := s.late(seq, uint64(s.lastSeq-seq))
+		s.totalPkts++
+		return 0, late
 	}
 
+	s.mark(seq)
 	s.lastSeq = seq
 	s.totalPkts++
 	
-	return lost
+	return lost, false
 }
 
 // GetStats returns current statistics
@@ -111,33 +263,283 @@ func (s *SeqTracker) GetStats() Stats {
 		Lost:     s.totalLost,
 		LastSeq:  s.lastSeq,
 		Cycles:   s.cycles,
+		Bursts:   s.bursts,
+		Duplicates: s.duplicates,
+		Reordered:  s.reordered,
+		MaxReorder: s.maxReorder,
+		Resets:     s.resets,
 	}
 }
 
+// Reception returns the counters for an RTCP reception report and starts
+// a new reporting interval for the fraction-lost calculation
+func (s *SeqTracker) Reception() Reception {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if !s.initialized || s.probation > 0 {
+		return Reception{}
+	}
+	
+	expected := uint64(s.maxSeq-s.baseSeq) + 1
+	received := s.totalPkts
+	
+	// Fraction lost over the interval since the previous report (RFC 3550 A.3)
+	expectedInterval := expected - s.expectedPrior
+	receivedInterval := received - s.receivedPrior
+	s.expectedPrior = expected
+	s.receivedPrior = received
+	
+	var fraction uint8
+	if expectedInterval > 0 && expectedInterval > receivedInterval {
+		fraction = uint8(((expectedInterval - receivedInterval) << 8) / expectedInterval)
+	}
+	
+	return Reception{
+		ExtendedMax:  s.maxSeq,
+		Expected:     expected,
+		Lost:         int64(expected) - int64(received),
+		FractionLost: fraction,
+	}
+}
+
+// Reception holds the counters needed for an RTCP reception report
+type Reception struct {
+	ExtendedMax  uint32 // Highest sequence number with cycle count
+	Expected     uint64 // Packets expected since the first one
+	Lost         int64  // Cumulative lost (negative with duplicates)
+	FractionLost uint8  // Interval loss fraction in 1/256 units
+}
+
 // Stats holds RTP statistics
 type Stats struct {
 	Packets  uint64
 	Lost     uint64
 	LastSeq  uint16
 	Cycles   uint32
+	Bursts   LossBursts
+	Duplicates uint64 // Packets received again
+	Reordered  uint64 // Packets that arrived after a later one
+	MaxReorder uint64 // Largest distance, in sequence numbers, a packet arrived behind the highest
+	Resets     uint64 // Stream restarts with a new sequence base, not counted as loss
+}
+
+// Loss burst length classes: runs of 1, 2-5, 6-20 and more than 20
+// consecutive lost packets
+const (
+	Burst1 = iota
+	Burst2to5
+	Burst6to20
+	BurstOver20
+	BurstClasses
+)
+
+// BurstClassNames names the burst length classes in reports
+var BurstClassNames = [BurstClasses]string{"1", "2-5", "6-20", ">20"}
+
+// BurstClass returns the length class of a run of n lost packets
+func BurstClass(n uint64) int {
+	switch {
+	case n <= 1:
+		return Burst1
+	case n <= 5:
+		return Burst2to5
+	case n <= 20:
+		return Burst6to20
+	default:
+		return BurstOver20
+	}
+}
+
+// LossBursts tells random single losses from multi-packet bursts: the
+// runs of consecutive lost packets by length class, and the longest run.
+// Runs are recorded when the gap is seen; packets of it arriving late
+// later do not shorten them.
+type LossBursts struct {
+	Counts  [BurstClasses]uint64
+	Longest uint64
+}
+
+// Add records a run of n lost packets
+func (b *LossBursts) Add(n uint64) {
+	if n == 0 {
+		return
+	}
+	b.Counts[BurstClass(n)]++
+	b.Longest = max(b.Longest, n)
+}
+
+// Merge adds the bursts of another source, e.g. across a session's tracks
+func (b *LossBursts) Merge(o LossBursts) {
+	for i := range b.Counts {
+		b.Counts[i] += o.Counts[i]
+	}
+	b.Longest = max(b.Longest, o.Longest)
 }
 
 // Aggregator collects statistics from multiple trackers
 type Aggregator struct {
-	packets atomic.Uint64
-	lost    atomic.Uint64
-	bytes   atomic.Uint64
+	packets  atomic.Uint64
+	lost     atomic.Uint64
+	bytes    atomic.Uint64
+	rejected atomic.Uint64 // UDP packets from unexpected sources
+	fallbacks atomic.Uint64 // Sessions that fell back from UDP to TCP
+	keepAliveRetries   atomic.Uint64 // Keepalives sent again after a failure
+	keepAliveRecovered atomic.Uint64 // Keepalives that succeeded on a retry
+	
+	// Jitter samples in microseconds
+	jitterSum   atomic.Uint64
+	jitterCount atomic.Uint64
+	jitterMin   atomic.Uint64
+	jitterMax   atomic.Uint64
+	
+	// Time from PLAY response to first RTP packet
+	ttfp *histogram.Histogram
+	
+	// Media stalls: gaps without RTP on a playing session, and the sessions
+	// checked for them
+	stallDuration   *histogram.Histogram
+	stallSessions   atomic.Uint64
+	stalledSessions atomic.Uint64
+	
+	// Sessions resumed over a new connection after losing theirs, how long
+	// each was interrupted, and losses that could not be resumed
+	interruption   *histogram.Histogram
+	resumeFailures atomic.Uint64
+	
+	// Loss bursts by length class, reported per session, and each
+	// session's longest burst. Burst lengths are recorded in the histogram
+	// as microseconds, which it keeps exactly up to 128.
+	bursts       [BurstClasses]atomic.Uint64
+	sessionBurst *histogram.Histogram
+	
+	// Duplicated and reordered packets, and stream restarts with a new
+	// sequence base, reported per session
+	duplicates        atomic.Uint64
+	reordered         atomic.Uint64
+	reorderMax        atomic.Uint64 // Largest reorder distance, sequence numbers
+	sessionsDuplicate atomic.Uint64
+	sessionsReordered atomic.Uint64
+	streamResets      atomic.Uint64
+	sessionsReset     atomic.Uint64
+	
+	// Video payload analysis, reported per session
+	keyframes      atomic.Uint64
+	fragmentErrors atomic.Uint64
+	gopSum         atomic.Uint64 // microseconds
+	gopCount       atomic.Uint64
+	
+	// RTP timestamp continuity, reported per session
+	clockDiscontinuities  atomic.Uint64
+	clockStalls           atomic.Uint64
+	sessionsDiscontinuous atomic.Uint64
+	sessionsStalled       atomic.Uint64
+	sessionsDrifting      atomic.Uint64
+	sessionsClockIssues   atomic.Uint64
+	driftMax              atomic.Uint64 // Largest |drift| in parts per million
+	
+	// Frame verification against a reference reader
+	framesVerified atomic.Uint64
+	framesCorrupt  atomic.Uint64
+	
+	// Mid-session PAUSE/PLAY round trips (viewer behavior)
+	pauseLatency  *histogram.Histogram
+	resumeLatency *histogram.Histogram
+	pauseFailures atomic.Uint64
+	
+	// Seeks on recorded media: PLAY round trip, time until media from the
+	// new position arrives, and whether it matched the RTP-Info header
+	seekLatency   *histogram.Histogram
+	seekMedia     *histogram.Histogram
+	seekFailures  atomic.Uint64
+	seeksChecked  atomic.Uint64
+	seekMismatch  atomic.Uint64
+	
+	// Publish-to-receive latency from publisher timestamp SEI messages
+	e2eLatency *histogram.Histogram
+	
+	// RTCP feedback: NACKed packets and their retransmission, PLIs and the
+	// keyframes answering them, BYEs
+	nacksSent     atomic.Uint64
+	nackRequested atomic.Uint64
+	nackRepair    *histogram.Histogram
+	plisSent      atomic.Uint64
+	pliKeyframe   *histogram.Histogram
+	byesSent      atomic.Uint64
+	
+	// RTX (RFC 4588) retransmissions, and those that filled a gap. Recovered
+	// packets are taken off the loss count in Snapshot.
+	rtxPackets   atomic.Uint64
+	rtxRecovered atomic.Uint64
+	
+	// Reordered packets that arrived into a gap counted as loss, taken off
+	// the loss count in Snapshot
+	lateArrivals atomic.Uint64
+	
+	// ONVIF: metadata received, backchannel audio sent
+	metadataPackets    atomic.Uint64
+	metadataBytes      atomic.Uint64
+	backchannelPackets atomic.Uint64
+	backchannelBytes   atomic.Uint64
+	
+	// AAC and Opus payload validation, reported per session
+	audioFrames       atomic.Uint64
+	audioMalformed    atomic.Uint64
+	sessionsMalformed atomic.Uint64
+	
+	// MPEG-TS over RTP, reported per session
+	tsPackets        atomic.Uint64
+	tsSyncErrors     atomic.Uint64
+	tsCCErrors       atomic.Uint64
+	sessionsCCErrors atomic.Uint64
+	pcrJitterSum     atomic.Uint64 // microseconds
+	pcrJitterCount   atomic.Uint64
+	pcrJitterMax     atomic.Uint64 // microseconds, largest single deviation
+	
+	// Emulated network impairment applied by impaired sessions
+	impairedSessions atomic.Uint64
+	impairDropped    atomic.Uint64
+	impairDuplicated atomic.Uint64
+	impairReordered  atomic.Uint64
+	
+	// Optional parent that receives a copy of every update
+	parent *Aggregator
 }
 
 // NewAggregator creates a new statistics aggregator
 func NewAggregator() *Aggregator {
-	return &Aggregator{}
+	a := &Aggregator{
+		ttfp:          histogram.New(),
+		stallDuration: histogram.New(),
+		interruption:  histogram.New(),
+		sessionBurst:  histogram.New(),
+		pauseLatency:  histogram.New(),
+		resumeLatency: histogram.New(),
+		seekLatency:   histogram.New(),
+		seekMedia:     histogram.New(),
+		e2eLatency:    histogram.New(),
+		nackRepair:    histogram.New(),
+		pliKeyframe:   histogram.New(),
+	}
+	a.jitterMin.Store(math.MaxUint64)
+	return a
+}
+
+// Child creates an aggregator whose updates are also applied to a,
+// for tracking a subset (e.g. one target URL) alongside the total
+func (a *Aggregator) Child() *Aggregator {
+	child := NewAggregator()
+	child.parent = a
+	return child
 }
 
 // AddPackets adds to packet count
 func (a *Aggregator) AddPackets(n uint64) {
 	if n > 0 {
 		a.packets.Add(n)
+		if a.parent != nil {
+			a.parent.AddPackets(n)
+		}
 	}
 }
 
@@ -145,6 +547,9 @@ func (a *Aggregator) AddPackets(n uint64) {
 func (a *Aggregator) AddLoss(n uint64) {
 	if n > 0 {
 		a.lost.Add(n)
+		if a.parent != nil {
+			a.parent.AddLoss(n)
+		}
 	}
 }
 
@@ -152,23 +557,698 @@ func (a *Aggregator) AddLoss(n uint64) {
 func (a *Aggregator) AddBytes(n uint64) {
 	if n > 0 {
 		a.bytes.Add(n)
+		if a.parent != nil {
+			a.parent.AddBytes(n)
+		}
+	}
+}
+
+// AddCounts adds a batch of packets, bytes and losses in one pass over the
+// aggregator and its parents (see Batch)
+func (a *Aggregator) AddCounts(packets, bytes, lost uint64) {
+	for agg := a; agg != nil; agg = agg.parent {
+		if packets > 0 {
+			agg.packets.Add(packets)
+		}
+		if bytes > 0 {
+			agg.bytes.Add(bytes)
+		}
+		if lost > 0 {
+			agg.lost.Add(lost)
+		}
+	}
+}
+
+// AddRejected adds to the count of packets dropped for an unexpected source
+func (a *Aggregator) AddRejected(n uint64) {
+	if n > 0 {
+		a.rejected.Add(n)
+		if a.parent != nil {
+			a.parent.AddRejected(n)
+		}
+	}
+}
+
+// AddFallback counts a session that got no RTP over UDP and retried over
+// TCP interleaved
+func (a *Aggregator) AddFallback() {
+	a.fallbacks.Add(1)
+	if a.parent != nil {
+		a.parent.AddFallback()
+	}
+}
+
+// AddKeepAliveRetry counts a keepalive sent again after it failed
+func (a *Aggregator) AddKeepAliveRetry() {
+	a.keepAliveRetries.Add(1)
+	if a.parent != nil {
+		a.parent.AddKeepAliveRetry()
+	}
+}
+
+// AddKeepAliveRecovered counts a keepalive that succeeded on a retry, a
+// session that would otherwise have been lost
+func (a *Aggregator) AddKeepAliveRecovered() {
+	a.keepAliveRecovered.Add(1)
+	if a.parent != nil {
+		a.parent.AddKeepAliveRecovered()
+	}
+}
+
+// AddJitter records a per-connection jitter sample in milliseconds
+func (a *Aggregator) AddJitter(ms float64) {
+	us := uint64(ms * 1000)
+	a.jitterSum.Add(us)
+	a.jitterCount.Add(1)
+	
+	for {
+		old := a.jitterMin.Load()
+		if us >= old || a.jitterMin.CompareAndSwap(old, us) {
+			break
+		}
+	}
+	for {
+		old := a.jitterMax.Load()
+		if us <= old || a.jitterMax.CompareAndSwap(old, us) {
+			break
+		}
+	}
+	
+	if a.parent != nil {
+		a.parent.AddJitter(ms)
+	}
+}
+
+// AddFirstPacket records a connection's time to first packet
+func (a *Aggregator) AddFirstPacket(d time.Duration) {
+	a.ttfp.Record(d)
+	if a.parent != nil {
+		a.parent.AddFirstPacket(d)
+	}
+}
+
+// AddStall records a gap without media on a playing session
+func (a *Aggregator) AddStall(d time.Duration) {
+	a.stallDuration.Record(d)
+	if a.parent != nil {
+		a.parent.AddStall(d)
+	}
+}
+
+// AddSessionResume records a session resumed over a new connection d
+// after losing its own
+func (a *Aggregator) AddSessionResume(d time.Duration) {
+	a.interruption.Record(d)
+	if a.parent != nil {
+		a.parent.AddSessionResume(d)
+	}
+}
+
+// AddSessionResumeFailure records a lost connection whose session could
+// not be resumed
+func (a *Aggregator) AddSessionResumeFailure() {
+	a.resumeFailures.Add(1)
+	if a.parent != nil {
+		a.parent.AddSessionResumeFailure()
+	}
+}
+
+// AddLossBursts records a session's loss bursts, merged across its sources
+func (a *Aggregator) AddLossBursts(b LossBursts) {
+	for i, n := range b.Counts {
+		if n > 0 {
+			a.bursts[i].Add(n)
+		}
+	}
+	a.sessionBurst.Record(time.Duration(b.Longest) * time.Microsecond)
+	if a.parent != nil {
+		a.parent.AddLossBursts(b)
+	}
+}
+
+// AddReordering records a session's duplicated and reordered packets and
+// its largest reorder distance, summed across its sources
+func (a *Aggregator) AddReordering(duplicates, reordered, maxDistance uint64) {
+	a.duplicates.Add(duplicates)
+	a.reordered.Add(reordered)
+	if duplicates > 0 {
+		a.sessionsDuplicate.Add(1)
+	}
+	if reordered > 0 {
+		a.sessionsReordered.Add(1)
+	}
+	for {
+		current := a.reorderMax.Load()
+		if maxDistance <= current || a.reorderMax.CompareAndSwap(current, maxDistance) {
+			break
+		}
+	}
+	
+	if a.parent != nil {
+		a.parent.AddReordering(duplicates, reordered, maxDistance)
+	}
+}
+
+// AddStreamResets records a session whose server restarted the stream n
+// times with a new sequence base
+func (a *Aggregator) AddStreamResets(n uint64) {
+	a.streamResets.Add(n)
+	a.sessionsReset.Add(1)
+	if a.parent != nil {
+		a.parent.AddStreamResets(n)
+	}
+}
+
+// AddStallSession records a session that received media, and whether it
+// stalled at least once
+func (a *Aggregator) AddStallSession(stalled bool) {
+	a.stallSessions.Add(1)
+	if stalled {
+		a.stalledSessions.Add(1)
+	}
+	if a.parent != nil {
+		a.parent.AddStallSession(stalled)
+	}
+}
+
+// AddVideo records a session's keyframe count, broken fragment count and
+// average GOP interval in milliseconds (0 if unknown)
+func (a *Aggregator) AddVideo(keyframes, fragmentErrors uint64, gopMs float64) {
+	a.keyframes.Add(keyframes)
+	a.fragmentErrors.Add(fragmentErrors)
+	if gopMs > 0 {
+		a.gopSum.Add(uint64(gopMs * 1000))
+		a.gopCount.Add(1)
+	}
+	
+	if a.parent != nil {
+		a.parent.AddVideo(keyframes, fragmentErrors, gopMs)
+	}
+}
+
+// AddAudio records a session's validated audio frame count and its
+// malformed AAC or Opus payloads
+func (a *Aggregator) AddAudio(frames, malformed uint64) {
+	a.audioFrames.Add(frames)
+	a.audioMalformed.Add(malformed)
+	if malformed > 0 {
+		a.sessionsMalformed.Add(1)
+	}
+	
+	if a.parent != nil {
+		a.parent.AddAudio(frames, malformed)
+	}
+}
+
+// AddTS records a session's MPEG-TS packet, sync error and
+// continuity-counter error counts, and its smoothed and largest PCR jitter
+// in milliseconds (0 without PCRs)
+func (a *Aggregator) AddTS(packets, syncErrors, ccErrors uint64, pcrJitterMs, pcrJitterMaxMs float64) {
+	a.tsPackets.Add(packets)
+	a.tsSyncErrors.Add(syncErrors)
+	a.tsCCErrors.Add(ccErrors)
+	if ccErrors > 0 {
+		a.sessionsCCErrors.Add(1)
+	}
+	if pcrJitterMs > 0 || pcrJitterMaxMs > 0 {
+		a.pcrJitterSum.Add(uint64(pcrJitterMs * 1000))
+		a.pcrJitterCount.Add(1)
+		us := uint64(pcrJitterMaxMs * 1000)
+		for {
+			current := a.pcrJitterMax.Load()
+			if us <= current || a.pcrJitterMax.CompareAndSwap(current, us) {
+				break
+			}
+		}
+	}
+	
+	if a.parent != nil {
+		a.parent.AddTS(packets, syncErrors, ccErrors, pcrJitterMs, pcrJitterMaxMs)
+	}
+}
+
+// AddClock records a session's RTP timestamp continuity, merged across its
+// sources
+func (a *Aggregator) AddClock(s ClockStats) {
+	a.clockDiscontinuities.Add(s.Discontinuities)
+	a.clockStalls.Add(s.Stalls)
+	if s.Discontinuities > 0 {
+		a.sessionsDiscontinuous.Add(1)
+	}
+	if s.Stalls > 0 {
+		a.sessionsStalled.Add(1)
+	}
+	if s.Drifting() {
+		a.sessionsDrifting.Add(1)
+	}
+	if s.Discontinuities > 0 || s.Stalls > 0 || s.Drifting() {
+		a.sessionsClockIssues.Add(1)
+	}
+	if s.DriftValid {
+		ppm := uint64(math.Abs(s.Drift) * 1e6)
+		for {
+			current := a.driftMax.Load()
+			if ppm <= current || a.driftMax.CompareAndSwap(current, ppm) {
+				break
+			}
+		}
+	}
+	
+	if a.parent != nil {
+		a.parent.AddClock(s)
+	}
+}
+
+// AddVerified records a verifying session's matched and mismatched frames
+func (a *Aggregator) AddVerified(matched, mismatched uint64) {
+	a.framesVerified.Add(matched + mismatched)
+	a.framesCorrupt.Add(mismatched)
+	
+	if a.parent != nil {
+		a.parent.AddVerified(matched, mismatched)
+	}
+}
+
+// AddPause records the round-trip time of a mid-session PAUSE
+func (a *Aggregator) AddPause(d time.Duration) {
+	a.pauseLatency.Record(d)
+	if a.parent != nil {
+		a.parent.AddPause(d)
+	}
+}
+
+// AddResume records the round-trip time of the PLAY that resumes a paused
+// session
+func (a *Aggregator) AddResume(d time.Duration) {
+	a.resumeLatency.Record(d)
+	if a.parent != nil {
+		a.parent.AddResume(d)
+	}
+}
+
+// AddPauseFailure counts a PAUSE or resuming PLAY that got an error status
+func (a *Aggregator) AddPauseFailure() {
+	a.pauseFailures.Add(1)
+	if a.parent != nil {
+		a.parent.AddPauseFailure()
+	}
+}
+
+// AddSeek records the round-trip time of a seeking PLAY
+func (a *Aggregator) AddSeek(d time.Duration) {
+	a.seekLatency.Record(d)
+	if a.parent != nil {
+		a.parent.AddSeek(d)
+	}
+}
+
+// AddSeekFailure counts a seeking PLAY that got an error status
+func (a *Aggregator) AddSeekFailure() {
+	a.seekFailures.Add(1)
+	if a.parent != nil {
+		a.parent.AddSeekFailure()
+	}
+}
+
+// AddSeekMedia records the time from sending a seeking PLAY to the first
+// packet from the new position, and whether that packet's sequence number
+// and timestamp matched RTP-Info (checked false if there was none)
+func (a *Aggregator) AddSeekMedia(d time.Duration, checked, match bool) {
+	a.seekMedia.Record(d)
+	if checked {
+		a.seeksChecked.Add(1)
+		if !match {
+			a.seekMismatch.Add(1)
+		}
+	}
+	if a.parent != nil {
+		a.parent.AddSeekMedia(d, checked, match)
+	}
+}
+
+// AddE2ELatency records the time from a publisher stamping a frame to a
+// reader receiving it
+func (a *Aggregator) AddE2ELatency(d time.Duration) {
+	a.e2eLatency.Record(d)
+	if a.parent != nil {
+		a.parent.AddE2ELatency(d)
+	}
+}
+
+// AddNACK counts a NACK packet asking for n packets
+func (a *Aggregator) AddNACK(n uint64) {
+	a.nacksSent.Add(1)
+	a.nackRequested.Add(n)
+	if a.parent != nil {
+		a.parent.AddNACK(n)
+	}
+}
+
+// AddNACKRepair records a NACKed packet arriving d after it was requested
+func (a *Aggregator) AddNACKRepair(d time.Duration) {
+	a.nackRepair.Record(d)
+	if a.parent != nil {
+		a.parent.AddNACKRepair(d)
+	}
+}
+
+// AddPLI counts a Picture Loss Indication
+func (a *Aggregator) AddPLI() {
+	a.plisSent.Add(1)
+	if a.parent != nil {
+		a.parent.AddPLI()
+	}
+}
+
+// AddPLIKeyframe records the time from a PLI to the next keyframe
+func (a *Aggregator) AddPLIKeyframe(d time.Duration) {
+	a.pliKeyframe.Record(d)
+	if a.parent != nil {
+		a.parent.AddPLIKeyframe(d)
+	}
+}
+
+// AddBye counts a session that sent RTCP BYE
+func (a *Aggregator) AddBye() {
+	a.byesSent.Add(1)
+	if a.parent != nil {
+		a.parent.AddBye()
+	}
+}
+
+// AddMetadata counts an ONVIF metadata packet of n bytes received
+func (a *Aggregator) AddMetadata(n int) {
+	a.metadataPackets.Add(1)
+	a.metadataBytes.Add(uint64(n))
+	if a.parent != nil {
+		a.parent.AddMetadata(n)
+	}
+}
+
+// AddBackchannel counts an ONVIF backchannel packet of n bytes sent
+func (a *Aggregator) AddBackchannel(n int) {
+	a.backchannelPackets.Add(1)
+	a.backchannelBytes.Add(uint64(n))
+	if a.parent != nil {
+		a.parent.AddBackchannel(n)
+	}
+}
+
+// AddRTX counts an RTX retransmission, recovered if it filled a gap
+func (a *Aggregator) AddRTX(recovered bool) {
+	a.rtxPackets.Add(1)
+	if recovered {
+		a.rtxRecovered.Add(1)
+	}
+	if a.parent != nil {
+		a.parent.AddRTX(recovered)
+	}
+}
+
+// AddLate counts n reordered packets that arrived after their gap was
+// counted as loss
+func (a *Aggregator) AddLate(n uint64) {
+	for agg := a; agg != nil; agg = agg.parent {
+		agg.lateArrivals.Add(n)
+	}
+}
+
+// AddImpairment records the packets an impaired session's emulated network
+// dropped, duplicated and reordered
+func (a *Aggregator) AddImpairment(dropped, duplicated, reordered uint64) {
+	a.impairedSessions.Add(1)
+	a.impairDropped.Add(dropped)
+	a.impairDuplicated.Add(duplicated)
+	a.impairReordered.Add(reordered)
+	if a.parent != nil {
+		a.parent.AddImpairment(dropped, duplicated, reordered)
 	}
 }
 
 // Snapshot returns current aggregate statistics
 func (a *Aggregator) Snapshot() Snapshot {
-	return Snapshot{
-		Packets: a.packets.Load(),
-		Lost:    a.lost.Load(),
-		Bytes:   a.bytes.Load(),
+	snap := Snapshot{
+		Packets:  a.packets.Load(),
+		Lost:     a.lost.Load(),
+		Bytes:    a.bytes.Load(),
+		Rejected: a.rejected.Load(),
+		Fallbacks: a.fallbacks.Load(),
+		KeepAliveRetries:   a.keepAliveRetries.Load(),
+		KeepAliveRecovered: a.keepAliveRecovered.Load(),
+		
+		Keyframes:      a.keyframes.Load(),
+		FragmentErrors: a.fragmentErrors.Load(),
+		FramesVerified: a.framesVerified.Load(),
+		FramesCorrupt:  a.framesCorrupt.Load(),
+		
+		TimestampDiscontinuities: a.clockDiscontinuities.Load(),
+		TimestampStalls:          a.clockStalls.Load(),
+		DiscontinuousSessions:    a.sessionsDiscontinuous.Load(),
+		StalledSessions:          a.sessionsStalled.Load(),
+		DriftingSessions:         a.sessionsDrifting.Load(),
+		ClockIssueSessions:       a.sessionsClockIssues.Load(),
+		DriftMaxPct:              float64(a.driftMax.Load()) / 1e4,
+		
+		Duplicates:        a.duplicates.Load(),
+		Reordered:         a.reordered.Load(),
+		ReorderMax:        a.reorderMax.Load(),
+		DuplicateSessions: a.sessionsDuplicate.Load(),
+		ReorderedSessions: a.sessionsReordered.Load(),
+		StreamResets:      a.streamResets.Load(),
+		ResetSessions:     a.sessionsReset.Load(),
+		
+		ImpairedSessions: a.impairedSessions.Load(),
+		ImpairDropped:    a.impairDropped.Load(),
+		ImpairDuplicated: a.impairDuplicated.Load(),
+		ImpairReordered:  a.impairReordered.Load(),
+		
+		NACKsSent:     a.nacksSent.Load(),
+		NACKRequested: a.nackRequested.Load(),
+		PLIsSent:      a.plisSent.Load(),
+		ByesSent:      a.byesSent.Load(),
+		
+		RTXPackets:   a.rtxPackets.Load(),
+		RTXRecovered: a.rtxRecovered.Load(),
+		
+		MetadataPackets:    a.metadataPackets.Load(),
+		MetadataBytes:      a.metadataBytes.Load(),
+		BackchannelPackets: a.backchannelPackets.Load(),
+		BackchannelBytes:   a.backchannelBytes.Load(),
+		
+		AudioFrames:            a.audioFrames.Load(),
+		AudioMalformed:         a.audioMalformed.Load(),
+		AudioMalformedSessions: a.sessionsMalformed.Load(),
+		
+		MPEGTSPackets:       a.tsPackets.Load(),
+		MPEGTSSyncErrors:    a.tsSyncErrors.Load(),
+		MPEGTSCCErrors:      a.tsCCErrors.Load(),
+		MPEGTSErrorSessions: a.sessionsCCErrors.Load(),
+		PCRJitterMax:        float64(a.pcrJitterMax.Load()) / 1000,
+	}
+	
+	// Loss after recovery and late arrivals. A recovery can be counted
+	// before the batch holding its loss is flushed, so clamp at zero.
+	if found := snap.RTXRecovered + a.lateArrivals.Load(); found < snap.Lost {
+		snap.Lost -= found
+	} else {
+		snap.Lost = 0
+	}
+	
+	if count := a.gopCount.Load(); count > 0 {
+		snap.GOPAvg = float64(a.gopSum.Load()) / float64(count) / 1000
+	}
+	
+	if count := a.pcrJitterCount.Load(); count > 0 {
+		snap.PCRJitterAvg = float64(a.pcrJitterSum.Load()) / float64(count) / 1000
+	}
+	
+	if count := a.jitterCount.Load(); count > 0 {
+		snap.JitterMin = float64(a.jitterMin.Load()) / 1000
+		snap.JitterAvg = float64(a.jitterSum.Load()) / float64(count) / 1000
+		snap.JitterMax = float64(a.jitterMax.Load()) / 1000
 	}
+	
+	ttfp := a.ttfp.Summary()
+	snap.TTFPMin = ttfp.Min
+	snap.TTFPAvg = ttfp.Mean
+	snap.TTFPP95 = ttfp.P95
+	snap.TTFPMax = ttfp.Max
+	
+	stall := a.stallDuration.Summary()
+	snap.Stalls = stall.Count
+	snap.StallTime = stall.Mean * float64(stall.Count) / 1000
+	snap.StallAvg = stall.Mean
+	snap.StallMax = stall.Max
+	snap.MediaStallSessions = a.stallSessions.Load()
+	snap.MediaStalledSessions = a.stalledSessions.Load()
+	
+	interruption := a.interruption.Summary()
+	snap.SessionResumes = interruption.Count
+	snap.SessionResumeFailures = a.resumeFailures.Load()
+	snap.InterruptionAvg = interruption.Mean
+	snap.InterruptionP95 = interruption.P95
+	snap.InterruptionMax = interruption.Max
+	
+	for i := range snap.LossBursts {
+		snap.LossBursts[i] = a.bursts[i].Load()
+	}
+	if a.sessionBurst.Count() > 0 {
+		snap.LongestBurst = uint64(a.sessionBurst.Max() / time.Microsecond)
+		snap.SessionBurstP50 = float64(a.sessionBurst.Percentile(50) / time.Microsecond)
+		snap.SessionBurstP95 = float64(a.sessionBurst.Percentile(95) / time.Microsecond)
+	}
+	
+	pause := a.pauseLatency.Summary()
+	resume := a.resumeLatency.Summary()
+	snap.Pauses = pause.Count
+	snap.PauseFailures = a.pauseFailures.Load()
+	snap.PauseAvg = pause.Mean
+	snap.PauseP95 = pause.P95
+	snap.ResumeAvg = resume.Mean
+	snap.ResumeP95 = resume.P95
+	
+	seek := a.seekLatency.Summary()
+	media := a.seekMedia.Summary()
+	snap.Seeks = seek.Count
+	snap.SeekFailures = a.seekFailures.Load()
+	snap.SeekAvg = seek.Mean
+	snap.SeekP95 = seek.P95
+	snap.SeekMediaAvg = media.Mean
+	snap.SeeksChecked = a.seeksChecked.Load()
+	snap.SeekMismatches = a.seekMismatch.Load()
+	
+	e2e := a.e2eLatency.Summary()
+	snap.E2ESamples = e2e.Count
+	snap.E2EAvg = e2e.Mean
+	snap.E2EP50 = e2e.P50
+	snap.E2EP95 = e2e.P95
+	snap.E2EP99 = e2e.P99
+	snap.E2EMax = e2e.Max
+	
+	repair := a.nackRepair.Summary()
+	snap.NACKRepaired = repair.Count
+	snap.NACKRepairAvg = repair.Mean
+	snap.NACKRepairP95 = repair.P95
+	pli := a.pliKeyframe.Summary()
+	snap.PLIAnswered = pli.Count
+	snap.PLIKeyframeAvg = pli.Mean
+	snap.PLIKeyframeP95 = pli.P95
+	return snap
 }
 
 // Snapshot represents a point-in-time statistics snapshot
 type Snapshot struct {
-	Packets uint64
-	Lost    uint64
-	Bytes   uint64
+	Packets   uint64
+	Lost      uint64 // After RTX recovery
+	Bytes     uint64
+	Rejected  uint64  // UDP packets dropped for an unexpected source
+	Fallbacks uint64  // Sessions that fell back from UDP to TCP
+	KeepAliveRetries   uint64 // Keepalives sent again after a failure
+	KeepAliveRecovered uint64 // Keepalives that succeeded on a retry
+	JitterMin float64 // milliseconds
+	JitterAvg float64 // milliseconds
+	JitterMax float64 // milliseconds
+	TTFPMin   float64 // milliseconds
+	TTFPAvg   float64 // milliseconds
+	TTFPP95   float64 // milliseconds
+	TTFPMax   float64 // milliseconds
+	
+	Stalls               uint64  // Gaps without RTP longer than the stall threshold
+	StallTime            float64 // Total stall time, seconds
+	StallAvg             float64 // milliseconds
+	StallMax             float64 // milliseconds
+	MediaStallSessions   uint64  // Sessions that received media and were checked for stalls
+	MediaStalledSessions uint64  // Those with at least one stall
+	
+	SessionResumes        uint64  // Sessions resumed after losing their connection
+	SessionResumeFailures uint64  // Lost connections that could not be resumed
+	InterruptionAvg       float64 // milliseconds from loss to resumed PLAY
+	InterruptionP95       float64 // milliseconds
+	InterruptionMax       float64 // milliseconds
+	
+	LossBursts      [BurstClasses]uint64 // Runs of consecutive lost packets by length class (see BurstClassNames)
+	LongestBurst    uint64               // Longest run in any session, packets
+	SessionBurstP50 float64              // Median of the sessions' longest runs, packets
+	SessionBurstP95 float64              // packets
+	
+	Duplicates        uint64 // Packets received more than once
+	Reordered         uint64 // Packets that arrived after a later one
+	ReorderMax        uint64 // Largest distance a packet arrived behind the highest, sequence numbers
+	DuplicateSessions uint64 // Sessions with at least one duplicate
+	ReorderedSessions uint64 // Sessions with at least one reordered packet
+	StreamResets      uint64 // Streams restarted with a new sequence base (RFC 3550 A.1)
+	ResetSessions     uint64 // Sessions with at least one stream reset
+	
+	Keyframes      uint64
+	FragmentErrors uint64  // Broken FU-A/FU reassemblies
+	GOPAvg         float64 // milliseconds between keyframes
+	FramesVerified uint64  // Frames compared against the reference reader
+	FramesCorrupt  uint64  // Verified frames that did not match
+	
+	TimestampDiscontinuities uint64  // RTP timestamp jumps inconsistent with arrival time
+	TimestampStalls          uint64  // Periods where the RTP timestamp stopped advancing
+	DiscontinuousSessions    uint64  // Sessions with at least one discontinuity
+	StalledSessions          uint64  // Sessions with at least one stall
+	DriftingSessions         uint64  // Sessions whose timestamp clock drifted past DriftThreshold
+	ClockIssueSessions       uint64  // Sessions with any of the above
+	DriftMaxPct              float64 // Largest drift seen in any session, percent
+	
+	Pauses        uint64  // Successful mid-session PAUSE requests
+	PauseFailures uint64  // PAUSE or resuming PLAY with an error status
+	PauseAvg      float64 // milliseconds
+	PauseP95      float64 // milliseconds
+	ResumeAvg     float64 // milliseconds
+	ResumeP95     float64 // milliseconds
+	
+	Seeks          uint64  // Successful seeking PLAY requests
+	SeekFailures   uint64  // Seeking PLAY with an error status
+	SeekAvg        float64 // milliseconds
+	SeekP95        float64 // milliseconds
+	SeekMediaAvg   float64 // PLAY sent to first packet from the new position, milliseconds
+	SeeksChecked   uint64  // Seeks whose first packet was compared to RTP-Info
+	SeekMismatches uint64  // Checked seeks whose sequence or timestamp did not restart as announced
+	
+	E2ESamples uint64  // Frames carrying a publisher timestamp
+	E2EAvg     float64 // Publish to receive, milliseconds
+	E2EP50     float64 // milliseconds
+	E2EP95     float64 // milliseconds
+	E2EP99     float64 // milliseconds
+	E2EMax     float64 // milliseconds
+	
+	ImpairedSessions uint64 // Sessions that ran behind an emulated impaired network
+	ImpairDropped    uint64 // Packets dropped by the emulation (also counted as loss)
+	ImpairDuplicated uint64 // Packets delivered twice
+	ImpairReordered  uint64 // Packets held back so later ones overtook them
+	
+	NACKsSent      uint64  // Generic NACK packets sent
+	NACKRequested  uint64  // Sequence numbers NACKed
+	NACKRepaired   uint64  // NACKed packets that arrived within NACKTimeout
+	NACKRepairAvg  float64 // NACK to retransmitted packet, milliseconds
+	NACKRepairP95  float64 // milliseconds
+	PLIsSent       uint64  // Picture Loss Indications sent
+	PLIAnswered    uint64  // PLIs followed by a keyframe
+	PLIKeyframeAvg float64 // PLI to keyframe, milliseconds
+	PLIKeyframeP95 float64 // milliseconds
+	ByesSent       uint64  // Sessions that sent RTCP BYE
+	
+	RTXPackets   uint64 // RTX retransmissions received
+	RTXRecovered uint64 // Retransmissions that filled a gap (taken off Lost)
+	
+	MetadataPackets    uint64 // ONVIF metadata packets received
+	MetadataBytes      uint64
+	BackchannelPackets uint64 // ONVIF backchannel audio packets sent
+	BackchannelBytes   uint64
+	
+	AudioFrames            uint64 // AAC access units or Opus frames in valid payloads
+	AudioMalformed         uint64 // AAC or Opus payloads that failed validation
+	AudioMalformedSessions uint64 // Sessions with at least one malformed audio payload
+	
+	MPEGTSPackets       uint64  // MPEG-TS packets in MP2T payloads
+	MPEGTSSyncErrors    uint64  // 188-byte units without the sync byte
+	MPEGTSCCErrors      uint64  // continuity_counter gaps
+	MPEGTSErrorSessions uint64  // Sessions with at least one continuity error
+	PCRJitterAvg        float64 // Smoothed PCR jitter averaged over sessions, milliseconds
+	PCRJitterMax        float64 // Largest PCR deviation in any session, milliseconds
 }
 
 // LossRate calculates the packet loss rate as a percentage
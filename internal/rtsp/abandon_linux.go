@@ -0,0 +1,31 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build linux
+
+package rtsp
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// abandon closes conn without sending FIN or RST by putting the socket in
+// TCP repair mode first (needs CAP_NET_ADMIN). On failure conn is left open.
+func abandon(conn *net.TCPConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	if err := raw.Control(func(fd uintptr) {
+		serr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_REPAIR, 1)
+	}); err != nil {
+		return err
+	}
+	if serr != nil {
+		return fmt.Errorf("tcp repair: %w", serr)
+	}
+	return conn.Close()
+}
@@ -0,0 +1,15 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build !linux
+
+package rtsp
+
+import (
+	"errors"
+	"net"
+)
+
+// abandon is not available outside Linux
+func abandon(conn *net.TCPConn) error {
+	return errors.New("closing without FIN is only supported on Linux")
+}
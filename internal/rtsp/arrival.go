@@ -0,0 +1,44 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/arrival"
+)
+
+// SetArrivalLog makes the client write the arrival time, sequence number,
+// timestamp and size of every RTP packet it receives to w, for offline
+// analysis. nil disables it.
+func (c *Client) SetArrivalLog(w *arrival.Writer) {
+	c.arrivals = w
+}
+
+// logTracks records the tracks that started playing
+func (c *Client) logTracks(at time.Time) {
+	if c.arrivals == nil {
+		return
+	}
+	for _, t := range c.tracks {
+		c.arrivals.Track(t.index, t.media.ClockRate(), t.media.Type+"/"+t.media.Codec(), at)
+	}
+}
+
+// logArrival records a received RTP packet, which the sequence tracker
+// found lost packets before
+func (c *Client) logArrival(t *mediaTrack, data []byte, now time.Time, lost uint64) {
+	if c.arrivals == nil {
+		return
+	}
+	c.arrivals.Packet(arrival.Packet{
+		Track:     t.index,
+		Time:      now,
+		Seq:       binary.BigEndian.Uint16(data[2:4]),
+		Timestamp: binary.BigEndian.Uint32(data[4:8]),
+		SSRC:      binary.BigEndian.Uint32(data[8:12]),
+		Size:      len(data),
+		Marker:    data[1]&0x80 != 0,
+		Lost:      lost,
+	})
+}
@@ -0,0 +1,182 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// authenticator answers RTSP WWW-Authenticate challenges (RFC 2617 / RFC 7616)
+type authenticator struct {
+	username string
+	password string
+
+	// Active challenge (scheme == "" until the first 401)
+	scheme    string // "basic" or "digest"
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string // MD5 or SHA-256
+	qop       string
+	nc        uint32 // Nonce count, reused nonce increments it
+	cnonce    string
+}
+
+// newAuthenticator creates an authenticator for the given credentials
+func newAuthenticator(username, password string) *authenticator {
+	return &authenticator{
+		username: username,
+		password: password,
+	}
+}
+
+// handleChallenge selects the strongest supported challenge from the
+// WWW-Authenticate headers. It returns false when retrying would not help:
+// no supported scheme was offered, or the same nonce was rejected again.
+func (a *authenticator) handleChallenge(challenges []string) bool {
+	var best map[string]string
+	bestScheme, bestRank := "", 0
+
+	for _, ch := range challenges {
+		scheme, params := parseChallenge(ch)
+		rank := 0
+		switch scheme {
+		case "basic":
+			rank = 1
+		case "digest":
+			switch strings.ToUpper(params["algorithm"]) {
+			case "", "MD5":
+				rank = 2
+			case "SHA-256":
+				rank = 3
+			}
+		}
+		if rank > bestRank {
+			best, bestScheme, bestRank = params, scheme, rank
+		}
+	}
+	if bestRank == 0 {
+		return false
+	}
+
+	// Same credentials rejected for the same nonce - give up unless stale
+	if a.scheme == bestScheme && a.nonce == best["nonce"] &&
+		!strings.EqualFold(best["stale"], "true") {
+		return false
+	}
+
+	a.scheme = bestScheme
+	a.realm = best["realm"]
+	a.nonce = best["nonce"]
+	a.opaque = best["opaque"]
+	a.algorithm = best["algorithm"]
+	a.qop = ""
+	for _, q := range strings.Split(best["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			a.qop = "auth"
+		}
+	}
+	a.nc = 0
+	a.cnonce = newCnonce()
+	return true
+}
+
+// header returns the Authorization header value for a request, or "" if
+// no challenge has been received yet
+func (a *authenticator) header(method, uri string) string {
+	switch a.scheme {
+	case "basic":
+		creds := a.username + ":" + a.password
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds))
+	case "digest":
+		return a.digest(method, uri)
+	}
+	return ""
+}
+
+// digest computes a Digest Authorization value, reusing the current nonce
+func (a *authenticator) digest(method, uri string) string {
+	var h func() hash.Hash = md5.New
+	if strings.EqualFold(a.algorithm, "SHA-256") {
+		h = sha256.New
+	}
+	sum := func(s string) string {
+		d := h()
+		d.Write([]byte(s))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	ha1 := sum(a.username + ":" + a.realm + ":" + a.password)
+	ha2 := sum(method + ":" + uri)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username="%s", realm="%s", nonce="%s", uri="%s"`,
+		a.username, a.realm, a.nonce, uri)
+
+	if a.qop != "" {
+		a.nc++
+		nc := fmt.Sprintf("%08x", a.nc)
+		response := sum(ha1 + ":" + a.nonce + ":" + nc + ":" + a.cnonce + ":" + a.qop + ":" + ha2)
+		fmt.Fprintf(&b, `, response="%s", qop=%s, nc=%s, cnonce="%s"`, response, a.qop, nc, a.cnonce)
+	} else {
+		fmt.Fprintf(&b, `, response="%s"`, sum(ha1+":"+a.nonce+":"+ha2))
+	}
+
+	if a.algorithm != "" {
+		fmt.Fprintf(&b, ", algorithm=%s", a.algorithm)
+	}
+	if a.opaque != "" {
+		fmt.Fprintf(&b, `, opaque="%s"`, a.opaque)
+	}
+	return b.String()
+}
+
+// parseChallenge splits a WWW-Authenticate value into scheme and parameters
+func parseChallenge(value string) (string, map[string]string) {
+	value = strings.TrimSpace(value)
+	scheme, rest := value, ""
+	if idx := strings.IndexByte(value, ' '); idx >= 0 {
+		scheme, rest = value[:idx], value[idx+1:]
+	}
+
+	params := make(map[string]string)
+	for len(rest) > 0 {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+
+		var val string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				val, rest = rest[1:], ""
+			} else {
+				val, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.IndexByte(rest, ','); comma >= 0 {
+			val, rest = strings.TrimSpace(rest[:comma]), rest[comma+1:]
+		} else {
+			val, rest = strings.TrimSpace(rest), ""
+		}
+		params[key] = val
+	}
+
+	return strings.ToLower(scheme), params
+}
+
+// newCnonce generates a random client nonce
+func newCnonce() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
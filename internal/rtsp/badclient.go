@@ -2,11 +2,16 @@
 package rtsp
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"math/rand"
 	"net"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,27 +26,70 @@ const (
 	InvalidProtocol                     // Sends invalid RTSP commands
 	ResourceHog                         // Connects and holds resources without activity
 	RandomDisconnect                    // Disconnects at random times
-	MalformedRequests                   // Sends malformed RTSP requests
+	MalformedRequests                   // Fuzzes the server with grammar-aware mutated requests
+	DescribeFlood                       // Repeats DESCRIBE on one connection as fast as it is answered
+	SilentHolder                        // Completes the TCP handshake and never sends a byte
+	HalfOpen                            // Silent with TCP keepalive off, vanishing without FIN at the end
+	MalformedPipeline                   // Pipelines, mismatches Content-Length and splits requests at awkward points
+	
+	NumBadClientTypes // Number of types, keep last
 )
 
+// badClientNames are the type names, indexed by BadClientType
+var badClientNames = []string{
+	"SlowConnector",
+	"SlowSender",
+	"GarbageSender",
+	"IncompleteHandshake",
+	"InvalidProtocol",
+	"ResourceHog",
+	"RandomDisconnect",
+	"MalformedRequests",
+	"DescribeFlood",
+	"SilentHolder",
+	"HalfOpen",
+	"MalformedPipeline",
+}
+
 // BadClient represents a misbehaving RTSP client for stress testing
 type BadClient struct {
 	url       string
 	clientType BadClientType
 	conn      net.Conn
+	
+	describes DescribeFloodResult // DescribeFlood only
+	idle      IdleResult          // SilentHolder and HalfOpen only
+	probes    ParserProbeResult   // MalformedPipeline only
+	fuzz      FuzzResult          // MalformedRequests only
+	corpusDir string              // Where MalformedRequests saves findings
+	rng       *rand.Rand          // Delays and generated requests
+	
+	mu        sync.Mutex
+	reactions Reactions
 }
 
-// NewBadClient creates a new misbehaving client
-func NewBadClient(url string) *BadClient {
-	// Randomly select a bad behavior type
-	clientType := BadClientType(rand.Intn(8))
-	
+// NewBadClient creates a misbehaving client of a type drawn from rng,
+// seeded from rng too
+func NewBadClient(url string, rng *rand.Rand) *BadClient {
+	bc := NewBadClientOfType(url, BadClientType(rng.Intn(int(NumBadClientTypes))))
+	bc.Seed(rng.Int63())
+	return bc
+}
+
+// NewBadClientOfType creates a misbehaving client of the given type
+func NewBadClientOfType(url string, clientType BadClientType) *BadClient {
 	return &BadClient{
 		url:        url,
 		clientType: clientType,
+		rng:        rand.New(rand.NewSource(rand.Int63())),
 	}
 }
 
+// Seed makes the client's random delays and generated requests repeatable
+func (bc *BadClient) Seed(seed int64) {
+	bc.rng = rand.New(rand.NewSource(seed))
+}
+
 // Run executes the bad client behavior
 func (bc *BadClient) Run(ctx context.Context) error {
 	switch bc.clientType {
@@ -61,6 +109,14 @@ func (bc *BadClient) Run(ctx context.Context) error {
 		return bc.runRandomDisconnect(ctx)
 	case MalformedRequests:
 		return bc.runMalformedRequests(ctx)
+	case DescribeFlood:
+		return bc.runDescribeFlood(ctx)
+	case SilentHolder:
+		return bc.runSilent(ctx, false)
+	case HalfOpen:
+		return bc.runSilent(ctx, true)
+	case MalformedPipeline:
+		return bc.runMalformedPipeline(ctx)
 	default:
 		return bc.runGarbageSender(ctx)
 	}
@@ -68,24 +124,11 @@ func (bc *BadClient) Run(ctx context.Context) error {
 
 // runSlowConnector connects extremely slowly
 func (bc *BadClient) runSlowConnector(ctx context.Context) error {
-	// Parse URL to get host
-	parts := strings.Split(bc.url, "://")
-	if len(parts) < 2 {
-		return fmt.Errorf("invalid URL")
-	}
-	
-	hostParts := strings.Split(parts[1], "/")
-	host := hostParts[0]
-	if !strings.Contains(host, ":") {
-		host = fmt.Sprintf("%s:8554", host)
-	}
-	
 	// Start connection but do it very slowly
-	conn, err := net.DialTimeout("tcp", host, 30*time.Second)
-	if err != nil {
+	if err := bc.dial(net.Dialer{Timeout: 30 * time.Second}); err != nil {
 		return err
 	}
-	bc.conn = conn
+	conn := bc.conn
 	defer conn.Close()
 	
 	// Send OPTIONS very slowly (1 byte per second)
@@ -94,13 +137,13 @@ func (bc *BadClient) runSlowConnector(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(time.Duration(100+rand.Intn(900)) * time.Millisecond):
+		case <-time.After(time.Duration(100+bc.rng.Intn(900)) * time.Millisecond):
 			if _, err := conn.Write([]byte{ch}); err != nil {
 				return err
 			}
 			// Occasionally pause for longer
 			if i%10 == 0 {
-				time.Sleep(time.Duration(1+rand.Intn(3)) * time.Second)
+				time.Sleep(time.Duration(1+bc.rng.Intn(3)) * time.Second)
 			}
 		}
 	}
@@ -133,7 +176,7 @@ func (bc *BadClient) runSlowSender(ctx context.Context) error {
 			
 			// Send each character with random delays
 			for _, ch := range []byte(message) {
-				delay := time.Duration(50+rand.Intn(450)) * time.Millisecond
+				delay := time.Duration(50+bc.rng.Intn(450)) * time.Millisecond
 				time.Sleep(delay)
 				if _, err := bc.conn.Write([]byte{ch}); err != nil {
 					return err
@@ -142,7 +185,7 @@ func (bc *BadClient) runSlowSender(ctx context.Context) error {
 			
 			cseq++
 			// Long pause between commands
-			time.Sleep(time.Duration(5+rand.Intn(10)) * time.Second)
+			time.Sleep(time.Duration(5+bc.rng.Intn(10)) * time.Second)
 		}
 	}
 }
@@ -171,11 +214,11 @@ func (bc *BadClient) runGarbageSender(ctx context.Context) error {
 			return ctx.Err()
 		default:
 			// Send random garbage
-			data := garbage[rand.Intn(len(garbage))]
-			if rand.Float32() < 0.3 {
+			data := garbage[bc.rng.Intn(len(garbage))]
+			if bc.rng.Float32() < 0.3 {
 				// Sometimes send completely random bytes
-				randomBytes := make([]byte, 100+rand.Intn(900))
-				_, _ = rand.Read(randomBytes) // crypto/rand.Read rarely fails
+				randomBytes := make([]byte, 100+bc.rng.Intn(900))
+				_, _ = bc.rng.Read(randomBytes) // Never fails
 				data = string(randomBytes)
 			}
 			
@@ -184,7 +227,7 @@ func (bc *BadClient) runGarbageSender(ctx context.Context) error {
 			}
 			
 			// Random delay
-			time.Sleep(time.Duration(100+rand.Intn(2000)) * time.Millisecond)
+			time.Sleep(time.Duration(100+bc.rng.Intn(2000)) * time.Millisecond)
 		}
 	}
 }
@@ -202,10 +245,8 @@ func (bc *BadClient) runIncompleteHandshake(ctx context.Context) error {
 		return err
 	}
 	
-	// Read response but ignore it (errors expected for bad clients)
-	buf := make([]byte, 1024)
-	_ = bc.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
-	_, _ = bc.conn.Read(buf)
+	// Give the server time to answer (the response is drained)
+	time.Sleep(100 * time.Millisecond)
 	
 	// Send DESCRIBE but incomplete
 	describe := fmt.Sprintf("DESCRIBE %s RTSP/1.0\r\nCSeq: 2\r\n", bc.url)
@@ -243,7 +284,7 @@ func (bc *BadClient) runInvalidProtocol(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
-			cmd := invalidCommands[rand.Intn(len(invalidCommands))]
+			cmd := invalidCommands[bc.rng.Intn(len(invalidCommands))]
 			// Sometimes inject the current CSeq
 			if strings.Contains(cmd, "CSeq: 1") {
 				cmd = strings.Replace(cmd, "CSeq: 1", fmt.Sprintf("CSeq: %d", cseq), 1)
@@ -254,7 +295,7 @@ func (bc *BadClient) runInvalidProtocol(ctx context.Context) error {
 			}
 			
 			cseq++
-			time.Sleep(time.Duration(500+rand.Intn(1500)) * time.Millisecond)
+			time.Sleep(time.Duration(500+bc.rng.Intn(1500)) * time.Millisecond)
 		}
 	}
 }
@@ -272,10 +313,8 @@ func (bc *BadClient) runResourceHog(ctx context.Context) error {
 		return err
 	}
 	
-	// Read and discard response (errors expected for bad clients)
-	buf := make([]byte, 4096)
-	_ = bc.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
-	_, _ = bc.conn.Read(buf)
+	// Give the server time to answer (the response is drained)
+	time.Sleep(100 * time.Millisecond)
 	
 	// Now just hold the connection open, occasionally sending incomplete data
 	ticker := time.NewTicker(30 * time.Second)
@@ -300,7 +339,7 @@ func (bc *BadClient) runRandomDisconnect(ctx context.Context) error {
 	defer bc.conn.Close()
 	
 	// Random duration before disconnect (between 1 and 30 seconds)
-	duration := time.Duration(1+rand.Intn(30)) * time.Second
+	duration := time.Duration(1+bc.rng.Intn(30)) * time.Second
 	
 	// Send OPTIONS
 	options := "OPTIONS * RTSP/1.0\r\nCSeq: 1\r\n\r\n"
@@ -319,105 +358,98 @@ func (bc *BadClient) runRandomDisconnect(ctx context.Context) error {
 	}
 }
 
-// runMalformedRequests sends requests with various malformations
-func (bc *BadClient) runMalformedRequests(ctx context.Context) error {
-	if err := bc.connect(); err != nil {
-		return err
-	}
-	defer bc.conn.Close()
-	
-	cseq := 1
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			// Generate malformed request
-			var request string
-			switch rand.Intn(6) {
-			case 0:
-				// Huge header value
-				request = fmt.Sprintf("OPTIONS * RTSP/1.0\r\nCSeq: %d\r\nUser-Agent: %s\r\n\r\n",
-					cseq, strings.Repeat("A", 10000))
-			case 1:
-				// Many headers
-				var headers strings.Builder
-				headers.WriteString(fmt.Sprintf("OPTIONS * RTSP/1.0\r\nCSeq: %d\r\n", cseq))
-				for i := 0; i < 1000; i++ {
-					headers.WriteString(fmt.Sprintf("X-Header-%d: value\r\n", i))
-				}
-				headers.WriteString("\r\n")
-				request = headers.String()
-			case 2:
-				// Unicode in headers
-				request = fmt.Sprintf("OPTIONS * RTSP/1.0\r\nCSeq: %d\r\nX-Test: 你好世界\r\n\r\n", cseq)
-			case 3:
-				// Null bytes in request
-				request = fmt.Sprintf("OPTIONS * RTSP/1.0\r\nCSeq: %d\r\nX-Null: \x00\x00\x00\r\n\r\n", cseq)
-			case 4:
-				// Very long URL
-				request = fmt.Sprintf("DESCRIBE rtsp://example.com/%s RTSP/1.0\r\nCSeq: %d\r\n\r\n",
-					strings.Repeat("path/", 1000), cseq)
-			case 5:
-				// Mixed case methods
-				methods := []string{"OpTiOnS", "options", "OPTIONS", "oPtIoNs"}
-				request = fmt.Sprintf("%s * RTSP/1.0\r\nCSeq: %d\r\n\r\n",
-					methods[rand.Intn(len(methods))], cseq)
-			}
-			
-			if _, err := bc.conn.Write([]byte(request)); err != nil {
-				return err
-			}
-			
-			// Try to read response but don't care about it
-			buf := make([]byte, 4096)
-			_ = bc.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
-			_, _ = bc.conn.Read(buf)
-			
-			cseq++
-			time.Sleep(time.Duration(200+rand.Intn(800)) * time.Millisecond)
-		}
-	}
-}
-
 // connect establishes a basic TCP connection
 func (bc *BadClient) connect() error {
+	return bc.dial(net.Dialer{Timeout: 5 * time.Second})
+}
+
+// dial establishes a TCP connection with dialer
+func (bc *BadClient) dial(dialer net.Dialer) error {
 	// Parse URL to get host
-	parts := strings.Split(bc.url, "://")
-	if len(parts) < 2 {
+	u, err := url.Parse(bc.url)
+	if err != nil || u.Host == "" {
 		return fmt.Errorf("invalid URL")
 	}
+	host := hostPort(u, 8554)
 	
-	hostParts := strings.Split(parts[1], "/")
-	host := hostParts[0]
-	if !strings.Contains(host, ":") {
-		host = fmt.Sprintf("%s:8554", host)
-	}
-	
-	conn, err := net.DialTimeout("tcp", host, 5*time.Second)
+	conn, err := dialer.Dial("tcp", host)
 	if err != nil {
+		bc.dialFailed(err)
 		return err
 	}
 	
-	bc.conn = conn
+	bc.conn = bc.watch(conn)
 	return nil
 }
 
 // GetTypeName returns a human-readable name for the bad client type
 func (bc *BadClient) GetTypeName() string {
-	names := []string{
-		"SlowConnector",
-		"SlowSender",
-		"GarbageSender",
-		"IncompleteHandshake",
-		"InvalidProtocol",
-		"ResourceHog",
-		"RandomDisconnect",
-		"MalformedRequests",
-	}
-	
-	if int(bc.clientType) < len(names) {
-		return names[bc.clientType]
+	if int(bc.clientType) < len(badClientNames) {
+		return badClientNames[bc.clientType]
 	}
 	return "Unknown"
-}
\ No newline at end of file
+}
+
+// ParseBadClientType returns the type with the given name (as reported by
+// GetTypeName, case-insensitive)
+func ParseBadClientType(name string) (BadClientType, error) {
+	for i, n := range badClientNames {
+		if strings.EqualFold(n, name) {
+			return BadClientType(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown bad client type %q (want one of %s)", name, strings.Join(badClientNames, ", "))
+}
+
+// rawResponse is a response read by a bad client
+type rawResponse struct {
+	status int
+	cseq   int // -1 if missing
+	body   []byte
+}
+
+// readRawResponse reads one RTSP response with its Content-Length body
+func readRawResponse(r *bufio.Reader) (rawResponse, error) {
+	resp := rawResponse{cseq: -1}
+	length := 0
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return resp, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if resp.status == 0 {
+				continue // Stray blank line between responses
+			}
+			break
+		}
+		if resp.status == 0 {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || !strings.HasPrefix(fields[0], "RTSP/") {
+				return resp, fmt.Errorf("malformed status line: %q", line)
+			}
+			if resp.status, err = strconv.Atoi(fields[1]); err != nil {
+				return resp, fmt.Errorf("malformed status line: %q", line)
+			}
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "content-length":
+			length, _ = strconv.Atoi(strings.TrimSpace(value))
+		case "cseq":
+			if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+				resp.cseq = n
+			}
+		}
+	}
+	resp.body = make([]byte, length)
+	if _, err := io.ReadFull(r, resp.body); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,94 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SetPauseCycle makes the client behave like a DVR viewer: every interval
+// of playback it sends PAUSE, stays paused for hold, then resumes with a
+// PLAY whose Range starts at the paused position. Round-trip times are
+// recorded on the aggregator.
+func (c *Client) SetPauseCycle(interval, hold time.Duration) {
+	c.pauseEvery = interval
+	c.pauseHold = hold
+}
+
+// pauseTicker returns the pause cycle channel and its stop function. The
+// channel is nil, and never fires, when no cycle is configured.
+func (c *Client) pauseTicker() (<-chan time.Time, func()) {
+	if c.pauseEvery <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(c.pauseEvery)
+	return t.C, t.Stop
+}
+
+// position returns the current playback position
+func (c *Client) position() time.Duration {
+	return c.nptBase + time.Since(c.nptSince) - c.pausedFor
+}
+
+// setPosition records that playback restarted at npt
+func (c *Client) setPosition(npt time.Duration) {
+	c.nptBase = npt
+	c.nptSince = time.Now()
+	c.pausedFor = 0
+}
+
+// pauseCycle sends PAUSE, waits out the hold time and resumes playback.
+// Error statuses are counted and leave the session running; I/O errors
+// are returned.
+func (c *Client) pauseCycle(ctx context.Context) error {
+	npt := c.position()
+
+	start := time.Now()
+	_, err := c.sendRequestWithResponse(c.buildRequest("PAUSE", map[string]string{
+		"Session": c.session,
+	}))
+	if err != nil {
+		return c.pauseError(err)
+	}
+	c.aggregator.AddPause(time.Since(start))
+	c.lastKeepAlive.Store(time.Now().UnixNano())
+	c.restartStall()
+
+	hold := time.NewTimer(c.pauseHold)
+	defer hold.Stop()
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-hold.C:
+	}
+
+	resume := time.Now()
+	resp, err := c.sendRequestWithResponse(c.buildRequest("PLAY", map[string]string{
+		"Session": c.session,
+		"Range":   fmt.Sprintf("npt=%.3f-", npt.Seconds()),
+	}))
+	c.pausedFor += time.Since(start)
+	if err != nil {
+		return c.pauseError(err)
+	}
+	c.aggregator.AddResume(time.Since(resume))
+	c.lastKeepAlive.Store(time.Now().UnixNano())
+
+	// A live server resumes at the current packet, not where we paused
+	c.expectRestart(resp, resume, false)
+	c.restartStall()
+	return nil
+}
+
+// pauseError counts an error status as a pause failure and swallows it,
+// so a server that rejects PAUSE on live streams does not end the session
+func (c *Client) pauseError(err error) error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Code != 454 {
+		c.aggregator.AddPauseFailure()
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,27 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build linux
+
+package rtsp
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindToDevice restricts a socket to one network interface with
+// SO_BINDTODEVICE (needs CAP_NET_RAW on older kernels)
+func bindToDevice(c syscall.RawConn, device string) error {
+	var err error
+	if cerr := c.Control(func(fd uintptr) {
+		err = unix.BindToDevice(int(fd), device)
+	}); cerr != nil {
+		return cerr
+	}
+	if err != nil {
+		return fmt.Errorf("bind to %s: %w", device, err)
+	}
+	return nil
+}
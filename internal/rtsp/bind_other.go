@@ -0,0 +1,15 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build !linux
+
+package rtsp
+
+import (
+	"errors"
+	"syscall"
+)
+
+// bindToDevice is not available outside Linux
+func bindToDevice(c syscall.RawConn, device string) error {
+	return errors.New("interface binding is only supported on Linux")
+}
@@ -0,0 +1,99 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"io"
+	"net"
+	"net/netip"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/pcap"
+)
+
+// SetCapture makes the client write what it sends and reads to w: the
+// control connection, with interleaved RTP and RTCP, as a TCP stream, and
+// UDP media as datagrams. Packets are synthesized from the client's own
+// reads and writes, so no capture privileges are needed; packets the
+// kernel dropped never show up. nil disables capture.
+func (c *Client) SetCapture(w *pcap.Writer) {
+	c.capture = w
+}
+
+// capturedConn records writes on the control connection
+type capturedConn struct {
+	net.Conn
+	stream *pcap.TCPStream
+}
+
+func (cc *capturedConn) Write(p []byte) (int, error) {
+	n, err := cc.Conn.Write(p)
+	if n > 0 {
+		cc.stream.Send(p[:n])
+	}
+	return n, err
+}
+
+func (cc *capturedConn) Close() error {
+	cc.stream.Close()
+	return cc.Conn.Close()
+}
+
+// capturedReader records reads from the control connection
+type capturedReader struct {
+	r      io.Reader
+	stream *pcap.TCPStream
+}
+
+func (cr *capturedReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	if n > 0 {
+		cr.stream.Receive(p[:n])
+	}
+	return n, err
+}
+
+// captureControl returns the control connection and its read source,
+// recording both directions when capturing
+func (c *Client) captureControl(conn net.Conn, src io.Reader) (net.Conn, io.Reader) {
+	if c.capture == nil {
+		return conn, src
+	}
+	stream := c.capture.TCP(addrPort(conn.LocalAddr()), addrPort(conn.RemoteAddr()))
+	return &capturedConn{Conn: conn, stream: stream}, &capturedReader{r: src, stream: stream}
+}
+
+// captureUDP records a datagram received on or sent from a track socket
+func (c *Client) captureUDP(conn net.PacketConn, peer net.Addr, data []byte, received bool) {
+	if c.capture == nil {
+		return
+	}
+	local := addrPort(conn.LocalAddr())
+	if !local.Addr().IsValid() || local.Addr().IsUnspecified() {
+		// Wildcard sockets: the address the control connection uses
+		local = netip.AddrPortFrom(addrPort(c.conn.LocalAddr()).Addr(), local.Port())
+	}
+	if received {
+		c.capture.UDP(addrPort(peer), local, data)
+	} else {
+		c.capture.UDP(local, addrPort(peer), data)
+	}
+}
+
+// writeUDP sends a datagram from a track socket
+func (c *Client) writeUDP(conn net.PacketConn, data []byte, addr *net.UDPAddr) error {
+	_, err := conn.WriteTo(data, addr)
+	if err == nil {
+		c.captureUDP(conn, addr, data, false)
+	}
+	return err
+}
+
+// addrPort converts a TCP or UDP address
+func addrPort(addr net.Addr) netip.AddrPort {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.AddrPort()
+	case *net.UDPAddr:
+		return a.AddrPort()
+	}
+	return netip.AddrPort{}
+}
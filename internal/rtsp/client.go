@@ -5,47 +5,193 @@ import (
 	"bufio"
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net"
 	"net/url"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/winkstreaming/wink-rtsp-bench/internal/arrival"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/codec"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/impair"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/pcap"
 	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/sdp"
+	"golang.org/x/time/rate"
 )
 
 const (
 	DefaultRTSPPort = 554
-	KeepAliveInterval = 20 * time.Second
+	KeepAliveInterval = 20 * time.Second // Used when the server sends no Session timeout
 	ReadTimeout = 10 * time.Second
+	TeardownTimeout = 5 * time.Second
+	DefaultUserAgent = "WINK-RTSP-Bench/1.0"
 )
 
+// ErrSessionExpired is returned from Run when the server dropped the session
+// for timing out: a keepalive was answered with 454 Session Not Found, or
+// the server closed the connection after a full timeout without a
+// successful keepalive
+var ErrSessionExpired = errors.New("session expired")
+
+// StatusError is returned for RTSP responses with a 4xx/5xx status
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("RTSP error %d", e.Code)
+}
+
+// defaultTracks is used when DESCRIBE does not return a usable SDP
+var defaultTracks = []sdp.Media{
+	{Type: "video", Control: "trackID=0"},
+	{Type: "audio", Control: "trackID=1"},
+}
+
 // Client represents an RTSP client connection
 type Client struct {
 	url        *url.URL
+	baseURL    *url.URL // Content-Base from DESCRIBE, or url
 	transport  string
 	conn       net.Conn
+	localAddr  atomic.Value // Local address of conn, readable from other goroutines
 	reader     *bufio.Reader
+	demux      *demux // Routes responses by CSeq during playback, nil otherwise
+	writes     atomic.Pointer[writer] // Queues writes during playback, nil otherwise
 	session    string
+	sessionTimeout time.Duration // From the Session header timeout= parameter
+	lastKeepAlive  atomic.Int64  // UnixNano of the last successful keepalive or PLAY
 	cseq       int
 	aggregator *rtp.Aggregator
-	tracker    *rtp.SeqTracker
+	
+	// Session description from DESCRIBE
+	sdp        *sdp.Session
+	medias     []sdp.Media
+	
+	// Tracks that were successfully set up, of the kind selected
+	tracks     []*mediaTrack
+	trackSelection string // TracksVideo, TracksAudio or TracksAll (empty = all)
+	backchannel    bool   // ONVIF audio backchannel requested
+	
+	// TCP: interleaved channel -> track, from the SETUP responses
+	channels   [256]channelRoute
 	
 	// UDP specific
-	rtpConn    net.PacketConn
-	rtcpConn   net.PacketConn
-	serverRTP  int
-	serverRTCP int
+	serverIP   net.IP    // Media source address for validation and RTCP
+	groups     GroupTracker // Multicast group statistics, nil if none
+	responses  ResponseRecorder // Per-method statuses and latency, nil if none
+	hops       HopRecorder      // Nodes named by Via and Server headers, nil if none
+	
+	// RTCP receiver reports
+	localSSRC  uint32
+	
+	// Seek positions and impairment draws; see Seed
+	rng        *rand.Rand
 	
 	mu         sync.Mutex
 	closed     bool
+	reported   bool // The session's final statistics went to the aggregator
+	
+	// Authentication (nil = no credentials)
+	auth       *authenticator
+	
+	// Local address and interface for all sockets, and how the server's
+	// addresses are dialed
+	source     Source
+	dialOpts   DialOptions
+	sockOpts   SocketOptions
+	shared     *SharedUDP // Receives UDP media instead of the tracks' own sockets, nil if none
+	resolve    Resolver // nil = system resolver
+	attempts   []DialAttempt // Outcomes of the last Connect
+	
+	// Slow consumer: read rate cap in kbps and receive buffer (0 = off)
+	readKbps   int
+	readBuffer int
+	slow       *slowRead // Delivery tracking, nil unless the cap is active
+	
+	// RTCP feedback sent during PLAY (zero = receiver reports only)
+	feedback   Feedback
+	
+	// Emulated network impairment (zero = none)
+	impairment impair.Model
+	
+	// Shared bandwidth cap (nil = unlimited)
+	bwLimiter  *rate.Limiter
+	
+	// Frame verification: the reference reader feeds reference, verifying
+	// readers compare against it through verifier
+	reference  *codec.Reference
+	verifier   *codec.Verifier
+	
+	// Viewer behavior: periodic PAUSE/PLAY (0 = disabled)
+	pauseEvery time.Duration
+	pauseHold  time.Duration
+	seekEvery  time.Duration // Re-seek recorded media this often (0 = disabled)
+	
+	// Playback position: nptBase at nptSince, less time spent paused since
+	nptBase    time.Duration
+	nptSince   time.Time
+	pausedFor  time.Duration
+	
+	// Resuming an earlier session of the same viewer
+	resumeAt    time.Duration // Start position of the first PLAY
+	endPosition time.Duration // Position when Run ended
+	
+	// Last control messages, for failure reports (nil = not kept)
+	transcript *transcript
+	
+	// Packet capture of the session (nil = off)
+	capture    *pcap.Writer
+	arrivals   *arrival.Writer // Packet arrival timeline (nil = off)
+	
+	// Identification sent with every request
+	userAgent    string      // Empty = DefaultUserAgent
+	extraHeaders [][2]string // Name and value
+	player       *PlayerProfile // Player whose behavior is mimicked, nil = the bench's own
+	public       string         // Public header of the OPTIONS response
+	
+	// Transport auto: UDP first, TCP interleaved if no RTP arrives
+	autoTransport bool
+	fallback      time.Duration // Time UDP may go without RTP (0 = the player's or DefaultFallbackTimeout)
+	fellBack      bool
+	
+	// Gaps in media arrival longer than stallThreshold (0 = DefaultStallThreshold)
+	stallThreshold time.Duration
+	stall          stallTracker
+	
+	// Failed keepalives are retried up to keepAliveAttempts tries, waiting
+	// keepAliveBackoff, doubled per retry (0 = the defaults)
+	keepAliveAttempts int
+	keepAliveBackoff  time.Duration
+	
+	// Control channel deadlines (0 = ReadTimeout and DefaultMessageTimeout)
+	requestTimeout time.Duration
+	messageTimeout time.Duration
+	
+	// A connection lost during playback is re-dialed up to resumeAttempts
+	// times, waiting resumeBackoff, doubled per retry (0 attempts = off)
+	resumeAttempts int
+	resumeBackoff  time.Duration
+	resuming       bool // Setting the session up again after a lost connection
 	
 	// Stats
-	bytesReceived uint64
-	packetsRcvd   uint64
+	bytesReceived atomic.Uint64
+	packetsRcvd   atomic.Uint64
+	playTime      time.Time // When the PLAY response arrived
+	redirects     int       // Redirects followed during the handshake
+	
+	// Requests from the server during playback
+	followServerRedirects bool
+	serverRequests        map[string]int
+	serverRedirects       int
+	teardown      TeardownResult
 }
 
 // NewClient creates a new RTSP client
@@ -62,32 +208,99 @@ func NewClient(rtspURL string, transport string, agg *rtp.Aggregator) (*Client,
 	if transport == "" {
 		transport = "tcp"
 	}
+	transport = strings.ToLower(transport)
+	auto := transport == TransportAuto
+	if auto {
+		transport = "udp"
+	}
+
+	var auth *authenticator
+	if u.User != nil {
+		password, _ := u.User.Password()
+		auth = newAuthenticator(u.User.Username(), password)
+	}
 
 	return &Client{
 		url:        u,
-		transport:  strings.ToLower(transport),
+		baseURL:    u,
+		transport:  transport,
+		autoTransport: auto,
 		cseq:       1,
 		aggregator: agg,
-		tracker:    rtp.NewSeqTracker(),
+		localSSRC:  rand.Uint32(),
+		rng:        rand.New(rand.NewSource(rand.Int63())),
+		auth:       auth,
 	}, nil
 }
 
+// Seed makes the client's random seek positions and network impairment
+// repeatable
+func (c *Client) Seed(seed int64) {
+	c.rng = rand.New(rand.NewSource(seed))
+}
+
+// SetBandwidthLimiter attaches a shared byte-rate limiter. Media reads wait
+// on it for the number of bytes received before the packet is processed.
+func (c *Client) SetBandwidthLimiter(l *rate.Limiter) {
+	c.bwLimiter = l
+}
+
+// SetCredentials sets the username and password used to answer Basic or
+// Digest challenges, overriding any credentials embedded in the URL.
+func (c *Client) SetCredentials(username, password string) {
+	c.auth = newAuthenticator(username, password)
+}
+
+// SetUserAgent sets the User-Agent sent with every request; empty restores
+// DefaultUserAgent
+func (c *Client) SetUserAgent(ua string) {
+	c.userAgent = ua
+}
+
+// SetHeader adds a header sent with every request, replacing an earlier
+// one of the same name
+func (c *Client) SetHeader(name, value string) {
+	for i, h := range c.extraHeaders {
+		if strings.EqualFold(h[0], name) {
+			c.extraHeaders[i][1] = value
+			return
+		}
+	}
+	c.extraHeaders = append(c.extraHeaders, [2]string{name, value})
+}
+
+// SetReference makes this client the reference reader for frame
+// verification: hashes of its complete video frames are added to ref.
+func (c *Client) SetReference(ref *codec.Reference) {
+	c.reference = ref
+}
+
+// SetVerifier makes this client hash its complete video frames and compare
+// them against ref, counting mismatches as corrupted frames.
+func (c *Client) SetVerifier(ref *codec.Reference) {
+	c.verifier = codec.NewVerifier(ref)
+}
+
 // Connect establishes the RTSP control connection
 func (c *Client) Connect() error {
-	host := c.url.Host
-	if !strings.Contains(host, ":") {
-		host = fmt.Sprintf("%s:%d", host, DefaultRTSPPort)
+	port := c.url.Port()
+	if port == "" {
+		port = strconv.Itoa(DefaultRTSPPort)
 	}
 
-	conn, err := net.DialTimeout("tcp", host, 5*time.Second)
+	conn, err := c.dial(c.url.Hostname(), port)
 	if err != nil {
+		c.transcript.add(TranscriptNote, fmt.Sprintf("connect to %s failed: %v", c.url.Host, err))
 		return fmt.Errorf("connection failed: %w", err)
 	}
+	c.transcript.add(TranscriptNote, fmt.Sprintf("connected to %s from %s", conn.RemoteAddr(), conn.LocalAddr()))
 
-	c.conn = conn
 	// Use much larger buffer to prevent overflow on long RTSP responses
 	// MediaMTX can send very large SDP bodies  
-	c.reader = bufio.NewReaderSize(conn, 1024*1024) // 1MB buffer
+	conn, src := c.captureControl(conn, c.readSource(conn))
+	c.conn = conn
+	c.localAddr.Store(conn.LocalAddr().String())
+	c.reader = bufio.NewReaderSize(src, 1024*1024) // 1MB buffer
 	return nil
 }
 
@@ -100,63 +313,154 @@ func (c *Client) Run(ctx context.Context) error {
 		}
 	}
 	defer c.Close()
+	defer c.reportStats() // However the session ended: drops count too
 
-	// RTSP handshake: OPTIONS -> DESCRIBE -> SETUP -> PLAY
-	if err := c.sendOptions(); err != nil {
-		return fmt.Errorf("OPTIONS failed: %w", err)
-	}
-
-	if err := c.sendDescribe(); err != nil {
-		return fmt.Errorf("DESCRIBE failed: %w", err)
+	for {
+		err := c.runSession(ctx)
+		for c.resumable(ctx, err) {
+			// The connection was lost mid-stream: the same viewer carries on
+			// over a new one if the server lets it
+			if c.resume(ctx, err) != nil {
+				break
+			}
+			err = c.stream(ctx)
+		}
+		if errors.Is(err, errNoUDPMedia) || c.udpRejected(err) {
+			// Transport auto: UDP does not get through, try TCP
+			if err := c.fallBack(); err != nil {
+				return err
+			}
+			continue
+		}
+		var redirect *redirectError
+		if !errors.As(err, &redirect) {
+			if c.Playing() && c.MediaDuration() > 0 {
+				c.endPosition = c.position()
+			}
+			return err
+		}
+		
+		// The server is draining this node: end the session and start over
+		// at the new location
+		c.reportStats()
+		c.mu.Lock()
+		c.sendTeardown()
+		c.mu.Unlock()
+		c.serverRedirects++
+		if err := c.reconnect(redirect.location); err != nil {
+			return fmt.Errorf("redirect to %s: %w", redirect.location, err)
+		}
+		c.reported = false // A new session to report
 	}
+}
 
-	if err := c.sendSetup(); err != nil {
-		return fmt.Errorf("SETUP failed: %w", err)
+// runSession sets up and plays a session until ctx ends or it fails. It
+// returns a redirectError when the server sent REDIRECT and the client
+// follows it.
+func (c *Client) runSession(ctx context.Context) error {
+	// RTSP handshake: OPTIONS -> DESCRIBE -> SETUP -> PLAY, starting over
+	// at the new URL when DESCRIBE or SETUP is redirected
+	for {
+		err := c.setupSession()
+		var redirect *redirectError
+		if !errors.As(err, &redirect) {
+			if err != nil {
+				return err
+			}
+			break
+		}
+		if err := c.followRedirect(redirect); err != nil {
+			return err
+		}
 	}
 
 	if err := c.sendPlay(); err != nil {
 		return fmt.Errorf("PLAY failed: %w", err)
 	}
+	c.playTime = time.Now()
+	c.nptSince = c.playTime
+	c.lastKeepAlive.Store(c.playTime.UnixNano())
+	c.logTracks(c.playTime)
+	return c.stream(ctx)
+}
+
+// stream receives media on the session set up and played until ctx ends
+// or it fails
+func (c *Client) stream(ctx context.Context) error {
+	// Emulated network impairment sits between the socket and analysis
+	impairCtx, stopImpairment := context.WithCancel(ctx)
+	waitImpairment := c.startImpairment(impairCtx)
+	defer func() {
+		stopImpairment()
+		waitImpairment()
+	}()
 
 	// Start media reception based on transport
-	if c.transport == "udp" {
+	if c.datagramMedia() {
 		return c.runUDP(ctx)
 	}
 	return c.runTCP(ctx)
 }
 
-// runTCP handles TCP interleaved RTP reception
-func (c *Client) runTCP(ctx context.Context) error {
-	keepAlive := time.NewTicker(KeepAliveInterval)
-	defer keepAlive.Stop()
+// setupSession runs the handshake up to SETUP
+func (c *Client) setupSession() error {
+	if c.player == nil || !c.player.SkipOptions {
+		if err := c.sendOptions(); err != nil {
+			return fmt.Errorf("OPTIONS failed: %w", err)
+		}
+	}
+
+	if err := c.sendDescribe(); err != nil {
+		return fmt.Errorf("DESCRIBE failed: %w", err)
+	}
 
-	// Channel for keepalive errors
-	errCh := make(chan error, 1)
+	if err := c.sendSetup(); err != nil {
+		return fmt.Errorf("SETUP failed: %w", err)
+	}
+	return nil
+}
+
+// runTCP handles TCP interleaved RTP reception. This goroutine reads the
+// connection; control requests wait for their responses through the
+// demultiplexer.
+func (c *Client) runTCP(ctx context.Context) error {
+	rrTicker := time.NewTicker(ReceiverReportInterval)
+	defer rrTicker.Stop()
+	pliTick, stopPLI := c.pliTicker()
+	defer stopPLI()
+	backTick, stopBack := c.backchannelTicker()
+	defer stopBack()
+
+	controlErr, stopControl := c.startControl(ctx)
+	defer stopControl()
+	
+	// Frames are read on this goroutine, so it owns the tracks' batches
+	defer c.flushCounts(c.tracks...)
 
 	for {
 		select {
 		case <-ctx.Done():
-			c.reportStats()
 			return ctx.Err()
-		case <-keepAlive.C:
-			go func() {
-				if err := c.sendKeepAlive(); err != nil {
-					select {
-					case errCh <- err:
-					default:
-					}
-				}
-			}()
-		case err := <-errCh:
-			return fmt.Errorf("keepalive failed: %w", err)
+		case err := <-controlErr:
+			return err
+		case <-rrTicker.C:
+			_ = c.sendReceiverReport() // Best effort
+		case <-pliTick:
+			c.sendPLI() // Best effort
+		case <-backTick:
+			c.sendBackchannel() // Best effort
 		default:
-			// Read interleaved frame
-			if err := c.readInterleavedFrame(); err != nil {
+			// Read interleaved frame, response or server request
+			if err := c.readControl(ctx); err != nil {
 				if ctx.Err() != nil {
-					c.reportStats()
 					return nil
 				}
-				return fmt.Errorf("read frame failed: %w", err)
+				select {
+				case err := <-controlErr:
+					return err // The reader was interrupted
+				default:
+				}
+				return fmt.Errorf("read frame failed: %w", c.sessionError(err))
 			}
 		}
 	}
@@ -164,268 +468,474 @@ func (c *Client) runTCP(ctx context.Context) error {
 
 // runUDP handles UDP RTP reception
 func (c *Client) runUDP(ctx context.Context) error {
-	// Set up UDP listeners if not already done
-	if c.rtpConn == nil {
-		rtpConn, err := net.ListenPacket("udp", ":0")
-		if err != nil {
-			return fmt.Errorf("failed to create RTP socket: %w", err)
-		}
-		// Increase receive buffer size for better performance
-		if conn, ok := rtpConn.(*net.UDPConn); ok {
-			conn.SetReadBuffer(2 * 1024 * 1024) // 2MB buffer
+	controlErr, stopControl := c.startControl(ctx)
+	defer stopControl()
+
+	// One RTP and one RTCP reader per track socket, or routes on the shared
+	// sockets. They are done with the tracks on return, as a resumed
+	// session reads the same sockets again.
+	readCtx, cancelRead := context.WithCancel(ctx)
+	readErr := make(chan error, len(c.tracks))
+	if c.shared != nil {
+		var unregister []func()
+		for _, t := range c.tracks {
+			unregister = append(unregister, c.shared.register(readCtx, c, t))
 		}
-		c.rtpConn = rtpConn
-		defer rtpConn.Close()
-
-		rtcpConn, err := net.ListenPacket("udp", ":0")
-		if err != nil {
-			return fmt.Errorf("failed to create RTCP socket: %w", err)
+		defer func() {
+			cancelRead()
+			for _, done := range unregister {
+				done()
+			}
+		}()
+	}
+	var readers sync.WaitGroup
+	for _, t := range c.tracks {
+		if t.shared {
+			continue
 		}
-		c.rtcpConn = rtcpConn
-		defer rtcpConn.Close()
+		t := t
+		readers.Add(2)
+		go func() {
+			defer readers.Done()
+			c.readUDPTrack(readCtx, t, readErr)
+		}()
+		go func() {
+			defer readers.Done()
+			c.readRTCP(readCtx, t)
+		}()
 	}
-
-	// Start keepalive goroutine
-	keepAliveCtx, cancelKeepAlive := context.WithCancel(ctx)
-	defer cancelKeepAlive()
-	
-	keepAliveErr := make(chan error, 1)
-	go func() {
-		ticker := time.NewTicker(KeepAliveInterval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-keepAliveCtx.Done():
-				return
-			case <-ticker.C:
-				if err := c.sendKeepAlive(); err != nil {
-					select {
-					case keepAliveErr <- err:
-					default:
-					}
-					return
-				}
+	defer func() {
+		cancelRead()
+		for _, t := range c.tracks {
+			if t.shared {
+				continue
 			}
+			t.rtpConn.SetReadDeadline(time.Now())
+			t.rtcpConn.SetReadDeadline(time.Now())
 		}
+		readers.Wait()
 	}()
 
-	// Use larger buffer for UDP packets
-	buf := make([]byte, 65536) // 64KB buffer for jumbo frames
-	
-	// Set a longer deadline to reduce syscall overhead
-	c.rtpConn.SetReadDeadline(time.Now().Add(30 * time.Second))
-	deadlineTimer := time.NewTicker(10 * time.Second)
-	defer deadlineTimer.Stop()
+	rrTicker := time.NewTicker(ReceiverReportInterval)
+	defer rrTicker.Stop()
+	pliTick, stopPLI := c.pliTicker()
+	defer stopPLI()
+	backTick, stopBack := c.backchannelTicker()
+	defer stopBack()
+	noMedia, stopNoMedia := c.fallbackTimer()
+	defer stopNoMedia()
 
 	for {
 		select {
 		case <-ctx.Done():
-			c.reportStats()
 			return ctx.Err()
-		case err := <-keepAliveErr:
-			return fmt.Errorf("keepalive failed: %w", err)
-		case <-deadlineTimer.C:
-			// Refresh deadline periodically
-			c.rtpConn.SetReadDeadline(time.Now().Add(30 * time.Second))
-		default:
-			n, _, err := c.rtpConn.ReadFrom(buf)
-			if err != nil {
-				if ne, ok := err.(net.Error); ok && ne.Timeout() {
-					// Refresh deadline on timeout
-					c.rtpConn.SetReadDeadline(time.Now().Add(30 * time.Second))
-					continue
-				}
-				if ctx.Err() != nil {
-					c.reportStats()
-					return nil
-				}
-				return fmt.Errorf("UDP read failed: %w", err)
+		case err := <-controlErr:
+			return err
+		case <-noMedia:
+			if c.packetsRcvd.Load() == 0 {
+				return errNoUDPMedia
 			}
-
-			// Process RTP packet
-			if n >= 12 {
-				// Make a copy to avoid data races
-				packet := make([]byte, n)
-				copy(packet, buf[:n])
-				c.processRTPPacket(packet)
+		case err := <-readErr:
+			if ctx.Err() != nil {
+				return nil
 			}
+			return fmt.Errorf("UDP read failed: %w", err)
+		case <-rrTicker.C:
+			_ = c.sendReceiverReport() // Best effort
+		case <-pliTick:
+			c.sendPLI() // Best effort
+		case <-backTick:
+			c.sendBackchannel() // Best effort
 		}
 	}
 }
 
-// readInterleavedFrame reads a TCP interleaved RTP/RTCP frame
-func (c *Client) readInterleavedFrame() error {
-	// Read magic byte
-	magic, err := c.reader.ReadByte()
-	if err != nil {
-		return err
-	}
+// readUDPTrack receives RTP on a track's socket until ctx is done or a
+// read fails
+func (c *Client) readUDPTrack(ctx context.Context, t *mediaTrack, errCh chan<- error) {
+	defer c.flushCounts(t)
+	
+	// Batched recvmmsg on Linux, one ReadFrom per packet elsewhere
+	reader := newUDPReader(t.rtpConn)
+	
+	// Set a longer deadline to reduce syscall overhead
+	deadline := time.Now().Add(30 * time.Second)
+	t.rtpConn.SetReadDeadline(deadline)
+
+	for ctx.Err() == nil {
+		// Refresh deadline periodically
+		if time.Until(deadline) < 20*time.Second {
+			deadline = time.Now().Add(30 * time.Second)
+			t.rtpConn.SetReadDeadline(deadline)
+		}
 
-	// Check for RTSP response (not interleaved data)
-	if magic != '$' {
-		// Might be RTSP response, consume the line
-		// Use a loop to handle very long lines that might exceed buffer
-		var line string
-		for {
-			partial, err := c.reader.ReadString('\n')
-			if err != nil && err != bufio.ErrBufferFull {
-				return err
+		packets, err := reader.read()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
 			}
-			line += partial
-			if err != bufio.ErrBufferFull {
-				break
+			errCh <- err
+			return
+		}
+
+		for _, p := range packets {
+			c.captureUDP(t.rtpConn, p.addr, p.buf[:p.n], true)
+			
+			// Drop packets that did not come from the server
+			if !c.validSource(p.addr, t.serverRTP) {
+				c.aggregator.AddRejected(1)
+				continue
+			}
+
+			// Throttle against the shared bandwidth cap
+			if err := c.throttle(ctx, p.n); err != nil {
+				return
+			}
+
+			// Process RTP packet
+			if p.n >= 12 {
+				c.receiveRTP(t, p.buf[:p.n])
 			}
 		}
-		_ = line // Ignore unsolicited responses
-		return nil
 	}
+}
 
-	// Read channel
-	channel, err := c.reader.ReadByte()
+// readInterleavedFrame reads a TCP interleaved RTP/RTCP frame; see
+// readControl
+func (c *Client) readInterleavedFrame(ctx context.Context) error {
+	// Peek the whole frame before consuming it, so a read interrupted by a
+	// deadline leaves the stream at a frame boundary
+	header, err := c.reader.Peek(4)
 	if err != nil {
 		return err
 	}
-
-	// Read length (16-bit big endian)
-	var length uint16
-	if err := binary.Read(c.reader, binary.BigEndian, &length); err != nil {
+	if header[0] != '$' {
+		return fmt.Errorf("expected interleaved frame, got %q", header[0])
+	}
+	channel := header[1]
+	length := binary.BigEndian.Uint16(header[2:4])
+	frame, err := c.reader.Peek(4 + int(length))
+	if err != nil {
 		return err
 	}
-
-	// Read payload
 	payload := make([]byte, length)
-	if _, err := io.ReadFull(c.reader, payload); err != nil {
+	copy(payload, frame[4:])
+	c.reader.Discard(len(frame))
+
+	// Throttle against the shared bandwidth cap
+	if err := c.throttle(ctx, 4+int(length)); err != nil {
 		return err
 	}
 
-	// Process based on channel (0=RTP, 1=RTCP typically)
-	if channel == 0 && len(payload) >= 12 {
-		c.processRTPPacket(payload)
+	// Dispatch to the track that owns this channel
+	route := c.channels[channel]
+	switch {
+	case route.track == nil:
+		// Channel not assigned by any SETUP response
+	case route.rtcp:
+		c.handleRTCPPacket(route.track, payload)
+	case len(payload) >= 12:
+		c.receiveRTP(route.track, payload)
 	}
 
-	c.bytesReceived += uint64(4 + length)
 	return nil
 }
 
+// throttle blocks until n bytes are available from the bandwidth limiter
+func (c *Client) throttle(ctx context.Context, n int) error {
+	if c.bwLimiter == nil {
+		return nil
+	}
+	return c.bwLimiter.WaitN(ctx, n)
+}
+
+// discardInterleavedFrame skips a single $-framed interleaved packet
+func (c *Client) discardInterleavedFrame() error {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return err
+	}
+	_, err := c.reader.Discard(int(binary.BigEndian.Uint16(header[2:4])))
+	return err
+}
+
 // processRTPPacket extracts sequence number and updates tracking
-func (c *Client) processRTPPacket(data []byte) {
+func (c *Client) processRTPPacket(t *mediaTrack, data []byte) {
 	if len(data) < 12 {
 		return
 	}
 
-	// Extract sequence number (bytes 2-3)
+	// Retransmissions repair a track instead of carrying media
+	if target := t.rtxTarget(data[1] & 0x7F); target != nil {
+		c.processRTX(target, data, time.Now())
+		return
+	}
+
+	// Time to first packet after PLAY, across all tracks
+	if c.packetsRcvd.Add(1) == 1 && !c.playTime.IsZero() {
+		c.aggregator.AddFirstPacket(time.Since(c.playTime))
+	}
+	if t.metadata {
+		c.aggregator.AddMetadata(len(data))
+	}
+
+	// Extract sequence number (bytes 2-3) and SSRC (bytes 8-11)
 	seq := binary.BigEndian.Uint16(data[2:4])
+	ssrc := binary.BigEndian.Uint32(data[8:12])
+	t.ssrc.Store(ssrc)
+	t.packets.Add(1)
 	
-	// Track sequence
-	lost := c.tracker.Push(seq)
-	c.packetsRcvd++
-
-	// Update aggregator
-	if lost > 0 {
-		c.aggregator.AddLoss(lost)
+	// The first packet from a new seek position is not a gap
+	restart := false
+	if chk := t.seek.Load(); chk != nil {
+		restart = c.checkSeek(t, chk, seq, binary.BigEndian.Uint32(data[4:8]))
 	}
-	c.aggregator.AddPackets(1)
+	
+	// Each SSRC has its own sequence space and timestamp clock
+	now := time.Now()
+	c.trackStall(now)
+	var lost uint64
+	if src := t.sources.Source(ssrc); src != nil {
+		if restart {
+			src.Restart()
+		}
+		
+		// Interarrival jitter and clock continuity from the RTP timestamp
+		// (bytes 4-7)
+		src.Jitter.Push(binary.BigEndian.Uint32(data[4:8]), now)
+		src.Clock.Push(binary.BigEndian.Uint32(data[4:8]), now)
+		c.trackSlowRead(t, binary.BigEndian.Uint32(data[4:8]), now)
+		
+		// Track sequence
+		var late bool
+		lost, late = src.Seq.Push(seq)
+		if late {
+			t.counts.Late()
+		}
+		if c.feedback.Enabled() || t.repaired {
+			c.feedbackRTP(t, ssrc, seq, lost, now)
+		}
+	}
+	
+	// Depacketize video to count keyframes and check FU reassembly
+	if t.video != nil {
+		if restart {
+			t.video.Restart()
+		}
+		if payload := rtp.Payload(data); payload != nil {
+			t.video.Push(payload, seq, binary.BigEndian.Uint32(data[4:8]))
+		}
+	}
+	
+	// Validate AAC and Opus payloads
+	if t.audio != nil {
+		if restart {
+			t.audio.Restart()
+		}
+		if payload := rtp.Payload(data); payload != nil {
+			t.audio.Push(payload, seq, data[1]&0x80 != 0)
+		}
+	}
+	
+	// Parse MP2T payloads for continuity-counter errors and PCR jitter
+	if t.ts != nil {
+		if restart {
+			t.ts.Restart()
+		}
+		if payload := rtp.Payload(data); payload != nil {
+			t.ts.Push(payload, now)
+		}
+	}
+
+	// Update aggregator, batched per track to keep shared atomics off the
+	// per-packet path
+	t.counts.Add(len(data), lost, now)
+	c.logArrival(t, data, now, lost)
 
-	c.bytesReceived += uint64(len(data))
+	c.bytesReceived.Add(uint64(len(data)))
 }
 
 // sendOptions sends RTSP OPTIONS request
 func (c *Client) sendOptions() error {
 	req := c.buildRequest("OPTIONS", nil)
-	return c.sendRequest(req)
+	resp, err := c.sendRequestWithResponse(req)
+	if err != nil {
+		return err
+	}
+	c.public = c.extractHeader(resp, "Public")
+	return nil
 }
 
-// sendDescribe sends RTSP DESCRIBE request
+// sendDescribe sends RTSP DESCRIBE request and parses the SDP tracks
 func (c *Client) sendDescribe() error {
 	headers := map[string]string{
 		"Accept": "application/sdp",
 	}
+	if c.player != nil {
+		delete(headers, "Accept")
+		if c.player.Accept != "" {
+			headers["Accept"] = c.player.Accept
+		}
+	}
+	c.requireBackchannel(headers)
 	req := c.buildRequest("DESCRIBE", headers)
-	return c.sendRequest(req)
+	resp, err := c.sendRequestWithResponse(req)
+	if err != nil {
+		return err
+	}
+	if err := c.checkRedirect(resp); err != nil {
+		return err
+	}
+
+	// Relative control URIs resolve against Content-Base when present
+	if base := c.extractHeader(resp, "Content-Base"); base != "" {
+		if u, err := url.Parse(base); err == nil && u.IsAbs() {
+			c.baseURL = u
+		}
+	}
+
+	// Fall back to the conventional trackID layout if the SDP is unusable
+	session, err := sdp.Parse(extractBody(resp))
+	if err != nil {
+		c.medias = defaultTracks
+		return nil
+	}
+	c.sdp = session
+	c.medias = session.Medias
+	return nil
+}
+
+// addE2ELatency records the age of a publisher-stamped frame. A negative
+// age means the publisher's clock is ahead and is recorded as zero.
+func (c *Client) addE2ELatency(sent time.Time) {
+	d := time.Since(sent)
+	if d < 0 {
+		d = 0
+	}
+	c.aggregator.AddE2ELatency(d)
 }
 
 // sendSetup sends RTSP SETUP request for each track
 func (c *Client) sendSetup() error {
-	// First, we need to know about tracks - for now assume standard video/audio
-	// In production, parse SDP from DESCRIBE response
-	
-	// Setup video track (trackID=0)
-	headers := make(map[string]string)
-	if c.transport == "udp" {
-		// For UDP, allocate local ports for video track
-		if c.rtpConn == nil {
-			rtpConn, err := net.ListenPacket("udp", ":0")
-			if err != nil {
+	medias, err := c.selectedMedias()
+	if err != nil {
+		return err
+	}
+	for i, media := range medias {
+		headers := make(map[string]string)
+		if i > 0 {
+			// Additional tracks join the session created by the first SETUP
+			if c.session == "" {
+				break
+			}
+			headers["Session"] = c.session
+		}
+
+		t := newMediaTrack(i, media, c.aggregator)
+		if t.video != nil {
+			if c.reference != nil {
+				t.video.OnFrame(c.reference.Add)
+			} else if c.verifier != nil {
+				t.video.OnFrame(c.verifier.Check)
+			}
+			t.video.OnTimestamp(c.addE2ELatency)
+		}
+		if c.transport == TransportMulticast {
+			// Sockets are opened once the response names the group
+			headers["Transport"] = "RTP/AVP;multicast"
+		} else if c.transport == "udp" {
+			// Each track gets its own socket pair, unless all share theirs
+			if c.shared != nil {
+				t.share(c.shared)
+			} else if err := t.listenUDP(c.source, c.sockOpts); err != nil {
 				return err
 			}
-			c.rtpConn = rtpConn
+			rtpPort, rtcpPort := t.clientPorts()
+			headers["Transport"] = fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d", rtpPort, rtcpPort)
+		} else {
+			// TCP interleaved, one channel pair per track
+			headers["Transport"] = fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%d-%d", t.rtpChannel, t.rtcpChannel)
+		}
 
-			rtcpConn, err := net.ListenPacket("udp", ":0")
-			if err != nil {
+		c.requireBackchannel(headers)
+		req := c.buildTrackRequest("SETUP", media.Control, headers)
+		resp, err := c.sendRequestWithResponse(req)
+		if err == nil && i == 0 {
+			// Only the first SETUP may move the session to another server
+			err = c.checkRedirect(resp)
+		}
+		if err != nil {
+			t.close()
+			if i == 0 {
 				return err
 			}
-			c.rtcpConn = rtcpConn
+			// Ignore errors on additional tracks - first track only is OK
+			continue
 		}
 
-		rtpPort := c.rtpConn.LocalAddr().(*net.UDPAddr).Port
-		rtcpPort := c.rtcpConn.LocalAddr().(*net.UDPAddr).Port
-		
-		headers["Transport"] = fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d", rtpPort, rtcpPort)
-	} else {
-		// TCP interleaved for video
-		headers["Transport"] = "RTP/AVP/TCP;unicast;interleaved=0-1"
+		// Server ports/source (UDP) or the interleaved channels the server
+		// actually assigned (TCP)
+		c.parseTransportHeader(t, c.extractHeader(resp, "Transport"))
+		if c.transport == TransportMulticast {
+			if err := c.joinGroup(t); err != nil {
+				if i == 0 {
+					return err
+				}
+				continue
+			}
+		}
+		c.tracks = append(c.tracks, t)
+		if !c.datagramMedia() {
+			c.channels[t.rtpChannel] = channelRoute{track: t}
+			c.channels[t.rtcpChannel] = channelRoute{track: t, rtcp: true}
+		}
+
+		// Extract session ID from first SETUP response
+		if c.session == "" {
+			c.setSession(resp)
+		}
 	}
 
-	// Setup video track
-	req := c.buildTrackRequest("SETUP", "/trackID=0", headers)
-	resp, err := c.sendRequestWithResponse(req)
-	if err != nil {
-		return err
+	c.linkRTX()
+
+	// Open the NAT path before PLAY so the first packets get through;
+	// multicast media is received through the group join instead
+	if c.datagramMedia() {
+		c.resolveServerIP()
+	}
+	if c.transport == "udp" {
+		c.punchHoles()
 	}
 
-	// Extract session ID from first SETUP response
+	return nil
+}
+
+// setSession takes the session ID and timeout from a SETUP response
+func (c *Client) setSession(resp string) {
 	if session := c.extractHeader(resp, "Session"); session != "" {
 		parts := strings.Split(session, ";")
 		c.session = strings.TrimSpace(parts[0])
+		c.sessionTimeout = parseSessionTimeout(parts[1:])
 	}
-
-	// For UDP, we could extract and store server ports from video track response
-	// but MediaMTX has specific UDP handling that makes this complex
-	// UDP support is best-effort for now
-
-	// Setup audio track (trackID=1) - using same session but different ports for UDP
-	if c.session != "" {
-		headers = make(map[string]string)
-		headers["Session"] = c.session
-		if c.transport == "tcp" {
-			headers["Transport"] = "RTP/AVP/TCP;unicast;interleaved=2-3"
-		} else if c.transport == "udp" {
-			// For UDP audio, we'll use the same sockets but different server ports
-			// Just reuse the same client ports for simplicity
-			rtpPort := c.rtpConn.LocalAddr().(*net.UDPAddr).Port
-			rtcpPort := c.rtcpConn.LocalAddr().(*net.UDPAddr).Port
-			headers["Transport"] = fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d", rtpPort, rtcpPort)
-		}
-		
-		req = c.buildTrackRequest("SETUP", "/trackID=1", headers)
-		_, err = c.sendRequestWithResponse(req)
-		// Ignore audio track errors - video only is OK
-	}
-
-	// For UDP, store server address for sending RTCP reports (not implemented yet)
-	// In a full implementation, we'd connect our UDP sockets to the server ports here
-
-	return nil
 }
 
 // sendPlay sends RTSP PLAY request
 func (c *Client) sendPlay() error {
+	c.nptBase = c.startPosition()
+	return c.sendRequest(c.playRequest(c.nptBase))
+}
+
+// playRequest builds the PLAY that starts playback at npt
+func (c *Client) playRequest(npt time.Duration) *request {
 	headers := map[string]string{
 		"Session": c.session,
-		"Range":   "npt=0.000-",
+		"Range":   fmt.Sprintf("npt=%.3f-", npt.Seconds()),
+	}
+	if c.player != nil && c.player.PlayRange != "" {
+		headers["Range"] = fmt.Sprintf(c.player.PlayRange, npt.Seconds())
 	}
-	req := c.buildRequest("PLAY", headers)
-	return c.sendRequest(req)
+	c.requireBackchannel(headers)
+	return c.buildRequest("PLAY", headers)
 }
 
 // sendKeepAlive sends a keep-alive request (GET_PARAMETER or OPTIONS)
@@ -433,11 +943,102 @@ func (c *Client) sendKeepAlive() error {
 	headers := map[string]string{
 		"Session": c.session,
 	}
-	req := c.buildRequest("GET_PARAMETER", headers)
-	return c.sendRequest(req)
+	req := c.buildRequest(c.keepAliveMethod(), headers)
+	req.keepAlive = true
+	if err := c.sendRequest(req); err != nil {
+		return err
+	}
+	c.lastKeepAlive.Store(time.Now().UnixNano())
+	return nil
+}
+
+// parseSessionTimeout extracts timeout=<seconds> from the Session header
+// parameters, returning 0 if absent
+func parseSessionTimeout(params []string) time.Duration {
+	for _, p := range params {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "timeout=") {
+			if secs, err := strconv.Atoi(p[8:]); err == nil && secs > 0 {
+				return time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return 0
+}
+
+// keepAliveInterval returns how often to refresh the session: a third of
+// the server's timeout, or the player's share of it, or KeepAliveInterval
+// if the server did not send one
+func (c *Client) keepAliveInterval() time.Duration {
+	if c.sessionTimeout <= 0 {
+		return KeepAliveInterval
+	}
+	interval := c.sessionTimeout / 3
+	if c.player != nil && c.player.KeepAliveShare > 0 {
+		interval = time.Duration(float64(c.sessionTimeout)*c.player.KeepAliveShare) - c.player.KeepAliveEarly
+		if interval < c.sessionTimeout/2 {
+			interval = c.sessionTimeout / 2
+		}
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return interval
+}
+
+// Received returns the RTP packets and bytes received so far
+func (c *Client) Received() (packets, bytes uint64) {
+	return c.packetsRcvd.Load(), c.bytesReceived.Load()
 }
 
-// sendTeardown sends RTSP TEARDOWN request
+// LocalAddr returns the local address of the control connection, or ""
+// before Connect. Safe to call while the client runs.
+func (c *Client) LocalAddr() string {
+	addr, _ := c.localAddr.Load().(string)
+	return addr
+}
+
+// SessionTimeout returns the session timeout announced by the server, or 0
+func (c *Client) SessionTimeout() time.Duration {
+	return c.sessionTimeout
+}
+
+// sessionError marks err as ErrSessionExpired if it indicates the server
+// dropped the session for timing out
+func (c *Client) sessionError(err error) error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == 454 {
+		return fmt.Errorf("%w: %v", ErrSessionExpired, err)
+	}
+
+	timeout := c.sessionTimeout
+	if timeout <= 0 {
+		timeout = 60 * time.Second // RFC 2326 default
+	}
+	idle := time.Since(time.Unix(0, c.lastKeepAlive.Load()))
+	if errors.Is(err, io.EOF) && idle >= timeout {
+		return fmt.Errorf("%w: %v", ErrSessionExpired, err)
+	}
+	return err
+}
+
+// TeardownResult is the outcome of the TEARDOWN sent when closing a session
+type TeardownResult struct {
+	Sent    bool          // False if no session was established
+	Latency time.Duration // Round-trip time of the TEARDOWN
+	Status  int           // Response status code, 0 if no response arrived
+	Err     error         // Write/read failure, timeout or error status
+}
+
+// LastTeardown returns the result of the TEARDOWN sent by Close
+func (c *Client) LastTeardown() TeardownResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.teardown
+}
+
+// sendTeardown sends RTSP TEARDOWN request and records its round-trip time.
+// The caller must hold c.mu.
 func (c *Client) sendTeardown() error {
 	if c.session == "" {
 		return nil
@@ -447,69 +1048,119 @@ func (c *Client) sendTeardown() error {
 		"Session": c.session,
 	}
 	req := c.buildRequest("TEARDOWN", headers)
-	return c.sendRequest(req)
+	
+	// Servers under stress often hang on TEARDOWN - don't wait forever
+	c.conn.SetDeadline(time.Now().Add(TeardownTimeout))
+	start := time.Now()
+	resp, err := c.roundTrip(req)
+	c.teardown = TeardownResult{
+		Sent:    true,
+		Latency: time.Since(start),
+		Status:  responseStatus(resp, err),
+		Err:     err,
+	}
+	return err
+}
+
+// request is an RTSP request; CSeq and Authorization are added when sent
+type request struct {
+	method    string
+	uri       string
+	headers   map[string]string
+	body      string // Sent with Content-Length when set
+	keepAlive bool   // Recorded as MethodKeepAlive
 }
 
 // buildRequest constructs an RTSP request
-func (c *Client) buildRequest(method string, headers map[string]string) string {
-	var b strings.Builder
-	
-	// Request line
+func (c *Client) buildRequest(method string, headers map[string]string) *request {
 	uri := fmt.Sprintf("%s://%s%s", c.url.Scheme, c.url.Host, c.url.Path)
-	b.WriteString(fmt.Sprintf("%s %s RTSP/1.0\r\n", method, uri))
-	
-	// CSeq header
-	b.WriteString(fmt.Sprintf("CSeq: %d\r\n", c.cseq))
-	c.cseq++
-	
-	// User-Agent
-	b.WriteString("User-Agent: WINK-RTSP-Bench/1.0\r\n")
-	
-	// Additional headers
-	for key, value := range headers {
-		b.WriteString(fmt.Sprintf("%s: %s\r\n", key, value))
-	}
-	
-	// End of headers
-	b.WriteString("\r\n")
-	
-	return b.String()
+	return &request{method: method, uri: uri, headers: headers}
 }
 
 // buildTrackRequest constructs an RTSP request for a specific track
-func (c *Client) buildTrackRequest(method string, trackPath string, headers map[string]string) string {
+func (c *Client) buildTrackRequest(method string, control string, headers map[string]string) *request {
+	// Request line targeting the track's control URI
+	uri := resolveControl(c.baseURL, control)
+	return &request{method: method, uri: uri, headers: headers}
+}
+
+// encodeRequest serializes a request with the next CSeq
+func (c *Client) encodeRequest(req *request) string {
 	var b strings.Builder
 	
-	// Request line with track path appended
-	uri := fmt.Sprintf("%s://%s%s%s", c.url.Scheme, c.url.Host, c.url.Path, trackPath)
-	b.WriteString(fmt.Sprintf("%s %s RTSP/1.0\r\n", method, uri))
+	// Request line
+	b.WriteString(fmt.Sprintf("%s %s RTSP/1.0\r\n", req.method, req.uri))
 	
 	// CSeq header
-	b.WriteString(fmt.Sprintf("CSeq: %d\r\n", c.cseq))
+	headers := [][2]string{{"CSeq", strconv.Itoa(c.cseq)}}
 	c.cseq++
 	
-	// User-Agent
-	b.WriteString("User-Agent: WINK-RTSP-Bench/1.0\r\n")
+	// User-Agent and identification headers
+	ua := c.userAgent
+	if ua == "" && c.player != nil {
+		ua = c.player.UserAgent
+	}
+	if ua == "" {
+		ua = DefaultUserAgent
+	}
+	headers = append(headers, [2]string{"User-Agent", ua})
+	headers = append(headers, c.extraHeaders...)
+	
+	// Authorization, once the server has challenged us
+	if c.auth != nil {
+		if value := c.auth.header(req.method, req.uri); value != "" {
+			headers = append(headers, [2]string{"Authorization", value})
+		}
+	}
 	
 	// Additional headers
-	for key, value := range headers {
-		b.WriteString(fmt.Sprintf("%s: %s\r\n", key, value))
+	for key, value := range req.headers {
+		headers = append(headers, [2]string{key, value})
+	}
+	if req.body != "" {
+		headers = append(headers, [2]string{"Content-Length", strconv.Itoa(len(req.body))})
+	}
+	
+	// In the order of the mimicked player, if any
+	c.player.sortHeaders(headers)
+	for _, h := range headers {
+		b.WriteString(fmt.Sprintf("%s: %s\r\n", h[0], h[1]))
 	}
 	
 	// End of headers
 	b.WriteString("\r\n")
+	b.WriteString(req.body)
 	
 	return b.String()
 }
 
+// resolveControl resolves an SDP a=control value against the base URL.
+// Absolute URIs (RFC 2326 C.1.1) are used verbatim, "*" or an empty value
+// refers to the base itself, and relative values are appended to the base
+// path with exactly one separating slash.
+func resolveControl(base *url.URL, control string) string {
+	control = strings.TrimSpace(control)
+	baseURI := fmt.Sprintf("%s://%s%s", base.Scheme, base.Host, base.Path)
+	if control == "" || control == "*" {
+		return baseURI
+	}
+
+	if u, err := url.Parse(control); err == nil && u.IsAbs() {
+		return control
+	}
+
+	return strings.TrimSuffix(baseURI, "/") + "/" + strings.TrimPrefix(control, "/")
+}
+
 // sendRequest sends a request and reads response (discarding body)
-func (c *Client) sendRequest(req string) error {
+func (c *Client) sendRequest(req *request) error {
 	_, err := c.sendRequestWithResponse(req)
 	return err
 }
 
-// sendRequestWithResponse sends request and returns full response
-func (c *Client) sendRequestWithResponse(req string) (string, error) {
+// sendRequestWithResponse sends request and returns full response.
+// A 401 with a supported challenge is retried once with credentials.
+func (c *Client) sendRequestWithResponse(req *request) (string, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -517,64 +1168,165 @@ func (c *Client) sendRequestWithResponse(req string) (string, error) {
 		return "", fmt.Errorf("connection closed")
 	}
 
+	resp, err := c.roundTrip(req)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == 401 && c.auth != nil {
+		if c.auth.handleChallenge(c.extractHeaders(resp, "WWW-Authenticate")) {
+			resp, err = c.roundTrip(req)
+		}
+	}
+	return resp, err
+}
+
+// roundTrip writes a single request and reads its response
+func (c *Client) roundTrip(req *request) (string, error) {
+	c.delayRequest()
+	
+	// During playback the responses are read, between media frames, by
+	// whoever reads the connection; wait for ours to be handed over
+	cseq := c.cseq
+	data := []byte(c.encodeRequest(req))
+	var answer <-chan demuxResponse
+	if c.demux != nil {
+		var err error
+		if answer, err = c.demux.expect(cseq); err != nil {
+			return "", err
+		}
+	}
+	
 	// Send request
-	if _, err := c.conn.Write([]byte(req)); err != nil {
+	start := time.Now()
+	if err := c.write(data); err != nil {
+		if answer != nil {
+			c.demux.forget(cseq)
+		}
+		c.transcript.add(TranscriptNote, fmt.Sprintf("%s not sent: %v", req.method, err))
 		return "", err
 	}
+	c.transcript.add(TranscriptSent, string(data))
 
 	// Read response
-	return c.readResponse()
+	var resp string
+	var err error
+	if answer != nil {
+		resp, err = c.demux.wait(cseq, answer, c.requestLimit())
+	} else {
+		// A server that never answers the handshake would hold the reader
+		// until the test ends; TEARDOWN sets its own deadline
+		if (c.playTime.IsZero() || c.resuming) && req.method != "TEARDOWN" {
+			c.conn.SetReadDeadline(time.Now().Add(c.requestLimit()))
+			defer c.conn.SetReadDeadline(time.Time{})
+		}
+		resp, err = c.readResponse()
+	}
+	if resp != "" {
+		c.transcript.add(TranscriptReceived, resp)
+	} else if err != nil {
+		c.transcript.add(TranscriptNote, fmt.Sprintf("no response to %s: %v", req.method, err))
+	}
+	latency := time.Since(start)
+	c.recordResponse(req, responseStatus(resp, err), err, latency)
+	c.recordHop(req, resp, latency)
+	return resp, err
+}
+
+// responseStatus returns the status code of a roundTrip result, or 0 if no
+// response was received
+func responseStatus(resp string, err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code
+	}
+	if err != nil {
+		return 0
+	}
+	parts := strings.Fields(resp)
+	if len(parts) < 2 {
+		return 0
+	}
+	code, _ := strconv.Atoi(parts[1])
+	return code
 }
 
 // readResponse reads an RTSP response
 func (c *Client) readResponse() (string, error) {
-	var response strings.Builder
-	
-	// Read status line with proper handling for long lines
 	var statusLine string
 	for {
-		partial, err := c.reader.ReadString('\n')
-		if err != nil && err != bufio.ErrBufferFull {
+		// Skip interleaved media frames that arrive ahead of the response
+		b, err := c.reader.Peek(1)
+		if err != nil {
 			return "", err
 		}
-		statusLine += partial
-		if err != bufio.ErrBufferFull {
+		if b[0] == 0x15 || b[0] == 0x16 {
+			return "", ErrTLSResponse // Alert or handshake record
+		}
+		if b[0] == '$' {
+			if err := c.discardInterleavedFrame(); err != nil {
+				return "", err
+			}
+			continue
+		}
+		
+		statusLine, err = readLine(c.reader)
+		if err != nil {
+			return "", err
+		}
+		if !isRequestLine(statusLine) {
 			break
 		}
-	}
-	response.WriteString(statusLine)
-	
-	// Check status code
-	if !strings.HasPrefix(statusLine, "RTSP/1.0") {
-		return "", fmt.Errorf("invalid response: %s", statusLine)
+		
+		// A request from the server is answered, but a REDIRECT is only
+		// followed during playback
+		msg, err := readMessage(c.reader, statusLine)
+		if err != nil {
+			return "", err
+		}
+		var redirect *redirectError
+		if err := c.serveRequest(msg); err != nil && !errors.As(err, &redirect) {
+			return "", err
+		}
 	}
 	
-	parts := strings.Fields(statusLine)
-	if len(parts) < 2 {
-		return "", fmt.Errorf("malformed status line")
+	if _, err := parseStatusLine(statusLine); err != nil {
+		return "", err
 	}
-	
-	statusCode, err := strconv.Atoi(parts[1])
+	response, err := readMessage(c.reader, statusLine)
 	if err != nil {
-		return "", fmt.Errorf("invalid status code: %s", parts[1])
+		return "", err
 	}
+	return checkResponse(response)
+}
+
+// readLine reads a line of any length, including the line ending
+func readLine(r *bufio.Reader) (string, error) {
+	var line string
+	for {
+		partial, err := r.ReadString('\n')
+		if err != nil && err != bufio.ErrBufferFull {
+			return "", err
+		}
+		line += partial
+		if err != bufio.ErrBufferFull {
+			return line, nil
+		}
+	}
+}
+
+// readMessage reads the headers and body of an RTSP message whose first
+// line was already read, and returns the whole message
+func readMessage(r *bufio.Reader, firstLine string) (string, error) {
+	var message strings.Builder
+	message.WriteString(firstLine)
 	
 	// Read headers
 	contentLength := 0
 	for {
-		// Read header line with proper buffer handling
-		var line string
-		for {
-			partial, err := c.reader.ReadString('\n')
-			if err != nil && err != bufio.ErrBufferFull {
-				return "", err
-			}
-			line += partial
-			if err != bufio.ErrBufferFull {
-				break
-			}
+		line, err := readLine(r)
+		if err != nil {
+			return "", err
 		}
-		response.WriteString(line)
+		message.WriteString(line)
 		
 		// End of headers
 		if line == "\r\n" || line == "\n" {
@@ -593,18 +1345,69 @@ func (c *Client) readResponse() (string, error) {
 	// Read body if present
 	if contentLength > 0 {
 		body := make([]byte, contentLength)
-		if _, err := io.ReadFull(c.reader, body); err != nil {
+		if _, err := io.ReadFull(r, body); err != nil {
 			return "", err
 		}
-		response.Write(body)
+		message.Write(body)
+	}
+	return message.String(), nil
+}
+
+// parseStatusLine returns the status code of an RTSP status line
+func parseStatusLine(statusLine string) (int, error) {
+	if !strings.HasPrefix(statusLine, "RTSP/1.0") {
+		return 0, fmt.Errorf("invalid response: %s", statusLine)
+	}
+	
+	parts := strings.Fields(statusLine)
+	if len(parts) < 2 {
+		return 0, fmt.Errorf("malformed status line")
 	}
 	
-	// Check for error status
+	statusCode, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid status code: %s", parts[1])
+	}
+	return statusCode, nil
+}
+
+// checkResponse returns a complete response, with a StatusError for a
+// 4xx/5xx status
+func checkResponse(response string) (string, error) {
+	statusLine, _, _ := strings.Cut(response, "\n")
+	statusCode, err := parseStatusLine(statusLine)
+	if err != nil {
+		return "", err
+	}
 	if statusCode >= 400 {
-		return response.String(), fmt.Errorf("RTSP error %d", statusCode)
+		return response, &StatusError{Code: statusCode}
 	}
+	return response, nil
+}
+
+// extractBody returns the message body following the header block
+func extractBody(response string) string {
+	if idx := strings.Index(response, "\r\n\r\n"); idx >= 0 {
+		return response[idx+4:]
+	}
+	if idx := strings.Index(response, "\n\n"); idx >= 0 {
+		return response[idx+2:]
+	}
+	return ""
+}
+
+// extractHeaders extracts all values of a repeated header from response
+func (c *Client) extractHeaders(response, header string) []string {
+	var values []string
+	header = strings.ToLower(header)
 	
-	return response.String(), nil
+	for _, line := range strings.Split(response, "\n") {
+		if strings.HasPrefix(strings.ToLower(line), header+":") {
+			parts := strings.SplitN(line, ":", 2)
+			values = append(values, strings.TrimSpace(parts[1]))
+		}
+	}
+	return values
 }
 
 // extractHeader extracts a header value from response
@@ -623,32 +1426,118 @@ func (c *Client) extractHeader(response, header string) string {
 	return ""
 }
 
-// parseTransportHeader extracts server ports from Transport header
-func (c *Client) parseTransportHeader(transport string) {
-	// Example: RTP/AVP;unicast;client_port=5000-5001;server_port=6000-6001
+// parseTransportHeader extracts server ports, source address and interleaved
+// channels from the Transport header
+func (c *Client) parseTransportHeader(t *mediaTrack, transport string) {
+	// Example: RTP/AVP;unicast;client_port=5000-5001;server_port=6000-6001;source=10.0.0.1
+	//          RTP/AVP/TCP;unicast;interleaved=2-3
 	parts := strings.Split(transport, ";")
 	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, "source=") {
+			c.serverIP = net.ParseIP(strings.TrimPrefix(part, "source="))
+		}
+		if strings.HasPrefix(part, "interleaved=") {
+			// interleaved=<rtp>-<rtcp>; RTCP defaults to the next channel
+			channels := strings.Split(strings.TrimPrefix(part, "interleaved="), "-")
+			if rtpCh, err := strconv.Atoi(channels[0]); err == nil && rtpCh >= 0 && rtpCh < 255 {
+				t.rtpChannel = rtpCh
+				t.rtcpChannel = rtpCh + 1
+				if len(channels) >= 2 {
+					if rtcpCh, err := strconv.Atoi(channels[1]); err == nil && rtcpCh >= 0 && rtcpCh < 256 {
+						t.rtcpChannel = rtcpCh
+					}
+				}
+			}
+		}
+		if strings.HasPrefix(part, "destination=") {
+			t.destination = net.ParseIP(strings.TrimPrefix(part, "destination="))
+		}
+		if strings.HasPrefix(part, "port=") {
+			// Multicast group ports: port=<rtp>-<rtcp>
+			ports := strings.Split(strings.TrimPrefix(part, "port="), "-")
+			t.groupPorts[0], _ = strconv.Atoi(ports[0])
+			if len(ports) >= 2 {
+				t.groupPorts[1], _ = strconv.Atoi(ports[1])
+			}
+		}
+		if strings.HasPrefix(part, "ssrc=") {
+			ssrc, _ := strconv.ParseUint(strings.TrimPrefix(part, "ssrc="), 16, 32)
+			t.announcedSSRC = uint32(ssrc)
+		}
 		if strings.HasPrefix(part, "server_port=") {
 			ports := strings.TrimPrefix(part, "server_port=")
 			portParts := strings.Split(ports, "-")
 			if len(portParts) >= 1 {
-				c.serverRTP, _ = strconv.Atoi(portParts[0])
+				t.serverRTP, _ = strconv.Atoi(portParts[0])
 				if len(portParts) >= 2 {
-					c.serverRTCP, _ = strconv.Atoi(portParts[1])
+					t.serverRTCP, _ = strconv.Atoi(portParts[1])
 				}
 			}
 		}
 	}
 }
 
-// reportStats reports final statistics to aggregator
+// reportStats reports final statistics to aggregator. Loss has already
+// been counted with the packets, so only jitter, timestamp continuity,
+// video, audio and MPEG-TS analysis, and stalled sessions are added here,
+// once per session.
 func (c *Client) reportStats() {
-	if c.tracker != nil {
-		stats := c.tracker.GetStats()
-		if stats.Lost > 0 {
-			c.aggregator.AddLoss(stats.Lost)
+	if c.reported {
+		return
+	}
+	c.reported = true
+	var clock rtp.ClockStats
+	var bursts rtp.LossBursts
+	var duplicates, reordered, maxReorder, resets uint64
+	received := false
+	var audio codec.AudioStats
+	validated := false
+	for _, t := range c.tracks {
+		for _, src := range t.sources.Sources() {
+			if seq := src.Seq.GetStats(); seq.Packets > 1 {
+				c.aggregator.AddJitter(src.Jitter.JitterMs())
+				clock.Merge(src.Clock.GetStats())
+				bursts.Merge(seq.Bursts)
+				duplicates += seq.Duplicates
+				reordered += seq.Reordered
+				maxReorder = max(maxReorder, seq.MaxReorder)
+				resets += seq.Resets
+				received = true
+			}
+		}
+		if t.video != nil {
+			vs := t.video.Stats()
+			c.aggregator.AddVideo(vs.Keyframes, vs.FragmentErrors, vs.AvgGOPMs)
+		}
+		if t.audio != nil {
+			as := t.audio.Stats()
+			audio.Frames += as.Frames
+			audio.Malformed += as.Malformed
+			validated = true
+		}
+		if t.ts != nil {
+			ts := t.ts.Stats()
+			c.aggregator.AddTS(ts.Packets, ts.SyncErrors, ts.ContinuityErrors, ts.PCRJitterMs, ts.PCRJitterMaxMs)
+		}
+	}
+	if received {
+		c.aggregator.AddClock(clock)
+		c.aggregator.AddLossBursts(bursts)
+		c.aggregator.AddReordering(duplicates, reordered, maxReorder)
+		if resets > 0 {
+			c.aggregator.AddStreamResets(resets)
 		}
 	}
+	if validated {
+		c.aggregator.AddAudio(audio.Frames, audio.Malformed)
+	}
+	c.reportStall()
+	c.reportImpairment()
+	if c.verifier != nil {
+		res := c.verifier.Flush()
+		c.aggregator.AddVerified(res.Matched, res.Mismatched)
+	}
 }
 
 // Close closes the RTSP connection
@@ -661,8 +1550,9 @@ func (c *Client) Close() error {
 	}
 	c.closed = true
 
-	// Send TEARDOWN if we have a session
+	// Send BYE and TEARDOWN if we have a session
 	if c.session != "" && c.conn != nil {
+		c.sendBye()
 		c.sendTeardown()
 	}
 
@@ -670,11 +1560,9 @@ func (c *Client) Close() error {
 	if c.conn != nil {
 		c.conn.Close()
 	}
-	if c.rtpConn != nil {
-		c.rtpConn.Close()
-	}
-	if c.rtcpConn != nil {
-		c.rtcpConn.Close()
+	c.leaveGroups()
+	for _, t := range c.tracks {
+		t.close()
 	}
 
 	return nil
@@ -0,0 +1,71 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultMessageTimeout is how long the rest of an RTSP message may take
+// once its first line arrived during playback
+const DefaultMessageTimeout = 5 * time.Second
+
+// ErrControlTimeout marks a control channel that hung during playback: a
+// request not answered or not sent in time, or a message that stopped
+// arriving halfway
+var ErrControlTimeout = errors.New("control channel timed out")
+
+// errNoResponse is a request during playback left unanswered
+var errNoResponse = fmt.Errorf("%w: no response", ErrControlTimeout)
+
+// SetTimeouts sets how long a request may wait for its response, or to be
+// written (0 = ReadTimeout), and how long the rest of a message may take
+// once it started arriving during playback (0 = DefaultMessageTimeout)
+func (c *Client) SetTimeouts(request, message time.Duration) {
+	c.requestTimeout = request
+	c.messageTimeout = message
+}
+
+// requestLimit returns the request timeout in effect
+func (c *Client) requestLimit() time.Duration {
+	if c.requestTimeout > 0 {
+		return c.requestTimeout
+	}
+	return ReadTimeout
+}
+
+// messageLimit returns the message timeout in effect
+func (c *Client) messageLimit() time.Duration {
+	if c.messageTimeout > 0 {
+		return c.messageTimeout
+	}
+	return DefaultMessageTimeout
+}
+
+// readMessageBounded reads the rest of a message whose first line was
+// read, giving up after the message timeout. The connection is then
+// unusable, which ends the session.
+func (c *Client) readMessageBounded(firstLine string) (string, error) {
+	fired := make(chan struct{})
+	stalled := time.AfterFunc(c.messageLimit(), func() {
+		c.conn.SetReadDeadline(time.Now())
+		close(fired)
+	})
+	msg, err := readMessage(c.reader, firstLine)
+	if !stalled.Stop() {
+		<-fired
+		if err == nil {
+			c.conn.SetReadDeadline(time.Time{}) // Complete just in time
+		} else if isTimeout(err) {
+			return "", fmt.Errorf("%w: message stopped halfway: %v", ErrControlTimeout, err)
+		}
+	}
+	return msg, err
+}
+
+// isTimeout reports whether err is an I/O deadline passing
+func isTimeout(err error) bool {
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
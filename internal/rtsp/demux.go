@@ -0,0 +1,222 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errPlaybackEnded fails requests still waiting for a response when
+// playback stops
+var errPlaybackEnded = errors.New("playback ended")
+
+// demuxResponse is a response handed to the request waiting for it
+type demuxResponse struct {
+	msg string
+	err error
+}
+
+// demux matches the responses read from the RTSP connection during
+// playback to the requests waiting for them, by CSeq. While it runs, only
+// the connection's reader reads from it; requests wait on their channel.
+type demux struct {
+	mu      sync.Mutex
+	pending map[int]chan demuxResponse
+	err     error // Set once reading stopped; fails pending and later requests
+}
+
+// newDemux creates a demultiplexer with no requests outstanding
+func newDemux() *demux {
+	return &demux{pending: make(map[int]chan demuxResponse)}
+}
+
+// expect registers a request about to be sent with cseq. It must be
+// called before writing, so a fast response finds its request.
+func (d *demux) expect(cseq int) (<-chan demuxResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return nil, d.err
+	}
+	ch := make(chan demuxResponse, 1)
+	d.pending[cseq] = ch
+	return ch, nil
+}
+
+// forget drops a request that will not be answered
+func (d *demux) forget(cseq int) {
+	d.mu.Lock()
+	delete(d.pending, cseq)
+	d.mu.Unlock()
+}
+
+// wait returns the response to cseq, or a timeout error if none arrives
+// within timeout
+func (d *demux) wait(cseq int, ch <-chan demuxResponse, timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return r.msg, r.err
+	case <-timer.C:
+		d.forget(cseq)
+		return "", fmt.Errorf("%w to CSeq %d", errNoResponse, cseq)
+	}
+}
+
+// deliver hands a response to the request with its CSeq. Responses nobody
+// waits for (late or unsolicited) are dropped.
+func (d *demux) deliver(cseq int, response string) {
+	d.mu.Lock()
+	ch, ok := d.pending[cseq]
+	delete(d.pending, cseq)
+	d.mu.Unlock()
+	if ok {
+		msg, err := checkResponse(response)
+		ch <- demuxResponse{msg: msg, err: err}
+	}
+}
+
+// stop fails the pending requests, and all later ones, with err
+func (d *demux) stop(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err == nil {
+		d.err = err
+	}
+	for cseq, ch := range d.pending {
+		ch <- demuxResponse{err: d.err}
+		delete(d.pending, cseq)
+	}
+}
+
+// readControl reads the next message from the RTSP connection during
+// playback: an interleaved frame, a response to a pending request or a
+// request from the server
+func (c *Client) readControl(ctx context.Context) error {
+	b, err := c.reader.Peek(1)
+	if err != nil {
+		return err
+	}
+	if b[0] == '$' {
+		return c.readInterleavedFrame(ctx)
+	}
+
+	line, err := readLine(c.reader)
+	if err != nil {
+		return err
+	}
+	isResponse := strings.HasPrefix(line, "RTSP/")
+	isRequest := isRequestLine(line)
+	if !isResponse && !isRequest {
+		return nil // Stray line, e.g. padding between frames
+	}
+	msg, err := c.readMessageBounded(line)
+	if err != nil {
+		return err
+	}
+	if isResponse {
+		if cseq, err := strconv.Atoi(c.extractHeader(msg, "CSeq")); err == nil {
+			c.demux.deliver(cseq, msg)
+		}
+		return nil
+	}
+	return c.serveRequest(msg)
+}
+
+// startControl starts the demultiplexer and the goroutine running
+// keepalives, pause cycles and seeks. Over TCP the caller reads the
+// connection with readControl, as it also carries the media; otherwise a
+// goroutine does. Failures are sent on the returned channel. stop ends the
+// goroutines, fails requests still waiting and detaches the demultiplexer,
+// so Close reads the TEARDOWN response directly.
+func (c *Client) startControl(ctx context.Context) (<-chan error, func()) {
+	c.demux = newDemux()
+	errCh := make(chan error, 1)
+	fail := func(err error) {
+		select {
+		case errCh <- err:
+		default:
+		}
+		c.conn.SetReadDeadline(time.Now()) // Unblock the reader
+	}
+	stopWriter := c.startWriter(fail)
+
+	controlCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		c.runControl(controlCtx, fail)
+	}()
+	go func() {
+		defer wg.Done()
+		<-controlCtx.Done()
+		c.conn.SetReadDeadline(time.Now()) // Unblock the reader, e.g. on a paused stream
+	}()
+	if c.datagramMedia() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for controlCtx.Err() == nil {
+				if err := c.readControl(controlCtx); err != nil {
+					if controlCtx.Err() == nil {
+						fail(fmt.Errorf("control connection failed: %w", c.sessionError(err)))
+					}
+					return
+				}
+			}
+		}()
+	}
+
+	return errCh, func() {
+		cancel()
+		c.demux.stop(errPlaybackEnded)
+		wg.Wait()
+		stopWriter()
+		c.conn.SetReadDeadline(time.Time{})
+		c.demux = nil
+	}
+}
+
+// runControl sends keepalives and runs the pause and seek cycles until ctx
+// ends, one request at a time. The first failure is passed to fail.
+func (c *Client) runControl(ctx context.Context, fail func(error)) {
+	keepAlive := time.NewTicker(c.keepAliveInterval())
+	defer keepAlive.Stop()
+	pauseTick, stopPause := c.pauseTicker()
+	defer stopPause()
+	seekTick, stopSeek := c.seekTicker()
+	defer stopSeek()
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			if err = c.keepAlive(ctx); err != nil {
+				err = fmt.Errorf("keepalive failed: %w", c.sessionError(err))
+			}
+		case <-pauseTick:
+			if err = c.pauseCycle(ctx); err != nil {
+				err = fmt.Errorf("pause cycle failed: %w", c.sessionError(err))
+			}
+		case <-seekTick:
+			if err = c.seek(); err != nil {
+				err = fmt.Errorf("seek failed: %w", c.sessionError(err))
+			}
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				fail(err)
+			}
+			return
+		}
+	}
+}
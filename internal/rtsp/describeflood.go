@@ -0,0 +1,120 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"time"
+)
+
+// DescribeFloodWindow is how many responses at the start and end of a
+// DESCRIBE flood are averaged to compare latency
+const DescribeFloodWindow = 20
+
+// maxSDPVariants bounds the distinct SDP bodies remembered per flood
+const maxSDPVariants = 64
+
+// DescribeFloodResult summarizes a DescribeFlood client's session.
+// Identical SDP bodies suggest the server caches the description; a body
+// that changes between responses (session version, timestamps) means it is
+// generated per request, and latency that grows across the flood shows the
+// cost.
+type DescribeFloodResult struct {
+	Requests    int           // DESCRIBEs answered
+	Errors      int           // Answered with a 4xx/5xx status
+	Baseline    time.Duration // Average latency of the first DescribeFloodWindow responses
+	Final       time.Duration // Average latency of the last DescribeFloodWindow responses
+	Max         time.Duration
+	SDPChanges  int // Responses whose body differed from the previous one
+	SDPVariants int // Distinct bodies seen (at most 64 are told apart)
+}
+
+// DescribeFlood returns the flood summary, or false if the client is of
+// another type
+func (bc *BadClient) DescribeFlood() (DescribeFloodResult, bool) {
+	return bc.describes, bc.clientType == DescribeFlood
+}
+
+// runDescribeFlood sends DESCRIBE for the same URL on one persistent
+// connection, each as soon as the previous response arrives
+func (bc *BadClient) runDescribeFlood(ctx context.Context) error {
+	if err := bc.connect(); err != nil {
+		return err
+	}
+	defer bc.conn.Close()
+	go func() {
+		<-ctx.Done()
+		bc.conn.SetDeadline(time.Now()) // Unblock a pending read
+	}()
+	
+	reader := bufio.NewReader(bc.conn)
+	res := &bc.describes
+	variants := make(map[uint64]bool)
+	var lastBody uint64
+	var recent [DescribeFloodWindow]time.Duration
+	var baselineSum time.Duration
+	
+	for cseq := 1; ctx.Err() == nil; cseq++ {
+		request := fmt.Sprintf("DESCRIBE %s RTSP/1.0\r\nCSeq: %d\r\nAccept: application/sdp\r\n\r\n", bc.url, cseq)
+		start := time.Now()
+		bc.conn.SetDeadline(start.Add(ReadTimeout))
+		if _, err := bc.conn.Write([]byte(request)); err != nil {
+			return bc.finishFlood(ctx, err, recent[:], baselineSum)
+		}
+		resp, err := readRawResponse(reader)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() && ctx.Err() == nil {
+				bc.timedOut()
+			}
+			return bc.finishFlood(ctx, err, recent[:], baselineSum)
+		}
+		latency := time.Since(start)
+		
+		if res.Requests < DescribeFloodWindow {
+			baselineSum += latency
+		}
+		recent[res.Requests%DescribeFloodWindow] = latency
+		res.Requests++
+		if latency > res.Max {
+			res.Max = latency
+		}
+		if resp.status >= 400 {
+			res.Errors++
+			continue
+		}
+		
+		h := fnv.New64a()
+		h.Write(resp.body)
+		sum := h.Sum64()
+		if res.Requests > 1 && sum != lastBody {
+			res.SDPChanges++
+		}
+		lastBody = sum
+		if !variants[sum] && len(variants) < maxSDPVariants {
+			variants[sum] = true
+			res.SDPVariants = len(variants)
+		}
+	}
+	return bc.finishFlood(ctx, nil, recent[:], baselineSum)
+}
+
+// finishFlood computes the latency averages and returns err, or ctx's
+// error if the flood was stopped
+func (bc *BadClient) finishFlood(ctx context.Context, err error, recent []time.Duration, baselineSum time.Duration) error {
+	res := &bc.describes
+	if n := min(res.Requests, DescribeFloodWindow); n > 0 {
+		res.Baseline = baselineSum / time.Duration(n)
+		var sum time.Duration
+		for _, d := range recent[:n] {
+			sum += d
+		}
+		res.Final = sum / time.Duration(n)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
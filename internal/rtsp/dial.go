@@ -0,0 +1,287 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// DialTimeout bounds name resolution plus all connection attempts
+const DialTimeout = 5 * time.Second
+
+// Address families for DialOptions.Family
+const (
+	FamilyAny  = ""
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// Source selects the local side of a client's sockets. The zero value
+// leaves both to the kernel.
+type Source struct {
+	IP     net.IP // Local address for the control connection and UDP sockets (nil = any)
+	Device string // Network interface to bind to (empty = none, Linux only)
+}
+
+// DialOptions controls how the control connection picks among the
+// server's resolved addresses
+type DialOptions struct {
+	Family string // FamilyIPv4, FamilyIPv6 or FamilyAny
+	
+	// Happy Eyeballs (RFC 8305): IPv6 and IPv4 addresses are interleaved
+	// and a new attempt starts every HappyEyeballs while earlier ones are
+	// still pending; the first to connect wins. 0 tries the addresses one
+	// after another in resolver order.
+	HappyEyeballs time.Duration
+}
+
+// Resolver returns the addresses to dial for a server hostname
+type Resolver func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+// DialAttempt is the outcome of one connection attempt to a resolved
+// server address
+type DialAttempt struct {
+	IPv6     bool
+	Err      error // nil if connected or canceled
+	Canceled bool  // Abandoned because another address won the race
+}
+
+// SetSource makes the client's control connection and UDP media sockets
+// originate from src. Rotating sources across clients lifts the ~64k
+// ephemeral port limit of a single local address.
+func (c *Client) SetSource(src Source) {
+	c.source = src
+}
+
+// SetDialOptions sets the address family and racing used by Connect
+func (c *Client) SetDialOptions(opts DialOptions) {
+	c.dialOpts = opts
+}
+
+// SetResolver replaces the system resolver for the server hostname, e.g.
+// to cache lookups or spread connections across records
+func (c *Client) SetResolver(resolve Resolver) {
+	c.resolve = resolve
+}
+
+// DialAttempts returns the attempts made by the last Connect
+func (c *Client) DialAttempts() []DialAttempt {
+	return c.attempts
+}
+
+// hostPort returns the host:port of u, using defaultPort if u has none.
+// IPv6 literals are bracketed.
+func hostPort(u *url.URL, defaultPort int) string {
+	port := u.Port()
+	if port == "" {
+		port = strconv.Itoa(defaultPort)
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// dial resolves host and opens the control connection to one of its
+// addresses from the client's source
+func (c *Client) dial(host, port string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DialTimeout)
+	defer cancel()
+	c.attempts = c.attempts[:0]
+	
+	resolve := c.resolve
+	if resolve == nil {
+		resolve = net.DefaultResolver.LookupIPAddr
+	}
+	addrs, err := resolve(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrResolve, err)
+	}
+	addrs = c.usableAddrs(addrs)
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%w: no usable address for %s", ErrResolve, host)
+	}
+	
+	if c.dialOpts.HappyEyeballs > 0 {
+		return c.race(ctx, interleaveFamilies(addrs), port)
+	}
+	var firstErr error
+	for _, addr := range addrs {
+		conn, err := c.dialAddr(ctx, addr, port)
+		c.recordAttempt(addr, err, false)
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, firstErr
+}
+
+// race dials addrs Happy Eyeballs style: the next attempt starts when the
+// previous one fails or after the HappyEyeballs delay, whichever is first
+func (c *Client) race(ctx context.Context, addrs []net.IPAddr, port string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	
+	type result struct {
+		conn net.Conn
+		addr net.IPAddr
+		err  error
+	}
+	results := make(chan result, len(addrs))
+	next, pending := 0, 0
+	start := func() {
+		addr := addrs[next]
+		next++
+		pending++
+		go func() {
+			conn, err := c.dialAddr(ctx, addr, port)
+			results <- result{conn, addr, err}
+		}()
+	}
+	
+	start()
+	delay := time.NewTimer(c.dialOpts.HappyEyeballs)
+	defer delay.Stop()
+	var firstErr error
+	for pending > 0 {
+		select {
+		case <-delay.C:
+			if next < len(addrs) {
+				start()
+				delay.Reset(c.dialOpts.HappyEyeballs)
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				c.recordAttempt(res.addr, nil, false)
+				cancel()
+				for ; pending > 0; pending-- {
+					loser := <-results
+					if loser.conn != nil {
+						loser.conn.Close()
+					}
+					c.recordAttempt(loser.addr, loser.err, true)
+				}
+				return res.conn, nil
+			}
+			c.recordAttempt(res.addr, res.err, false)
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			if next < len(addrs) && ctx.Err() == nil {
+				start()
+				if !delay.Stop() {
+					select {
+					case <-delay.C:
+					default:
+					}
+				}
+				delay.Reset(c.dialOpts.HappyEyeballs)
+			}
+		}
+	}
+	return nil, firstErr
+}
+
+// dialAddr connects to one resolved address
+func (c *Client) dialAddr(ctx context.Context, addr net.IPAddr, port string) (net.Conn, error) {
+	d := &net.Dialer{Control: c.dialControl, KeepAlive: c.sockOpts.KeepAliveIdle}
+	if c.source.IP != nil {
+		d.LocalAddr = &net.TCPAddr{IP: c.source.IP}
+	}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(addr.String(), port))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sockOpts.tuneTCP(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// recordAttempt adds the outcome of a connection attempt to addr. An
+// attempt that lost the race counts as canceled unless it had already
+// failed on its own.
+func (c *Client) recordAttempt(addr net.IPAddr, err error, lost bool) {
+	attempt := DialAttempt{IPv6: addr.IP.To4() == nil, Err: err}
+	if lost && (err == nil || errors.Is(err, context.Canceled)) {
+		attempt.Err = nil
+		attempt.Canceled = true
+	}
+	c.attempts = append(c.attempts, attempt)
+}
+
+// usableAddrs keeps the addresses of the configured family that the
+// source address, if any, can reach
+func (c *Client) usableAddrs(addrs []net.IPAddr) []net.IPAddr {
+	family := c.dialOpts.Family
+	if c.source.IP != nil {
+		if c.source.IP.To4() != nil {
+			family = FamilyIPv4
+		} else {
+			family = FamilyIPv6
+		}
+	}
+	if family == FamilyAny {
+		return addrs
+	}
+	
+	// A new slice: addrs may be shared by a caching resolver
+	var usable []net.IPAddr
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == (family == FamilyIPv4) {
+			usable = append(usable, addr)
+		}
+	}
+	return usable
+}
+
+// interleaveFamilies orders addrs IPv6 first, alternating families
+func interleaveFamilies(addrs []net.IPAddr) []net.IPAddr {
+	var v6, v4 []net.IPAddr
+	for _, addr := range addrs {
+		if addr.IP.To4() == nil {
+			v6 = append(v6, addr)
+		} else {
+			v4 = append(v4, addr)
+		}
+	}
+	ordered := make([]net.IPAddr, 0, len(addrs))
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			ordered = append(ordered, v6[i])
+		}
+		if i < len(v4) {
+			ordered = append(ordered, v4[i])
+		}
+	}
+	return ordered
+}
+
+// listenUDP opens a UDP socket on an ephemeral port of the source address
+func (s Source) listenUDP() (net.PacketConn, error) {
+	addr := ":0"
+	if s.IP != nil {
+		addr = net.JoinHostPort(s.IP.String(), "0")
+	}
+	lc := net.ListenConfig{Control: s.control}
+	return lc.ListenPacket(context.Background(), "udp", addr)
+}
+
+// control binds a socket to the source device before it connects
+func (s Source) control(network, address string, c syscall.RawConn) error {
+	if s.Device == "" {
+		return nil
+	}
+	return bindToDevice(c, s.Device)
+}
@@ -0,0 +1,118 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// ErrResolve wraps errors resolving the server hostname
+var ErrResolve = errors.New("resolve failed")
+
+// ErrTLSResponse is returned when the server answers a request with a TLS
+// record, as a TLS-only port does
+var ErrTLSResponse = errors.New("server answered with TLS")
+
+// Failure categories, from ClassifyFailure
+const (
+	FailureDNS             = "dns"              // Hostname resolution failed
+	FailureConnectTimeout  = "connect_timeout"  // TCP connect timed out
+	FailureRefused         = "refused"          // TCP connect refused
+	FailureTLS             = "tls"              // TLS handshake failed or TLS where RTSP was expected
+	Failure4xx             = "rtsp_4xx"         // Request rejected with 4xx
+	Failure5xx             = "rtsp_5xx"         // Request failed with 5xx
+	FailureResponseTimeout = "response_timeout" // No response during the handshake
+	FailureControlTimeout  = "control_timeout"  // Request unanswered or unsent, or a message cut off, after PLAY
+	FailureStream          = "stream"           // Read error, reset or expiry after PLAY
+	FailureOther           = "other"            // Resets, closes and malformed responses during the handshake
+)
+
+// FailureCategories lists the categories in report order
+var FailureCategories = [...]string{
+	FailureDNS, FailureConnectTimeout, FailureRefused, FailureTLS, Failure4xx, Failure5xx,
+	FailureResponseTimeout, FailureControlTimeout, FailureStream, FailureOther,
+}
+
+// ClassifyFailure returns the category of an error from Connect or Run;
+// streaming tells whether the session had reached PLAY
+func ClassifyFailure(err error, streaming bool) string {
+	var dnsErr *net.DNSError
+	var statusErr *StatusError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, ErrResolve) || errors.As(err, &dnsErr):
+		return FailureDNS
+	case errors.Is(err, ErrTLSResponse) || errors.As(err, &recordErr) || errors.As(err, &alertErr) ||
+		errors.As(err, &certErr) || errors.As(err, &unknownAuthority):
+		return FailureTLS
+	case errors.As(err, &statusErr):
+		if statusErr.Code >= 500 {
+			return Failure5xx
+		}
+		return Failure4xx
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return FailureRefused
+	case errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout():
+		return FailureConnectTimeout
+	case streaming && errors.Is(err, ErrControlTimeout):
+		return FailureControlTimeout
+	case streaming:
+		return FailureStream
+	case errors.Is(err, ErrControlTimeout) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return FailureResponseTimeout
+	default:
+		return FailureOther
+	}
+}
+
+// Causes of a session the server ended after PLAY, from ClassifyTermination
+const (
+	TerminationReset   = "reset"      // Connection reset (RST)
+	TerminationClosed  = "closed"     // Connection closed (FIN)
+	TerminationError   = "rtsp_error" // A keepalive, PAUSE or seek answered with an error status
+	TerminationExpired = "expired"    // The server timed the session out
+	TerminationTimeout = "timeout"    // The server stopped answering
+	TerminationOther   = "other"
+)
+
+// TerminationCauses lists the causes in report order
+var TerminationCauses = [...]string{
+	TerminationReset, TerminationClosed, TerminationError, TerminationExpired, TerminationTimeout, TerminationOther,
+}
+
+// ClassifyTermination returns why the server ended a playing session,
+// from the error Run returned
+func ClassifyTermination(err error) string {
+	var statusErr *StatusError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrSessionExpired):
+		return TerminationExpired
+	case errors.As(err, &statusErr):
+		return TerminationError
+	case errors.Is(err, ErrControlTimeout):
+		return TerminationTimeout
+	case errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE):
+		return TerminationReset
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		return TerminationClosed
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return TerminationTimeout
+	default:
+		return TerminationOther
+	}
+}
+
+// Playing reports whether the session got as far as a successful PLAY
+func (c *Client) Playing() bool {
+	return !c.playTime.IsZero()
+}
@@ -0,0 +1,72 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TransportAuto plays over UDP and, if no RTP arrives within the fallback
+// timeout, tears the session down and plays over TCP interleaved, as
+// FFmpeg, VLC and GStreamer do
+const TransportAuto = "auto"
+
+// DefaultFallbackTimeout is how long a TransportAuto session waits for RTP
+// over UDP before falling back
+const DefaultFallbackTimeout = 5 * time.Second
+
+// errNoUDPMedia ends a TransportAuto session that got no RTP over UDP
+var errNoUDPMedia = errors.New("no RTP received over UDP")
+
+// SetFallbackTimeout sets how long a TransportAuto session waits for RTP
+// over UDP before it falls back to TCP
+func (c *Client) SetFallbackTimeout(d time.Duration) {
+	c.fallback = d
+}
+
+// FellBack reports whether the session fell back from UDP to TCP
+func (c *Client) FellBack() bool {
+	return c.fellBack
+}
+
+// fallbackTimer returns a channel that fires when UDP may be given up on,
+// and its stop function. The channel is nil, and never fires, unless the
+// transport is TransportAuto and the session has not fallen back yet.
+func (c *Client) fallbackTimer() (<-chan time.Time, func()) {
+	if !c.autoTransport || c.fellBack {
+		return nil, func() {}
+	}
+	d := c.fallback
+	if d <= 0 && c.player != nil {
+		d = c.player.FallbackTimeout
+	}
+	if d <= 0 {
+		d = DefaultFallbackTimeout
+	}
+	t := time.NewTimer(d)
+	return t.C, func() { t.Stop() }
+}
+
+// udpRejected reports whether err is a TransportAuto SETUP answered with
+// 461 Unsupported Transport, on which players retry over TCP as well
+func (c *Client) udpRejected(err error) bool {
+	var status *StatusError
+	return c.autoTransport && !c.fellBack && errors.As(err, &status) && status.Code == 461
+}
+
+// fallBack ends the UDP session and reconnects to play over TCP
+// interleaved. The aggregator counts the fallback.
+func (c *Client) fallBack() error {
+	c.transcript.add(TranscriptNote, "no RTP over UDP, falling back to TCP")
+	c.mu.Lock()
+	c.sendTeardown()
+	c.mu.Unlock()
+	c.transport = "tcp"
+	c.fellBack = true
+	c.aggregator.AddFallback()
+	if err := c.reconnect(c.url); err != nil {
+		return fmt.Errorf("TCP fallback: %w", err)
+	}
+	return nil
+}
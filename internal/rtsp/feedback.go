@@ -0,0 +1,162 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtcp"
+)
+
+// NACK limits
+const (
+	NACKTimeout = time.Second // A lost packet arriving later than this is not counted as repaired or recovered
+	MaxNACKGap  = 256         // Larger gaps are not NACKed (outage or stream restart)
+)
+
+// Feedback selects the RTCP feedback (RFC 4585) and BYE packets a client
+// sends in addition to receiver reports
+type Feedback struct {
+	NACK        bool          // NACK lost sequence numbers and watch for retransmissions
+	PLIInterval time.Duration // Request a keyframe on video tracks this often (0 = never)
+	Bye         bool          // Send RTCP BYE before TEARDOWN
+}
+
+// Enabled reports whether any feedback is sent
+func (f Feedback) Enabled() bool {
+	return f.NACK || f.PLIInterval > 0 || f.Bye
+}
+
+// missingSet tracks the sequence numbers a track has seen a gap for and
+// not yet received, for NACK repairs and RTX recovery
+type missingSet struct {
+	mu         sync.Mutex
+	pending    map[uint64]time.Time // SSRC<<16 | seq -> when the gap was seen
+	lastExpire time.Time
+}
+
+// newMissingSet creates an empty set
+func newMissingSet() *missingSet {
+	return &missingSet{pending: make(map[uint64]time.Time)}
+}
+
+// add records the given sequence numbers of ssrc as missing since now,
+// dropping entries older than NACKTimeout
+func (s *missingSet) add(ssrc uint32, seqs []uint16, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastExpire) > NACKTimeout {
+		for k, since := range s.pending {
+			if now.Sub(since) > NACKTimeout {
+				delete(s.pending, k)
+			}
+		}
+		s.lastExpire = now
+	}
+	for _, seq := range seqs {
+		s.pending[uint64(ssrc)<<16|uint64(seq)] = now
+	}
+}
+
+// resolve removes seq of ssrc from the set and returns how long it was
+// missing, or false if it was not missing or arrived after NACKTimeout
+func (s *missingSet) resolve(ssrc uint32, seq uint16, now time.Time) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := uint64(ssrc)<<16 | uint64(seq)
+	since, ok := s.pending[key]
+	if !ok {
+		return 0, false
+	}
+	delete(s.pending, key)
+	d := now.Sub(since)
+	return d, d <= NACKTimeout
+}
+
+// SetFeedback makes the client send RTCP feedback during PLAY: generic
+// NACKs for lost packets, periodic PLIs on video tracks, and a BYE when
+// the session is closed.
+func (c *Client) SetFeedback(f Feedback) {
+	c.feedback = f
+}
+
+// pliTicker returns the PLI channel and its stop function; the channel is
+// nil when PLIs are disabled
+func (c *Client) pliTicker() (<-chan time.Time, func()) {
+	if c.feedback.PLIInterval <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(c.feedback.PLIInterval)
+	return t.C, t.Stop
+}
+
+// feedbackRTP runs the NACK, RTX and PLI bookkeeping for a received
+// packet whose sequence number revealed lost missing packets before it
+func (c *Client) feedbackRTP(t *mediaTrack, ssrc uint32, seq uint16, lost uint64, now time.Time) {
+	if c.feedback.NACK || t.repaired {
+		c.trackMissing(t, ssrc, seq, lost, now)
+	}
+	if sent := t.pliSent.Load(); sent != 0 && t.video != nil && t.video.Stats().Keyframes > t.pliKeyframes.Load() {
+		if t.pliSent.CompareAndSwap(sent, 0) {
+			c.aggregator.AddPLIKeyframe(now.Sub(time.Unix(0, sent)))
+		}
+	}
+}
+
+// trackMissing matches the packet against the missing set, records the gap
+// in front of it and NACKs the gap if enabled
+func (c *Client) trackMissing(t *mediaTrack, ssrc uint32, seq uint16, lost uint64, now time.Time) {
+	if d, ok := t.missing.resolve(ssrc, seq, now); ok && c.feedback.NACK {
+		c.aggregator.AddNACKRepair(d)
+	}
+	if lost == 0 || lost > MaxNACKGap {
+		return
+	}
+
+	missing := make([]uint16, 0, lost)
+	for i := lost; i > 0; i-- {
+		missing = append(missing, seq-uint16(i))
+	}
+	t.missing.add(ssrc, missing, now)
+
+	if c.feedback.NACK {
+		pkt := append(rtcp.BuildReceiverReport(c.localSSRC, nil), rtcp.BuildNACK(c.localSSRC, ssrc, missing)...)
+		if c.sendRTCP(t, pkt) == nil {
+			c.aggregator.AddNACK(uint64(len(missing)))
+		}
+	}
+}
+
+// sendPLI asks the server for a keyframe on every video track that has
+// received media, and starts timing the keyframe's arrival
+func (c *Client) sendPLI() {
+	for _, t := range c.tracks {
+		ssrc := t.ssrc.Load()
+		if t.video == nil || ssrc == 0 {
+			continue
+		}
+		pkt := append(rtcp.BuildReceiverReport(c.localSSRC, nil), rtcp.BuildPLI(c.localSSRC, ssrc)...)
+		t.pliKeyframes.Store(t.video.Stats().Keyframes)
+		if c.sendRTCP(t, pkt) == nil {
+			t.pliSent.Store(time.Now().UnixNano())
+			c.aggregator.AddPLI()
+		}
+	}
+}
+
+// sendBye sends an RTCP BYE on every track. The caller must hold c.mu.
+func (c *Client) sendBye() {
+	if !c.feedback.Bye || len(c.tracks) == 0 {
+		return
+	}
+	sent := false
+	for _, t := range c.tracks {
+		pkt := append(rtcp.BuildReceiverReport(c.localSSRC, nil), rtcp.BuildBye(c.localSSRC)...)
+		if c.sendRTCP(t, pkt) == nil {
+			sent = true
+		}
+	}
+	if sent {
+		c.aggregator.AddBye()
+	}
+}
@@ -0,0 +1,442 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fuzzer limits
+const (
+	fuzzResponseTimeout = 2 * time.Second
+	fuzzCloseCheck      = 50 * time.Millisecond // Wait after a response to see if the server closes
+	maxFuzzFindings     = 20                    // Findings kept in FuzzResult; all are saved to the corpus
+)
+
+// Fuzz finding reasons
+const (
+	FuzzDropped     = "dropped"      // The server closed the connection without answering
+	FuzzServerError = "server-error" // The server answered 5xx
+)
+
+// fuzzRefusals are the 5xx statuses RTSP uses to refuse what it does not
+// support (Not Implemented, Version Not Supported, Option Not Supported);
+// they count as rejections
+var fuzzRefusals = map[int]bool{501: true, 505: true, 551: true}
+
+// FuzzFinding is a request that made the server misbehave
+type FuzzFinding struct {
+	Reason  string // FuzzDropped or FuzzServerError
+	Status  int    // FuzzServerError only
+	Request []byte
+	File    string // Corpus file, empty if not saved
+}
+
+// FuzzResult summarizes a MalformedRequests client's session
+type FuzzResult struct {
+	Requests     int
+	Rejected     int           // Answered with 4xx, 501, 505 or 551
+	ServerErrors int           // Answered with any other 5xx
+	Dropped      int           // Connection closed without an answer
+	Timeouts     int           // No answer within 2s
+	Findings     []FuzzFinding // The first maxFuzzFindings dropped and 5xx requests
+}
+
+// Fuzz returns the fuzzing summary, or false if the client is of another
+// type
+func (bc *BadClient) Fuzz() (FuzzResult, bool) {
+	return bc.fuzz, bc.clientType == MalformedRequests
+}
+
+// SetFuzzCorpus makes the MalformedRequests client save every request that
+// the server dropped the connection on or answered 5xx to into dir, one
+// raw request per file, named by reason and content hash
+func (bc *BadClient) SetFuzzCorpus(dir string) {
+	bc.corpusDir = dir
+}
+
+// fuzzRequest is a request being mutated, kept as parts so mutations stay
+// grammar-aware
+type fuzzRequest struct {
+	method  string
+	uri     string
+	version string
+	headers []string // Raw header lines, may contain folding
+	body    string
+	length  bool // Add Content-Length for the body
+}
+
+// bytes serializes the request
+func (r *fuzzRequest) bytes() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\r\n", r.method, r.uri, r.version)
+	for _, h := range r.headers {
+		b.WriteString(h)
+		b.WriteString("\r\n")
+	}
+	if r.length && r.body != "" {
+		fmt.Fprintf(&b, "Content-Length: %d\r\n", len(r.body))
+	}
+	b.WriteString("\r\n")
+	b.WriteString(r.body)
+	return []byte(b.String())
+}
+
+// setHeader replaces the first header called name, or adds it
+func (r *fuzzRequest) setHeader(name, value string) {
+	line := name + ": " + value
+	for i, h := range r.headers {
+		if n, _, ok := strings.Cut(h, ":"); ok && strings.EqualFold(n, name) {
+			r.headers[i] = line
+			return
+		}
+	}
+	r.headers = append(r.headers, line)
+}
+
+// fuzzSDP is the valid description ANNOUNCE bodies are mutated from
+var fuzzSDP = []string{
+	"v=0",
+	"o=- 0 0 IN IP4 127.0.0.1",
+	"s=fuzz",
+	"c=IN IP4 0.0.0.0",
+	"t=0 0",
+	"m=video 0 RTP/AVP 96",
+	"a=rtpmap:96 H264/90000",
+	"a=fmtp:96 packetization-mode=1;sprop-parameter-sets=Z0IAH5WoFAFuQA==,aM48gA==",
+	"a=control:trackID=0",
+}
+
+// baseFuzzRequest returns a valid request for a random method
+func (bc *BadClient) baseFuzzRequest(cseq int) *fuzzRequest {
+	r := &fuzzRequest{uri: bc.url, version: "RTSP/1.0", length: true}
+	r.headers = []string{fmt.Sprintf("CSeq: %d", cseq), "User-Agent: wink-rtsp-bench"}
+	switch bc.rng.Intn(7) {
+	case 0:
+		r.method, r.uri = "OPTIONS", "*"
+	case 1:
+		r.method = "DESCRIBE"
+		r.headers = append(r.headers, "Accept: application/sdp")
+	case 2:
+		r.method = "SETUP"
+		r.uri += "/trackID=0"
+		r.headers = append(r.headers, "Transport: RTP/AVP/TCP;unicast;interleaved=0-1")
+	case 3:
+		r.method = "PLAY"
+		r.headers = append(r.headers, "Session: 12345678", "Range: npt=0.000-")
+	case 4:
+		r.method = "ANNOUNCE"
+		r.headers = append(r.headers, "Content-Type: application/sdp")
+		r.body = strings.Join(fuzzSDP, "\r\n") + "\r\n"
+	case 5:
+		r.method = "SET_PARAMETER"
+		r.headers = append(r.headers, "Content-Type: text/parameters")
+		r.body = "x: y\r\n"
+	default:
+		r.method = "GET_PARAMETER"
+	}
+	return r
+}
+
+// fuzzMutations change one part of a request. Each keeps the rest valid,
+// so the server's handling of that part is what gets exercised.
+var fuzzMutations = []func(bc *BadClient, r *fuzzRequest){
+	mutateMethod,
+	mutateURI,
+	mutateVersion,
+	mutateCSeq,
+	mutateFolding,
+	mutateHeaderSize,
+	mutateHeaderSyntax,
+	mutateTransport,
+	mutateSDP,
+	mutateChunked,
+	mutateLength,
+}
+
+// pickFuzz returns one of choices
+func (bc *BadClient) pickFuzz(choices ...string) string {
+	return choices[bc.rng.Intn(len(choices))]
+}
+
+// mutateMethod replaces the method with a near miss or nonsense
+func mutateMethod(bc *BadClient, r *fuzzRequest) {
+	r.method = bc.pickFuzz(
+		strings.ToLower(r.method),
+		"OpTiOnS",
+		r.method+"X",
+		"SET PARAMETER",
+		"GET",
+		"",
+		r.method+"\x00",
+		strings.Repeat("A", 1000+bc.rng.Intn(64000)),
+	)
+}
+
+// mutateURI replaces the request URI
+func mutateURI(bc *BadClient, r *fuzzRequest) {
+	r.uri = bc.pickFuzz(
+		"",
+		"rtsp://",
+		"rtsp://[::1",
+		"rtsp://127.0.0.1:99999/live",
+		"/relative/path",
+		"../../../../etc/passwd",
+		bc.url+"/%00%ff%",
+		bc.url+"?"+strings.Repeat("a=b&", 2000),
+		bc.url+strings.Repeat("/path", 1000),
+		"*",
+	)
+}
+
+// mutateVersion replaces the protocol version
+func mutateVersion(bc *BadClient, r *fuzzRequest) {
+	r.version = bc.pickFuzz("RTSP/2.0", "RTSP/1.1", "HTTP/1.1", "rtsp/1.0", "RTSP/99999999999.0", "RTSP/1.0 extra", "")
+}
+
+// mutateCSeq makes the CSeq oversized, invalid, missing or duplicated
+func mutateCSeq(bc *BadClient, r *fuzzRequest) {
+	switch bc.rng.Intn(4) {
+	case 0:
+		kept := r.headers[:0]
+		for _, h := range r.headers {
+			if !strings.HasPrefix(h, "CSeq:") {
+				kept = append(kept, h)
+			}
+		}
+		r.headers = kept
+	case 1:
+		r.headers = append(r.headers, fmt.Sprintf("CSeq: %d", bc.rng.Intn(1000)))
+	default:
+		r.setHeader("CSeq", bc.pickFuzz(
+			"99999999999999999999999999",
+			strconv.FormatUint(1<<64-1, 10),
+			"-1",
+			"0x10",
+			"",
+			"1 2",
+			"1\x00",
+			strings.Repeat("9", 10000),
+		))
+	}
+}
+
+// mutateFolding continues a header on the next line (obsolete RFC 2616
+// folding), or starts the headers with a continuation line
+func mutateFolding(bc *BadClient, r *fuzzRequest) {
+	i := bc.rng.Intn(len(r.headers))
+	name, value, _ := strings.Cut(r.headers[i], ":")
+	value = strings.TrimSpace(value)
+	split := 0
+	if len(value) > 0 {
+		split = bc.rng.Intn(len(value))
+	}
+	switch bc.rng.Intn(3) {
+	case 0:
+		r.headers[i] = name + ": " + value[:split] + "\r\n " + value[split:]
+	case 1:
+		r.headers[i] = name + ":\r\n\t" + value
+	default:
+		r.headers = append([]string{" folded-before-first-header"}, r.headers...)
+	}
+}
+
+// mutateHeaderSize adds a huge header value or very many headers
+func mutateHeaderSize(bc *BadClient, r *fuzzRequest) {
+	if bc.rng.Intn(2) == 0 {
+		r.headers = append(r.headers, "User-Agent: "+strings.Repeat("A", 10000+bc.rng.Intn(100000)))
+		return
+	}
+	for i := 0; i < 1000+bc.rng.Intn(9000); i++ {
+		r.headers = append(r.headers, fmt.Sprintf("X-Header-%d: value", i))
+	}
+}
+
+// mutateHeaderSyntax adds a header that is not name: value
+func mutateHeaderSyntax(bc *BadClient, r *fuzzRequest) {
+	r.headers = append(r.headers, bc.pickFuzz(
+		"X-No-Colon",
+		": no-name",
+		"X Space: value",
+		"X-Test: 你好世界",
+		"X-Null: \x00\x00\x00",
+		"X-Bare-CR: a\rb",
+		"X-Control: \x01\x02\x7f",
+	))
+}
+
+// mutateTransport turns the request into a SETUP with a broken Transport
+func mutateTransport(bc *BadClient, r *fuzzRequest) {
+	r.method = "SETUP"
+	r.setHeader("Transport", bc.pickFuzz(
+		"RTP/AVP;unicast;client_port=65535-65536",
+		"RTP/AVP;unicast;client_port=0-0",
+		"RTP/AVP;unicast;client_port=9000-8000",
+		"RTP/AVP;unicast;client_port=a-b",
+		"RTP/AVP;unicast;client_port="+strings.Repeat("9", 100),
+		"RTP/AVP/TCP;interleaved=255-256",
+		"RTP/AVP/TCP;interleaved=-1",
+		"RTP/SAVPF/XYZ;unicast",
+		"RTP/AVP;multicast;destination=255.255.255.255;ttl=999",
+		"RTP/AVP;unicast;mode=\"PLAY",
+		";;;;;;",
+		"",
+		strings.Repeat("RTP/AVP;unicast;client_port=5000-5001,", 1000),
+	))
+}
+
+// mutateSDP turns the request into an ANNOUNCE with a broken description
+func mutateSDP(bc *BadClient, r *fuzzRequest) {
+	r.method = "ANNOUNCE"
+	r.setHeader("Content-Type", "application/sdp")
+	lines := append([]string(nil), fuzzSDP...)
+	switch bc.rng.Intn(8) {
+	case 0:
+		lines = lines[1:] // No v=
+	case 1:
+		lines[5] = "m=video 99999999 RTP/AVP 96"
+	case 2:
+		lines[5] = "m=video 0 RTP/AVP" + strings.Repeat(" 96", 1000)
+	case 3:
+		lines[6] = "a=rtpmap:96 H264/0"
+	case 4:
+		lines[7] = "a=fmtp:96 sprop-parameter-sets=!!!!,,,," + strings.Repeat("A", 10000)
+	case 5:
+		lines[3] = "c=IN IP4 999.999.999.999/999"
+	case 6:
+		lines = append(lines, "no equals sign", "=", "x")
+	default:
+		for i := 0; i < 10000; i++ {
+			lines = append(lines, fmt.Sprintf("a=x-attr-%d:value", i))
+		}
+	}
+	r.body = strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// mutateChunked sends the body with HTTP chunked encoding, which RTSP
+// does not have, optionally alongside a Content-Length or with a bogus
+// chunk size
+func mutateChunked(bc *BadClient, r *fuzzRequest) {
+	if r.body == "" {
+		r.method = "SET_PARAMETER"
+		r.body = "x: y\r\n"
+	}
+	size := strconv.FormatInt(int64(len(r.body)), 16)
+	switch bc.rng.Intn(3) {
+	case 0:
+		r.length = false
+	case 1:
+		size = "fffffffffffffff"
+		r.length = false
+	}
+	r.headers = append(r.headers, "Transfer-Encoding: chunked")
+	r.body = size + "\r\n" + r.body + "\r\n0\r\n\r\n"
+}
+
+// mutateLength sends an invalid Content-Length
+func mutateLength(bc *BadClient, r *fuzzRequest) {
+	r.length = false
+	r.headers = append(r.headers, "Content-Length: "+bc.pickFuzz("-1", "abc", "99999999999999999999", "1e3", " 4", ""))
+}
+
+// runMalformedRequests fuzzes the server with mutated requests, each a
+// valid request with one to three grammar-aware mutations. Requests the
+// server drops the connection on or answers 5xx to are findings.
+func (bc *BadClient) runMalformedRequests(ctx context.Context) error {
+	var reader *bufio.Reader
+	defer func() {
+		if bc.conn != nil {
+			bc.conn.Close()
+		}
+	}()
+	
+	for cseq := 1; ctx.Err() == nil; cseq++ {
+		if bc.conn == nil {
+			if err := bc.connect(); err != nil {
+				return err
+			}
+			reader = bufio.NewReader(bc.conn)
+		}
+		
+		req := bc.baseFuzzRequest(cseq)
+		for n := 1 + bc.rng.Intn(3); n > 0; n-- {
+			fuzzMutations[bc.rng.Intn(len(fuzzMutations))](bc, req)
+		}
+		if !bc.sendFuzz(reader, req.bytes()) {
+			bc.conn.Close()
+			bc.conn = nil
+		}
+		
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(200+bc.rng.Intn(800)) * time.Millisecond):
+		}
+	}
+	return ctx.Err()
+}
+
+// sendFuzz sends one fuzzed request and classifies the reaction. It
+// returns false if the connection can no longer be used.
+func (bc *BadClient) sendFuzz(reader *bufio.Reader, request []byte) bool {
+	res := &bc.fuzz
+	res.Requests++
+	bc.conn.SetDeadline(time.Now().Add(fuzzResponseTimeout))
+	if _, err := bc.conn.Write(request); err != nil {
+		res.Dropped++
+		bc.fuzzFinding(FuzzDropped, 0, request)
+		return false
+	}
+	
+	resp, err := readRawResponse(reader)
+	if err != nil {
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() {
+			res.Timeouts++
+			bc.timedOut()
+			return false // The server may still be reading a body
+		}
+		res.Dropped++
+		bc.fuzzFinding(FuzzDropped, 0, request)
+		return false
+	}
+	switch {
+	case resp.status >= 500 && !fuzzRefusals[resp.status]:
+		res.ServerErrors++
+		bc.fuzzFinding(FuzzServerError, resp.status, request)
+	case resp.status >= 400:
+		res.Rejected++
+	}
+	
+	// Closing after answering is a legitimate defense, not a finding
+	bc.conn.SetReadDeadline(time.Now().Add(fuzzCloseCheck))
+	if _, err := reader.Peek(1); err != nil {
+		var ne net.Error
+		return errors.As(err, &ne) && ne.Timeout()
+	}
+	return true
+}
+
+// fuzzFinding records a finding and saves it to the corpus
+func (bc *BadClient) fuzzFinding(reason string, status int, request []byte) {
+	finding := FuzzFinding{Reason: reason, Status: status, Request: request}
+	if bc.corpusDir != "" {
+		h := fnv.New64a()
+		h.Write(request)
+		file := filepath.Join(bc.corpusDir, fmt.Sprintf("%s-%016x.rtsp", reason, h.Sum64()))
+		if err := os.MkdirAll(bc.corpusDir, 0o755); err == nil && os.WriteFile(file, request, 0o644) == nil {
+			finding.File = file
+		}
+	}
+	if len(bc.fuzz.Findings) < maxFuzzFindings {
+		bc.fuzz.Findings = append(bc.fuzz.Findings, finding)
+	}
+}
@@ -0,0 +1,58 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"strings"
+	"time"
+)
+
+// Hop describes the nodes a response came from, as its Via and Server
+// headers name them
+type Hop struct {
+	Method    string        // Request method, or MethodKeepAlive
+	Code      int           // Status code
+	Via       []string      // Via entries in header order, nearest the origin first
+	Server    string        // Server header, empty if none
+	Redirects int           // Redirects the session followed before the request; each may lead to another node
+	Latency   time.Duration // From writing the request to reading the full response
+}
+
+// HopRecorder checks which nodes answer a client, for proxy chains and
+// load balancers
+type HopRecorder interface {
+	// RecordHop is called once per response received
+	RecordHop(hop Hop)
+}
+
+// SetHopRecorder reports the Via and Server headers of every response the
+// client receives to r
+func (c *Client) SetHopRecorder(r HopRecorder) {
+	c.hops = r
+}
+
+// recordHop reports the nodes resp to req came from
+func (c *Client) recordHop(req *request, resp string, latency time.Duration) {
+	if c.hops == nil || resp == "" {
+		return
+	}
+	method := req.method
+	if req.keepAlive {
+		method = MethodKeepAlive
+	}
+	var via []string
+	for _, header := range c.extractHeaders(resp, "Via") {
+		for _, entry := range strings.Split(header, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				via = append(via, entry)
+			}
+		}
+	}
+	c.hops.RecordHop(Hop{
+		Method:    method,
+		Code:      responseStatus(resp, nil),
+		Via:       via,
+		Server:    c.extractHeader(resp, "Server"),
+		Redirects: c.redirects + c.serverRedirects,
+		Latency:   latency,
+	})
+}
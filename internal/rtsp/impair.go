@@ -0,0 +1,93 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/impair"
+)
+
+// SetImpairment makes the client emulate a bad network: received RTP is
+// dropped, delayed, duplicated and reordered according to m before it is
+// analyzed, and RTSP requests are held back by m.RequestDelay. RTCP
+// receiver reports then tell the server about the emulated loss.
+func (c *Client) SetImpairment(m impair.Model) {
+	c.impairment = m
+}
+
+// startImpairment runs each track's impairer, created on the first call,
+// delivering packets until ctx is done. From then on the impairer owns the
+// track's packet processing. The returned function waits for the
+// impairers to stop and flushes their tracks' counts; call it after the
+// readers pushing to them are done.
+func (c *Client) startImpairment(ctx context.Context) (wait func()) {
+	if !c.impairment.Enabled() {
+		return func() {}
+	}
+	tracks := c.tracks
+	var waits []func()
+	for _, t := range tracks {
+		t := t
+		if t.impairer == nil {
+			rng := rand.New(rand.NewSource(c.rng.Int63()))
+			t.impairer = impair.New(c.impairment, rng, func(data []byte) {
+				c.processRTPPacket(t, data)
+			})
+		}
+		waits = append(waits, t.impairer.Start(ctx))
+	}
+	return func() {
+		for i, t := range tracks {
+			waits[i]()
+			t.counts.Flush()
+		}
+	}
+}
+
+// receiveRTP passes a received RTP packet to the track, through its
+// impairer if it has one
+func (c *Client) receiveRTP(t *mediaTrack, data []byte) {
+	if t.impairer != nil {
+		t.impairer.Push(data)
+		return
+	}
+	c.processRTPPacket(t, data)
+}
+
+// flushCounts flushes the batched counts of the tracks this goroutine
+// processes; impaired tracks flush from their impairer
+func (c *Client) flushCounts(tracks ...*mediaTrack) {
+	for _, t := range tracks {
+		if t.impairer == nil {
+			t.counts.Flush()
+		}
+	}
+}
+
+// delayRequest holds an outgoing RTSP request back by the impairment
+// request delay
+func (c *Client) delayRequest() {
+	if c.impairment.RequestDelay > 0 {
+		time.Sleep(c.impairment.RequestDelay)
+	}
+}
+
+// reportImpairment adds what the impairers did to the aggregator
+func (c *Client) reportImpairment() {
+	var total impair.Counts
+	impaired := false
+	for _, t := range c.tracks {
+		if t.impairer != nil {
+			counts := t.impairer.Counts()
+			total.Dropped += counts.Dropped
+			total.Duplicated += counts.Duplicated
+			total.Reordered += counts.Reordered
+			impaired = true
+		}
+	}
+	if impaired {
+		c.aggregator.AddImpairment(total.Dropped, total.Duplicated, total.Reordered)
+	}
+}
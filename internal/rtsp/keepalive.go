@@ -0,0 +1,74 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Keepalive retry defaults: a keepalive is tried up to three times, one
+// and then two seconds apart, before the session is given up
+const (
+	DefaultKeepAliveAttempts = 3
+	DefaultKeepAliveBackoff  = time.Second
+)
+
+// SetKeepAliveRetry sets how many times a failing keepalive is tried
+// before the session fails (1 = no retry), and the wait before the first
+// retry, doubled for each one after. Zero keeps the default.
+func (c *Client) SetKeepAliveRetry(attempts int, backoff time.Duration) {
+	c.keepAliveAttempts = attempts
+	c.keepAliveBackoff = backoff
+}
+
+// keepAlive refreshes the session, retrying keepalives that time out or
+// get a 5xx until one succeeds or the attempts run out. Other errors, such
+// as a closed connection or 454 Session Not Found, are not retried: the
+// session is gone either way.
+func (c *Client) keepAlive(ctx context.Context) error {
+	attempts := c.keepAliveAttempts
+	if attempts <= 0 {
+		attempts = DefaultKeepAliveAttempts
+	}
+	backoff := c.keepAliveBackoff
+	if backoff <= 0 {
+		backoff = DefaultKeepAliveBackoff
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = c.sendKeepAlive(); err == nil {
+			if attempt > 1 {
+				c.aggregator.AddKeepAliveRecovered()
+			}
+			return nil
+		}
+		if attempt >= attempts || !retryableKeepAlive(err) {
+			return err
+		}
+		
+		c.transcript.add(TranscriptNote, fmt.Sprintf("keepalive failed (%v), retrying in %v", err, backoff))
+		wait := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return err
+		case <-wait.C:
+		}
+		backoff *= 2
+		c.aggregator.AddKeepAliveRetry()
+	}
+}
+
+// retryableKeepAlive reports whether a keepalive failed in a way a retry
+// may get past: no response in time, or a server error. A write that timed
+// out may have left half a request on the connection, so it is not retried.
+func retryableKeepAlive(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code >= 500
+	}
+	return errors.Is(err, errNoResponse)
+}
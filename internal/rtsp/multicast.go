@@ -0,0 +1,114 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+)
+
+// TransportMulticast selects multicast RTP: the server announces a group
+// in the SETUP response and the client joins it
+const TransportMulticast = "multicast"
+
+// GroupTracker follows the multicast groups clients join, for per-group
+// statistics. Groups are named "ip:port" after their RTP address.
+type GroupTracker interface {
+	// Join is called when a track joins group. The track's packet, byte
+	// and loss counts are also added to the returned aggregator (nil = none).
+	Join(group string) *rtp.Aggregator
+	
+	// Leave is called when the track leaves group
+	Leave(group string)
+}
+
+// SetGroupTracker reports the multicast groups the client joins to g
+func (c *Client) SetGroupTracker(g GroupTracker) {
+	c.groups = g
+}
+
+// datagramMedia reports whether media arrives on per-track UDP sockets
+// (unicast or multicast) rather than the RTSP connection
+func (c *Client) datagramMedia() bool {
+	return c.transport == "udp" || c.transport == TransportMulticast
+}
+
+// joinGroup joins the multicast group announced for a track: destination
+// and port from the SETUP Transport header, falling back to the SDP c= line
+// and m= port. RTCP uses the next port unless one was announced.
+func (c *Client) joinGroup(t *mediaTrack) error {
+	ip := t.destination
+	if ip == nil {
+		addr := t.media.Connection
+		if addr == "" && c.sdp != nil {
+			addr = c.sdp.Connection
+		}
+		ip = net.ParseIP(addr)
+	}
+	port, rtcpPort := t.groupPorts[0], t.groupPorts[1]
+	if port == 0 {
+		port = t.media.Port
+	}
+	if rtcpPort == 0 {
+		rtcpPort = port + 1
+	}
+	if ip == nil || !ip.IsMulticast() || port == 0 {
+		return fmt.Errorf("no multicast group announced for track %d", t.index)
+	}
+
+	var ifi *net.Interface
+	if c.source.Device != "" {
+		var err error
+		if ifi, err = net.InterfaceByName(c.source.Device); err != nil {
+			return fmt.Errorf("multicast interface: %w", err)
+		}
+	}
+	network := "udp4"
+	if ip.To4() == nil {
+		network = "udp6"
+	}
+
+	group := &net.UDPAddr{IP: ip, Port: port}
+	rtpConn, err := net.ListenMulticastUDP(network, ifi, group)
+	if err != nil {
+		return fmt.Errorf("failed to join %s: %w", group, err)
+	}
+	rtpConn.SetReadBuffer(2 * 1024 * 1024)
+	rtcpConn, err := net.ListenMulticastUDP(network, ifi, &net.UDPAddr{IP: ip, Port: rtcpPort})
+	if err != nil {
+		rtpConn.Close()
+		return fmt.Errorf("failed to join %s RTCP port: %w", group, err)
+	}
+	for _, conn := range []net.PacketConn{rtpConn, rtcpConn} {
+		if err := c.sockOpts.tuneUDP(conn); err != nil {
+			rtpConn.Close()
+			rtcpConn.Close()
+			return fmt.Errorf("failed to tune %s socket: %w", group, err)
+		}
+	}
+
+	t.rtpConn = rtpConn
+	t.rtcpConn = rtcpConn
+	t.group = group
+	t.groupRTCP = rtcpPort
+	if c.groups != nil {
+		if agg := c.groups.Join(group.String()); agg != nil {
+			t.counts.Tee(agg)
+		}
+	}
+	return nil
+}
+
+// leaveGroups reports the tracks' groups as left; the sockets themselves
+// are closed with the tracks
+func (c *Client) leaveGroups() {
+	if c.groups == nil {
+		return
+	}
+	for _, t := range c.tracks {
+		if t.group != nil {
+			c.groups.Leave(t.group.String())
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/sdp"
+)
+
+// ONVIFBackchannel is the Require tag that asks an ONVIF server for the
+// audio backchannel (ONVIF Streaming Specification 5.3)
+const ONVIFBackchannel = "www.onvif.org/ver20/backchannel"
+
+// BackchannelInterval is the audio sent per backchannel packet
+const BackchannelInterval = 20 * time.Millisecond
+
+// SetBackchannel makes the client ask for the ONVIF audio backchannel with
+// Require: www.onvif.org/ver20/backchannel on DESCRIBE, SETUP and PLAY, set
+// up the backchannel tracks the SDP offers, and send silence on them
+// during playback. Servers without backchannel support answer 551 Option
+// Not Supported.
+func (c *Client) SetBackchannel(enabled bool) {
+	c.backchannel = enabled
+}
+
+// requireBackchannel adds the Require header to a handshake request's
+// headers when the backchannel is enabled
+func (c *Client) requireBackchannel(headers map[string]string) {
+	if c.backchannel {
+		headers["Require"] = ONVIFBackchannel
+	}
+}
+
+// backchannelSender generates the RTP stream of a backchannel track:
+// silence in the track's first payload format
+type backchannelSender struct {
+	pt        uint8
+	ssrc      uint32
+	seq       uint16
+	timestamp uint32
+	samples   uint32 // Per packet
+	payload   []byte
+	started   bool
+}
+
+// newBackchannelSender returns the sender of a backchannel media, or nil
+// for other media
+func newBackchannelSender(media sdp.Media) *backchannelSender {
+	if !media.IsBackchannel() || len(media.PayloadTypes) == 0 {
+		return nil
+	}
+	rate := media.ClockRate()
+	if rate <= 0 {
+		rate = 8000
+	}
+	samples := uint32(rate) * uint32(BackchannelInterval/time.Millisecond) / 1000
+
+	// G.711 silence is the encoded zero level; other codecs get zeros
+	payload := make([]byte, samples)
+	switch strings.ToUpper(media.Codec()) {
+	case "PCMU":
+		for i := range payload {
+			payload[i] = 0xFF
+		}
+	case "PCMA":
+		for i := range payload {
+			payload[i] = 0xD5
+		}
+	}
+	return &backchannelSender{
+		pt:        uint8(media.PayloadTypes[0]),
+		ssrc:      rand.Uint32(),
+		seq:       uint16(rand.Uint32()),
+		timestamp: rand.Uint32(),
+		samples:   samples,
+		payload:   payload,
+	}
+}
+
+// next returns the next RTP packet, the first one with the marker bit
+func (s *backchannelSender) next() []byte {
+	pkt := make([]byte, 12+len(s.payload))
+	pkt[0] = 0x80
+	pkt[1] = s.pt
+	if !s.started {
+		pkt[1] |= 0x80
+		s.started = true
+	}
+	binary.BigEndian.PutUint16(pkt[2:4], s.seq)
+	binary.BigEndian.PutUint32(pkt[4:8], s.timestamp)
+	binary.BigEndian.PutUint32(pkt[8:12], s.ssrc)
+	copy(pkt[12:], s.payload)
+	s.seq++
+	s.timestamp += s.samples
+	return pkt
+}
+
+// backchannelTicker returns the channel that paces backchannel packets and
+// its stop function. The channel is nil, and never fires, without a
+// backchannel track or on multicast.
+func (c *Client) backchannelTicker() (<-chan time.Time, func()) {
+	if c.transport == TransportMulticast {
+		return nil, func() {}
+	}
+	for _, t := range c.tracks {
+		if t.send != nil {
+			ticker := time.NewTicker(BackchannelInterval)
+			return ticker.C, ticker.Stop
+		}
+	}
+	return nil, func() {}
+}
+
+// sendBackchannel sends one packet on every backchannel track, over the
+// track's interleaved channel or to the server's RTP port
+func (c *Client) sendBackchannel() error {
+	var firstErr error
+	for _, t := range c.tracks {
+		if t.send == nil {
+			continue
+		}
+		pkt := t.send.next()
+		var err error
+		if c.transport == "udp" {
+			if t.serverRTP == 0 || c.serverIP == nil {
+				continue
+			}
+			err = c.writeUDP(t.rtpConn, pkt, &net.UDPAddr{IP: c.serverIP, Port: t.serverRTP})
+		} else {
+			frame := make([]byte, 4+len(pkt))
+			frame[0] = '$'
+			frame[1] = byte(t.rtpChannel)
+			binary.BigEndian.PutUint16(frame[2:4], uint16(len(pkt)))
+			copy(frame[4:], pkt)
+			err = c.post(frame)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		c.aggregator.AddBackchannel(len(pkt))
+	}
+	return firstErr
+}
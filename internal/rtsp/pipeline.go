@@ -0,0 +1,242 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Parser probe timing
+const (
+	probeChunkGap        = 20 * time.Millisecond // Between the pieces of a split request, so they leave as separate segments
+	probeResponseTimeout = 2 * time.Second
+)
+
+// ParserProbeResult summarizes a MalformedPipeline client's session
+type ParserProbeResult struct {
+	Probes     int // Probes sent
+	Requests   int // Requests in them the server should answer
+	Answered   int
+	Disordered int // Pipelined responses that came back out of CSeq order
+	Smuggled   int // Requests hidden in a body that the server answered anyway
+	Closed     int // Probes the server closed the connection on
+}
+
+// ParserProbes returns the probe summary, or false if the client is of
+// another type
+func (bc *BadClient) ParserProbes() (ParserProbeResult, bool) {
+	return bc.probes, bc.clientType == MalformedPipeline
+}
+
+// parserProbe is a request sequence written in pieces
+type parserProbe struct {
+	chunks   []string // Written separately with probeChunkGap in between
+	expect   []int    // CSeqs that should be answered, in order
+	smuggled []int    // CSeqs inside a body, which must not be answered
+}
+
+// runMalformedPipeline probes the server's request parser: many requests
+// pipelined in one write, Content-Length values that disagree with the
+// body, and requests split across writes at awkward points. The connection
+// is reopened after any probe that was not fully answered, since the
+// server may still be waiting for a body.
+func (bc *BadClient) runMalformedPipeline(ctx context.Context) error {
+	res := &bc.probes
+	var reader *bufio.Reader
+	defer func() {
+		if bc.conn != nil {
+			bc.conn.Close()
+		}
+	}()
+	
+	cseq := 1
+	for ctx.Err() == nil {
+		if bc.conn == nil {
+			if err := bc.connect(); err != nil {
+				return err
+			}
+			reader = bufio.NewReader(bc.conn)
+		}
+		
+		var probe parserProbe
+		switch bc.rng.Intn(3) {
+		case 0:
+			probe = bc.pipelineProbe(&cseq)
+		case 1:
+			probe = bc.lengthProbe(&cseq)
+		default:
+			probe = bc.splitProbe(&cseq)
+		}
+		res.Probes++
+		res.Requests += len(probe.expect)
+		
+		complete, err := bc.sendProbe(reader, probe)
+		if err != nil {
+			res.Closed++
+		}
+		if !complete {
+			bc.conn.Close()
+			bc.conn = nil
+		}
+		
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(200+bc.rng.Intn(800)) * time.Millisecond):
+		}
+	}
+	return ctx.Err()
+}
+
+// sendProbe writes the probe and reads responses until every expected CSeq
+// is answered (complete) or probeResponseTimeout passes. err is set if the
+// server closed the connection.
+func (bc *BadClient) sendProbe(reader *bufio.Reader, probe parserProbe) (bool, error) {
+	res := &bc.probes
+	for i, chunk := range probe.chunks {
+		if i > 0 {
+			time.Sleep(probeChunkGap)
+		}
+		bc.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err := bc.conn.Write([]byte(chunk)); err != nil {
+			return false, err
+		}
+	}
+	
+	pending := make(map[int]bool, len(probe.expect))
+	for _, n := range probe.expect {
+		pending[n] = true
+	}
+	smuggled := make(map[int]bool, len(probe.smuggled))
+	for _, n := range probe.smuggled {
+		smuggled[n] = true
+	}
+	
+	last := 0
+	bc.conn.SetReadDeadline(time.Now().Add(probeResponseTimeout))
+	for len(pending) > 0 {
+		resp, err := readRawResponse(reader)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				bc.timedOut()
+				return false, nil
+			}
+			return false, err
+		}
+		switch {
+		case pending[resp.cseq]:
+			delete(pending, resp.cseq)
+			res.Answered++
+			if resp.cseq < last {
+				res.Disordered++
+			}
+			last = resp.cseq
+		case smuggled[resp.cseq]:
+			res.Smuggled++
+		}
+	}
+	return true, nil
+}
+
+// pipelineProbe writes 5-50 OPTIONS and DESCRIBE requests at once
+func (bc *BadClient) pipelineProbe(cseq *int) parserProbe {
+	var probe parserProbe
+	var b strings.Builder
+	for n := 5 + bc.rng.Intn(46); n > 0; n-- {
+		if n%2 == 0 {
+			fmt.Fprintf(&b, "OPTIONS * RTSP/1.0\r\nCSeq: %d\r\n\r\n", *cseq)
+		} else {
+			fmt.Fprintf(&b, "DESCRIBE %s RTSP/1.0\r\nCSeq: %d\r\nAccept: application/sdp\r\n\r\n", bc.url, *cseq)
+		}
+		probe.expect = append(probe.expect, *cseq)
+		*cseq++
+	}
+	probe.chunks = []string{b.String()}
+	return probe
+}
+
+// lengthProbe sends a SET_PARAMETER whose Content-Length disagrees with
+// its body, followed by an OPTIONS. Where the body holds a complete
+// request, answering it means the server ignored Content-Length.
+func (bc *BadClient) lengthProbe(cseq *int) parserProbe {
+	first, hidden, next := *cseq, *cseq+1, *cseq+2
+	*cseq += 3
+	embedded := fmt.Sprintf("OPTIONS * RTSP/1.0\r\nCSeq: %d\r\n\r\n", hidden)
+	body := "x: y\r\n" + embedded
+	
+	var lengths string
+	probe := parserProbe{expect: []int{first, next}}
+	switch bc.rng.Intn(3) {
+	case 0:
+		// Honest length covering a request-shaped body
+		lengths = fmt.Sprintf("Content-Length: %d\r\n", len(body))
+		probe.smuggled = []int{hidden}
+	case 1:
+		// Conflicting lengths: 0 exposes the body as a request
+		lengths = fmt.Sprintf("Content-Length: 0\r\nContent-Length: %d\r\n", len(body))
+		probe.smuggled = []int{hidden}
+	default:
+		// Short length: the rest of the body is left as garbage
+		body = "x: y\r\nz: w\r\n"
+		lengths = "Content-Length: 4\r\n"
+	}
+	probe.chunks = []string{fmt.Sprintf("SET_PARAMETER %s RTSP/1.0\r\nCSeq: %d\r\nContent-Type: text/parameters\r\n%s\r\n%sOPTIONS * RTSP/1.0\r\nCSeq: %d\r\n\r\n",
+		bc.url, first, lengths, body, next)}
+	return probe
+}
+
+// splitProbe writes one request in pieces cut inside the method, between
+// CR and LF, inside a header name, before the final blank line and inside
+// the body
+func (bc *BadClient) splitProbe(cseq *int) parserProbe {
+	var request string
+	switch bc.rng.Intn(3) {
+	case 0:
+		request = fmt.Sprintf("OPTIONS * RTSP/1.0\r\nCSeq: %d\r\nUser-Agent: probe\r\n\r\n", *cseq)
+	case 1:
+		request = fmt.Sprintf("DESCRIBE %s RTSP/1.0\r\nCSeq: %d\r\nAccept: application/sdp\r\n\r\n", bc.url, *cseq)
+	default:
+		body := "x: y\r\nz: w\r\n"
+		request = fmt.Sprintf("SET_PARAMETER %s RTSP/1.0\r\nCSeq: %d\r\nContent-Type: text/parameters\r\nContent-Length: %d\r\n\r\n%s",
+			bc.url, *cseq, len(body), body)
+	}
+	probe := parserProbe{expect: []int{*cseq}}
+	*cseq++
+	
+	head := strings.Index(request, "\r\n\r\n")
+	candidates := []int{
+		1 + bc.rng.Intn(strings.IndexByte(request, ' ')-1), // Inside the method
+		strings.Index(request, "CSeq") + 2,
+		head + 2, // Before the blank line
+	}
+	for i := strings.Index(request, "\r\n"); i >= 0 && i < head; {
+		candidates = append(candidates, i+1) // Between CR and LF
+		next := strings.Index(request[i+2:], "\r\n")
+		if next < 0 {
+			break
+		}
+		i += 2 + next
+	}
+	if body := head + 4; body < len(request)-1 {
+		candidates = append(candidates, body+bc.rng.Intn(len(request)-body-1)+1)
+	}
+	
+	bc.rng.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	cuts := candidates[:2+bc.rng.Intn(min(3, len(candidates)-1))]
+	sort.Ints(cuts)
+	start := 0
+	for _, cut := range cuts {
+		if cut > start {
+			probe.chunks = append(probe.chunks, request[start:cut])
+			start = cut
+		}
+	}
+	probe.chunks = append(probe.chunks, request[start:])
+	return probe
+}
@@ -0,0 +1,142 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// KeepAliveAuto sends GET_PARAMETER if the OPTIONS response listed it in
+// Public, and OPTIONS otherwise, as FFmpeg, live555 and GStreamer do
+const KeepAliveAuto = "auto"
+
+// PlayerProfile reproduces how a common RTSP client talks to a server, for
+// servers that special-case clients by their behavior rather than just the
+// User-Agent. The zero value is the bench's own behavior.
+type PlayerProfile struct {
+	Name            string
+	UserAgent       string
+	HeaderOrder     []string      // Header names in the order the player writes them; unlisted headers follow in the bench's order
+	SkipOptions     bool          // Start the handshake with DESCRIBE
+	Accept          string        // Accept header of DESCRIBE (empty = none)
+	PlayRange       string        // fmt verb for the start of the first PLAY in seconds, e.g. "npt=%.3f-" (empty = bench format)
+	Transport       string        // Preferred transport when none is configured: udp, tcp or TransportAuto
+	KeepAlive       string        // Keepalive method: GET_PARAMETER, OPTIONS, SET_PARAMETER or KeepAliveAuto (empty = GET_PARAMETER)
+	KeepAliveShare  float64       // Share of the session timeout between keepalives (0 = a third)
+	KeepAliveEarly  time.Duration // Subtracted from that, as players that refresh just before the timeout do
+	FallbackTimeout time.Duration // TransportAuto: time without RTP over UDP before retrying over TCP
+}
+
+// PlayerProfiles are the built-in profiles by name. They follow the
+// players' source (FFmpeg libavformat/rtsp.c, live555 RTSPClient as used by
+// VLC, GStreamer rtspsrc); the versions in the User-Agents are examples.
+var PlayerProfiles = map[string]PlayerProfile{
+	"ffmpeg": {
+		Name:            "ffmpeg",
+		UserAgent:       "Lavf60.16.100",
+		HeaderOrder:     []string{"Accept", "Transport", "Range", "CSeq", "User-Agent", "Session", "Authorization"},
+		Accept:          "application/sdp",
+		PlayRange:       "npt=%.3f-",
+		Transport:       TransportAuto,
+		KeepAlive:       KeepAliveAuto,
+		KeepAliveShare:  0.5,
+		FallbackTimeout: 10 * time.Second,
+	},
+	"vlc": {
+		Name:            "vlc",
+		UserAgent:       "LibVLC/3.0.20 (LIVE555 Streaming Media v2016.11.28)",
+		HeaderOrder:     []string{"CSeq", "Authorization", "User-Agent", "Transport", "Session", "Range", "Accept"},
+		Accept:          "application/sdp",
+		PlayRange:       "npt=%.3f-",
+		Transport:       TransportAuto,
+		KeepAlive:       KeepAliveAuto,
+		KeepAliveShare:  1,
+		KeepAliveEarly:  2 * time.Second,
+		FallbackTimeout: 10 * time.Second,
+	},
+	"gstreamer": {
+		Name:            "gstreamer",
+		UserAgent:       "GStreamer/1.22.0",
+		HeaderOrder:     []string{"CSeq", "User-Agent", "Accept", "Transport", "Range", "Session", "Authorization"},
+		Accept:          "application/sdp",
+		PlayRange:       "npt=%g-",
+		Transport:       TransportAuto,
+		KeepAlive:       KeepAliveAuto,
+		KeepAliveShare:  1,
+		KeepAliveEarly:  5 * time.Second,
+		FallbackTimeout: 5 * time.Second,
+	},
+	// The Kinesis Video Streams producer pulls cameras through rtspsrc,
+	// over TCP as its samples configure it
+	"kinesis": {
+		Name:           "kinesis",
+		UserAgent:      "GStreamer/1.20.3 (kvssink)",
+		HeaderOrder:    []string{"CSeq", "User-Agent", "Accept", "Transport", "Range", "Session", "Authorization"},
+		Accept:         "application/sdp",
+		PlayRange:      "npt=%g-",
+		Transport:      "tcp",
+		KeepAlive:      KeepAliveAuto,
+		KeepAliveShare: 1,
+		KeepAliveEarly: 5 * time.Second,
+	},
+}
+
+// ParsePlayerProfile returns the built-in profile called name
+func ParsePlayerProfile(name string) (PlayerProfile, error) {
+	p, ok := PlayerProfiles[strings.ToLower(name)]
+	if !ok {
+		return PlayerProfile{}, fmt.Errorf("unknown player profile %q", name)
+	}
+	return p, nil
+}
+
+// SetPlayerProfile makes the client behave like the player p describes.
+// Its User-Agent is used unless SetUserAgent sets another.
+func (c *Client) SetPlayerProfile(p PlayerProfile) {
+	c.player = &p
+}
+
+// sortHeaders puts headers in the player's order, keeping the order of
+// the headers it does not list
+func (p *PlayerProfile) sortHeaders(headers [][2]string) {
+	if p == nil || len(p.HeaderOrder) == 0 {
+		return
+	}
+	rank := func(name string) int {
+		for i, h := range p.HeaderOrder {
+			if strings.EqualFold(h, name) {
+				return i
+			}
+		}
+		return len(p.HeaderOrder)
+	}
+	sort.SliceStable(headers, func(i, j int) bool {
+		return rank(headers[i][0]) < rank(headers[j][0])
+	})
+}
+
+// keepAliveMethod returns the method of keepalive requests
+func (c *Client) keepAliveMethod() string {
+	if c.player == nil || c.player.KeepAlive == "" {
+		return "GET_PARAMETER"
+	}
+	if c.player.KeepAlive != KeepAliveAuto {
+		return c.player.KeepAlive
+	}
+	if c.player.SkipOptions || publicHas(c.public, "GET_PARAMETER") {
+		return "GET_PARAMETER"
+	}
+	return "OPTIONS"
+}
+
+// publicHas reports whether a Public header lists method
+func publicHas(public, method string) bool {
+	for _, m := range strings.Split(public, ",") {
+		if strings.EqualFold(strings.TrimSpace(m), method) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,277 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/sdp"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/synth"
+)
+
+// Publisher pushes a synthetic H.264 stream to the server with ANNOUNCE
+// and RECORD, so readers have a source without an external encoder
+type Publisher struct {
+	c      *Client
+	stream *synth.Stream
+	track  *mediaTrack
+
+	recording   atomic.Bool
+	packetsSent atomic.Uint64
+	bytesSent   atomic.Uint64
+}
+
+// NewPublisher creates a publisher for rtspURL sending a stream built from opts
+func NewPublisher(rtspURL string, transport string, opts synth.Options) (*Publisher, error) {
+	c, err := NewClient(rtspURL, transport, rtp.NewAggregator())
+	if err != nil {
+		return nil, err
+	}
+	return &Publisher{c: c, stream: synth.NewStream(opts)}, nil
+}
+
+// SetCredentials sets the username and password used to answer Basic or
+// Digest challenges
+func (p *Publisher) SetCredentials(username, password string) {
+	p.c.SetCredentials(username, password)
+}
+
+// SetSource sets the local address and interface the publisher connects from
+func (p *Publisher) SetSource(src Source) {
+	p.c.SetSource(src)
+}
+
+// SetDialOptions sets the address family and racing used by Connect
+func (p *Publisher) SetDialOptions(opts DialOptions) {
+	p.c.SetDialOptions(opts)
+}
+
+// SetSocketOptions tunes the sockets the publisher opens
+func (p *Publisher) SetSocketOptions(opts SocketOptions) {
+	p.c.SetSocketOptions(opts)
+}
+
+// SetResolver replaces the system resolver for the server hostname
+func (p *Publisher) SetResolver(resolve Resolver) {
+	p.c.SetResolver(resolve)
+}
+
+// Connect establishes the RTSP control connection
+func (p *Publisher) Connect() error {
+	return p.c.Connect()
+}
+
+// Run announces the stream, starts recording and sends frames at the
+// stream's frame rate until ctx is cancelled
+func (p *Publisher) Run(ctx context.Context) error {
+	c := p.c
+	if c.conn == nil {
+		if err := c.Connect(); err != nil {
+			return err
+		}
+	}
+	defer c.Close()
+
+	// RTSP handshake: OPTIONS -> ANNOUNCE -> SETUP -> RECORD
+	if err := c.sendOptions(); err != nil {
+		return fmt.Errorf("OPTIONS failed: %w", err)
+	}
+	if err := p.sendAnnounce(); err != nil {
+		return fmt.Errorf("ANNOUNCE failed: %w", err)
+	}
+	if err := p.sendSetup(); err != nil {
+		return fmt.Errorf("SETUP failed: %w", err)
+	}
+	if err := c.sendRequest(c.buildRequest("RECORD", map[string]string{
+		"Session": c.session,
+		"Range":   "npt=0.000-",
+	})); err != nil {
+		return fmt.Errorf("RECORD failed: %w", err)
+	}
+	c.lastKeepAlive.Store(time.Now().UnixNano())
+	p.recording.Store(true)
+	defer p.recording.Store(false)
+
+	if c.transport == "udp" {
+		return p.runUDP(ctx)
+	}
+	return p.runTCP(ctx)
+}
+
+// sendAnnounce describes the synthetic stream to the server
+func (p *Publisher) sendAnnounce() error {
+	c := p.c
+	host, _, _ := net.SplitHostPort(c.conn.LocalAddr().String())
+	body := p.stream.SDP(host, "WINK RTSP Bench")
+
+	session, err := sdp.Parse(body)
+	if err != nil {
+		return err
+	}
+	c.sdp = session
+	c.medias = session.Medias
+
+	req := c.buildRequest("ANNOUNCE", map[string]string{
+		"Content-Type": "application/sdp",
+	})
+	req.body = body
+	return c.sendRequest(req)
+}
+
+// sendSetup sets up the video track in record mode
+func (p *Publisher) sendSetup() error {
+	c := p.c
+	t := newMediaTrack(0, c.medias[0], c.aggregator)
+	headers := make(map[string]string)
+	if c.transport == "udp" {
+		if err := t.listenUDP(c.source, c.sockOpts); err != nil {
+			return err
+		}
+		rtpPort, rtcpPort := t.clientPorts()
+		headers["Transport"] = fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d;mode=record", rtpPort, rtcpPort)
+	} else {
+		headers["Transport"] = fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%d-%d;mode=record", t.rtpChannel, t.rtcpChannel)
+	}
+
+	resp, err := c.sendRequestWithResponse(c.buildTrackRequest("SETUP", t.media.Control, headers))
+	if err != nil {
+		t.close()
+		return err
+	}
+	c.parseTransportHeader(t, c.extractHeader(resp, "Transport"))
+	c.tracks = append(c.tracks, t)
+	p.track = t
+
+	if session := c.extractHeader(resp, "Session"); session != "" {
+		parts := strings.Split(session, ";")
+		c.session = strings.TrimSpace(parts[0])
+		c.sessionTimeout = parseSessionTimeout(parts[1:])
+	}
+	if c.transport == "udp" {
+		c.resolveServerIP()
+		if c.serverIP == nil || t.serverRTP == 0 {
+			return fmt.Errorf("no server_port in Transport response")
+		}
+	}
+	return nil
+}
+
+// runTCP sends interleaved frames. Incoming data (RTCP receiver reports)
+// is drained in the background; the media itself keeps the session alive.
+func (p *Publisher) runTCP(ctx context.Context) error {
+	c := p.c
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		io.Copy(io.Discard, c.reader)
+	}()
+	defer func() {
+		// Unblock the drain so Close can read the TEARDOWN response
+		c.conn.SetReadDeadline(time.Now())
+		<-drained
+		c.conn.SetReadDeadline(time.Time{})
+	}()
+
+	frame := make([]byte, 0, 4+synth.DefaultPacketSize+12)
+	return p.send(ctx, func(pkt []byte) error {
+		frame = append(frame[:0], '$', byte(p.track.rtpChannel), 0, 0)
+		binary.BigEndian.PutUint16(frame[2:4], uint16(len(pkt)))
+		frame = append(frame, pkt...)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.closed {
+			return fmt.Errorf("connection closed")
+		}
+		return c.write(frame)
+	})
+}
+
+// runUDP sends frames to the server's RTP port and refreshes the session
+// on the control connection
+func (p *Publisher) runUDP(ctx context.Context) error {
+	c := p.c
+	dst := &net.UDPAddr{IP: c.serverIP, Port: p.track.serverRTP}
+
+	keepAliveCtx, cancelKeepAlive := context.WithCancel(ctx)
+	defer cancelKeepAlive()
+	keepAliveErr := make(chan error, 1)
+	go func() {
+		ticker := time.NewTicker(c.keepAliveInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-keepAliveCtx.Done():
+				return
+			case <-ticker.C:
+				if err := c.sendKeepAlive(); err != nil {
+					keepAliveErr <- err
+					return
+				}
+			}
+		}
+	}()
+
+	return p.send(ctx, func(pkt []byte) error {
+		select {
+		case err := <-keepAliveErr:
+			return fmt.Errorf("keepalive failed: %w", c.sessionError(err))
+		default:
+		}
+		_, err := p.track.rtpConn.WriteTo(pkt, dst)
+		return err
+	})
+}
+
+// send writes one frame of packets per frame interval until ctx is done
+func (p *Publisher) send(ctx context.Context, write func(pkt []byte) error) error {
+	ticker := time.NewTicker(p.stream.FrameInterval())
+	defer ticker.Stop()
+
+	for {
+		for _, pkt := range p.stream.NextFrame() {
+			if err := write(pkt); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("send failed: %w", err)
+			}
+			p.packetsSent.Add(1)
+			p.bytesSent.Add(uint64(len(pkt)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Recording reports whether the server accepted RECORD and frames are
+// being sent
+func (p *Publisher) Recording() bool {
+	return p.recording.Load()
+}
+
+// PacketsSent returns the number of RTP packets sent
+func (p *Publisher) PacketsSent() uint64 {
+	return p.packetsSent.Load()
+}
+
+// BytesSent returns the number of RTP bytes sent
+func (p *Publisher) BytesSent() uint64 {
+	return p.bytesSent.Load()
+}
+
+// LastTeardown returns the result of the TEARDOWN sent when Run finished
+func (p *Publisher) LastTeardown() TeardownResult {
+	return p.c.LastTeardown()
+}
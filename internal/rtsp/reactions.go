@@ -0,0 +1,153 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+// Reactions is how the server responded to a bad client
+type Reactions struct {
+	Statuses        map[int]int // Response status codes seen
+	Closed          int         // Connections the server closed
+	Resets          int         // Connections the server reset
+	Timeouts        int         // Requests not answered in time
+	Refused         int         // Connection attempts refused
+	ConnectTimeouts int         // Connection attempts that timed out, as when a firewall ban drops SYNs
+}
+
+// Reactions returns what the server did to the client so far
+func (bc *BadClient) Reactions() Reactions {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	r := bc.reactions
+	r.Statuses = make(map[int]int, len(bc.reactions.Statuses))
+	for code, n := range bc.reactions.Statuses {
+		r.Statuses[code] = n
+	}
+	return r
+}
+
+// drainsResponses reports whether the type never reads what the server
+// sends itself; those get a reader that discards it after counting
+func (t BadClientType) drainsResponses() bool {
+	switch t {
+	case SlowConnector, SlowSender, GarbageSender, IncompleteHandshake, InvalidProtocol, ResourceHog, RandomDisconnect:
+		return true
+	}
+	return false
+}
+
+// watchedConn counts the status lines the server sends and how it ends
+// the connection
+type watchedConn struct {
+	net.Conn
+	bc    *BadClient
+	line  []byte // Start of the current line
+	ended bool   // Closed or reset already counted
+}
+
+// watch wraps a new connection of the client
+func (bc *BadClient) watch(conn net.Conn) net.Conn {
+	w := &watchedConn{Conn: conn, bc: bc, line: make([]byte, 0, 16)}
+	if bc.clientType.drainsResponses() {
+		go io.Copy(io.Discard, w)
+	}
+	return w
+}
+
+// Read reads from the connection, counting status lines and errors
+func (c *watchedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	for _, b := range p[:n] {
+		if b == '\n' {
+			c.status()
+			c.line = c.line[:0]
+		} else if len(c.line) < cap(c.line) {
+			c.line = append(c.line, b)
+		}
+	}
+	if err != nil {
+		c.failed(err)
+	}
+	return n, err
+}
+
+// Write writes to the connection, counting errors
+func (c *watchedConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if err != nil {
+		c.failed(err)
+	}
+	return n, err
+}
+
+// status counts the current line if it is "RTSP/x.y NNN"
+func (c *watchedConn) status() {
+	if len(c.line) < 12 || string(c.line[:5]) != "RTSP/" || c.line[8] != ' ' {
+		return
+	}
+	code, err := strconv.Atoi(string(c.line[9:12]))
+	if err != nil {
+		return
+	}
+	c.bc.mu.Lock()
+	if c.bc.reactions.Statuses == nil {
+		c.bc.reactions.Statuses = make(map[int]int)
+	}
+	c.bc.reactions.Statuses[code]++
+	c.bc.mu.Unlock()
+}
+
+// failed counts the server closing or resetting the connection, once per
+// connection. Timeouts and the client's own close are not counted here.
+func (c *watchedConn) failed(err error) {
+	var ne net.Error
+	if errors.Is(err, net.ErrClosed) || (errors.As(err, &ne) && ne.Timeout()) {
+		return
+	}
+	c.bc.mu.Lock()
+	defer c.bc.mu.Unlock()
+	if c.ended {
+		return
+	}
+	c.ended = true
+	if errors.Is(err, syscall.ECONNRESET) {
+		c.bc.reactions.Resets++
+	} else {
+		c.bc.reactions.Closed++
+	}
+}
+
+// dialFailed counts a connection attempt the server refused or ignored
+func (bc *BadClient) dialFailed(err error) {
+	var ne net.Error
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		bc.reactions.Refused++
+	case errors.As(err, &ne) && ne.Timeout():
+		bc.reactions.ConnectTimeouts++
+	}
+}
+
+// timedOut counts a request the server did not answer in time
+func (bc *BadClient) timedOut() {
+	bc.mu.Lock()
+	bc.reactions.Timeouts++
+	bc.mu.Unlock()
+}
+
+// tcpConn returns the client's TCP connection under the watcher
+func (bc *BadClient) tcpConn() (*net.TCPConn, bool) {
+	conn := bc.conn
+	if w, ok := conn.(*watchedConn); ok {
+		conn = w.Conn
+	}
+	tcp, ok := conn.(*net.TCPConn)
+	return tcp, ok
+}
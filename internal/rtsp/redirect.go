@@ -0,0 +1,100 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// MaxRedirects is how many redirects a session follows before giving up
+const MaxRedirects = 5
+
+// ErrTooManyRedirects is returned from Run when a session was redirected
+// more than MaxRedirects times
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// redirectError is a 3xx response to DESCRIBE or SETUP, or a REDIRECT
+// request from the server, pointing the client at another URL
+type redirectError struct {
+	code     int // 0 for a REDIRECT request
+	location *url.URL
+}
+
+func (e *redirectError) Error() string {
+	if e.code == 0 {
+		return fmt.Sprintf("server REDIRECT to %s", e.location)
+	}
+	return fmt.Sprintf("RTSP redirect %d to %s", e.code, e.location)
+}
+
+// Redirects returns how many redirects the session followed during
+// handshakes
+func (c *Client) Redirects() int {
+	return c.redirects
+}
+
+// checkRedirect returns a redirectError if resp is a 3xx response. The
+// Location header may be relative to the current URL.
+func (c *Client) checkRedirect(resp string) error {
+	code := responseStatus(resp, nil)
+	if code < 300 || code >= 400 {
+		return nil
+	}
+	location := c.extractHeader(resp, "Location")
+	if location == "" {
+		return fmt.Errorf("redirect %d without Location", code)
+	}
+	u, err := c.parseLocation(location)
+	if err != nil {
+		return fmt.Errorf("redirect %d: %w", code, err)
+	}
+	return &redirectError{code: code, location: u}
+}
+
+// followRedirect drops the connection and the state of the handshake so
+// far and connects to the redirect's location
+func (c *Client) followRedirect(redirect *redirectError) error {
+	if c.redirects == MaxRedirects {
+		return fmt.Errorf("%w: %d followed, last to %s", ErrTooManyRedirects, c.redirects, redirect.location)
+	}
+	c.redirects++
+	if err := c.reconnect(redirect.location); err != nil {
+		return fmt.Errorf("redirect to %s: %w", redirect.location, err)
+	}
+	return nil
+}
+
+// reconnect drops the connection and the session state and connects to u
+func (c *Client) reconnect(u *url.URL) error {
+	c.conn.Close()
+	c.conn = nil
+	c.leaveGroups()
+	for _, t := range c.tracks {
+		t.close()
+	}
+	c.tracks = nil
+	c.channels = [256]channelRoute{}
+	c.sdp = nil
+	c.medias = nil
+	c.session = ""
+	c.sessionTimeout = 0
+	c.playTime = time.Time{}
+	c.url = u
+	c.baseURL = u
+
+	return c.Connect()
+}
+
+// parseLocation resolves a redirect target against the current URL
+func (c *Client) parseLocation(location string) (*url.URL, error) {
+	u, err := c.url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Location %q: %w", location, err)
+	}
+	if u.Scheme != "rtsp" && u.Scheme != "rtsps" {
+		return nil, fmt.Errorf("unsupported scheme: %s", u.Scheme)
+	}
+	return u, nil
+}
@@ -0,0 +1,39 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// MethodKeepAlive is the name keepalive requests are recorded under, to
+// tell them from handshake requests of the same method
+const MethodKeepAlive = "keepalive"
+
+// ResponseRecorder counts the responses clients receive, for per-method
+// status code histograms and latency percentiles
+type ResponseRecorder interface {
+	// RecordResponse is called once per request sent. code is 0 when no
+	// response was received; latency runs from writing the request to
+	// reading the full response.
+	RecordResponse(method string, code int, latency time.Duration)
+}
+
+// SetResponseRecorder reports every request the client sends to r
+func (c *Client) SetResponseRecorder(r ResponseRecorder) {
+	c.responses = r
+}
+
+// recordResponse reports the outcome of req. Requests cut short by Close
+// are not counted.
+func (c *Client) recordResponse(req *request, code int, err error, latency time.Duration) {
+	if c.responses == nil || (code == 0 && errors.Is(err, net.ErrClosed)) {
+		return
+	}
+	method := req.method
+	if req.keepAlive {
+		method = MethodKeepAlive
+	}
+	c.responses.RecordResponse(method, code, latency)
+}
@@ -0,0 +1,165 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SetStartPosition makes the first PLAY on recorded media start at npt, to
+// resume where an earlier session of the same viewer stopped. Live streams
+// start at the live edge as usual.
+func (c *Client) SetStartPosition(npt time.Duration) {
+	c.resumeAt = npt
+}
+
+// Position returns the playback position of recorded media when the
+// session ended, or 0 for live streams and sessions that never played
+func (c *Client) Position() time.Duration {
+	return c.endPosition
+}
+
+// PlayTime returns when the PLAY response arrived, or the zero time if the
+// session never played
+func (c *Client) PlayTime() time.Time {
+	return c.playTime
+}
+
+// DefaultResumeBackoff is the wait before the second attempt to resume a
+// lost session, doubled for each one after
+const DefaultResumeBackoff = time.Second
+
+// SetResume makes the client resume a session whose connection is lost
+// during playback, by a reset, a close or a control timeout: it re-dials,
+// sets the same tracks up again and plays on from where it was, as the
+// same viewer. attempts is how many times it tries (0 = off), the first
+// at once and the next after backoff, doubled per retry (0 = the default).
+func (c *Client) SetResume(attempts int, backoff time.Duration) {
+	c.resumeAttempts = attempts
+	c.resumeBackoff = backoff
+}
+
+// resumable reports whether the session that ended with err lost its
+// connection while playing and should be resumed. Sessions the server
+// ended on purpose, with an error status or by expiring them, are not.
+func (c *Client) resumable(ctx context.Context, err error) bool {
+	if c.resumeAttempts <= 0 || err == nil || ctx.Err() != nil || !c.Playing() || c.transport == TransportMulticast {
+		return false
+	}
+	switch ClassifyTermination(err) {
+	case TerminationReset, TerminationClosed, TerminationTimeout:
+		return true
+	}
+	return false
+}
+
+// resume re-establishes a session whose connection was lost with cause.
+// The tracks and their statistics carry on; the time from the loss until
+// the server accepted PLAY again is recorded as the interruption.
+func (c *Client) resume(ctx context.Context, cause error) error {
+	lost := time.Now()
+	npt := time.Duration(0)
+	if d := c.MediaDuration(); d > 0 {
+		npt = c.position() % d
+	}
+	c.transcript.add(TranscriptNote, fmt.Sprintf("connection lost (%v), resuming", cause))
+	backoff := c.resumeBackoff
+	if backoff <= 0 {
+		backoff = DefaultResumeBackoff
+	}
+	c.resuming = true
+	defer func() { c.resuming = false }()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = c.resumeSession(npt); err == nil {
+			c.aggregator.AddSessionResume(time.Since(lost))
+			return nil
+		}
+		if attempt >= c.resumeAttempts {
+			break
+		}
+		
+		c.transcript.add(TranscriptNote, fmt.Sprintf("resume failed (%v), retrying in %v", err, backoff))
+		wait := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return ctx.Err()
+		case <-wait.C:
+		}
+		backoff *= 2
+	}
+	c.transcript.add(TranscriptNote, fmt.Sprintf("resume failed: %v", err))
+	c.aggregator.AddSessionResumeFailure()
+	return err
+}
+
+// resumeSession dials a new connection and plays the tracks from npt
+// under a new session
+func (c *Client) resumeSession(npt time.Duration) error {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	c.session = ""
+	c.sessionTimeout = 0
+	c.serverIP = nil
+	if err := c.Connect(); err != nil {
+		return err
+	}
+	if err := c.setupTracks(); err != nil {
+		return fmt.Errorf("SETUP failed: %w", err)
+	}
+
+	sent := time.Now()
+	resp, err := c.sendRequestWithResponse(c.playRequest(npt))
+	if err != nil {
+		return fmt.Errorf("PLAY failed: %w", err)
+	}
+	c.lastKeepAlive.Store(time.Now().UnixNano())
+	c.setPosition(npt)
+	c.expectRestart(resp, sent, false)
+	c.logTracks(time.Now())
+	return nil
+}
+
+// setupTracks sets the tracks up again on a new connection, asking for the
+// client ports or interleaved channels they had, so their sockets and
+// statistics are kept
+func (c *Client) setupTracks() error {
+	c.channels = [256]channelRoute{}
+	for i, t := range c.tracks {
+		headers := make(map[string]string)
+		if i > 0 {
+			headers["Session"] = c.session
+		}
+		if c.datagramMedia() {
+			rtpPort, rtcpPort := t.clientPorts()
+			headers["Transport"] = fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d", rtpPort, rtcpPort)
+		} else {
+			headers["Transport"] = fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%d-%d", t.rtpChannel, t.rtcpChannel)
+		}
+		c.requireBackchannel(headers)
+		resp, err := c.sendRequestWithResponse(c.buildTrackRequest("SETUP", t.media.Control, headers))
+		if err != nil {
+			return err
+		}
+
+		c.parseTransportHeader(t, c.extractHeader(resp, "Transport"))
+		if !c.datagramMedia() {
+			c.channels[t.rtpChannel] = channelRoute{track: t}
+			c.channels[t.rtcpChannel] = channelRoute{track: t, rtcp: true}
+		}
+		if c.session == "" {
+			c.setSession(resp)
+		}
+	}
+
+	if c.datagramMedia() {
+		c.resolveServerIP()
+		c.punchHoles()
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build linux
+
+package rtsp
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reusePort lets several sockets bind the same port, the kernel spreading
+// incoming flows across them (SO_REUSEPORT)
+func reusePort(c syscall.RawConn) error {
+	var err error
+	if cerr := c.Control(func(fd uintptr) {
+		err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	}); cerr != nil {
+		return cerr
+	}
+	if err != nil {
+		return fmt.Errorf("SO_REUSEPORT: %w", err)
+	}
+	return nil
+}
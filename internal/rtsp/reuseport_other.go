@@ -0,0 +1,16 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build !linux
+
+package rtsp
+
+import (
+	"errors"
+	"syscall"
+)
+
+// reusePort is not available outside Linux, where SO_REUSEPORT does not
+// spread UDP flows across sockets
+func reusePort(c syscall.RawConn) error {
+	return errors.New("shared UDP sockets are only supported on Linux")
+}
@@ -0,0 +1,119 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtcp"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+)
+
+// ReceiverReportInterval is how often RTCP RRs are sent during PLAY
+const ReceiverReportInterval = 5 * time.Second
+
+// handleRTCPPacket records the last SR from the track's sender for LSR/DLSR
+func (c *Client) handleRTCPPacket(t *mediaTrack, data []byte) {
+	for _, sr := range rtcp.ParseSenderReports(data) {
+		t.srMu.Lock()
+		if _, known := t.senderRpt[sr.SSRC]; known || len(t.senderRpt) < rtp.MaxSources {
+			t.senderRpt[sr.SSRC] = senderInfo{lsr: sr.MiddleNTP(), received: time.Now()}
+		}
+		t.srMu.Unlock()
+	}
+}
+
+// buildReceiverReport builds an RR for a track with one report block per
+// SSRC, from each source's sequence tracker state
+func (c *Client) buildReceiverReport(t *mediaTrack) []byte {
+	var reports []rtcp.ReceptionReport
+	for _, src := range t.sources.Sources() {
+		rec := src.Seq.Reception()
+
+		t.srMu.Lock()
+		sr := t.senderRpt[src.SSRC]
+		t.srMu.Unlock()
+
+		report := rtcp.ReceptionReport{
+			SSRC:           src.SSRC,
+			FractionLost:   rec.FractionLost,
+			CumulativeLost: int32(rec.Lost),
+			HighestSeq:     rec.ExtendedMax,
+			Jitter:         src.Jitter.Jitter(),
+			LSR:            sr.lsr,
+		}
+		if sr.lsr != 0 {
+			report.DLSR = rtcp.DelaySince(sr.received)
+		}
+		reports = append(reports, report)
+	}
+
+	return rtcp.BuildReceiverReport(c.localSSRC, reports)
+}
+
+// sendReceiverReport sends an RR for every track, on the track's interleaved
+// RTCP channel (TCP) or to the track's server RTCP port (UDP)
+func (c *Client) sendReceiverReport() error {
+	var firstErr error
+	for _, t := range c.tracks {
+		if t.send != nil {
+			continue // The server receives on backchannels
+		}
+		if err := c.sendTrackReport(t); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// sendTrackReport sends a single track's RR
+func (c *Client) sendTrackReport(t *mediaTrack) error {
+	return c.sendRTCP(t, c.buildReceiverReport(t))
+}
+
+// sendRTCP sends a compound RTCP packet for a track. Multicast tracks send
+// to the group's RTCP port, as every member does (RFC 3550).
+func (c *Client) sendRTCP(t *mediaTrack, pkt []byte) error {
+	if t.group != nil {
+		return c.writeUDP(t.rtcpConn, pkt, &net.UDPAddr{IP: t.group.IP, Port: t.groupRTCP})
+	}
+	if c.transport == "udp" {
+		if t.rtcpConn == nil || t.serverRTCP == 0 || c.serverIP == nil {
+			return nil
+		}
+		return c.writeUDP(t.rtcpConn, pkt, &net.UDPAddr{IP: c.serverIP, Port: t.serverRTCP})
+	}
+
+	frame := make([]byte, 4+len(pkt))
+	frame[0] = '$'
+	frame[1] = byte(t.rtcpChannel)
+	binary.BigEndian.PutUint16(frame[2:4], uint16(len(pkt)))
+	copy(frame[4:], pkt)
+	return c.post(frame)
+}
+
+// readRTCP reads RTCP packets from a track's UDP RTCP socket until ctx is done
+func (c *Client) readRTCP(ctx context.Context, t *mediaTrack) {
+	buf := make([]byte, 2048)
+	for ctx.Err() == nil {
+		t.rtcpConn.SetReadDeadline(time.Now().Add(time.Second))
+		n, addr, err := t.rtcpConn.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return
+		}
+		c.captureUDP(t.rtcpConn, addr, buf[:n], true)
+		
+		// Group members' receiver reports come back on a multicast RTCP
+		// port; only the server's SRs are of interest
+		if t.group == nil && !c.validSource(addr, t.serverRTCP) {
+			c.aggregator.AddRejected(1)
+			continue
+		}
+		c.handleRTCPPacket(t, buf[:n])
+	}
+}
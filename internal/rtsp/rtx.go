@@ -0,0 +1,95 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+)
+
+// linkRTX connects retransmission streams (RFC 4588) to the tracks they
+// repair: RTX payload types within a track's own m= section, and RTX-only
+// sections through the payload type they repair (apt)
+func (c *Client) linkRTX() {
+	for _, t := range c.tracks {
+		rtx := t.media.RTX()
+		if t.media.IsRTX() {
+			for _, apt := range rtx {
+				if target := c.trackForPayload(apt); target != nil {
+					t.repairs = target
+					target.repaired = true
+					break
+				}
+			}
+			continue
+		}
+		for pt, apt := range rtx {
+			if hasPayloadType(t.media.PayloadTypes, apt) {
+				if t.rtxTypes == nil {
+					t.rtxTypes = make(map[uint8]bool)
+				}
+				t.rtxTypes[uint8(pt)] = true
+				t.repaired = true
+			}
+		}
+	}
+}
+
+// trackForPayload returns the media track carrying payload type pt
+func (c *Client) trackForPayload(pt int) *mediaTrack {
+	for _, t := range c.tracks {
+		if !t.media.IsRTX() && hasPayloadType(t.media.PayloadTypes, pt) {
+			return t
+		}
+	}
+	return nil
+}
+
+// hasPayloadType reports whether pt is among types
+func hasPayloadType(types []int, pt int) bool {
+	for _, p := range types {
+		if p == pt {
+			return true
+		}
+	}
+	return false
+}
+
+// rtxTarget returns the track a packet of payload type pt received on t
+// repairs, or nil if it is regular media
+func (t *mediaTrack) rtxTarget(pt uint8) *mediaTrack {
+	if t.repairs != nil {
+		return t.repairs
+	}
+	if t.rtxTypes[pt] {
+		return t
+	}
+	return nil
+}
+
+// processRTX handles a retransmission for track t. The payload starts with
+// the original sequence number; if that packet was missing it counts as
+// recovered, is taken off the loss count and is reported as received in
+// receiver reports.
+func (c *Client) processRTX(t *mediaTrack, data []byte, now time.Time) {
+	c.bytesReceived.Add(uint64(len(data)))
+	payload := rtp.Payload(data)
+	if len(payload) < 2 {
+		c.aggregator.AddRTX(false)
+		return
+	}
+
+	osn := binary.BigEndian.Uint16(payload[:2])
+	ssrc := t.ssrc.Load()
+	d, recovered := t.missing.resolve(ssrc, osn, now)
+	if recovered {
+		if src := t.sources.Lookup(ssrc); src != nil {
+			src.Seq.Recover(osn)
+		}
+		if c.feedback.NACK {
+			c.aggregator.AddNACKRepair(d)
+		}
+	}
+	c.aggregator.AddRTX(recovered)
+}
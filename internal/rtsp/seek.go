@@ -0,0 +1,191 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// seekWindow is how many packets may arrive after a seek before the one
+// announced in RTP-Info is considered missing
+const seekWindow = 500
+
+// seekCheck is the RTP-Info expectation for a track after a seek or the
+// PLAY that resumes a pause
+type seekCheck struct {
+	seek    bool      // Record seek statistics (false for a resume)
+	sent    time.Time // When the PLAY was sent
+	seq     uint16
+	rtptime uint32
+	hasSeq  bool
+	hasTime bool
+	skipped int // Packets seen before the expected one (reader goroutine only)
+}
+
+// rtpInfo is one entry of an RTP-Info header
+type rtpInfo struct {
+	url     string
+	seq     uint16
+	rtptime uint32
+	hasSeq  bool
+	hasTime bool
+}
+
+// SetSeek makes the client bench recorded media: PLAY starts at a random
+// position and a new random position is requested every interval. Seek
+// latency and whether RTP restarts as announced in RTP-Info are recorded
+// on the aggregator. Streams without an SDP media duration are played
+// normally.
+func (c *Client) SetSeek(interval time.Duration) {
+	c.seekEvery = interval
+}
+
+// MediaDuration returns the length of recorded media from the SDP a=range,
+// or 0 for live streams
+func (c *Client) MediaDuration() time.Duration {
+	if c.sdp == nil {
+		return 0
+	}
+	d, _ := c.sdp.Duration()
+	return d
+}
+
+// seekTicker returns the seek channel and its stop function. The channel
+// is nil, and never fires, unless seeking recorded media.
+func (c *Client) seekTicker() (<-chan time.Time, func()) {
+	if c.seekEvery <= 0 || c.MediaDuration() <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(c.seekEvery)
+	return t.C, t.Stop
+}
+
+// startPosition returns where the initial PLAY starts on recorded media:
+// the resume position if set, a random position when seeking, otherwise 0
+func (c *Client) startPosition() time.Duration {
+	d := c.MediaDuration()
+	switch {
+	case d <= 0:
+		return 0
+	case c.resumeAt > 0:
+		return c.resumeAt % d
+	case c.seekEvery > 0:
+		return time.Duration(c.rng.Int63n(int64(d)))
+	}
+	return 0
+}
+
+// seek sends PLAY from a random position and arms the RTP-Info check on
+// every track. Error statuses are counted and leave the session running;
+// I/O errors are returned.
+func (c *Client) seek() error {
+	npt := time.Duration(c.rng.Int63n(int64(c.MediaDuration())))
+
+	start := time.Now()
+	resp, err := c.sendRequestWithResponse(c.buildRequest("PLAY", map[string]string{
+		"Session": c.session,
+		"Range":   fmt.Sprintf("npt=%.3f-", npt.Seconds()),
+	}))
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.Code != 454 {
+			c.aggregator.AddSeekFailure()
+			return nil
+		}
+		return err
+	}
+	c.aggregator.AddSeek(time.Since(start))
+	c.lastKeepAlive.Store(time.Now().UnixNano())
+	c.setPosition(npt)
+	c.expectRestart(resp, start, true)
+	c.restartStall()
+	return nil
+}
+
+// expectRestart arms every track to restart tracking at the first packet
+// announced in the PLAY response's RTP-Info
+func (c *Client) expectRestart(resp string, sent time.Time, seek bool) {
+	infos := parseRTPInfo(c.extractHeader(resp, "RTP-Info"))
+	for _, t := range c.tracks {
+		chk := &seekCheck{seek: seek, sent: sent}
+		if info, ok := c.trackRTPInfo(t, infos); ok {
+			chk.seq, chk.hasSeq = info.seq, info.hasSeq
+			chk.rtptime, chk.hasTime = info.rtptime, info.hasTime
+		}
+		t.seek.Store(chk)
+	}
+}
+
+// trackRTPInfo finds the RTP-Info entry for t. A single entry applies to a
+// single track whatever its URL.
+func (c *Client) trackRTPInfo(t *mediaTrack, infos []rtpInfo) (rtpInfo, bool) {
+	if len(infos) == 1 && len(c.tracks) == 1 {
+		return infos[0], true
+	}
+	uri := resolveControl(c.baseURL, t.media.Control)
+	for _, info := range infos {
+		if info.url == uri || (t.media.Control != "" && strings.HasSuffix(info.url, "/"+strings.TrimPrefix(t.media.Control, "/"))) {
+			return info, true
+		}
+	}
+	return rtpInfo{}, false
+}
+
+// parseRTPInfo parses "url=...;seq=N;rtptime=T, url=..."
+func parseRTPInfo(header string) []rtpInfo {
+	var infos []rtpInfo
+	for _, entry := range strings.Split(header, ",") {
+		var info rtpInfo
+		for _, param := range strings.Split(entry, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			switch name {
+			case "url":
+				info.url = value
+			case "seq":
+				if n, err := strconv.ParseUint(value, 10, 16); err == nil {
+					info.seq, info.hasSeq = uint16(n), true
+				}
+			case "rtptime":
+				if n, err := strconv.ParseUint(value, 10, 32); err == nil {
+					info.rtptime, info.hasTime = uint32(n), true
+				}
+			}
+		}
+		if info.url != "" || info.hasSeq || info.hasTime {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// checkSeek matches a packet against a pending seek or resume. It returns
+// true for the first packet from the new position, which restarts
+// sequence, jitter and video tracking. Packets still in flight from the old position are
+// skipped; if the announced packet does not show up within seekWindow
+// packets, the seek counts as a mismatch.
+func (c *Client) checkSeek(t *mediaTrack, chk *seekCheck, seq uint16, ts uint32) bool {
+	var match bool
+	switch {
+	case chk.hasSeq:
+		match = seq == chk.seq && (!chk.hasTime || ts == chk.rtptime)
+		if seq != chk.seq && chk.skipped < seekWindow {
+			chk.skipped++
+			return false
+		}
+	case chk.hasTime:
+		match = ts == chk.rtptime
+		if !match && chk.skipped < seekWindow {
+			chk.skipped++
+			return false
+		}
+	}
+
+	t.seek.CompareAndSwap(chk, nil)
+	if chk.seek {
+		c.aggregator.AddSeekMedia(time.Since(chk.sent), chk.hasSeq || chk.hasTime, match)
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MethodOther is the name server requests with a method outside RFC 2326
+// are counted under
+const MethodOther = "other"
+
+// serverMethods are the requests from the server the client accepts
+var serverMethods = []string{"OPTIONS", "GET_PARAMETER", "SET_PARAMETER", "ANNOUNCE", "REDIRECT"}
+
+// rtspMethods are the methods of RFC 2326, counted by name
+var rtspMethods = map[string]bool{
+	"DESCRIBE": true, "ANNOUNCE": true, "GET_PARAMETER": true, "OPTIONS": true,
+	"PAUSE": true, "PLAY": true, "RECORD": true, "REDIRECT": true,
+	"SETUP": true, "SET_PARAMETER": true, "TEARDOWN": true,
+}
+
+// SetFollowServerRedirects makes the client obey a REDIRECT request from
+// the server during playback: it tears the session down and starts over at
+// the new location. Otherwise REDIRECT is acknowledged and ignored.
+func (c *Client) SetFollowServerRedirects(follow bool) {
+	c.followServerRedirects = follow
+}
+
+// ServerRequests returns the requests the server sent on the connection, by
+// method
+func (c *Client) ServerRequests() map[string]int {
+	return c.serverRequests
+}
+
+// ServerRedirects returns how many REDIRECT requests the session followed
+func (c *Client) ServerRedirects() int {
+	return c.serverRedirects
+}
+
+// isRequestLine reports whether line is the request line of an RTSP request
+func isRequestLine(line string) bool {
+	fields := strings.Fields(line)
+	return len(fields) == 3 && strings.HasPrefix(fields[2], "RTSP/")
+}
+
+// serveRequest answers a request the server sent on the RTSP connection.
+// It returns a redirectError for a REDIRECT the client should follow.
+func (c *Client) serveRequest(msg string) error {
+	c.transcript.add(TranscriptReceived, msg)
+	method := strings.Fields(msg)[0]
+	if c.serverRequests == nil {
+		c.serverRequests = make(map[string]int)
+	}
+	if rtspMethods[method] {
+		c.serverRequests[method]++
+	} else {
+		c.serverRequests[MethodOther]++
+	}
+
+	status := "501 Not Implemented"
+	for _, m := range serverMethods {
+		if m == method {
+			status = "200 OK"
+		}
+	}
+
+	var redirect *redirectError
+	if method == "REDIRECT" {
+		u, err := c.parseLocation(c.extractHeader(msg, "Location"))
+		if err != nil || u.Host == "" {
+			status = "400 Bad Request"
+		} else if c.followServerRedirects {
+			redirect = &redirectError{location: u}
+		}
+	}
+
+	resp := fmt.Sprintf("RTSP/1.0 %s\r\nCSeq: %s\r\n", status, c.extractHeader(msg, "CSeq"))
+	if session := c.extractHeader(msg, "Session"); session != "" {
+		resp += fmt.Sprintf("Session: %s\r\n", session)
+	}
+	if method == "OPTIONS" {
+		resp += fmt.Sprintf("Public: %s\r\n", strings.Join(serverMethods, ", "))
+	}
+	resp += "\r\n"
+
+	// A single write, so it doesn't interleave with requests and RTCP;
+	// posted, as the reader must not wait for the server to read
+	if err := c.post([]byte(resp)); err != nil {
+		return err
+	}
+	c.transcript.add(TranscriptSent, resp)
+	if redirect != nil {
+		return redirect
+	}
+	return nil
+}
@@ -0,0 +1,541 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// sharedQueueSize is how many packets may wait for each shared UDP worker
+// before the receiving sockets stop reading
+const sharedQueueSize = 4096
+
+// SharedUDPStats describes the packets a SharedUDP received
+type SharedUDPStats struct {
+	Sockets       int
+	Packets       uint64   // RTP and RTCP packets received
+	Unrouted      uint64   // Packets no registered track was expecting
+	SocketPackets []uint64 // RTP packets received by each RTP socket
+	FanoutMax     int      // Most tracks sharing one route: identical streams told apart by sequence number
+}
+
+// SharedUDP receives the UDP media of many clients on one port pair:
+// several RTP sockets bound to the same port with SO_REUSEPORT, which the
+// kernel spreads flows across, and one RTCP socket on the next port.
+// Packets are routed to the track they belong to by the server address
+// they come from and their SSRC, and processed by a pool of workers, each
+// track always by the same one.
+type SharedUDP struct {
+	rtp     []net.PacketConn
+	rtcp    net.PacketConn
+	port    int
+	workers []chan sharedPacket
+	bufs    sync.Pool
+
+	rtpRoutes  routeTable
+	rtcpRoutes routeTable
+	nextWorker atomic.Uint64
+
+	packets       atomic.Uint64
+	unrouted      atomic.Uint64
+	socketPackets []atomic.Uint64
+
+	closeOnce sync.Once
+	readers   sync.WaitGroup
+	working   sync.WaitGroup
+}
+
+// sharedPacket is a packet on its way to a worker, or with done set, the
+// marker that a track's packets have all been processed
+type sharedPacket struct {
+	route *udpRoute
+	buf   *[]byte
+	n     int
+	peer  netip.AddrPort
+	rtcp  bool
+	done  chan struct{}
+}
+
+// NewSharedUDP opens sockets RTP sockets on port, or on any free even
+// port if 0, and an RTCP socket on the next port, from src and tuned by
+// opts. workers goroutines process the packets (0 = GOMAXPROCS).
+func NewSharedUDP(src Source, sockets, workers, port int, opts SocketOptions) (*SharedUDP, error) {
+	if sockets < 1 {
+		return nil, errors.New("shared UDP needs at least one socket")
+	}
+	if port%2 != 0 || port < 0 || port > 65534 {
+		return nil, fmt.Errorf("shared UDP port %d is not an even port number", port)
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	s := &SharedUDP{socketPackets: make([]atomic.Uint64, sockets)}
+	s.bufs.New = func() any {
+		buf := make([]byte, udpSlotSize)
+		return &buf
+	}
+	if err := s.listen(src, sockets, port, opts); err != nil {
+		s.closeSockets()
+		return nil, err
+	}
+
+	s.workers = make([]chan sharedPacket, workers)
+	for i := range s.workers {
+		queue := make(chan sharedPacket, sharedQueueSize)
+		s.workers[i] = queue
+		s.working.Add(1)
+		go s.work(queue)
+	}
+	for i, conn := range s.rtp {
+		s.readers.Add(1)
+		go s.receive(conn, i, false)
+	}
+	s.readers.Add(1)
+	go s.receive(s.rtcp, -1, true)
+	return s, nil
+}
+
+// listen opens the sockets. With no port given, free pairs are tried
+// until one has an even RTP port and a free RTCP port above it.
+func (s *SharedUDP) listen(src Source, sockets, port int, opts SocketOptions) error {
+	var err error
+	for attempt := 0; attempt < 20; attempt++ {
+		var first net.PacketConn
+		if first, err = src.listenShared(port); err != nil {
+			return fmt.Errorf("shared RTP socket: %w", err)
+		}
+		rtpPort := first.LocalAddr().(*net.UDPAddr).Port
+		var rtcp net.PacketConn
+		if rtpPort%2 == 0 {
+			rtcp, err = src.listenPort(rtpPort + 1)
+		}
+		if rtcp == nil {
+			first.Close()
+			if port != 0 {
+				return fmt.Errorf("shared RTCP port %d: %w", port+1, err)
+			}
+			continue // Odd or no free neighbour: try another pair
+		}
+		s.port, s.rtp, s.rtcp = rtpPort, []net.PacketConn{first}, rtcp
+		break
+	}
+	if s.rtp == nil {
+		return errors.New("no free even UDP port pair for shared sockets")
+	}
+	for len(s.rtp) < sockets {
+		conn, err := src.listenShared(s.port)
+		if err != nil {
+			return fmt.Errorf("shared RTP socket %d: %w", len(s.rtp)+1, err)
+		}
+		s.rtp = append(s.rtp, conn)
+	}
+	for _, conn := range append([]net.PacketConn{s.rtcp}, s.rtp...) {
+		if conn, ok := conn.(*net.UDPConn); ok && opts.RecvBuffer == 0 {
+			conn.SetReadBuffer(2 * 1024 * 1024)
+		}
+		if err := opts.tuneUDP(conn); err != nil {
+			return fmt.Errorf("failed to tune shared UDP socket: %w", err)
+		}
+	}
+	return nil
+}
+
+// listenPort opens a plain UDP socket on port of the source address
+func (s Source) listenPort(port int) (net.PacketConn, error) {
+	addr := fmt.Sprintf(":%d", port)
+	if s.IP != nil {
+		addr = net.JoinHostPort(s.IP.String(), fmt.Sprint(port))
+	}
+	lc := net.ListenConfig{Control: s.control}
+	return lc.ListenPacket(context.Background(), "udp", addr)
+}
+
+// listenShared opens a UDP socket on port of the source address that other
+// sockets may bind as well
+func (s Source) listenShared(port int) (net.PacketConn, error) {
+	addr := fmt.Sprintf(":%d", port)
+	if s.IP != nil {
+		addr = net.JoinHostPort(s.IP.String(), fmt.Sprint(port))
+	}
+	lc := net.ListenConfig{Control: func(network, address string, c syscall.RawConn) error {
+		if err := s.control(network, address, c); err != nil {
+			return err
+		}
+		return reusePort(c)
+	}}
+	return lc.ListenPacket(context.Background(), "udp", addr)
+}
+
+// SetSharedUDP makes the client receive UDP media on s instead of a socket
+// pair per track. Multicast is not affected.
+func (c *Client) SetSharedUDP(s *SharedUDP) {
+	c.shared = s
+}
+
+// Port returns the RTP port of the shared sockets; RTCP is on the next
+func (s *SharedUDP) Port() int {
+	return s.port
+}
+
+// Stats returns the packet counts so far
+func (s *SharedUDP) Stats() SharedUDPStats {
+	stats := SharedUDPStats{
+		Sockets:       len(s.rtp),
+		Packets:       s.packets.Load(),
+		Unrouted:      s.unrouted.Load(),
+		SocketPackets: make([]uint64, len(s.socketPackets)),
+		FanoutMax:     max(s.rtpRoutes.fanoutMax(), s.rtcpRoutes.fanoutMax()),
+	}
+	for i := range s.socketPackets {
+		stats.SocketPackets[i] = s.socketPackets[i].Load()
+	}
+	return stats
+}
+
+// Close closes the sockets and stops the workers once the packets already
+// received are processed
+func (s *SharedUDP) Close() error {
+	s.closeOnce.Do(func() {
+		s.closeSockets()
+		s.readers.Wait()
+		for _, queue := range s.workers {
+			close(queue)
+		}
+		s.working.Wait()
+	})
+	return nil
+}
+
+// closeSockets closes every socket opened so far
+func (s *SharedUDP) closeSockets() {
+	for _, conn := range s.rtp {
+		conn.Close()
+	}
+	if s.rtcp != nil {
+		s.rtcp.Close()
+	}
+}
+
+// receive reads a socket until it is closed and hands its packets to the
+// workers of their tracks
+func (s *SharedUDP) receive(conn net.PacketConn, index int, rtcp bool) {
+	defer s.readers.Done()
+	reader := newUDPReader(conn)
+	for {
+		packets, err := reader.read()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		s.packets.Add(uint64(len(packets)))
+		if index >= 0 {
+			s.socketPackets[index].Add(uint64(len(packets)))
+		}
+		for _, p := range packets {
+			s.dispatch(p, rtcp)
+		}
+	}
+}
+
+// dispatch routes one packet to its track's worker
+func (s *SharedUDP) dispatch(p udpPacket, rtcp bool) {
+	data := p.buf[:p.n]
+	peer := addrPort(p.addr)
+	peer = netip.AddrPortFrom(peer.Addr().Unmap(), peer.Port())
+	var route *udpRoute
+	if rtcp {
+		if len(data) >= 8 {
+			ssrc := binary.BigEndian.Uint32(data[4:8])
+			route = s.rtcpRoutes.lookup(peer, ssrc).next(ssrc)
+		}
+	} else if len(data) >= 12 {
+		ssrc := binary.BigEndian.Uint32(data[8:12])
+		route = s.rtpRoutes.lookup(peer, ssrc).pick(binary.BigEndian.Uint16(data[2:4]), ssrc)
+	}
+	if route == nil {
+		s.unrouted.Add(1)
+		return
+	}
+	buf := s.bufs.Get().(*[]byte)
+	n := copy(*buf, data)
+	s.workers[route.worker] <- sharedPacket{route: route, buf: buf, n: n, peer: peer, rtcp: rtcp}
+}
+
+// work processes packets until its queue is closed
+func (s *SharedUDP) work(queue chan sharedPacket) {
+	defer s.working.Done()
+	for p := range queue {
+		r := p.route
+		if p.done != nil {
+			r.c.flushCounts(r.t)
+			close(p.done)
+			continue
+		}
+		data := (*p.buf)[:p.n]
+		conn := s.rtp[0]
+		if p.rtcp {
+			conn = s.rtcp
+		}
+		r.c.captureUDP(conn, net.UDPAddrFromAddrPort(p.peer), data, true)
+		if r.c.throttle(r.ctx, p.n) == nil {
+			if p.rtcp {
+				r.c.handleRTCPPacket(r.t, data)
+			} else {
+				r.c.receiveRTP(r.t, data)
+			}
+		}
+		s.bufs.Put(p.buf)
+	}
+}
+
+// register routes the packets the server sends to t until the returned
+// function is called, which waits until they are all processed
+func (s *SharedUDP) register(ctx context.Context, c *Client, t *mediaTrack) func() {
+	r := &udpRoute{c: c, t: t, ctx: ctx, worker: int(s.nextWorker.Add(1) % uint64(len(s.workers)))}
+	if t.announcedSSRC != 0 {
+		r.learn(t.announcedSSRC)
+	}
+	ip, _ := netip.AddrFromSlice(c.serverIP)
+	ip = ip.Unmap()
+	rtpKey := routeKey{netip.AddrPortFrom(ip, uint16(t.serverRTP)), t.announcedSSRC}
+	rtcpKey := routeKey{netip.AddrPortFrom(ip, uint16(t.serverRTCP)), t.announcedSSRC}
+	s.rtpRoutes.add(rtpKey, r)
+	s.rtcpRoutes.add(rtcpKey, r)
+	return func() {
+		s.rtpRoutes.remove(rtpKey, r)
+		s.rtcpRoutes.remove(rtcpKey, r)
+		done := make(chan struct{})
+		s.workers[r.worker] <- sharedPacket{route: r, done: done}
+		<-done
+	}
+}
+
+// share makes t send and receive on the shared sockets
+func (t *mediaTrack) share(s *SharedUDP) {
+	t.rtpConn = s.rtp[0]
+	t.rtcpConn = s.rtcp
+	t.shared = true
+}
+
+// udpRoute is one track receiving on the shared sockets
+type udpRoute struct {
+	c      *Client
+	t      *mediaTrack
+	ctx    context.Context
+	worker int
+
+	ssrc    atomic.Uint64 // Announced or first seen SSRC | 1<<32, 0 = none yet
+	lastSeq uint16        // Of the last RTP packet routed to the track
+	started bool
+}
+
+// learn sets the SSRC of the track's stream
+func (r *udpRoute) learn(ssrc uint32) {
+	r.ssrc.Store(1<<32 | uint64(ssrc))
+}
+
+// carries reports whether the track's stream is ssrc: true is sure, and
+// known false means the SSRC is not known yet
+func (r *udpRoute) carries(ssrc uint32) (match, known bool) {
+	v := r.ssrc.Load()
+	return v == 1<<32|uint64(ssrc), v != 0
+}
+
+// routeKey is where a track's packets come from: the server address and
+// port (0 = any), and the SSRC the SETUP response announced (0 = any)
+type routeKey struct {
+	peer netip.AddrPort
+	ssrc uint32
+}
+
+// routeTable maps packet sources to the tracks expecting them
+type routeTable struct {
+	mu     sync.RWMutex
+	groups map[routeKey]*routeGroup
+	peak   int
+}
+
+// add routes key to r as well
+func (rt *routeTable) add(key routeKey, r *udpRoute) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if rt.groups == nil {
+		rt.groups = make(map[routeKey]*routeGroup)
+	}
+	g, ok := rt.groups[key]
+	if !ok {
+		g = &routeGroup{}
+		rt.groups[key] = g
+	}
+	g.mu.Lock()
+	g.members = append(g.members, r)
+	rt.peak = max(rt.peak, len(g.members))
+	g.mu.Unlock()
+}
+
+// remove stops routing key to r
+func (rt *routeTable) remove(key routeKey, r *udpRoute) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	g, ok := rt.groups[key]
+	if !ok {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, m := range g.members {
+		if m == r {
+			g.members = append(g.members[:i:i], g.members[i+1:]...)
+			break
+		}
+	}
+	g.unwait(r)
+	if len(g.members) == 0 {
+		delete(rt.groups, key)
+	} else if g.cursor >= len(g.members) {
+		g.cursor = 0
+	}
+}
+
+// lookup returns the tracks expecting packets of ssrc from peer, trying
+// the routes that name the port or the SSRC before those that do not
+func (rt *routeTable) lookup(peer netip.AddrPort, ssrc uint32) *routeGroup {
+	anyPort := netip.AddrPortFrom(peer.Addr(), 0)
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	for _, key := range [...]routeKey{{peer, ssrc}, {peer, 0}, {anyPort, ssrc}, {anyPort, 0}} {
+		if g, ok := rt.groups[key]; ok {
+			return g
+		}
+	}
+	return nil
+}
+
+// fanoutMax returns the most tracks that shared a route at once
+func (rt *routeTable) fanoutMax() int {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return rt.peak
+}
+
+// routeGroup is the tracks sharing a route: readers of the same stream
+// sent from the same server port get identical copies of each packet
+type routeGroup struct {
+	mu      sync.Mutex
+	members []*udpRoute
+	cursor  int
+	waiting map[seqKey][]*udpRoute // RTP members by the packet they expect next
+}
+
+// seqKey is an RTP packet of a stream
+type seqKey struct {
+	ssrc uint32
+	seq  uint16
+}
+
+// pick returns the member an RTP packet of ssrc with sequence number seq
+// goes to. Servers send each session's copy of a packet on their own, so
+// copies interleave: the packet goes to a member of that stream expecting
+// it next, else to the one that had the closest earlier packet, else to
+// one that has not seen a packet yet, which then takes ssrc as its stream
+func (g *routeGroup) pick(seq uint16, ssrc uint32) *udpRoute {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if w := g.waiting[seqKey{ssrc, seq}]; len(w) > 0 {
+		return g.advance(w[0], seq, ssrc)
+	}
+	var closest, fresh, again *udpRoute
+	for _, m := range g.members {
+		match, known := m.carries(ssrc)
+		switch {
+		case !known:
+			if fresh == nil {
+				fresh = m
+			}
+		case !match:
+		case !m.started:
+			fresh = m
+		case int16(seq-m.lastSeq) > 0:
+			if closest == nil || int16(seq-m.lastSeq) < int16(seq-closest.lastSeq) {
+				closest = m
+			}
+		case again == nil:
+			again = m // Another copy of a packet the member had: a duplicate
+		}
+	}
+	switch {
+	case closest != nil:
+		return g.advance(closest, seq, ssrc)
+	case fresh != nil:
+		fresh.learn(ssrc)
+		return g.advance(fresh, seq, ssrc)
+	}
+	return again
+}
+
+// advance records that m got packet seq of ssrc and returns it
+func (g *routeGroup) advance(m *udpRoute, seq uint16, ssrc uint32) *udpRoute {
+	g.unwait(m)
+	m.lastSeq, m.started = seq, true
+	if g.waiting == nil {
+		g.waiting = make(map[seqKey][]*udpRoute)
+	}
+	key := seqKey{ssrc, seq + 1}
+	g.waiting[key] = append(g.waiting[key], m)
+	return m
+}
+
+// unwait removes m from the members expecting a packet
+func (g *routeGroup) unwait(m *udpRoute) {
+	if !m.started {
+		return
+	}
+	key := seqKey{uint32(m.ssrc.Load()), m.lastSeq + 1}
+	w := g.waiting[key]
+	for i, o := range w {
+		if o == m {
+			if i == 0 {
+				w = w[1:] // The usual case: the longest waiting member got it
+			} else {
+				w = append(w[:i:i], w[i+1:]...)
+			}
+			break
+		}
+	}
+	if len(w) == 0 {
+		delete(g.waiting, key)
+	} else {
+		g.waiting[key] = w
+	}
+}
+
+// next returns the members receiving ssrc in turn
+func (g *routeGroup) next(ssrc uint32) *udpRoute {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n := len(g.members)
+	for i := 0; i < n; i++ {
+		j := (g.cursor + i) % n
+		if match, _ := g.members[j].carries(ssrc); match {
+			g.cursor = (j + 1) % n
+			return g.members[j]
+		}
+	}
+	return nil
+}
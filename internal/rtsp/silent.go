@@ -0,0 +1,67 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// IdleResult reports what the server did with a silent connection
+type IdleResult struct {
+	Connected bool
+	Reaped    bool          // The server closed or reset the connection
+	After     time.Duration // Connect to reaping
+	Vanished  bool          // HalfOpen: the socket was dropped without FIN or RST
+}
+
+// Idle returns the silent connection outcome, or false if the client is of
+// another type
+func (bc *BadClient) Idle() (IdleResult, bool) {
+	return bc.idle, bc.clientType == SilentHolder || bc.clientType == HalfOpen
+}
+
+// runSilent connects and sends nothing, waiting for the server to reap the
+// connection. With halfOpen, TCP keepalive is off and at the end the
+// socket is dropped without telling the server, leaving its side half-open
+// until it notices on its own.
+func (bc *BadClient) runSilent(ctx context.Context, halfOpen bool) error {
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	if halfOpen {
+		dialer.KeepAlive = -1
+	}
+	if err := bc.dial(dialer); err != nil {
+		return err
+	}
+	start := time.Now()
+	bc.idle.Connected = true
+
+	closed := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			if _, err := bc.conn.Read(buf); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-closed:
+		bc.idle.Reaped = true
+		bc.idle.After = time.Since(start)
+		bc.conn.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	if halfOpen {
+		if tcp, ok := bc.tcpConn(); ok && abandon(tcp) == nil {
+			bc.idle.Vanished = true
+			return ctx.Err()
+		}
+	}
+	bc.conn.Close()
+	return ctx.Err()
+}
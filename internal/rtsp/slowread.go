@@ -0,0 +1,126 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"context"
+	"io"
+	"net"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// SlowStallThreshold is how long a rate-capped reader may go without
+// media before the gap counts as a stall
+const SlowStallThreshold = time.Second
+
+// SlowReadResult describes how the server treated a rate-capped reader
+type SlowReadResult struct {
+	Enabled bool
+	Packets uint64        // RTP packets received
+	Lost    uint64        // Sequence gaps: the server dropped media rather than queue it
+	Lag     time.Duration // Arrival time minus media time elapsed on the first track: how far the server's queue fell behind
+	Stalls  uint64        // Gaps of more than SlowStallThreshold without media
+}
+
+// slowRead tracks media delivery on the first track of a rate-capped reader
+type slowRead struct {
+	started      bool
+	lastTS       uint32
+	elapsed      int64 // Timestamp units since the first packet
+	firstArrival time.Time
+	lastArrival  time.Time
+	stalls       uint64
+}
+
+// SetReadRate makes the client a slow consumer: the TCP connection is read
+// at no more than kbps, so the server's send queue and TCP backpressure
+// take the excess. A positive rcvbuf shrinks the socket receive buffer so
+// the backpressure reaches the server sooner. Only TCP transport is
+// affected; UDP cannot push back.
+func (c *Client) SetReadRate(kbps, rcvbuf int) {
+	c.readKbps = kbps
+	c.readBuffer = rcvbuf
+}
+
+// SlowReadResult reports the slow consumer outcome once Run has returned
+func (c *Client) SlowReadResult() SlowReadResult {
+	if c.slow == nil {
+		return SlowReadResult{}
+	}
+	res := SlowReadResult{
+		Enabled: true,
+		Packets: c.packetsRcvd.Load(),
+		Stalls:  c.slow.stalls,
+	}
+	for _, t := range c.tracks {
+		res.Lost += t.sources.GetStats().Lost
+	}
+	if c.slow.started && len(c.tracks) > 0 {
+		media := time.Duration(float64(c.slow.elapsed) / float64(c.tracks[0].media.ClockRate()) * float64(time.Second))
+		res.Lag = c.slow.lastArrival.Sub(c.slow.firstArrival) - media
+	}
+	return res
+}
+
+// readSource returns what the control connection is read through: conn
+// itself, or conn behind the read rate cap
+func (c *Client) readSource(conn net.Conn) io.Reader {
+	if c.readKbps <= 0 || c.datagramMedia() {
+		return conn
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok && c.readBuffer > 0 {
+		tcp.SetReadBuffer(c.readBuffer)
+	}
+
+	bytesPerSec := float64(c.readKbps) * 1000 / 8
+	burst := int(bytesPerSec / 10)
+	if burst < 1500 {
+		burst = 1500
+	}
+	c.slow = &slowRead{}
+	return &throttledReader{
+		r:       conn,
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSec), burst),
+	}
+}
+
+// trackSlowRead follows media time against arrival time on the first track
+func (c *Client) trackSlowRead(t *mediaTrack, timestamp uint32, now time.Time) {
+	if c.slow == nil || t.index != 0 {
+		return
+	}
+	s := c.slow
+	if !s.started {
+		s.started = true
+		s.firstArrival = now
+	} else {
+		if now.Sub(s.lastArrival) > SlowStallThreshold {
+			s.stalls++
+		}
+		if delta := int32(timestamp - s.lastTS); delta > 0 {
+			s.elapsed += int64(delta)
+		}
+	}
+	s.lastTS = timestamp
+	s.lastArrival = now
+}
+
+// throttledReader reads no faster than its limiter allows
+type throttledReader struct {
+	r       net.Conn
+	limiter *rate.Limiter
+}
+
+// Read reads at most one burst and then waits until the limiter has
+// tokens for what was read
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if burst := t.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.limiter.WaitN(context.Background(), n)
+	}
+	return n, err
+}
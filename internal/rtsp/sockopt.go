@@ -0,0 +1,119 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// SocketOptions tunes a client's sockets: the control connection, which
+// also carries interleaved media, and the UDP media sockets. The zero
+// value keeps the Go and kernel defaults.
+type SocketOptions struct {
+	Nagle             bool          // Clear TCP_NODELAY, which Go sets, so small writes are coalesced
+	RecvBuffer        int           // SO_RCVBUF in bytes (0 = kernel default, 2MB for UDP RTP sockets)
+	SendBuffer        int           // SO_SNDBUF in bytes (0 = kernel default)
+	KeepAliveIdle     time.Duration // Idle time before TCP keepalive probes (0 = Go's 15s, negative = no keepalive)
+	KeepAliveInterval time.Duration // Between keepalive probes (0 = KeepAliveIdle; Linux only)
+	KeepAliveCount    int           // Unanswered probes before the connection drops (0 = kernel default; Linux only)
+	DSCP              int           // Differentiated Services code point of sent packets, 0-63 (0 = unmarked; Linux only)
+}
+
+// Validate reports options out of range or unsupported on this platform
+func (o SocketOptions) Validate() error {
+	if o.DSCP < 0 || o.DSCP > 63 {
+		return fmt.Errorf("DSCP %d is not in 0-63", o.DSCP)
+	}
+	if o.RecvBuffer < 0 || o.SendBuffer < 0 {
+		return errors.New("negative socket buffer size")
+	}
+	if o.KeepAliveInterval < 0 || o.KeepAliveCount < 0 {
+		return errors.New("negative keepalive interval or count")
+	}
+	return o.supported()
+}
+
+// SetSocketOptions tunes the sockets the client opens from now on
+func (c *Client) SetSocketOptions(opts SocketOptions) {
+	c.sockOpts = opts
+}
+
+// dialControl prepares a control connection socket before it connects
+func (c *Client) dialControl(network, address string, raw syscall.RawConn) error {
+	if err := c.source.control(network, address, raw); err != nil {
+		return err
+	}
+	return c.sockOpts.control(network, raw)
+}
+
+// tuneTCP applies the options that take effect on a connected control
+// connection
+func (o SocketOptions) tuneTCP(conn net.Conn) error {
+	tcp, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if o.Nagle {
+		if err := tcp.SetNoDelay(false); err != nil {
+			return fmt.Errorf("TCP_NODELAY: %w", err)
+		}
+	}
+	if !buffersBeforeConnect {
+		if err := setBuffers(tcp, o); err != nil {
+			return err
+		}
+	}
+	if o.KeepAliveIdle >= 0 && (o.KeepAliveInterval > 0 || o.KeepAliveCount > 0) {
+		return o.keepAliveProbes(tcp)
+	}
+	return nil
+}
+
+// tuneUDP applies the options to a UDP media socket
+func (o SocketOptions) tuneUDP(conn net.PacketConn) error {
+	udp, ok := conn.(*net.UDPConn)
+	if !ok {
+		return nil
+	}
+	if err := setBuffers(udp, o); err != nil {
+		return err
+	}
+	if o.DSCP == 0 {
+		return nil
+	}
+	raw, err := udp.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return o.markDSCP(raw, udp.LocalAddr().(*net.UDPAddr).IP.To4() == nil)
+}
+
+// bufferedConn is a socket whose buffers Go can size
+type bufferedConn interface {
+	SetReadBuffer(bytes int) error
+	SetWriteBuffer(bytes int) error
+}
+
+// setBuffers sizes the buffers of an open socket
+func setBuffers(conn bufferedConn, o SocketOptions) error {
+	if o.RecvBuffer > 0 {
+		if err := conn.SetReadBuffer(o.RecvBuffer); err != nil {
+			return fmt.Errorf("SO_RCVBUF: %w", err)
+		}
+	}
+	if o.SendBuffer > 0 {
+		if err := conn.SetWriteBuffer(o.SendBuffer); err != nil {
+			return fmt.Errorf("SO_SNDBUF: %w", err)
+		}
+	}
+	return nil
+}
+
+// ipv6Network reports whether a Dialer.Control network is IPv6
+func ipv6Network(network string) bool {
+	return strings.HasSuffix(network, "6")
+}
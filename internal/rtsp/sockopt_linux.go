@@ -0,0 +1,103 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build linux
+
+package rtsp
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// buffersBeforeConnect: the receive buffer set before connecting also
+// bounds the window scale offered in the SYN
+const buffersBeforeConnect = true
+
+// supported accepts every option on Linux
+func (o SocketOptions) supported() error {
+	return nil
+}
+
+// control sets the buffers and DSCP of a control connection socket before
+// it connects, so the SYN is already marked
+func (o SocketOptions) control(network string, c syscall.RawConn) error {
+	if o.RecvBuffer == 0 && o.SendBuffer == 0 && o.DSCP == 0 {
+		return nil
+	}
+	var err error
+	if cerr := c.Control(func(fd uintptr) {
+		if o.RecvBuffer > 0 {
+			if err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF, o.RecvBuffer); err != nil {
+				err = fmt.Errorf("SO_RCVBUF: %w", err)
+				return
+			}
+		}
+		if o.SendBuffer > 0 {
+			if err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_SNDBUF, o.SendBuffer); err != nil {
+				err = fmt.Errorf("SO_SNDBUF: %w", err)
+				return
+			}
+		}
+		if o.DSCP > 0 {
+			err = setDSCP(int(fd), o.DSCP, ipv6Network(network))
+		}
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// markDSCP sets the DSCP of an open socket
+func (o SocketOptions) markDSCP(c syscall.RawConn, ipv6 bool) error {
+	var err error
+	if cerr := c.Control(func(fd uintptr) {
+		err = setDSCP(int(fd), o.DSCP, ipv6)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// setDSCP marks the packets of fd with dscp. IPv6 sockets get the traffic
+// class too, and the TOS for the IPv4 peers of a dual-stack socket.
+func setDSCP(fd, dscp int, ipv6 bool) error {
+	tos := dscp << 2
+	if ipv6 {
+		if err := unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_TCLASS, tos); err != nil {
+			return fmt.Errorf("IPV6_TCLASS: %w", err)
+		}
+	}
+	if err := unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_TOS, tos); err != nil {
+		return fmt.Errorf("IP_TOS: %w", err)
+	}
+	return nil
+}
+
+// keepAliveProbes sets the interval and count of keepalive probes once
+// Go has enabled keepalives, which sets the interval to the idle time
+func (o SocketOptions) keepAliveProbes(conn *net.TCPConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	if cerr := raw.Control(func(fd uintptr) {
+		if o.KeepAliveInterval > 0 {
+			secs := max(int(o.KeepAliveInterval.Seconds()), 1)
+			if err = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, secs); err != nil {
+				err = fmt.Errorf("TCP_KEEPINTVL: %w", err)
+				return
+			}
+		}
+		if o.KeepAliveCount > 0 {
+			if err = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPCNT, o.KeepAliveCount); err != nil {
+				err = fmt.Errorf("TCP_KEEPCNT: %w", err)
+			}
+		}
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build !linux
+
+package rtsp
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// buffersBeforeConnect: buffers are sized once the socket is connected
+const buffersBeforeConnect = false
+
+// supported rejects the options only implemented on Linux
+func (o SocketOptions) supported() error {
+	if o.DSCP != 0 || o.KeepAliveInterval != 0 || o.KeepAliveCount != 0 {
+		return errors.New("DSCP marking and keepalive probe interval and count are only supported on Linux")
+	}
+	return nil
+}
+
+// control sets nothing before connecting outside Linux
+func (o SocketOptions) control(network string, c syscall.RawConn) error {
+	return nil
+}
+
+// markDSCP is not available outside Linux
+func (o SocketOptions) markDSCP(c syscall.RawConn, ipv6 bool) error {
+	return errors.New("DSCP marking is only supported on Linux")
+}
+
+// keepAliveProbes is not available outside Linux
+func (o SocketOptions) keepAliveProbes(conn *net.TCPConn) error {
+	return errors.New("keepalive probe interval and count are only supported on Linux")
+}
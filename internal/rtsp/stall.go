@@ -0,0 +1,84 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DefaultStallThreshold is how long a playing session may go without any
+// RTP packet before the gap counts as a stall
+const DefaultStallThreshold = 500 * time.Millisecond
+
+// stallTracker detects gaps in media arrival across all tracks of a
+// session. Packets of every track count, so an audio track keeps a video
+// freeze from being a stall; the bench measures rebuffering, not frames.
+type stallTracker struct {
+	last   atomic.Int64  // UnixNano of the last RTP packet, 0 = none since (re)start
+	stalls atomic.Uint64 // In the current session
+	count  atomic.Uint64 // Across sessions
+	total  atomic.Int64  // Across sessions, nanoseconds
+}
+
+// SetStallThreshold sets how long a playing session may go without RTP
+// before the gap is recorded as a stall (default DefaultStallThreshold)
+func (c *Client) SetStallThreshold(d time.Duration) {
+	c.stallThreshold = d
+}
+
+// stallLimit returns the stall threshold in effect
+func (c *Client) stallLimit() time.Duration {
+	if c.stallThreshold > 0 {
+		return c.stallThreshold
+	}
+	return DefaultStallThreshold
+}
+
+// trackStall records a packet arrival, and the stall it ends if the gap
+// since the previous packet exceeded the threshold
+func (c *Client) trackStall(now time.Time) {
+	prev := c.stall.last.Swap(now.UnixNano())
+	if prev == 0 {
+		return
+	}
+	if gap := time.Duration(now.UnixNano() - prev); gap > c.stallLimit() {
+		c.addStall(gap)
+	}
+}
+
+// addStall counts a stall of the current session
+func (c *Client) addStall(gap time.Duration) {
+	c.stall.stalls.Add(1)
+	c.stall.count.Add(1)
+	c.stall.total.Add(int64(gap))
+	c.aggregator.AddStall(gap)
+}
+
+// Stalls returns the stalls of every session the client played so far,
+// and the time spent in them
+func (c *Client) Stalls() (int, time.Duration) {
+	return int(c.stall.count.Load()), time.Duration(c.stall.total.Load())
+}
+
+// restartStall forgets the last arrival, so the gap of a PAUSE or seek,
+// which the viewer asked for, is not a stall
+func (c *Client) restartStall() {
+	c.stall.last.Store(0)
+}
+
+// reportStall ends the session's stall tracking: a gap still running
+// counts as a stall, and the session is counted as stalled or not. Sessions
+// that never received media are not counted; they failed rather than
+// stalled.
+func (c *Client) reportStall() {
+	last := c.stall.last.Swap(0)
+	if c.packetsRcvd.Load() == 0 {
+		return
+	}
+	if last != 0 {
+		if gap := time.Since(time.Unix(0, last)); gap > c.stallLimit() {
+			c.addStall(gap)
+		}
+	}
+	c.aggregator.AddStallSession(c.stall.stalls.Swap(0) > 0)
+}
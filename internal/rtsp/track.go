@@ -0,0 +1,230 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/codec"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/impair"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtp"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/sdp"
+)
+
+// mediaTrack holds the transport and reception state of one SETUP track
+type mediaTrack struct {
+	index int
+	media sdp.Media
+
+	// UDP: dedicated socket pair, or the SharedUDP sockets, and the server
+	// ports and SSRC (0 = not announced) it talks to
+	rtpConn       net.PacketConn
+	rtcpConn      net.PacketConn
+	shared        bool
+	serverRTP     int
+	serverRTCP    int
+	announcedSSRC uint32
+
+	// Multicast: group announced in the SETUP response (destination and
+	// port pair), and the group joined
+	destination net.IP
+	groupPorts  [2]int
+	group       *net.UDPAddr
+	groupRTCP   int
+
+	// TCP: interleaved channel pair
+	rtpChannel  int
+	rtcpChannel int
+
+	// Reception state, demultiplexed by SSRC
+	ssrc    atomic.Uint32 // SSRC of the last RTP packet received
+	packets atomic.Uint64
+	sources *rtp.SourceDemux
+	counts  *rtp.Batch // Packet, byte and loss counts pending for the aggregator
+	
+	// RTCP feedback: lost packets awaiting a NACK repair or RTX recovery,
+	// and the last unanswered PLI (UnixNano, 0 = none) with the keyframe
+	// count when it was sent
+	missing      *missingSet
+	pliSent      atomic.Int64
+	pliKeyframes atomic.Uint64
+	
+	// Retransmission (RFC 4588): payload types of this track carrying RTX,
+	// or on an RTX-only track the track it repairs; repaired is set on
+	// tracks that have an RTX stream
+	rtxTypes map[uint8]bool
+	repairs  *mediaTrack
+	repaired bool
+	
+	// Emulated network impairment, nil if none
+	impairer *impair.Impairer
+	
+	// H.264/H.265 payload analysis, nil for other codecs
+	video *codec.Analyzer
+	
+	// AAC/Opus payload validation, nil for other codecs
+	audio *codec.AudioValidator
+	
+	// MPEG-TS continuity and PCR analysis for MP2T tracks, nil otherwise
+	ts *codec.TSAnalyzer
+	
+	// ONVIF: metadata received on the track, or backchannel audio sent on
+	// it (nil = a receive track)
+	metadata bool
+	send     *backchannelSender
+	
+	// Pending RTP-Info check after a seek, nil if none
+	seek atomic.Pointer[seekCheck]
+
+	// Last SR per sender SSRC, for LSR/DLSR
+	srMu      sync.Mutex
+	senderRpt map[uint32]senderInfo
+}
+
+// channelRoute maps a TCP interleaved channel to its track
+type channelRoute struct {
+	track *mediaTrack
+	rtcp  bool
+}
+
+// senderInfo records the last SR received from a sender
+type senderInfo struct {
+	lsr      uint32 // Middle 32 bits of the SR NTP timestamp
+	received time.Time
+}
+
+// newMediaTrack creates the state for the index-th track of the SDP,
+// counting its packets into agg
+func newMediaTrack(index int, media sdp.Media, agg *rtp.Aggregator) *mediaTrack {
+	return &mediaTrack{
+		index:       index,
+		media:       media,
+		rtpChannel:  2 * index,
+		rtcpChannel: 2*index + 1,
+		sources:     rtp.NewSourceDemux(media.ClockRate()),
+		counts:      rtp.NewBatch(agg),
+		video:       codec.NewAnalyzer(media.Codec(), media.ClockRate()),
+		ts:          codec.NewTSAnalyzer(media.Codec()),
+		audio:       newAudioValidator(media),
+		metadata:    media.IsONVIFMetadata(),
+		send:        newBackchannelSender(media),
+		senderRpt:   make(map[uint32]senderInfo),
+		missing:     newMissingSet(),
+	}
+}
+
+// newAudioValidator returns the validator of an AAC or Opus media, or nil
+func newAudioValidator(media sdp.Media) *codec.AudioValidator {
+	if len(media.PayloadTypes) == 0 {
+		return nil
+	}
+	return codec.NewAudioValidator(media.Codec(), media.FMTP[media.PayloadTypes[0]])
+}
+
+// listenUDP allocates the track's RTP and RTCP sockets on src, tuned by
+// opts
+func (t *mediaTrack) listenUDP(src Source, opts SocketOptions) error {
+	rtpConn, err := src.listenUDP()
+	if err != nil {
+		return fmt.Errorf("failed to create RTP socket: %w", err)
+	}
+	// Increase receive buffer size for better performance
+	if conn, ok := rtpConn.(*net.UDPConn); ok {
+		conn.SetReadBuffer(2 * 1024 * 1024) // 2MB buffer
+	}
+
+	rtcpConn, err := src.listenUDP()
+	if err != nil {
+		rtpConn.Close()
+		return fmt.Errorf("failed to create RTCP socket: %w", err)
+	}
+	for _, conn := range []net.PacketConn{rtpConn, rtcpConn} {
+		if err := opts.tuneUDP(conn); err != nil {
+			rtpConn.Close()
+			rtcpConn.Close()
+			return fmt.Errorf("failed to tune UDP socket: %w", err)
+		}
+	}
+
+	t.rtpConn = rtpConn
+	t.rtcpConn = rtcpConn
+	return nil
+}
+
+// clientPorts returns the local RTP and RTCP ports
+func (t *mediaTrack) clientPorts() (int, int) {
+	return t.rtpConn.LocalAddr().(*net.UDPAddr).Port, t.rtcpConn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// close releases the track's UDP sockets; shared ones stay open
+func (t *mediaTrack) close() {
+	if t.shared {
+		return
+	}
+	if t.rtpConn != nil {
+		t.rtpConn.Close()
+	}
+	if t.rtcpConn != nil {
+		t.rtcpConn.Close()
+	}
+}
+
+// TrackStats holds per-track reception statistics
+type TrackStats struct {
+	Type     string // video, audio, application
+	Codec    string // Encoding name from the SDP
+	SSRC     uint32 // Last SSRC seen
+	Sources  int    // Distinct SSRCs seen
+	Packets  uint64
+	Lost     uint64
+	JitterMs float64 // Highest jitter across sources
+	
+	Video *codec.Stats      // Keyframe/GOP analysis for H.264/H.265 tracks
+	TS    *codec.TSStats    // Continuity and PCR analysis for MP2T tracks
+	Audio *codec.AudioStats // Payload validation for AAC and Opus tracks
+}
+
+// TrackStats returns reception statistics for each set-up track
+func (c *Client) TrackStats() []TrackStats {
+	stats := make([]TrackStats, 0, len(c.tracks))
+	for _, t := range c.tracks {
+		sources := t.sources.Sources()
+		var jitter float64
+		for _, src := range sources {
+			if j := src.Jitter.JitterMs(); j > jitter {
+				jitter = j
+			}
+		}
+		var video *codec.Stats
+		if t.video != nil {
+			vs := t.video.Stats()
+			video = &vs
+		}
+		var ts *codec.TSStats
+		if t.ts != nil {
+			s := t.ts.Stats()
+			ts = &s
+		}
+		var audio *codec.AudioStats
+		if t.audio != nil {
+			s := t.audio.Stats()
+			audio = &s
+		}
+		stats = append(stats, TrackStats{
+			Type:     t.media.Type,
+			Codec:    t.media.Codec(),
+			SSRC:     t.ssrc.Load(),
+			Sources:  len(sources),
+			Packets:  t.packets.Load(),
+			Lost:     t.sources.GetStats().Lost,
+			JitterMs: jitter,
+			Video:    video,
+			TS:       ts,
+			Audio:    audio,
+		})
+	}
+	return stats
+}
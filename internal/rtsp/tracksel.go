@@ -0,0 +1,61 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/sdp"
+)
+
+// Track selections: which of the SDP's media a reader sets up
+const (
+	TracksAll   = "all"
+	TracksVideo = "video"
+	TracksAudio = "audio"
+)
+
+// ErrNoTrack is returned from Run when the SDP has no media of the
+// selected kind
+var ErrNoTrack = errors.New("no track of the selected kind")
+
+// ParseTrackSelection checks a track selection name; empty is TracksAll
+func ParseTrackSelection(s string) (string, error) {
+	switch s = strings.ToLower(s); s {
+	case "":
+		return TracksAll, nil
+	case TracksAll, TracksVideo, TracksAudio:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown track selection %q: want all, video or audio", s)
+}
+
+// SetTrackSelection makes the client SETUP only the SDP's video or audio
+// media (TracksVideo, TracksAudio), or all of them (TracksAll, the default)
+func (c *Client) SetTrackSelection(tracks string) {
+	c.trackSelection = tracks
+}
+
+// selectedMedias returns the media to SETUP: those of the selected kind,
+// and backchannels only if the backchannel is enabled
+func (c *Client) selectedMedias() ([]sdp.Media, error) {
+	var medias []sdp.Media
+	selected := 0
+	for _, m := range c.medias {
+		if m.IsBackchannel() {
+			if c.backchannel {
+				medias = append(medias, m)
+			}
+			continue
+		}
+		if c.trackSelection == "" || c.trackSelection == TracksAll || strings.EqualFold(m.Type, c.trackSelection) {
+			medias = append(medias, m)
+			selected++
+		}
+	}
+	if selected == 0 && c.trackSelection != "" && c.trackSelection != TracksAll {
+		return nil, fmt.Errorf("%w: %s", ErrNoTrack, c.trackSelection)
+	}
+	return medias, nil
+}
@@ -0,0 +1,107 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transcript entry kinds
+const (
+	TranscriptSent     = "sent"     // Request or answer written by the client
+	TranscriptReceived = "received" // Response or request from the server
+	TranscriptNote     = "note"     // Connection event, not on the wire
+)
+
+// TranscriptEntry is a message exchanged on the RTSP connection
+type TranscriptEntry struct {
+	Time    time.Time
+	Kind    string
+	Message string
+}
+
+// transcript keeps the last messages of a client in a ring buffer.
+// Interleaved media and RTCP are not recorded.
+type transcript struct {
+	mu      sync.Mutex
+	entries []TranscriptEntry
+	next    int
+	total   int
+}
+
+// add records a message; a nil transcript records nothing
+func (t *transcript) add(kind, message string) {
+	if t == nil {
+		return
+	}
+	if kind == TranscriptSent {
+		message = redactBasicAuth(message)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries[t.next] = TranscriptEntry{Time: time.Now(), Kind: kind, Message: message}
+	t.next = (t.next + 1) % len(t.entries)
+	t.total++
+}
+
+// redactBasicAuth hides Basic credentials, which are the password in
+// base64, so transcripts can be attached to bug reports
+func redactBasicAuth(message string) string {
+	lines := strings.SplitAfter(message, "\n")
+	for i, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(name, "Authorization") &&
+			strings.HasPrefix(strings.ToLower(strings.TrimSpace(value)), "basic ") {
+			lines[i] = name + ": Basic (redacted)\r\n"
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// SetTranscript keeps the last n control messages of the client, across
+// reconnects, for Transcript. 0 disables it.
+func (c *Client) SetTranscript(n int) {
+	c.transcript = nil
+	if n > 0 {
+		c.transcript = &transcript{entries: make([]TranscriptEntry, n)}
+	}
+}
+
+// Transcript returns the kept messages, oldest first, and how many older
+// ones the ring buffer dropped
+func (c *Client) Transcript() ([]TranscriptEntry, int) {
+	t := c.transcript
+	if t == nil {
+		return nil, 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.total <= len(t.entries) {
+		return append([]TranscriptEntry(nil), t.entries[:t.total]...), 0
+	}
+	entries := append([]TranscriptEntry(nil), t.entries[t.next:]...)
+	return append(entries, t.entries[:t.next]...), t.total - len(t.entries)
+}
+
+// WriteTranscript prints entries as plain text: a marker line with the
+// time and kind, then the message as it was on the wire
+func WriteTranscript(w io.Writer, entries []TranscriptEntry, dropped int) error {
+	if dropped > 0 {
+		if _, err := fmt.Fprintf(w, "(%d earlier messages dropped)\n\n", dropped); err != nil {
+			return err
+		}
+	}
+	for _, e := range entries {
+		message := e.Message
+		if !strings.HasSuffix(message, "\n") {
+			message += "\n"
+		}
+		if _, err := fmt.Fprintf(w, "--- %s %s\n%s\n", e.Time.Format("15:04:05.000"), e.Kind, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
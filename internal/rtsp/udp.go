@@ -0,0 +1,99 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"net"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/rtcp"
+)
+
+// rtpPunchPacket is sent to the server RTP port to open NAT/firewall
+// pinholes; servers ignore it as it is not a valid RTP packet
+var rtpPunchPacket = []byte{0xce, 0xfa, 0xed, 0xfe}
+
+// resolveServerIP determines the media source address: the Transport
+// source= parameter if given, otherwise the RTSP control peer
+func (c *Client) resolveServerIP() {
+	if c.serverIP != nil {
+		return
+	}
+	host, _, err := net.SplitHostPort(c.conn.RemoteAddr().String())
+	if err == nil {
+		c.serverIP = net.ParseIP(host)
+	}
+}
+
+// punchHoles sends a packet from each local UDP socket to the matching
+// server port so that inbound RTP/RTCP can traverse NAT and stateful
+// firewalls
+func (c *Client) punchHoles() {
+	if c.serverIP == nil {
+		return
+	}
+	for _, t := range c.tracks {
+		if t.serverRTP > 0 {
+			c.writeUDP(t.rtpConn, rtpPunchPacket, &net.UDPAddr{IP: c.serverIP, Port: t.serverRTP})
+		}
+		if t.serverRTCP > 0 {
+			// An empty receiver report is a valid RTCP packet
+			c.writeUDP(t.rtcpConn, rtcp.BuildReceiverReport(c.localSSRC, nil), &net.UDPAddr{IP: c.serverIP, Port: t.serverRTCP})
+		}
+	}
+}
+
+// validSource reports whether a UDP packet came from the server. The port
+// is only checked when the server announced one.
+func (c *Client) validSource(addr net.Addr, port int) bool {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	if c.serverIP != nil && !udpAddr.IP.Equal(c.serverIP) {
+		return false
+	}
+	return port == 0 || udpAddr.Port == port
+}
+
+// UDP receive batching
+const (
+	udpBatchSize = 32    // Datagrams per receive call where batching is supported
+	udpSlotSize  = 2048  // Bytes per batched datagram; RTP is packetized below the MTU
+	udpReadSize  = 65536 // Single-read buffer, large enough for jumbo frames
+)
+
+// udpPacket is one datagram of a UDP read
+type udpPacket struct {
+	buf  []byte
+	n    int
+	addr net.Addr
+}
+
+// udpReader receives one or more datagrams per call. Returned packets are
+// only valid until the next read.
+type udpReader interface {
+	read() ([]udpPacket, error)
+}
+
+// readFromReader is the portable udpReader: one ReadFrom per datagram
+type readFromReader struct {
+	conn net.PacketConn
+	pkt  [1]udpPacket
+}
+
+// newReadFromReader creates a udpReader that reads one datagram at a time
+func newReadFromReader(conn net.PacketConn) *readFromReader {
+	r := &readFromReader{conn: conn}
+	r.pkt[0].buf = make([]byte, udpReadSize)
+	return r
+}
+
+// read receives a single datagram
+func (r *readFromReader) read() ([]udpPacket, error) {
+	n, addr, err := r.conn.ReadFrom(r.pkt[0].buf)
+	if err != nil {
+		return nil, err
+	}
+	r.pkt[0].n = n
+	r.pkt[0].addr = addr
+	return r.pkt[:], nil
+}
@@ -0,0 +1,127 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build linux
+
+package rtsp
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// mmsghdr mirrors struct mmsghdr from <sys/socket.h>
+type mmsghdr struct {
+	hdr unix.Msghdr
+	len uint32
+}
+
+// mmsgReader receives up to udpBatchSize datagrams per recvmmsg(2) call,
+// waiting for readiness through the runtime poller so read deadlines still
+// apply
+type mmsgReader struct {
+	raw   syscall.RawConn
+	pkts  []udpPacket
+	hdrs  []mmsghdr
+	iovs  []unix.Iovec
+	names []unix.RawSockaddrInet6 // Large enough for either family
+	addrs []net.UDPAddr
+}
+
+// newUDPReader returns a recvmmsg reader for UDP sockets, or the ReadFrom
+// loop for anything else
+func newUDPReader(conn net.PacketConn) udpReader {
+	udpConn, ok := conn.(*net.UDPConn)
+	if !ok {
+		return newReadFromReader(conn)
+	}
+	raw, err := udpConn.SyscallConn()
+	if err != nil {
+		return newReadFromReader(conn)
+	}
+
+	r := &mmsgReader{
+		raw:   raw,
+		pkts:  make([]udpPacket, udpBatchSize),
+		hdrs:  make([]mmsghdr, udpBatchSize),
+		iovs:  make([]unix.Iovec, udpBatchSize),
+		names: make([]unix.RawSockaddrInet6, udpBatchSize),
+		addrs: make([]net.UDPAddr, udpBatchSize),
+	}
+	buf := make([]byte, udpBatchSize*udpSlotSize)
+	for i := range r.pkts {
+		r.pkts[i].buf = buf[i*udpSlotSize : (i+1)*udpSlotSize : (i+1)*udpSlotSize]
+		r.pkts[i].addr = &r.addrs[i]
+		r.addrs[i].IP = make(net.IP, net.IPv6len)
+		r.iovs[i].Base = &r.pkts[i].buf[0]
+		r.iovs[i].SetLen(udpSlotSize)
+		r.hdrs[i].hdr.Iov = &r.iovs[i]
+		r.hdrs[i].hdr.SetIovlen(1)
+		r.hdrs[i].hdr.Name = (*byte)(unsafe.Pointer(&r.names[i]))
+	}
+	return r
+}
+
+// read receives at least one and up to udpBatchSize datagrams
+func (r *mmsgReader) read() ([]udpPacket, error) {
+	var n int
+	var errno syscall.Errno
+	err := r.raw.Read(func(fd uintptr) bool {
+		for i := range r.hdrs {
+			r.hdrs[i].hdr.Namelen = unix.SizeofSockaddrInet6
+		}
+		for {
+			res, _, e := unix.Syscall6(unix.SYS_RECVMMSG, fd,
+				uintptr(unsafe.Pointer(&r.hdrs[0])), uintptr(len(r.hdrs)),
+				unix.MSG_DONTWAIT, 0, 0)
+			switch e {
+			case unix.EINTR:
+				continue
+			case unix.EAGAIN:
+				return false // Wait for the poller
+			}
+			n, errno = int(res), e
+			return true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if errno != 0 {
+		return nil, os.NewSyscallError("recvmmsg", errno)
+	}
+
+	for i := 0; i < n; i++ {
+		r.pkts[i].n = int(r.hdrs[i].len)
+		r.decodeAddr(i)
+	}
+	return r.pkts[:n], nil
+}
+
+// decodeAddr fills the i-th source address from its raw sockaddr
+func (r *mmsgReader) decodeAddr(i int) {
+	addr := &r.addrs[i]
+	addr.IP = addr.IP[:cap(addr.IP)]
+	switch sa := &r.names[i]; sa.Family {
+	case unix.AF_INET:
+		sa4 := (*unix.RawSockaddrInet4)(unsafe.Pointer(sa))
+		addr.IP = addr.IP[:net.IPv4len]
+		copy(addr.IP, sa4.Addr[:])
+		addr.Port = networkPort(sa4.Port)
+	case unix.AF_INET6:
+		copy(addr.IP, sa.Addr[:])
+		addr.Port = networkPort(sa.Port)
+	default:
+		addr.IP = addr.IP[:0]
+		addr.Port = 0
+	}
+}
+
+// networkPort converts a sockaddr port, stored in network byte order
+func networkPort(port uint16) int {
+	b := (*[2]byte)(unsafe.Pointer(&port))
+	return int(b[0])<<8 | int(b[1])
+}
@@ -0,0 +1,12 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+//go:build !linux
+
+package rtsp
+
+import "net"
+
+// newUDPReader returns the ReadFrom loop; batched receive is Linux only
+func newUDPReader(conn net.PacketConn) udpReader {
+	return newReadFromReader(conn)
+}
@@ -0,0 +1,115 @@
+// Created by WINK Streaming (https://www.wink.co)
+package rtsp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// writeQueueSize is how many writes may wait for the connection during
+// playback before posted frames are dropped
+const writeQueueSize = 64
+
+// errWriteQueueFull is returned by post when the server has stopped taking
+// data for long enough that the queue filled up
+var errWriteQueueFull = errors.New("write queue full")
+
+// writeJob is data waiting for the connection. done receives the outcome,
+// unless it is nil: the data was posted and nobody waits.
+type writeJob struct {
+	data []byte
+	done chan error
+}
+
+// writer owns the connection's writes during playback, so the reader never
+// blocks on a server that stopped reading: RTCP, backchannel media and
+// answers to server requests are posted, and requests wait their turn.
+type writer struct {
+	queue   chan writeJob
+	stopped chan struct{}
+}
+
+// startWriter starts the writer goroutine; writes go through it until
+// stop. Failures of posted writes, which nobody waits for, are passed to
+// fail.
+func (c *Client) startWriter(fail func(error)) (stop func()) {
+	w := &writer{queue: make(chan writeJob, writeQueueSize), stopped: make(chan struct{})}
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case <-w.stopped:
+				return
+			case job := <-w.queue:
+				err := c.writeNow(job.data)
+				if job.done != nil {
+					job.done <- err
+				} else if err != nil {
+					fail(fmt.Errorf("write failed: %w", err))
+				}
+			}
+		}
+	}()
+	c.writes.Store(w)
+	return func() {
+		c.writes.Store(nil)
+		close(w.stopped)
+		<-finished
+	}
+}
+
+// write sends data on the control connection and returns the outcome.
+// During playback it waits its turn behind the writes queued before it.
+func (c *Client) write(data []byte) error {
+	w := c.writes.Load()
+	if w == nil {
+		return c.writeNow(data)
+	}
+	job := writeJob{data: data, done: make(chan error, 1)}
+	select {
+	case w.queue <- job:
+	case <-w.stopped:
+		return errPlaybackEnded
+	}
+	select {
+	case err := <-job.done:
+		return err
+	case <-w.stopped:
+		return errPlaybackEnded
+	}
+}
+
+// post sends data without waiting for it to be written during playback,
+// for callers that must not block, such as the connection's reader. It
+// fails with errWriteQueueFull rather than wait for room in the queue.
+// Outside playback it writes at once, as write does.
+func (c *Client) post(data []byte) error {
+	w := c.writes.Load()
+	if w == nil {
+		return c.writeNow(data)
+	}
+	select {
+	case w.queue <- writeJob{data: data}:
+		return nil
+	case <-w.stopped:
+		return errPlaybackEnded
+	default:
+		return errWriteQueueFull
+	}
+}
+
+// writeNow writes data on the connection, failing if the server does not
+// take it within the request timeout instead of blocking the caller, and
+// whatever lock it holds, for as long as the server stops reading
+func (c *Client) writeNow(data []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(c.requestLimit()))
+	if _, err := c.conn.Write(data); err != nil {
+		if isTimeout(err) {
+			return fmt.Errorf("%w: write: %v", ErrControlTimeout, err)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+// Created by WINK Streaming (https://www.wink.co)
+package sdp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Session represents a parsed SDP session description
+type Session struct {
+	Name       string  // s= line
+	Control    string  // Session-level a=control (aggregate URI)
+	Range      string  // Session-level a=range
+	Connection string  // Session-level c= address, without TTL or count
+	Medias     []Media // m= sections in order of appearance
+}
+
+// Media represents a single m= section
+type Media struct {
+	Type         string // video, audio, application, ...
+	Port         int
+	Protocol     string // RTP/AVP, RTP/AVP/TCP, ...
+	PayloadTypes []int
+	Control      string         // a=control for this track
+	Connection   string         // Media-level c= address, without TTL or count
+	RTPMaps      map[int]RTPMap // a=rtpmap keyed by payload type
+	FMTP         map[int]string // a=fmtp keyed by payload type
+	Direction    string         // sendonly, recvonly, sendrecv or inactive (empty = not given)
+}
+
+// RTPMap holds an a=rtpmap entry
+type RTPMap struct {
+	Encoding  string
+	ClockRate int
+	Channels  int
+}
+
+// staticPayloads maps RFC 3551 static payload types to their encodings
+var staticPayloads = map[int]RTPMap{
+	0:  {Encoding: "PCMU", ClockRate: 8000, Channels: 1},
+	3:  {Encoding: "GSM", ClockRate: 8000, Channels: 1},
+	4:  {Encoding: "G723", ClockRate: 8000, Channels: 1},
+	8:  {Encoding: "PCMA", ClockRate: 8000, Channels: 1},
+	9:  {Encoding: "G722", ClockRate: 8000, Channels: 1},
+	10: {Encoding: "L16", ClockRate: 44100, Channels: 2},
+	11: {Encoding: "L16", ClockRate: 44100, Channels: 1},
+	14: {Encoding: "MPA", ClockRate: 90000},
+	18: {Encoding: "G729", ClockRate: 8000, Channels: 1},
+	26: {Encoding: "JPEG", ClockRate: 90000},
+	32: {Encoding: "MPV", ClockRate: 90000},
+	33: {Encoding: "MP2T", ClockRate: 90000},
+}
+
+// Parse parses an SDP body as returned by DESCRIBE
+func Parse(body string) (*Session, error) {
+	s := &Session{}
+	var media *Media
+
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(line) < 2 || line[1] != '=' {
+			continue
+		}
+
+		key, value := line[0], line[2:]
+		switch key {
+		case 's':
+			s.Name = value
+		case 'c':
+			if media == nil {
+				s.Connection = parseConnection(value)
+			} else {
+				media.Connection = parseConnection(value)
+			}
+		case 'm':
+			m, err := parseMediaLine(value)
+			if err != nil {
+				return nil, err
+			}
+			s.Medias = append(s.Medias, m)
+			media = &s.Medias[len(s.Medias)-1]
+		case 'a':
+			name, attr := value, ""
+			if idx := strings.IndexByte(value, ':'); idx >= 0 {
+				name, attr = value[:idx], value[idx+1:]
+			}
+			if media == nil {
+				s.parseSessionAttribute(name, attr)
+			} else {
+				media.parseAttribute(name, attr)
+			}
+		}
+	}
+
+	if len(s.Medias) == 0 {
+		return nil, fmt.Errorf("no media sections in SDP")
+	}
+	return s, nil
+}
+
+// parseMediaLine parses "video 0 RTP/AVP 96 97"
+func parseMediaLine(value string) (Media, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return Media{}, fmt.Errorf("malformed media line: %q", value)
+	}
+
+	m := Media{
+		Type:     fields[0],
+		Protocol: fields[2],
+		RTPMaps:  make(map[int]RTPMap),
+		FMTP:     make(map[int]string),
+	}
+
+	// Port may be "port/count"
+	port := strings.SplitN(fields[1], "/", 2)[0]
+	m.Port, _ = strconv.Atoi(port)
+
+	for _, f := range fields[3:] {
+		if pt, err := strconv.Atoi(f); err == nil {
+			m.PayloadTypes = append(m.PayloadTypes, pt)
+		}
+	}
+	return m, nil
+}
+
+// parseConnection returns the address of "IN IP4 239.1.1.1/127"
+func parseConnection(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return ""
+	}
+	return strings.SplitN(fields[2], "/", 2)[0]
+}
+
+// parseSessionAttribute handles session-level a= lines
+func (s *Session) parseSessionAttribute(name, value string) {
+	switch name {
+	case "control":
+		s.Control = strings.TrimSpace(value)
+	case "range":
+		s.Range = strings.TrimSpace(value)
+	}
+}
+
+// parseAttribute handles media-level a= lines
+func (m *Media) parseAttribute(name, value string) {
+	switch name {
+	case "control":
+		m.Control = strings.TrimSpace(value)
+	case "rtpmap":
+		// rtpmap:<pt> <encoding>/<clock>[/<channels>]
+		fields := strings.Fields(value)
+		if len(fields) < 2 {
+			return
+		}
+		pt, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return
+		}
+		parts := strings.Split(fields[1], "/")
+		rm := RTPMap{Encoding: parts[0]}
+		if len(parts) > 1 {
+			rm.ClockRate, _ = strconv.Atoi(parts[1])
+		}
+		if len(parts) > 2 {
+			rm.Channels, _ = strconv.Atoi(parts[2])
+		}
+		m.RTPMaps[pt] = rm
+	case "fmtp":
+		// fmtp:<pt> <params>
+		idx := strings.IndexByte(value, ' ')
+		if idx < 0 {
+			return
+		}
+		pt, err := strconv.Atoi(value[:idx])
+		if err != nil {
+			return
+		}
+		m.FMTP[pt] = strings.TrimSpace(value[idx+1:])
+	case "sendonly", "recvonly", "sendrecv", "inactive":
+		m.Direction = name
+	}
+}
+
+// RTPMap returns the rtpmap for a payload type, falling back to the
+// static RFC 3551 assignments
+func (m Media) RTPMap(pt int) (RTPMap, bool) {
+	if rm, ok := m.RTPMaps[pt]; ok {
+		return rm, true
+	}
+	rm, ok := staticPayloads[pt]
+	return rm, ok
+}
+
+// Codec returns the encoding name of the first payload type, or "" if unknown
+func (m Media) Codec() string {
+	if len(m.PayloadTypes) == 0 {
+		return ""
+	}
+	rm, _ := m.RTPMap(m.PayloadTypes[0])
+	return rm.Encoding
+}
+
+// ClockRate returns the clock rate of the first payload type, or 0 if unknown
+func (m Media) ClockRate() int {
+	if len(m.PayloadTypes) == 0 {
+		return 0
+	}
+	rm, _ := m.RTPMap(m.PayloadTypes[0])
+	return rm.ClockRate
+}
+
+// RTX returns the media's retransmission payload types (RFC 4588 rtpmap
+// "rtx") mapped to the payload type each one repairs, from fmtp apt=
+func (m Media) RTX() map[int]int {
+	var rtx map[int]int
+	for pt, rm := range m.RTPMaps {
+		if !strings.EqualFold(rm.Encoding, "rtx") {
+			continue
+		}
+		for _, param := range strings.Split(m.FMTP[pt], ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if name != "apt" {
+				continue
+			}
+			if apt, err := strconv.Atoi(value); err == nil {
+				if rtx == nil {
+					rtx = make(map[int]int)
+				}
+				rtx[pt] = apt
+			}
+		}
+	}
+	return rtx
+}
+
+// IsONVIFMetadata reports whether the media is an ONVIF metadata stream
+// (application/vnd.onvif.metadata)
+func (m Media) IsONVIFMetadata() bool {
+	return m.Type == "application" && strings.EqualFold(m.Codec(), "vnd.onvif.metadata")
+}
+
+// IsBackchannel reports whether the media is an ONVIF audio backchannel,
+// which the server marks sendonly from the client's point of view: the
+// client sends and the server receives
+func (m Media) IsBackchannel() bool {
+	return m.Type == "audio" && m.Direction == "sendonly"
+}
+
+// IsRTX reports whether the media only carries retransmissions, as a
+// separate m= section repairing another one
+func (m Media) IsRTX() bool {
+	if len(m.PayloadTypes) == 0 {
+		return false
+	}
+	rtx := m.RTX()
+	for _, pt := range m.PayloadTypes {
+		if _, ok := rtx[pt]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Duration returns the length of recorded media from a=range:npt=<start>-<end>.
+// Returns false for live streams (open-ended or "now" ranges) or if absent.
+func (s *Session) Duration() (time.Duration, bool) {
+	value, ok := strings.CutPrefix(s.Range, "npt=")
+	if !ok {
+		return 0, false
+	}
+	start, end, ok := strings.Cut(value, "-")
+	if !ok || start == "now" {
+		return 0, false
+	}
+	from, err := strconv.ParseFloat(strings.TrimSpace(start), 64)
+	if err != nil {
+		return 0, false
+	}
+	to, err := strconv.ParseFloat(strings.TrimSpace(end), 64)
+	if err != nil || to <= from {
+		return 0, false
+	}
+	return time.Duration((to - from) * float64(time.Second)), true
+}
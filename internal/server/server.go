@@ -0,0 +1,243 @@
+// Created by WINK Streaming (https://www.wink.co)
+package server
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/logging"
+	"github.com/winkstreaming/wink-rtsp-bench/internal/synth"
+)
+
+// Server defaults
+const (
+	DefaultAddr    = ":8554"
+	SessionTimeout = 60 * time.Second
+	frameQueue     = 64 // Frames buffered per session before dropping
+)
+
+// Options configures the test server
+type Options struct {
+	Addr   string        // RTSP listen address (default :8554)
+	Stream synth.Options // Stream served on every path
+	Log    *slog.Logger  // nil = discard
+}
+
+// Stats holds server counters
+type Stats struct {
+	Sessions      int64  `json:"sessions"` // Open RTSP connections
+	Playing       int64  `json:"playing"`  // Sessions receiving media
+	PacketsSent   uint64 `json:"packets_sent"`
+	BytesSent     uint64 `json:"bytes_sent"`
+	FramesDropped uint64 `json:"frames_dropped"` // Skipped for readers that fell behind
+}
+
+// Server is a minimal RTSP server that plays a deterministic synthetic
+// H.264 stream on any path, over TCP interleaved or UDP. Every path is an
+// independent live stream shared by all of its readers.
+type Server struct {
+	opts Options
+	log  *slog.Logger
+
+	mu      sync.Mutex
+	streams map[string]*broadcast
+
+	// Shared UDP sockets media is sent from; RTCP from readers is ignored
+	rtpConn  net.PacketConn
+	rtcpConn net.PacketConn
+
+	nextSession atomic.Uint64
+	sessions    atomic.Int64
+	playing     atomic.Int64
+	packets     atomic.Uint64
+	bytes       atomic.Uint64
+	dropped     atomic.Uint64
+}
+
+// New creates a server; call ListenAndServe to start it
+func New(opts Options) *Server {
+	if opts.Addr == "" {
+		opts.Addr = DefaultAddr
+	}
+	log := opts.Log
+	if log == nil {
+		log = logging.Discard()
+	}
+	return &Server{
+		opts:    opts,
+		log:     log,
+		streams: make(map[string]*broadcast),
+	}
+}
+
+// ListenAndServe listens on Options.Addr and serves until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve accepts RTSP connections on ln until ctx is done
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	host, _, _ := net.SplitHostPort(ln.Addr().String())
+	var err error
+	if s.rtpConn, err = net.ListenPacket("udp", net.JoinHostPort(host, "0")); err != nil {
+		ln.Close()
+		return err
+	}
+	defer s.rtpConn.Close()
+	if s.rtcpConn, err = net.ListenPacket("udp", net.JoinHostPort(host, "0")); err != nil {
+		ln.Close()
+		return err
+	}
+	defer s.rtcpConn.Close()
+	go discardPackets(s.rtcpConn)
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	s.log.Info("test server listening", "addr", ln.Addr().String(),
+		"bitrate_kbps", s.opts.Stream.BitrateKbps, "udp_rtp_port", udpPort(s.rtpConn))
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+// Stats returns the current counters
+func (s *Server) Stats() Stats {
+	return Stats{
+		Sessions:      s.sessions.Load(),
+		Playing:       s.playing.Load(),
+		PacketsSent:   s.packets.Load(),
+		BytesSent:     s.bytes.Load(),
+		FramesDropped: s.dropped.Load(),
+	}
+}
+
+// stream returns the broadcast for path, starting it on first use
+func (s *Server) stream(ctx context.Context, path string) *broadcast {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if b, ok := s.streams[path]; ok {
+		return b
+	}
+
+	// Each path gets its own stable SSRC
+	opts := s.opts.Stream
+	if opts.SSRC == 0 {
+		h := fnv.New32a()
+		h.Write([]byte(path))
+		opts.SSRC = h.Sum32() | 1
+	}
+	b := newBroadcast(synth.NewStream(opts), s)
+	s.streams[path] = b
+	go b.run(ctx)
+	return b
+}
+
+// discardPackets reads and drops packets until conn is closed
+func discardPackets(conn net.PacketConn) {
+	buf := make([]byte, 65536)
+	for {
+		if _, _, err := conn.ReadFrom(buf); err != nil {
+			return
+		}
+	}
+}
+
+// udpPort returns the local port of a UDP socket
+func udpPort(conn net.PacketConn) int {
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// broadcast generates one stream and fans its frames out to subscribers
+type broadcast struct {
+	stream *synth.Stream
+	srv    *Server
+
+	mu   sync.Mutex
+	subs map[*session]struct{}
+}
+
+// newBroadcast creates a broadcast of stream
+func newBroadcast(stream *synth.Stream, srv *Server) *broadcast {
+	return &broadcast{
+		stream: stream,
+		srv:    srv,
+		subs:   make(map[*session]struct{}),
+	}
+}
+
+// run generates a frame every frame interval until ctx is done. Frames are
+// produced whether or not anyone is watching, like a live encoder.
+func (b *broadcast) run(ctx context.Context) {
+	ticker := time.NewTicker(b.stream.FrameInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		b.mu.Lock()
+		frame := b.stream.NextFrame()
+		for sub := range b.subs {
+			select {
+			case sub.frames <- frame:
+			default:
+				b.srv.dropped.Add(1)
+			}
+		}
+		b.mu.Unlock()
+	}
+}
+
+// subscribe starts sending frames to sess and returns the sequence number
+// and timestamp of the first packet it will get
+func (b *broadcast) subscribe(sess *session) (uint16, uint32) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sess] = struct{}{}
+	return b.stream.Next()
+}
+
+// unsubscribe stops sending frames to sess
+func (b *broadcast) unsubscribe(sess *session) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs, sess)
+}
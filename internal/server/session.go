@@ -0,0 +1,420 @@
+// Created by WINK Streaming (https://www.wink.co)
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// trackControl is the a=control of the single video track
+const trackControl = "trackID=0"
+
+// Limits on request size, so garbage input cannot exhaust memory
+const (
+	maxHeaders = 64
+	maxBody    = 64 * 1024
+)
+
+// writeTimeout drops readers that stop draining their connection
+const writeTimeout = 5 * time.Second
+
+// request is a parsed RTSP request
+type request struct {
+	method  string
+	uri     string
+	headers map[string]string // Lower-case names
+	body    []byte
+}
+
+// session is one RTSP connection and the session set up on it
+type session struct {
+	srv    *Server
+	conn   net.Conn
+	reader *bufio.Reader
+	log    *slog.Logger
+	id     string
+
+	writeMu sync.Mutex
+
+	// Set up by SETUP
+	stream  *broadcast
+	base    string       // Aggregate URL, for RTP-Info
+	udp     *net.UDPAddr // Reader's RTP address, nil for TCP interleaved
+	channel byte         // TCP interleaved RTP channel
+	playing bool
+	writing bool // Media writer started; guarded by writeMu
+	frames  chan [][]byte
+	done    chan struct{}
+}
+
+// serveConn handles one RTSP connection until it closes, times out or ctx
+// is done
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	s.sessions.Add(1)
+	defer s.sessions.Add(-1)
+
+	sess := &session{
+		srv:    s,
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		id:     strconv.FormatUint(s.nextSession.Add(1)+100000, 10),
+		frames: make(chan [][]byte, frameQueue),
+		done:   make(chan struct{}),
+	}
+	sess.log = s.log.With("session", sess.id, "remote", conn.RemoteAddr().String())
+	defer sess.close()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-sess.done:
+		}
+	}()
+
+	sess.log.Debug("connection opened")
+	for {
+		// Readers must send a request (or RTCP, over TCP) within the timeout
+		conn.SetReadDeadline(time.Now().Add(SessionTimeout))
+
+		b, err := sess.reader.Peek(1)
+		if err != nil {
+			sess.log.Debug("connection closed", "error", err)
+			return
+		}
+		if b[0] == '$' {
+			if err := sess.discardInterleaved(); err != nil {
+				return
+			}
+			continue
+		}
+
+		req, err := readRequest(sess.reader)
+		if err != nil {
+			sess.log.Debug("bad request", "error", err)
+			sess.respond(nil, 400, "Bad Request", nil, "")
+			return
+		}
+		if !sess.handle(ctx, req) {
+			return
+		}
+	}
+}
+
+// handle answers one request. Returns false when the connection should close.
+func (sess *session) handle(ctx context.Context, req *request) bool {
+	if id, ok := req.headers["session"]; ok && sess.stream != nil {
+		if strings.TrimSpace(strings.Split(id, ";")[0]) != sess.id {
+			sess.respond(req, 454, "Session Not Found", nil, "")
+			return true
+		}
+	}
+
+	switch req.method {
+	case "OPTIONS":
+		sess.respond(req, 200, "OK", [][2]string{
+			{"Public", "OPTIONS, DESCRIBE, SETUP, PLAY, PAUSE, GET_PARAMETER, SET_PARAMETER, TEARDOWN"},
+		}, "")
+	case "DESCRIBE":
+		base := strings.TrimSuffix(req.uri, "/") + "/"
+		host, _, _ := net.SplitHostPort(sess.conn.LocalAddr().String())
+		b := sess.srv.stream(ctx, streamPath(req.uri))
+		sess.respond(req, 200, "OK", [][2]string{
+			{"Content-Base", base},
+			{"Content-Type", "application/sdp"},
+		}, b.stream.SDP(host, "WINK RTSP Bench test stream"))
+	case "SETUP":
+		sess.setup(ctx, req)
+	case "PLAY":
+		sess.play(req)
+	case "PAUSE":
+		if sess.stream == nil {
+			sess.respond(req, 455, "Method Not Valid in This State", nil, "")
+			break
+		}
+		sess.stopPlaying()
+		sess.respond(req, 200, "OK", nil, "")
+	case "GET_PARAMETER", "SET_PARAMETER":
+		sess.respond(req, 200, "OK", nil, "")
+	case "TEARDOWN":
+		sess.respond(req, 200, "OK", nil, "")
+		sess.log.Debug("teardown")
+		return false
+	case "ANNOUNCE", "RECORD":
+		sess.respond(req, 405, "Method Not Allowed", [][2]string{
+			{"Allow", "OPTIONS, DESCRIBE, SETUP, PLAY, PAUSE, GET_PARAMETER, SET_PARAMETER, TEARDOWN"},
+		}, "")
+	default:
+		sess.respond(req, 501, "Not Implemented", nil, "")
+	}
+	return true
+}
+
+// setup negotiates TCP interleaved or UDP transport for the video track
+func (sess *session) setup(ctx context.Context, req *request) {
+	if sess.stream != nil {
+		// One track per session; a repeated SETUP is not supported
+		sess.respond(req, 459, "Aggregate Operation Not Allowed", nil, "")
+		return
+	}
+
+	transport := req.headers["transport"]
+	if strings.Contains(transport, "mode=record") || strings.Contains(transport, "mode=\"record\"") {
+		sess.respond(req, 461, "Unsupported Transport", nil, "")
+		return
+	}
+
+	var reply string
+	if lo, hi, ok := portRange(transport, "interleaved="); ok {
+		sess.channel = byte(lo)
+		reply = fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%d-%d", lo, hi)
+	} else if lo, hi, ok := portRange(transport, "client_port="); ok {
+		host, _, _ := net.SplitHostPort(sess.conn.RemoteAddr().String())
+		sess.udp = &net.UDPAddr{IP: net.ParseIP(host), Port: lo}
+		reply = fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d;server_port=%d-%d",
+			lo, hi, udpPort(sess.srv.rtpConn), udpPort(sess.srv.rtcpConn))
+	} else {
+		sess.respond(req, 461, "Unsupported Transport", nil, "")
+		return
+	}
+
+	path := streamPath(req.uri)
+	sess.stream = sess.srv.stream(ctx, path)
+	sess.base = strings.TrimSuffix(strings.TrimSuffix(req.uri, trackControl), "/")
+	sess.respond(req, 200, "OK", [][2]string{
+		{"Transport", reply},
+		{"Session", fmt.Sprintf("%s;timeout=%d", sess.id, int(SessionTimeout.Seconds()))},
+	}, "")
+	sess.log.Debug("setup", "path", path, "transport", reply)
+}
+
+// play subscribes the session to its stream. The response is written
+// before any media, so it always precedes the first interleaved packet.
+func (sess *session) play(req *request) {
+	if sess.stream == nil {
+		sess.respond(req, 455, "Method Not Valid in This State", nil, "")
+		return
+	}
+
+	sess.writeMu.Lock()
+	defer sess.writeMu.Unlock()
+
+	seq, ts := sess.stream.subscribe(sess)
+	if !sess.playing {
+		sess.playing = true
+		sess.srv.playing.Add(1)
+	}
+	sess.writeResponse(req, 200, "OK", [][2]string{
+		{"Range", "npt=0.000-"},
+		{"RTP-Info", fmt.Sprintf("url=%s/%s;seq=%d;rtptime=%d", sess.base, trackControl, seq, ts)},
+	}, "")
+
+	if !sess.writing {
+		sess.writing = true
+		go sess.writeMedia()
+	}
+}
+
+// stopPlaying unsubscribes the session from its stream
+func (sess *session) stopPlaying() {
+	if sess.stream != nil {
+		sess.stream.unsubscribe(sess)
+	}
+	if sess.playing {
+		sess.playing = false
+		sess.srv.playing.Add(-1)
+	}
+}
+
+// close stops media and closes the connection
+func (sess *session) close() {
+	sess.stopPlaying()
+	close(sess.done)
+	sess.conn.Close()
+}
+
+// writeMedia sends queued frames until the session closes
+func (sess *session) writeMedia() {
+	var buf []byte
+	for {
+		var frame [][]byte
+		select {
+		case <-sess.done:
+			return
+		case frame = <-sess.frames:
+		}
+
+		if sess.udp != nil {
+			for _, pkt := range frame {
+				if _, err := sess.srv.rtpConn.WriteTo(pkt, sess.udp); err != nil {
+					sess.log.Debug("UDP send failed", "error", err)
+					continue
+				}
+				sess.srv.packets.Add(1)
+				sess.srv.bytes.Add(uint64(len(pkt)))
+			}
+			continue
+		}
+
+		// One write per frame over TCP
+		buf = buf[:0]
+		var n int
+		for _, pkt := range frame {
+			buf = append(buf, '$', sess.channel, 0, 0)
+			binary.BigEndian.PutUint16(buf[len(buf)-2:], uint16(len(pkt)))
+			buf = append(buf, pkt...)
+			n += len(pkt)
+		}
+		sess.writeMu.Lock()
+		sess.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		_, err := sess.conn.Write(buf)
+		sess.writeMu.Unlock()
+		if err != nil {
+			sess.log.Debug("media write failed", "error", err)
+			sess.conn.Close()
+			return
+		}
+		sess.srv.packets.Add(uint64(len(frame)))
+		sess.srv.bytes.Add(uint64(n))
+	}
+}
+
+// discardInterleaved skips an interleaved frame (RTCP from the reader)
+func (sess *session) discardInterleaved() error {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(sess.reader, header); err != nil {
+		return err
+	}
+	_, err := sess.reader.Discard(int(binary.BigEndian.Uint16(header[2:4])))
+	return err
+}
+
+// respond writes a response
+func (sess *session) respond(req *request, code int, reason string, headers [][2]string, body string) {
+	sess.writeMu.Lock()
+	defer sess.writeMu.Unlock()
+	sess.writeResponse(req, code, reason, headers, body)
+}
+
+// writeResponse writes a response. The caller must hold writeMu.
+func (sess *session) writeResponse(req *request, code int, reason string, headers [][2]string, body string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "RTSP/1.0 %d %s\r\n", code, reason)
+	if req != nil {
+		fmt.Fprintf(&b, "CSeq: %s\r\n", req.headers["cseq"])
+	}
+	b.WriteString("Server: WINK-RTSP-Bench/1.0\r\n")
+	if sess.stream != nil && req != nil && req.method != "SETUP" {
+		fmt.Fprintf(&b, "Session: %s\r\n", sess.id)
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&b, "%s: %s\r\n", h[0], h[1])
+	}
+	if body != "" {
+		fmt.Fprintf(&b, "Content-Length: %d\r\n", len(body))
+	}
+	b.WriteString("\r\n")
+	b.WriteString(body)
+
+	sess.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	sess.conn.Write([]byte(b.String()))
+}
+
+// readRequest reads a request line, headers and any body
+func readRequest(r *bufio.Reader) (*request, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 3 || !strings.HasPrefix(fields[2], "RTSP/") {
+		return nil, fmt.Errorf("malformed request line: %q", line)
+	}
+
+	req := &request{method: fields[0], uri: fields[1], headers: make(map[string]string)}
+	for i := 0; ; i++ {
+		if i == maxHeaders {
+			return nil, errors.New("too many headers")
+		}
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header: %q", line)
+		}
+		req.headers[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+
+	if cl := req.headers["content-length"]; cl != "" {
+		n, err := strconv.Atoi(cl)
+		if err != nil || n < 0 || n > maxBody {
+			return nil, fmt.Errorf("invalid Content-Length: %q", cl)
+		}
+		req.body = make([]byte, n)
+		if _, err := io.ReadFull(r, req.body); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// readLine reads a CRLF or LF terminated line of at most 4KB
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", errors.New("line too long")
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// streamPath returns the stream a request URI refers to, without the
+// track control suffix
+func streamPath(uri string) string {
+	path := uri
+	if u, err := url.Parse(uri); err == nil {
+		path = u.Path
+	}
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "/"), "/"+trackControl)
+	return strings.TrimSuffix(path, "/")
+}
+
+// portRange extracts "<lo>-<hi>" following key in a Transport header;
+// hi defaults to lo+1
+func portRange(transport, key string) (int, int, bool) {
+	for _, part := range strings.Split(transport, ";") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(part), key)
+		if !ok {
+			continue
+		}
+		lo, hi, _ := strings.Cut(value, "-")
+		l, err := strconv.Atoi(lo)
+		if err != nil || l < 0 || l > 65535 {
+			return 0, 0, false
+		}
+		h, err := strconv.Atoi(hi)
+		if err != nil {
+			h = l + 1
+		}
+		return l, h, true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,222 @@
+// Created by WINK Streaming (https://www.wink.co)
+package synth
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/winkstreaming/wink-rtsp-bench/internal/codec"
+)
+
+// ClockRate is the RTP clock rate of the synthetic video
+const ClockRate = 90000
+
+// Stream defaults
+const (
+	DefaultBitrateKbps = 2000
+	DefaultPacketSize  = 1200
+	DefaultPayloadType = 96
+	DefaultFPS         = 30
+)
+
+// Baseline profile 640x480 parameter sets. The slices that follow are not
+// decodable video, but they are well-formed NAL units that depacketizers
+// and servers handle like real H.264.
+var (
+	sps = []byte{0x67, 0x42, 0x00, 0x1f, 0x95, 0xa8, 0x14, 0x01, 0x6e, 0x40}
+	pps = []byte{0x68, 0xce, 0x3c, 0x80}
+)
+
+// Options configures a synthetic H.264 stream
+type Options struct {
+	BitrateKbps int    // Average video bitrate (default 2000)
+	PacketSize  int    // Maximum RTP payload in bytes (default 1200)
+	PayloadType int    // Dynamic RTP payload type (default 96)
+	FPS         int    // Frames per second (default 30)
+	GOP         int    // Frames per keyframe interval (default 2s worth)
+	SSRC        uint32 // 0 = random
+	Timestamps  bool   // Send a wall-clock SEI message before each frame
+}
+
+// Stream generates RTP packets for a deterministic H.264 stream: every
+// GOP starts with SPS, PPS and an IDR slice, followed by non-IDR slices
+// fragmented with FU-A. Frame contents depend only on the frame number.
+type Stream struct {
+	opts      Options
+	frameSize int // Non-IDR frame bytes
+	idrSize   int // IDR frame bytes
+	ssrc      uint32
+	seq       uint16
+	timestamp uint32
+	frame     uint64
+}
+
+// NewStream creates a stream, filling in defaults for zero options
+func NewStream(opts Options) *Stream {
+	if opts.BitrateKbps <= 0 {
+		opts.BitrateKbps = DefaultBitrateKbps
+	}
+	if opts.PacketSize <= 0 {
+		opts.PacketSize = DefaultPacketSize
+	}
+	if opts.PacketSize < 16 {
+		opts.PacketSize = 16
+	}
+	if opts.PayloadType <= 0 {
+		opts.PayloadType = DefaultPayloadType
+	}
+	if opts.FPS <= 0 {
+		opts.FPS = DefaultFPS
+	}
+	if opts.GOP <= 0 {
+		opts.GOP = 2 * opts.FPS
+	}
+	if opts.SSRC == 0 {
+		opts.SSRC = rand.Uint32()
+	}
+
+	// IDR frames are four times the average; the rest share what is left
+	avg := opts.BitrateKbps * 1000 / 8 / opts.FPS
+	idr := 4 * avg
+	frame := avg
+	if opts.GOP > 1 {
+		frame = (opts.GOP*avg - idr) / (opts.GOP - 1)
+	}
+	if frame < 16 {
+		frame = 16
+	}
+
+	return &Stream{
+		opts:      opts,
+		frameSize: frame,
+		idrSize:   idr,
+		ssrc:      opts.SSRC,
+		seq:       uint16(rand.Intn(1 << 16)),
+		timestamp: rand.Uint32(),
+	}
+}
+
+// Options returns the stream options with defaults applied
+func (s *Stream) Options() Options {
+	return s.opts
+}
+
+// Next returns the sequence number and timestamp of the next packet, as
+// announced in a PLAY response's RTP-Info
+func (s *Stream) Next() (uint16, uint32) {
+	return s.seq, s.timestamp
+}
+
+// FrameInterval returns the time between frames
+func (s *Stream) FrameInterval() time.Duration {
+	return time.Second / time.Duration(s.opts.FPS)
+}
+
+// Media returns the SDP media section for the stream
+func (s *Stream) Media(control string) string {
+	pt := s.opts.PayloadType
+	params := base64.StdEncoding.EncodeToString(sps) + "," + base64.StdEncoding.EncodeToString(pps)
+	var b strings.Builder
+	fmt.Fprintf(&b, "m=video 0 RTP/AVP %d\r\n", pt)
+	fmt.Fprintf(&b, "a=rtpmap:%d H264/%d\r\n", pt, ClockRate)
+	fmt.Fprintf(&b, "a=fmtp:%d packetization-mode=1;profile-level-id=42001f;sprop-parameter-sets=%s\r\n", pt, params)
+	fmt.Fprintf(&b, "a=control:%s\r\n", control)
+	return b.String()
+}
+
+// SDP returns a complete session description for the stream
+func (s *Stream) SDP(host, name string) string {
+	return fmt.Sprintf("v=0\r\no=- 0 0 IN IP4 %s\r\ns=%s\r\nc=IN IP4 %s\r\nt=0 0\r\n", host, name, host) +
+		s.Media("trackID=0")
+}
+
+// NextFrame returns the RTP packets of the next frame and advances the
+// timestamp by one frame interval
+func (s *Stream) NextFrame() [][]byte {
+	keyframe := s.frame%uint64(s.opts.GOP) == 0
+	var packets [][]byte
+	if s.opts.Timestamps {
+		packets = append(packets, s.packet(codec.TimestampSEI(time.Now()), false))
+	}
+	if keyframe {
+		packets = append(packets, s.packet(sps, false), s.packet(pps, false))
+		packets = append(packets, s.slice(0x65, s.idrSize)...)
+	} else {
+		packets = append(packets, s.slice(0x41, s.frameSize)...)
+	}
+
+	s.frame++
+	s.timestamp += uint32(ClockRate / s.opts.FPS)
+	return packets
+}
+
+// slice builds a size-byte slice NAL unit with the given header and
+// packetizes it, as a single NAL unit or FU-A fragments
+func (s *Stream) slice(header byte, size int) [][]byte {
+	nal := make([]byte, size)
+	nal[0] = header
+	fill(nal[1:], s.frame)
+
+	max := s.opts.PacketSize
+	if len(nal) <= max {
+		return [][]byte{s.packet(nal, true)}
+	}
+
+	// FU-A: indicator keeps NRI with type 28, header carries S/E and type
+	indicator := header&0xe0 | 28
+	var packets [][]byte
+	body := nal[1:]
+	for first := true; len(body) > 0; first = false {
+		n := max - 2
+		if n > len(body) {
+			n = len(body)
+		}
+		fuHeader := header & 0x1f
+		if first {
+			fuHeader |= 0x80
+		}
+		last := n == len(body)
+		if last {
+			fuHeader |= 0x40
+		}
+		payload := make([]byte, 2+n)
+		payload[0] = indicator
+		payload[1] = fuHeader
+		copy(payload[2:], body[:n])
+		packets = append(packets, s.packet(payload, last))
+		body = body[n:]
+	}
+	return packets
+}
+
+// packet wraps payload in an RTP header with the next sequence number
+func (s *Stream) packet(payload []byte, marker bool) []byte {
+	pkt := make([]byte, 12+len(payload))
+	pkt[0] = 0x80
+	pkt[1] = byte(s.opts.PayloadType)
+	if marker {
+		pkt[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(pkt[2:4], s.seq)
+	binary.BigEndian.PutUint32(pkt[4:8], s.timestamp)
+	binary.BigEndian.PutUint32(pkt[8:12], s.ssrc)
+	copy(pkt[12:], payload)
+	s.seq++
+	return pkt
+}
+
+// fill writes a pattern derived from the frame number. Zero bytes are
+// avoided so the data can never contain an Annex B start code.
+func fill(b []byte, frame uint64) {
+	x := uint32(frame)*2654435761 + 1
+	for i := range b {
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		b[i] = byte(x) | 0x01
+	}
+}
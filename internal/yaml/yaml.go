@@ -0,0 +1,249 @@
+// Created by WINK Streaming (https://www.wink.co)
+
+// Package yaml decodes the block-style YAML subset used by scenario files:
+// nested maps, lists (including lists of maps), scalars, comments and
+// single-line flow lists. Anchors, multi-line strings and flow maps are not
+// supported.
+package yaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// line is a non-blank source line with its indentation
+type line struct {
+	num    int
+	indent int
+	text   string
+}
+
+// Unmarshal decodes YAML into v, using v's json tags for field names
+func Unmarshal(data []byte, v interface{}) error {
+	value, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	// Round-trip through JSON so struct tags and types are honoured
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, v)
+}
+
+// Parse decodes YAML into maps, slices and scalars
+func Parse(data []byte) (interface{}, error) {
+	var lines []line
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		if text == "" || strings.HasPrefix(text, "#") || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, line{num: i + 1, indent: len(raw) - len(text), text: text})
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	value, next, err := parseBlock(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("yaml: line %d: unexpected indentation", lines[next].num)
+	}
+	return value, nil
+}
+
+// parseBlock parses the map or list starting at lines[i]
+func parseBlock(lines []line, i, indent int) (interface{}, int, error) {
+	if isListItem(lines[i].text) {
+		return parseList(lines, i, indent)
+	}
+	return parseMap(lines, i, indent)
+}
+
+// parseMap parses "key: value" lines at the given indentation
+func parseMap(lines []line, i, indent int) (interface{}, int, error) {
+	m := make(map[string]interface{})
+	for i < len(lines) && lines[i].indent == indent && !isListItem(lines[i].text) {
+		l := lines[i]
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, i, fmt.Errorf("yaml: line %d: expected key: value", l.num)
+		}
+		i++
+
+		if rest != "" {
+			value, err := parseScalar(rest)
+			if err != nil {
+				return nil, i, fmt.Errorf("yaml: line %d: %w", l.num, err)
+			}
+			m[key] = value
+			continue
+		}
+
+		// Nested block: more indented, or a list at the same indentation
+		if i < len(lines) && (lines[i].indent > indent || (lines[i].indent == indent && isListItem(lines[i].text))) {
+			value, next, err := parseBlock(lines, i, lines[i].indent)
+			if err != nil {
+				return nil, next, err
+			}
+			m[key] = value
+			i = next
+		} else {
+			m[key] = nil
+		}
+	}
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, i, fmt.Errorf("yaml: line %d: unexpected indentation", lines[i].num)
+	}
+	return m, i, nil
+}
+
+// parseList parses "- item" lines at the given indentation
+func parseList(lines []line, i, indent int) (interface{}, int, error) {
+	var list []interface{}
+	for i < len(lines) && lines[i].indent == indent && isListItem(lines[i].text) {
+		l := lines[i]
+		rest := strings.TrimLeft(l.text[1:], " ")
+
+		switch {
+		case rest == "":
+			// Item is the following indented block
+			i++
+			if i >= len(lines) || lines[i].indent <= indent {
+				list = append(list, nil)
+				continue
+			}
+			value, next, err := parseBlock(lines, i, lines[i].indent)
+			if err != nil {
+				return nil, next, err
+			}
+			list = append(list, value)
+			i = next
+		case isListItem(rest) || isMapEntry(rest):
+			// "- key: value" starts a map (or nested list) indented to
+			// where the item text begins
+			itemIndent := indent + len(l.text) - len(rest)
+			lines[i] = line{num: l.num, indent: itemIndent, text: rest}
+			value, next, err := parseBlock(lines, i, itemIndent)
+			if err != nil {
+				return nil, next, err
+			}
+			list = append(list, value)
+			i = next
+		default:
+			value, err := parseScalar(rest)
+			if err != nil {
+				return nil, i, fmt.Errorf("yaml: line %d: %w", l.num, err)
+			}
+			list = append(list, value)
+			i++
+		}
+	}
+	return list, i, nil
+}
+
+// isListItem reports whether text is a "- " list entry
+func isListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// isMapEntry reports whether text is an unquoted "key: value" entry
+func isMapEntry(text string) bool {
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") || strings.HasPrefix(text, "[") {
+		return false
+	}
+	_, _, ok := splitKey(text)
+	return ok
+}
+
+// splitKey splits "key: value" (or "key:") into key and value
+func splitKey(text string) (string, string, bool) {
+	idx := strings.Index(text, ": ")
+	if idx < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false
+		}
+		idx = len(text) - 1
+	}
+	key := strings.TrimSpace(text[:idx])
+	if key == "" {
+		return "", "", false
+	}
+	key = strings.Trim(key, "\"'")
+	return key, strings.TrimSpace(text[idx+1:]), true
+}
+
+// parseScalar converts a scalar or single-line flow list
+func parseScalar(s string) (interface{}, error) {
+	s = stripComment(s)
+
+	if strings.HasPrefix(s, "[") {
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated flow list")
+		}
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		list := []interface{}{}
+		if inner == "" {
+			return list, nil
+		}
+		for _, item := range strings.Split(inner, ",") {
+			value, err := parseScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') {
+		if s[len(s)-1] != s[0] {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		if s[0] == '"' {
+			return strconv.Unquote(s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+
+	switch s {
+	case "", "~", "null":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return n, nil
+	}
+	return s, nil
+}
+
+// stripComment removes a trailing " # comment" outside of quotes
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && i > 0 && (s[i-1] == ' ' || s[i-1] == '\t'):
+			return strings.TrimSpace(s[:i])
+		}
+	}
+	return s
+}